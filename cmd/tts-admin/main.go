@@ -0,0 +1,355 @@
+// Command tts-admin holds operator tasks for tts-service. Its replay command
+// publishes failed or archived jobs again, optionally with changed settings.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/book-expert/tts-service/internal/natsconn"
+	"github.com/book-expert/tts-service/internal/replay"
+	"github.com/nats-io/nats.go"
+)
+
+// maxRecordSize bounds one line of a replay file.
+const maxRecordSize = 16 << 20
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errInvalidFlag    = errors.New("invalid flag")
+)
+
+// overrideFlags collects repeated -set key=value flags.
+type overrideFlags map[string]string
+
+func (o overrideFlags) String() string {
+	pairs := make([]string, 0, len(o))
+	for key, value := range o {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	return strings.Join(pairs, ",")
+}
+
+func (o overrideFlags) Set(value string) error {
+	key, fieldValue, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		return fmt.Errorf("%w: -set %q is not key=value", errInvalidFlag, value)
+	}
+
+	o[key] = fieldValue
+
+	return nil
+}
+
+// replayConfig holds the flags of the replay command.
+type replayConfig struct {
+	url         string
+	fromFile    string
+	fromSubject string
+	to          string
+	reply       string
+	workflows   string
+	since       string
+	until       string
+	wait        time.Duration
+	overrides   overrideFlags
+	dryRun      bool
+}
+
+func parseReplayFlags(args []string) (replayConfig, replay.Filter, error) {
+	var cfg replayConfig
+
+	cfg.overrides = overrideFlags{}
+
+	flags := flag.NewFlagSet("tts-admin replay", flag.ContinueOnError)
+	flags.StringVar(&cfg.url, "url", nats.DefaultURL, "NATS URL")
+	flags.StringVar(&cfg.fromFile, "from-file", "", "file of failure or job events, one JSON object per line; - reads stdin")
+	flags.StringVar(&cfg.fromSubject, "from-subject", "",
+		"subject to read the retained failure events of, e.g. the job_failed_subject; it must be captured by a stream")
+	flags.StringVar(&cfg.to, "to", "text.processed", "subject to publish the jobs on")
+	flags.StringVar(&cfg.reply, "reply", "", "reply subject of the published jobs")
+	flags.StringVar(&cfg.workflows, "workflow", "", "comma-separated workflow IDs to replay; empty replays every workflow")
+	flags.StringVar(&cfg.since, "since", "", "replay jobs that failed at or after this RFC 3339 time")
+	flags.StringVar(&cfg.until, "until", "", "replay jobs that failed before this RFC 3339 time")
+	flags.DurationVar(&cfg.wait, "wait", 5*time.Second, "how long -from-subject waits for the next event")
+	flags.Var(cfg.overrides, "set", "job field to change, as json_name=value; repeatable, e.g. -set voice=leo")
+	flags.BoolVar(&cfg.dryRun, "dry-run", false, "print the jobs instead of publishing them")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return replayConfig{}, replay.Filter{}, fmt.Errorf("%w: %w", errInvalidFlag, err)
+	}
+
+	if (cfg.fromFile == "") == (cfg.fromSubject == "") {
+		return replayConfig{}, replay.Filter{}, fmt.Errorf("%w: set one of -from-file and -from-subject", errInvalidFlag)
+	}
+
+	filter := replay.Filter{WorkflowIDs: nil, Since: time.Time{}, Until: time.Time{}}
+
+	for _, workflowID := range strings.Split(cfg.workflows, ",") {
+		workflowID = strings.TrimSpace(workflowID)
+		if workflowID != "" {
+			filter.WorkflowIDs = append(filter.WorkflowIDs, workflowID)
+		}
+	}
+
+	filter.Since, err = parseTime("since", cfg.since)
+	if err != nil {
+		return replayConfig{}, replay.Filter{}, err
+	}
+
+	filter.Until, err = parseTime("until", cfg.until)
+	if err != nil {
+		return replayConfig{}, replay.Filter{}, err
+	}
+
+	return cfg, filter, nil
+}
+
+// parseTime parses an optional RFC 3339 flag value.
+func parseTime(name, value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: -%s: %w", errInvalidFlag, name, err)
+	}
+
+	return parsed, nil
+}
+
+// publishFunc publishes or prints one job.
+type publishFunc func(event []byte) error
+
+// replayJobs selects the records that match filter, applies the overrides and
+// publishes them. It returns the number of records read and published;
+// records that are not jobs are reported and skipped.
+func replayJobs(records [][]byte, filter replay.Filter, overrides map[string]string, publish publishFunc) (int, error) {
+	replayed := 0
+
+	for _, record := range records {
+		job, err := replay.Decode(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "skipping record: %v\n", err)
+
+			continue
+		}
+
+		if !filter.Match(job) {
+			continue
+		}
+
+		event, err := replay.Override(job.Event, overrides)
+		if err != nil {
+			return replayed, fmt.Errorf("job of workflow '%s': %w", job.WorkflowID, err)
+		}
+
+		err = publish(event)
+		if err != nil {
+			return replayed, err
+		}
+
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// readFile returns the non-empty lines of a replay file.
+func readFile(name string) ([][]byte, error) {
+	var reader io.Reader = os.Stdin
+
+	if name != "-" {
+		file, err := os.Open(name) // #nosec G304 -- the operator names the file
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %w", name, err)
+		}
+		defer file.Close()
+
+		reader = file
+	}
+
+	var records [][]byte
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordSize)
+
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			records = append(records, []byte(line))
+		}
+	}
+
+	err := scanner.Err()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	return records, nil
+}
+
+// readSubject returns the messages a JetStream stream retains on subject, in
+// order, stopping at the last one or when none arrives within wait.
+func readSubject(ctx context.Context, jetstreamContext nats.JetStreamContext, subject string, wait time.Duration) ([][]byte, error) {
+	subscription, err := jetstreamContext.SubscribeSync(subject, nats.OrderedConsumer(), nats.DeliverAll())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from its stream: %w", subject, err)
+	}
+
+	defer func() { _ = subscription.Unsubscribe() }()
+
+	var records [][]byte
+
+	for {
+		waitCtx, cancel := context.WithTimeout(ctx, wait)
+		msg, err := subscription.NextMsgWithContext(waitCtx)
+
+		cancel()
+
+		if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+			return records, nil
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", subject, err)
+		}
+
+		records = append(records, msg.Data)
+
+		metadata, err := msg.Metadata()
+		if err == nil && metadata.NumPending == 0 {
+			return records, nil
+		}
+	}
+}
+
+func runReplay(args []string) error {
+	cfg, filter, err := parseReplayFlags(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	var natsConnection *nats.Conn
+
+	if cfg.fromSubject != "" || !cfg.dryRun {
+		natsConnection, err = natsconn.Connect(cfg.url, natsconn.Options{
+			Name:      "tts-admin",
+			Auth:      natsconn.Auth{},
+			TLS:       natsconn.TLS{},
+			Reconnect: natsconn.Reconnect{},
+			Health:    nil,
+		})
+		if err != nil {
+			return err
+		}
+		defer natsConnection.Close()
+	}
+
+	var records [][]byte
+
+	if cfg.fromFile != "" {
+		records, err = readFile(cfg.fromFile)
+	} else {
+		var jetstreamContext nats.JetStreamContext
+
+		jetstreamContext, err = natsConnection.JetStream()
+		if err != nil {
+			return fmt.Errorf("failed to get JetStream context: %w", err)
+		}
+
+		records, err = readSubject(ctx, jetstreamContext, cfg.fromSubject, cfg.wait)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	publish := func(event []byte) error {
+		if cfg.dryRun {
+			fmt.Fprintln(os.Stdout, string(event))
+
+			return nil
+		}
+
+		msg := nats.NewMsg(cfg.to)
+		msg.Reply = cfg.reply
+		msg.Data = event
+
+		publishErr := natsConnection.PublishMsg(msg)
+		if publishErr != nil {
+			return fmt.Errorf("failed to publish job: %w", publishErr)
+		}
+
+		return nil
+	}
+
+	replayed, err := replayJobs(records, filter, cfg.overrides, publish)
+	if err == nil && natsConnection != nil {
+		err = natsConnection.FlushWithContext(ctx)
+	}
+
+	fmt.Fprintf(os.Stderr, "replayed %d of %d jobs (%d skipped)\n", replayed, len(records), len(records)-replayed)
+
+	return err
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: tts-admin <command> [flags]
+
+commands:
+  replay   publish failed or archived jobs again
+  version  print the build version
+
+Run 'tts-admin <command> -h' for the flags of a command.
+`)
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+
+		return fmt.Errorf("%w: none given", errUnknownCommand)
+	}
+
+	switch args[0] {
+	case "replay":
+		return runReplay(args[1:])
+	case "version", "-version", "--version":
+		fmt.Fprintln(os.Stdout, "tts-admin "+buildinfo.Get().String())
+
+		return nil
+	case "help", "-h", "-help", "--help":
+		usage()
+
+		return nil
+	default:
+		usage()
+
+		return fmt.Errorf("%w: %q", errUnknownCommand, args[0])
+	}
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "tts-admin: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	htmltemplate "html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+// take is the audio of one sample with one variant.
+type take struct {
+	// Path is relative to the output directory; empty when the take failed.
+	Path            string
+	DurationSeconds float64
+	Error           string
+}
+
+// column is one variant and its takes, one per sample.
+type column struct {
+	Name     string
+	Settings string
+	Takes    []take
+}
+
+// comparison is the content of the index: samples as rows, variants as columns.
+type comparison struct {
+	Title    string
+	Samples  []string
+	Variants []column
+}
+
+// Take returns the take of variant v for sample s.
+func (c comparison) Take(v, s int) take {
+	return c.Variants[v].Takes[s]
+}
+
+// settingsText lists settings as sorted key=value pairs.
+func settingsText(settings map[string]string) string {
+	pairs := make([]string, 0, len(settings))
+	for key, value := range settings {
+		pairs = append(pairs, key+"="+value)
+	}
+
+	slices.Sort(pairs)
+
+	return strings.Join(pairs, " ")
+}
+
+// excerpt shortens a sample for a table cell.
+func excerpt(text string) string {
+	const maxRunes = 80
+
+	runes := []rune(text)
+	if len(runes) <= maxRunes {
+		return text
+	}
+
+	return string(runes[:maxRunes]) + "…"
+}
+
+// markdownCell escapes the characters that end a Markdown table cell.
+func markdownCell(text string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(text, "|", `\|`), "\n", " ")
+}
+
+var templateFuncs = map[string]any{
+	"excerpt": excerpt,
+	"cell":    markdownCell,
+	"inc":     func(i int) int { return i + 1 },
+}
+
+const markdownIndex = `# {{.Title}}
+
+| Sample |{{range .Variants}} {{cell .Name}} |{{end}}
+|---|{{range .Variants}}---|{{end}}
+{{- $c := .}}
+{{range $s, $text := .Samples}}| {{inc $s}}. {{cell (excerpt $text)}} |
+{{- range $v, $_ := $c.Variants}}{{with $c.Take $v $s}} {{if .Path}}[{{.Path}}]({{.Path}}) ({{printf "%.1f" .DurationSeconds}} s)` +
+	`{{else}}failed: {{cell .Error}}{{end}} |{{end}}{{end}}
+{{end}}
+## Settings
+{{range .Variants}}
+- **{{.Name}}**: {{if .Settings}}` + "`{{.Settings}}`" + `{{else}}service defaults{{end}}
+{{- end}}
+`
+
+const htmlIndex = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 0.5em; vertical-align: top; }
+td.text { max-width: 30em; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<table>
+<tr><th>Sample</th>{{range .Variants}}<th>{{.Name}}<br><small>{{.Settings}}</small></th>{{end}}</tr>
+{{- $c := .}}
+{{range $s, $text := .Samples}}<tr><td class="text">{{inc $s}}. {{$text}}</td>
+{{- range $v, $_ := $c.Variants}}{{with $c.Take $v $s}}<td>{{if .Path}}<audio controls preload="none" src="{{.Path}}"></audio>` +
+	`<br><small>{{printf "%.1f" .DurationSeconds}} s</small>{{else}}<span class="error">{{.Error}}</span>{{end}}</td>{{end}}{{end}}</tr>
+{{end}}</table>
+</body>
+</html>
+`
+
+// writeIndex writes index.md and index.html to out.
+func writeIndex(out string, index comparison) error {
+	err := os.MkdirAll(out, 0o750)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", out, err)
+	}
+
+	var markdown strings.Builder
+
+	err = template.Must(template.New("index.md").Funcs(templateFuncs).Parse(markdownIndex)).Execute(&markdown, index)
+	if err != nil {
+		return fmt.Errorf("failed to render index.md: %w", err)
+	}
+
+	var html strings.Builder
+
+	err = htmltemplate.Must(htmltemplate.New("index.html").Funcs(templateFuncs).Parse(htmlIndex)).Execute(&html, index)
+	if err != nil {
+		return fmt.Errorf("failed to render index.html: %w", err)
+	}
+
+	for name, content := range map[string]string{"index.md": markdown.String(), "index.html": html.String()} {
+		err = os.WriteFile(filepath.Join(out, name), []byte(content), 0o600)
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %w", name, err)
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "wrote %s and %s\n", filepath.Join(out, "index.md"), filepath.Join(out, "index.html"))
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// update writes the index of the test comparison as the golden one.
+var update = flag.Bool("update", false, "write testdata/index.md and testdata/index.html from the current templates")
+
+func TestWriteIndex_Golden(t *testing.T) {
+	t.Parallel()
+
+	index := comparison{
+		Title: "Seeds of tara",
+		Samples: []string{
+			"The house stood at the end of the lane, its windows dark against the evening sky. Nobody had lived there.",
+			"A | B <b>bold</b>\nand a second line.",
+		},
+		Variants: []column{
+			{
+				Name:     "seed-7",
+				Settings: settingsText(map[string]string{"voice": "tara", "seed": "7"}),
+				Takes: []take{
+					{Path: "seed-7/sample-01.wav", DurationSeconds: 6.26, Error: ""},
+					{Path: "seed-7/sample-02.wav", DurationSeconds: 2, Error: ""},
+				},
+			},
+			{
+				Name:     "defaults",
+				Settings: "",
+				Takes: []take{
+					{Path: "defaults/sample-01.wav", DurationSeconds: 5.95, Error: ""},
+					{Path: "", DurationSeconds: 0, Error: "no reply from worker: timeout | <retry>"},
+				},
+			},
+		},
+	}
+
+	out := t.TempDir()
+	require.NoError(t, writeIndex(out, index))
+
+	for _, name := range []string{"index.md", "index.html"} {
+		got, err := os.ReadFile(filepath.Join(out, name))
+		require.NoError(t, err)
+
+		golden := filepath.Join("testdata", name)
+
+		if *update {
+			require.NoError(t, os.WriteFile(golden, got, 0o600))
+		}
+
+		want, err := os.ReadFile(golden) // #nosec G304 -- a fixed test file
+		require.NoError(t, err)
+		assert.Equal(t, string(want), string(got), "%s differs from %s; run with -update if the change is intended", name, golden)
+	}
+}
+
+func TestExcerpt(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "short", excerpt("short"))
+
+	long := strings.Repeat("é", 81)
+	assert.Equal(t, strings.Repeat("é", 80)+"…", excerpt(long), "text is cut after 80 characters, not bytes")
+}
@@ -0,0 +1,305 @@
+// Command tts-audition helps pick narration settings. Its compare command
+// synthesizes the same sample texts with several voices or parameter sets
+// through a running tts-service and writes the audio with an index to listen
+// to them side by side. Its seeds command does the same for one voice with
+// different seeds, and pick records the take that was chosen.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	errUnknownCommand = errors.New("unknown command")
+	errInvalidFlag    = errors.New("invalid flag")
+)
+
+// defaultSamples are read when no -texts file is given: narration, dialogue
+// and a passage with numbers and names.
+var defaultSamples = []string{
+	"The house stood at the end of the lane, its windows dark against the evening sky. " +
+		"Nobody had lived there for years, or so the village said.",
+	`"Are you coming?" she asked, already halfway out of the door. ` +
+		`"In a minute," he said, and did not move.`,
+	"On the 3rd of May, 1897, Dr. Abraham Van Helsing arrived in Whitby with two trunks " +
+		"and a letter he would not let anyone read.",
+}
+
+// variant is one set of job settings to compare.
+type variant struct {
+	name     string
+	settings map[string]string
+}
+
+// variantFlags collects repeated -variant flags.
+type variantFlags []variant
+
+func (v *variantFlags) String() string {
+	names := make([]string, 0, len(*v))
+	for _, item := range *v {
+		names = append(names, item.name)
+	}
+
+	return strings.Join(names, ",")
+}
+
+// Set parses "name key=value key=value ...", where the keys are job fields by
+// their JSON names.
+func (v *variantFlags) Set(value string) error {
+	fields := strings.Fields(value)
+	if len(fields) == 0 {
+		return fmt.Errorf("%w: -variant is empty", errInvalidFlag)
+	}
+
+	item := variant{name: fields[0], settings: map[string]string{}}
+
+	for _, field := range fields[1:] {
+		key, fieldValue, found := strings.Cut(field, "=")
+		if !found || key == "" {
+			return fmt.Errorf("%w: -variant %q: %q is not key=value", errInvalidFlag, value, field)
+		}
+
+		item.settings[key] = fieldValue
+	}
+
+	*v = append(*v, item)
+
+	return nil
+}
+
+// connectionFlags are the flags every command needs to reach the service.
+type connectionFlags struct {
+	url     string
+	subject string
+	bucket  string
+	timeout time.Duration
+}
+
+func (c *connectionFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&c.url, "url", nats.DefaultURL, "NATS URL")
+	flags.StringVar(&c.subject, "subject", "text.processed", "job subject of the service")
+	flags.StringVar(&c.bucket, "bucket", "AUDIO_FILES", "object store bucket the worker reads text from and writes audio to")
+	flags.DurationVar(&c.timeout, "timeout", 5*time.Minute, "timeout of one job")
+}
+
+// compareConfig holds the flags of the compare command.
+type compareConfig struct {
+	connection connectionFlags
+	texts      string
+	voices     string
+	variants   variantFlags
+	out        string
+}
+
+func parseCompareFlags(args []string) (compareConfig, error) {
+	var cfg compareConfig
+
+	flags := flag.NewFlagSet("tts-audition compare", flag.ContinueOnError)
+	cfg.connection.register(flags)
+	flags.StringVar(&cfg.texts, "texts", "", "file of sample texts separated by blank lines; empty uses built-in samples")
+	flags.StringVar(&cfg.voices, "voices", "", "comma-separated voices, each compared with the service's other defaults")
+	flags.Var(&cfg.variants, "variant",
+		`settings to compare, as "name key=value ..." with job fields by JSON name; repeatable, `+
+			`e.g. -variant "calm voice=tara temperature=0.4"`)
+	flags.StringVar(&cfg.out, "out", "audition", "directory to write the audio and index to")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return compareConfig{}, fmt.Errorf("%w: %w", errInvalidFlag, err)
+	}
+
+	for _, voice := range strings.Split(cfg.voices, ",") {
+		voice = strings.TrimSpace(voice)
+		if voice != "" {
+			cfg.variants = append(cfg.variants, variant{name: voice, settings: map[string]string{"voice": voice}})
+		}
+	}
+
+	if len(cfg.variants) == 0 {
+		return compareConfig{}, fmt.Errorf("%w: set -voices or -variant", errInvalidFlag)
+	}
+
+	seen := map[string]bool{}
+
+	for _, item := range cfg.variants {
+		if seen[fileName(item.name)] {
+			return compareConfig{}, fmt.Errorf("%w: variant %q is given twice", errInvalidFlag, item.name)
+		}
+
+		seen[fileName(item.name)] = true
+	}
+
+	return cfg, nil
+}
+
+// readSamples returns the paragraphs of the file name, or the built-in samples.
+func readSamples(name string) ([]string, error) {
+	if name == "" {
+		return defaultSamples, nil
+	}
+
+	data, err := os.ReadFile(name) // #nosec G304 -- the user names the file
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+
+	var samples []string
+
+	for _, paragraph := range regexp.MustCompile(`\n\s*\n`).Split(string(data), -1) {
+		paragraph = strings.Join(strings.Fields(paragraph), " ")
+		if paragraph != "" {
+			samples = append(samples, paragraph)
+		}
+	}
+
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("%w: %s has no text", errInvalidFlag, name)
+	}
+
+	return samples, nil
+}
+
+// unsafeName matches the characters replaced in file and directory names.
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// fileName turns a variant name into a directory name.
+func fileName(name string) string {
+	return strings.Trim(unsafeName.ReplaceAllString(name, "_"), "._")
+}
+
+func runCompare(args []string) error {
+	cfg, err := parseCompareFlags(args)
+	if err != nil {
+		return err
+	}
+
+	return audition(cfg.connection, cfg.texts, cfg.out, "Voice comparison", cfg.variants)
+}
+
+// audition synthesizes every sample with every variant, writes the audio to
+// one directory per variant under out, and indexes the takes.
+func audition(connection connectionFlags, texts, out, title string, variants []variant) error {
+	samples, err := readSamples(texts)
+	if err != nil {
+		return err
+	}
+
+	synth, err := newSynthesizer(connection.url, connection.bucket, connection.subject, connection.timeout)
+	if err != nil {
+		return err
+	}
+	defer synth.Close()
+
+	// Ctrl-C stops and indexes what has finished.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	index := comparison{Title: title, Samples: samples, Variants: nil}
+
+	for _, item := range variants {
+		column := column{Name: item.name, Settings: settingsText(item.settings), Takes: nil}
+
+		for i, sample := range samples {
+			path := filepath.Join(fileName(item.name), fmt.Sprintf("sample-%02d.wav", i+1))
+			column.Takes = append(column.Takes, record(ctx, synth, out, path, sample, item.settings))
+
+			fmt.Fprintf(os.Stderr, "%s: sample %d of %d done\n", item.name, i+1, len(samples))
+		}
+
+		index.Variants = append(index.Variants, column)
+	}
+
+	err = writeVariants(out, variants)
+	if err != nil {
+		return err
+	}
+
+	return writeIndex(out, index)
+}
+
+// record synthesizes one take and writes its audio to path under out.
+func record(ctx context.Context, synth *synthesizer, out, path, text string, settings map[string]string) take {
+	if ctx.Err() != nil {
+		return take{Path: "", DurationSeconds: 0, Error: ctx.Err().Error()}
+	}
+
+	chunk, audio, err := synth.synthesize(ctx, text, settings)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(filepath.Join(out, path)), 0o750)
+	}
+
+	if err == nil {
+		err = os.WriteFile(filepath.Join(out, path), audio, 0o600)
+	}
+
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+
+		return take{Path: "", DurationSeconds: 0, Error: err.Error()}
+	}
+
+	return take{Path: filepath.ToSlash(path), DurationSeconds: chunk.DurationSeconds, Error: ""}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, `usage: tts-audition <command> [flags]
+
+commands:
+  compare  synthesize sample texts with several voices or settings
+  seeds    synthesize a passage with one voice and several seeds
+  pick     record the chosen variant of a run
+  version  print the build version
+
+Run 'tts-audition <command> -h' for the flags of a command.
+`)
+}
+
+func run(args []string) error {
+	if len(args) == 0 {
+		usage()
+
+		return fmt.Errorf("%w: none given", errUnknownCommand)
+	}
+
+	switch args[0] {
+	case "compare":
+		return runCompare(args[1:])
+	case "seeds":
+		return runSeeds(args[1:])
+	case "pick":
+		return runPick(args[1:])
+	case "version", "-version", "--version":
+		fmt.Fprintln(os.Stdout, "tts-audition "+buildinfo.Get().String())
+
+		return nil
+	case "help", "-h", "-help", "--help":
+		usage()
+
+		return nil
+	default:
+		usage()
+
+		return fmt.Errorf("%w: %q", errUnknownCommand, args[0])
+	}
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "tts-audition: %v\n", err)
+		os.Exit(1)
+	}
+}
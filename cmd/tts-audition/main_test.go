@@ -0,0 +1,155 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVariantFlags_Set(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    variant
+		wantErr bool
+	}{
+		{
+			name:    "name only",
+			value:   "defaults",
+			want:    variant{name: "defaults", settings: map[string]string{}},
+			wantErr: false,
+		},
+		{
+			name:  "settings",
+			value: "calm  voice=tara temperature=0.4",
+			want: variant{name: "calm", settings: map[string]string{
+				"voice": "tara", "temperature": "0.4",
+			}},
+			wantErr: false,
+		},
+		{
+			name:    "value with equals sign",
+			value:   "styled style=a=b empty=",
+			want:    variant{name: "styled", settings: map[string]string{"style": "a=b", "empty": ""}},
+			wantErr: false,
+		},
+		{name: "empty", value: "  ", want: variant{name: "", settings: nil}, wantErr: true},
+		{name: "no equals sign", value: "calm voice", want: variant{name: "", settings: nil}, wantErr: true},
+		{name: "empty key", value: "calm =tara", want: variant{name: "", settings: nil}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var flags variantFlags
+
+			err := flags.Set(tt.value)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errInvalidFlag)
+				assert.Empty(t, flags, "a rejected variant is not added")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, variantFlags{tt.want}, flags)
+		})
+	}
+}
+
+func TestParseCompareFlags(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "voices", args: []string{"-voices", "tara, leo,,"}, want: []string{"tara", "leo"}, wantErr: false},
+		{
+			name:    "variants before voices",
+			args:    []string{"-variant", "calm voice=tara", "-voices", "leo"},
+			want:    []string{"calm", "leo"},
+			wantErr: false,
+		},
+		{name: "nothing to compare", args: []string{"-out", "x"}, want: nil, wantErr: true},
+		{name: "same name twice", args: []string{"-voices", "tara", "-variant", "tara seed=1"}, want: nil, wantErr: true},
+		{name: "same directory twice", args: []string{"-variant", "a b", "-variant", "a_b"}, want: nil, wantErr: true},
+		{name: "invalid variant", args: []string{"-variant", "calm voice"}, want: nil, wantErr: true},
+		{name: "unknown flag", args: []string{"-voice", "tara"}, want: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg, err := parseCompareFlags(tt.args)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errInvalidFlag)
+
+				return
+			}
+
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(cfg.variants))
+			for _, item := range cfg.variants {
+				names = append(names, item.name)
+			}
+
+			assert.Equal(t, tt.want, names)
+			assert.Equal(t, "audition", cfg.out)
+		})
+	}
+}
+
+func TestReadSamples(t *testing.T) {
+	t.Parallel()
+
+	samples, err := readSamples("")
+	require.NoError(t, err)
+	assert.Equal(t, defaultSamples, samples)
+
+	dir := t.TempDir()
+	texts := filepath.Join(dir, "texts.txt")
+	require.NoError(t, os.WriteFile(texts, []byte("First  line\nwrapped.\n\n  \n\nSecond.\n"), 0o600))
+
+	samples, err = readSamples(texts)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"First line wrapped.", "Second."}, samples)
+
+	empty := filepath.Join(dir, "empty.txt")
+	require.NoError(t, os.WriteFile(empty, []byte("\n \n"), 0o600))
+
+	_, err = readSamples(empty)
+	require.ErrorIs(t, err, errInvalidFlag)
+
+	_, err = readSamples(filepath.Join(dir, "missing.txt"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestFileName(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "seed-42", fileName("seed-42"))
+	assert.Equal(t, "calm_tara", fileName("calm / tara"))
+	assert.Equal(t, "hidden", fileName("..hidden."))
+}
+
+func TestRun_Errors(t *testing.T) {
+	t.Parallel()
+
+	require.ErrorIs(t, run(nil), errUnknownCommand)
+	require.ErrorIs(t, run([]string{"listen"}), errUnknownCommand)
+	require.ErrorIs(t, run([]string{"compare"}), errInvalidFlag)
+	require.ErrorIs(t, run([]string{"seeds", "-seeds", "3"}), errInvalidFlag, "seeds needs a voice")
+	require.ErrorIs(t, run([]string{"pick", "-out", t.TempDir()}), errInvalidFlag, "pick needs a variant")
+	require.NoError(t, run([]string{"help"}))
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Files written next to the index.
+const (
+	variantsFile = "variants.json"
+	chosenFile   = "chosen.json"
+)
+
+// settingFlags collects repeated -set key=value flags.
+type settingFlags map[string]string
+
+func (s settingFlags) String() string {
+	return settingsText(s)
+}
+
+func (s settingFlags) Set(value string) error {
+	key, fieldValue, found := strings.Cut(value, "=")
+	if !found || key == "" {
+		return fmt.Errorf("%w: -set %q is not key=value", errInvalidFlag, value)
+	}
+
+	s[key] = fieldValue
+
+	return nil
+}
+
+// seedsConfig holds the flags of the seeds command.
+type seedsConfig struct {
+	connection connectionFlags
+	texts      string
+	voice      string
+	settings   settingFlags
+	count      int
+	firstSeed  int
+	seedList   string
+	out        string
+}
+
+func parseSeedsFlags(args []string) (seedsConfig, []int, error) {
+	var cfg seedsConfig
+
+	cfg.settings = settingFlags{}
+
+	flags := flag.NewFlagSet("tts-audition seeds", flag.ContinueOnError)
+	cfg.connection.register(flags)
+	flags.StringVar(&cfg.texts, "texts", "", "file of passages separated by blank lines; empty uses built-in samples")
+	flags.StringVar(&cfg.voice, "voice", "", "voice of every take")
+	flags.Var(cfg.settings, "set", "other job field of every take, as json_name=value; repeatable, e.g. -set temperature=0.6")
+	flags.IntVar(&cfg.count, "seeds", 8, "number of seeds to try, counting up from -first-seed")
+	flags.IntVar(&cfg.firstSeed, "first-seed", 1, "first seed to try")
+	flags.StringVar(&cfg.seedList, "seed-list", "", "comma-separated seeds to try instead of -seeds")
+	flags.StringVar(&cfg.out, "out", "seeds", "directory to write the audio and index to")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return seedsConfig{}, nil, fmt.Errorf("%w: %w", errInvalidFlag, err)
+	}
+
+	if cfg.voice == "" {
+		return seedsConfig{}, nil, fmt.Errorf("%w: set -voice", errInvalidFlag)
+	}
+
+	seeds, err := seedsToTry(cfg)
+	if err != nil {
+		return seedsConfig{}, nil, err
+	}
+
+	return cfg, seeds, nil
+}
+
+// seedsToTry returns the seeds of -seed-list, or -seeds seeds from -first-seed.
+// Seed 0 is rejected: the service replaces it with its default seed. A seed
+// given twice is rejected too, since both takes would share a directory.
+func seedsToTry(cfg seedsConfig) ([]int, error) {
+	var seeds []int
+
+	if cfg.seedList != "" {
+		for _, field := range strings.Split(cfg.seedList, ",") {
+			seed, err := strconv.Atoi(strings.TrimSpace(field))
+			if err != nil {
+				return nil, fmt.Errorf("%w: -seed-list: %w", errInvalidFlag, err)
+			}
+
+			seeds = append(seeds, seed)
+		}
+	} else {
+		if cfg.count <= 0 {
+			return nil, fmt.Errorf("%w: -seeds must be positive", errInvalidFlag)
+		}
+
+		if cfg.firstSeed > math.MaxInt-(cfg.count-1) {
+			return nil, fmt.Errorf("%w: -first-seed %d with -seeds %d passes the largest seed", errInvalidFlag,
+				cfg.firstSeed, cfg.count)
+		}
+
+		for i := range cfg.count {
+			seeds = append(seeds, cfg.firstSeed+i)
+		}
+	}
+
+	seen := map[int]bool{}
+
+	for _, seed := range seeds {
+		if seed == 0 {
+			return nil, fmt.Errorf("%w: seed 0 selects the service's default seed", errInvalidFlag)
+		}
+
+		if seen[seed] {
+			return nil, fmt.Errorf("%w: seed %d is given twice", errInvalidFlag, seed)
+		}
+
+		seen[seed] = true
+	}
+
+	return seeds, nil
+}
+
+func runSeeds(args []string) error {
+	cfg, seeds, err := parseSeedsFlags(args)
+	if err != nil {
+		return err
+	}
+
+	variants := make([]variant, 0, len(seeds))
+
+	for _, seed := range seeds {
+		settings := maps.Clone(cfg.settings)
+		settings["voice"] = cfg.voice
+		settings["seed"] = strconv.Itoa(seed)
+
+		variants = append(variants, variant{name: fmt.Sprintf("seed-%d", seed), settings: settings})
+	}
+
+	return audition(cfg.connection, cfg.texts, cfg.out, "Seeds of "+cfg.voice, variants)
+}
+
+// variantRecord describes the takes of one variant in variants.json.
+type variantRecord struct {
+	Name      string            `json:"name"`
+	Directory string            `json:"directory"`
+	Settings  map[string]string `json:"settings"`
+}
+
+// choice is the take recorded in chosen.json.
+type choice struct {
+	variantRecord
+
+	ChosenAt time.Time `json:"chosen_at"`
+	Note     string    `json:"note,omitempty"`
+}
+
+// writeVariants records the settings of every variant, so a take can be
+// picked and reproduced later.
+func writeVariants(out string, variants []variant) error {
+	records := make([]variantRecord, 0, len(variants))
+	for _, item := range variants {
+		records = append(records, variantRecord{Name: item.name, Directory: fileName(item.name), Settings: item.settings})
+	}
+
+	return writeJSON(filepath.Join(out, variantsFile), records)
+}
+
+func writeJSON(path string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s: %w", path, err)
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// pickConfig holds the flags of the pick command.
+type pickConfig struct {
+	out     string
+	variant string
+	note    string
+}
+
+func parsePickFlags(args []string) (pickConfig, error) {
+	var cfg pickConfig
+
+	flags := flag.NewFlagSet("tts-audition pick", flag.ContinueOnError)
+	flags.StringVar(&cfg.out, "out", "seeds", "directory of a compare or seeds run")
+	flags.StringVar(&cfg.variant, "variant", "", "variant to choose, e.g. seed-42")
+	flags.StringVar(&cfg.note, "note", "", "why the take was chosen")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return pickConfig{}, fmt.Errorf("%w: %w", errInvalidFlag, err)
+	}
+
+	if cfg.variant == "" {
+		return pickConfig{}, fmt.Errorf("%w: set -variant", errInvalidFlag)
+	}
+
+	return cfg, nil
+}
+
+// runPick records the chosen variant of a run in chosen.json and prints its
+// settings as -set flags.
+func runPick(args []string) error {
+	cfg, err := parsePickFlags(args)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(filepath.Join(cfg.out, variantsFile)) // #nosec G304 -- the user names the directory
+	if err != nil {
+		return fmt.Errorf("failed to read the variants of %s: %w", cfg.out, err)
+	}
+
+	var records []variantRecord
+
+	err = json.Unmarshal(data, &records)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", variantsFile, err)
+	}
+
+	names := make([]string, 0, len(records))
+
+	for _, record := range records {
+		if record.Name != cfg.variant {
+			names = append(names, record.Name)
+
+			continue
+		}
+
+		err = writeJSON(filepath.Join(cfg.out, chosenFile), choice{
+			variantRecord: record,
+			ChosenAt:      time.Now().UTC(),
+			Note:          cfg.note,
+		})
+		if err != nil {
+			return err
+		}
+
+		var flags []string
+		for _, pair := range strings.Fields(settingsText(record.Settings)) {
+			flags = append(flags, "-set "+pair)
+		}
+
+		fmt.Fprintln(os.Stdout, strings.Join(flags, " "))
+
+		return nil
+	}
+
+	return fmt.Errorf("%w: no variant %q in %s (have %s)", errInvalidFlag, cfg.variant, cfg.out, strings.Join(names, ", "))
+}
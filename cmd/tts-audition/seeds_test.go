@@ -0,0 +1,78 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeedsToTry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		count     int
+		firstSeed int
+		seedList  string
+		want      []int
+		wantErr   bool
+	}{
+		{name: "default range", count: 8, firstSeed: 1, seedList: "", want: []int{1, 2, 3, 4, 5, 6, 7, 8}, wantErr: false},
+		{name: "one seed", count: 1, firstSeed: 42, seedList: "", want: []int{42}, wantErr: false},
+		{name: "negative range", count: 3, firstSeed: -5, seedList: "", want: []int{-5, -4, -3}, wantErr: false},
+		{
+			name: "range up to the largest seed", count: 2, firstSeed: math.MaxInt - 1, seedList: "",
+			want: []int{math.MaxInt - 1, math.MaxInt}, wantErr: false,
+		},
+		{name: "range past the largest seed", count: 3, firstSeed: math.MaxInt - 1, seedList: "", want: nil, wantErr: true},
+		{name: "range through zero", count: 3, firstSeed: -1, seedList: "", want: nil, wantErr: true},
+		{name: "no seeds", count: 0, firstSeed: 1, seedList: "", want: nil, wantErr: true},
+		{name: "negative count", count: -2, firstSeed: 1, seedList: "", want: nil, wantErr: true},
+		{name: "list", count: 8, firstSeed: 1, seedList: "7, 42,-3", want: []int{7, 42, -3}, wantErr: false},
+		{name: "list with a duplicate", count: 8, firstSeed: 1, seedList: "7,42,7", want: nil, wantErr: true},
+		{name: "list with zero", count: 8, firstSeed: 1, seedList: "7,0", want: nil, wantErr: true},
+		{name: "list with a word", count: 8, firstSeed: 1, seedList: "7,lucky", want: nil, wantErr: true},
+		{name: "list with a gap", count: 8, firstSeed: 1, seedList: "7,,8", want: nil, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var cfg seedsConfig
+
+			cfg.count = tt.count
+			cfg.firstSeed = tt.firstSeed
+			cfg.seedList = tt.seedList
+
+			seeds, err := seedsToTry(cfg)
+			if tt.wantErr {
+				require.ErrorIs(t, err, errInvalidFlag)
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, seeds)
+		})
+	}
+}
+
+func TestParseSeedsFlags(t *testing.T) {
+	t.Parallel()
+
+	cfg, seeds, err := parseSeedsFlags([]string{
+		"-voice", "tara", "-seeds", "2", "-first-seed", "10", "-set", "temperature=0.6", "-set", "style=calm",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int{10, 11}, seeds)
+	assert.Equal(t, settingFlags{"temperature": "0.6", "style": "calm"}, cfg.settings)
+
+	_, _, err = parseSeedsFlags([]string{"-voice", "tara", "-set", "temperature"})
+	require.ErrorIs(t, err, errInvalidFlag)
+
+	_, _, err = parseSeedsFlags([]string{"-voice", "tara", "-seed-list", "3,3"})
+	require.ErrorIs(t, err, errInvalidFlag)
+}
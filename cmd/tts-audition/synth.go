@@ -0,0 +1,156 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/book-expert/events"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/natsconn"
+	"github.com/book-expert/tts-service/internal/objectstore"
+	"github.com/book-expert/tts-service/internal/replay"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+var errEmptyAudio = errors.New("reply has no audio")
+
+// synthesizer sends jobs to a running tts-service and downloads their audio.
+type synthesizer struct {
+	natsConnection *nats.Conn
+	store          *objectstore.NatsObjectStore
+	subject        string
+	timeout        time.Duration
+	runID          string
+	jobs           int
+}
+
+// newSynthesizer connects to NATS and opens the object store the worker reads
+// text from and writes audio to.
+func newSynthesizer(url, bucket, subject string, timeout time.Duration) (*synthesizer, error) {
+	natsConnection, err := natsconn.Connect(url, natsconn.Options{
+		Name:      "tts-audition",
+		Auth:      natsconn.Auth{},
+		TLS:       natsconn.TLS{},
+		Reconnect: natsconn.Reconnect{},
+		Health:    nil,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	jetstreamContext, err := natsConnection.JetStream()
+	if err != nil {
+		natsConnection.Close()
+
+		return nil, fmt.Errorf("failed to get JetStream context: %w", err)
+	}
+
+	store, err := objectstore.New(jetstreamContext, bucket)
+	if err != nil {
+		natsConnection.Close()
+
+		return nil, fmt.Errorf("failed to open object store: %w", err)
+	}
+
+	return &synthesizer{
+		natsConnection: natsConnection,
+		store:          store,
+		subject:        subject,
+		timeout:        timeout,
+		runID:          uuid.NewString(),
+		jobs:           0,
+	}, nil
+}
+
+// Close closes the NATS connection.
+func (s *synthesizer) Close() {
+	s.natsConnection.Close()
+}
+
+// synthesize runs one job for text with the job fields in settings, set by
+// their JSON names as with tts-admin replay -set, and returns the worker's
+// reply and the audio.
+func (s *synthesizer) synthesize(
+	parent context.Context,
+	text string,
+	settings map[string]string,
+) (core.AudioChunkEvent, []byte, error) {
+	ctx, cancel := context.WithTimeout(parent, s.timeout)
+	defer cancel()
+
+	s.jobs++
+	textKey := fmt.Sprintf("tts-audition/%s/%d.txt", s.runID, s.jobs)
+
+	err := s.store.Upload(ctx, textKey, []byte(text))
+	if err != nil {
+		return core.AudioChunkEvent{}, nil, fmt.Errorf("failed to upload text: %w", err)
+	}
+
+	event, err := json.Marshal(s.newJob(textKey))
+	if err != nil {
+		return core.AudioChunkEvent{}, nil, fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	event, err = replay.Override(event, settings)
+	if err != nil {
+		return core.AudioChunkEvent{}, nil, fmt.Errorf("invalid settings: %w", err)
+	}
+
+	reply, err := s.natsConnection.RequestWithContext(ctx, s.subject, event)
+	if err != nil {
+		return core.AudioChunkEvent{}, nil, fmt.Errorf("no reply from worker: %w", err)
+	}
+
+	var chunk core.AudioChunkEvent
+
+	err = json.Unmarshal(reply.Data, &chunk)
+	if err != nil {
+		return core.AudioChunkEvent{}, nil, fmt.Errorf("failed to parse reply: %w", err)
+	}
+
+	if chunk.AudioKey == "" {
+		return core.AudioChunkEvent{}, nil, errEmptyAudio
+	}
+
+	audio, err := s.store.Download(ctx, chunk.AudioKey)
+	if err != nil {
+		return core.AudioChunkEvent{}, nil, fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	return chunk, audio, nil
+}
+
+// newJob returns a job for textKey that leaves every setting to the worker.
+func (s *synthesizer) newJob(textKey string) core.JobEvent {
+	return core.JobEvent{
+		TextProcessedEvent: events.TextProcessedEvent{
+			Header: events.EventHeader{
+				Timestamp:  time.Now().UTC(),
+				WorkflowID: "tts-audition-" + s.runID,
+				EventID:    uuid.NewString(),
+				UserID:     "",
+				TenantID:   "",
+			},
+			TextKey:           textKey,
+			PNGKey:            "",
+			PageNumber:        s.jobs,
+			TotalPages:        0,
+			Voice:             "",
+			Seed:              0,
+			NGL:               0,
+			TopP:              0,
+			RepetitionPenalty: 0,
+			Temperature:       0,
+		},
+		Model:          "",
+		Language:       "",
+		Rate:           0,
+		Pitch:          0,
+		Style:          "",
+		TimeoutSeconds: 0,
+	}
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/objectstore"
+	"github.com/book-expert/tts-service/internal/replay"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startService runs a NATS server with a stand-in for tts-service on the
+// "jobs" subject. It answers a job with the voice "silent" without audio,
+// leaves a job with the voice "mute" unanswered, and otherwise stores the text
+// as the job's audio.
+func startService(t *testing.T) connectionFlags {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	natsConnection, err := nats.Connect(natsServer.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(natsConnection.Close)
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	store, err := objectstore.New(jetstreamContext, "audio")
+	require.NoError(t, err)
+
+	_, err = natsConnection.Subscribe("jobs", func(msg *nats.Msg) {
+		var job core.JobEvent
+
+		if json.Unmarshal(msg.Data, &job) != nil || job.Voice == "mute" {
+			return
+		}
+
+		var reply core.AudioChunkEvent
+
+		reply.Header = job.Header
+		reply.PageNumber = job.PageNumber
+
+		if job.Voice != "silent" {
+			text, downloadErr := store.Download(context.Background(), job.TextKey)
+			if downloadErr != nil {
+				return
+			}
+
+			reply.AudioKey = job.TextKey + ".wav"
+			reply.DurationSeconds = 1.5
+
+			if store.Upload(context.Background(), reply.AudioKey, []byte(job.Voice+": "+string(text))) != nil {
+				return
+			}
+		}
+
+		data, marshalErr := json.Marshal(reply)
+		if marshalErr == nil {
+			_ = msg.Respond(data)
+		}
+	})
+	require.NoError(t, err)
+
+	return connectionFlags{url: natsServer.ClientURL(), subject: "jobs", bucket: "audio", timeout: 500 * time.Millisecond}
+}
+
+func TestSynthesizer_Synthesize(t *testing.T) {
+	t.Parallel()
+
+	connection := startService(t)
+
+	synth, err := newSynthesizer(connection.url, connection.bucket, connection.subject, connection.timeout)
+	require.NoError(t, err)
+	t.Cleanup(synth.Close)
+
+	ctx := context.Background()
+
+	chunk, audio, err := synth.synthesize(ctx, "Hello.", map[string]string{"voice": "tara"})
+	require.NoError(t, err)
+	assert.Equal(t, "tara: Hello.", string(audio))
+	assert.InDelta(t, 1.5, chunk.DurationSeconds, 1e-9)
+	assert.Equal(t, "tts-audition-"+synth.runID, chunk.Header.WorkflowID)
+	assert.Equal(t, 1, chunk.PageNumber, "jobs are numbered")
+
+	tests := []struct {
+		name     string
+		settings map[string]string
+		wantErr  error
+	}{
+		{name: "invalid settings", settings: map[string]string{"seed": "many"}, wantErr: replay.ErrInvalidOverride},
+		{name: "reply without audio", settings: map[string]string{"voice": "silent"}, wantErr: errEmptyAudio},
+		{name: "no reply", settings: map[string]string{"voice": "mute"}, wantErr: context.DeadlineExceeded},
+	}
+
+	for _, tt := range tests {
+		_, _, err = synth.synthesize(ctx, "Hello.", tt.settings)
+		require.ErrorIs(t, err, tt.wantErr, tt.name)
+	}
+}
+
+func TestAudition(t *testing.T) {
+	t.Parallel()
+
+	connection := startService(t)
+	out := filepath.Join(t.TempDir(), "run")
+
+	variants := []variant{
+		{name: "tara", settings: map[string]string{"voice": "tara"}},
+		{name: "silent", settings: map[string]string{"voice": "silent"}},
+	}
+
+	texts := filepath.Join(t.TempDir(), "texts.txt")
+	require.NoError(t, os.WriteFile(texts, []byte("One.\n\nTwo."), 0o600))
+
+	require.NoError(t, audition(connection, texts, out, "Test", variants))
+
+	audio, err := os.ReadFile(filepath.Join(out, "tara", "sample-02.wav"))
+	require.NoError(t, err)
+	assert.Equal(t, "tara: Two.", string(audio))
+
+	_, err = os.Stat(filepath.Join(out, "silent"))
+	require.ErrorIs(t, err, os.ErrNotExist, "failed takes write no audio")
+
+	index, err := os.ReadFile(filepath.Join(out, "index.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(index), "[tara/sample-01.wav](tara/sample-01.wav) (1.5 s)")
+	assert.Equal(t, 2, strings.Count(string(index), "failed: "+errEmptyAudio.Error()), "failures are indexed")
+
+	require.NoError(t, runPick([]string{"-out", out, "-variant", "tara", "-note", "clear"}))
+
+	chosen, err := os.ReadFile(filepath.Join(out, chosenFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(chosen), `"note": "clear"`)
+
+	require.ErrorIs(t, runPick([]string{"-out", out, "-variant", "leo"}), errInvalidFlag)
+	require.ErrorIs(t, runPick([]string{"-out", t.TempDir(), "-variant", "tara"}), os.ErrNotExist)
+}
+
+func TestAudition_UnreachableService(t *testing.T) {
+	t.Parallel()
+
+	connection := connectionFlags{url: "nats://127.0.0.1:1", subject: "jobs", bucket: "audio", timeout: time.Second}
+
+	err := audition(connection, "", t.TempDir(), "Test", []variant{{name: "tara", settings: nil}})
+	require.Error(t, err)
+}
@@ -0,0 +1,498 @@
+// Command tts-bench sends synthetic text workloads to a TTS HTTP service or to
+// the tts-service NATS subject and reports latency percentiles, throughput and
+// the failure rate, for capacity planning.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/book-expert/events"
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/natsconn"
+	"github.com/book-expert/tts-service/internal/objectstore"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// Benchmark targets.
+const (
+	targetHTTP = "http"
+	targetNATS = "nats"
+)
+
+var (
+	errUnknownTarget = errors.New("unknown target")
+	errInvalidFlag   = errors.New("invalid flag")
+	errEmptyAudio    = errors.New("reply has no audio")
+)
+
+// benchConfig holds the command-line flags.
+type benchConfig struct {
+	target      string
+	url         string
+	requests    int
+	concurrency int
+	chars       int
+	timeout     time.Duration
+	seed        uint64
+	voice       string
+	model       string
+	language    string
+	subject     string
+	bucket      string
+	jsonOutput  bool
+	version     bool
+}
+
+// synthesizeFunc runs one synthesis request for a workload item.
+type synthesizeFunc func(ctx context.Context, index int, text string) error
+
+// result is the outcome of one request.
+type result struct {
+	latency time.Duration
+	chars   int
+	err     error
+}
+
+// report summarizes a benchmark run.
+type report struct {
+	Target         string  `json:"target"`
+	Requests       int     `json:"requests"`
+	Concurrency    int     `json:"concurrency"`
+	Succeeded      int     `json:"succeeded"`
+	Failed         int     `json:"failed"`
+	FailureRate    float64 `json:"failure_rate"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	RequestsPerSec float64 `json:"requests_per_second"`
+	CharsPerSec    float64 `json:"chars_per_second"`
+	LatencyP50MS   float64 `json:"latency_p50_ms"`
+	LatencyP90MS   float64 `json:"latency_p90_ms"`
+	LatencyP95MS   float64 `json:"latency_p95_ms"`
+	LatencyP99MS   float64 `json:"latency_p99_ms"`
+	LatencyMaxMS   float64 `json:"latency_max_ms"`
+	FirstError     string  `json:"first_error,omitempty"`
+}
+
+func parseFlags(args []string) (benchConfig, error) {
+	var cfg benchConfig
+
+	flags := flag.NewFlagSet("tts-bench", flag.ContinueOnError)
+	flags.StringVar(&cfg.target, "target", targetHTTP, "target to load: http or nats")
+	flags.StringVar(&cfg.url, "url", "http://localhost:8000", "HTTP service base URL, or NATS URL for -target nats")
+	flags.IntVar(&cfg.requests, "requests", 50, "total number of requests")
+	flags.IntVar(&cfg.concurrency, "concurrency", 4, "requests in flight at once")
+	flags.IntVar(&cfg.chars, "chars", 400, "approximate characters of text per request")
+	flags.DurationVar(&cfg.timeout, "timeout", 2*time.Minute, "timeout of one request")
+	flags.Uint64Var(&cfg.seed, "seed", 1, "seed of the synthetic text generator")
+	flags.StringVar(&cfg.voice, "voice", "", "voice of every request")
+	flags.StringVar(&cfg.model, "model", "", "model of every request; empty uses the default")
+	flags.StringVar(&cfg.language, "language", "en", "language code of every request")
+	flags.StringVar(&cfg.subject, "subject", "text.processed", "job subject for -target nats")
+	flags.StringVar(&cfg.bucket, "bucket", "AUDIO_FILES", "object store bucket the worker reads text from, for -target nats")
+	flags.BoolVar(&cfg.jsonOutput, "json", false, "print the report as JSON")
+	flags.BoolVar(&cfg.version, "version", false, "print the build version and exit")
+
+	err := flags.Parse(args)
+	if err != nil {
+		return benchConfig{}, fmt.Errorf("%w: %w", errInvalidFlag, err)
+	}
+
+	if cfg.requests <= 0 || cfg.concurrency <= 0 || cfg.chars <= 0 {
+		return benchConfig{}, fmt.Errorf("%w: -requests, -concurrency and -chars must be positive", errInvalidFlag)
+	}
+
+	return cfg, nil
+}
+
+// words are the vocabulary of the synthetic text.
+var words = strings.Fields(`the a of and to in was he she it that his her with for as on at by
+chapter morning evening river mountain window letter garden quiet ancient silver winter summer
+walked whispered remembered carried opened followed wondered listened waited turned answered
+slowly suddenly gently carefully perhaps always never again together beneath across beyond`)
+
+// syntheticText returns about chars characters of sentence-like text.
+// Every request gets different text, so caches cannot skew the results.
+func syntheticText(rng *rand.Rand, chars int) string {
+	var builder strings.Builder
+
+	sentenceLength := 0
+
+	for builder.Len() < chars {
+		word := words[rng.IntN(len(words))]
+		if sentenceLength == 0 {
+			word = strings.ToUpper(word[:1]) + word[1:]
+		}
+
+		builder.WriteString(word)
+
+		sentenceLength++
+		if sentenceLength >= 8+rng.IntN(10) {
+			builder.WriteString(". ")
+
+			sentenceLength = 0
+		} else {
+			builder.WriteString(" ")
+		}
+	}
+
+	return strings.TrimSpace(builder.String()) + "."
+}
+
+// httpSynthesizer sends requests to a TTS HTTP service.
+func httpSynthesizer(cfg benchConfig) synthesizeFunc {
+	client := tts.NewHTTPClient(cfg.url, cfg.timeout)
+	// A benchmark measures failures instead of backing off from them.
+	client.SetCircuitBreaker(nil)
+
+	return func(ctx context.Context, _ int, text string) error {
+		_, err := client.GenerateSpeech(ctx, tts.Request{
+			Text:           text,
+			Voice:          "",
+			SpeakerRefPath: "",
+			Language:       cfg.language,
+			Model:          cfg.model,
+			Temperature:    0,
+			Seed:           0,
+			Style:          "",
+		})
+
+		return err
+	}
+}
+
+// natsSynthesizer uploads the text to the worker's object store and sends the
+// job as a request on the job subject, waiting for the worker's reply. Failed
+// jobs get no reply and count as timeouts.
+func natsSynthesizer(cfg benchConfig) (synthesizeFunc, func(), error) {
+	natsConnection, err := natsconn.Connect(cfg.url, natsconn.Options{
+		Name:      "tts-bench",
+		Auth:      natsconn.Auth{},
+		TLS:       natsconn.TLS{},
+		Reconnect: natsconn.Reconnect{},
+		Health:    nil,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jetstreamContext, err := natsConnection.JetStream()
+	if err != nil {
+		natsConnection.Close()
+
+		return nil, nil, fmt.Errorf("failed to get JetStream context: %w", err)
+	}
+
+	store, err := objectstore.New(jetstreamContext, cfg.bucket)
+	if err != nil {
+		natsConnection.Close()
+
+		return nil, nil, fmt.Errorf("failed to open object store: %w", err)
+	}
+
+	runID := uuid.NewString()
+
+	synthesize := func(ctx context.Context, index int, text string) error {
+		textKey := fmt.Sprintf("tts-bench/%s/%d.txt", runID, index)
+
+		uploadErr := store.Upload(ctx, textKey, []byte(text))
+		if uploadErr != nil {
+			return fmt.Errorf("failed to upload text: %w", uploadErr)
+		}
+
+		return requestJob(ctx, natsConnection, cfg, runID, index, textKey)
+	}
+
+	return synthesize, natsConnection.Close, nil
+}
+
+// requestJob sends one job event and checks the worker's reply.
+func requestJob(ctx context.Context, natsConnection *nats.Conn, cfg benchConfig, runID string, index int, textKey string) error {
+	event := core.JobEvent{
+		TextProcessedEvent: events.TextProcessedEvent{
+			Header: events.EventHeader{
+				Timestamp:  time.Now().UTC(),
+				WorkflowID: "tts-bench-" + runID,
+				EventID:    uuid.NewString(),
+				UserID:     "",
+				TenantID:   "",
+			},
+			TextKey:           textKey,
+			PNGKey:            "",
+			PageNumber:        index + 1,
+			TotalPages:        cfg.requests,
+			Voice:             cfg.voice,
+			Seed:              0,
+			NGL:               0,
+			TopP:              0,
+			RepetitionPenalty: 0,
+			Temperature:       0,
+		},
+		Model:          cfg.model,
+		Language:       cfg.language,
+		Rate:           0,
+		Pitch:          0,
+		Style:          "",
+		TimeoutSeconds: 0,
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal job: %w", err)
+	}
+
+	reply, err := natsConnection.RequestWithContext(ctx, cfg.subject, data)
+	if err != nil {
+		return fmt.Errorf("no reply from worker: %w", err)
+	}
+
+	var chunk core.AudioChunkEvent
+
+	err = json.Unmarshal(reply.Data, &chunk)
+	if err != nil {
+		return fmt.Errorf("failed to parse reply: %w", err)
+	}
+
+	if chunk.AudioKey == "" {
+		return errEmptyAudio
+	}
+
+	return nil
+}
+
+// runLoad sends cfg.requests requests with cfg.concurrency workers.
+func runLoad(ctx context.Context, cfg benchConfig, synthesize synthesizeFunc) ([]result, time.Duration) {
+	rng := rand.New(rand.NewPCG(cfg.seed, cfg.seed)) // #nosec G404 -- synthetic text, not security
+	texts := make([]string, cfg.requests)
+
+	for i := range texts {
+		texts[i] = syntheticText(rng, cfg.chars)
+	}
+
+	results := make([]result, cfg.requests)
+	indexes := make(chan int)
+
+	var waitGroup sync.WaitGroup
+
+	start := time.Now()
+
+	for range cfg.concurrency {
+		waitGroup.Go(func() {
+			for index := range indexes {
+				requestCtx, cancel := context.WithTimeout(ctx, cfg.timeout)
+				requestStart := time.Now()
+				err := synthesize(requestCtx, index, texts[index])
+				results[index] = result{latency: time.Since(requestStart), chars: len(texts[index]), err: err}
+
+				cancel()
+			}
+		})
+	}
+
+	for index := range cfg.requests {
+		select {
+		case indexes <- index:
+		case <-ctx.Done():
+		}
+
+		if ctx.Err() != nil {
+			// Requests that never started count as failures.
+			for rest := index; rest < cfg.requests; rest++ {
+				results[rest] = result{latency: 0, chars: 0, err: ctx.Err()}
+			}
+
+			break
+		}
+	}
+
+	close(indexes)
+	waitGroup.Wait()
+
+	return results, time.Since(start)
+}
+
+// summarize computes the report of a run. Latency percentiles and throughput
+// count successful requests only.
+func summarize(cfg benchConfig, results []result, elapsed time.Duration) report {
+	summary := report{
+		Target:         cfg.target,
+		Requests:       len(results),
+		Concurrency:    cfg.concurrency,
+		Succeeded:      0,
+		Failed:         0,
+		FailureRate:    0,
+		ElapsedSeconds: elapsed.Seconds(),
+		RequestsPerSec: 0,
+		CharsPerSec:    0,
+		LatencyP50MS:   0,
+		LatencyP90MS:   0,
+		LatencyP95MS:   0,
+		LatencyP99MS:   0,
+		LatencyMaxMS:   0,
+		FirstError:     "",
+	}
+
+	var (
+		latencies []time.Duration
+		chars     int
+	)
+
+	for _, outcome := range results {
+		if outcome.err != nil {
+			summary.Failed++
+
+			if summary.FirstError == "" {
+				summary.FirstError = outcome.err.Error()
+			}
+
+			continue
+		}
+
+		summary.Succeeded++
+		chars += outcome.chars
+		latencies = append(latencies, outcome.latency)
+	}
+
+	if len(results) > 0 {
+		summary.FailureRate = float64(summary.Failed) / float64(len(results))
+	}
+
+	if elapsed > 0 {
+		summary.RequestsPerSec = float64(summary.Succeeded) / elapsed.Seconds()
+		summary.CharsPerSec = float64(chars) / elapsed.Seconds()
+	}
+
+	if len(latencies) == 0 {
+		return summary
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+
+	summary.LatencyP50MS = milliseconds(percentile(latencies, 50))
+	summary.LatencyP90MS = milliseconds(percentile(latencies, 90))
+	summary.LatencyP95MS = milliseconds(percentile(latencies, 95))
+	summary.LatencyP99MS = milliseconds(percentile(latencies, 99))
+	summary.LatencyMaxMS = milliseconds(latencies[len(latencies)-1])
+
+	return summary
+}
+
+// percentile returns the p-th percentile of sorted latencies, interpolated
+// linearly between the two nearest samples. It is zero without samples.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+
+	position := float64(p) / 100 * float64(len(sorted)-1)
+	lower := int(position)
+
+	if lower >= len(sorted)-1 {
+		return sorted[len(sorted)-1]
+	}
+
+	fraction := position - float64(lower)
+
+	return sorted[lower] + time.Duration(math.Round(fraction*float64(sorted[lower+1]-sorted[lower])))
+}
+
+func milliseconds(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
+
+func printReport(out io.Writer, summary report, jsonOutput bool) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+
+		return encoder.Encode(summary)
+	}
+
+	_, err := fmt.Fprintf(out, `target:       %s
+requests:     %d (concurrency %d)
+succeeded:    %d
+failed:       %d (%.1f%%)
+elapsed:      %.2fs
+throughput:   %.2f req/s, %.0f chars/s
+latency (ms): p50 %.0f  p90 %.0f  p95 %.0f  p99 %.0f  max %.0f
+`,
+		summary.Target, summary.Requests, summary.Concurrency, summary.Succeeded,
+		summary.Failed, summary.FailureRate*100, summary.ElapsedSeconds,
+		summary.RequestsPerSec, summary.CharsPerSec,
+		summary.LatencyP50MS, summary.LatencyP90MS, summary.LatencyP95MS, summary.LatencyP99MS, summary.LatencyMaxMS)
+	if err != nil {
+		return fmt.Errorf("failed to print report: %w", err)
+	}
+
+	if summary.FirstError != "" {
+		_, err = fmt.Fprintf(out, "first error:  %s\n", summary.FirstError)
+		if err != nil {
+			return fmt.Errorf("failed to print report: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func run(args []string) error {
+	cfg, err := parseFlags(args)
+	if err != nil {
+		return err
+	}
+
+	if cfg.version {
+		fmt.Fprintln(os.Stdout, "tts-bench "+buildinfo.Get().String())
+
+		return nil
+	}
+
+	var (
+		synthesize synthesizeFunc
+		closeFunc  = func() {}
+	)
+
+	switch cfg.target {
+	case targetHTTP:
+		synthesize = httpSynthesizer(cfg)
+	case targetNATS:
+		synthesize, closeFunc, err = natsSynthesizer(cfg)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: %q", errUnknownTarget, cfg.target)
+	}
+
+	defer closeFunc()
+
+	// Ctrl-C stops sending and reports what has finished.
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	results, elapsed := runLoad(ctx, cfg, synthesize)
+
+	return printReport(os.Stdout, summarize(cfg, results, elapsed), cfg.jsonOutput)
+}
+
+func main() {
+	err := run(os.Args[1:])
+	if err != nil && !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "tts-bench: %v\n", err)
+		os.Exit(1)
+	}
+}
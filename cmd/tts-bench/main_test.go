@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPercentile(t *testing.T) {
+	t.Parallel()
+
+	ms := time.Millisecond
+
+	tests := []struct {
+		name   string
+		sorted []time.Duration
+		p      int
+		want   time.Duration
+	}{
+		{name: "no samples", sorted: nil, p: 50, want: 0},
+		{name: "one sample, median", sorted: []time.Duration{7 * ms}, p: 50, want: 7 * ms},
+		{name: "one sample, p99", sorted: []time.Duration{7 * ms}, p: 99, want: 7 * ms},
+		{name: "minimum", sorted: []time.Duration{10 * ms, 20 * ms, 40 * ms}, p: 0, want: 10 * ms},
+		{name: "maximum", sorted: []time.Duration{10 * ms, 20 * ms, 40 * ms}, p: 100, want: 40 * ms},
+		{name: "on a sample", sorted: []time.Duration{10 * ms, 20 * ms, 40 * ms}, p: 50, want: 20 * ms},
+		{name: "between two samples", sorted: []time.Duration{10 * ms, 20 * ms}, p: 50, want: 15 * ms},
+		{name: "interpolated p90", sorted: []time.Duration{10 * ms, 20 * ms, 40 * ms}, p: 90, want: 36 * ms},
+		{
+			name:   "interpolated p95 of ten",
+			sorted: []time.Duration{1 * ms, 2 * ms, 3 * ms, 4 * ms, 5 * ms, 6 * ms, 7 * ms, 8 * ms, 9 * ms, 20 * ms},
+			p:      95,
+			want:   15050 * time.Microsecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, percentile(tt.sorted, tt.p))
+		})
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	var cfg benchConfig
+
+	cfg.target = "http"
+	cfg.concurrency = 2
+
+	errBusy := errors.New("busy")
+
+	tests := []struct {
+		name    string
+		results []result
+		elapsed time.Duration
+		want    report
+	}{
+		{
+			name:    "no requests",
+			results: nil,
+			elapsed: 0,
+			want: report{
+				Target: "http", Requests: 0, Concurrency: 2, Succeeded: 0, Failed: 0, FailureRate: 0,
+				ElapsedSeconds: 0, RequestsPerSec: 0, CharsPerSec: 0, LatencyP50MS: 0, LatencyP90MS: 0,
+				LatencyP95MS: 0, LatencyP99MS: 0, LatencyMaxMS: 0, FirstError: "",
+			},
+		},
+		{
+			name:    "one request",
+			results: []result{{latency: 250 * time.Millisecond, chars: 100, err: nil}},
+			elapsed: time.Second,
+			want: report{
+				Target: "http", Requests: 1, Concurrency: 2, Succeeded: 1, Failed: 0, FailureRate: 0,
+				ElapsedSeconds: 1, RequestsPerSec: 1, CharsPerSec: 100, LatencyP50MS: 250, LatencyP90MS: 250,
+				LatencyP95MS: 250, LatencyP99MS: 250, LatencyMaxMS: 250, FirstError: "",
+			},
+		},
+		{
+			name: "failures are left out of latencies and throughput",
+			results: []result{
+				{latency: 300 * time.Millisecond, chars: 100, err: nil},
+				{latency: 5 * time.Second, chars: 100, err: errBusy},
+				{latency: 100 * time.Millisecond, chars: 100, err: nil},
+				{latency: time.Millisecond, chars: 100, err: errors.New("closed")},
+			},
+			elapsed: 2 * time.Second,
+			want: report{
+				Target: "http", Requests: 4, Concurrency: 2, Succeeded: 2, Failed: 2, FailureRate: 0.5,
+				ElapsedSeconds: 2, RequestsPerSec: 1, CharsPerSec: 100, LatencyP50MS: 200, LatencyP90MS: 280,
+				LatencyP95MS: 290, LatencyP99MS: 298, LatencyMaxMS: 300, FirstError: "busy",
+			},
+		},
+		{
+			name:    "every request failed",
+			results: []result{{latency: time.Second, chars: 100, err: errBusy}},
+			elapsed: time.Second,
+			want: report{
+				Target: "http", Requests: 1, Concurrency: 2, Succeeded: 0, Failed: 1, FailureRate: 1,
+				ElapsedSeconds: 1, RequestsPerSec: 0, CharsPerSec: 0, LatencyP50MS: 0, LatencyP90MS: 0,
+				LatencyP95MS: 0, LatencyP99MS: 0, LatencyMaxMS: 0, FirstError: "busy",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := summarize(cfg, tt.results, tt.elapsed)
+			assert.InDeltaMapValues(t, reportFields(tt.want), reportFields(got), 1e-9)
+			assert.Equal(t, tt.want.FirstError, got.FirstError)
+		})
+	}
+}
+
+// reportFields returns the numbers of a report by name, to compare them with a
+// tolerance.
+func reportFields(summary report) map[string]float64 {
+	return map[string]float64{
+		"requests":     float64(summary.Requests),
+		"concurrency":  float64(summary.Concurrency),
+		"succeeded":    float64(summary.Succeeded),
+		"failed":       float64(summary.Failed),
+		"failure_rate": summary.FailureRate,
+		"elapsed":      summary.ElapsedSeconds,
+		"requests/s":   summary.RequestsPerSec,
+		"chars/s":      summary.CharsPerSec,
+		"p50":          summary.LatencyP50MS,
+		"p90":          summary.LatencyP90MS,
+		"p95":          summary.LatencyP95MS,
+		"p99":          summary.LatencyP99MS,
+		"max":          summary.LatencyMaxMS,
+	}
+}
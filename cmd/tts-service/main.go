@@ -3,22 +3,72 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"maps"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/assembler"
+	"github.com/book-expert/tts-service/internal/buildinfo"
 	"github.com/book-expert/tts-service/internal/config"
 	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/diff"
+	"github.com/book-expert/tts-service/internal/gpu"
+	"github.com/book-expert/tts-service/internal/jobstatus"
+	"github.com/book-expert/tts-service/internal/load"
+	"github.com/book-expert/tts-service/internal/logging"
+	"github.com/book-expert/tts-service/internal/metrics"
+	"github.com/book-expert/tts-service/internal/models"
+	"github.com/book-expert/tts-service/internal/natsconn"
 	"github.com/book-expert/tts-service/internal/objectstore"
+	"github.com/book-expert/tts-service/internal/quality"
+	"github.com/book-expert/tts-service/internal/safety"
+	"github.com/book-expert/tts-service/internal/scheduler"
 	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/tts/dialogue"
+	"github.com/book-expert/tts-service/internal/tts/multilingual"
+	"github.com/book-expert/tts-service/internal/tts/pause"
+	"github.com/book-expert/tts-service/internal/tts/prosody"
+	"github.com/book-expert/tts-service/internal/tts/style"
 	"github.com/book-expert/tts-service/internal/worker"
 	"github.com/nats-io/nats.go"
 )
 
-func setupLogger(logPath string) (*logger.Logger, error) {
-	log, err := logger.New(logPath, "tts-service.log")
+// Backends that a model registry entry can select.
+const (
+	backendChatLLM = "chatllm"
+	backendPiper   = "piper"
+	backendGoogle  = "google"
+	backendLlama   = "llama"
+	backendHTTP    = "http"
+)
+
+// serviceName identifies the service to the NATS server.
+const serviceName = "tts-service"
+
+// fallbackDefaultModel names the tts_service model in a fallback chain.
+const fallbackDefaultModel = "default"
+
+// mebibyte converts the MiB settings to bytes.
+const mebibyte = 1 << 20
+
+var (
+	errUnknownBackend          = errors.New("unknown model backend")
+	errUnknownFallbackModel    = errors.New("unknown model in fallback chain")
+	errHTTPProviderURLEmpty    = errors.New("providers.http url cannot be empty")
+	errHTTPProviderAPIKeyEmpty = errors.New("providers.http.api api_key_env names an empty variable")
+	errWorkerStopped           = errors.New("worker stopped unexpectedly")
+)
+
+func setupLogger(logDir, logFile string) (*logger.Logger, error) {
+	log, err := logger.New(logDir, logFile)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create logger: %w", err)
 	}
@@ -27,7 +77,7 @@ func setupLogger(logPath string) (*logger.Logger, error) {
 }
 
 func bootstrap() (*config.Config, *logger.Logger, error) {
-	bootstrapLog, err := setupLogger(os.TempDir())
+	bootstrapLog, err := setupLogger(os.TempDir(), config.DefaultLogFile)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "FATAL: Failed to create bootstrap logger: %v\n", err)
 
@@ -48,8 +98,22 @@ func bootstrap() (*config.Config, *logger.Logger, error) {
 	return cfg, bootstrapLog, nil
 }
 
-func startWorker(ctx context.Context, cfg *config.Config, log *logger.Logger) (context.CancelFunc, error) {
-	natsConnection, err := nats.Connect(cfg.NATS.URL)
+// runningWorker is a started worker. stopped is closed when the worker stops
+// without being cancelled, for example when the NATS connection is lost for good.
+type runningWorker struct {
+	cancel  context.CancelFunc
+	stopped <-chan struct{}
+	reloads *reloader
+	// models frees the models loaded in-process; nil when there are none.
+	models io.Closer
+}
+
+// startWorker connects to NATS and starts the worker.
+func startWorker(ctx context.Context, cfg *config.Config, log *logger.Logger) (*runningWorker, error) {
+	registry := metrics.NewRegistry()
+	health := natsconn.NewHealth(registry, log)
+
+	natsConnection, err := natsconn.Connect(cfg.NATS.URL, natsOptions(cfg, health))
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
@@ -61,39 +125,262 @@ func startWorker(ctx context.Context, cfg *config.Config, log *logger.Logger) (c
 		return nil, fmt.Errorf("failed to get JetStream context: %w", err)
 	}
 
-	store, err := objectstore.New(jetstreamContext, cfg.NATS.AudioObjectStoreBucket)
+	store, err := newObjectStore(jetstreamContext, cfg)
 	if err != nil {
 		natsConnection.Close()
 
-		return nil, fmt.Errorf("failed to create object store: %w", err)
+		return nil, err
 	}
 
-	processor, err := tts.New(core.TTSConfig{
-		ModelPath:         cfg.TTS.ModelPath,
-		SnacModelPath:     cfg.TTS.SnacModelPath,
-		Voice:             cfg.TTS.Voice,
-		Seed:              cfg.TTS.Seed,
-		NGL:               cfg.TTS.NGL,
-		TopP:              cfg.TTS.TopP,
-		RepetitionPenalty: cfg.TTS.RepetitionPenalty,
-		Temperature:       cfg.TTS.Temperature,
-	}, log)
+	if cfg.Encryption.Enabled {
+		log.System("Objects are encrypted at rest with key '%s'.", cfg.Encryption.KeyID)
+	}
+
+	removed, err := workspace(cfg).Prepare()
 	if err != nil {
 		natsConnection.Close()
 
-		return nil, fmt.Errorf("failed to create TTS processor: %w", err)
+		return nil, fmt.Errorf("failed to prepare work directory: %w", err)
+	}
+
+	if removed > 0 {
+		log.Info("Removed %d job directories left by a previous run.", removed)
+	}
+
+	workerCtx, workerCancel := context.WithCancel(ctx)
+
+	processor, modelResolver, err := newProcessor(workerCtx, natsConnection, cfg, registry, log)
+	if err != nil {
+		workerCancel()
+		natsConnection.Close()
+
+		return nil, err
+	}
+
+	// Each language run goes straight to the router, inside styles and casting.
+	languages, ok := modelResolver.(core.LanguageResolver)
+	if cfg.Multilingual.Enabled && ok {
+		processor = multilingual.NewProcessor(processor, languages, cfg.Multilingual.Scripts)
+
+		log.System("Mixed-language synthesis enabled for %d scripts.", len(cfg.Multilingual.Scripts))
+	}
+
+	// Styles are applied per cast voice, so casting wraps them.
+	processor = style.NewProcessor(processor, styles(cfg.Styles))
+
+	// Prosody hints select styles per sentence, so they wrap styles too.
+	hints := prosody.Hints{
+		Question:    cfg.Prosody.Question,
+		Exclamation: cfg.Prosody.Exclamation,
+		Aside:       cfg.Prosody.Aside,
+		Emphasis:    cfg.Prosody.Emphasis,
+	}
+	if hints != (prosody.Hints{}) {
+		processor = prosody.NewProcessor(processor, hints)
+
+		log.System("Prosody hints from punctuation enabled.")
+	}
+
+	// Casting runs before post-processing, so every voice is normalized alike.
+	if cfg.Casting.Dialogue != "" || len(cfg.Casting.Characters) > 0 {
+		processor = dialogue.NewProcessor(processor, dialogue.Cast{
+			Narrator:   cfg.Casting.Narrator,
+			Dialogue:   cfg.Casting.Dialogue,
+			Characters: cfg.Casting.Characters,
+		})
+
+		log.System("Dialogue casting enabled with %d character voices.", len(cfg.Casting.Characters))
+	}
+
+	// Pacing splits the text before casting, so each sentence is cast on its own.
+	if cfg.Pauses.Enabled {
+		processor = pause.NewProcessor(processor, pause.Rules{
+			Sentence:  seconds(cfg.Pauses.SentenceSeconds),
+			Paragraph: seconds(cfg.Pauses.ParagraphSeconds),
+			Chapter:   seconds(cfg.Pauses.ChapterSeconds),
+		})
+
+		log.System("Pauses between sentences, paragraphs and chapters enabled.")
+	}
+
+	// Post-processing also applies each job's rate and pitch, so it is always installed.
+	processor = audio.NewProcessor(processor, audio.Options{
+		SampleRate: cfg.Audio.SampleRate,
+		Channels:   cfg.Audio.Channels,
+		TargetLUFS: cfg.Audio.TargetLUFS,
+		TruePeakDB: cfg.Audio.TruePeakDB,
+	})
+
+	workerOpts := worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      cfg.NATS.JobStatusSubject,
+		VersionSubject:     cfg.NATS.VersionSubject,
+		DocumentSubject:    cfg.NATS.DocumentSubject,
+		Models:             modelResolver,
+		JobTimeout:         cfg.JobTimeout(),
+		MaxJobTimeout:      time.Duration(cfg.TTS.MaxTimeoutSeconds) * time.Second,
+		FailureSubject:     cfg.NATS.JobFailedSubject,
+		Defaults:           jobDefaults(cfg),
+		DryRun:             nil,
+		MaxTextChars:       cfg.TTS.MaxTextChars,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  cfg.TTS.MaxConcurrentJobs,
+		MaxPendingMessages: cfg.TTS.MaxPendingMessages,
+		StreamSubject:      cfg.NATS.TextStreamSubject,
+		StreamAudioSubject: cfg.NATS.StreamAudioSubject,
+		StreamIdleTimeout:  time.Duration(cfg.TTS.StreamIdleSeconds) * time.Second,
+	}
+
+	if cfg.DryRun.Enabled {
+		workerOpts.DryRun = &worker.Estimator{
+			CharsPerSecond: cfg.DryRun.CharsPerSecond,
+			RealTimeFactor: cfg.DryRun.RealTimeFactor,
+		}
+
+		log.System("Dry-run mode: jobs are estimated, not synthesized.")
+	}
+
+	if cfg.TextReport.Enabled {
+		// Durations are estimated at the dry-run pace.
+		workerOpts.TextReport = &worker.TextReport{
+			Estimator: worker.Estimator{
+				CharsPerSecond: cfg.DryRun.CharsPerSecond,
+				RealTimeFactor: cfg.DryRun.RealTimeFactor,
+			},
+			MaxSentenceWords:   cfg.TextReport.MaxSentenceWords,
+			SplitLongSentences: cfg.TextReport.SplitLongSentences,
+		}
+	}
+
+	if cfg.Safety.DefaultPolicy != "" || len(cfg.Safety.Tenants) > 0 {
+		filter, filterErr := newSafetyFilter(cfg)
+		if filterErr != nil {
+			workerCancel()
+			natsConnection.Close()
+
+			return nil, fmt.Errorf("failed to create content safety filter: %w", filterErr)
+		}
+
+		workerOpts.Filter = filter
+
+		log.System("Content safety enabled: default policy '%s', %d tenant policies.",
+			cfg.Safety.DefaultPolicy, len(cfg.Safety.Tenants))
+	}
+
+	if cfg.PII.Enabled {
+		redactor, redactErr := safety.NewRedactor(cfg.PII.Categories, cfg.PII.Replacement)
+		if redactErr != nil {
+			workerCancel()
+			natsConnection.Close()
+
+			return nil, fmt.Errorf("failed to create PII redactor: %w", redactErr)
+		}
+
+		// Personal data is masked first, so it never reaches a moderation API.
+		if workerOpts.Filter == nil {
+			workerOpts.Filter = redactor
+		} else {
+			workerOpts.Filter = safety.Chain{redactor, workerOpts.Filter}
+		}
+
+		log.System("PII redaction enabled.")
+	}
+
+	if cfg.Load.Enabled() {
+		monitor := newLoadMonitor(cfg, registry, log)
+		workerOpts.Load = monitor
+
+		go monitor.Run(workerCtx)
+
+		log.System("Backpressure enabled: job intake pauses above the [load] limits.")
+	}
+
+	if cfg.Quality.Enabled {
+		workerOpts.Quality = &quality.Gate{
+			SilenceDB:         cfg.Quality.SilenceDB,
+			MaxClippedRatio:   cfg.Quality.MaxClippedRatio,
+			MinCharsPerSecond: cfg.Quality.MinCharsPerSecond,
+			MaxCharsPerSecond: cfg.Quality.MaxCharsPerSecond,
+		}
+		workerOpts.Retry = worker.RetryPolicy{
+			Attempts:        cfg.Quality.MaxAttempts,
+			TemperatureStep: cfg.Quality.TemperatureStep,
+		}
+
+		log.System("Quality gate enabled: rejected chunks are synthesized again with the next seed.")
+	}
+
+	if cfg.NATS.JobStatusBucket != "" {
+		statusStore, statusErr := jobstatus.New(jetstreamContext, cfg.NATS.JobStatusBucket)
+		if statusErr != nil {
+			workerCancel()
+			natsConnection.Close()
+
+			return nil, fmt.Errorf("failed to create job status store: %w", statusErr)
+		}
+
+		workerOpts.StatusStore = statusStore
+	}
+
+	if cfg.NATS.ManifestBucket != "" {
+		manifestStore, manifestErr := diff.New(jetstreamContext, cfg.NATS.ManifestBucket)
+		if manifestErr != nil {
+			workerCancel()
+			natsConnection.Close()
+
+			return nil, fmt.Errorf("failed to create document manifest store: %w", manifestErr)
+		}
+
+		workerOpts.Manifests = manifestStore
 	}
 
 	natsWorker, err := worker.NewNatsWorker(
-		natsConnection, jetstreamContext, cfg.NATS.TextProcessedSubject, store, processor, log,
+		natsConnection, jetstreamContext, cfg.NATS.TextProcessedSubject, store, processor, log, workerOpts,
 	)
 	if err != nil {
+		workerCancel()
 		natsConnection.Close()
 
 		return nil, fmt.Errorf("failed to create NATS worker: %w", err)
 	}
 
-	workerCtx, workerCancel := context.WithCancel(ctx)
+	if cfg.TTS.SelfTest && !cfg.DryRun.Enabled {
+		err = selfTest(workerCtx, natsWorker, cfg, log)
+		if err != nil {
+			workerCancel()
+			natsConnection.Close()
+
+			return nil, err
+		}
+	}
+
+	reloads := newReloader(cfg, natsWorker, log)
+	if cfg.NATS.ReloadSubject != "" {
+		go reloads.serve(workerCtx, natsConnection, cfg.NATS.ReloadSubject)
+	}
+
+	startMetrics(workerCtx, natsConnection, cfg, registry, log)
+
+	err = startScheduler(workerCtx, natsConnection, jetstreamContext, cfg, log)
+	if err != nil {
+		workerCancel()
+		natsConnection.Close()
+
+		return nil, err
+	}
+
+	err = startAssembler(workerCtx, natsConnection, jetstreamContext, store, cfg, log)
+	if err != nil {
+		workerCancel()
+		natsConnection.Close()
+
+		return nil, err
+	}
 
 	go func() {
 		defer natsConnection.Close()
@@ -105,25 +392,919 @@ func startWorker(ctx context.Context, cfg *config.Config, log *logger.Logger) (c
 		}
 	}()
 
+	go func() {
+		select {
+		case <-health.Closed():
+			log.Error("NATS connection closed after %d reconnect attempts, stopping the worker", cfg.NATS.MaxReconnects)
+			workerCancel()
+		case <-workerCtx.Done():
+		}
+	}()
+
 	log.System("TTS-Service successfully initialized. Listening for jobs on subject: %s", cfg.NATS.TextProcessedSubject)
 
-	return workerCancel, nil
+	models, _ := modelResolver.(io.Closer)
+
+	return &runningWorker{cancel: workerCancel, stopped: workerCtx.Done(), reloads: reloads, models: models}, nil
+}
+
+// selfTest synthesizes a short text before the worker subscribes to jobs, so a
+// misconfigured model or backend stops the service at startup.
+func selfTest(ctx context.Context, natsWorker *worker.NatsWorker, cfg *config.Config, log *logger.Logger) error {
+	testCtx, cancel := context.WithTimeout(ctx, cfg.JobTimeout())
+	defer cancel()
+
+	started := time.Now()
+
+	info, err := natsWorker.SelfTest(testCtx)
+	if err != nil {
+		return fmt.Errorf("startup self-test: %w", err)
+	}
+
+	log.System("Self-test passed: %s of %d Hz audio in %s.",
+		info.Duration().Round(time.Millisecond), info.SampleRate, time.Since(started).Round(time.Millisecond))
+
+	return nil
+}
+
+// natsOptions maps the [nats] authentication, TLS and reconnect settings to
+// connection options. health tracks the connection across broker outages.
+func natsOptions(cfg *config.Config, health *natsconn.Health) natsconn.Options {
+	auth := cfg.NATS.Auth
+	tls := cfg.NATS.TLS
+
+	return natsconn.Options{
+		Name: serviceName,
+		Auth: natsconn.Auth{
+			User:            auth.User,
+			PasswordEnv:     auth.PasswordEnv,
+			PasswordFile:    auth.PasswordFile,
+			TokenEnv:        auth.TokenEnv,
+			TokenFile:       auth.TokenFile,
+			CredentialsFile: auth.CredentialsFile,
+			NKeySeedFile:    auth.NKeySeedFile,
+		},
+		TLS: natsconn.TLS{
+			CAFile:   tls.CAFile,
+			CertFile: tls.CertFile,
+			KeyFile:  tls.KeyFile,
+		},
+		Reconnect: natsconn.Reconnect{
+			MaxAttempts: cfg.NATS.MaxReconnects,
+			Wait:        time.Duration(cfg.NATS.ReconnectWaitSeconds) * time.Second,
+			Jitter:      time.Duration(cfg.NATS.ReconnectJitterSeconds) * time.Second,
+		},
+		Health: health,
+	}
+}
+
+// styles converts the configured speaking styles.
+func styles(configured map[string]config.StyleConfig) map[string]style.Style {
+	converted := make(map[string]style.Style, len(configured))
+	for name, styleCfg := range configured {
+		converted[name] = style.Style{
+			Prefix:      styleCfg.Prefix,
+			Temperature: styleCfg.Temperature,
+			TopP:        styleCfg.TopP,
+			Voices:      styleCfg.Voices,
+		}
+	}
+
+	return converted
+}
+
+// seconds converts a configured number of seconds.
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
+
+// newProcessor creates the chatllm processor, routed between the registered
+// models. With auto_ngl, every chatllm process runs in a GPU slot. The returned
+// resolver is nil when no model registry is configured.
+func newProcessor(
+	ctx context.Context,
+	natsConnection *nats.Conn,
+	cfg *config.Config,
+	registry *metrics.Registry,
+	log *logger.Logger,
+) (core.TTSProcessor, core.ModelResolver, error) {
+	modelManager, modelPath, snacModelPath, err := resolveModels(ctx, cfg, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	gpuManager, err := newGPUManager(ctx, cfg, registry, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defaultProcessor, err := newChatLLMProcessor(cfg, modelPath, snacModelPath, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	startModelControl(ctx, natsConnection, cfg, modelManager, defaultProcessor, log)
+
+	processor, err := withGPU(gpuManager, defaultProcessor, cfg.GPU.ModelLayers)
+	if err != nil {
+		return nil, nil, fmt.Errorf("model '%s': %w", fallbackDefaultModel, err)
+	}
+
+	// Language routing needs the router even without registry models.
+	if len(cfg.Models.Registry) == 0 && len(cfg.Languages) == 0 && len(cfg.TTS.Languages) == 0 &&
+		!cfg.Multilingual.Enabled {
+		return processor, nil, nil
+	}
+
+	router, err := newRouter(ctx, cfg, modelManager, gpuManager, processor, log)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return router, router, nil
+}
+
+// newGPUManager detects GPUs when auto_ngl is enabled. It returns nil otherwise.
+func newGPUManager(
+	ctx context.Context,
+	cfg *config.Config,
+	registry *metrics.Registry,
+	log *logger.Logger,
+) (*gpu.Manager, error) {
+	if !cfg.GPU.AutoNGL {
+		return nil, nil
+	}
+
+	manager, err := gpu.NewManager(ctx, gpu.Config{
+		MaxJobsPerGPU:   cfg.GPU.MaxJobsPerGPU,
+		VRAMFraction:    cfg.GPU.VRAMFraction,
+		ReserveMiB:      cfg.GPU.ReserveMiB,
+		RefreshInterval: time.Duration(cfg.GPU.RefreshSeconds) * time.Second,
+	}, gpu.ExecRunner, registry, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GPU manager: %w", err)
+	}
+
+	go manager.Run(ctx)
+
+	return manager, nil
+}
+
+// withGPU wraps a chatllm processor for GPU-aware scheduling. Without a GPU
+// manager the processor is returned unchanged.
+func withGPU(manager *gpu.Manager, processor core.TTSProcessor, modelLayers int) (core.TTSProcessor, error) {
+	if manager == nil {
+		return processor, nil
+	}
+
+	wrapped, err := gpu.NewProcessor(processor, manager, modelLayers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enable GPU scheduling: %w", err)
+	}
+
+	return wrapped, nil
+}
+
+// newRouter registers every model in the registry next to the default model.
+func newRouter(
+	ctx context.Context,
+	cfg *config.Config,
+	modelManager *models.Manager,
+	gpuManager *gpu.Manager,
+	defaultProcessor core.TTSProcessor,
+	log *logger.Logger,
+) (*tts.Router, error) {
+	routes := make(map[string]tts.Route, len(cfg.Models.Registry))
+
+	for name, entry := range cfg.Models.Registry {
+		processor, err := newRegistryProcessor(ctx, cfg, modelManager, gpuManager, entry, log)
+		if err != nil {
+			return nil, fmt.Errorf("model '%s': %w", name, err)
+		}
+
+		routes[name] = tts.Route{
+			Processor:    processor,
+			DefaultVoice: entry.DefaultVoice,
+			Languages:    entry.Languages,
+		}
+	}
+
+	var processor core.TTSProcessor = defaultProcessor
+
+	if len(cfg.Fallback.Chain) > 0 {
+		fallback, err := newFallback(cfg, defaultProcessor, routes)
+		if err != nil {
+			return nil, err
+		}
+
+		processor = fallback
+	}
+
+	languages := make(map[string]tts.LanguageRoute, len(cfg.Languages))
+	for code, language := range cfg.Languages {
+		languages[code] = tts.LanguageRoute{Model: language.Model, Voice: language.Voice}
+	}
+
+	return tts.NewRouter(tts.Route{
+		Processor:    processor,
+		DefaultVoice: cfg.TTS.Voice,
+		Languages:    cfg.TTS.Languages,
+	}, routes, languages), nil
+}
+
+// newObjectStore opens the object store of text and audio, with compression
+// and envelope encryption when they are enabled.
+func newObjectStore(jetstreamContext nats.JetStreamContext, cfg *config.Config) (core.ObjectStore, error) {
+	store, err := objectstore.New(jetstreamContext, cfg.NATS.AudioObjectStoreBucket)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create object store: %w", err)
+	}
+
+	compression := objectstore.Compression{Encoding: cfg.Compression.Encoding, MinBytes: cfg.Compression.MinBytes}
+
+	if !cfg.Encryption.Enabled {
+		err = store.SetCompression(compression)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set object compression: %w", err)
+		}
+
+		return store, nil
+	}
+
+	keys, err := cfg.Encryption.KeyMap()
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := objectstore.NewStaticKeys(cfg.Encryption.KeyID, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load encryption keys: %w", err)
+	}
+
+	// Encrypted objects do not compress, so the encrypted store compresses
+	// before it encrypts.
+	encrypted := objectstore.NewEncrypted(store, provider, cfg.Encryption.AllowPlaintext)
+
+	err = encrypted.SetCompression(compression)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set object compression: %w", err)
+	}
+
+	return encrypted, nil
+}
+
+// newLoadMonitor watches the host against the [load] limits. CPU and memory
+// are read from /proc; GPU utilization, the busiest device's, from the vendor
+// tooling when max_gpu is set.
+func newLoadMonitor(cfg *config.Config, registry *metrics.Registry, log *logger.Logger) *load.Monitor {
+	sources := load.Sources{CPU: nil, Memory: nil, GPU: nil}
+
+	if cfg.Load.MaxCPU > 0 {
+		sources.CPU = load.ProcCPU()
+	}
+
+	if cfg.Load.MaxMemory > 0 {
+		sources.Memory = load.ProcMemory
+	}
+
+	if cfg.Load.MaxGPU > 0 {
+		sources.GPU = func(ctx context.Context) (float64, error) {
+			devices, err := gpu.Detect(ctx, gpu.ExecRunner)
+			if err != nil {
+				return 0, err
+			}
+
+			busiest := 0.0
+			for _, device := range devices {
+				busiest = max(busiest, device.UtilizationPercent/100)
+			}
+
+			return busiest, nil
+		}
+	}
+
+	return load.NewMonitor(load.Thresholds{
+		CPU:    cfg.Load.MaxCPU,
+		Memory: cfg.Load.MaxMemory,
+		GPU:    cfg.Load.MaxGPU,
+		Queue:  cfg.Load.MaxQueuedJobs,
+	}, sources, seconds(cfg.Load.IntervalSeconds), registry, log)
+}
+
+// newSafetyFilter builds the content safety policy of every tenant: a
+// deny-list, followed by the moderation API when the policy names one.
+func newSafetyFilter(cfg *config.Config) (safety.Policies, error) {
+	filters := make(map[string]core.TextFilter, len(cfg.Safety.Policies))
+
+	for name, policy := range cfg.Safety.Policies {
+		denyList, err := safety.NewDenyList(policy.Deny, policy.Patterns, policy.Action == "redact", policy.Replacement)
+		if err != nil {
+			return safety.Policies{}, fmt.Errorf("policy '%s': %w", name, err)
+		}
+
+		chain := safety.Chain{denyList}
+		if policy.ModerationURL != "" {
+			chain = append(chain, safety.NewModeration(
+				policy.ModerationURL, time.Duration(policy.ModerationTimeoutSeconds)*time.Second,
+			))
+		}
+
+		filters[name] = chain
+	}
+
+	policies := safety.Policies{
+		Default: filters[cfg.Safety.DefaultPolicy],
+		Tenants: make(map[string]core.TextFilter, len(cfg.Safety.Tenants)),
+	}
+
+	for tenant, name := range cfg.Safety.Tenants {
+		policies.Tenants[tenant] = filters[name]
+	}
+
+	return policies, nil
+}
+
+// newFallback chains the configured models, each with its own timeout and circuit breaker.
+func newFallback(
+	cfg *config.Config,
+	defaultProcessor core.TTSProcessor,
+	routes map[string]tts.Route,
+) (*tts.FallbackProcessor, error) {
+	stages := make([]tts.FallbackStage, 0, len(cfg.Fallback.Chain))
+
+	for _, name := range cfg.Fallback.Chain {
+		processor := defaultProcessor
+
+		if name != fallbackDefaultModel {
+			route, ok := routes[name]
+			if !ok {
+				return nil, fmt.Errorf("%w: '%s'", errUnknownFallbackModel, name)
+			}
+
+			processor = route.Processor
+		}
+
+		stages = append(stages, tts.FallbackStage{
+			Name:      name,
+			Processor: processor,
+			Timeout:   time.Duration(cfg.Fallback.TimeoutSeconds) * time.Second,
+			Breaker: tts.NewCircuitBreaker(
+				cfg.Fallback.FailureThreshold,
+				time.Duration(cfg.Fallback.CooldownSeconds)*time.Second,
+			),
+		})
+	}
+
+	return tts.NewFallback(stages), nil
+}
+
+// newRegistryProcessor creates the processor for one registry entry, resolving
+// its model paths through the model catalog when one is configured. Chatllm
+// entries use their own model_layers for GPU scheduling, or [gpu] model_layers.
+func newRegistryProcessor(
+	ctx context.Context,
+	cfg *config.Config,
+	modelManager *models.Manager,
+	gpuManager *gpu.Manager,
+	entry config.ModelEntry,
+	log *logger.Logger,
+) (core.TTSProcessor, error) {
+	switch entry.Backend {
+	case backendGoogle:
+		return newGoogleProcessor(cfg)
+	case backendHTTP:
+		return newHTTPProcessor(cfg)
+	}
+
+	modelPath, err := resolveModelPath(ctx, modelManager, entry.ModelPath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch entry.Backend {
+	case "", backendChatLLM:
+		snacModelPath, resolveErr := resolveModelPath(ctx, modelManager, entry.SnacModelPath)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+
+		processor, chatllmErr := newChatLLMProcessor(cfg, modelPath, snacModelPath, log)
+		if chatllmErr != nil {
+			return nil, chatllmErr
+		}
+
+		modelLayers := entry.ModelLayers
+		if modelLayers <= 0 {
+			modelLayers = cfg.GPU.ModelLayers
+		}
+
+		return withGPU(gpuManager, processor, modelLayers)
+	case backendPiper:
+		command := cfg.Providers.Piper.Command
+		if command == "" {
+			command = tts.DefaultPiperCommand
+		}
+
+		processor, piperErr := tts.NewPiper(core.TTSConfig{
+			Model:             "",
+			ModelPath:         modelPath,
+			SnacModelPath:     "",
+			Voice:             cfg.TTS.Voice,
+			Seed:              0,
+			NGL:               0,
+			TopP:              0,
+			RepetitionPenalty: 0,
+			Temperature:       0,
+			Device:            "",
+			Language:          "",
+			Rate:              0,
+			Pitch:             0,
+			Style:             "",
+		}, command, log)
+		if piperErr != nil {
+			return nil, fmt.Errorf("failed to create piper processor: %w", piperErr)
+		}
+
+		processor.SetWorkspace(workspace(cfg))
+
+		return processor, nil
+	case backendLlama:
+		snacModelPath, resolveErr := resolveModelPath(ctx, modelManager, entry.SnacModelPath)
+		if resolveErr != nil {
+			return nil, resolveErr
+		}
+
+		return newLlamaProcessor(cfg, modelPath, snacModelPath, log)
+	default:
+		return nil, fmt.Errorf("%w: '%s'", errUnknownBackend, entry.Backend)
+	}
+}
+
+// newGoogleProcessor creates the Google Cloud Text-to-Speech adapter from [providers.google].
+func newGoogleProcessor(cfg *config.Config) (core.TTSProcessor, error) {
+	provider := cfg.Providers.Google
+
+	processor, err := tts.NewGoogle(tts.GoogleConfig{
+		Endpoint:     provider.Endpoint,
+		APIKey:       os.Getenv(provider.APIKeyEnv),
+		LanguageCode: provider.LanguageCode,
+		Voices:       provider.Voices,
+		Timeout:      time.Duration(provider.TimeoutSeconds) * time.Second,
+	}, core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             cfg.TTS.Voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Google TTS processor (api_key_env '%s'): %w", provider.APIKeyEnv, err)
+	}
+
+	return processor, nil
 }
 
-func waitForShutdownSignal(log *logger.Logger) {
+// newLlamaProcessor loads an Orpheus model in-process with [tts_service] ngl
+// layers on the GPU. It fails unless the binary was built with -tags llamacpp.
+func newLlamaProcessor(
+	cfg *config.Config,
+	modelPath string,
+	snacModelPath string,
+	log *logger.Logger,
+) (core.TTSProcessor, error) {
+	provider := cfg.Providers.Llama
+
+	processor, err := tts.NewLlama(core.TTSConfig{
+		Model:             "",
+		ModelPath:         modelPath,
+		SnacModelPath:     snacModelPath,
+		Voice:             cfg.TTS.Voice,
+		Seed:              cfg.TTS.Seed,
+		NGL:               cfg.TTS.NGL,
+		TopP:              cfg.TTS.TopP,
+		RepetitionPenalty: cfg.TTS.RepetitionPenalty,
+		Temperature:       cfg.TTS.Temperature,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}, tts.LlamaOptions{
+		ContextSize: provider.ContextSize,
+		MaxTokens:   provider.MaxTokens,
+		Threads:     provider.Threads,
+	}, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create llama.cpp processor: %w", err)
+	}
+
+	return processor, nil
+}
+
+// newHTTPProcessor creates the client for the [providers.http] service, with its
+// request rate and concurrency limits.
+func newHTTPProcessor(cfg *config.Config) (core.TTSProcessor, error) {
+	provider := cfg.Providers.HTTP
+	if provider.URL == "" {
+		return nil, errHTTPProviderURLEmpty
+	}
+
+	client := tts.NewHTTPClient(provider.URL, time.Duration(provider.TimeoutSeconds)*time.Second)
+
+	err := client.SetTransport(tts.TransportOptions{
+		MaxIdleConnsPerHost: provider.MaxIdleConnsPerHost,
+		IdleConnTimeout:     time.Duration(provider.IdleConnTimeoutSeconds) * time.Second,
+		KeepAlive:           time.Duration(provider.KeepAliveSeconds) * time.Second,
+		HTTP2:               provider.HTTP2,
+		TLS: tts.TLSOptions{
+			CAFile:     provider.TLS.CAFile,
+			CertFile:   provider.TLS.CertFile,
+			KeyFile:    provider.TLS.KeyFile,
+			ServerName: provider.TLS.ServerName,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the HTTP provider connection: %w", err)
+	}
+
+	api, err := httpAPI(provider.API)
+	if err != nil {
+		return nil, err
+	}
+
+	err = client.SetAPI(api)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure the HTTP provider API: %w", err)
+	}
+
+	client.SetRateLimit(provider.RequestsPerSecond, provider.MaxConcurrent)
+	client.SetMaxTextLength(provider.MaxTextChars)
+
+	if provider.AdaptiveConcurrency {
+		client.SetAdaptiveConcurrency(provider.MinConcurrent, provider.MaxConcurrent)
+	}
+
+	return tts.NewHTTPProcessor(client, provider.Model, core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             cfg.TTS.Voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       cfg.TTS.Temperature,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}), nil
+}
+
+// httpAPI builds the API of [providers.http.api]: the settings override the
+// built-in profile where set, and the API key from api_key_env is sent in
+// api_key_header, or as a bearer token.
+func httpAPI(settings config.HTTPAPIConfig) (tts.API, error) {
+	api, err := tts.Profile(settings.Profile)
+	if err != nil {
+		return tts.API{}, fmt.Errorf("failed to configure the HTTP provider API: %w", err)
+	}
+
+	if settings.Method != "" {
+		api.Method = settings.Method
+	}
+
+	if settings.SpeechPath != "" {
+		api.SpeechPath = settings.SpeechPath
+	}
+
+	if settings.AudioField != "" {
+		api.AudioField = settings.AudioField
+	}
+
+	if settings.ErrorDetailField != "" {
+		api.ErrorDetailField = settings.ErrorDetailField
+	}
+
+	if settings.ErrorCodeField != "" {
+		api.ErrorCodeField = settings.ErrorCodeField
+	}
+
+	api.Fields = maps.Clone(api.Fields)
+	for field, name := range settings.Fields {
+		if name == "" {
+			delete(api.Fields, field)
+		} else {
+			api.Fields[field] = name
+		}
+	}
+
+	api.Extra = mergeStrings(api.Extra, settings.ExtraFields)
+	api.Headers = mergeStrings(api.Headers, settings.Headers)
+
+	if settings.APIKeyEnv != "" {
+		key := os.Getenv(settings.APIKeyEnv)
+		if key == "" {
+			return tts.API{}, fmt.Errorf("%w: %s", errHTTPProviderAPIKeyEmpty, settings.APIKeyEnv)
+		}
+
+		if settings.APIKeyHeader != "" {
+			api.Headers[settings.APIKeyHeader] = key
+		} else {
+			api.Headers["Authorization"] = "Bearer " + key
+		}
+	}
+
+	return api, nil
+}
+
+// mergeStrings returns the entries of base with those of overrides on top.
+func mergeStrings(base, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(base)+len(overrides))
+	maps.Copy(merged, base)
+	maps.Copy(merged, overrides)
+
+	return merged
+}
+
+// resolveModelPath resolves a model name through the model catalog. Without a
+// catalog the path is used as given.
+func resolveModelPath(ctx context.Context, modelManager *models.Manager, nameOrPath string) (string, error) {
+	if modelManager == nil {
+		return nameOrPath, nil
+	}
+
+	path, err := modelManager.Resolve(ctx, nameOrPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve model '%s': %w", nameOrPath, err)
+	}
+
+	return path, nil
+}
+
+// newChatLLMProcessor creates a chatllm processor for one model.
+func newChatLLMProcessor(
+	cfg *config.Config,
+	modelPath string,
+	snacModelPath string,
+	log *logger.Logger,
+) (*tts.ChatLLMProcessor, error) {
+	processor, err := tts.New(core.TTSConfig{
+		Model:             "",
+		ModelPath:         modelPath,
+		SnacModelPath:     snacModelPath,
+		Voice:             cfg.TTS.Voice,
+		Seed:              cfg.TTS.Seed,
+		NGL:               cfg.TTS.NGL,
+		TopP:              cfg.TTS.TopP,
+		RepetitionPenalty: cfg.TTS.RepetitionPenalty,
+		Temperature:       cfg.TTS.Temperature,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TTS processor: %w", err)
+	}
+
+	processor.SetWorkspace(workspace(cfg))
+	processor.SetOutputLogging(cfg.TTS.LogChatLLMOutput)
+	processor.SetResourceLimits(tts.ResourceLimits{
+		Nice:           cfg.TTS.Nice,
+		MaxMemoryBytes: uint64(cfg.TTS.MaxMemoryMiB) * mebibyte, // #nosec G115 -- validated >= 0
+		MaxCPUSeconds:  uint64(cfg.TTS.MaxCPUSeconds),           // #nosec G115 -- validated >= 0
+		MaxRuntime:     time.Duration(cfg.TTS.MaxRuntimeSeconds) * time.Second,
+	})
+
+	return processor, nil
+}
+
+// workspace returns the directory job files are written to.
+func workspace(cfg *config.Config) tts.Workspace {
+	return tts.Workspace{Root: cfg.TTS.WorkDir}
+}
+
+// resolveModels resolves the configured model names through the model catalog,
+// downloading missing models. Without a catalog the paths are used as given.
+func resolveModels(ctx context.Context, cfg *config.Config, log *logger.Logger) (*models.Manager, string, string, error) {
+	if len(cfg.Models.Catalog) == 0 {
+		return nil, cfg.TTS.ModelPath, cfg.TTS.SnacModelPath, nil
+	}
+
+	catalog := make(map[string]models.Spec, len(cfg.Models.Catalog))
+	for name, spec := range cfg.Models.Catalog {
+		catalog[name] = models.Spec{URL: spec.URL, SHA256: spec.SHA256, Filename: spec.Filename}
+	}
+
+	modelManager, err := models.New(cfg.Models.Dir, catalog, nil, log)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to create model manager: %w", err)
+	}
+
+	modelPath, snacModelPath, err := modelManager.Activate(ctx, cfg.TTS.ModelPath, cfg.TTS.SnacModelPath)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("failed to resolve models: %w", err)
+	}
+
+	return modelManager, modelPath, snacModelPath, nil
+}
+
+// startModelControl accepts model hot-swap requests when a model catalog and control subject are configured.
+func startModelControl(
+	ctx context.Context,
+	natsConnection *nats.Conn,
+	cfg *config.Config,
+	modelManager *models.Manager,
+	swapper models.Swapper,
+	log *logger.Logger,
+) {
+	if modelManager == nil || cfg.NATS.ModelControlSubject == "" {
+		return
+	}
+
+	go func() {
+		serveErr := modelManager.ServeControl(ctx, natsConnection, cfg.NATS.ModelControlSubject, swapper)
+		if serveErr != nil {
+			log.Error("Model control responder stopped with error: %v", serveErr)
+		}
+	}()
+}
+
+// startMetrics serves the metrics registry when a metrics subject is configured.
+func startMetrics(
+	ctx context.Context,
+	natsConnection *nats.Conn,
+	cfg *config.Config,
+	registry *metrics.Registry,
+	log *logger.Logger,
+) {
+	if cfg.NATS.MetricsSubject == "" {
+		return
+	}
+
+	go func() {
+		serveErr := registry.Serve(ctx, natsConnection, cfg.NATS.MetricsSubject, log)
+		if serveErr != nil {
+			log.Error("Metrics responder stopped with error: %v", serveErr)
+		}
+	}()
+}
+
+// startScheduler runs the deferred/recurring job scheduler when a schedule bucket is configured.
+func startScheduler(
+	ctx context.Context,
+	natsConnection *nats.Conn,
+	jetstreamContext nats.JetStreamContext,
+	cfg *config.Config,
+	log *logger.Logger,
+) error {
+	if cfg.NATS.ScheduleBucket == "" {
+		return nil
+	}
+
+	jobScheduler, err := scheduler.New(
+		natsConnection,
+		jetstreamContext,
+		cfg.NATS.ScheduleBucket,
+		cfg.NATS.ScheduleSubject,
+		cfg.NATS.TextProcessedSubject,
+		cfg.NATS.AudioChunkCreatedSubject,
+		scheduler.DefaultPollInterval,
+		log,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create scheduler: %w", err)
+	}
+
+	go func() {
+		runErr := jobScheduler.Run(ctx)
+		if runErr != nil {
+			log.Error("Scheduler stopped with error: %v", runErr)
+		}
+	}()
+
+	log.Info("Scheduler accepting jobs on subject: %s", cfg.NATS.ScheduleSubject)
+
+	return nil
+}
+
+// startAssembler merges the chunks of each workflow when an assembly bucket is configured.
+func startAssembler(
+	ctx context.Context,
+	natsConnection *nats.Conn,
+	jetstreamContext nats.JetStreamContext,
+	store core.ObjectStore,
+	cfg *config.Config,
+	log *logger.Logger,
+) error {
+	if cfg.NATS.AssemblyBucket == "" {
+		return nil
+	}
+
+	chunkAssembler, err := assembler.New(
+		natsConnection,
+		jetstreamContext,
+		cfg.NATS.AssemblyBucket,
+		time.Duration(cfg.NATS.AssemblyTTLSeconds)*time.Second,
+		store,
+		cfg.NATS.AudioChunkCreatedSubject,
+		cfg.NATS.AudioAssembledSubject,
+		log,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to create assembler: %w", err)
+	}
+
+	go func() {
+		runErr := chunkAssembler.Run(ctx)
+		if runErr != nil {
+			log.Error("Assembler stopped with error: %v", runErr)
+		}
+	}()
+
+	log.Info("Assembling audio chunks from subject: %s", cfg.NATS.AudioChunkCreatedSubject)
+
+	return nil
+}
+
+// waitForShutdownSignal blocks until SIGINT or SIGTERM, reloading the
+// configuration on every SIGHUP in the meantime. It returns errWorkerStopped
+// when the worker stops first.
+func waitForShutdownSignal(log *logger.Logger, running *runningWorker) error {
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-	<-sigChan
-	log.Info("Shutdown signal received, gracefully shutting down...")
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+
+	for {
+		select {
+		case <-running.stopped:
+			return errWorkerStopped
+		case sig := <-sigChan:
+			if sig != syscall.SIGHUP {
+				log.Info("Shutdown signal received, gracefully shutting down...")
+
+				return nil
+			}
+
+			log.Info("SIGHUP received, reloading configuration...")
+			running.reloads.reload()
+		}
+	}
 }
 
 func run() error {
+	dryRun := flag.Bool("dry-run", false, "estimate every job's duration instead of synthesizing it")
+	seed := flag.Int("seed", 0, "seed of jobs that do not set one, overriding tts_service.seed; 0 keeps the configured seed")
+	version := flag.Bool("version", false, "print the build version and exit")
+	flag.Parse()
+
+	if *version {
+		fmt.Fprintln(os.Stdout, serviceName+" "+buildinfo.Get().String())
+
+		return nil
+	}
+
 	cfg, bootstrapLog, err := bootstrap()
 	if err != nil {
 		return err
 	}
 
-	log, err := setupLogger(os.TempDir())
+	if *dryRun {
+		cfg.DryRun.Enabled = true
+	}
+
+	if *seed != 0 {
+		cfg.TTS.Seed = *seed
+	}
+
+	logDir, logFile := cfg.Logging.Dir, cfg.Logging.File
+	if logDir == "" {
+		logDir = os.TempDir()
+	}
+
+	if logFile == "" {
+		logFile = config.DefaultLogFile
+	}
+
+	log, closeLog, err := logging.New(logging.Options{
+		Dir:        logDir,
+		File:       logFile,
+		Level:      cfg.Logging.Level,
+		MaxSizeMiB: cfg.Logging.MaxSizeMiB,
+		MaxBackups: cfg.Logging.MaxBackups,
+		Stdout:     os.Stdout,
+	})
 	if err != nil {
 		bootstrapLog.Error("Failed to create final logger: %v", err)
 
@@ -131,7 +1312,7 @@ func run() error {
 	}
 
 	defer func() {
-		closeErr := log.Close()
+		closeErr := closeLog()
 		if closeErr != nil {
 			fmt.Fprintf(os.Stderr, "error closing logger: %v\n", closeErr)
 		}
@@ -140,19 +1321,27 @@ func run() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	workerCancel, err := startWorker(ctx, cfg, log)
+	running, err := startWorker(ctx, cfg, log)
 	if err != nil {
 		log.Error("Failed to start worker: %v", err)
 
 		return err
 	}
 
-	waitForShutdownSignal(log)
-	workerCancel()
+	err = waitForShutdownSignal(log, running)
+	running.cancel()
+
+	// Freeing the models waits for the jobs that use them to stop.
+	if running.models != nil {
+		closeErr := running.models.Close()
+		if closeErr != nil {
+			log.Error("Failed to free models: %v", closeErr)
+		}
+	}
 
 	log.Info("Shutdown complete.")
 
-	return nil
+	return err
 }
 
 func main() {
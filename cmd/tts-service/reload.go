@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/config"
+	"github.com/book-expert/tts-service/internal/worker"
+	"github.com/nats-io/nats.go"
+)
+
+// Reload outcomes reported on the reload subject.
+const (
+	reloadStatusReloaded = "reloaded"
+	reloadStatusFailed   = "failed"
+)
+
+// reloadableSettings are the settings a reload applies to the running worker.
+// Changes to any other setting are reported but need a restart.
+var reloadableSettings = map[string]bool{
+	"tts_service.voice":              true,
+	"tts_service.temperature":        true,
+	"tts_service.top_p":              true,
+	"tts_service.repetition_penalty": true,
+	"tts_service.ngl":                true,
+	"tts_service.timeout_seconds":    true,
+	"voices":                         true,
+}
+
+// reloadResponse answers a request on the reload subject.
+type reloadResponse struct {
+	Status string `json:"status"`
+	// Applied lists the changes now used by new jobs.
+	Applied []string `json:"applied,omitempty"`
+	// Pending lists the changes that take effect after a restart.
+	Pending []string `json:"pending,omitempty"`
+	Error   string   `json:"error,omitempty"`
+}
+
+// reloader re-reads the configuration and applies the reloadable settings to
+// the worker. It keeps its own copy of the configuration that is in effect.
+type reloader struct {
+	mu      sync.Mutex
+	current config.Config
+	worker  *worker.NatsWorker
+	log     *logger.Logger
+}
+
+func newReloader(cfg *config.Config, natsWorker *worker.NatsWorker, log *logger.Logger) *reloader {
+	return &reloader{
+		mu:      sync.Mutex{},
+		current: *cfg,
+		worker:  natsWorker,
+		log:     log,
+	}
+}
+
+// jobDefaults returns the worker defaults configured in [tts_service] and [voices].
+func jobDefaults(cfg *config.Config) worker.JobDefaults {
+	return worker.JobDefaults{
+		Voice:             cfg.TTS.Voice,
+		Seed:              cfg.TTS.Seed,
+		NGL:               cfg.TTS.NGL,
+		TopP:              cfg.TTS.TopP,
+		RepetitionPenalty: cfg.TTS.RepetitionPenalty,
+		Temperature:       cfg.TTS.Temperature,
+		Voices:            voiceProfiles(cfg.Voices),
+	}
+}
+
+// voiceProfiles converts the configured voice profiles to worker profiles.
+func voiceProfiles(voices map[string]config.VoiceProfileConfig) map[string]worker.VoiceProfile {
+	profiles := make(map[string]worker.VoiceProfile, len(voices))
+
+	for name, voice := range voices {
+		profiles[name] = worker.VoiceProfile{
+			TopP:              voice.TopP,
+			RepetitionPenalty: voice.RepetitionPenalty,
+			Temperature:       voice.Temperature,
+			Rate:              voice.Rate,
+		}
+	}
+
+	return profiles
+}
+
+// reload loads and validates the configuration, logs what changed, and
+// applies the reloadable settings to subsequent jobs. An invalid configuration
+// changes nothing.
+func (r *reloader) reload() reloadResponse {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	updated, err := config.Load(r.log)
+	if err != nil {
+		r.log.Error("Configuration reload failed, keeping the current settings: %v", err)
+
+		return reloadResponse{Status: reloadStatusFailed, Applied: nil, Pending: nil, Error: err.Error()}
+	}
+
+	response := reloadResponse{Status: reloadStatusReloaded, Applied: nil, Pending: nil, Error: ""}
+
+	for _, change := range r.current.Diff(updated) {
+		key, _, _ := strings.Cut(change, ":")
+		if reloadableSettings[key] {
+			r.log.Info("Reloaded %s", change)
+			response.Applied = append(response.Applied, change)
+		} else {
+			r.log.Warn("Changed %s; this takes effect after a restart", change)
+			response.Pending = append(response.Pending, change)
+		}
+	}
+
+	r.current.TTS.Voice = updated.TTS.Voice
+	r.current.TTS.Temperature = updated.TTS.Temperature
+	r.current.TTS.TopP = updated.TTS.TopP
+	r.current.TTS.RepetitionPenalty = updated.TTS.RepetitionPenalty
+	r.current.TTS.NGL = updated.TTS.NGL
+	r.current.TTS.TimeoutSeconds = updated.TTS.TimeoutSeconds
+
+	r.worker.UpdateSettings(r.current.JobTimeout(), jobDefaults(&r.current))
+
+	if len(response.Applied) == 0 && len(response.Pending) == 0 {
+		r.log.Info("Configuration reloaded without changes.")
+	}
+
+	return response
+}
+
+// serve answers reload requests on subject until the context is cancelled.
+func (r *reloader) serve(ctx context.Context, natsConnection *nats.Conn, subject string) {
+	sub, err := natsConnection.Subscribe(subject, func(msg *nats.Msg) {
+		data, marshalErr := json.Marshal(r.reload())
+		if marshalErr != nil {
+			r.log.Error("Failed to marshal reload response: %v", marshalErr)
+
+			return
+		}
+
+		respondErr := msg.Respond(data)
+		if respondErr != nil {
+			r.log.Warn("Failed to respond to reload request: %v", respondErr)
+		}
+	})
+	if err != nil {
+		r.log.Error("Failed to subscribe to reload subject %s: %v", subject, err)
+
+		return
+	}
+
+	<-ctx.Done()
+
+	drainErr := sub.Drain()
+	if drainErr != nil {
+		r.log.Warn("Failed to drain reload subscription: %v", drainErr)
+	}
+}
@@ -0,0 +1,447 @@
+// Package assembler collects the audio chunks of each workflow and, once every
+// page has arrived, merges them in page order into one audio object, so that
+// downstream services do not have to stitch chunks themselves.
+package assembler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// KV key prefixes. Chunks are kept per page until their workflow is
+// assembled; the assembled key claims a workflow so it is merged only once.
+const (
+	chunkKeyPrefix     = "chunks."
+	assembledKeyPrefix = "assembled."
+)
+
+// DefaultTTL is how long the chunks and assembly records of a workflow are
+// kept when no TTL is given. A workflow whose first chunk is older than that
+// when its last one arrives is never assembled.
+const DefaultTTL = 7 * 24 * time.Hour
+
+// DefaultRetryDelay is how long a JetStream chunk message whose workflow failed
+// to assemble waits before it is redelivered.
+const DefaultRetryDelay = 5 * time.Second
+
+// ErrInvalidChunk indicates a chunk event without a workflow, audio key or
+// valid page number.
+var ErrInvalidChunk = errors.New("invalid audio chunk event")
+
+// Assembler listens for the AudioChunkCreatedEvents of every workflow and
+// publishes an AudioAssembledEvent for each workflow whose pages are all in.
+// Received chunks are kept in a NATS KV bucket, so a restart loses none, until
+// the bucket's TTL removes the chunks of workflows that were never completed.
+type Assembler struct {
+	natsConnection   *nats.Conn
+	kv               nats.KeyValue
+	bucket           string
+	store            core.ObjectStore
+	chunkSubject     string
+	assembledSubject string
+	retryDelay       time.Duration
+	log              *logger.Logger
+}
+
+// New creates an Assembler backed by the given KV bucket, creating it if
+// needed, whose entries expire after ttl; zero uses DefaultTTL. Chunks are read
+// from and merged audio is written to store.
+func New(
+	natsConnection *nats.Conn,
+	jetstreamContext nats.JetStreamContext,
+	bucketName string,
+	ttl time.Duration,
+	store core.ObjectStore,
+	chunkSubject string,
+	assembledSubject string,
+	log *logger.Logger,
+) (*Assembler, error) {
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	// Use a "create-first" approach.
+	kv, err := jetstreamContext.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:       bucketName,
+		Description:  fmt.Sprintf("Audio chunks awaiting assembly for the %s bucket.", bucketName),
+		MaxValueSize: 0,
+		History:      1,
+		TTL:          ttl,
+		MaxBytes:     0,
+		Storage:      nats.FileStorage,
+		Replicas:     1,
+		Placement:    nil,
+		RePublish:    nil,
+		Mirror:       nil,
+		Sources:      nil,
+		Compression:  false,
+	})
+
+	// If the bucket already exists with a different configuration, bind to it.
+	if err != nil {
+		if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+			kv, err = jetstreamContext.KeyValue(bucketName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to bind to existing key-value bucket '%s': %w", bucketName, err)
+			}
+
+			err = setTTL(jetstreamContext, kv, ttl)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			return nil, fmt.Errorf("failed to create key-value bucket '%s': %w", bucketName, err)
+		}
+	}
+
+	return &Assembler{
+		natsConnection:   natsConnection,
+		kv:               kv,
+		bucket:           bucketName,
+		store:            store,
+		chunkSubject:     chunkSubject,
+		assembledSubject: assembledSubject,
+		retryDelay:       DefaultRetryDelay,
+		log:              log,
+	}, nil
+}
+
+// setTTL updates the TTL of an existing bucket, such as one created before it
+// had a TTL, by changing the maximum age of the stream behind it.
+func setTTL(jetstreamContext nats.JetStreamContext, kv nats.KeyValue, ttl time.Duration) error {
+	status, err := kv.Status()
+	if err != nil {
+		return fmt.Errorf("failed to read the status of key-value bucket '%s': %w", kv.Bucket(), err)
+	}
+
+	if status.TTL() == ttl {
+		return nil
+	}
+
+	info, err := jetstreamContext.StreamInfo("KV_" + kv.Bucket())
+	if err != nil {
+		return fmt.Errorf("failed to read the stream of key-value bucket '%s': %w", kv.Bucket(), err)
+	}
+
+	streamConfig := info.Config
+	streamConfig.MaxAge = ttl
+
+	_, err = jetstreamContext.UpdateStream(&streamConfig)
+	if err != nil {
+		return fmt.Errorf("failed to set the TTL of key-value bucket '%s': %w", kv.Bucket(), err)
+	}
+
+	return nil
+}
+
+// SetRetryDelay sets how long a JetStream chunk message whose workflow failed
+// to assemble waits before it is redelivered.
+func (a *Assembler) SetRetryDelay(delay time.Duration) {
+	a.retryDelay = delay
+}
+
+// Run assembles workflows from the chunks on the chunk subject until the
+// context is cancelled.
+func (a *Assembler) Run(ctx context.Context) error {
+	sub, err := a.natsConnection.Subscribe(a.chunkSubject, func(msg *nats.Msg) {
+		a.handleChunk(ctx, msg)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to subject %s: %w", a.chunkSubject, err)
+	}
+
+	<-ctx.Done()
+
+	err = sub.Drain()
+	if err != nil {
+		return fmt.Errorf("failed to drain subscription: %w", err)
+	}
+
+	return nil
+}
+
+// handleChunk records a chunk and publishes its workflow once assembled. A
+// chunk delivered by JetStream is acknowledged only once it is recorded and,
+// for the last missing page, its workflow assembled, so a failed assembly is
+// retried when the message is redelivered. Chunks that can never be recorded
+// are terminated.
+func (a *Assembler) handleChunk(ctx context.Context, msg *nats.Msg) {
+	_, jetStreamErr := msg.Metadata()
+	jetStream := jetStreamErr == nil
+
+	var chunk core.AudioChunkEvent
+
+	err := json.Unmarshal(msg.Data, &chunk)
+	if err != nil {
+		a.log.Error("Failed to parse audio chunk event: %v", err)
+		a.settle(msg, jetStream, msg.Term)
+
+		return
+	}
+
+	assembled, err := a.Add(ctx, chunk)
+	if errors.Is(err, ErrInvalidChunk) {
+		a.log.Error("Failed to assemble workflow %s: %v", chunk.Header.WorkflowID, err)
+		a.settle(msg, jetStream, msg.Term)
+
+		return
+	}
+
+	if err != nil {
+		a.log.Error("Failed to assemble workflow %s: %v", chunk.Header.WorkflowID, err)
+		a.settle(msg, jetStream, func(opts ...nats.AckOpt) error {
+			return msg.NakWithDelay(a.retryDelay, opts...)
+		})
+
+		return
+	}
+
+	a.settle(msg, jetStream, msg.Ack)
+
+	if assembled == nil {
+		return
+	}
+
+	data, err := json.Marshal(assembled)
+	if err != nil {
+		a.log.Error("Failed to marshal assembled event for workflow %s: %v", chunk.Header.WorkflowID, err)
+
+		return
+	}
+
+	err = a.natsConnection.Publish(a.assembledSubject, data)
+	if err != nil {
+		a.log.Error("Failed to publish assembled event for workflow %s: %v", chunk.Header.WorkflowID, err)
+
+		return
+	}
+
+	a.log.Info("Assembled %d pages of workflow %s into %s",
+		assembled.TotalPages, chunk.Header.WorkflowID, assembled.AudioKey)
+}
+
+// settle acknowledges, hands back or terminates a JetStream message with ack.
+// Messages delivered outside JetStream cannot be redelivered and are left alone.
+func (a *Assembler) settle(msg *nats.Msg, jetStream bool, ack func(opts ...nats.AckOpt) error) {
+	if !jetStream {
+		return
+	}
+
+	err := ack()
+	if err != nil {
+		a.log.Warn("Failed to settle audio chunk message on subject %s: %v", msg.Subject, err)
+	}
+}
+
+// Add records a chunk and, when it is the last missing page of its workflow,
+// merges the workflow's chunks. It returns the AudioAssembledEvent of the
+// merged workflow, or nil while pages are missing. Each workflow is merged
+// once; chunks that arrive again after that are ignored. When merging fails,
+// the next chunk of the workflow, e.g. the redelivered final chunk, tries
+// again.
+func (a *Assembler) Add(ctx context.Context, chunk core.AudioChunkEvent) (*core.AudioAssembledEvent, error) {
+	workflowID := chunk.Header.WorkflowID
+	if workflowID == "" || chunk.AudioKey == "" || chunk.PageNumber < 1 || chunk.PageNumber > chunk.TotalPages {
+		return nil, fmt.Errorf("%w: workflow '%s', page %d of %d, audio key '%s'",
+			ErrInvalidChunk, workflowID, chunk.PageNumber, chunk.TotalPages, chunk.AudioKey)
+	}
+
+	_, err := a.kv.Get(assembledKeyPrefix + workflowID)
+	if err == nil {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(chunk)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal chunk: %w", err)
+	}
+
+	_, err = a.kv.Put(chunkKey(workflowID, chunk.PageNumber), data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store page %d in bucket '%s': %w", chunk.PageNumber, a.bucket, err)
+	}
+
+	chunks, err := a.chunks(ctx, workflowID)
+	if err != nil {
+		return nil, err
+	}
+
+	for page := 1; page <= chunk.TotalPages; page++ {
+		_, ok := chunks[page]
+		if !ok {
+			return nil, nil
+		}
+	}
+
+	// Only the first chunk to complete the workflow merges it.
+	claimRevision, err := a.kv.Create(assembledKeyPrefix+workflowID, nil)
+	if errors.Is(err, nats.ErrKeyExists) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim the assembly: %w", err)
+	}
+
+	assembled, err := a.assemble(ctx, chunk.TotalPages, chunks)
+	if err != nil {
+		releaseErr := a.kv.Delete(assembledKeyPrefix+workflowID, nats.LastRevision(claimRevision))
+		if releaseErr != nil {
+			return nil, errors.Join(err, fmt.Errorf("failed to release the assembly claim: %w", releaseErr))
+		}
+
+		return nil, err
+	}
+
+	a.finish(workflowID, assembled, chunks)
+
+	return assembled, nil
+}
+
+// chunkKey is the KV key of one page of a workflow.
+func chunkKey(workflowID string, pageNumber int) string {
+	return fmt.Sprintf("%s%s.%d", chunkKeyPrefix, workflowID, pageNumber)
+}
+
+// chunks returns the recorded chunks of a workflow by page number.
+func (a *Assembler) chunks(ctx context.Context, workflowID string) (map[int]core.AudioChunkEvent, error) {
+	watcher, err := a.kv.Watch(chunkKeyPrefix+workflowID+".*", nats.IgnoreDeletes(), nats.Context(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the chunks from bucket '%s': %w", a.bucket, err)
+	}
+
+	defer func() {
+		_ = watcher.Stop()
+	}()
+
+	chunks := make(map[int]core.AudioChunkEvent)
+
+	// The watcher delivers the current value of every matching key, then nil.
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+
+		var chunk core.AudioChunkEvent
+
+		err = json.Unmarshal(entry.Value(), &chunk)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal chunk '%s': %w", entry.Key(), err)
+		}
+
+		chunks[chunk.PageNumber] = chunk
+	}
+
+	return chunks, nil
+}
+
+// assemble downloads the chunks of pages 1 to totalPages and uploads them as
+// one WAV file. Chunks in another format are converted to that of page 1.
+func (a *Assembler) assemble(
+	ctx context.Context,
+	totalPages int,
+	chunks map[int]core.AudioChunkEvent,
+) (*core.AudioAssembledEvent, error) {
+	var merged wav.Audio
+
+	chunkKeys := make([]string, 0, totalPages)
+
+	for page := 1; page <= totalPages; page++ {
+		chunk := chunks[page]
+
+		data, err := a.store.Download(ctx, chunk.AudioKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download page %d ('%s'): %w", page, chunk.AudioKey, err)
+		}
+
+		if chunk.SHA256 != "" {
+			digest := sha256.Sum256(data)
+			if !strings.EqualFold(hex.EncodeToString(digest[:]), chunk.SHA256) {
+				return nil, fmt.Errorf("%w: page %d ('%s'): expected %s, got %x",
+					core.ErrChecksumMismatch, page, chunk.AudioKey, chunk.SHA256, digest)
+			}
+		}
+
+		decoded, err := wav.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode page %d ('%s'): %w", page, chunk.AudioKey, err)
+		}
+
+		chunkKeys = append(chunkKeys, chunk.AudioKey)
+
+		if page == 1 {
+			merged = decoded
+
+			continue
+		}
+
+		decoded, err = audio.Remix(audio.Resample(decoded, merged.SampleRate), merged.Channels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join page %d: %w", page, err)
+		}
+
+		merged.Samples = append(merged.Samples, decoded.Samples...)
+	}
+
+	data := wav.Encode(merged)
+	audioKey := uuid.NewString() + ".wav"
+
+	err := a.store.Upload(ctx, audioKey, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload merged audio '%s': %w", audioKey, err)
+	}
+
+	digest := sha256.Sum256(data)
+	duration := 0.0
+
+	if merged.SampleRate > 0 {
+		duration = float64(merged.Frames()) / float64(merged.SampleRate)
+	}
+
+	return &core.AudioAssembledEvent{
+		Header:          chunks[1].Header,
+		AudioKey:        audioKey,
+		TotalPages:      totalPages,
+		ChunkKeys:       chunkKeys,
+		DurationSeconds: duration,
+		SampleRate:      merged.SampleRate,
+		Channels:        merged.Channels,
+		SizeBytes:       len(data),
+		SHA256:          hex.EncodeToString(digest[:]),
+		Build:           buildinfo.Get(),
+	}, nil
+}
+
+// finish records the assembled event under the workflow's claim and removes
+// its chunks from the bucket. Failures are logged, since the audio is merged.
+func (a *Assembler) finish(workflowID string, assembled *core.AudioAssembledEvent, chunks map[int]core.AudioChunkEvent) {
+	data, err := json.Marshal(assembled)
+	if err == nil {
+		_, err = a.kv.Put(assembledKeyPrefix+workflowID, data)
+	}
+
+	if err != nil {
+		a.log.Warn("Failed to record the assembly of workflow %s: %v", workflowID, err)
+	}
+
+	for page := range chunks {
+		err = a.kv.Delete(chunkKey(workflowID, page))
+		if err != nil {
+			a.log.Warn("Failed to remove page %d of workflow %s from bucket '%s': %v", page, workflowID, a.bucket, err)
+		}
+	}
+}
@@ -0,0 +1,341 @@
+package assembler_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/book-expert/events"
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/assembler"
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errMockNotFound = errors.New("mock object not found")
+
+// memoryStore is an object store kept in memory.
+type memoryStore struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (s *memoryStore) Download(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.objects[key]
+	if !ok {
+		return nil, errMockNotFound
+	}
+
+	return data, nil
+}
+
+func (s *memoryStore) Upload(_ context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[key] = data
+
+	return nil
+}
+
+func newAssembler(t *testing.T) (*assembler.Assembler, *memoryStore, *nats.Conn) {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	natsConnection, err := nats.Connect(natsServer.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(natsConnection.Close)
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	testLogger, err := logger.New(t.TempDir(), "test-log.log")
+	require.NoError(t, err)
+
+	store := &memoryStore{mu: sync.Mutex{}, objects: map[string][]byte{}}
+
+	assemblerInstance, err := assembler.New(
+		natsConnection, jetstreamContext, "test-assembly", 0, store, "test_chunks", "test_assembled", testLogger,
+	)
+	require.NoError(t, err)
+
+	return assemblerInstance, store, natsConnection
+}
+
+// newChunk stores frames of silence as the audio of one page and returns its event.
+func newChunk(store *memoryStore, workflowID string, page, totalPages, frames, sampleRate int) core.AudioChunkEvent {
+	audioKey := fmt.Sprintf("%s-page-%d.wav", workflowID, page)
+	store.objects[audioKey] = wav.EncodePCM16(make([]float32, frames), sampleRate)
+
+	return core.AudioChunkEvent{
+		AudioChunkCreatedEvent: events.AudioChunkCreatedEvent{
+			Header: events.EventHeader{
+				Timestamp:  time.Now().UTC(),
+				WorkflowID: workflowID,
+				EventID:    "",
+				UserID:     "",
+				TenantID:   "",
+			},
+			AudioKey:   audioKey,
+			PageNumber: page,
+			TotalPages: totalPages,
+		},
+		DurationSeconds:  0,
+		SampleRate:       sampleRate,
+		Channels:         1,
+		SizeBytes:        0,
+		SHA256:           "",
+		Config:           core.EffectiveConfig{},
+		Build:            buildinfo.Info{},
+		SynthesisAttempt: 1,
+		TextStats:        nil,
+	}
+}
+
+func TestAssembler_Add(t *testing.T) {
+	t.Parallel()
+
+	assemblerInstance, store, _ := newAssembler(t)
+	ctx := context.Background()
+
+	page1 := newChunk(store, "book-1", 1, 3, 24000, 24000)
+	page2 := newChunk(store, "book-1", 2, 3, 12000, 24000)
+	// Chunks in another format are converted to that of page 1.
+	page3 := newChunk(store, "book-1", 3, 3, 8000, 16000)
+
+	for _, chunk := range []core.AudioChunkEvent{page3, page1} {
+		assembled, err := assemblerInstance.Add(ctx, chunk)
+		require.NoError(t, err)
+		assert.Nil(t, assembled, "pages are missing")
+	}
+
+	assembled, err := assemblerInstance.Add(ctx, page2)
+	require.NoError(t, err)
+	require.NotNil(t, assembled)
+
+	assert.Equal(t, "book-1", assembled.Header.WorkflowID)
+	assert.Equal(t, 3, assembled.TotalPages)
+	assert.Equal(t, []string{page1.AudioKey, page2.AudioKey, page3.AudioKey}, assembled.ChunkKeys)
+	assert.Equal(t, 24000, assembled.SampleRate)
+	assert.InDelta(t, 2.0, assembled.DurationSeconds, 0.01)
+
+	info, err := wav.Inspect(store.objects[assembled.AudioKey])
+	require.NoError(t, err)
+	assert.Equal(t, 24000, info.SampleRate)
+	assert.Len(t, store.objects[assembled.AudioKey], assembled.SizeBytes)
+
+	again, err := assemblerInstance.Add(ctx, page2)
+	require.NoError(t, err)
+	assert.Nil(t, again, "a workflow is assembled once")
+
+	_, err = assemblerInstance.Add(ctx, newChunk(store, "book-2", 4, 3, 10, 24000))
+	require.ErrorIs(t, err, assembler.ErrInvalidChunk)
+}
+
+func TestAssembler_RetriesFailedAssembly(t *testing.T) {
+	t.Parallel()
+
+	assemblerInstance, store, _ := newAssembler(t)
+	ctx := context.Background()
+
+	page1 := newChunk(store, "book-3", 1, 2, 100, 24000)
+	page2 := newChunk(store, "book-3", 2, 2, 100, 24000)
+	delete(store.objects, page2.AudioKey)
+
+	_, err := assemblerInstance.Add(ctx, page1)
+	require.NoError(t, err)
+
+	_, err = assemblerInstance.Add(ctx, page2)
+	require.ErrorIs(t, err, errMockNotFound)
+
+	store.objects[page2.AudioKey] = wav.EncodePCM16(make([]float32, 100), 24000)
+
+	assembled, err := assemblerInstance.Add(ctx, page2)
+	require.NoError(t, err)
+	require.NotNil(t, assembled, "the redelivered chunk assembles the workflow")
+}
+
+func TestAssembler_VerifiesChecksums(t *testing.T) {
+	t.Parallel()
+
+	assemblerInstance, store, _ := newAssembler(t)
+	ctx := context.Background()
+
+	page1 := newChunk(store, "book-4", 1, 2, 100, 24000)
+	page2 := newChunk(store, "book-4", 2, 2, 100, 24000)
+
+	digest := sha256.Sum256(store.objects[page1.AudioKey])
+	page1.SHA256 = hex.EncodeToString(digest[:])
+	page2.SHA256 = page1.SHA256
+	store.objects[page2.AudioKey] = wav.EncodePCM16(make([]float32, 101), 24000)
+
+	_, err := assemblerInstance.Add(ctx, page1)
+	require.NoError(t, err)
+
+	_, err = assemblerInstance.Add(ctx, page2)
+	require.ErrorIs(t, err, core.ErrChecksumMismatch)
+	assert.Contains(t, err.Error(), "page 2")
+}
+
+func TestAssembler_Run(t *testing.T) {
+	t.Parallel()
+
+	assemblerInstance, store, natsConnection := newAssembler(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assembledSub, err := natsConnection.SubscribeSync("test_assembled")
+	require.NoError(t, err)
+
+	go func() {
+		_ = assemblerInstance.Run(ctx)
+	}()
+
+	page1, err := json.Marshal(newChunk(store, "book-4", 1, 2, 100, 24000))
+	require.NoError(t, err)
+
+	page2, err := json.Marshal(newChunk(store, "book-4", 2, 2, 100, 24000))
+	require.NoError(t, err)
+
+	var msg *nats.Msg
+
+	// Publish until the assembler's subscription is in place.
+	require.Eventually(t, func() bool {
+		require.NoError(t, natsConnection.Publish("test_chunks", page1))
+		require.NoError(t, natsConnection.Publish("test_chunks", page2))
+
+		msg, err = assembledSub.NextMsg(100 * time.Millisecond)
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var assembled core.AudioAssembledEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &assembled))
+	assert.Equal(t, "book-4", assembled.Header.WorkflowID)
+	assert.Len(t, assembled.ChunkKeys, 2)
+}
+
+func TestAssembler_ExpiresEntries(t *testing.T) {
+	t.Parallel()
+
+	assemblerInstance, store, natsConnection := newAssembler(t)
+	require.NotNil(t, assemblerInstance)
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	kv, err := jetstreamContext.KeyValue("test-assembly")
+	require.NoError(t, err)
+
+	status, err := kv.Status()
+	require.NoError(t, err)
+	assert.Equal(t, assembler.DefaultTTL, status.TTL())
+
+	testLogger, err := logger.New(t.TempDir(), "test-log.log")
+	require.NoError(t, err)
+
+	_, err = assembler.New(
+		natsConnection, jetstreamContext, "test-assembly", time.Hour, store, "test_chunks", "test_assembled", testLogger,
+	)
+	require.NoError(t, err)
+
+	status, err = kv.Status()
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, status.TTL(), "an existing bucket takes the configured TTL")
+}
+
+func TestAssembler_RedeliversFailedAssembly(t *testing.T) {
+	t.Parallel()
+
+	assemblerInstance, store, natsConnection := newAssembler(t)
+	assemblerInstance.SetRetryDelay(50 * time.Millisecond)
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	// A push consumer delivers the chunks of the stream to the assembler.
+	var streamConfig nats.StreamConfig
+
+	streamConfig.Name = "CHUNKS"
+	streamConfig.Subjects = []string{"chunks.created"}
+
+	_, err = jetstreamContext.AddStream(&streamConfig)
+	require.NoError(t, err)
+
+	var consumerConfig nats.ConsumerConfig
+
+	consumerConfig.Durable = "assembler"
+	consumerConfig.DeliverSubject = "test_chunks"
+	consumerConfig.AckPolicy = nats.AckExplicitPolicy
+	consumerConfig.AckWait = time.Minute
+
+	_, err = jetstreamContext.AddConsumer("CHUNKS", &consumerConfig)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assembledSub, err := natsConnection.SubscribeSync("test_assembled")
+	require.NoError(t, err)
+
+	go func() {
+		_ = assemblerInstance.Run(ctx)
+	}()
+
+	page1 := newChunk(store, "book-5", 1, 2, 100, 24000)
+	page2 := newChunk(store, "book-5", 2, 2, 100, 24000)
+	delete(store.objects, page2.AudioKey)
+
+	for _, chunk := range []core.AudioChunkEvent{page1, page2} {
+		data, marshalErr := json.Marshal(chunk)
+		require.NoError(t, marshalErr)
+
+		_, marshalErr = jetstreamContext.Publish("chunks.created", data)
+		require.NoError(t, marshalErr)
+	}
+
+	// The final chunk is handed back while its audio is missing.
+	require.Eventually(t, func() bool {
+		info, infoErr := jetstreamContext.ConsumerInfo("CHUNKS", "assembler")
+
+		return infoErr == nil && info.NumRedelivered > 0 && info.NumAckPending == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, store.Upload(ctx, page2.AudioKey, wav.EncodePCM16(make([]float32, 100), 24000)))
+
+	msg, err := assembledSub.NextMsg(5 * time.Second)
+	require.NoError(t, err, "the redelivered final chunk assembles the workflow")
+
+	var assembled core.AudioAssembledEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &assembled))
+	assert.Equal(t, "book-5", assembled.Header.WorkflowID)
+
+	require.Eventually(t, func() bool {
+		info, infoErr := jetstreamContext.ConsumerInfo("CHUNKS", "assembler")
+
+		return infoErr == nil && info.NumAckPending == 0
+	}, 5*time.Second, 10*time.Millisecond, "every chunk is acknowledged once assembled")
+}
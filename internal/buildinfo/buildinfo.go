@@ -0,0 +1,77 @@
+// Package buildinfo identifies the build of the service binaries, so audio can
+// be traced back to the engine build that produced it.
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Version and Commit are set at build time, e.g. with
+// -ldflags "-X github.com/book-expert/tts-service/internal/buildinfo.Version=v1.2.0".
+// Without them, the commit is read from the VCS information Go embeds.
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// Info describes one build.
+type Info struct {
+	Version string `json:"version"`
+	// Commit is the git SHA the binary was built from, with a "-dirty"
+	// suffix when the tree had uncommitted changes. It may be empty.
+	Commit    string `json:"commit,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build of the running binary.
+var Get = sync.OnceValue(func() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+	}
+
+	if info.Commit == "" {
+		info.Commit = vcsCommit()
+	}
+
+	return info
+})
+
+// vcsCommit returns the commit recorded by the Go toolchain, if any.
+func vcsCommit() string {
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+
+	var revision, modified string
+
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value
+		}
+	}
+
+	if revision != "" && modified == "true" {
+		revision += "-dirty"
+	}
+
+	return revision
+}
+
+// String formats the build for --version output.
+func (i Info) String() string {
+	commit := i.Commit
+	if commit == "" {
+		commit = "unknown"
+	}
+
+	return fmt.Sprintf("%s (commit %s, %s)", i.Version, commit, i.GoVersion)
+}
@@ -0,0 +1,27 @@
+package buildinfo_test
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGet(t *testing.T) {
+	t.Parallel()
+
+	info := buildinfo.Get()
+	assert.Equal(t, "dev", info.Version, "the version defaults to dev without -ldflags")
+	assert.Equal(t, runtime.Version(), info.GoVersion)
+}
+
+func TestInfo_String(t *testing.T) {
+	t.Parallel()
+
+	info := buildinfo.Info{Version: "v1.4.0", Commit: "3186f325ff40-dirty", GoVersion: "go1.25.1"}
+	assert.Equal(t, "v1.4.0 (commit 3186f325ff40-dirty, go1.25.1)", info.String())
+
+	info.Commit = ""
+	assert.Equal(t, "v1.4.0 (commit unknown, go1.25.1)", info.String())
+}
@@ -2,19 +2,97 @@
 package config
 
 import (
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
 	"github.com/book-expert/configurator"
 	"github.com/book-expert/logger"
 )
 
+// DefaultJobTimeout bounds a job when tts_service timeout_seconds is not set.
+const DefaultJobTimeout = 30 * time.Second
+
+// DefaultLogFile names the log file when logging.file is not set.
+const DefaultLogFile = "tts-service.log"
+
+// Configuration errors.
+var (
+	ErrFallbackTimeoutTooLong = errors.New("fallback timeout_seconds exceeds the job timeout")
+	ErrAudioLevelPositive     = errors.New("audio levels must be at or below 0 dB")
+	ErrAudioFormatNegative    = errors.New("audio sample_rate and channels cannot be negative")
+	ErrMissingSetting         = errors.New("required setting is missing")
+	ErrModelFileNotFound      = errors.New("model file not found")
+	ErrOutOfRange             = errors.New("setting is out of range")
+	ErrUnknownModel           = errors.New("model is not in the registry")
+	ErrUnknownScript          = errors.New("unknown Unicode script")
+	ErrUnknownStyle           = errors.New("style is not defined")
+	ErrUnknownPolicy          = errors.New("safety policy is not defined")
+	ErrInvalidSafetyAction    = errors.New("safety action must be reject or redact")
+	ErrInvalidPattern         = errors.New("invalid regular expression")
+	ErrUnknownPIICategory     = errors.New("unknown PII category")
+	ErrInvalidEncryptionKey   = errors.New("invalid encryption key")
+	ErrUnknownEncoding        = errors.New("compression encoding must be gzip or zstd")
+	ErrInvalidHTTPAPI         = errors.New("invalid HTTP provider API")
+	ErrUnknownLogLevel        = errors.New("log level must be info, warn or error")
+)
+
 // NATSConfig holds the configuration for NATS.
 type NATSConfig struct {
-	URL                      string `toml:"url"`
-	TTStreamName             string `toml:"tts_stream_name"`
-	TTSConsumerName          string `toml:"tts_consumer_name"`
-	TextProcessedSubject     string `toml:"text_processed_subject"`
-	AudioChunkCreatedSubject string `toml:"audio_chunk_created_subject"`
-	AudioObjectStoreBucket   string `toml:"audio_object_store_bucket"`
+	URL                      string         `toml:"url"`
+	TTStreamName             string         `toml:"tts_stream_name"`
+	TTSConsumerName          string         `toml:"tts_consumer_name"`
+	TextProcessedSubject     string         `toml:"text_processed_subject"`
+	AudioChunkCreatedSubject string         `toml:"audio_chunk_created_subject"`
+	AudioObjectStoreBucket   string         `toml:"audio_object_store_bucket"`
+	JobStatusBucket          string         `toml:"job_status_bucket"`
+	JobStatusSubject         string         `toml:"job_status_subject"`
+	VersionSubject           string         `toml:"version_subject"`
+	DocumentSubject          string         `toml:"document_subject"`
+	TextStreamSubject        string         `toml:"text_stream_subject"`
+	StreamAudioSubject       string         `toml:"stream_audio_subject"`
+	ManifestBucket           string         `toml:"manifest_bucket"`
+	JobFailedSubject         string         `toml:"job_failed_subject"`
+	ScheduleBucket           string         `toml:"schedule_bucket"`
+	ScheduleSubject          string         `toml:"schedule_subject"`
+	AssemblyBucket           string         `toml:"assembly_bucket"`
+	AssemblyTTLSeconds       int            `toml:"assembly_ttl_seconds"`
+	AudioAssembledSubject    string         `toml:"audio_assembled_subject"`
+	MetricsSubject           string         `toml:"metrics_subject"`
+	ModelControlSubject      string         `toml:"model_control_subject"`
+	ReloadSubject            string         `toml:"reload_subject"`
+	MaxReconnects            int            `toml:"max_reconnects"`
+	ReconnectWaitSeconds     int            `toml:"reconnect_wait_seconds"`
+	ReconnectJitterSeconds   int            `toml:"reconnect_jitter_seconds"`
+	Auth                     NATSAuthConfig `toml:"auth"`
+	TLS                      NATSTLSConfig  `toml:"tls"`
+}
+
+// NATSAuthConfig authenticates the NATS connection with at most one method: user
+// and password, token, credentials file, or nkey seed file. Passwords and tokens
+// are read from the named environment variable or file, never from the TOML.
+type NATSAuthConfig struct {
+	User            string `toml:"user"`
+	PasswordEnv     string `toml:"password_env"`
+	PasswordFile    string `toml:"password_file"`
+	TokenEnv        string `toml:"token_env"`
+	TokenFile       string `toml:"token_file"`
+	CredentialsFile string `toml:"credentials_file"`
+	NKeySeedFile    string `toml:"nkey_seed_file"`
+}
+
+// NATSTLSConfig secures the NATS connection. CAFile verifies the server;
+// CertFile and KeyFile present a client certificate.
+type NATSTLSConfig struct {
+	CAFile   string `toml:"ca_file"`
+	CertFile string `toml:"cert_file"`
+	KeyFile  string `toml:"key_file"`
 }
 
 // TTSServiceConfig holds the specific configuration for the TTS service.
@@ -28,15 +106,420 @@ type TTSServiceConfig struct {
 	NGL               int     `toml:"ngl"`
 	TopP              float64 `toml:"top_p"`
 	RepetitionPenalty float64 `toml:"repetition_penalty"`
+	MaxTextChars      int     `toml:"max_text_chars"`
+	WorkDir           string  `toml:"work_dir"`
+	LogChatLLMOutput  bool    `toml:"log_chatllm_output"`
+	Nice              int     `toml:"nice"`
+	MaxMemoryMiB      int     `toml:"max_memory_mib"`
+	MaxCPUSeconds     int     `toml:"max_cpu_seconds"`
+	MaxRuntimeSeconds int     `toml:"max_runtime_seconds"`
+	MaxTimeoutSeconds int     `toml:"max_timeout_seconds"`
+	SelfTest          bool    `toml:"self_test"`
+	// MaxConcurrentJobs is the number of jobs and documents synthesized at
+	// once; zero handles them one at a time per subject.
+	MaxConcurrentJobs int `toml:"max_concurrent_jobs"`
+	// MaxPendingMessages caps the messages waiting in the client per job
+	// subject; zero uses the NATS default.
+	MaxPendingMessages int `toml:"max_pending_messages"`
+	// StreamIdleSeconds ends a text stream that has had no delta for this
+	// long; zero uses one minute.
+	StreamIdleSeconds int `toml:"stream_idle_seconds"`
+	// Languages lists the language codes the default model serves. Empty
+	// accepts any language.
+	Languages []string `toml:"languages"`
+}
+
+// GPUConfig holds the configuration for GPU-aware scheduling.
+// When AutoNGL is set, NGL is derived per job from detected VRAM instead of
+// being taken from the request.
+type GPUConfig struct {
+	AutoNGL        bool    `toml:"auto_ngl"`
+	MaxJobsPerGPU  int     `toml:"max_jobs_per_gpu"`
+	ModelLayers    int     `toml:"model_layers"`
+	VRAMFraction   float64 `toml:"vram_fraction"`
+	ReserveMiB     uint64  `toml:"reserve_mib"`
+	RefreshSeconds int     `toml:"refresh_seconds"`
+}
+
+// ModelSpec describes a downloadable model in the model catalog.
+type ModelSpec struct {
+	URL      string `toml:"url"`
+	SHA256   string `toml:"sha256"`
+	Filename string `toml:"filename"`
+}
+
+// ModelEntry is a model that jobs can select by name. Paths may name catalog entries.
+// Backend is "chatllm" (the default), "piper", whose model_path is an ONNX voice,
+// "google", which synthesizes with the [providers.google] account, "http", which
+// calls the [providers.http] service, or "llama",
+// which runs an Orpheus GGUF in-process (binaries built with -tags llamacpp).
+type ModelEntry struct {
+	Backend       string   `toml:"backend"`
+	ModelPath     string   `toml:"model_path"`
+	SnacModelPath string   `toml:"snac_model_path"`
+	DefaultVoice  string   `toml:"default_voice"`
+	Languages     []string `toml:"languages"`
+	ModelLayers   int      `toml:"model_layers"`
+}
+
+// ModelsConfig holds the model catalog and registry. When the catalog is present,
+// model paths may name catalog entries instead of files. The model configured in
+// tts_service is the default; the registry adds models selectable per job.
+type ModelsConfig struct {
+	Dir      string                `toml:"dir"`
+	Catalog  map[string]ModelSpec  `toml:"catalog"`
+	Registry map[string]ModelEntry `toml:"registry"`
+}
+
+// GoogleProviderConfig holds the Google Cloud Text-to-Speech credentials and voice mapping.
+// The API key is read from the environment variable named by APIKeyEnv.
+type GoogleProviderConfig struct {
+	Endpoint       string            `toml:"endpoint"`
+	APIKeyEnv      string            `toml:"api_key_env"`
+	LanguageCode   string            `toml:"language_code"`
+	Voices         map[string]string `toml:"voices"`
+	TimeoutSeconds int               `toml:"timeout_seconds"`
+}
+
+// HTTPProviderConfig points at a standalone TTS HTTP service. RequestsPerSecond
+// and MaxConcurrent cap the load this service puts on it; zero disables a limit.
+// With AdaptiveConcurrency, the requests in flight are tuned between
+// MinConcurrent and MaxConcurrent from the service's latency and errors.
+// Texts longer than MaxTextChars characters fail before they are sent; zero
+// leaves the limit to the service.
+type HTTPProviderConfig struct {
+	URL                 string  `toml:"url"`
+	Model               string  `toml:"model"`
+	TimeoutSeconds      int     `toml:"timeout_seconds"`
+	RequestsPerSecond   float64 `toml:"requests_per_second"`
+	MaxConcurrent       int     `toml:"max_concurrent"`
+	AdaptiveConcurrency bool    `toml:"adaptive_concurrency"`
+	MinConcurrent       int     `toml:"min_concurrent"`
+	MaxTextChars        int     `toml:"max_text_chars"`
+	// Connection reuse; zero values use the tts package defaults.
+	MaxIdleConnsPerHost    int           `toml:"max_idle_conns_per_host"`
+	IdleConnTimeoutSeconds int           `toml:"idle_conn_timeout_seconds"`
+	KeepAliveSeconds       int           `toml:"keep_alive_seconds"`
+	HTTP2                  bool          `toml:"http2"`
+	TLS                    HTTPTLSConfig `toml:"tls"`
+	API                    HTTPAPIConfig `toml:"api"`
+}
+
+// HTTPAPIConfig adapts the client to TTS servers other than the book-expert
+// one. Profile picks the built-in API of "default", "openai", "coqui" or
+// "piper"; the other settings override it where set. Fields maps request
+// fields (text, voice, speaker_ref_path, language, model, temperature, seed,
+// style) to the server's names, and an empty name stops a field from being
+// sent. ExtraFields are sent with every request. The API key is read from the
+// environment variable named by APIKeyEnv and sent in APIKeyHeader, or as a
+// bearer token in Authorization when no header is named.
+type HTTPAPIConfig struct {
+	Profile          string            `toml:"profile"`
+	Method           string            `toml:"method"`
+	SpeechPath       string            `toml:"speech_path"`
+	Fields           map[string]string `toml:"fields"`
+	ExtraFields      map[string]string `toml:"extra_fields"`
+	Headers          map[string]string `toml:"headers"`
+	APIKeyEnv        string            `toml:"api_key_env"`
+	APIKeyHeader     string            `toml:"api_key_header"`
+	AudioField       string            `toml:"audio_field"`
+	ErrorDetailField string            `toml:"error_detail_field"`
+	ErrorCodeField   string            `toml:"error_code_field"`
+}
+
+// HTTPTLSConfig secures the connection to an https TTS service: ca_file
+// verifies the service, cert_file and key_file authenticate this client, and
+// server_name overrides the host name checked against the certificate.
+type HTTPTLSConfig struct {
+	CAFile     string `toml:"ca_file"`
+	CertFile   string `toml:"cert_file"`
+	KeyFile    string `toml:"key_file"`
+	ServerName string `toml:"server_name"`
+}
+
+// PiperProviderConfig locates the piper binary. An empty command runs "piper" from PATH.
+type PiperProviderConfig struct {
+	Command string `toml:"command"`
+}
+
+// LlamaProviderConfig tunes the in-process llama.cpp backend. Zero values use
+// the tts package defaults; Threads 0 lets llama.cpp choose.
+type LlamaProviderConfig struct {
+	ContextSize int `toml:"context_size"`
+	MaxTokens   int `toml:"max_tokens"`
+	Threads     int `toml:"threads"`
+}
+
+// ProvidersConfig holds the cloud TTS provider accounts and in-process backend settings.
+type ProvidersConfig struct {
+	Google GoogleProviderConfig `toml:"google"`
+	HTTP   HTTPProviderConfig   `toml:"http"`
+	Piper  PiperProviderConfig  `toml:"piper"`
+	Llama  LlamaProviderConfig  `toml:"llama"`
+}
+
+// FallbackConfig turns the default model into a chain of backends that are tried
+// in order. Chain lists registry model names; "default" is the tts_service model.
+type FallbackConfig struct {
+	Chain            []string `toml:"chain"`
+	TimeoutSeconds   int      `toml:"timeout_seconds"`
+	FailureThreshold int      `toml:"failure_threshold"`
+	CooldownSeconds  int      `toml:"cooldown_seconds"`
+}
+
+// AudioConfig post-processes every synthesized chunk. Zero values keep the
+// backend's sample rate, channels and loudness; a zero TruePeakDB uses the
+// audio package default of -1 dBTP.
+type AudioConfig struct {
+	SampleRate int     `toml:"sample_rate"`
+	Channels   int     `toml:"channels"`
+	TargetLUFS float64 `toml:"target_lufs"`
+	TruePeakDB float64 `toml:"true_peak_db"`
+}
+
+// LoggingConfig selects where the service log is written. Log lines always go
+// to stdout as well, so a service manager such as systemd also collects them.
+type LoggingConfig struct {
+	// Dir is the directory of the log file; empty uses the system temp directory.
+	Dir string `toml:"dir"`
+	// File is the name of the log file; empty uses DefaultLogFile.
+	File string `toml:"file"`
+	// Level is the lowest level logged: info (the default), warn or error.
+	Level string `toml:"level"`
+	// MaxSizeMiB rotates the log file before it grows past this size; 0 never
+	// rotates it.
+	MaxSizeMiB int `toml:"max_size_mib"`
+	// MaxBackups is how many rotated log files are kept; 0 keeps none.
+	MaxBackups int `toml:"max_backups"`
+}
+
+// DryRunConfig makes the worker estimate jobs instead of synthesizing them.
+// The --dry-run flag also enables it. Zero rates use the worker defaults.
+type DryRunConfig struct {
+	Enabled        bool    `toml:"enabled"`
+	CharsPerSecond float64 `toml:"chars_per_second"`
+	RealTimeFactor float64 `toml:"realtime_factor"`
+}
+
+// TextReportConfig describes the text of every job before synthesis, in the
+// log and the reply, and flags sentences longer than MaxSentenceWords. Zero
+// MaxSentenceWords uses the worker default.
+type TextReportConfig struct {
+	Enabled            bool `toml:"enabled"`
+	MaxSentenceWords   int  `toml:"max_sentence_words"`
+	SplitLongSentences bool `toml:"split_long_sentences"`
+}
+
+// QualityConfig checks every chunk for silence, clipping and an implausible
+// duration before it is uploaded. Zero limits use the quality package defaults.
+// Rejected chunks are synthesized again, up to MaxAttempts times in all, each
+// time with the next seed and TemperatureStep lower temperature.
+type QualityConfig struct {
+	Enabled           bool    `toml:"enabled"`
+	SilenceDB         float64 `toml:"silence_db"`
+	MaxClippedRatio   float64 `toml:"max_clipped_ratio"`
+	MinCharsPerSecond float64 `toml:"min_chars_per_second"`
+	MaxCharsPerSecond float64 `toml:"max_chars_per_second"`
+	MaxAttempts       int     `toml:"max_attempts"`
+	TemperatureStep   float64 `toml:"temperature_step"`
+}
+
+// CastingConfig voices quoted dialogue with its own voices. It is enabled when
+// Dialogue or Characters is set. An empty Narrator uses the job's voice.
+type CastingConfig struct {
+	Narrator   string            `toml:"narrator"`
+	Dialogue   string            `toml:"dialogue"`
+	Characters map[string]string `toml:"characters"`
+}
+
+// StyleConfig defines one speaking style under [styles.<name>]. Prefix is
+// prepended to the text; non-zero Temperature and TopP replace the job's.
+// Empty Voices allows the style with every voice.
+type StyleConfig struct {
+	Prefix      string   `toml:"prefix"`
+	Temperature float64  `toml:"temperature"`
+	TopP        float64  `toml:"top_p"`
+	Voices      []string `toml:"voices"`
+}
+
+// ProsodyConfig speaks the parts of a text cued by punctuation in the styles
+// named here, which must be defined under [styles]. It is enabled when any
+// style is set.
+type ProsodyConfig struct {
+	Question    string `toml:"question"`
+	Exclamation string `toml:"exclamation"`
+	Aside       string `toml:"aside"`
+	Emphasis    string `toml:"emphasis"`
+}
+
+// LanguageConfig routes jobs in one language that select no model, under
+// [languages.<code>]. An empty Model selects the default model; a non-empty
+// Voice replaces the model's default voice.
+type LanguageConfig struct {
+	Model string `toml:"model"`
+	Voice string `toml:"voice"`
+}
+
+// MultilingualConfig synthesizes the runs of a text that switches language with
+// the model and voice of each language. Scripts maps Unicode script names, as
+// in Go's unicode.Scripts (e.g. "Cyrillic"), to language codes.
+type MultilingualConfig struct {
+	Enabled bool              `toml:"enabled"`
+	Scripts map[string]string `toml:"scripts"`
+}
+
+// PausesConfig synthesizes texts sentence by sentence and inserts silences
+// after sentences, paragraphs and chapter breaks, in seconds. Zero pauses use
+// the pause package defaults.
+type PausesConfig struct {
+	Enabled          bool    `toml:"enabled"`
+	SentenceSeconds  float64 `toml:"sentence_seconds"`
+	ParagraphSeconds float64 `toml:"paragraph_seconds"`
+	ChapterSeconds   float64 `toml:"chapter_seconds"`
+}
+
+// SafetyConfig screens the text of jobs before synthesis, for deployments that
+// take text from the public. Jobs use the policy their tenant is mapped to in
+// Tenants, or DefaultPolicy; a tenant mapped to "" is exempt. It is enabled
+// when DefaultPolicy or Tenants is set.
+type SafetyConfig struct {
+	DefaultPolicy string                        `toml:"default_policy"`
+	Tenants       map[string]string             `toml:"tenants"`
+	Policies      map[string]SafetyPolicyConfig `toml:"policies"`
+}
+
+// SafetyPolicyConfig defines one policy under [safety.policies.<name>]. Deny
+// words match whole words regardless of case; Patterns are Go regular
+// expressions. Action "reject" (the default) fails jobs with a match and
+// "redact" replaces every match with Replacement. A non-empty ModerationURL
+// also asks that moderation API; zero ModerationTimeoutSeconds uses the safety
+// package default.
+type SafetyPolicyConfig struct {
+	Action                   string   `toml:"action"`
+	Deny                     []string `toml:"deny"`
+	Patterns                 []string `toml:"patterns"`
+	Replacement              string   `toml:"replacement"`
+	ModerationURL            string   `toml:"moderation_url"`
+	ModerationTimeoutSeconds int      `toml:"moderation_timeout_seconds"`
+}
+
+// PIIConfig masks personal data in the text of every job before it is logged
+// or synthesized. Categories selects among "email", "phone" and "card"; empty
+// selects all. An empty Replacement masks each match with a spoken label of
+// its category.
+type PIIConfig struct {
+	Enabled     bool     `toml:"enabled"`
+	Categories  []string `toml:"categories"`
+	Replacement string   `toml:"replacement"`
+}
+
+// EncryptionConfig encrypts the objects the service stores, and decrypts those
+// it reads, with AES-GCM envelope encryption. Keys are "<id>:<base64 key>"
+// entries of 16, 24 or 32 bytes; new objects use the key named KeyID and the
+// others still decrypt older objects. With AllowPlaintext, objects stored
+// without encryption are read as they are.
+type EncryptionConfig struct {
+	Enabled        bool     `toml:"enabled"`
+	KeyID          string   `toml:"key_id"`
+	Keys           []string `toml:"keys"`
+	AllowPlaintext bool     `toml:"allow_plaintext"`
+}
+
+// KeyMap decodes Keys into keys by ID.
+func (e EncryptionConfig) KeyMap() (map[string][]byte, error) {
+	keys := make(map[string][]byte, len(e.Keys))
+
+	for i, entry := range e.Keys {
+		keyID, encoded, ok := strings.Cut(entry, ":")
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("%w: encryption.keys[%d] must be <id>:<base64 key>", ErrInvalidEncryptionKey, i)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: encryption.keys[%d] (%s): %w", ErrInvalidEncryptionKey, i, keyID, err)
+		}
+
+		if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+			return nil, fmt.Errorf("%w: encryption.keys[%d] (%s) has %d bytes, must have 16, 24 or 32",
+				ErrInvalidEncryptionKey, i, keyID, len(key))
+		}
+
+		keys[keyID] = key
+	}
+
+	return keys, nil
+}
+
+// CompressionConfig compresses the text objects the service uploads with
+// Encoding, "gzip" or "zstd", when they have at least MinBytes bytes; zero
+// MinBytes uses the objectstore default. Compressed objects are always
+// decompressed on download.
+type CompressionConfig struct {
+	Encoding string `toml:"encoding"`
+	MinBytes int    `toml:"min_bytes"`
+}
+
+// LoadConfig pauses taking new jobs while the host is busier than these
+// limits, and resumes when capacity frees up. MaxCPU, MaxMemory and MaxGPU are
+// utilizations from 0 to 1; MaxQueuedJobs counts jobs received but not started.
+// Zero disables a limit, and it is enabled when any limit is set. Zero
+// IntervalSeconds uses the load package default.
+type LoadConfig struct {
+	MaxCPU          float64 `toml:"max_cpu"`
+	MaxMemory       float64 `toml:"max_memory"`
+	MaxGPU          float64 `toml:"max_gpu"`
+	MaxQueuedJobs   int     `toml:"max_queued_jobs"`
+	IntervalSeconds float64 `toml:"interval_seconds"`
+}
+
+// Enabled reports whether any load limit is set.
+func (l LoadConfig) Enabled() bool {
+	return l.MaxCPU > 0 || l.MaxMemory > 0 || l.MaxGPU > 0 || l.MaxQueuedJobs > 0
+}
+
+// VoiceProfileConfig tunes one voice under [voices.<name>]. Non-zero values
+// apply to jobs with the voice that leave them at zero, before the
+// [tts_service] defaults.
+type VoiceProfileConfig struct {
+	Temperature       float64 `toml:"temperature"`
+	TopP              float64 `toml:"top_p"`
+	RepetitionPenalty float64 `toml:"repetition_penalty"`
+	Rate              float64 `toml:"rate"`
 }
 
 // Config is the root configuration structure.
 type Config struct {
-	NATS NATSConfig       `toml:"nats"`
-	TTS  TTSServiceConfig `toml:"tts_service"`
+	NATS      NATSConfig       `toml:"nats"`
+	TTS       TTSServiceConfig `toml:"tts_service"`
+	GPU       GPUConfig        `toml:"gpu"`
+	Models    ModelsConfig     `toml:"models"`
+	Providers ProvidersConfig  `toml:"providers"`
+	Fallback  FallbackConfig   `toml:"fallback"`
+	Audio     AudioConfig      `toml:"audio"`
+	DryRun    DryRunConfig     `toml:"dry_run"`
+	Quality   QualityConfig    `toml:"quality"`
+	Casting   CastingConfig    `toml:"casting"`
+	Pauses    PausesConfig     `toml:"pauses"`
+	Prosody   ProsodyConfig    `toml:"prosody"`
+	Logging   LoggingConfig    `toml:"logging"`
+	// Styles are the speaking styles jobs can select, by name.
+	Styles map[string]StyleConfig `toml:"styles"`
+	// Voices are the voice profiles, by voice name.
+	Voices map[string]VoiceProfileConfig `toml:"voices"`
+	// Languages route jobs without a model by language code.
+	Languages    map[string]LanguageConfig `toml:"languages"`
+	Multilingual MultilingualConfig        `toml:"multilingual"`
+	TextReport   TextReportConfig          `toml:"text_report"`
+	Safety       SafetyConfig              `toml:"safety"`
+	PII          PIIConfig                 `toml:"pii"`
+	Encryption   EncryptionConfig          `toml:"encryption"`
+	Compression  CompressionConfig         `toml:"compression"`
+	Load         LoadConfig                `toml:"load"`
 }
 
-// Load loads the configuration for the tts-service.
+// Load loads the configuration for the tts-service, applies the environment
+// overrides described at EnvName and validates the result.
 func Load(log *logger.Logger) (*Config, error) {
 	var cfg Config
 
@@ -45,5 +528,497 @@ func Load(log *logger.Logger) (*Config, error) {
 		return nil, fmt.Errorf("failed to load configuration from configurator: %w", err)
 	}
 
+	err = cfg.ApplyEnv(os.LookupEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	err = cfg.Validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return &cfg, nil
 }
+
+// JobTimeout returns the deadline of one TTS job: tts_service timeout_seconds,
+// or DefaultJobTimeout when it is not set.
+func (c *Config) JobTimeout() time.Duration {
+	if c.TTS.TimeoutSeconds <= 0 {
+		return DefaultJobTimeout
+	}
+
+	return time.Duration(c.TTS.TimeoutSeconds) * time.Second
+}
+
+// Validate checks the configuration at startup: required settings, model files,
+// numeric ranges, and settings that contradict each other. Every problem is
+// reported, joined into one error.
+func (c *Config) Validate() error {
+	var problems []error
+
+	problems = append(problems, c.validateRequired()...)
+	problems = append(problems, c.validateModelFiles()...)
+	problems = append(problems, c.validateRanges()...)
+	problems = append(problems, c.validateLanguages()...)
+	problems = append(problems, c.validateProsody()...)
+	problems = append(problems, c.validateSafety()...)
+	problems = append(problems, c.validateEncryption()...)
+	problems = append(problems, c.validateHTTPAPI()...)
+
+	switch c.Logging.Level {
+	case "", "info", "warn", "error":
+	default:
+		problems = append(problems, fmt.Errorf("%w: logging.level = %q", ErrUnknownLogLevel, c.Logging.Level))
+	}
+
+	stageTimeout := time.Duration(c.Fallback.TimeoutSeconds) * time.Second
+	if stageTimeout > c.JobTimeout() {
+		problems = append(problems, fmt.Errorf("%w: %s > %s; raise tts_service.timeout_seconds or lower fallback.timeout_seconds",
+			ErrFallbackTimeoutTooLong, stageTimeout, c.JobTimeout()))
+	}
+
+	if c.Audio.TargetLUFS > 0 || c.Audio.TruePeakDB > 0 {
+		problems = append(problems, fmt.Errorf("%w: target_lufs %g, true_peak_db %g",
+			ErrAudioLevelPositive, c.Audio.TargetLUFS, c.Audio.TruePeakDB))
+	}
+
+	if c.Compression.Encoding != "" && c.Compression.Encoding != "gzip" && c.Compression.Encoding != "zstd" {
+		problems = append(problems, fmt.Errorf("%w: compression.encoding = %q", ErrUnknownEncoding, c.Compression.Encoding))
+	}
+
+	if c.Audio.SampleRate < 0 || c.Audio.Channels < 0 {
+		problems = append(problems, fmt.Errorf("%w: sample_rate %d, channels %d",
+			ErrAudioFormatNegative, c.Audio.SampleRate, c.Audio.Channels))
+	}
+
+	return errors.Join(problems...)
+}
+
+// validateRequired reports the settings the service cannot start without.
+func (c *Config) validateRequired() []error {
+	type setting struct {
+		key   string
+		value string
+	}
+
+	required := []setting{
+		{key: "nats.url", value: c.NATS.URL},
+		{key: "nats.text_processed_subject", value: c.NATS.TextProcessedSubject},
+		{key: "nats.audio_object_store_bucket", value: c.NATS.AudioObjectStoreBucket},
+		{key: "tts_service.model_path", value: c.TTS.ModelPath},
+	}
+
+	// Streams publish the audio of their segments.
+	if c.NATS.TextStreamSubject != "" {
+		required = append(required, setting{key: "nats.stream_audio_subject", value: c.NATS.StreamAudioSubject})
+	}
+
+	// The assembler reads the chunk events and publishes the merged audio.
+	if c.NATS.AssemblyBucket != "" {
+		required = append(required,
+			setting{key: "nats.audio_chunk_created_subject", value: c.NATS.AudioChunkCreatedSubject},
+			setting{key: "nats.audio_assembled_subject", value: c.NATS.AudioAssembledSubject},
+		)
+	}
+
+	var problems []error
+
+	for _, setting := range required {
+		if setting.value == "" {
+			problems = append(problems, fmt.Errorf("%w: set %s or %s", ErrMissingSetting, setting.key, EnvName(setting.key)))
+		}
+	}
+
+	return problems
+}
+
+// validateModelFiles reports model paths that do not exist. With a model
+// catalog, paths may name catalog entries that are downloaded at startup, so
+// they are not checked.
+func (c *Config) validateModelFiles() []error {
+	if len(c.Models.Catalog) > 0 {
+		return nil
+	}
+
+	paths := map[string]string{
+		"tts_service.model_path":      c.TTS.ModelPath,
+		"tts_service.snac_model_path": c.TTS.SnacModelPath,
+	}
+
+	for name, entry := range c.Models.Registry {
+		// Cloud and HTTP backends have no model file.
+		if entry.Backend == "google" || entry.Backend == "http" {
+			continue
+		}
+
+		paths["models.registry."+name+".model_path"] = entry.ModelPath
+		paths["models.registry."+name+".snac_model_path"] = entry.SnacModelPath
+	}
+
+	keys := make([]string, 0, len(paths))
+	for key := range paths {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var problems []error
+
+	for _, key := range keys {
+		path := paths[key]
+		if path == "" {
+			continue
+		}
+
+		_, err := os.Stat(path)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%w: %s = %q: %w", ErrModelFileNotFound, key, path, err))
+		}
+	}
+
+	return problems
+}
+
+// validateLanguages reports language routes to models missing from the registry
+// and multilingual scripts that Go does not know.
+func (c *Config) validateLanguages() []error {
+	codes := make([]string, 0, len(c.Languages))
+	for code := range c.Languages {
+		codes = append(codes, code)
+	}
+
+	sort.Strings(codes)
+
+	var problems []error
+
+	for _, code := range codes {
+		model := c.Languages[code].Model
+		if model == "" {
+			continue
+		}
+
+		_, ok := c.Models.Registry[model]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: languages.%s.model = %q", ErrUnknownModel, code, model))
+		}
+	}
+
+	names := make([]string, 0, len(c.Multilingual.Scripts))
+	for name := range c.Multilingual.Scripts {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, ok := unicode.Scripts[name]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: multilingual.scripts.%s", ErrUnknownScript, name))
+		}
+	}
+
+	return problems
+}
+
+// validateProsody reports prosody hints that name undefined styles.
+func (c *Config) validateProsody() []error {
+	hints := []struct {
+		key   string
+		style string
+	}{
+		{"prosody.question", c.Prosody.Question},
+		{"prosody.exclamation", c.Prosody.Exclamation},
+		{"prosody.aside", c.Prosody.Aside},
+		{"prosody.emphasis", c.Prosody.Emphasis},
+	}
+
+	var problems []error
+
+	for _, hint := range hints {
+		if hint.style == "" {
+			continue
+		}
+
+		_, ok := c.Styles[hint.style]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: %s = %q", ErrUnknownStyle, hint.key, hint.style))
+		}
+	}
+
+	return problems
+}
+
+// validateSafety reports tenants mapped to undefined policies, unknown actions,
+// invalid patterns, negative moderation timeouts and unknown PII categories.
+func (c *Config) validateSafety() []error {
+	var problems []error
+
+	if c.Safety.DefaultPolicy != "" {
+		_, ok := c.Safety.Policies[c.Safety.DefaultPolicy]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: safety.default_policy = %q", ErrUnknownPolicy, c.Safety.DefaultPolicy))
+		}
+	}
+
+	tenants := make([]string, 0, len(c.Safety.Tenants))
+	for tenant := range c.Safety.Tenants {
+		tenants = append(tenants, tenant)
+	}
+
+	sort.Strings(tenants)
+
+	for _, tenant := range tenants {
+		policy := c.Safety.Tenants[tenant]
+		if policy == "" {
+			continue
+		}
+
+		_, ok := c.Safety.Policies[policy]
+		if !ok {
+			problems = append(problems, fmt.Errorf("%w: safety.tenants.%s = %q", ErrUnknownPolicy, tenant, policy))
+		}
+	}
+
+	names := make([]string, 0, len(c.Safety.Policies))
+	for name := range c.Safety.Policies {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		policy := c.Safety.Policies[name]
+
+		if policy.Action != "" && policy.Action != "reject" && policy.Action != "redact" {
+			problems = append(problems, fmt.Errorf("%w: safety.policies.%s.action = %q", ErrInvalidSafetyAction, name, policy.Action))
+		}
+
+		for _, pattern := range policy.Patterns {
+			_, err := regexp.Compile(pattern)
+			if err != nil {
+				problems = append(problems, fmt.Errorf("%w: safety.policies.%s.patterns: %w", ErrInvalidPattern, name, err))
+			}
+		}
+
+		if policy.ModerationTimeoutSeconds < 0 {
+			problems = append(problems, fmt.Errorf("%w: safety.policies.%s.moderation_timeout_seconds is %d, must be >= 0",
+				ErrOutOfRange, name, policy.ModerationTimeoutSeconds))
+		}
+	}
+
+	for _, category := range c.PII.Categories {
+		if category != "email" && category != "phone" && category != "card" {
+			problems = append(problems, fmt.Errorf("%w: pii.categories has %q; use email, phone or card",
+				ErrUnknownPIICategory, category))
+		}
+	}
+
+	return problems
+}
+
+// validateEncryption reports malformed encryption keys and a missing current
+// key when encryption is enabled.
+func (c *Config) validateEncryption() []error {
+	if !c.Encryption.Enabled {
+		return nil
+	}
+
+	keys, err := c.Encryption.KeyMap()
+	if err != nil {
+		return []error{err}
+	}
+
+	_, ok := keys[c.Encryption.KeyID]
+	if !ok {
+		return []error{fmt.Errorf("%w: encryption.key_id %q is not among encryption.keys", ErrInvalidEncryptionKey, c.Encryption.KeyID)}
+	}
+
+	return nil
+}
+
+// validateHTTPAPI reports unknown profiles, methods and request fields of
+// [providers.http.api], paths without a leading slash, and an unsent text.
+func (c *Config) validateHTTPAPI() []error {
+	api := c.Providers.HTTP.API
+
+	var problems []error
+
+	switch api.Profile {
+	case "", "default", "openai", "coqui", "piper":
+	default:
+		problems = append(problems, fmt.Errorf("%w: providers.http.api.profile = %q; use default, openai, coqui or piper",
+			ErrInvalidHTTPAPI, api.Profile))
+	}
+
+	if api.Method != "" && api.Method != "GET" && api.Method != "POST" {
+		problems = append(problems, fmt.Errorf("%w: providers.http.api.method = %q; use GET or POST",
+			ErrInvalidHTTPAPI, api.Method))
+	}
+
+	if api.SpeechPath != "" && !strings.HasPrefix(api.SpeechPath, "/") {
+		problems = append(problems, fmt.Errorf("%w: providers.http.api.speech_path = %q must start with /",
+			ErrInvalidHTTPAPI, api.SpeechPath))
+	}
+
+	fields := make([]string, 0, len(api.Fields))
+	for field := range api.Fields {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	for _, field := range fields {
+		switch field {
+		case "text", "voice", "speaker_ref_path", "language", "model", "temperature", "seed", "style":
+		default:
+			problems = append(problems, fmt.Errorf(
+				"%w: providers.http.api.fields has %q; use text, voice, speaker_ref_path, language, model, temperature, seed or style",
+				ErrInvalidHTTPAPI, field))
+		}
+	}
+
+	if name, ok := api.Fields["text"]; ok && name == "" {
+		problems = append(problems, fmt.Errorf("%w: providers.http.api.fields.text cannot be empty", ErrInvalidHTTPAPI))
+	}
+
+	return problems
+}
+
+// rangeCheck is one numeric setting checked by validateRanges.
+type rangeCheck struct {
+	key   string
+	valid bool
+	value any
+	want  string
+}
+
+// validateRanges reports numeric settings outside the ranges the backends
+// accept. The tts_service sampling settings follow the per-job limits.
+func (c *Config) validateRanges() []error {
+	ranges := []rangeCheck{
+		{"tts_service.temperature", c.TTS.Temperature >= 0, c.TTS.Temperature, ">= 0"},
+		{"tts_service.top_p", c.TTS.TopP >= 0 && c.TTS.TopP <= 1, c.TTS.TopP, "between 0 and 1"},
+		{
+			"tts_service.repetition_penalty", c.TTS.RepetitionPenalty == 0 || c.TTS.RepetitionPenalty >= 1,
+			c.TTS.RepetitionPenalty, "0 (unset) or >= 1",
+		},
+		{"tts_service.ngl", c.TTS.NGL >= 0, c.TTS.NGL, ">= 0"},
+		{"tts_service.timeout_seconds", c.TTS.TimeoutSeconds >= 0, c.TTS.TimeoutSeconds, ">= 0"},
+		{"tts_service.max_concurrent_jobs", c.TTS.MaxConcurrentJobs >= 0, c.TTS.MaxConcurrentJobs, ">= 0"},
+		{"tts_service.max_pending_messages", c.TTS.MaxPendingMessages >= 0, c.TTS.MaxPendingMessages, ">= 0"},
+		{"tts_service.max_text_chars", c.TTS.MaxTextChars >= 0, c.TTS.MaxTextChars, ">= 0"},
+		{"tts_service.stream_idle_seconds", c.TTS.StreamIdleSeconds >= 0, c.TTS.StreamIdleSeconds, ">= 0"},
+		{"tts_service.nice", c.TTS.Nice >= 0 && c.TTS.Nice <= 19, c.TTS.Nice, "between 0 and 19"},
+		{"tts_service.max_memory_mib", c.TTS.MaxMemoryMiB >= 0, c.TTS.MaxMemoryMiB, ">= 0"},
+		{"tts_service.max_cpu_seconds", c.TTS.MaxCPUSeconds >= 0, c.TTS.MaxCPUSeconds, ">= 0"},
+		{"tts_service.max_runtime_seconds", c.TTS.MaxRuntimeSeconds >= 0, c.TTS.MaxRuntimeSeconds, ">= 0"},
+		{"tts_service.max_timeout_seconds", c.TTS.MaxTimeoutSeconds >= 0, c.TTS.MaxTimeoutSeconds, ">= 0"},
+		{"gpu.max_jobs_per_gpu", c.GPU.MaxJobsPerGPU >= 0, c.GPU.MaxJobsPerGPU, ">= 0"},
+		{"gpu.vram_fraction", c.GPU.VRAMFraction >= 0 && c.GPU.VRAMFraction <= 1, c.GPU.VRAMFraction, "between 0 and 1"},
+		{"gpu.refresh_seconds", c.GPU.RefreshSeconds >= 0, c.GPU.RefreshSeconds, ">= 0"},
+		{
+			"providers.http.requests_per_second", c.Providers.HTTP.RequestsPerSecond >= 0,
+			c.Providers.HTTP.RequestsPerSecond, ">= 0",
+		},
+		{"providers.http.max_concurrent", c.Providers.HTTP.MaxConcurrent >= 0, c.Providers.HTTP.MaxConcurrent, ">= 0"},
+		{"providers.http.max_text_chars", c.Providers.HTTP.MaxTextChars >= 0, c.Providers.HTTP.MaxTextChars, ">= 0"},
+		{
+			"providers.http.max_idle_conns_per_host", c.Providers.HTTP.MaxIdleConnsPerHost >= 0,
+			c.Providers.HTTP.MaxIdleConnsPerHost, ">= 0",
+		},
+		{
+			"providers.http.idle_conn_timeout_seconds", c.Providers.HTTP.IdleConnTimeoutSeconds >= 0,
+			c.Providers.HTTP.IdleConnTimeoutSeconds, ">= 0",
+		},
+		{"providers.http.keep_alive_seconds", c.Providers.HTTP.KeepAliveSeconds >= 0, c.Providers.HTTP.KeepAliveSeconds, ">= 0"},
+		{
+			"providers.http.min_concurrent",
+			c.Providers.HTTP.MinConcurrent >= 0 &&
+				(c.Providers.HTTP.MaxConcurrent == 0 || c.Providers.HTTP.MinConcurrent <= c.Providers.HTTP.MaxConcurrent),
+			c.Providers.HTTP.MinConcurrent, ">= 0 and at most max_concurrent",
+		},
+		{"nats.reconnect_wait_seconds", c.NATS.ReconnectWaitSeconds >= 0, c.NATS.ReconnectWaitSeconds, ">= 0"},
+		{"nats.reconnect_jitter_seconds", c.NATS.ReconnectJitterSeconds >= 0, c.NATS.ReconnectJitterSeconds, ">= 0"},
+		{"nats.assembly_ttl_seconds", c.NATS.AssemblyTTLSeconds >= 0, c.NATS.AssemblyTTLSeconds, ">= 0"},
+		{"dry_run.chars_per_second", c.DryRun.CharsPerSecond >= 0, c.DryRun.CharsPerSecond, ">= 0"},
+		{"dry_run.realtime_factor", c.DryRun.RealTimeFactor >= 0, c.DryRun.RealTimeFactor, ">= 0"},
+		{"load.max_cpu", c.Load.MaxCPU >= 0 && c.Load.MaxCPU <= 1, c.Load.MaxCPU, "between 0 and 1"},
+		{"load.max_memory", c.Load.MaxMemory >= 0 && c.Load.MaxMemory <= 1, c.Load.MaxMemory, "between 0 and 1"},
+		{"load.max_gpu", c.Load.MaxGPU >= 0 && c.Load.MaxGPU <= 1, c.Load.MaxGPU, "between 0 and 1"},
+		{"load.max_queued_jobs", c.Load.MaxQueuedJobs >= 0, c.Load.MaxQueuedJobs, ">= 0"},
+		{"logging.max_size_mib", c.Logging.MaxSizeMiB >= 0, c.Logging.MaxSizeMiB, ">= 0"},
+		{"logging.max_backups", c.Logging.MaxBackups >= 0, c.Logging.MaxBackups, ">= 0"},
+		{"load.interval_seconds", c.Load.IntervalSeconds >= 0, c.Load.IntervalSeconds, ">= 0"},
+		{"compression.min_bytes", c.Compression.MinBytes >= 0, c.Compression.MinBytes, ">= 0"},
+		{"text_report.max_sentence_words", c.TextReport.MaxSentenceWords >= 0, c.TextReport.MaxSentenceWords, ">= 0"},
+		{"quality.silence_db", c.Quality.SilenceDB <= 0, c.Quality.SilenceDB, "<= 0"},
+		{
+			"quality.max_clipped_ratio", c.Quality.MaxClippedRatio >= 0 && c.Quality.MaxClippedRatio <= 1,
+			c.Quality.MaxClippedRatio, "between 0 and 1",
+		},
+		{"quality.min_chars_per_second", c.Quality.MinCharsPerSecond >= 0, c.Quality.MinCharsPerSecond, ">= 0"},
+		{
+			"quality.max_chars_per_second",
+			c.Quality.MaxCharsPerSecond >= 0 &&
+				(c.Quality.MaxCharsPerSecond == 0 || c.Quality.MaxCharsPerSecond >= c.Quality.MinCharsPerSecond),
+			c.Quality.MaxCharsPerSecond, ">= 0 and at least min_chars_per_second",
+		},
+		{"quality.max_attempts", c.Quality.MaxAttempts >= 0, c.Quality.MaxAttempts, ">= 0"},
+		{"quality.temperature_step", c.Quality.TemperatureStep >= 0, c.Quality.TemperatureStep, ">= 0"},
+		{"pauses.sentence_seconds", c.Pauses.SentenceSeconds >= 0, c.Pauses.SentenceSeconds, ">= 0"},
+		{"pauses.paragraph_seconds", c.Pauses.ParagraphSeconds >= 0, c.Pauses.ParagraphSeconds, ">= 0"},
+		{"pauses.chapter_seconds", c.Pauses.ChapterSeconds >= 0, c.Pauses.ChapterSeconds, ">= 0"},
+		{"fallback.timeout_seconds", c.Fallback.TimeoutSeconds >= 0, c.Fallback.TimeoutSeconds, ">= 0"},
+		{"fallback.failure_threshold", c.Fallback.FailureThreshold >= 0, c.Fallback.FailureThreshold, ">= 0"},
+		{"fallback.cooldown_seconds", c.Fallback.CooldownSeconds >= 0, c.Fallback.CooldownSeconds, ">= 0"},
+	}
+
+	styleNames := make([]string, 0, len(c.Styles))
+	for name := range c.Styles {
+		styleNames = append(styleNames, name)
+	}
+
+	sort.Strings(styleNames)
+
+	for _, name := range styleNames {
+		style := c.Styles[name]
+		ranges = append(ranges,
+			rangeCheck{"styles." + name + ".temperature", style.Temperature >= 0, style.Temperature, ">= 0"},
+			rangeCheck{"styles." + name + ".top_p", style.TopP >= 0 && style.TopP <= 1, style.TopP, "between 0 and 1"},
+		)
+	}
+
+	voiceNames := make([]string, 0, len(c.Voices))
+	for name := range c.Voices {
+		voiceNames = append(voiceNames, name)
+	}
+
+	sort.Strings(voiceNames)
+
+	for _, name := range voiceNames {
+		voice := c.Voices[name]
+		ranges = append(ranges,
+			rangeCheck{"voices." + name + ".temperature", voice.Temperature >= 0, voice.Temperature, ">= 0"},
+			rangeCheck{"voices." + name + ".top_p", voice.TopP >= 0 && voice.TopP <= 1, voice.TopP, "between 0 and 1"},
+			rangeCheck{
+				"voices." + name + ".repetition_penalty", voice.RepetitionPenalty == 0 || voice.RepetitionPenalty >= 1,
+				voice.RepetitionPenalty, "0 (unset) or >= 1",
+			},
+			rangeCheck{
+				"voices." + name + ".rate", voice.Rate == 0 || (voice.Rate >= 0.5 && voice.Rate <= 2),
+				voice.Rate, "0 (unset) or between 0.5 and 2",
+			},
+		)
+	}
+
+	var problems []error
+
+	for _, setting := range ranges {
+		if !setting.valid {
+			problems = append(problems, fmt.Errorf("%w: %s is %v, must be %s", ErrOutOfRange, setting.key, setting.value, setting.want))
+		}
+	}
+
+	return problems
+}
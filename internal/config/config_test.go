@@ -2,7 +2,10 @@
 package config_test
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/book-expert/tts-service/internal/config"
 	"github.com/pelletier/go-toml/v2"
@@ -43,3 +46,212 @@ timeout_seconds = 300
 	assert.InEpsilon(t, 0.7, cfg.TTS.Temperature, 0.001)
 	assert.Equal(t, 300, cfg.TTS.TimeoutSeconds)
 }
+
+// validConfig returns the smallest configuration that passes Validate.
+func validConfig(t *testing.T) config.Config {
+	t.Helper()
+
+	modelPath := filepath.Join(t.TempDir(), "model.bin")
+	require.NoError(t, os.WriteFile(modelPath, []byte("model"), 0o600))
+
+	var cfg config.Config
+
+	cfg.NATS.URL = "nats://127.0.0.1:4222"
+	cfg.NATS.TextProcessedSubject = "text.processed"
+	cfg.NATS.AudioObjectStoreBucket = "AUDIO_FILES"
+	cfg.TTS.ModelPath = modelPath
+
+	return cfg
+}
+
+func TestConfig_Validate(t *testing.T) {
+	t.Parallel()
+
+	cfg := validConfig(t)
+
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, config.DefaultJobTimeout, cfg.JobTimeout())
+
+	cfg.Fallback.TimeoutSeconds = 60
+	require.ErrorIs(t, cfg.Validate(), config.ErrFallbackTimeoutTooLong,
+		"a stage timeout longer than the default job timeout can never be reached")
+
+	cfg.TTS.TimeoutSeconds = 300
+	require.NoError(t, cfg.Validate())
+	assert.Equal(t, 300*time.Second, cfg.JobTimeout())
+
+	cfg.Audio.TargetLUFS = 16
+	require.ErrorIs(t, cfg.Validate(), config.ErrAudioLevelPositive, "LUFS targets are negative")
+
+	cfg.Audio.TargetLUFS = -16
+	require.NoError(t, cfg.Validate())
+
+	cfg.Audio.Channels = -1
+	require.ErrorIs(t, cfg.Validate(), config.ErrAudioFormatNegative)
+}
+
+func TestConfig_ValidateReportsEveryProblem(t *testing.T) {
+	t.Parallel()
+
+	var cfg config.Config
+
+	cfg.TTS.ModelPath = filepath.Join(t.TempDir(), "missing.bin")
+	cfg.TTS.TopP = 1.5
+	cfg.TTS.RepetitionPenalty = 0.5
+	cfg.TTS.Nice = 20
+	cfg.GPU.VRAMFraction = 2
+	cfg.Quality.MinCharsPerSecond = 20
+	cfg.Quality.MaxCharsPerSecond = 10
+	cfg.Pauses.ParagraphSeconds = -1
+	cfg.NATS.AssemblyBucket = "tts_assembly"
+	cfg.NATS.TextStreamSubject = "tts.streams.text"
+	cfg.TTS.StreamIdleSeconds = -1
+	cfg.Styles = map[string]config.StyleConfig{"calm": {Prefix: "", Temperature: 0.4, TopP: 1.2, Voices: nil}}
+	cfg.Voices = map[string]config.VoiceProfileConfig{"tara": {Temperature: 0.5, TopP: 0.9, RepetitionPenalty: 1.1, Rate: 3}}
+	cfg.Languages = map[string]config.LanguageConfig{"de": {Model: "german", Voice: ""}, "en": {Model: "", Voice: "tara"}}
+	cfg.Prosody = config.ProsodyConfig{Question: "calm", Exclamation: "excited", Aside: "", Emphasis: ""}
+	cfg.Safety = config.SafetyConfig{
+		DefaultPolicy: "public",
+		Tenants:       map[string]string{"acme": "strict", "staff": ""},
+		Policies: map[string]config.SafetyPolicyConfig{"public": {
+			Action: "block", Deny: []string{"darn"}, Patterns: []string{"("}, Replacement: "",
+			ModerationURL: "", ModerationTimeoutSeconds: 0,
+		}},
+	}
+	cfg.PII = config.PIIConfig{Enabled: true, Categories: []string{"email", "ssn"}, Replacement: ""}
+	cfg.Compression = config.CompressionConfig{Encoding: "lz4", MinBytes: 0}
+	cfg.Load = config.LoadConfig{MaxCPU: 90, MaxMemory: 0.9, MaxGPU: 0, MaxQueuedJobs: 0, IntervalSeconds: 0}
+	cfg.Encryption = config.EncryptionConfig{Enabled: true, KeyID: "k1", Keys: []string{"k1:c2hvcnQ="}, AllowPlaintext: false}
+	cfg.Providers.HTTP.MaxTextChars = -1
+	cfg.Providers.HTTP.API.Profile = "espeak"
+	cfg.Providers.HTTP.API.Method = "PUT"
+	cfg.Providers.HTTP.API.SpeechPath = "speak"
+	cfg.Providers.HTTP.API.Fields = map[string]string{"text": "", "pitch": "p", "voice": "speaker"}
+	cfg.Logging = config.LoggingConfig{Dir: "", File: "", Level: "debug", MaxSizeMiB: -1, MaxBackups: 3}
+
+	err := cfg.Validate()
+	require.ErrorIs(t, err, config.ErrMissingSetting)
+	require.ErrorIs(t, err, config.ErrUnknownModel)
+	require.NotErrorIs(t, err, config.ErrUnknownScript)
+	assert.Contains(t, err.Error(), `languages.de.model = "german"`)
+	require.ErrorIs(t, err, config.ErrUnknownStyle)
+	assert.Contains(t, err.Error(), `prosody.exclamation = "excited"`)
+	assert.NotContains(t, err.Error(), "prosody.question")
+	require.ErrorIs(t, err, config.ErrUnknownPolicy)
+	assert.Contains(t, err.Error(), `safety.tenants.acme = "strict"`)
+	assert.NotContains(t, err.Error(), "safety.default_policy")
+	require.ErrorIs(t, err, config.ErrInvalidSafetyAction)
+	require.ErrorIs(t, err, config.ErrInvalidPattern)
+	require.ErrorIs(t, err, config.ErrUnknownPIICategory)
+	assert.Contains(t, err.Error(), `"ssn"`)
+	require.ErrorIs(t, err, config.ErrInvalidEncryptionKey)
+	require.ErrorIs(t, err, config.ErrUnknownEncoding)
+	require.ErrorIs(t, err, config.ErrInvalidHTTPAPI)
+	assert.Contains(t, err.Error(), `providers.http.api.profile = "espeak"`)
+	assert.Contains(t, err.Error(), `providers.http.api.method = "PUT"`)
+	assert.Contains(t, err.Error(), `providers.http.api.speech_path = "speak"`)
+	assert.Contains(t, err.Error(), `providers.http.api.fields has "pitch"`)
+	assert.NotContains(t, err.Error(), `fields has "voice"`)
+	assert.Contains(t, err.Error(), "providers.http.api.fields.text cannot be empty")
+	assert.Contains(t, err.Error(), "load.max_cpu is 90, must be between 0 and 1")
+	require.ErrorIs(t, err, config.ErrUnknownLogLevel)
+	assert.Contains(t, err.Error(), `logging.level = "debug"`)
+	assert.Contains(t, err.Error(), "logging.max_size_mib is -1")
+	assert.NotContains(t, err.Error(), "logging.max_backups")
+	assert.Contains(t, err.Error(), "encryption.keys[0] (k1) has 5 bytes")
+	require.ErrorIs(t, err, config.ErrModelFileNotFound)
+	require.ErrorIs(t, err, config.ErrOutOfRange)
+	assert.Contains(t, err.Error(), "set nats.url or TTS_NATS_URL")
+	assert.Contains(t, err.Error(), "set nats.audio_assembled_subject", "the assembler needs its subjects")
+	assert.Contains(t, err.Error(), "set nats.stream_audio_subject", "streams publish their audio")
+	assert.Contains(t, err.Error(), "tts_service.stream_idle_seconds is -1")
+	assert.Contains(t, err.Error(), "tts_service.top_p is 1.5")
+	assert.Contains(t, err.Error(), "tts_service.repetition_penalty is 0.5")
+	assert.Contains(t, err.Error(), "tts_service.nice is 20")
+	assert.Contains(t, err.Error(), "gpu.vram_fraction is 2")
+	assert.Contains(t, err.Error(), "quality.max_chars_per_second is 10")
+	assert.Contains(t, err.Error(), "pauses.paragraph_seconds is -1")
+	assert.Contains(t, err.Error(), "providers.http.max_text_chars is -1")
+	assert.Contains(t, err.Error(), "styles.calm.top_p is 1.2")
+	assert.Contains(t, err.Error(), "voices.tara.rate is 3")
+	assert.NotContains(t, err.Error(), "voices.tara.top_p")
+
+	cfg.Multilingual.Scripts = map[string]string{"Cyrillic": "ru", "cyrillic": "ru"}
+	err = cfg.Validate()
+	require.ErrorIs(t, err, config.ErrUnknownScript)
+	assert.Contains(t, err.Error(), "multilingual.scripts.cyrillic")
+	assert.NotContains(t, err.Error(), "multilingual.scripts.Cyrillic")
+
+	cfg.Models.Catalog = map[string]config.ModelSpec{"narrator": {URL: "https://example.com/m.bin", SHA256: "", Filename: ""}}
+	err = cfg.Validate()
+	require.NotErrorIs(t, err, config.ErrModelFileNotFound, "catalog names are downloaded at startup")
+}
+
+func TestConfig_ApplyEnv(t *testing.T) {
+	t.Parallel()
+
+	var cfg config.Config
+
+	cfg.NATS.URL = "nats://toml:4222"
+	cfg.TTS.Voice = "tara"
+
+	env := map[string]string{
+		"TTS_NATS_URL":                     "nats://env:4222",
+		"TTS_MODEL_PATH":                   "/models/orpheus.gguf",
+		"TTS_TEMPERATURE":                  "0.5",
+		"TTS_NGL":                          "33",
+		"TTS_GPU_AUTO_NGL":                 "true",
+		"TTS_GPU_RESERVE_MIB":              "512",
+		"TTS_PROVIDERS_HTTP_URL":           "http://tts:8080",
+		"TTS_FALLBACK_CHAIN":               "default, piper",
+		"TTS_PROVIDERS_LLAMA_CONTEXT_SIZE": "4096",
+		"TTS_LOGGING_DIR":                  "/var/log/tts",
+	}
+
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+
+		return value, ok
+	}
+
+	require.NoError(t, cfg.ApplyEnv(lookup))
+	assert.Equal(t, "nats://env:4222", cfg.NATS.URL)
+	assert.Equal(t, "tara", cfg.TTS.Voice, "settings without an override keep their TOML value")
+	assert.Equal(t, "/models/orpheus.gguf", cfg.TTS.ModelPath)
+	assert.InEpsilon(t, 0.5, cfg.TTS.Temperature, 0.001)
+	assert.Equal(t, 33, cfg.TTS.NGL)
+	assert.True(t, cfg.GPU.AutoNGL)
+	assert.Equal(t, uint64(512), cfg.GPU.ReserveMiB)
+	assert.Equal(t, "http://tts:8080", cfg.Providers.HTTP.URL)
+	assert.Equal(t, []string{"default", "piper"}, cfg.Fallback.Chain)
+	assert.Equal(t, 4096, cfg.Providers.Llama.ContextSize)
+	assert.Equal(t, "/var/log/tts", cfg.Logging.Dir)
+
+	env["TTS_NGL"] = "many"
+	err := cfg.ApplyEnv(lookup)
+	require.ErrorIs(t, err, config.ErrInvalidEnv)
+	assert.Contains(t, err.Error(), "TTS_NGL")
+}
+
+func TestConfig_Diff(t *testing.T) {
+	t.Parallel()
+
+	var old config.Config
+
+	old.TTS.Temperature = 0.7
+	old.Models.Registry = map[string]config.ModelEntry{}
+
+	updated := old
+	updated.TTS.Temperature = 0.5
+	updated.NATS.URL = "nats://broker:4222"
+	updated.Models.Registry = map[string]config.ModelEntry{"fast": {
+		Backend: "piper", ModelPath: "voice.onnx", SnacModelPath: "", DefaultVoice: "", Languages: nil, ModelLayers: 0,
+	}}
+
+	assert.Empty(t, old.Diff(&old))
+	assert.Equal(t, []string{
+		"nats.url:  -> nats://broker:4222",
+		"tts_service.temperature: 0.7 -> 0.5",
+		"models.registry: map[] -> map[fast:{piper voice.onnx   [] 0}]",
+	}, old.Diff(&updated))
+}
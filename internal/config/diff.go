@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Diff lists the settings that differ between c and updated, one
+// "section.key: old -> new" line per setting, named by their TOML keys.
+func (c *Config) Diff(updated *Config) []string {
+	return diffValues("", reflect.ValueOf(*c), reflect.ValueOf(*updated))
+}
+
+// diffValues compares two values of the same type, descending into structs.
+func diffValues(path string, old, updated reflect.Value) []string {
+	if old.Kind() != reflect.Struct {
+		if reflect.DeepEqual(old.Interface(), updated.Interface()) {
+			return nil
+		}
+
+		return []string{fmt.Sprintf("%s: %v -> %v", path, old.Interface(), updated.Interface())}
+	}
+
+	var changes []string
+
+	for i := range old.NumField() {
+		field := old.Type().Field(i)
+
+		key, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if key == "" {
+			key = field.Name
+		}
+
+		if path != "" {
+			key = path + "." + key
+		}
+
+		changes = append(changes, diffValues(key, old.Field(i), updated.Field(i))...)
+	}
+
+	return changes
+}
@@ -0,0 +1,125 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix starts the name of every environment override.
+const EnvPrefix = "TTS_"
+
+// ErrInvalidEnv indicates an environment override that does not parse as its setting's type.
+var ErrInvalidEnv = errors.New("invalid environment override")
+
+// EnvName returns the environment variable that overrides a setting named by
+// its TOML path, for example "nats.url" -> TTS_NATS_URL. Settings in
+// [tts_service] drop the section: "tts_service.model_path" -> TTS_MODEL_PATH.
+func EnvName(key string) string {
+	key = strings.TrimPrefix(key, "tts_service.")
+
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// ApplyEnv overrides settings with the environment variables named by EnvName.
+// Lists are comma-separated; maps such as the model catalog cannot be overridden.
+// lookup is usually os.LookupEnv.
+func (c *Config) ApplyEnv(lookup func(string) (string, bool)) error {
+	return applyEnv("", reflect.ValueOf(c).Elem(), lookup)
+}
+
+// applyEnv sets the fields of a struct value from the environment, descending into structs.
+func applyEnv(path string, value reflect.Value, lookup func(string) (string, bool)) error {
+	for i := range value.NumField() {
+		field := value.Type().Field(i)
+
+		key, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+		if key == "" {
+			key = field.Name
+		}
+
+		if path != "" {
+			key = path + "." + key
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			err := applyEnv(key, value.Field(i), lookup)
+			if err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		name := EnvName(key)
+
+		raw, ok := lookup(name)
+		if !ok {
+			continue
+		}
+
+		err := setFromString(value.Field(i), raw)
+		if err != nil {
+			return fmt.Errorf("%w: %s=%q: %w", ErrInvalidEnv, name, raw, err)
+		}
+	}
+
+	return nil
+}
+
+// setFromString parses raw into a scalar or string list field. Other kinds are left unchanged.
+func setFromString(field reflect.Value, raw string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+
+		field.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+
+		field.SetFloat(parsed)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+
+		var items []string
+
+		for item := range strings.SplitSeq(raw, ",") {
+			item = strings.TrimSpace(item)
+			if item != "" {
+				items = append(items, item)
+			}
+		}
+
+		field.Set(reflect.ValueOf(items))
+	default:
+		return nil
+	}
+
+	return nil
+}
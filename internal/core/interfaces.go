@@ -1,7 +1,60 @@
 // Package core defines the core business logic and interfaces for the TTS service.
 package core
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/book-expert/events"
+	"github.com/book-expert/tts-service/internal/buildinfo"
+)
+
+// ErrInvalidAudio is wrapped by processors whose backend wrote something other
+// than the audio it should have, such as a truncated or empty WAV file, so the
+// job fails instead of uploading corrupt audio.
+var ErrInvalidAudio = errors.New("backend produced invalid audio")
+
+// ErrChecksumMismatch is wrapped by object stores and their consumers when an
+// object does not match the SHA-256 digest recorded for it, so corruption is
+// not mistaken for a transient download failure.
+var ErrChecksumMismatch = errors.New("object checksum mismatch")
+
+// ErrContentRejected is wrapped by text filters that reject a job's text
+// under the content policy of its tenant.
+var ErrContentRejected = errors.New("text rejected by content policy")
+
+// ErrUnsupportedLanguage is wrapped by LanguageError.
+var ErrUnsupportedLanguage = errors.New("language not supported")
+
+// LanguageError rejects a job whose language no model serves, or which its
+// selected model does not serve, and lists the languages that are served.
+type LanguageError struct {
+	// Language is the job's language.
+	Language string
+	// Model is the selected model; it is empty when the job selected none.
+	Model string
+	// Supported lists the languages that can be requested instead.
+	Supported []string
+}
+
+func (e *LanguageError) Error() string {
+	if e.Model == "" {
+		return fmt.Sprintf("%s: '%s' (supported: %s)",
+			ErrUnsupportedLanguage, e.Language, strings.Join(e.Supported, ", "))
+	}
+
+	return fmt.Sprintf("%s: model '%s' serves %s, got '%s'",
+		ErrUnsupportedLanguage, e.Model, strings.Join(e.Supported, ", "), e.Language)
+}
+
+// Unwrap lets errors.Is match ErrUnsupportedLanguage.
+func (e *LanguageError) Unwrap() error {
+	return ErrUnsupportedLanguage
+}
 
 // ObjectStore defines the interface for interacting with a key-value blob store.
 type ObjectStore interface {
@@ -12,6 +65,8 @@ type ObjectStore interface {
 // TTSConfig holds the configuration for a single TTS processing job.
 // This allows for per-request customization of the TTS output.
 type TTSConfig struct {
+	// Model names the registered model to use. Empty selects the default model.
+	Model             string
 	ModelPath         string
 	SnacModelPath     string
 	Voice             string
@@ -20,6 +75,297 @@ type TTSConfig struct {
 	TopP              float64
 	RepetitionPenalty float64
 	Temperature       float64
+	// Device selects the GPU the job runs on. Empty leaves the choice to the backend.
+	Device string
+	// Language is the language code of the text, e.g. "en". Empty skips the
+	// model's language check.
+	Language string
+	// Rate scales the speaking rate after synthesis, e.g. 1.25 for 25% faster
+	// at the same pitch. Zero leaves the rate unchanged.
+	Rate float64
+	// Pitch shifts the voice by this many semitones after synthesis.
+	Pitch float64
+	// Style names a speaking style or emotion, e.g. "calm" or "excited". Empty
+	// uses the voice's default delivery.
+	Style string
+}
+
+// JobEvent is a TextProcessedEvent extended with the fields this service
+// accepts on top of the shared event schema.
+type JobEvent struct {
+	events.TextProcessedEvent
+
+	// Model selects a registered model; empty uses the default model.
+	Model string `json:"model,omitempty"`
+	// Language is the language code of the text, checked against the model's languages.
+	Language string `json:"language,omitempty"`
+	// Rate is the speaking-rate factor applied after synthesis.
+	Rate float64 `json:"rate,omitempty"`
+	// Pitch is the pitch shift in semitones applied after synthesis.
+	Pitch float64 `json:"pitch,omitempty"`
+	// Style is the speaking style, checked against the styles of the voice.
+	Style string `json:"style,omitempty"`
+	// TimeoutSeconds bounds the processing of this job; zero uses the
+	// service's job timeout.
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+}
+
+// DocumentProcessedEvent asks for the audio of a whole document in one event.
+// The worker expands it into one chunk job per text, tracked as the pages of
+// the document's workflow, and answers with one AudioDocumentCreatedEvent. The
+// settings of the embedded JobEvent apply to every chunk; its PageNumber and
+// TotalPages are ignored.
+type DocumentProcessedEvent struct {
+	JobEvent
+
+	// TextKeys lists the text of each chunk in order. When it is empty, the
+	// text at TextKey is split into chunks instead.
+	TextKeys []string `json:"text_keys,omitempty"`
+	// ChunkChars is the most characters of one chunk when TextKey is split.
+	// Zero uses the service's default.
+	ChunkChars int `json:"chunk_chars,omitempty"`
+	// DocumentID names the document across its versions. When it is set and
+	// manifests are kept, chunks whose text did not change since the last
+	// version reuse that version's audio.
+	DocumentID string `json:"document_id,omitempty"`
+}
+
+// TextDeltaEvent carries the next piece of a text stream, such as the tokens
+// an LLM produced since the previous event. Header.WorkflowID identifies the
+// stream, and its pieces are joined in the order they arrive. The settings of
+// the embedded JobEvent are taken from the first event of a stream; its
+// TextKey, PageNumber and TotalPages are ignored.
+type TextDeltaEvent struct {
+	JobEvent
+
+	// Text is appended to the text of the stream.
+	Text string `json:"text"`
+	// Final ends the stream once Text is appended.
+	Final bool `json:"final,omitempty"`
+}
+
+// AudioSegmentEvent is published for each segment of a text stream as soon as
+// it is synthesized, in the order of the text. PageNumber numbers the segments
+// from 1, and TotalPages is zero except on the final event, where it is the
+// number of segments. A stream that ends with no text left to synthesize gets
+// a final event without audio: its AudioKey is empty and its PageNumber zero.
+type AudioSegmentEvent struct {
+	AudioChunkEvent
+
+	// Text is the text of the segment as it was synthesized, after filtering.
+	Text string `json:"text"`
+	// Final marks the last event of the stream.
+	Final bool `json:"final"`
+}
+
+// AudioDocumentCreatedEvent is the reply to a DocumentProcessedEvent once
+// every chunk has been synthesized and uploaded.
+type AudioDocumentCreatedEvent struct {
+	Header events.EventHeader `json:"header"`
+	// AudioKeys lists the audio of the chunks in document order.
+	AudioKeys []string `json:"audio_keys"`
+	// Chunks describes each chunk as its own job would, in the same order.
+	Chunks []AudioChunkEvent `json:"chunks"`
+	// DurationSeconds is the playing time of all chunks together.
+	DurationSeconds float64 `json:"duration_seconds"`
+	// ReusedChunks counts the chunks whose audio was reused from the
+	// document's previous version instead of being synthesized.
+	ReusedChunks int            `json:"reused_chunks"`
+	Build        buildinfo.Info `json:"build"`
+}
+
+// DocumentManifest records the chunks of the last synthesized version of a
+// document, so that the next version only synthesizes the chunks that changed.
+type DocumentManifest struct {
+	DocumentID string `json:"document_id"`
+	// ConfigHash identifies the settings the chunks were synthesized with;
+	// audio is only reused for the same settings.
+	ConfigHash string `json:"config_hash"`
+	// TextHashes holds the hash of each chunk's normalized text, in order.
+	TextHashes []string `json:"text_hashes"`
+	// Chunks describes the audio of each chunk, in the same order.
+	Chunks    []AudioChunkEvent `json:"chunks"`
+	UpdatedAt time.Time         `json:"updated_at"`
+}
+
+// AudioChunkEvent is the AudioChunkCreatedEvent published for a finished job,
+// extended with metadata about the chunk so that consumers can validate and
+// index it without downloading the WAV. The format fields are zero when the
+// backend's audio is not a WAV file.
+type AudioChunkEvent struct {
+	events.AudioChunkCreatedEvent
+
+	// DurationSeconds is the playing time of the audio.
+	DurationSeconds float64 `json:"duration_seconds"`
+	SampleRate      int     `json:"sample_rate"`
+	Channels        int     `json:"channels"`
+	// SizeBytes is the size of the uploaded object.
+	SizeBytes int `json:"size_bytes"`
+	// SHA256 is the hex-encoded SHA-256 digest of the uploaded audio, as
+	// Download returns it, so consumers can verify the transfer.
+	SHA256 string `json:"sha256"`
+	// Config is the configuration the job was synthesized with, after model and
+	// voice defaults were applied.
+	Config EffectiveConfig `json:"config"`
+	// Build identifies the service build that synthesized the chunk.
+	Build buildinfo.Info `json:"build"`
+	// SynthesisAttempt is the attempt whose audio passed the quality gate,
+	// counting from 1. Config holds the seed and temperature it used.
+	SynthesisAttempt int `json:"synthesis_attempt"`
+	// TextStats describes the job's text; nil unless text reports are enabled.
+	TextStats *TextStats `json:"text_stats,omitempty"`
+}
+
+// TextStats describes the text of a job before synthesis.
+type TextStats struct {
+	Words     int `json:"words"`
+	Sentences int `json:"sentences"`
+	// EstimatedDurationSeconds is the predicted playing time, after the job's rate.
+	EstimatedDurationSeconds float64 `json:"estimated_duration_seconds"`
+	// SentenceWords is the distribution of sentence lengths in words.
+	SentenceWords SentenceLengths `json:"sentence_words"`
+	// LongSentences are the sentences longer than the service's limit, which
+	// are likely to be synthesized poorly.
+	LongSentences []LongSentence `json:"long_sentences,omitempty"`
+	// SplitSentences counts the long sentences split before synthesis.
+	SplitSentences int `json:"split_sentences,omitempty"`
+}
+
+// SentenceLengths summarizes the lengths of a text's sentences.
+type SentenceLengths struct {
+	Min    int     `json:"min"`
+	Median int     `json:"median"`
+	P90    int     `json:"p90"`
+	Max    int     `json:"max"`
+	Mean   float64 `json:"mean"`
+}
+
+// LongSentence identifies a sentence longer than the service's limit.
+type LongSentence struct {
+	// Index counts the text's sentences from 1.
+	Index int `json:"index"`
+	Words int `json:"words"`
+	// Start is the first words of the sentence.
+	Start string `json:"start"`
+}
+
+// AudioAssembledEvent is published once the chunks of every page of a
+// workflow have been merged, in page order, into one audio object.
+type AudioAssembledEvent struct {
+	// Header is the header of the workflow's first page.
+	Header     events.EventHeader `json:"header"`
+	AudioKey   string             `json:"audio_key"`
+	TotalPages int                `json:"total_pages"`
+	// ChunkKeys lists the audio keys of the merged chunks in page order.
+	ChunkKeys       []string       `json:"chunk_keys"`
+	DurationSeconds float64        `json:"duration_seconds"`
+	SampleRate      int            `json:"sample_rate"`
+	Channels        int            `json:"channels"`
+	SizeBytes       int            `json:"size_bytes"`
+	SHA256          string         `json:"sha256"`
+	Build           buildinfo.Info `json:"build"`
+}
+
+// VersionInfo is the reply sent to version requests: the service build and
+// the files of the default model.
+type VersionInfo struct {
+	Build     buildinfo.Info `json:"build"`
+	ModelFile string         `json:"model_file,omitempty"`
+	SnacFile  string         `json:"snac_model_file,omitempty"`
+}
+
+// EffectiveConfig is the part of a TTSConfig reported to consumers. Local
+// model paths and devices are left out; ModelFile is the model's file name.
+type EffectiveConfig struct {
+	Model             string  `json:"model,omitempty"`
+	ModelFile         string  `json:"model_file,omitempty"`
+	Voice             string  `json:"voice"`
+	Language          string  `json:"language,omitempty"`
+	Seed              int     `json:"seed"`
+	NGL               int     `json:"ngl"`
+	TopP              float64 `json:"top_p"`
+	RepetitionPenalty float64 `json:"repetition_penalty"`
+	Temperature       float64 `json:"temperature"`
+	Rate              float64 `json:"rate,omitempty"`
+	Pitch             float64 `json:"pitch,omitempty"`
+	Style             string  `json:"style,omitempty"`
+}
+
+// JobEstimate is the reply to a job in dry-run mode: the job was downloaded
+// and validated, and its audio duration and processing time are estimated
+// from the text length instead of synthesizing it.
+type JobEstimate struct {
+	Header     events.EventHeader `json:"header"`
+	PageNumber int                `json:"page_number"`
+	TotalPages int                `json:"total_pages"`
+	// DryRun is always true, so consumers can tell an estimate from a chunk.
+	DryRun     bool `json:"dry_run"`
+	Characters int  `json:"characters"`
+	Words      int  `json:"words"`
+	// EstimatedDurationSeconds is the predicted playing time, after the job's rate.
+	EstimatedDurationSeconds float64 `json:"estimated_duration_seconds"`
+	// EstimatedProcessingSeconds is the predicted synthesis time.
+	EstimatedProcessingSeconds float64         `json:"estimated_processing_seconds"`
+	Config                     EffectiveConfig `json:"config"`
+	// TextStats describes the text; nil unless text reports are enabled.
+	TextStats *TextStats `json:"text_stats,omitempty"`
+}
+
+// ErrorClass identifies the stage at which a job failed.
+type ErrorClass string
+
+// Job failure classes reported in TTSJobFailedEvent.
+const (
+	// ErrorClassInvalidEvent means the message could not be parsed or lacked required fields.
+	ErrorClassInvalidEvent ErrorClass = "invalid_event"
+	// ErrorClassInvalidConfig means the job's model, voice or parameters were rejected.
+	ErrorClassInvalidConfig ErrorClass = "invalid_config"
+	// ErrorClassUnsupportedLanguage means no model serves the job's language.
+	ErrorClassUnsupportedLanguage ErrorClass = "unsupported_language"
+	// ErrorClassInvalidText means the text was not valid UTF-8.
+	ErrorClassInvalidText ErrorClass = "invalid_text"
+	// ErrorClassTextTooLong means the text exceeded the service's length limit.
+	ErrorClassTextTooLong ErrorClass = "text_too_long"
+	// ErrorClassContentRejected means the text was rejected by a content policy.
+	ErrorClassContentRejected ErrorClass = "content_rejected"
+	// ErrorClassCorruptObject means a downloaded object did not match its checksum.
+	ErrorClassCorruptObject ErrorClass = "corrupt_object"
+	// ErrorClassDownload means the text could not be downloaded.
+	ErrorClassDownload ErrorClass = "download"
+	// ErrorClassSynthesis means the backend failed to produce audio.
+	ErrorClassSynthesis ErrorClass = "synthesis"
+	// ErrorClassInvalidAudio means the backend's output was not valid audio.
+	ErrorClassInvalidAudio ErrorClass = "invalid_audio"
+	// ErrorClassQuality means the audio failed the quality gate on every attempt.
+	ErrorClassQuality ErrorClass = "quality"
+	// ErrorClassUpload means the audio could not be uploaded.
+	ErrorClassUpload ErrorClass = "upload"
+	// ErrorClassTimeout means the job ran out of time.
+	ErrorClassTimeout ErrorClass = "timeout"
+	// ErrorClassCancelled means the job was cancelled, e.g. by a shutdown.
+	ErrorClassCancelled ErrorClass = "cancelled"
+	// ErrorClassInternal covers any other failure.
+	ErrorClassInternal ErrorClass = "internal"
+)
+
+// TTSJobFailedEvent is published when a job fails, so orchestrators learn about
+// failures instead of waiting for a reply that never comes. Header, PageNumber
+// and TotalPages are copied from the job and are zero when it could not be parsed.
+type TTSJobFailedEvent struct {
+	Header     events.EventHeader `json:"header"`
+	PageNumber int                `json:"page_number"`
+	TotalPages int                `json:"total_pages"`
+	ErrorClass ErrorClass         `json:"error_class"`
+	Error      string             `json:"error"`
+	// SupportedLanguages lists the languages the service serves when the job
+	// failed with ErrorClassUnsupportedLanguage.
+	SupportedLanguages []string `json:"supported_languages,omitempty"`
+	// Attempt is the delivery attempt that failed; messages delivered outside
+	// JetStream are always on their first attempt.
+	Attempt int `json:"attempt"`
+	// Event is the job message as received: its JSON, or a JSON string when it is not JSON.
+	Event    json.RawMessage `json:"event"`
+	FailedAt time.Time       `json:"failed_at"`
 }
 
 // TTSProcessor defines the interface for a text-to-speech processing engine.
@@ -27,3 +373,110 @@ type TTSProcessor interface {
 	Process(ctx context.Context, text []byte, cfg TTSConfig) ([]byte, error)
 	GetConfig() TTSConfig
 }
+
+// ConfigValidator is implemented by processors that can reject a job's
+// configuration before synthesis, since each backend needs different settings.
+type ConfigValidator interface {
+	ValidateConfig(cfg TTSConfig) error
+}
+
+// ModelResolver maps a requested model name to its base configuration.
+// An empty name selects the default model.
+type ModelResolver interface {
+	ResolveModel(name string) (TTSConfig, error)
+}
+
+// LanguageResolver is implemented by model resolvers that can choose the model
+// and voice for a job that selects a language but no model.
+type LanguageResolver interface {
+	ResolveLanguage(language string) (TTSConfig, error)
+}
+
+// TextFilter screens the text of a job before synthesis. It returns the text
+// to synthesize, which may be redacted, or an error wrapping
+// ErrContentRejected when the text may not be synthesized. Tenant is the
+// job's tenant ID, so policies can differ per tenant.
+type TextFilter interface {
+	Filter(ctx context.Context, tenant string, text []byte) ([]byte, error)
+}
+
+// JobState identifies a stage in the lifecycle of a TTS job.
+type JobState string
+
+// Job lifecycle states recorded in the job status store.
+const (
+	JobStateReceived   JobState = "received"
+	JobStateProcessing JobState = "processing"
+	JobStateCompleted  JobState = "completed"
+	JobStateFailed     JobState = "failed"
+)
+
+// JobStatus is the last known state of the job for one page of a workflow.
+type JobStatus struct {
+	WorkflowID string    `json:"workflowId"`
+	State      JobState  `json:"state"`
+	PageNumber int       `json:"pageNumber"`
+	TotalPages int       `json:"totalPages"`
+	AudioKey   string    `json:"audioKey,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	Progress   *Progress `json:"progress,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// WorkflowStatus aggregates the page statuses of a workflow. State is failed
+// as soon as any page failed, completed once every page completed, and
+// otherwise the most advanced state of the pages still in flight.
+type WorkflowStatus struct {
+	WorkflowID     string      `json:"workflowId"`
+	State          JobState    `json:"state"`
+	TotalPages     int         `json:"totalPages"`
+	PagesCompleted int         `json:"pagesCompleted"`
+	PagesFailed    int         `json:"pagesFailed"`
+	Pages          []JobStatus `json:"pages"`
+	UpdatedAt      time.Time   `json:"updatedAt"`
+}
+
+// JobStatusResponse is the reply sent to job status queries.
+// Exactly one of Status and Error is set.
+type JobStatusResponse struct {
+	Status *WorkflowStatus `json:"status,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// Progress is the inference progress a backend reports while it synthesizes a
+// job. Zero fields were not reported.
+type Progress struct {
+	Tokens          int     `json:"tokens,omitempty"`
+	TokensPerSecond float64 `json:"tokensPerSecond,omitempty"`
+}
+
+// ProgressFunc receives the progress of the job it was registered for.
+type ProgressFunc func(progress Progress)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose processors report their progress to report.
+func WithProgress(ctx context.Context, report ProgressFunc) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+// ReportProgress passes progress to the ProgressFunc of ctx, if it has one.
+func ReportProgress(ctx context.Context, progress Progress) {
+	report, ok := ctx.Value(progressKey{}).(ProgressFunc)
+	if ok && report != nil {
+		report(progress)
+	}
+}
+
+// ManifestStore keeps the latest DocumentManifest of each document.
+type ManifestStore interface {
+	Put(ctx context.Context, manifest DocumentManifest) error
+	Get(ctx context.Context, documentID string) (DocumentManifest, error)
+}
+
+// JobStatusStore defines the interface for persisting job lifecycle transitions.
+// Statuses are stored per page and read back per workflow.
+type JobStatusStore interface {
+	Put(ctx context.Context, status JobStatus) error
+	Get(ctx context.Context, workflowID string) (WorkflowStatus, error)
+}
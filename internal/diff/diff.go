@@ -0,0 +1,105 @@
+// Package diff compares the chunks of a new version of a document with the
+// manifest of its previous version, so that only chunks whose text changed are
+// synthesized again, and keeps the manifests in a NATS KV bucket.
+package diff
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Normalize returns text with runs of whitespace replaced by one space and
+// leading and trailing whitespace removed, so that layout changes such as
+// rewrapped lines do not count as changes.
+func Normalize(text []byte) string {
+	return strings.Join(strings.Fields(string(text)), " ")
+}
+
+// Hash returns the hex-encoded SHA-256 digest of the normalized text.
+func Hash(text []byte) string {
+	digest := sha256.Sum256([]byte(Normalize(text)))
+
+	return hex.EncodeToString(digest[:])
+}
+
+// ConfigHash returns the hex-encoded SHA-256 digest of the settings a
+// document is synthesized with.
+func ConfigHash(cfg core.EffectiveConfig) string {
+	// Marshalling a struct of strings and numbers cannot fail.
+	data, _ := json.Marshal(cfg)
+	digest := sha256.Sum256(data)
+
+	return hex.EncodeToString(digest[:])
+}
+
+// Index finds the audio of the previous version of a document by text hash.
+// Chunks are found wherever they were, so moved chunks are reused too.
+type Index struct {
+	chunks map[string]core.AudioChunkEvent
+}
+
+// NewIndex indexes the chunks of previous when it was synthesized with the
+// settings of configHash. For other settings, the index is empty.
+func NewIndex(previous core.DocumentManifest, configHash string) Index {
+	index := Index{chunks: make(map[string]core.AudioChunkEvent)}
+
+	if previous.ConfigHash != configHash || len(previous.TextHashes) != len(previous.Chunks) {
+		return index
+	}
+
+	for i, textHash := range previous.TextHashes {
+		index.chunks[textHash] = previous.Chunks[i]
+	}
+
+	return index
+}
+
+// Lookup returns the previous audio of the chunk with textHash.
+func (i Index) Lookup(textHash string) (core.AudioChunkEvent, bool) {
+	chunk, ok := i.chunks[textHash]
+
+	return chunk, ok
+}
+
+// Changes summarizes a new version of a document against its previous one.
+type Changes struct {
+	// Unchanged counts the new chunks that reuse previous audio.
+	Unchanged int
+	// Changed counts the new chunks that are synthesized.
+	Changed int
+	// Removed counts the previous chunks that no new chunk reuses.
+	Removed int
+}
+
+// Compare summarizes the chunks with textHashes against the previous version
+// of their document, which was synthesized with the settings of configHash.
+func Compare(previous core.DocumentManifest, configHash string, textHashes []string) Changes {
+	index := NewIndex(previous, configHash)
+	reused := make(map[string]bool)
+
+	var changes Changes
+
+	for _, textHash := range textHashes {
+		_, ok := index.Lookup(textHash)
+		if !ok {
+			changes.Changed++
+
+			continue
+		}
+
+		changes.Unchanged++
+		reused[textHash] = true
+	}
+
+	for _, textHash := range previous.TextHashes {
+		if !reused[textHash] {
+			changes.Removed++
+		}
+	}
+
+	return changes
+}
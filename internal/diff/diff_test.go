@@ -0,0 +1,75 @@
+package diff_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/diff"
+	"github.com/stretchr/testify/assert"
+)
+
+func newManifest(configHash string, texts ...string) core.DocumentManifest {
+	manifest := core.DocumentManifest{
+		DocumentID: "book",
+		ConfigHash: configHash,
+		TextHashes: nil,
+		Chunks:     nil,
+		UpdatedAt:  time.Time{},
+	}
+
+	for _, text := range texts {
+		var chunk core.AudioChunkEvent
+
+		chunk.AudioKey = text + ".wav"
+
+		manifest.TextHashes = append(manifest.TextHashes, diff.Hash([]byte(text)))
+		manifest.Chunks = append(manifest.Chunks, chunk)
+	}
+
+	return manifest
+}
+
+func TestHash(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "One two three.", diff.Normalize([]byte("  One two\n\tthree.\r\n")))
+	assert.Equal(t, diff.Hash([]byte("One two\nthree.")), diff.Hash([]byte(" One  two three. ")), "layout is ignored")
+	assert.NotEqual(t, diff.Hash([]byte("One two three.")), diff.Hash([]byte("One, two three.")))
+
+	var calm, fast core.EffectiveConfig
+
+	calm.Voice = "tara"
+	fast.Voice = "tara"
+	fast.Rate = 1.25
+
+	assert.NotEqual(t, diff.ConfigHash(calm), diff.ConfigHash(fast))
+}
+
+func TestIndex(t *testing.T) {
+	t.Parallel()
+
+	previous := newManifest("config", "First.", "Second.")
+	index := diff.NewIndex(previous, "config")
+
+	chunk, ok := index.Lookup(diff.Hash([]byte("Second.")))
+	assert.True(t, ok)
+	assert.Equal(t, "Second..wav", chunk.AudioKey)
+
+	_, ok = index.Lookup(diff.Hash([]byte("Third.")))
+	assert.False(t, ok)
+
+	_, ok = diff.NewIndex(previous, "other config").Lookup(diff.Hash([]byte("Second.")))
+	assert.False(t, ok, "audio made with other settings is not reused")
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	previous := newManifest("config", "First.", "Second.", "Third.")
+	next := []string{diff.Hash([]byte("Second.")), diff.Hash([]byte("New.")), diff.Hash([]byte("First."))}
+
+	assert.Equal(t, diff.Changes{Unchanged: 2, Changed: 1, Removed: 1}, diff.Compare(previous, "config", next))
+	assert.Equal(t, diff.Changes{Unchanged: 0, Changed: 3, Removed: 3}, diff.Compare(previous, "other config", next))
+	assert.Equal(t, diff.Changes{Unchanged: 0, Changed: 3, Removed: 0}, diff.Compare(core.DocumentManifest{}, "config", next))
+}
@@ -0,0 +1,94 @@
+package diff
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/nats-io/nats.go"
+)
+
+// ErrManifestNotFound is returned when no manifest has been recorded for a document.
+var ErrManifestNotFound = errors.New("document manifest not found")
+
+// NatsManifestStore implements the core.ManifestStore interface using a NATS KV bucket.
+type NatsManifestStore struct {
+	bucket string
+	kv     nats.KeyValue
+}
+
+// New creates and initializes a new NatsManifestStore.
+func New(jetstreamContext nats.JetStreamContext, bucketName string) (*NatsManifestStore, error) {
+	// Use a "create-first" approach.
+	kv, err := jetstreamContext.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:       bucketName,
+		Description:  fmt.Sprintf("Document manifests for the %s bucket.", bucketName),
+		MaxValueSize: 0,
+		History:      1,
+		TTL:          0,
+		MaxBytes:     0,
+		Storage:      nats.FileStorage,
+		Replicas:     1,
+		Placement:    nil,
+		RePublish:    nil,
+		Mirror:       nil,
+		Sources:      nil,
+		Compression:  false,
+	})
+
+	// If the bucket already exists with a different configuration, bind to it.
+	if err != nil {
+		if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+			kv, err = jetstreamContext.KeyValue(bucketName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to bind to existing key-value bucket '%s': %w", bucketName, err)
+			}
+		} else {
+			return nil, fmt.Errorf("failed to create key-value bucket '%s': %w", bucketName, err)
+		}
+	}
+
+	return &NatsManifestStore{
+		bucket: bucketName,
+		kv:     kv,
+	}, nil
+}
+
+// Put records the manifest of a document, replacing its previous version.
+func (s *NatsManifestStore) Put(_ context.Context, manifest core.DocumentManifest) error {
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to marshal manifest for document '%s': %w", manifest.DocumentID, err)
+	}
+
+	_, err = s.kv.Put(manifest.DocumentID, data)
+	if err != nil {
+		return fmt.Errorf("failed to put manifest for document '%s' to bucket '%s': %w", manifest.DocumentID, s.bucket, err)
+	}
+
+	return nil
+}
+
+// Get returns the latest manifest of a document.
+func (s *NatsManifestStore) Get(_ context.Context, documentID string) (core.DocumentManifest, error) {
+	entry, err := s.kv.Get(documentID)
+	if errors.Is(err, nats.ErrKeyNotFound) {
+		return core.DocumentManifest{}, fmt.Errorf("%w: document '%s'", ErrManifestNotFound, documentID)
+	}
+
+	if err != nil {
+		return core.DocumentManifest{}, fmt.Errorf(
+			"failed to read manifest for document '%s' from bucket '%s': %w", documentID, s.bucket, err)
+	}
+
+	var manifest core.DocumentManifest
+
+	err = json.Unmarshal(entry.Value(), &manifest)
+	if err != nil {
+		return core.DocumentManifest{}, fmt.Errorf("failed to unmarshal manifest for document '%s': %w", documentID, err)
+	}
+
+	return manifest, nil
+}
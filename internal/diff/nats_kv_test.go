@@ -0,0 +1,58 @@
+package diff_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/diff"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestStore(t *testing.T) *diff.NatsManifestStore {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	natsConnection, err := nats.Connect(natsServer.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(natsConnection.Close)
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	store, err := diff.New(jetstreamContext, "test-manifests")
+	require.NoError(t, err)
+
+	return store
+}
+
+func TestNatsManifestStore_PutGet(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	_, err := store.Get(ctx, "book")
+	require.ErrorIs(t, err, diff.ErrManifestNotFound)
+
+	first := newManifest("config", "First.", "Second.")
+	first.UpdatedAt = time.Now().UTC().Truncate(time.Millisecond)
+	require.NoError(t, store.Put(ctx, first))
+
+	second := newManifest("config", "First.", "Changed.")
+	second.UpdatedAt = first.UpdatedAt.Add(time.Minute)
+	require.NoError(t, store.Put(ctx, second))
+
+	got, err := store.Get(ctx, "book")
+	require.NoError(t, err)
+	assert.Equal(t, second, got, "the latest version replaces the previous one")
+}
@@ -0,0 +1,281 @@
+// Package golden catches silent quality regressions: it reduces synthesized
+// speech to a few acoustic metrics, which are compared against metrics
+// recorded from a known-good model and settings.
+package golden
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// EnvelopeBins is the number of equal parts of the audio whose levels make up
+// its envelope.
+const EnvelopeBins = 32
+
+// FloorDB is the level reported for silence, in dBFS or LUFS, since JSON has
+// no infinity.
+const FloorDB = -100.0
+
+// Tolerance defaults.
+const (
+	// DefaultDurationRatio allows the duration to differ by 15%.
+	DefaultDurationRatio = 0.15
+	// DefaultLoudnessLU allows the integrated loudness to differ by 3 LU.
+	DefaultLoudnessLU = 3.0
+	// DefaultEnvelopeDB allows the envelope to differ by 6 dB on average.
+	DefaultEnvelopeDB = 6.0
+)
+
+// Golden file errors.
+var (
+	// ErrMismatch indicates audio whose metrics are outside the tolerance.
+	ErrMismatch = errors.New("audio does not match its golden metrics")
+	// ErrInvalidCorpus indicates a corpus that cannot be read or has unnamed
+	// or duplicate cases.
+	ErrInvalidCorpus = errors.New("invalid golden corpus")
+	// ErrInvalidMetrics indicates a metrics file that cannot be read or written.
+	ErrInvalidMetrics = errors.New("invalid golden metrics")
+)
+
+// Case is one text of the corpus, synthesized with fixed settings.
+type Case struct {
+	Name  string `json:"name"`
+	Text  string `json:"text"`
+	Voice string `json:"voice,omitempty"`
+	Seed  int    `json:"seed,omitempty"`
+}
+
+// Metrics describe synthesized speech well enough to notice when a model or
+// its parameters change it.
+type Metrics struct {
+	SampleRate      int     `json:"sample_rate"`
+	Channels        int     `json:"channels"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	// LoudnessLUFS is the integrated loudness, as in ITU-R BS.1770-4.
+	LoudnessLUFS float64 `json:"loudness_lufs"`
+	PeakDB       float64 `json:"peak_db"`
+	// Envelope is the RMS level, in dBFS, of each of EnvelopeBins equal parts
+	// of the audio, so pauses, truncation and babbling shift it.
+	Envelope []float64 `json:"envelope"`
+}
+
+// Tolerance bounds how far metrics may drift from the golden ones. Zero
+// fields use the defaults. Sample rate and channels must match exactly.
+type Tolerance struct {
+	// DurationRatio is the allowed relative difference in duration.
+	DurationRatio float64
+	// LoudnessLU is the allowed difference in loudness and peak level.
+	LoudnessLU float64
+	// EnvelopeDB is the allowed mean absolute difference of the envelopes.
+	EnvelopeDB float64
+}
+
+// Measure returns the metrics of audio.
+func Measure(speech wav.Audio) Metrics {
+	metrics := Metrics{
+		SampleRate:      speech.SampleRate,
+		Channels:        speech.Channels,
+		DurationSeconds: 0,
+		LoudnessLUFS:    floor(audio.Loudness(speech)),
+		PeakDB:          FloorDB,
+		Envelope:        make([]float64, EnvelopeBins),
+	}
+
+	frames := speech.Frames()
+	if frames == 0 || speech.SampleRate <= 0 {
+		for bin := range metrics.Envelope {
+			metrics.Envelope[bin] = FloorDB
+		}
+
+		return metrics
+	}
+
+	metrics.DurationSeconds = round(float64(frames) / float64(speech.SampleRate))
+
+	var peak float64
+	for _, sample := range speech.Samples {
+		peak = max(peak, math.Abs(float64(sample)))
+	}
+
+	metrics.PeakDB = floor(20 * math.Log10(peak))
+
+	for bin := range metrics.Envelope {
+		start := bin * frames / EnvelopeBins * speech.Channels
+		end := max((bin+1)*frames/EnvelopeBins*speech.Channels, start+speech.Channels)
+
+		var sum float64
+		for _, sample := range speech.Samples[start:min(end, len(speech.Samples))] {
+			sum += float64(sample) * float64(sample)
+		}
+
+		metrics.Envelope[bin] = floor(10 * math.Log10(sum/float64(end-start)))
+	}
+
+	return metrics
+}
+
+// floor rounds a level to 0.01 dB and raises it to FloorDB.
+func floor(level float64) float64 {
+	if math.IsNaN(level) || level < FloorDB {
+		return FloorDB
+	}
+
+	return round(level)
+}
+
+// round keeps two decimals, which is all a golden file needs.
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// Compare returns an error wrapping ErrMismatch that lists every metric of got
+// outside tolerance of want.
+func Compare(want, got Metrics, tolerance Tolerance) error {
+	tolerance = tolerance.withDefaults()
+
+	var problems []string
+
+	if got.SampleRate != want.SampleRate {
+		problems = append(problems, fmt.Sprintf("sample rate is %d Hz, want %d Hz", got.SampleRate, want.SampleRate))
+	}
+
+	if got.Channels != want.Channels {
+		problems = append(problems, fmt.Sprintf("channels are %d, want %d", got.Channels, want.Channels))
+	}
+
+	if math.Abs(got.DurationSeconds-want.DurationSeconds) > tolerance.DurationRatio*want.DurationSeconds {
+		problems = append(problems, fmt.Sprintf("duration is %.2fs, want %.2fs ± %.0f%%",
+			got.DurationSeconds, want.DurationSeconds, 100*tolerance.DurationRatio))
+	}
+
+	if math.Abs(got.LoudnessLUFS-want.LoudnessLUFS) > tolerance.LoudnessLU {
+		problems = append(problems, fmt.Sprintf("loudness is %.1f LUFS, want %.1f ± %.1f LU",
+			got.LoudnessLUFS, want.LoudnessLUFS, tolerance.LoudnessLU))
+	}
+
+	if math.Abs(got.PeakDB-want.PeakDB) > tolerance.LoudnessLU {
+		problems = append(problems, fmt.Sprintf("peak is %.1f dBFS, want %.1f ± %.1f dB",
+			got.PeakDB, want.PeakDB, tolerance.LoudnessLU))
+	}
+
+	if len(got.Envelope) != len(want.Envelope) {
+		problems = append(problems, fmt.Sprintf("envelope has %d bins, want %d", len(got.Envelope), len(want.Envelope)))
+	} else if distance := envelopeDistance(want.Envelope, got.Envelope); distance > tolerance.EnvelopeDB {
+		problems = append(problems, fmt.Sprintf("envelope differs by %.1f dB on average, want at most %.1f dB",
+			distance, tolerance.EnvelopeDB))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrMismatch, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// envelopeDistance returns the mean absolute difference of two envelopes of
+// the same length.
+func envelopeDistance(want, got []float64) float64 {
+	if len(want) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for bin := range want {
+		sum += math.Abs(got[bin] - want[bin])
+	}
+
+	return sum / float64(len(want))
+}
+
+// withDefaults fills the zero fields of t.
+func (t Tolerance) withDefaults() Tolerance {
+	if t.DurationRatio == 0 {
+		t.DurationRatio = DefaultDurationRatio
+	}
+
+	if t.LoudnessLU == 0 {
+		t.LoudnessLU = DefaultLoudnessLU
+	}
+
+	if t.EnvelopeDB == 0 {
+		t.EnvelopeDB = DefaultEnvelopeDB
+	}
+
+	return t
+}
+
+// LoadCorpus reads a JSON array of cases from path.
+func LoadCorpus(path string) ([]Case, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the corpus is chosen by the developer
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCorpus, err)
+	}
+
+	var cases []Case
+
+	err = json.Unmarshal(data, &cases)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidCorpus, path, err)
+	}
+
+	names := make(map[string]bool, len(cases))
+
+	for _, item := range cases {
+		if item.Name == "" || item.Text == "" {
+			return nil, fmt.Errorf("%w: every case needs a name and a text", ErrInvalidCorpus)
+		}
+
+		if names[item.Name] {
+			return nil, fmt.Errorf("%w: case %q appears twice", ErrInvalidCorpus, item.Name)
+		}
+
+		names[item.Name] = true
+	}
+
+	return cases, nil
+}
+
+// LoadMetrics reads golden metrics by case name from path. A missing file has
+// no metrics.
+func LoadMetrics(path string) (map[string]Metrics, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the metrics file is chosen by the developer
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]Metrics{}, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidMetrics, err)
+	}
+
+	metrics := map[string]Metrics{}
+
+	err = json.Unmarshal(data, &metrics)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrInvalidMetrics, path, err)
+	}
+
+	return metrics, nil
+}
+
+// SaveMetrics writes golden metrics by case name to path, indented and with
+// sorted names so that changes review well.
+func SaveMetrics(path string, metrics map[string]Metrics) error {
+	data, err := json.MarshalIndent(metrics, "", "  ")
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMetrics, err)
+	}
+
+	err = os.WriteFile(path, append(data, '\n'), 0o600)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidMetrics, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package golden_test
+
+import (
+	"context"
+	"flag"
+	"math"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/golden"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// update records the metrics of the current model as the golden ones.
+var update = flag.Bool("update", false, "write testdata/metrics.json from the current model")
+
+const (
+	corpusFile  = "testdata/corpus.json"
+	metricsFile = "testdata/metrics.json"
+)
+
+// tone returns seconds of a 440 Hz tone at amplitude, followed by as many
+// seconds of silence.
+func tone(seconds, amplitude float64) wav.Audio {
+	const sampleRate = 24000
+
+	samples := make([]float32, int(2*seconds*sampleRate))
+	for index := range int(seconds * sampleRate) {
+		samples[index] = float32(amplitude * math.Sin(2*math.Pi*440*float64(index)/sampleRate))
+	}
+
+	return wav.Audio{SampleRate: sampleRate, Channels: 1, Samples: samples}
+}
+
+func TestMeasure(t *testing.T) {
+	t.Parallel()
+
+	metrics := golden.Measure(tone(1, 0.5))
+
+	assert.Equal(t, 24000, metrics.SampleRate)
+	assert.Equal(t, 1, metrics.Channels)
+	assert.InDelta(t, 2.0, metrics.DurationSeconds, 1e-9)
+	assert.InDelta(t, -6.02, metrics.PeakDB, 0.01)
+	assert.InDelta(t, -10.4, metrics.LoudnessLUFS, 0.1, "the silence is gated out")
+	require.Len(t, metrics.Envelope, golden.EnvelopeBins)
+	assert.InDelta(t, -9.03, metrics.Envelope[0], 0.05, "a sine's RMS is 3 dB below its peak")
+	assert.InDelta(t, golden.FloorDB, metrics.Envelope[golden.EnvelopeBins-1], 1e-9)
+
+	empty := golden.Measure(wav.Audio{SampleRate: 24000, Channels: 1, Samples: nil})
+	assert.InDelta(t, golden.FloorDB, empty.LoudnessLUFS, 1e-9)
+	assert.InDelta(t, golden.FloorDB, empty.Envelope[0], 1e-9)
+}
+
+func TestCompare(t *testing.T) {
+	t.Parallel()
+
+	want := golden.Measure(tone(1, 0.5))
+	tolerance := golden.Tolerance{DurationRatio: 0, LoudnessLU: 0, EnvelopeDB: 0}
+
+	require.NoError(t, golden.Compare(want, golden.Measure(tone(1.05, 0.45)), tolerance), "small drift passes")
+
+	err := golden.Compare(want, golden.Measure(tone(1.5, 0.5)), tolerance)
+	require.ErrorIs(t, err, golden.ErrMismatch)
+	assert.Contains(t, err.Error(), "duration is 3.00s")
+
+	err = golden.Compare(want, golden.Measure(tone(1, 0.1)), tolerance)
+	require.ErrorIs(t, err, golden.ErrMismatch)
+	assert.Contains(t, err.Error(), "loudness")
+	assert.Contains(t, err.Error(), "peak")
+	assert.Contains(t, err.Error(), "envelope")
+
+	resampled := want
+	resampled.SampleRate = 22050
+	require.ErrorIs(t, golden.Compare(want, resampled, tolerance), golden.ErrMismatch)
+}
+
+func TestMetrics_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "metrics.json")
+
+	metrics, err := golden.LoadMetrics(path)
+	require.NoError(t, err, "a missing file has no metrics")
+	assert.Empty(t, metrics)
+
+	saved := map[string]golden.Metrics{"tone": golden.Measure(tone(0.5, 0.25))}
+	require.NoError(t, golden.SaveMetrics(path, saved))
+
+	metrics, err = golden.LoadMetrics(path)
+	require.NoError(t, err)
+	assert.Equal(t, saved, metrics)
+}
+
+func TestLoadCorpus(t *testing.T) {
+	t.Parallel()
+
+	cases, err := golden.LoadCorpus(corpusFile)
+	require.NoError(t, err)
+	assert.NotEmpty(t, cases)
+
+	_, err = golden.LoadMetrics(metricsFile)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "corpus.json")
+	require.NoError(t, os.WriteFile(path, []byte(`[{"name": "a", "text": "A."}, {"name": "a", "text": "B."}]`), 0o600))
+
+	_, err = golden.LoadCorpus(path)
+	require.ErrorIs(t, err, golden.ErrInvalidCorpus)
+}
+
+// TestRegression synthesizes the corpus with chatllm and compares every case
+// against its golden metrics. It needs chatllm on PATH and the models named
+// by TTS_GOLDEN_MODEL and TTS_GOLDEN_SNAC_MODEL; TTS_GOLDEN_NGL offloads
+// layers to the GPU. Run it with -update to record new golden metrics after
+// an intended change.
+func TestRegression(t *testing.T) {
+	modelPath, snacModelPath := os.Getenv("TTS_GOLDEN_MODEL"), os.Getenv("TTS_GOLDEN_SNAC_MODEL")
+	if modelPath == "" || snacModelPath == "" {
+		t.Skip("set TTS_GOLDEN_MODEL and TTS_GOLDEN_SNAC_MODEL to run the golden-audio suite")
+	}
+
+	_, err := exec.LookPath("chatllm")
+	require.NoError(t, err, "chatllm must be on PATH")
+
+	ngl, err := strconv.Atoi(os.Getenv("TTS_GOLDEN_NGL"))
+	if err != nil {
+		ngl = 0
+	}
+
+	cases, err := golden.LoadCorpus(corpusFile)
+	require.NoError(t, err)
+
+	want, err := golden.LoadMetrics(metricsFile)
+	require.NoError(t, err)
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         modelPath,
+		SnacModelPath:     snacModelPath,
+		Voice:             "",
+		Seed:              0,
+		NGL:               ngl,
+		TopP:              0.95,
+		RepetitionPenalty: 1.1,
+		Temperature:       0.7,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	got := make(map[string]golden.Metrics, len(cases))
+
+	for _, item := range cases {
+		t.Run(item.Name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+			defer cancel()
+
+			job := cfg
+			job.Voice = item.Voice
+			job.Seed = item.Seed
+
+			data, err := processor.Process(ctx, []byte(item.Text), job)
+			require.NoError(t, err)
+
+			speech, err := wav.Decode(data)
+			require.NoError(t, err)
+
+			got[item.Name] = golden.Measure(speech)
+
+			if *update {
+				return
+			}
+
+			metrics, ok := want[item.Name]
+			require.True(t, ok, "no golden metrics for %q, run the suite with -update", item.Name)
+			require.NoError(t, golden.Compare(metrics, got[item.Name], golden.Tolerance{DurationRatio: 0, LoudnessLU: 0, EnvelopeDB: 0}))
+		})
+	}
+
+	if *update {
+		require.NoError(t, golden.SaveMetrics(metricsFile, got))
+	}
+}
@@ -0,0 +1,213 @@
+// Package gpu detects available GPUs, derives a safe number of model layers to
+// offload to each, and limits concurrent inference jobs per device.
+package gpu
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// Vendor identifies the GPU tooling a device was detected with.
+type Vendor string
+
+// Supported vendors.
+const (
+	VendorNVIDIA Vendor = "nvidia"
+	VendorAMD    Vendor = "amd"
+)
+
+const bytesPerMiB = 1024 * 1024
+
+// Static errors.
+var (
+	ErrNoGPU           = errors.New("no GPU detected")
+	ErrUnexpectedQuery = errors.New("unexpected GPU query output")
+)
+
+// Device describes one GPU as reported by the vendor tooling.
+type Device struct {
+	Index              int
+	Vendor             Vendor
+	MemoryTotalMiB     uint64
+	MemoryFreeMiB      uint64
+	UtilizationPercent float64
+}
+
+// CommandRunner executes an external command and returns its standard output.
+type CommandRunner func(ctx context.Context, name string, args ...string) ([]byte, error)
+
+// ExecRunner runs commands with os/exec.
+func ExecRunner(ctx context.Context, name string, args ...string) ([]byte, error) {
+	// #nosec G204 -- only the fixed nvidia-smi/rocm-smi invocations below are run
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w", name, err)
+	}
+
+	return output, nil
+}
+
+// Detect queries nvidia-smi, then rocm-smi, and returns the devices found.
+// It returns ErrNoGPU when neither tool reports a device.
+func Detect(ctx context.Context, run CommandRunner) ([]Device, error) {
+	output, err := run(ctx, "nvidia-smi",
+		"--query-gpu=index,memory.total,memory.free,utilization.gpu",
+		"--format=csv,noheader,nounits",
+	)
+	if err == nil {
+		devices, parseErr := ParseNvidiaSMI(output)
+		if parseErr == nil && len(devices) > 0 {
+			return devices, nil
+		}
+	}
+
+	output, err = run(ctx, "rocm-smi", "--showmeminfo", "vram", "--showuse", "--csv")
+	if err == nil {
+		devices, parseErr := ParseRocmSMI(output)
+		if parseErr == nil && len(devices) > 0 {
+			return devices, nil
+		}
+	}
+
+	return nil, ErrNoGPU
+}
+
+// ParseNvidiaSMI parses the output of
+// "nvidia-smi --query-gpu=index,memory.total,memory.free,utilization.gpu --format=csv,noheader,nounits".
+func ParseNvidiaSMI(output []byte) ([]Device, error) {
+	records, err := readCSV(output)
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(records))
+
+	for _, record := range records {
+		const nvidiaFields = 4
+		if len(record) != nvidiaFields {
+			return nil, fmt.Errorf("%w: nvidia-smi line has %d fields", ErrUnexpectedQuery, len(record))
+		}
+
+		index, indexErr := strconv.Atoi(record[0])
+		total, totalErr := strconv.ParseUint(record[1], 10, 64)
+		free, freeErr := strconv.ParseUint(record[2], 10, 64)
+		utilization, utilErr := parseUtilization(record[3])
+
+		parseErr := errors.Join(indexErr, totalErr, freeErr, utilErr)
+		if parseErr != nil {
+			return nil, fmt.Errorf("%w: nvidia-smi: %w", ErrUnexpectedQuery, parseErr)
+		}
+
+		devices = append(devices, Device{
+			Index:              index,
+			Vendor:             VendorNVIDIA,
+			MemoryTotalMiB:     total,
+			MemoryFreeMiB:      free,
+			UtilizationPercent: utilization,
+		})
+	}
+
+	return devices, nil
+}
+
+// ParseRocmSMI parses the CSV output of "rocm-smi --showmeminfo vram --showuse --csv".
+// Columns are located by header name because their order varies between releases.
+func ParseRocmSMI(output []byte) ([]Device, error) {
+	records, err := readCSV(output)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(records) < 2 {
+		return nil, fmt.Errorf("%w: rocm-smi returned no devices", ErrUnexpectedQuery)
+	}
+
+	deviceCol, totalCol, usedCol, useCol := -1, -1, -1, -1
+
+	for col, name := range records[0] {
+		switch {
+		case name == "device":
+			deviceCol = col
+		case strings.HasPrefix(name, "VRAM Total Memory"):
+			totalCol = col
+		case strings.HasPrefix(name, "VRAM Total Used Memory"):
+			usedCol = col
+		case strings.HasPrefix(name, "GPU use"):
+			useCol = col
+		}
+	}
+
+	if deviceCol < 0 || totalCol < 0 || usedCol < 0 {
+		return nil, fmt.Errorf("%w: rocm-smi header %v", ErrUnexpectedQuery, records[0])
+	}
+
+	devices := make([]Device, 0, len(records)-1)
+
+	for _, record := range records[1:] {
+		if len(record) != len(records[0]) {
+			return nil, fmt.Errorf("%w: rocm-smi line has %d fields", ErrUnexpectedQuery, len(record))
+		}
+
+		index, indexErr := strconv.Atoi(strings.TrimPrefix(record[deviceCol], "card"))
+		total, totalErr := strconv.ParseUint(record[totalCol], 10, 64)
+		used, usedErr := strconv.ParseUint(record[usedCol], 10, 64)
+
+		var (
+			utilization float64
+			utilErr     error
+		)
+
+		if useCol >= 0 {
+			utilization, utilErr = parseUtilization(record[useCol])
+		}
+
+		parseErr := errors.Join(indexErr, totalErr, usedErr, utilErr)
+		if parseErr != nil {
+			return nil, fmt.Errorf("%w: rocm-smi: %w", ErrUnexpectedQuery, parseErr)
+		}
+
+		devices = append(devices, Device{
+			Index:              index,
+			Vendor:             VendorAMD,
+			MemoryTotalMiB:     total / bytesPerMiB,
+			MemoryFreeMiB:      (total - min(used, total)) / bytesPerMiB,
+			UtilizationPercent: utilization,
+		})
+	}
+
+	return devices, nil
+}
+
+func readCSV(output []byte) ([][]string, error) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimSpace(output)))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnexpectedQuery, err)
+	}
+
+	return records, nil
+}
+
+// parseUtilization accepts "37" as well as "[N/A]", which is reported as zero.
+func parseUtilization(field string) (float64, error) {
+	field = strings.TrimSpace(field)
+	if strings.Contains(field, "N/A") {
+		return 0, nil
+	}
+
+	value, err := strconv.ParseFloat(field, 64)
+	if err != nil {
+		return 0, fmt.Errorf("bad utilization '%s': %w", field, err)
+	}
+
+	return value, nil
+}
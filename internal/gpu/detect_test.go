@@ -0,0 +1,78 @@
+// Package gpu_test tests GPU detection and slot management.
+package gpu_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/gpu"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errToolMissing = errors.New("executable file not found")
+
+func TestParseNvidiaSMI(t *testing.T) {
+	t.Parallel()
+
+	devices, err := gpu.ParseNvidiaSMI([]byte("0, 24564, 20100, 37\n1, 12288, 11000, [N/A]\n"))
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+
+	assert.Equal(t, gpu.Device{
+		Index: 0, Vendor: gpu.VendorNVIDIA, MemoryTotalMiB: 24564, MemoryFreeMiB: 20100, UtilizationPercent: 37,
+	}, devices[0])
+	assert.Equal(t, 1, devices[1].Index)
+	assert.Zero(t, devices[1].UtilizationPercent)
+
+	_, err = gpu.ParseNvidiaSMI([]byte("0, lots, 1, 2\n"))
+	require.ErrorIs(t, err, gpu.ErrUnexpectedQuery)
+}
+
+func TestParseRocmSMI(t *testing.T) {
+	t.Parallel()
+
+	output := "device,GPU use (%),VRAM Total Memory (B),VRAM Total Used Memory (B)\n" +
+		"card0,12,17163091968,1073741824\n"
+
+	devices, err := gpu.ParseRocmSMI([]byte(output))
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+
+	assert.Equal(t, gpu.Device{
+		Index: 0, Vendor: gpu.VendorAMD, MemoryTotalMiB: 16368, MemoryFreeMiB: 15344, UtilizationPercent: 12,
+	}, devices[0])
+
+	_, err = gpu.ParseRocmSMI([]byte("device,Temperature\ncard0,40\n"))
+	require.ErrorIs(t, err, gpu.ErrUnexpectedQuery)
+}
+
+func TestDetect_FallsBackToRocm(t *testing.T) {
+	t.Parallel()
+
+	run := func(_ context.Context, name string, _ ...string) ([]byte, error) {
+		if name == "nvidia-smi" {
+			return nil, errToolMissing
+		}
+
+		return []byte("device,VRAM Total Memory (B),VRAM Total Used Memory (B)\ncard1,8589934592,0\n"), nil
+	}
+
+	devices, err := gpu.Detect(context.Background(), run)
+	require.NoError(t, err)
+	require.Len(t, devices, 1)
+	assert.Equal(t, 1, devices[0].Index)
+	assert.Equal(t, gpu.VendorAMD, devices[0].Vendor)
+}
+
+func TestDetect_NoGPU(t *testing.T) {
+	t.Parallel()
+
+	run := func(context.Context, string, ...string) ([]byte, error) {
+		return nil, errToolMissing
+	}
+
+	_, err := gpu.Detect(context.Background(), run)
+	require.ErrorIs(t, err, gpu.ErrNoGPU)
+}
@@ -0,0 +1,277 @@
+package gpu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/metrics"
+)
+
+// Defaults applied to zero-valued Config fields.
+const (
+	DefaultMaxJobsPerGPU   = 1
+	DefaultVRAMFraction    = 0.9
+	DefaultRefreshInterval = 30 * time.Second
+)
+
+// ErrModelLayersRequired indicates that NGL cannot be derived without the model's layer count.
+var ErrModelLayersRequired = errors.New("model layer count must be positive")
+
+// Config controls how the Manager shares GPUs between jobs.
+type Config struct {
+	// MaxJobsPerGPU limits concurrent chatllm executions per device.
+	MaxJobsPerGPU int
+	// VRAMFraction is the share of each device's memory the service may use.
+	VRAMFraction float64
+	// ReserveMiB is held back per device for the SNAC decoder, KV cache and driver overhead.
+	ReserveMiB uint64
+	// RefreshInterval controls how often device memory and utilization are re-read.
+	RefreshInterval time.Duration
+}
+
+// Lease is a reserved execution slot on a device. Release must be called exactly once.
+type Lease struct {
+	// Device is the device ID to expose to the backend; empty when running on CPU.
+	Device string
+	// NGL is the number of layers that fit in the slot's share of VRAM.
+	NGL     int
+	release func()
+}
+
+// Release returns the slot to the manager.
+func (l Lease) Release() {
+	if l.release != nil {
+		l.release()
+	}
+}
+
+// Manager tracks GPUs and hands out per-device execution slots.
+type Manager struct {
+	mu       sync.Mutex
+	devices  []Device
+	active   []int
+	released chan struct{}
+	cfg      Config
+	run      CommandRunner
+	registry *metrics.Registry
+	log      *logger.Logger
+}
+
+// NewManager detects GPUs and creates a Manager. When no GPU is found the
+// manager still works and hands out CPU-only leases with NGL 0.
+func NewManager(
+	ctx context.Context,
+	cfg Config,
+	run CommandRunner,
+	registry *metrics.Registry,
+	log *logger.Logger,
+) (*Manager, error) {
+	if cfg.MaxJobsPerGPU <= 0 {
+		cfg.MaxJobsPerGPU = DefaultMaxJobsPerGPU
+	}
+
+	if cfg.VRAMFraction <= 0 || cfg.VRAMFraction > 1 {
+		cfg.VRAMFraction = DefaultVRAMFraction
+	}
+
+	if cfg.RefreshInterval <= 0 {
+		cfg.RefreshInterval = DefaultRefreshInterval
+	}
+
+	devices, err := Detect(ctx, run)
+	if err != nil {
+		if !errors.Is(err, ErrNoGPU) {
+			return nil, err
+		}
+
+		log.Warn("No GPU detected; chatllm will run on CPU with NGL 0")
+	}
+
+	manager := &Manager{
+		mu:       sync.Mutex{},
+		devices:  devices,
+		active:   make([]int, len(devices)),
+		released: make(chan struct{}),
+		cfg:      cfg,
+		run:      run,
+		registry: registry,
+		log:      log,
+	}
+	manager.publishMetrics()
+
+	for _, device := range devices {
+		log.Info("Detected %s GPU %d with %d MiB total, %d MiB free",
+			device.Vendor, device.Index, device.MemoryTotalMiB, device.MemoryFreeMiB)
+	}
+
+	return manager, nil
+}
+
+// Devices returns a copy of the last known device state.
+func (m *Manager) Devices() []Device {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]Device(nil), m.devices...)
+}
+
+// Acquire blocks until a slot is free on some device, preferring the least
+// loaded one, and returns a lease with the NGL that is safe for a model of
+// modelBytes with modelLayers layers on that device.
+func (m *Manager) Acquire(ctx context.Context, modelBytes int64, modelLayers int) (Lease, error) {
+	for {
+		m.mu.Lock()
+
+		if len(m.devices) == 0 {
+			m.mu.Unlock()
+
+			return Lease{Device: "", NGL: 0, release: func() {}}, nil
+		}
+
+		slot := m.pickDevice()
+		if slot >= 0 {
+			m.active[slot]++
+			device := m.devices[slot]
+			m.mu.Unlock()
+			m.publishMetrics()
+
+			var once sync.Once
+
+			return Lease{
+				Device:  strconv.Itoa(device.Index),
+				NGL:     m.SafeNGL(device, modelBytes, modelLayers),
+				release: func() { once.Do(func() { m.release(slot) }) },
+			}, nil
+		}
+
+		wait := m.released
+		m.mu.Unlock()
+
+		select {
+		case <-ctx.Done():
+			return Lease{Device: "", NGL: 0, release: nil}, fmt.Errorf("waiting for a GPU slot: %w", ctx.Err())
+		case <-wait:
+		}
+	}
+}
+
+// pickDevice returns the device with the fewest active jobs that still has a
+// free slot, or -1. The caller must hold m.mu.
+func (m *Manager) pickDevice() int {
+	best := -1
+
+	for slot := range m.devices {
+		if m.active[slot] >= m.cfg.MaxJobsPerGPU {
+			continue
+		}
+
+		if best < 0 || m.active[slot] < m.active[best] {
+			best = slot
+		}
+	}
+
+	return best
+}
+
+func (m *Manager) release(slot int) {
+	m.mu.Lock()
+	m.active[slot]--
+	close(m.released)
+	m.released = make(chan struct{})
+	m.mu.Unlock()
+	m.publishMetrics()
+}
+
+// SafeNGL returns how many of the model's layers fit in the memory a new job
+// may use on the device. Every concurrent chatllm process loads its own copy of
+// the offloaded layers, so a job gets at most one slot's share of the usable
+// memory, and never more than the device reported free at the last refresh.
+func (m *Manager) SafeNGL(device Device, modelBytes int64, modelLayers int) int {
+	if modelBytes <= 0 || modelLayers <= 0 {
+		return 0
+	}
+
+	usableMiB := float64(device.MemoryTotalMiB)*m.cfg.VRAMFraction - float64(m.cfg.ReserveMiB)
+	shareMiB := usableMiB / float64(m.cfg.MaxJobsPerGPU)
+	freeMiB := float64(device.MemoryFreeMiB) - float64(m.cfg.ReserveMiB)
+
+	slotMiB := min(shareMiB, freeMiB)
+	if slotMiB <= 0 {
+		return 0
+	}
+
+	layerBytes := float64(modelBytes) / float64(modelLayers)
+
+	return min(int(slotMiB*bytesPerMiB/layerBytes), modelLayers)
+}
+
+// Run refreshes device state periodically until the context is cancelled.
+func (m *Manager) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := m.Refresh(ctx)
+			if err != nil {
+				m.log.Warn("Failed to refresh GPU state: %v", err)
+			}
+		}
+	}
+}
+
+// Refresh re-reads memory and utilization for the known devices.
+func (m *Manager) Refresh(ctx context.Context) error {
+	m.mu.Lock()
+	hasDevices := len(m.devices) > 0
+	m.mu.Unlock()
+
+	if !hasDevices {
+		return nil
+	}
+
+	devices, err := Detect(ctx, m.run)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+
+	for _, fresh := range devices {
+		for slot := range m.devices {
+			if m.devices[slot].Index == fresh.Index {
+				m.devices[slot] = fresh
+			}
+		}
+	}
+
+	m.mu.Unlock()
+	m.publishMetrics()
+
+	return nil
+}
+
+func (m *Manager) publishMetrics() {
+	if m.registry == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for slot, device := range m.devices {
+		prefix := fmt.Sprintf("gpu.%d.", device.Index)
+		m.registry.SetGauge(prefix+"memory_total_mib", float64(device.MemoryTotalMiB))
+		m.registry.SetGauge(prefix+"memory_free_mib", float64(device.MemoryFreeMiB))
+		m.registry.SetGauge(prefix+"utilization_percent", device.UtilizationPercent)
+		m.registry.SetGauge(prefix+"active_jobs", float64(m.active[slot]))
+	}
+}
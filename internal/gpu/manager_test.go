@@ -0,0 +1,128 @@
+package gpu_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/gpu"
+	"github.com/book-expert/tts-service/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const mib = 1024 * 1024
+
+func newTestManager(t *testing.T, nvidiaOutput string, maxJobs int) (*gpu.Manager, *metrics.Registry) {
+	t.Helper()
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	run := func(_ context.Context, name string, _ ...string) ([]byte, error) {
+		if name == "nvidia-smi" && nvidiaOutput != "" {
+			return []byte(nvidiaOutput), nil
+		}
+
+		return nil, errToolMissing
+	}
+
+	registry := metrics.NewRegistry()
+
+	manager, err := gpu.NewManager(context.Background(), gpu.Config{
+		MaxJobsPerGPU:   maxJobs,
+		VRAMFraction:    1,
+		ReserveMiB:      1024,
+		RefreshInterval: time.Minute,
+	}, run, registry, testLogger)
+	require.NoError(t, err)
+
+	return manager, registry
+}
+
+func TestManager_SafeNGL(t *testing.T) {
+	t.Parallel()
+
+	manager, _ := newTestManager(t, "0, 9216, 9000, 0\n", 2)
+	device := manager.Devices()[0]
+
+	// (9216 MiB - 1024 MiB reserve) / 2 slots = 4096 MiB per job; 28 layers of 256 MiB.
+	assert.Equal(t, 16, manager.SafeNGL(device, 28*256*mib, 28))
+	// The same share holds 8 layers of a model with 14 layers of 512 MiB.
+	assert.Equal(t, 8, manager.SafeNGL(device, 14*512*mib, 14))
+	// A small model fits entirely.
+	assert.Equal(t, 28, manager.SafeNGL(device, 28*mib, 28))
+	// An unknown model size or layer count offloads nothing.
+	assert.Zero(t, manager.SafeNGL(device, 0, 28))
+	assert.Zero(t, manager.SafeNGL(device, 28*mib, 0))
+}
+
+func TestManager_SafeNGLUsesFreeMemory(t *testing.T) {
+	t.Parallel()
+
+	// Another process holds most of the device: 3072 MiB free - 1024 MiB reserve
+	// leaves 2048 MiB, less than the 4096 MiB slot share.
+	manager, _ := newTestManager(t, "0, 9216, 3072, 0\n", 2)
+	device := manager.Devices()[0]
+
+	assert.Equal(t, 8, manager.SafeNGL(device, 28*256*mib, 28))
+
+	// Nothing is offloaded when the reserve is not free.
+	manager, _ = newTestManager(t, "0, 9216, 512, 0\n", 2)
+	assert.Zero(t, manager.SafeNGL(manager.Devices()[0], 28*256*mib, 28))
+}
+
+func TestManager_AcquireLimitsJobsPerGPU(t *testing.T) {
+	t.Parallel()
+
+	manager, registry := newTestManager(t, "0, 9216, 9000, 0\n1, 9216, 9000, 0\n", 1)
+
+	first, err := manager.Acquire(context.Background(), 28*mib, 28)
+	require.NoError(t, err)
+
+	second, err := manager.Acquire(context.Background(), 28*mib, 28)
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Device, second.Device, "jobs should spread across GPUs")
+	assert.InDelta(t, 1, registry.Snapshot().Gauges["gpu.0.active_jobs"], 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err = manager.Acquire(ctx, 28*mib, 28)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "all slots are taken")
+
+	acquired := make(chan gpu.Lease, 1)
+
+	go func() {
+		lease, acquireErr := manager.Acquire(context.Background(), 28*mib, 28)
+		if acquireErr == nil {
+			acquired <- lease
+		}
+	}()
+
+	first.Release()
+	first.Release() // Releasing twice must not free a second slot.
+
+	select {
+	case lease := <-acquired:
+		assert.Equal(t, first.Device, lease.Device)
+		lease.Release()
+	case <-time.After(time.Second):
+		t.Fatal("waiting Acquire was not woken by Release")
+	}
+
+	second.Release()
+}
+
+func TestManager_NoGPU(t *testing.T) {
+	t.Parallel()
+
+	manager, _ := newTestManager(t, "", 1)
+
+	lease, err := manager.Acquire(context.Background(), 28*mib, 28)
+	require.NoError(t, err)
+	assert.Empty(t, lease.Device)
+	assert.Zero(t, lease.NGL)
+	lease.Release()
+}
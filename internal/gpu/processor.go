@@ -0,0 +1,78 @@
+package gpu
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Processor wraps a chatllm core.TTSProcessor so that every job runs in a GPU
+// slot with an automatically derived NGL, replacing the static NGL setting.
+type Processor struct {
+	inner       core.TTSProcessor
+	manager     *Manager
+	modelLayers int
+}
+
+// NewProcessor creates a GPU-aware wrapper around inner, whose model has
+// modelLayers transformer layers.
+func NewProcessor(inner core.TTSProcessor, manager *Manager, modelLayers int) (*Processor, error) {
+	if modelLayers <= 0 {
+		return nil, ErrModelLayersRequired
+	}
+
+	return &Processor{
+		inner:       inner,
+		manager:     manager,
+		modelLayers: modelLayers,
+	}, nil
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig delegates to the wrapped processor when it validates jobs.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateConfig(cfg)
+}
+
+// Process waits for a GPU slot, overrides NGL and Device for the job, and
+// delegates to the wrapped processor.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	modelPath := cfg.ModelPath
+	if modelPath == "" {
+		modelPath = p.inner.GetConfig().ModelPath
+	}
+
+	var modelBytes int64
+
+	info, err := os.Stat(modelPath)
+	if err == nil {
+		modelBytes = info.Size()
+	}
+
+	lease, err := p.manager.Acquire(ctx, modelBytes, p.modelLayers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire GPU slot: %w", err)
+	}
+	defer lease.Release()
+
+	cfg.NGL = lease.NGL
+	cfg.Device = lease.Device
+
+	audio, err := p.inner.Process(ctx, text, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("processing on GPU '%s' with NGL %d: %w", lease.Device, lease.NGL, err)
+	}
+
+	return audio, nil
+}
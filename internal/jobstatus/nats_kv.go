@@ -0,0 +1,151 @@
+// Package jobstatus provides a NATS KV-based implementation of the JobStatusStore interface.
+package jobstatus
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/nats-io/nats.go"
+)
+
+var (
+	// ErrJobNotFound is returned when no status has been recorded for a workflow.
+	ErrJobNotFound = errors.New("job status not found")
+	// ErrInvalidWorkflowID is returned for a workflow ID that cannot be one
+	// token of a KV key.
+	ErrInvalidWorkflowID = errors.New("invalid workflow ID")
+)
+
+// NatsStatusStore implements the core.JobStatusStore interface using a NATS KV bucket.
+type NatsStatusStore struct {
+	bucket string
+	kv     nats.KeyValue
+}
+
+// New creates and initializes a new NatsStatusStore.
+func New(jetstreamContext nats.JetStreamContext, bucketName string) (*NatsStatusStore, error) {
+	// Use a "create-first" approach.
+	kv, err := jetstreamContext.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:       bucketName,
+		Description:  fmt.Sprintf("Job status for the %s bucket.", bucketName),
+		MaxValueSize: 0,
+		History:      1,
+		TTL:          0,
+		MaxBytes:     0,
+		Storage:      nats.FileStorage,
+		Replicas:     1,
+		Placement:    nil,
+		RePublish:    nil,
+		Mirror:       nil,
+		Sources:      nil,
+		Compression:  false,
+	})
+
+	// If the bucket already exists with a different configuration, bind to it.
+	if err != nil {
+		if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+			kv, err = jetstreamContext.KeyValue(bucketName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to bind to existing key-value bucket '%s': %w", bucketName, err)
+			}
+		} else {
+			return nil, fmt.Errorf("failed to create key-value bucket '%s': %w", bucketName, err)
+		}
+	}
+
+	return &NatsStatusStore{
+		bucket: bucketName,
+		kv:     kv,
+	}, nil
+}
+
+// ValidateWorkflowID checks that a workflow ID is one token of a KV key: letters,
+// digits and '-', '_', '=' or '/'. Dots, wildcards and spaces would make its
+// keys match those of other workflows, or be invalid.
+func ValidateWorkflowID(workflowID string) error {
+	if workflowID == "" {
+		return fmt.Errorf("%w: it is empty", ErrInvalidWorkflowID)
+	}
+
+	for _, char := range workflowID {
+		valid := (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') ||
+			strings.ContainsRune("-_=/", char)
+		if !valid {
+			return fmt.Errorf("%w: %q contains %q", ErrInvalidWorkflowID, workflowID, char)
+		}
+	}
+
+	return nil
+}
+
+// pageKey is the KV key of one page of a workflow. Keeping pages under their
+// own keys stops concurrent pages from overwriting each other's status.
+func pageKey(workflowID string, pageNumber int) string {
+	return fmt.Sprintf("%s.%d", workflowID, pageNumber)
+}
+
+// Put records the status of one page, replacing any previous status for that page.
+func (s *NatsStatusStore) Put(_ context.Context, status core.JobStatus) error {
+	err := ValidateWorkflowID(status.WorkflowID)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to marshal status for workflow '%s': %w", status.WorkflowID, err)
+	}
+
+	_, err = s.kv.Put(pageKey(status.WorkflowID, status.PageNumber), data)
+	if err != nil {
+		return fmt.Errorf("failed to put status for workflow '%s' to bucket '%s': %w", status.WorkflowID, s.bucket, err)
+	}
+
+	return nil
+}
+
+// Get returns the aggregated status of every recorded page of a workflow.
+func (s *NatsStatusStore) Get(ctx context.Context, workflowID string) (core.WorkflowStatus, error) {
+	err := ValidateWorkflowID(workflowID)
+	if err != nil {
+		return core.WorkflowStatus{}, err
+	}
+
+	watcher, err := s.kv.Watch(workflowID+".*", nats.IgnoreDeletes(), nats.Context(ctx))
+	if err != nil {
+		return core.WorkflowStatus{}, fmt.Errorf(
+			"failed to read status for workflow '%s' from bucket '%s': %w", workflowID, s.bucket, err)
+	}
+
+	defer func() {
+		_ = watcher.Stop()
+	}()
+
+	var pages []core.JobStatus
+
+	// The watcher delivers the current value of every matching key, then nil.
+	for entry := range watcher.Updates() {
+		if entry == nil {
+			break
+		}
+
+		var status core.JobStatus
+
+		err = json.Unmarshal(entry.Value(), &status)
+		if err != nil {
+			return core.WorkflowStatus{}, fmt.Errorf("failed to unmarshal status '%s': %w", entry.Key(), err)
+		}
+
+		pages = append(pages, status)
+	}
+
+	if len(pages) == 0 {
+		return core.WorkflowStatus{}, fmt.Errorf("%w: workflow '%s'", ErrJobNotFound, workflowID)
+	}
+
+	return Summarize(workflowID, pages), nil
+}
@@ -0,0 +1,161 @@
+// Package jobstatus_test tests the NATS KV job status store implementation.
+package jobstatus_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/jobstatus"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/require"
+)
+
+// StartTestServer starts an in-memory NATS server for testing purposes.
+func StartTestServer(t *testing.T) (*server.Server, *nats.Conn) {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	natsServer := test.RunServer(&opts)
+
+	natsConnection, err := nats.Connect(natsServer.ClientURL())
+	if err != nil {
+		t.Fatalf("Failed to connect to test NATS server: %v", err)
+	}
+
+	return natsServer, natsConnection
+}
+
+func newTestStore(t *testing.T) *jobstatus.NatsStatusStore {
+	t.Helper()
+
+	natsServer, natsConnection := StartTestServer(t)
+	t.Cleanup(natsServer.Shutdown)
+	t.Cleanup(natsConnection.Close)
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	store, err := jobstatus.New(jetstreamContext, "test-job-status")
+	require.NoError(t, err)
+
+	return store
+}
+
+func newPageStatus(workflowID string, page int, state core.JobState) core.JobStatus {
+	return core.JobStatus{
+		WorkflowID: workflowID,
+		State:      state,
+		PageNumber: page,
+		TotalPages: 3,
+		AudioKey:   "",
+		Error:      "",
+		Progress:   nil,
+		UpdatedAt:  time.Now().UTC().Truncate(time.Millisecond),
+	}
+}
+
+func TestNatsStatusStore_PutGet(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, newPageStatus("workflow-1", 2, core.JobStateReceived)))
+
+	completed := newPageStatus("workflow-1", 2, core.JobStateCompleted)
+	completed.AudioKey = "audio.wav"
+	require.NoError(t, store.Put(ctx, completed))
+
+	got, err := store.Get(ctx, "workflow-1")
+	require.NoError(t, err)
+	require.Equal(t, "workflow-1", got.WorkflowID)
+	require.Equal(t, core.JobStateProcessing, got.State, "pages 1 and 3 are still outstanding")
+	require.Equal(t, 3, got.TotalPages)
+	require.Equal(t, 1, got.PagesCompleted)
+	require.Len(t, got.Pages, 1)
+	require.Equal(t, "audio.wav", got.Pages[0].AudioKey)
+	require.True(t, completed.UpdatedAt.Equal(got.UpdatedAt))
+}
+
+func TestNatsStatusStore_FailedPageIsNotOverwritten(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	failed := newPageStatus("workflow-2", 2, core.JobStateFailed)
+	failed.Error = "chatllm exploded"
+	require.NoError(t, store.Put(ctx, newPageStatus("workflow-2", 1, core.JobStateCompleted)))
+	require.NoError(t, store.Put(ctx, failed))
+	require.NoError(t, store.Put(ctx, newPageStatus("workflow-2", 3, core.JobStateCompleted)))
+
+	// A different workflow sharing the prefix must not leak into the result.
+	require.NoError(t, store.Put(ctx, newPageStatus("workflow-20", 1, core.JobStateCompleted)))
+
+	got, err := store.Get(ctx, "workflow-2")
+	require.NoError(t, err)
+	require.Equal(t, core.JobStateFailed, got.State)
+	require.Equal(t, 2, got.PagesCompleted)
+	require.Equal(t, 1, got.PagesFailed)
+	require.Len(t, got.Pages, 3)
+	require.Equal(t, "chatllm exploded", got.Pages[1].Error)
+
+	got, err = store.Get(ctx, "workflow-20")
+	require.NoError(t, err)
+	require.Equal(t, core.JobStateProcessing, got.State)
+}
+
+func TestSummarize(t *testing.T) {
+	t.Parallel()
+
+	pages := []core.JobStatus{
+		newPageStatus("workflow-3", 3, core.JobStateCompleted),
+		newPageStatus("workflow-3", 1, core.JobStateCompleted),
+		newPageStatus("workflow-3", 2, core.JobStateCompleted),
+	}
+
+	summary := jobstatus.Summarize("workflow-3", pages)
+	require.Equal(t, core.JobStateCompleted, summary.State)
+	require.Equal(t, []int{1, 2, 3}, []int{
+		summary.Pages[0].PageNumber, summary.Pages[1].PageNumber, summary.Pages[2].PageNumber,
+	})
+
+	summary = jobstatus.Summarize("workflow-3", pages[:1])
+	require.Equal(t, core.JobStateProcessing, summary.State)
+
+	summary = jobstatus.Summarize("workflow-3", []core.JobStatus{newPageStatus("workflow-3", 1, core.JobStateReceived)})
+	require.Equal(t, core.JobStateReceived, summary.State)
+}
+
+func TestNatsStatusStore_GetUnknownWorkflow(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+
+	_, err := store.Get(context.Background(), "missing")
+	require.ErrorIs(t, err, jobstatus.ErrJobNotFound)
+}
+
+func TestNatsStatusStore_RejectsWorkflowIDsThatAreNotOneToken(t *testing.T) {
+	t.Parallel()
+
+	store := newTestStore(t)
+
+	require.NoError(t, store.Put(context.Background(), newPageStatus("wf-1", 1, core.JobStateCompleted)))
+	require.NoError(t, store.Put(context.Background(), newPageStatus("wf-2", 1, core.JobStateCompleted)))
+
+	for _, workflowID := range []string{"*", ">", "wf-1.1", "wf-1.*", "wf 1", ""} {
+		_, err := store.Get(context.Background(), workflowID)
+		require.ErrorIs(t, err, jobstatus.ErrInvalidWorkflowID, "a query for %q does not read other workflows", workflowID)
+	}
+
+	err := store.Put(context.Background(), newPageStatus("wf.3", 1, core.JobStateCompleted))
+	require.ErrorIs(t, err, jobstatus.ErrInvalidWorkflowID)
+}
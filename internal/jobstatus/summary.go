@@ -0,0 +1,59 @@
+package jobstatus
+
+import (
+	"sort"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Summarize aggregates the page statuses of a workflow into a core.WorkflowStatus.
+// Pages are returned in page order.
+func Summarize(workflowID string, pages []core.JobStatus) core.WorkflowStatus {
+	sorted := make([]core.JobStatus, len(pages))
+	copy(sorted, pages)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].PageNumber < sorted[j].PageNumber })
+
+	summary := core.WorkflowStatus{
+		WorkflowID:     workflowID,
+		State:          core.JobStateReceived,
+		TotalPages:     0,
+		PagesCompleted: 0,
+		PagesFailed:    0,
+		Pages:          sorted,
+		UpdatedAt:      time.Time{},
+	}
+
+	processing := false
+
+	for _, page := range sorted {
+		summary.TotalPages = max(summary.TotalPages, page.TotalPages)
+
+		if page.UpdatedAt.After(summary.UpdatedAt) {
+			summary.UpdatedAt = page.UpdatedAt
+		}
+
+		switch page.State {
+		case core.JobStateCompleted:
+			summary.PagesCompleted++
+		case core.JobStateFailed:
+			summary.PagesFailed++
+		case core.JobStateProcessing:
+			processing = true
+		case core.JobStateReceived:
+		}
+	}
+
+	expectedPages := max(summary.TotalPages, len(sorted))
+
+	switch {
+	case summary.PagesFailed > 0:
+		summary.State = core.JobStateFailed
+	case summary.PagesCompleted == expectedPages:
+		summary.State = core.JobStateCompleted
+	case processing || summary.PagesCompleted > 0:
+		summary.State = core.JobStateProcessing
+	}
+
+	return summary
+}
@@ -0,0 +1,186 @@
+// Package load watches the host's CPU, memory and GPU utilization and the
+// worker's local job queue, so the worker can stop taking jobs while the host
+// is saturated and resume when capacity frees up.
+package load
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/metrics"
+)
+
+// DefaultInterval is how often the monitor samples the host when no interval
+// is given.
+const DefaultInterval = 2 * time.Second
+
+// Thresholds are the limits above which the worker pauses. Utilizations are
+// fractions from 0 to 1; zero disables a limit.
+type Thresholds struct {
+	CPU    float64
+	Memory float64
+	GPU    float64
+	// Queue is the number of jobs waiting in the worker's local queue.
+	Queue int
+}
+
+// Sources read the current utilizations, each as a fraction from 0 to 1. A nil
+// source is not sampled.
+type Sources struct {
+	CPU    func() (float64, error)
+	Memory func() (float64, error)
+	GPU    func(ctx context.Context) (float64, error)
+}
+
+// Sample is one reading of the host.
+type Sample struct {
+	CPU    float64
+	Memory float64
+	GPU    float64
+}
+
+// Monitor samples the host periodically and reports whether it is over its
+// thresholds.
+type Monitor struct {
+	mu         sync.Mutex
+	last       Sample
+	thresholds Thresholds
+	sources    Sources
+	interval   time.Duration
+	registry   *metrics.Registry
+	log        *logger.Logger
+}
+
+// NewMonitor creates a monitor. A zero interval uses DefaultInterval; registry
+// may be nil.
+func NewMonitor(
+	thresholds Thresholds,
+	sources Sources,
+	interval time.Duration,
+	registry *metrics.Registry,
+	log *logger.Logger,
+) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+
+	return &Monitor{
+		mu:         sync.Mutex{},
+		last:       Sample{CPU: 0, Memory: 0, GPU: 0},
+		thresholds: thresholds,
+		sources:    sources,
+		interval:   interval,
+		registry:   registry,
+		log:        log,
+	}
+}
+
+// Interval returns how often the monitor samples the host.
+func (m *Monitor) Interval() time.Duration {
+	return m.interval
+}
+
+// Run samples the host every interval until the context is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	m.Refresh(ctx)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Refresh(ctx)
+		}
+	}
+}
+
+// Refresh samples the host once. A source that fails keeps its last value.
+func (m *Monitor) Refresh(ctx context.Context) {
+	m.mu.Lock()
+	sample := m.last
+	m.mu.Unlock()
+
+	var readGPU func() (float64, error)
+	if m.sources.GPU != nil {
+		readGPU = func() (float64, error) { return m.sources.GPU(ctx) }
+	}
+
+	readers := []struct {
+		name  string
+		read  func() (float64, error)
+		value *float64
+	}{
+		{"cpu", m.sources.CPU, &sample.CPU},
+		{"memory", m.sources.Memory, &sample.Memory},
+		{"gpu", readGPU, &sample.GPU},
+	}
+
+	for _, reader := range readers {
+		if reader.read == nil {
+			continue
+		}
+
+		value, err := reader.read()
+		if err != nil {
+			m.log.Warn("Failed to sample %s load: %v", reader.name, err)
+
+			continue
+		}
+
+		*reader.value = value
+	}
+
+	m.mu.Lock()
+	m.last = sample
+	m.mu.Unlock()
+
+	if m.registry != nil {
+		m.registry.SetGauge("load.cpu", sample.CPU)
+		m.registry.SetGauge("load.memory", sample.Memory)
+		m.registry.SetGauge("load.gpu", sample.GPU)
+	}
+}
+
+// Last returns the latest sample.
+func (m *Monitor) Last() Sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.last
+}
+
+// Overloaded reports the first utilization threshold the latest sample
+// exceeds, or "" when there is capacity.
+func (m *Monitor) Overloaded() string {
+	sample := m.Last()
+
+	checks := []struct {
+		name  string
+		value float64
+		limit float64
+	}{
+		{"CPU", sample.CPU, m.thresholds.CPU},
+		{"memory", sample.Memory, m.thresholds.Memory},
+		{"GPU", sample.GPU, m.thresholds.GPU},
+	}
+
+	for _, check := range checks {
+		if check.limit > 0 && check.value > check.limit {
+			return fmt.Sprintf("%s at %.0f%% > %.0f%%", check.name, check.value*100, check.limit*100)
+		}
+	}
+
+	return ""
+}
+
+// QueueFull reports whether queued, the number of jobs waiting locally,
+// exceeds the queue threshold.
+func (m *Monitor) QueueFull(queued int) bool {
+	return m.thresholds.Queue > 0 && queued > m.thresholds.Queue
+}
@@ -0,0 +1,73 @@
+package load_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/load"
+	"github.com/book-expert/tts-service/internal/metrics"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errNoGPU = errors.New("no GPU")
+
+func TestParseProcStat(t *testing.T) {
+	t.Parallel()
+
+	times, err := load.ParseProcStat([]byte("cpu  100 5 50 800 40 3 2 0 20 0\ncpu0 1 2 3 4 5 6 7 8 9 10\n"))
+	require.NoError(t, err)
+	assert.Equal(t, load.CPUTimes{Busy: 160, Total: 1000}, times)
+
+	_, err = load.ParseProcStat([]byte("intr 1 2 3"))
+	require.ErrorIs(t, err, load.ErrUnexpectedFormat)
+}
+
+func TestParseMeminfo(t *testing.T) {
+	t.Parallel()
+
+	used, err := load.ParseMeminfo([]byte("MemTotal:       16000000 kB\nMemFree:  1000 kB\nMemAvailable:    4000000 kB\n"))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.75, used, 1e-9)
+
+	_, err = load.ParseMeminfo([]byte("MemFree: 1000 kB\n"))
+	require.ErrorIs(t, err, load.ErrUnexpectedFormat)
+}
+
+func TestMonitor_Overloaded(t *testing.T) {
+	t.Parallel()
+
+	testLogger, err := logger.New(t.TempDir(), "test-log.log")
+	require.NoError(t, err)
+
+	cpu := 0.5
+	registry := metrics.NewRegistry()
+	monitor := load.NewMonitor(
+		load.Thresholds{CPU: 0.9, Memory: 0, GPU: 0.8, Queue: 2},
+		load.Sources{
+			CPU:    func() (float64, error) { return cpu, nil },
+			Memory: func() (float64, error) { return 0.99, nil },
+			GPU:    func(context.Context) (float64, error) { return 0, errNoGPU },
+		},
+		0, registry, testLogger,
+	)
+	assert.Equal(t, load.DefaultInterval, monitor.Interval())
+
+	monitor.Refresh(context.Background())
+	assert.Empty(t, monitor.Overloaded(), "memory has no limit and the GPU cannot be read")
+	assert.False(t, monitor.QueueFull(2))
+	assert.True(t, monitor.QueueFull(3))
+
+	cpu = 0.95
+
+	monitor.Refresh(context.Background())
+	assert.Equal(t, "CPU at 95% > 90%", monitor.Overloaded())
+	assert.InDelta(t, 0.95, registry.Snapshot().Gauges["load.cpu"], 1e-9)
+
+	cpu = 0.2
+
+	monitor.Refresh(context.Background())
+	assert.Empty(t, monitor.Overloaded(), "work resumes once capacity frees up")
+}
@@ -0,0 +1,132 @@
+package load
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrUnexpectedFormat indicates /proc contents the parsers do not understand.
+var ErrUnexpectedFormat = errors.New("unexpected /proc format")
+
+// CPUTimes are the cumulative busy and total jiffies of all CPUs.
+type CPUTimes struct {
+	Busy  uint64
+	Total uint64
+}
+
+// ParseProcStat reads the aggregate "cpu" line of /proc/stat. Idle and
+// iowait time count as idle.
+func ParseProcStat(data []byte) (CPUTimes, error) {
+	line, _, _ := bytes.Cut(data, []byte("\n"))
+
+	fields := strings.Fields(string(line))
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return CPUTimes{}, fmt.Errorf("%w: /proc/stat starts with %q", ErrUnexpectedFormat, string(line))
+	}
+
+	var times CPUTimes
+
+	for i, field := range fields[1:] {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return CPUTimes{}, fmt.Errorf("%w: /proc/stat field %q: %w", ErrUnexpectedFormat, field, err)
+		}
+
+		// Guest time is already counted in user time.
+		if i >= 8 {
+			break
+		}
+
+		times.Total += value
+
+		// Fields 3 and 4 are idle and iowait.
+		if i != 3 && i != 4 {
+			times.Busy += value
+		}
+	}
+
+	return times, nil
+}
+
+// ParseMeminfo returns the fraction of memory in use from /proc/meminfo:
+// everything but MemAvailable.
+func ParseMeminfo(data []byte) (float64, error) {
+	values := make(map[string]uint64, 2)
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		name, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok || (name != "MemTotal" && name != "MemAvailable") {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			return 0, fmt.Errorf("%w: /proc/meminfo %s is empty", ErrUnexpectedFormat, name)
+		}
+
+		value, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: /proc/meminfo %s: %w", ErrUnexpectedFormat, name, err)
+		}
+
+		values[name] = value
+	}
+
+	total, available := values["MemTotal"], values["MemAvailable"]
+	if total == 0 || available > total {
+		return 0, fmt.Errorf("%w: /proc/meminfo has MemTotal %d and MemAvailable %d", ErrUnexpectedFormat, total, available)
+	}
+
+	return 1 - float64(available)/float64(total), nil
+}
+
+// ProcCPU returns a CPU source that reports the utilization of all CPUs since
+// its previous call, from /proc/stat. The first call reports the average since
+// boot.
+func ProcCPU() func() (float64, error) {
+	var (
+		mu       sync.Mutex
+		previous CPUTimes
+	)
+
+	return func() (float64, error) {
+		data, err := os.ReadFile("/proc/stat")
+		if err != nil {
+			return 0, fmt.Errorf("failed to read CPU times: %w", err)
+		}
+
+		times, err := ParseProcStat(data)
+		if err != nil {
+			return 0, err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		busy, total := times.Busy-previous.Busy, times.Total-previous.Total
+		previous = times
+
+		if total == 0 {
+			return 0, nil
+		}
+
+		return float64(busy) / float64(total), nil
+	}
+}
+
+// ProcMemory is a memory source that reads /proc/meminfo.
+func ProcMemory() (float64, error) {
+	data, err := os.ReadFile("/proc/meminfo")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read memory usage: %w", err)
+	}
+
+	return ParseMeminfo(data)
+}
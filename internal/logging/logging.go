@@ -0,0 +1,312 @@
+// Package logging writes the service log with a level filter and size-based
+// rotation. The logger module writes every line to stdout and to a file it
+// opens itself, with no way to filter or rotate them; New points its stdout at
+// a pipe and its file at the null device, and writes the lines that come
+// through the pipe and pass the filter to stdout and to a rotated log file.
+package logging
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/book-expert/logger"
+)
+
+// ErrUnknownLevel is returned for a level other than info, warn or error.
+var ErrUnknownLevel = errors.New("log level must be info, warn or error")
+
+const (
+	bytesPerMiB = 1 << 20
+	dirPerm     = 0o750
+	filePerm    = 0o600
+	// timeLayout is the timestamp the logger module starts each line with.
+	timeLayout = "2006/01/02 15:04:05"
+)
+
+// levelRanks orders the level names of the configuration.
+var levelRanks = map[string]int{"": 0, "info": 0, "warn": 1, "error": 2}
+
+// lineRanks orders the levels the logger module writes. SYSTEM lines are
+// always kept.
+var lineRanks = map[string]int{
+	"INFO": 0, "SUCCESS": 0, "WARN": 1, "ERROR": 2, "FATAL": 2, "PANIC": 2, "SYSTEM": 3,
+}
+
+// stdoutMu serializes the swap of os.Stdout in New.
+var stdoutMu sync.Mutex
+
+// Options selects the log file and what is written to it.
+type Options struct {
+	// Dir is the directory of the log file; it is created if missing.
+	Dir string
+	// File is the name of the log file.
+	File string
+	// Level is the lowest level written: info, warn or error. Empty means info.
+	Level string
+	// MaxSizeMiB rotates the file before it grows past this size; 0 never rotates.
+	MaxSizeMiB int
+	// MaxBackups is how many rotated files are kept, as File.1 (the newest)
+	// to File.N. With 0 the file is truncated when it rotates.
+	MaxBackups int
+	// Stdout receives every line that is written to the file; nil uses os.Stdout.
+	Stdout io.Writer
+}
+
+// New returns a logger whose lines are filtered by level and written to stdout
+// and to the log file, and a function that flushes and closes both. The
+// function must be called instead of the logger's Close.
+func New(opts Options) (*logger.Logger, func() error, error) {
+	minRank, ok := levelRanks[opts.Level]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownLevel, opts.Level)
+	}
+
+	stdout := opts.Stdout
+	if stdout == nil {
+		stdout = os.Stdout
+	}
+
+	out := &sink{
+		stdout:     stdout,
+		path:       filepath.Join(opts.Dir, opts.File),
+		maxBytes:   int64(opts.MaxSizeMiB) * bytesPerMiB,
+		maxBackups: opts.MaxBackups,
+		minRank:    minRank,
+		file:       nil,
+		size:       0,
+		keep:       true,
+	}
+
+	err := os.MkdirAll(opts.Dir, dirPerm)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create log dir: %w", err)
+	}
+
+	err = out.open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, nil, errors.Join(fmt.Errorf("create log pipe: %w", err), out.close())
+	}
+
+	log, err := newPipedLogger(writer)
+	if err != nil {
+		return nil, nil, errors.Join(err, reader.Close(), writer.Close(), out.close())
+	}
+
+	done := make(chan error, 1)
+
+	go func() {
+		done <- out.run(reader)
+	}()
+
+	closeLog := func() error {
+		closeErr := log.Close()
+		writerErr := writer.Close()
+		runErr := <-done
+
+		return errors.Join(closeErr, writerErr, runErr, reader.Close(), out.close())
+	}
+
+	return log, closeLog, nil
+}
+
+// newPipedLogger creates a logger that writes to the pipe instead of stdout and
+// to the null device instead of a file. The logger module reads os.Stdout when
+// it is created, so os.Stdout is swapped for that moment.
+func newPipedLogger(writer *os.File) (*logger.Logger, error) {
+	stdoutMu.Lock()
+	defer stdoutMu.Unlock()
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	log, err := logger.New(filepath.Dir(os.DevNull), filepath.Base(os.DevNull))
+
+	os.Stdout = stdout
+
+	if err != nil {
+		return nil, fmt.Errorf("create logger: %w", err)
+	}
+
+	return log, nil
+}
+
+// sink writes the lines read from the logger to stdout and the log file.
+type sink struct {
+	stdout     io.Writer
+	path       string
+	maxBytes   int64
+	maxBackups int
+	minRank    int
+	file       *os.File
+	size       int64
+	// keep is whether the last line with a level passed the filter; lines of a
+	// multi-line message follow it.
+	keep bool
+}
+
+// run writes every line from reader until it is closed. A file that cannot be
+// written or rotated is reported on stderr once, and stdout still gets the log.
+func (s *sink) run(reader io.Reader) error {
+	lines := bufio.NewReader(reader)
+
+	var fileErr error
+
+	for {
+		line, err := lines.ReadString('\n')
+		if line != "" && s.kept(line) {
+			_, _ = io.WriteString(s.stdout, line)
+
+			if fileErr == nil {
+				fileErr = s.write(line)
+				if fileErr != nil {
+					fmt.Fprintf(os.Stderr, "log file %s: %v\n", s.path, fileErr)
+				}
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			return fileErr
+		}
+
+		if err != nil {
+			return errors.Join(fileErr, fmt.Errorf("read log pipe: %w", err))
+		}
+	}
+}
+
+// kept reports whether a line passes the level filter.
+func (s *sink) kept(line string) bool {
+	rank, ok := lineRank(line)
+	if ok {
+		s.keep = rank >= s.minRank
+	}
+
+	return s.keep
+}
+
+// lineRank returns the rank of the level of a line the logger module wrote,
+// "2006/01/02 15:04:05 [LEVEL] message". It is false for lines without a
+// timestamp and level, such as the rest of a multi-line message.
+func lineRank(line string) (int, bool) {
+	if len(line) <= len(timeLayout) {
+		return 0, false
+	}
+
+	_, err := time.Parse(timeLayout, line[:len(timeLayout)])
+	if err != nil {
+		return 0, false
+	}
+
+	rest, ok := strings.CutPrefix(line[len(timeLayout):], " [")
+	if !ok {
+		return 0, false
+	}
+
+	level, _, ok := strings.Cut(rest, "]")
+	if !ok {
+		return 0, false
+	}
+
+	rank, ok := lineRanks[level]
+
+	return rank, ok
+}
+
+// write appends a line to the log file, rotating it first if the line would
+// take it past the maximum size.
+func (s *sink) write(line string) error {
+	if s.maxBytes > 0 && s.size > 0 && s.size+int64(len(line)) > s.maxBytes {
+		err := s.rotate()
+		if err != nil {
+			return err
+		}
+	}
+
+	n, err := io.WriteString(s.file, line)
+	s.size += int64(n)
+
+	if err != nil {
+		return fmt.Errorf("write log file: %w", err)
+	}
+
+	return nil
+}
+
+// rotate closes the log file, shifts the backups up by one, dropping the
+// oldest, moves the file to the first backup and opens a new one.
+func (s *sink) rotate() error {
+	err := s.close()
+	if err != nil {
+		return err
+	}
+
+	if s.maxBackups == 0 {
+		err = os.Remove(s.path)
+	} else {
+		for i := s.maxBackups - 1; i >= 1; i-- {
+			err = os.Rename(s.backup(i), s.backup(i+1))
+			if err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("rotate log file: %w", err)
+			}
+		}
+
+		err = os.Rename(s.path, s.backup(1))
+	}
+
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("rotate log file: %w", err)
+	}
+
+	return s.open()
+}
+
+func (s *sink) backup(i int) string {
+	return s.path + "." + strconv.Itoa(i)
+}
+
+// close closes the log file, unless a failed rotation left it closed.
+func (s *sink) close() error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Close()
+	s.file = nil
+
+	if err != nil {
+		return fmt.Errorf("close log file: %w", err)
+	}
+
+	return nil
+}
+
+// open opens the log file for appending and records its size.
+func (s *sink) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, filePerm)
+	if err != nil {
+		return fmt.Errorf("open log file: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return errors.Join(fmt.Errorf("stat log file: %w", err), file.Close())
+	}
+
+	s.file = file
+	s.size = info.Size()
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package logging_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew_FiltersByLevel(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	var stdout bytes.Buffer
+
+	log, closeLog, err := logging.New(logging.Options{
+		Dir: dir, File: "service.log", Level: "warn", MaxSizeMiB: 0, MaxBackups: 0, Stdout: &stdout,
+	})
+	require.NoError(t, err)
+
+	log.Info("job started")
+	log.Success("job done")
+	log.Warn("slow job\nsecond line of the warning")
+	log.Info("multi\nline info")
+	log.Error("job failed")
+	log.System("shutting down")
+	require.NoError(t, closeLog())
+
+	written, err := os.ReadFile(filepath.Join(dir, "service.log"))
+	require.NoError(t, err)
+
+	assert.Equal(t, stdout.String(), string(written), "stdout gets the same lines as the file")
+
+	text := string(written)
+	for _, want := range []string{"[WARN] slow job", "second line of the warning", "[ERROR] job failed", "[SYSTEM] shutting down"} {
+		assert.Contains(t, text, want)
+	}
+
+	for _, unwanted := range []string{"job started", "job done", "multi", "line info"} {
+		assert.NotContains(t, text, unwanted, "lines below the level are dropped, with the rest of their message")
+	}
+}
+
+func TestNew_RotatesBySize(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "service.log")
+
+	log, closeLog, err := logging.New(logging.Options{
+		Dir: dir, File: "service.log", Level: "", MaxSizeMiB: 1, MaxBackups: 2, Stdout: &bytes.Buffer{},
+	})
+	require.NoError(t, err)
+
+	// Each message is about 1 KiB, so 1024 of them fill a file.
+	message := strings.Repeat("x", 1000)
+	for i := range 4 * 1024 {
+		log.Info("%d %s", i, message)
+	}
+
+	require.NoError(t, closeLog())
+
+	for _, name := range []string{path, path + ".1", path + ".2"} {
+		info, err := os.Stat(name)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(1<<20), "%s is rotated before it passes max_size_mib", name)
+		assert.Positive(t, info.Size())
+	}
+
+	_, err = os.Stat(path + ".3")
+	require.ErrorIs(t, err, os.ErrNotExist, "only max_backups rotated files are kept")
+
+	newest, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(newest), "4095 x", "the last line is in the current file")
+
+	backup, err := os.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.NotContains(t, string(backup), "4095 x")
+}
+
+func TestNew_RejectsUnknownLevel(t *testing.T) {
+	t.Parallel()
+
+	_, _, err := logging.New(logging.Options{
+		Dir: t.TempDir(), File: "service.log", Level: "debug", MaxSizeMiB: 0, MaxBackups: 0, Stdout: nil,
+	})
+	require.ErrorIs(t, err, logging.ErrUnknownLevel)
+}
@@ -0,0 +1,104 @@
+// Package metrics provides a small in-process registry of gauges and counters
+// that can be queried over a NATS request/reply subject.
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/nats-io/nats.go"
+)
+
+// Snapshot is a point-in-time copy of all registered metrics.
+type Snapshot struct {
+	Timestamp time.Time          `json:"timestamp"`
+	Gauges    map[string]float64 `json:"gauges"`
+	Counters  map[string]uint64  `json:"counters"`
+}
+
+// Registry holds named gauges and counters. It is safe for concurrent use.
+type Registry struct {
+	mu       sync.RWMutex
+	gauges   map[string]float64
+	counters map[string]uint64
+}
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{
+		mu:       sync.RWMutex{},
+		gauges:   make(map[string]float64),
+		counters: make(map[string]uint64),
+	}
+}
+
+// SetGauge sets the current value of a gauge.
+func (r *Registry) SetGauge(name string, value float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.gauges[name] = value
+}
+
+// AddCounter increases a counter by delta.
+func (r *Registry) AddCounter(name string, delta uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.counters[name] += delta
+}
+
+// Snapshot returns a copy of all metrics.
+func (r *Registry) Snapshot() Snapshot {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	snapshot := Snapshot{
+		Timestamp: time.Now().UTC(),
+		Gauges:    make(map[string]float64, len(r.gauges)),
+		Counters:  make(map[string]uint64, len(r.counters)),
+	}
+
+	for name, value := range r.gauges {
+		snapshot.Gauges[name] = value
+	}
+
+	for name, value := range r.counters {
+		snapshot.Counters[name] = value
+	}
+
+	return snapshot
+}
+
+// Serve answers requests on subject with a JSON Snapshot until the context is cancelled.
+func (r *Registry) Serve(ctx context.Context, natsConnection *nats.Conn, subject string, log *logger.Logger) error {
+	sub, err := natsConnection.Subscribe(subject, func(msg *nats.Msg) {
+		data, marshalErr := json.Marshal(r.Snapshot())
+		if marshalErr != nil {
+			log.Error("Failed to marshal metrics snapshot: %v", marshalErr)
+
+			return
+		}
+
+		respondErr := msg.Respond(data)
+		if respondErr != nil {
+			log.Error("Failed to respond to metrics request: %v", respondErr)
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to subject %s: %w", subject, err)
+	}
+
+	<-ctx.Done()
+
+	drainErr := sub.Drain()
+	if drainErr != nil {
+		return fmt.Errorf("failed to drain subscription: %w", drainErr)
+	}
+
+	return nil
+}
@@ -0,0 +1,58 @@
+// Package metrics_test tests the metrics registry.
+package metrics_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/metrics"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_ServeSnapshot(t *testing.T) {
+	t.Parallel()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	natsConnection, err := nats.Connect(natsServer.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(natsConnection.Close)
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	registry := metrics.NewRegistry()
+	registry.SetGauge("gpu.0.memory_free_mib", 2048)
+	registry.AddCounter("jobs.completed", 2)
+	registry.AddCounter("jobs.completed", 3)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	go func() {
+		_ = registry.Serve(ctx, natsConnection, "test.metrics", testLogger)
+	}()
+
+	var reply *nats.Msg
+
+	require.Eventually(t, func() bool {
+		reply, err = natsConnection.Request("test.metrics", nil, time.Second)
+
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	var snapshot metrics.Snapshot
+
+	require.NoError(t, json.Unmarshal(reply.Data, &snapshot))
+	assert.InDelta(t, 2048, snapshot.Gauges["gpu.0.memory_free_mib"], 0)
+	assert.Equal(t, uint64(5), snapshot.Counters["jobs.completed"])
+}
@@ -0,0 +1,460 @@
+// Package models resolves model names to local files, downloads missing models
+// with checksum verification, and hot-swaps the models used by the processor.
+package models
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/book-expert/logger"
+	"github.com/nats-io/nats.go"
+)
+
+const (
+	modelDirPermissions = 0o750
+	partialSuffix       = ".partial"
+	sha256HexLength     = 64
+
+	// ResultSubjectSuffix is appended to the control subject to form the
+	// subject on which swap results are published.
+	ResultSubjectSuffix = ".result"
+)
+
+// Swap states reported in SwapResponse.Status.
+const (
+	SwapAccepted  = "accepted"
+	SwapCompleted = "completed"
+	SwapFailed    = "failed"
+)
+
+// Static errors.
+var (
+	ErrUnknownModel     = errors.New("unknown model")
+	ErrChecksumMismatch = errors.New("model checksum mismatch")
+	ErrDownloadFailed   = errors.New("model download failed")
+	ErrInvalidFilename  = errors.New("model filename must be a plain file name")
+	ErrSwapRequest      = errors.New("swap request must name a model or snac model")
+	ErrInvalidChecksum  = errors.New("model sha256 must be a 64 character hex digest")
+	ErrModelOutsideDir  = errors.New("model path is outside the models directory")
+	ErrSwapInProgress   = errors.New("a model swap is already in progress")
+)
+
+// Spec describes a downloadable model.
+type Spec struct {
+	// URL is where the model is downloaded from, e.g. a Hugging Face "resolve" link.
+	URL string
+	// SHA256 is the expected hex digest. It is required.
+	SHA256 string
+	// Filename is the name of the file inside the models directory.
+	Filename string
+}
+
+// Swapper is implemented by processors whose models can be replaced at runtime.
+type Swapper interface {
+	SetModelPaths(modelPath, snacModelPath string)
+}
+
+// SwapRequest is the payload accepted on the control subject. Each field is a
+// catalog name or a path inside the models directory; an empty field keeps the
+// current model.
+type SwapRequest struct {
+	Model     string `json:"model,omitempty"`
+	SnacModel string `json:"snacModel,omitempty"`
+}
+
+// SwapResponse is the reply sent for a SwapRequest, with Status SwapAccepted or
+// SwapFailed, and the result published once an accepted swap finishes, with
+// Status SwapCompleted or SwapFailed.
+type SwapResponse struct {
+	Status        string `json:"status"`
+	ModelPath     string `json:"modelPath,omitempty"`
+	SnacModelPath string `json:"snacModelPath,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Manager resolves and downloads models.
+type Manager struct {
+	dir        string
+	catalog    map[string]Spec
+	httpClient *http.Client
+	log        *logger.Logger
+
+	mu            sync.Mutex // serializes downloads and swaps
+	modelPath     string
+	snacModelPath string
+	swapping      atomic.Bool
+}
+
+// New creates a Manager storing downloaded models in dir. Every catalog entry
+// must have a plain file name and a SHA256 digest.
+func New(dir string, catalog map[string]Spec, httpClient *http.Client, log *logger.Logger) (*Manager, error) {
+	for name, spec := range catalog {
+		if spec.Filename == "" || filepath.Base(spec.Filename) != spec.Filename {
+			return nil, fmt.Errorf("%w: model '%s' has filename '%s'", ErrInvalidFilename, name, spec.Filename)
+		}
+
+		_, err := hex.DecodeString(spec.SHA256)
+		if err != nil || len(spec.SHA256) != sha256HexLength {
+			return nil, fmt.Errorf("%w: model '%s' has sha256 '%s'", ErrInvalidChecksum, name, spec.SHA256)
+		}
+	}
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Manager{
+		dir:           dir,
+		catalog:       catalog,
+		httpClient:    httpClient,
+		log:           log,
+		mu:            sync.Mutex{},
+		modelPath:     "",
+		snacModelPath: "",
+		swapping:      atomic.Bool{},
+	}, nil
+}
+
+// Resolve returns the local path for a catalog name or an existing file path,
+// downloading and verifying catalog models that are not present yet.
+func (m *Manager) Resolve(ctx context.Context, nameOrPath string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.resolve(ctx, nameOrPath)
+}
+
+func (m *Manager) resolve(ctx context.Context, nameOrPath string) (string, error) {
+	spec, known := m.catalog[nameOrPath]
+	if !known {
+		_, err := os.Stat(nameOrPath)
+		if err != nil {
+			return "", fmt.Errorf("%w: '%s' is neither a catalog name nor a readable file: %w",
+				ErrUnknownModel, nameOrPath, err)
+		}
+
+		return nameOrPath, nil
+	}
+
+	path := filepath.Join(m.dir, spec.Filename)
+
+	_, err := os.Stat(path)
+	if err == nil {
+		return path, nil
+	}
+
+	err = m.download(ctx, nameOrPath, spec, path)
+	if err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// download fetches spec.URL into a partial file, verifies its digest while
+// streaming, and renames it into place only when the digest matches.
+func (m *Manager) download(ctx context.Context, name string, spec Spec, path string) error {
+	m.log.Info("Downloading model '%s' from %s", name, spec.URL)
+
+	err := os.MkdirAll(m.dir, modelDirPermissions)
+	if err != nil {
+		return fmt.Errorf("failed to create models directory '%s': %w", m.dir, err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, spec.URL, http.NoBody)
+	if err != nil {
+		return fmt.Errorf("failed to create download request for model '%s': %w", name, err)
+	}
+
+	resp, err := m.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: model '%s': %w", ErrDownloadFailed, name, err)
+	}
+
+	defer func() {
+		closeErr := resp.Body.Close()
+		if closeErr != nil {
+			m.log.Warn("Failed to close download body for model '%s': %v", name, closeErr)
+		}
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%w: model '%s': %s", ErrDownloadFailed, name, resp.Status)
+	}
+
+	partialPath := path + partialSuffix
+
+	digest, err := writeWithDigest(partialPath, resp.Body)
+	if err != nil {
+		removePartial(partialPath, m.log)
+
+		return fmt.Errorf("%w: model '%s': %w", ErrDownloadFailed, name, err)
+	}
+
+	if !strings.EqualFold(digest, spec.SHA256) {
+		removePartial(partialPath, m.log)
+
+		return fmt.Errorf("%w: model '%s': expected %s, got %s", ErrChecksumMismatch, name, spec.SHA256, digest)
+	}
+
+	err = os.Rename(partialPath, path)
+	if err != nil {
+		removePartial(partialPath, m.log)
+
+		return fmt.Errorf("failed to move model '%s' into place: %w", name, err)
+	}
+
+	m.log.Info("Model '%s' stored at %s (sha256 %s)", name, path, digest)
+
+	return nil
+}
+
+func writeWithDigest(path string, body io.Reader) (string, error) {
+	file, err := os.Create(path) // #nosec G304 -- path is built from the configured models directory
+	if err != nil {
+		return "", fmt.Errorf("failed to create '%s': %w", path, err)
+	}
+
+	hasher := sha256.New()
+
+	_, copyErr := io.Copy(io.MultiWriter(file, hasher), body)
+	closeErr := file.Close()
+
+	if copyErr != nil {
+		return "", fmt.Errorf("failed to write '%s': %w", path, copyErr)
+	}
+
+	if closeErr != nil {
+		return "", fmt.Errorf("failed to close '%s': %w", path, closeErr)
+	}
+
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+func removePartial(path string, log *logger.Logger) {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Warn("Failed to remove partial download '%s': %v", path, err)
+	}
+}
+
+// Activate resolves the initial model and SNAC model and records them as current.
+func (m *Manager) Activate(ctx context.Context, model, snacModel string) (string, string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	modelPath, err := m.resolve(ctx, model)
+	if err != nil {
+		return "", "", err
+	}
+
+	snacModelPath, err := m.resolve(ctx, snacModel)
+	if err != nil {
+		return "", "", err
+	}
+
+	m.modelPath, m.snacModelPath = modelPath, snacModelPath
+
+	return modelPath, snacModelPath, nil
+}
+
+// Swap resolves the requested models and installs them on the swapper.
+// Jobs already running keep the models they started with.
+func (m *Manager) Swap(ctx context.Context, req SwapRequest, swapper Swapper) (string, string, error) {
+	err := m.checkSwapRequest(req)
+	if err != nil {
+		return "", "", err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	modelPath, snacModelPath := m.modelPath, m.snacModelPath
+
+	if req.Model != "" {
+		modelPath, err = m.resolve(ctx, req.Model)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if req.SnacModel != "" {
+		snacModelPath, err = m.resolve(ctx, req.SnacModel)
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	swapper.SetModelPaths(modelPath, snacModelPath)
+	m.modelPath, m.snacModelPath = modelPath, snacModelPath
+	m.log.Info("Swapped models: model=%s snac_model=%s", modelPath, snacModelPath)
+
+	return modelPath, snacModelPath, nil
+}
+
+// checkSwapRequest rejects empty requests and models that are neither catalog
+// names nor files inside the models directory.
+func (m *Manager) checkSwapRequest(req SwapRequest) error {
+	if req.Model == "" && req.SnacModel == "" {
+		return ErrSwapRequest
+	}
+
+	for _, nameOrPath := range []string{req.Model, req.SnacModel} {
+		if nameOrPath == "" {
+			continue
+		}
+
+		_, known := m.catalog[nameOrPath]
+		if known {
+			continue
+		}
+
+		err := m.checkInDir(nameOrPath)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkInDir verifies that path, after resolving symlinks, is inside the models directory.
+func (m *Manager) checkInDir(path string) error {
+	dir, err := filepath.EvalSymlinks(m.dir)
+	if err != nil {
+		return fmt.Errorf("failed to resolve models directory '%s': %w", m.dir, err)
+	}
+
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fmt.Errorf("%w: '%s' is neither a catalog name nor a readable file: %w",
+			ErrUnknownModel, path, err)
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return fmt.Errorf("%w: '%s'", ErrModelOutsideDir, path)
+	}
+
+	return nil
+}
+
+// ServeControl answers SwapRequests on subject until the context is cancelled.
+// A valid request is acknowledged immediately and swapped in the background;
+// the result is published on subject + ResultSubjectSuffix. One swap runs at a
+// time.
+func (m *Manager) ServeControl(ctx context.Context, natsConnection *nats.Conn, subject string, swapper Swapper) error {
+	var swaps sync.WaitGroup
+
+	sub, err := natsConnection.Subscribe(subject, func(msg *nats.Msg) {
+		m.handleSwap(ctx, natsConnection, msg, swapper, &swaps, subject+ResultSubjectSuffix)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to subject %s: %w", subject, err)
+	}
+
+	<-ctx.Done()
+
+	drainErr := sub.Drain()
+
+	swaps.Wait()
+
+	if drainErr != nil {
+		return fmt.Errorf("failed to drain subscription: %w", drainErr)
+	}
+
+	return nil
+}
+
+func (m *Manager) handleSwap(
+	ctx context.Context,
+	natsConnection *nats.Conn,
+	msg *nats.Msg,
+	swapper Swapper,
+	swaps *sync.WaitGroup,
+	resultSubject string,
+) {
+	var req SwapRequest
+
+	err := json.Unmarshal(msg.Data, &req)
+	if err != nil {
+		err = fmt.Errorf("failed to unmarshal swap request: %w", err)
+	} else {
+		err = m.checkSwapRequest(req)
+	}
+
+	if err == nil && !m.swapping.CompareAndSwap(false, true) {
+		err = ErrSwapInProgress
+	}
+
+	if err != nil {
+		m.log.Error("Model swap rejected: %v", err)
+		m.reply(msg, swapResponse("", "", err))
+
+		return
+	}
+
+	m.reply(msg, SwapResponse{Status: SwapAccepted, ModelPath: "", SnacModelPath: "", Error: ""})
+
+	swaps.Add(1)
+
+	go func() {
+		defer swaps.Done()
+		defer m.swapping.Store(false)
+
+		modelPath, snacModelPath, swapErr := m.Swap(ctx, req, swapper)
+		if swapErr != nil {
+			m.log.Error("Model swap failed: %v", swapErr)
+		}
+
+		m.publishResult(natsConnection, resultSubject, swapResponse(modelPath, snacModelPath, swapErr))
+	}()
+}
+
+// swapResponse builds the response for a finished or rejected swap.
+func swapResponse(modelPath, snacModelPath string, err error) SwapResponse {
+	if err != nil {
+		return SwapResponse{Status: SwapFailed, ModelPath: "", SnacModelPath: "", Error: err.Error()}
+	}
+
+	return SwapResponse{Status: SwapCompleted, ModelPath: modelPath, SnacModelPath: snacModelPath, Error: ""}
+}
+
+func (m *Manager) reply(msg *nats.Msg, response SwapResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		m.log.Error("Failed to marshal swap response: %v", err)
+
+		return
+	}
+
+	err = msg.Respond(data)
+	if err != nil {
+		m.log.Error("Failed to respond to swap request: %v", err)
+	}
+}
+
+func (m *Manager) publishResult(natsConnection *nats.Conn, subject string, response SwapResponse) {
+	data, err := json.Marshal(response)
+	if err != nil {
+		m.log.Error("Failed to marshal swap result: %v", err)
+
+		return
+	}
+
+	err = natsConnection.Publish(subject, data)
+	if err != nil {
+		m.log.Error("Failed to publish swap result on %s: %v", subject, err)
+	}
+}
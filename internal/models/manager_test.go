@@ -0,0 +1,294 @@
+// Package models_test tests model resolution, download and hot-swap.
+package models_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/models"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var modelBytes = []byte("pretend this is a multi-gigabyte model")
+
+type recordingSwapper struct {
+	mu            sync.Mutex
+	modelPath     string
+	snacModelPath string
+}
+
+func (r *recordingSwapper) SetModelPaths(modelPath, snacModelPath string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.modelPath = modelPath
+	r.snacModelPath = snacModelPath
+}
+
+func (r *recordingSwapper) paths() (string, string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.modelPath, r.snacModelPath
+}
+
+func digest(data []byte) string {
+	sum := sha256.Sum256(data)
+
+	return hex.EncodeToString(sum[:])
+}
+
+func newTestManager(t *testing.T, catalog map[string]models.Spec) (*models.Manager, string) {
+	t.Helper()
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+
+	manager, err := models.New(dir, catalog, nil, testLogger)
+	require.NoError(t, err)
+
+	return manager, dir
+}
+
+func newModelServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+
+	var downloads atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		downloads.Add(1)
+
+		_, _ = w.Write(modelBytes)
+	}))
+	t.Cleanup(server.Close)
+
+	return server, &downloads
+}
+
+func TestResolve_DownloadsOnceAndVerifies(t *testing.T) {
+	t.Parallel()
+
+	server, downloads := newModelServer(t)
+	manager, dir := newTestManager(t, map[string]models.Spec{
+		"orpheus": {URL: server.URL + "/orpheus.bin", SHA256: digest(modelBytes), Filename: "orpheus.bin"},
+	})
+
+	path, err := manager.Resolve(context.Background(), "orpheus")
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "orpheus.bin"), path)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, modelBytes, data)
+
+	_, err = manager.Resolve(context.Background(), "orpheus")
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), downloads.Load(), "an existing model must not be downloaded again")
+}
+
+func TestResolve_ChecksumMismatch(t *testing.T) {
+	t.Parallel()
+
+	server, _ := newModelServer(t)
+	manager, dir := newTestManager(t, map[string]models.Spec{
+		"orpheus": {URL: server.URL, SHA256: digest([]byte("something else")), Filename: "orpheus.bin"},
+	})
+
+	_, err := manager.Resolve(context.Background(), "orpheus")
+	require.ErrorIs(t, err, models.ErrChecksumMismatch)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a corrupt download must not be left behind")
+}
+
+func TestResolve_PathsAndUnknownNames(t *testing.T) {
+	t.Parallel()
+
+	manager, _ := newTestManager(t, map[string]models.Spec{})
+
+	existing := filepath.Join(t.TempDir(), "local.bin")
+	require.NoError(t, os.WriteFile(existing, modelBytes, 0o600))
+
+	path, err := manager.Resolve(context.Background(), existing)
+	require.NoError(t, err)
+	assert.Equal(t, existing, path)
+
+	_, err = manager.Resolve(context.Background(), "no-such-model")
+	require.ErrorIs(t, err, models.ErrUnknownModel)
+}
+
+func TestNew_RejectsUnsafeFilenames(t *testing.T) {
+	t.Parallel()
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	_, err = models.New(t.TempDir(), map[string]models.Spec{
+		"evil": {URL: "http://example.invalid", SHA256: "", Filename: "../../etc/passwd"},
+	}, nil, testLogger)
+	require.ErrorIs(t, err, models.ErrInvalidFilename)
+}
+
+func TestNew_RejectsMissingChecksum(t *testing.T) {
+	t.Parallel()
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	for _, checksum := range []string{"", "abc123", digest(modelBytes)[:62] + "zz"} {
+		_, err = models.New(t.TempDir(), map[string]models.Spec{
+			"orpheus": {URL: "http://example.invalid", SHA256: checksum, Filename: "orpheus.bin"},
+		}, nil, testLogger)
+		require.ErrorIs(t, err, models.ErrInvalidChecksum, "sha256 %q", checksum)
+	}
+}
+
+func newSwapTestManager(t *testing.T) (*models.Manager, string) {
+	t.Helper()
+
+	server, _ := newModelServer(t)
+	manager, dir := newTestManager(t, map[string]models.Spec{
+		"orpheus":    {URL: server.URL, SHA256: digest(modelBytes), Filename: "orpheus.bin"},
+		"orpheus-v2": {URL: server.URL, SHA256: digest(modelBytes), Filename: "orpheus-v2.bin"},
+		"snac":       {URL: server.URL, SHA256: digest(modelBytes), Filename: "snac.bin"},
+	})
+
+	_, _, err := manager.Activate(context.Background(), "orpheus", "snac")
+	require.NoError(t, err)
+
+	return manager, dir
+}
+
+func TestSwap(t *testing.T) {
+	t.Parallel()
+
+	manager, dir := newSwapTestManager(t)
+	swapper := &recordingSwapper{mu: sync.Mutex{}, modelPath: "", snacModelPath: ""}
+
+	modelPath, snacModelPath, err := manager.Swap(context.Background(), models.SwapRequest{
+		Model: "orpheus-v2", SnacModel: "",
+	}, swapper)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "orpheus-v2.bin"), modelPath)
+	assert.Equal(t, filepath.Join(dir, "snac.bin"), snacModelPath, "an empty field keeps the current model")
+
+	swappedModel, swappedSnac := swapper.paths()
+	assert.Equal(t, modelPath, swappedModel)
+	assert.Equal(t, snacModelPath, swappedSnac)
+
+	// A file inside the models directory may be named by path.
+	modelPath, _, err = manager.Swap(context.Background(), models.SwapRequest{
+		Model: filepath.Join(dir, "orpheus.bin"), SnacModel: "",
+	}, swapper)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "orpheus.bin"), modelPath)
+
+	_, _, err = manager.Swap(context.Background(), models.SwapRequest{Model: "", SnacModel: ""}, swapper)
+	require.ErrorIs(t, err, models.ErrSwapRequest)
+}
+
+func TestSwap_RejectsPathsOutsideModelsDir(t *testing.T) {
+	t.Parallel()
+
+	manager, dir := newSwapTestManager(t)
+	swapper := &recordingSwapper{mu: sync.Mutex{}, modelPath: "", snacModelPath: ""}
+
+	outside := filepath.Join(t.TempDir(), "other.bin")
+	require.NoError(t, os.WriteFile(outside, modelBytes, 0o600))
+
+	link := filepath.Join(dir, "link.bin")
+	require.NoError(t, os.Symlink(outside, link))
+
+	for _, path := range []string{outside, filepath.Join(dir, "..", filepath.Base(outside)), link} {
+		_, _, err := manager.Swap(context.Background(), models.SwapRequest{Model: path, SnacModel: ""}, swapper)
+		require.Error(t, err, path)
+	}
+
+	_, _, err := manager.Swap(context.Background(), models.SwapRequest{Model: outside, SnacModel: ""}, swapper)
+	require.ErrorIs(t, err, models.ErrModelOutsideDir)
+
+	swappedModel, _ := swapper.paths()
+	assert.Empty(t, swappedModel, "a rejected swap must not reach the processor")
+}
+
+func TestServeControl(t *testing.T) {
+	t.Parallel()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	natsConnection, err := nats.Connect(natsServer.ClientURL())
+	require.NoError(t, err)
+	t.Cleanup(natsConnection.Close)
+
+	manager, dir := newSwapTestManager(t)
+	swapper := &recordingSwapper{mu: sync.Mutex{}, modelPath: "", snacModelPath: ""}
+
+	results, err := natsConnection.SubscribeSync("tts.control.models" + models.ResultSubjectSuffix)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+
+	go func() {
+		served <- manager.ServeControl(ctx, natsConnection, "tts.control.models", swapper)
+	}()
+
+	request := func(body string) models.SwapResponse {
+		var (
+			reply    *nats.Msg
+			response models.SwapResponse
+		)
+
+		require.Eventually(t, func() bool {
+			var requestErr error
+
+			reply, requestErr = natsConnection.Request("tts.control.models", []byte(body), time.Second)
+
+			return requestErr == nil
+		}, 5*time.Second, 50*time.Millisecond)
+		require.NoError(t, json.Unmarshal(reply.Data, &response))
+
+		return response
+	}
+
+	response := request(`{"model": "/etc/passwd"}`)
+	assert.Equal(t, models.SwapFailed, response.Status)
+	assert.Contains(t, response.Error, models.ErrModelOutsideDir.Error())
+
+	response = request(`{"model": "orpheus-v2"}`)
+	assert.Equal(t, models.SwapAccepted, response.Status)
+
+	msg, err := results.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+
+	var result models.SwapResponse
+	require.NoError(t, json.Unmarshal(msg.Data, &result))
+	assert.Equal(t, models.SwapCompleted, result.Status)
+	assert.Equal(t, filepath.Join(dir, "orpheus-v2.bin"), result.ModelPath)
+
+	swappedModel, _ := swapper.paths()
+	assert.Equal(t, result.ModelPath, swappedModel)
+
+	cancel()
+	require.NoError(t, <-served)
+}
@@ -0,0 +1,96 @@
+package natsconn
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/metrics"
+	"github.com/nats-io/nats.go"
+)
+
+// Health metric names.
+const (
+	metricConnected   = "nats.connected"
+	metricDisconnects = "nats.disconnects"
+	metricReconnects  = "nats.reconnects"
+)
+
+// Health tracks whether a NATS connection is up. It logs every disconnect and
+// reconnect and, with a registry, publishes the state as the nats.connected
+// gauge and the nats.disconnects and nats.reconnects counters.
+type Health struct {
+	connected atomic.Bool
+	closeOnce sync.Once
+	closed    chan struct{}
+	registry  *metrics.Registry
+	log       *logger.Logger
+}
+
+// NewHealth creates a tracker that reports to registry, which may be nil.
+func NewHealth(registry *metrics.Registry, log *logger.Logger) *Health {
+	return &Health{
+		connected: atomic.Bool{},
+		closeOnce: sync.Once{},
+		closed:    make(chan struct{}),
+		registry:  registry,
+		log:       log,
+	}
+}
+
+// Connected reports whether the connection is currently up.
+func (h *Health) Connected() bool {
+	return h.connected.Load()
+}
+
+// Closed is closed when the connection is closed for good: by the client, or
+// because the reconnect attempts ran out.
+func (h *Health) Closed() <-chan struct{} {
+	return h.closed
+}
+
+// handlers returns the connection callbacks that keep the state current.
+func (h *Health) handlers() []nats.Option {
+	return []nats.Option{
+		nats.DisconnectErrHandler(func(natsConnection *nats.Conn, err error) {
+			h.setConnected(false)
+			h.addCounter(metricDisconnects)
+			h.log.Warn("Disconnected from NATS, reconnecting: %v", err)
+		}),
+		nats.ReconnectHandler(func(natsConnection *nats.Conn) {
+			h.setConnected(true)
+			h.addCounter(metricReconnects)
+			h.log.Info("Reconnected to NATS at %s", natsConnection.ConnectedUrl())
+		}),
+		nats.ClosedHandler(func(natsConnection *nats.Conn) {
+			h.setConnected(false)
+			h.closeOnce.Do(func() { close(h.closed) })
+
+			lastErr := natsConnection.LastError()
+			if lastErr != nil {
+				h.log.Error("NATS connection closed: %v", lastErr)
+			}
+		}),
+	}
+}
+
+func (h *Health) setConnected(connected bool) {
+	h.connected.Store(connected)
+
+	if h.registry == nil {
+		return
+	}
+
+	value := 0.0
+	if connected {
+		value = 1
+	}
+
+	h.registry.SetGauge(metricConnected, value)
+}
+
+func (h *Health) addCounter(name string) {
+	if h.registry != nil {
+		h.registry.AddCounter(name, 1)
+	}
+}
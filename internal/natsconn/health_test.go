@@ -0,0 +1,71 @@
+package natsconn_test
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/metrics"
+	"github.com/book-expert/tts-service/internal/natsconn"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth_TracksOutage(t *testing.T) {
+	t.Parallel()
+
+	log, err := logger.New(t.TempDir(), "test.log")
+	require.NoError(t, err)
+
+	natsServer := startServer(t, "", "", "")
+	registry := metrics.NewRegistry()
+	health := natsconn.NewHealth(registry, log)
+
+	natsConnection, err := natsconn.Connect(natsServer.ClientURL(), natsconn.Options{
+		Name: "",
+		Auth: natsconn.Auth{},
+		TLS:  natsconn.TLS{},
+		Reconnect: natsconn.Reconnect{
+			MaxAttempts: 0,
+			Wait:        10 * time.Millisecond,
+			Jitter:      time.Millisecond,
+		},
+		Health: health,
+	})
+	require.NoError(t, err)
+	t.Cleanup(natsConnection.Close)
+	assert.True(t, health.Connected())
+
+	// The broker comes back on the same port, as after a restart.
+	addr, ok := natsServer.Addr().(*net.TCPAddr)
+	require.True(t, ok)
+
+	opts := test.DefaultTestOptions
+	opts.Port = addr.Port
+	natsServer.Shutdown()
+
+	require.Eventually(t, func() bool { return !health.Connected() }, 5*time.Second, 10*time.Millisecond)
+	assert.InDelta(t, 0.0, registry.Snapshot().Gauges["nats.connected"], 0)
+
+	restarted := test.RunServer(&opts)
+	t.Cleanup(restarted.Shutdown)
+
+	require.Eventually(t, health.Connected, 5*time.Second, 10*time.Millisecond)
+
+	snapshot := registry.Snapshot()
+	assert.InDelta(t, 1.0, snapshot.Gauges["nats.connected"], 0)
+	assert.Equal(t, uint64(1), snapshot.Counters["nats.disconnects"])
+	assert.Equal(t, uint64(1), snapshot.Counters["nats.reconnects"])
+
+	natsConnection.Close()
+
+	select {
+	case <-health.Closed():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Closed was not signalled")
+	}
+
+	assert.False(t, health.Connected())
+}
@@ -0,0 +1,238 @@
+// Package natsconn connects to NATS with authentication, TLS and a reconnect
+// policy. Secrets are read from files or environment variables, so the
+// configuration only names them.
+package natsconn
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// Connection errors.
+var (
+	ErrConflictingAuth = errors.New("more than one NATS authentication method is configured")
+	ErrMissingSecret   = errors.New("NATS secret is not available")
+	ErrIncompleteTLS   = errors.New("NATS TLS client certificate needs both cert_file and key_file")
+)
+
+// Auth selects how the client authenticates. At most one method may be set:
+// a user with a password, a token, a credentials file (JWT and nkey seed), or
+// an nkey seed file. Password and token are read from the named environment
+// variable or file.
+type Auth struct {
+	User            string
+	PasswordEnv     string
+	PasswordFile    string
+	TokenEnv        string
+	TokenFile       string
+	CredentialsFile string
+	NKeySeedFile    string
+}
+
+// TLS configures the connection's TLS. CAFile verifies the server; CertFile and
+// KeyFile present a client certificate. Empty fields use the system defaults.
+type TLS struct {
+	CAFile   string
+	CertFile string
+	KeyFile  string
+}
+
+// Reconnect defaults, used for zero Reconnect fields.
+const (
+	DefaultReconnectWait   = 2 * time.Second
+	DefaultReconnectJitter = time.Second
+)
+
+// Reconnect controls how the client rides out broker outages. Between attempts
+// it waits Wait plus a random delay of up to Jitter, so a restarted broker is
+// not hit by every client at once.
+type Reconnect struct {
+	// MaxAttempts limits consecutive reconnect attempts; zero or less retries forever.
+	MaxAttempts int
+	Wait        time.Duration
+	Jitter      time.Duration
+}
+
+// Options configures a NATS connection.
+type Options struct {
+	// Name identifies the client in the server's monitoring.
+	Name      string
+	Auth      Auth
+	TLS       TLS
+	Reconnect Reconnect
+	// Health, when set, tracks the connection state.
+	Health *Health
+}
+
+// Connect connects to the NATS server at url.
+func Connect(url string, options Options) (*nats.Conn, error) {
+	natsOptions, err := ConnectOptions(options)
+	if err != nil {
+		return nil, err
+	}
+
+	natsConnection, err := nats.Connect(url, natsOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", url, err)
+	}
+
+	if options.Health != nil {
+		options.Health.setConnected(true)
+	}
+
+	return natsConnection, nil
+}
+
+// ConnectOptions returns the nats.go options for options, reading its secrets.
+func ConnectOptions(options Options) ([]nats.Option, error) {
+	natsOptions := reconnectOptions(options.Reconnect)
+
+	if options.Name != "" {
+		natsOptions = append(natsOptions, nats.Name(options.Name))
+	}
+
+	if options.Health != nil {
+		natsOptions = append(natsOptions, options.Health.handlers()...)
+	}
+
+	authOption, err := authOption(options.Auth)
+	if err != nil {
+		return nil, err
+	}
+
+	if authOption != nil {
+		natsOptions = append(natsOptions, authOption)
+	}
+
+	tlsOptions, err := tlsOptions(options.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(natsOptions, tlsOptions...), nil
+}
+
+// reconnectOptions returns the reconnect policy, filling in the defaults.
+func reconnectOptions(reconnect Reconnect) []nats.Option {
+	maxAttempts := reconnect.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = -1
+	}
+
+	wait := reconnect.Wait
+	if wait <= 0 {
+		wait = DefaultReconnectWait
+	}
+
+	jitter := reconnect.Jitter
+	if jitter <= 0 {
+		jitter = DefaultReconnectJitter
+	}
+
+	return []nats.Option{
+		nats.MaxReconnects(maxAttempts),
+		nats.ReconnectWait(wait),
+		nats.ReconnectJitter(jitter, jitter),
+	}
+}
+
+// authOption returns the option for the configured authentication method, or nil for none.
+func authOption(auth Auth) (nats.Option, error) {
+	methods := 0
+
+	for _, set := range []bool{
+		auth.User != "",
+		auth.TokenEnv != "" || auth.TokenFile != "",
+		auth.CredentialsFile != "",
+		auth.NKeySeedFile != "",
+	} {
+		if set {
+			methods++
+		}
+	}
+
+	if methods > 1 {
+		return nil, ErrConflictingAuth
+	}
+
+	switch {
+	case auth.User != "":
+		password, err := readSecret("password", auth.PasswordEnv, auth.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return nats.UserInfo(auth.User, password), nil
+	case auth.TokenEnv != "" || auth.TokenFile != "":
+		token, err := readSecret("token", auth.TokenEnv, auth.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		return nats.Token(token), nil
+	case auth.CredentialsFile != "":
+		return nats.UserCredentials(auth.CredentialsFile), nil
+	case auth.NKeySeedFile != "":
+		option, err := nats.NkeyOptionFromSeed(auth.NKeySeedFile)
+		if err != nil {
+			return nil, fmt.Errorf("%w: nkey seed file %s: %w", ErrMissingSecret, auth.NKeySeedFile, err)
+		}
+
+		return option, nil
+	default:
+		return nil, nil
+	}
+}
+
+// tlsOptions returns the options for the configured CA and client certificate.
+func tlsOptions(settings TLS) ([]nats.Option, error) {
+	var natsOptions []nats.Option
+
+	if settings.CAFile != "" {
+		natsOptions = append(natsOptions, nats.RootCAs(settings.CAFile))
+	}
+
+	if (settings.CertFile == "") != (settings.KeyFile == "") {
+		return nil, ErrIncompleteTLS
+	}
+
+	if settings.CertFile != "" {
+		natsOptions = append(natsOptions, nats.ClientCert(settings.CertFile, settings.KeyFile))
+	}
+
+	return natsOptions, nil
+}
+
+// readSecret reads a secret from exactly one of an environment variable and a
+// file. Surrounding whitespace, such as a file's trailing newline, is removed.
+func readSecret(name, envName, file string) (string, error) {
+	if envName != "" && file != "" {
+		return "", fmt.Errorf("%w: set either the %s environment variable or file, not both", ErrConflictingAuth, name)
+	}
+
+	var secret string
+
+	switch {
+	case envName != "":
+		secret = os.Getenv(envName)
+	case file != "":
+		data, err := os.ReadFile(file) // #nosec G304 -- the secret file is chosen by the operator
+		if err != nil {
+			return "", fmt.Errorf("%w: %s file: %w", ErrMissingSecret, name, err)
+		}
+
+		secret = string(data)
+	}
+
+	secret = strings.TrimSpace(secret)
+	if secret == "" {
+		return "", fmt.Errorf("%w: %s is empty (env %q, file %q)", ErrMissingSecret, name, envName, file)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,156 @@
+// Package natsconn_test tests NATS authentication and TLS options.
+package natsconn_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/natsconn"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// startServer starts a NATS server with the given credentials.
+func startServer(t *testing.T, user, password, token string) *server.Server {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1
+	opts.Username = user
+	opts.Password = password
+	opts.Authorization = token
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	return natsServer
+}
+
+// writeSecret writes a secret file with a trailing newline, as editors do.
+func writeSecret(t *testing.T, secret string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte(secret+"\n"), 0o600))
+
+	return path
+}
+
+func TestConnect_UserPasswordFromFile(t *testing.T) {
+	t.Parallel()
+
+	natsServer := startServer(t, "tts", "s3cret", "")
+
+	options := natsconn.Options{
+		Name: "tts-service-test",
+		Auth: natsconn.Auth{
+			User:            "tts",
+			PasswordEnv:     "",
+			PasswordFile:    writeSecret(t, "s3cret"),
+			TokenEnv:        "",
+			TokenFile:       "",
+			CredentialsFile: "",
+			NKeySeedFile:    "",
+		},
+		TLS:       natsconn.TLS{},
+		Reconnect: natsconn.Reconnect{},
+		Health:    nil,
+	}
+
+	natsConnection, err := natsconn.Connect(natsServer.ClientURL(), options)
+	require.NoError(t, err)
+	natsConnection.Close()
+
+	options.Auth.PasswordFile = writeSecret(t, "wrong")
+	_, err = natsconn.Connect(natsServer.ClientURL(), options)
+	require.Error(t, err)
+}
+
+func TestConnect_TokenFromEnv(t *testing.T) {
+	t.Setenv("TTS_TEST_NATS_TOKEN", "t0ken")
+
+	natsServer := startServer(t, "", "", "t0ken")
+
+	options := natsconn.Options{
+		Name: "",
+		Auth: natsconn.Auth{
+			User:            "",
+			PasswordEnv:     "",
+			PasswordFile:    "",
+			TokenEnv:        "TTS_TEST_NATS_TOKEN",
+			TokenFile:       "",
+			CredentialsFile: "",
+			NKeySeedFile:    "",
+		},
+		TLS:       natsconn.TLS{},
+		Reconnect: natsconn.Reconnect{},
+		Health:    nil,
+	}
+
+	natsConnection, err := natsconn.Connect(natsServer.ClientURL(), options)
+	require.NoError(t, err)
+	natsConnection.Close()
+}
+
+func TestConnectOptions_Errors(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		options natsconn.Options
+		wantErr error
+	}{
+		{
+			name: "two methods",
+			options: natsconn.Options{Name: "", Auth: natsconn.Auth{
+				User: "tts", PasswordEnv: "", PasswordFile: "", TokenEnv: "", TokenFile: "",
+				CredentialsFile: "user.creds", NKeySeedFile: "",
+			}, TLS: natsconn.TLS{}, Reconnect: natsconn.Reconnect{}, Health: nil},
+			wantErr: natsconn.ErrConflictingAuth,
+		},
+		{
+			name: "missing password file",
+			options: natsconn.Options{Name: "", Auth: natsconn.Auth{
+				User: "tts", PasswordEnv: "", PasswordFile: filepath.Join(t.TempDir(), "missing"), TokenEnv: "",
+				TokenFile: "", CredentialsFile: "", NKeySeedFile: "",
+			}, TLS: natsconn.TLS{}, Reconnect: natsconn.Reconnect{}, Health: nil},
+			wantErr: natsconn.ErrMissingSecret,
+		},
+		{
+			name: "unset token variable",
+			options: natsconn.Options{Name: "", Auth: natsconn.Auth{
+				User: "", PasswordEnv: "", PasswordFile: "", TokenEnv: "TTS_TEST_UNSET_NATS_TOKEN",
+				TokenFile: "", CredentialsFile: "", NKeySeedFile: "",
+			}, TLS: natsconn.TLS{}, Reconnect: natsconn.Reconnect{}, Health: nil},
+			wantErr: natsconn.ErrMissingSecret,
+		},
+		{
+			name: "client cert without key",
+			options: natsconn.Options{Name: "", Auth: natsconn.Auth{}, TLS: natsconn.TLS{
+				CAFile: "", CertFile: "client.pem", KeyFile: "",
+			}, Reconnect: natsconn.Reconnect{}, Health: nil},
+			wantErr: natsconn.ErrIncompleteTLS,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := natsconn.ConnectOptions(tc.options)
+			require.ErrorIs(t, err, tc.wantErr)
+		})
+	}
+}
+
+func TestConnectOptions_NoAuth(t *testing.T) {
+	t.Parallel()
+
+	natsOptions, err := natsconn.ConnectOptions(natsconn.Options{
+		Name: "", Auth: natsconn.Auth{}, TLS: natsconn.TLS{}, Reconnect: natsconn.Reconnect{}, Health: nil,
+	})
+	require.NoError(t, err)
+	assert.Len(t, natsOptions, 3, "only the reconnect policy")
+}
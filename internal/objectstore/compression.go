@@ -0,0 +1,145 @@
+package objectstore
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings of compressed objects.
+const (
+	EncodingGzip = "gzip"
+	EncodingZstd = "zstd"
+)
+
+// EncodingMetadata is the object metadata key that names the content encoding
+// of a compressed object.
+const EncodingMetadata = "content-encoding"
+
+// DefaultMinCompressBytes is the smallest object compressed when
+// Compression.MinBytes is zero; smaller ones rarely shrink.
+const DefaultMinCompressBytes = 512
+
+// maxDecompressedBytes bounds a decompressed object, so a corrupt or hostile
+// object cannot exhaust memory.
+const maxDecompressedBytes = 1 << 30
+
+var (
+	// ErrUnknownEncoding indicates a content encoding that is not gzip or zstd.
+	ErrUnknownEncoding = errors.New("unknown content encoding")
+	// ErrDecompress indicates a compressed object that cannot be decompressed.
+	ErrDecompress = errors.New("failed to decompress object")
+)
+
+// wavMagic starts WAV audio, which is stored as it is: general-purpose
+// compressors gain little on PCM samples.
+var wavMagic = []byte("RIFF")
+
+// Compression compresses the text objects a store uploads. The zero value
+// stores every object as it is.
+type Compression struct {
+	// Encoding is EncodingGzip, EncodingZstd or empty for no compression.
+	Encoding string
+	// MinBytes is the smallest object that is compressed; zero uses
+	// DefaultMinCompressBytes.
+	MinBytes int
+}
+
+// Validate reports an unknown encoding.
+func (c Compression) Validate() error {
+	switch c.Encoding {
+	case "", EncodingGzip, EncodingZstd:
+		return nil
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownEncoding, c.Encoding)
+	}
+}
+
+// compress returns data compressed with the configured encoding, and that
+// encoding, or data and "" when it is audio, too small, or does not shrink.
+func (c Compression) compress(data []byte) ([]byte, string, error) {
+	minBytes := c.MinBytes
+	if minBytes <= 0 {
+		minBytes = DefaultMinCompressBytes
+	}
+
+	if c.Encoding == "" || len(data) < minBytes || bytes.HasPrefix(data, wavMagic) {
+		return data, "", nil
+	}
+
+	var buffer bytes.Buffer
+
+	var writer io.WriteCloser
+
+	switch c.Encoding {
+	case EncodingGzip:
+		writer = gzip.NewWriter(&buffer)
+	case EncodingZstd:
+		encoder, err := zstd.NewWriter(&buffer)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+
+		writer = encoder
+	default:
+		return nil, "", fmt.Errorf("%w: %q", ErrUnknownEncoding, c.Encoding)
+	}
+
+	_, err := writer.Write(data)
+	if err == nil {
+		err = writer.Close()
+	}
+
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to compress with %s: %w", c.Encoding, err)
+	}
+
+	if buffer.Len() >= len(data) {
+		return data, "", nil
+	}
+
+	return buffer.Bytes(), c.Encoding, nil
+}
+
+// decompress reverses compress for data stored with encoding.
+func decompress(encoding string, data []byte) ([]byte, error) {
+	var reader io.Reader
+
+	switch encoding {
+	case "":
+		return data, nil
+	case EncodingGzip:
+		gzipReader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDecompress, err)
+		}
+		defer gzipReader.Close()
+
+		reader = gzipReader
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(bytes.NewReader(data), zstd.WithDecoderMaxMemory(maxDecompressedBytes))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrDecompress, err)
+		}
+		defer decoder.Close()
+
+		reader = decoder
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownEncoding, encoding)
+	}
+
+	plain, err := io.ReadAll(io.LimitReader(reader, maxDecompressedBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecompress, err)
+	}
+
+	if len(plain) > maxDecompressedBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", ErrDecompress, maxDecompressedBytes)
+	}
+
+	return plain, nil
+}
@@ -0,0 +1,279 @@
+package objectstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// dataKeySize is the size of the AES-256 key generated for every object.
+const dataKeySize = 32
+
+// envelopeMagic starts every encrypted object, so plaintext objects can be
+// told apart.
+var envelopeMagic = []byte("TTSENC\x00\x01")
+
+var (
+	// ErrInvalidKey indicates a key-encryption key that is not 16, 24 or 32
+	// bytes long, or a key ID that is unknown or too long.
+	ErrInvalidKey = errors.New("invalid encryption key")
+	// ErrNotEncrypted indicates a plaintext object read from a store that
+	// requires encryption.
+	ErrNotEncrypted = errors.New("object is not encrypted")
+	// ErrDecrypt indicates an encrypted object that cannot be decrypted: it is
+	// corrupt, was tampered with, was stored under another key, or its key is
+	// gone.
+	ErrDecrypt = errors.New("failed to decrypt object")
+)
+
+// KeyProvider protects the data keys of objects with key-encryption keys, as a
+// KMS does. Keys are named by ID so old objects stay readable after rotation.
+type KeyProvider interface {
+	// WrapKey encrypts dataKey with the current key-encryption key and returns
+	// that key's ID.
+	WrapKey(ctx context.Context, dataKey []byte) (keyID string, wrapped []byte, err error)
+	// UnwrapKey decrypts a data key wrapped with the key named keyID.
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// StaticKeys is a KeyProvider with key-encryption keys held in memory, for
+// example from the configuration. It wraps data keys with AES-GCM.
+type StaticKeys struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewStaticKeys creates a provider that wraps new data keys with the key named
+// current and unwraps them with any of keys, which are AES keys by ID.
+func NewStaticKeys(current string, keys map[string][]byte) (*StaticKeys, error) {
+	provider := &StaticKeys{current: current, keys: make(map[string]cipher.AEAD, len(keys))}
+
+	for keyID, key := range keys {
+		if keyID == "" || len(keyID) > 255 {
+			return nil, fmt.Errorf("%w: key ID %q must have 1 to 255 bytes", ErrInvalidKey, keyID)
+		}
+
+		aead, err := newGCM(key)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %w", ErrInvalidKey, keyID, err)
+		}
+
+		provider.keys[keyID] = aead
+	}
+
+	_, ok := provider.keys[current]
+	if !ok {
+		return nil, fmt.Errorf("%w: the current key %q is not among the keys", ErrInvalidKey, current)
+	}
+
+	return provider, nil
+}
+
+// WrapKey encrypts dataKey with the current key, bound to its ID.
+func (s *StaticKeys) WrapKey(_ context.Context, dataKey []byte) (string, []byte, error) {
+	wrapped, err := seal(s.keys[s.current], dataKey, []byte(s.current))
+	if err != nil {
+		return "", nil, err
+	}
+
+	return s.current, wrapped, nil
+}
+
+// UnwrapKey decrypts a data key wrapped by WrapKey.
+func (s *StaticKeys) UnwrapKey(_ context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := s.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown key %q", ErrInvalidKey, keyID)
+	}
+
+	return open(aead, wrapped, []byte(keyID))
+}
+
+// EncryptedStore encrypts objects before they reach another store and decrypts
+// them after download, so callers of Upload and Download see plaintext. Text
+// objects are compressed first when compression is set. Each
+// object is sealed with AES-256-GCM under its own data key, which is stored
+// with it wrapped by the KeyProvider. The object's key is authenticated, so an
+// object copied to another key does not decrypt.
+type EncryptedStore struct {
+	store          core.ObjectStore
+	keys           KeyProvider
+	allowPlaintext bool
+	compression    Compression
+}
+
+// NewEncrypted wraps store with envelope encryption. With allowPlaintext,
+// objects stored without encryption, for example text uploaded by a producer
+// that does not encrypt, are downloaded as they are; otherwise they are
+// refused.
+func NewEncrypted(store core.ObjectStore, keys KeyProvider, allowPlaintext bool) *EncryptedStore {
+	return &EncryptedStore{store: store, keys: keys, allowPlaintext: allowPlaintext, compression: Compression{}}
+}
+
+// SetCompression compresses text objects before they are encrypted, since
+// encrypted data does not compress. The encoding is sealed with the object.
+func (e *EncryptedStore) SetCompression(compression Compression) error {
+	err := compression.Validate()
+	if err != nil {
+		return err
+	}
+
+	e.compression = compression
+
+	return nil
+}
+
+// Upload encrypts data and stores it under key.
+func (e *EncryptedStore) Upload(ctx context.Context, key string, data []byte) error {
+	dataKey := make([]byte, dataKeySize)
+
+	_, err := rand.Read(dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to generate data key for object '%s': %w", key, err)
+	}
+
+	keyID, wrapped, err := e.keys.WrapKey(ctx, dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to wrap data key for object '%s': %w", key, err)
+	}
+
+	if len(keyID) == 0 || len(keyID) > 255 || len(wrapped) > 0xffff {
+		return fmt.Errorf("%w: wrapped data key for object '%s' does not fit the envelope", ErrInvalidKey, key)
+	}
+
+	compressed, encoding, err := e.compression.compress(data)
+	if err != nil {
+		return fmt.Errorf("failed to compress object '%s': %w", key, err)
+	}
+
+	// The content encoding leads the sealed payload, so it is authenticated.
+	payload := make([]byte, 0, 1+len(encoding)+len(compressed))
+	payload = append(payload, byte(len(encoding)))
+	payload = append(payload, encoding...)
+	payload = append(payload, compressed...)
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt object '%s': %w", key, err)
+	}
+
+	sealed, err := seal(aead, payload, []byte(key))
+	if err != nil {
+		return fmt.Errorf("failed to encrypt object '%s': %w", key, err)
+	}
+
+	envelope := make([]byte, 0, len(envelopeMagic)+1+len(keyID)+2+len(wrapped)+len(sealed))
+	envelope = append(envelope, envelopeMagic...)
+	envelope = append(envelope, byte(len(keyID)))
+	envelope = append(envelope, keyID...)
+	envelope = binary.BigEndian.AppendUint16(envelope, uint16(len(wrapped)))
+	envelope = append(envelope, wrapped...)
+	envelope = append(envelope, sealed...)
+
+	return e.store.Upload(ctx, key, envelope)
+}
+
+// Download fetches the object at key and decrypts it.
+func (e *EncryptedStore) Download(ctx context.Context, key string) ([]byte, error) {
+	envelope, err := e.store.Download(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !bytes.HasPrefix(envelope, envelopeMagic) {
+		if e.allowPlaintext {
+			return envelope, nil
+		}
+
+		return nil, fmt.Errorf("%w: '%s'", ErrNotEncrypted, key)
+	}
+
+	rest := envelope[len(envelopeMagic):]
+
+	if len(rest) < 1 || len(rest) < 1+int(rest[0])+2 {
+		return nil, fmt.Errorf("%w: '%s': truncated envelope", ErrDecrypt, key)
+	}
+
+	keyID := string(rest[1 : 1+int(rest[0])])
+	rest = rest[1+int(rest[0]):]
+	wrappedLen := int(binary.BigEndian.Uint16(rest))
+	rest = rest[2:]
+
+	if len(rest) < wrappedLen {
+		return nil, fmt.Errorf("%w: '%s': truncated envelope", ErrDecrypt, key)
+	}
+
+	dataKey, err := e.keys.UnwrapKey(ctx, keyID, rest[:wrappedLen])
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", ErrDecrypt, key, err)
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", ErrDecrypt, key, err)
+	}
+
+	payload, err := open(aead, rest[wrappedLen:], []byte(key))
+	if err != nil {
+		return nil, fmt.Errorf("%w: '%s': %w", ErrDecrypt, key, err)
+	}
+
+	if len(payload) == 0 || len(payload) < 1+int(payload[0]) {
+		return nil, fmt.Errorf("%w: '%s': truncated payload", ErrDecrypt, key)
+	}
+
+	data, err := decompress(string(payload[1:1+int(payload[0])]), payload[1+int(payload[0]):])
+	if err != nil {
+		return nil, fmt.Errorf("object '%s': %w", key, err)
+	}
+
+	return data, nil
+}
+
+// newGCM creates AES-GCM with key.
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+
+	return aead, nil
+}
+
+// seal encrypts plaintext with a random nonce, which it prepends.
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+// open decrypts the output of seal.
+func open(aead cipher.AEAD, sealed, additional []byte) ([]byte, error) {
+	if len(sealed) < aead.NonceSize() {
+		return nil, fmt.Errorf("%w: too short", ErrDecrypt)
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additional)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+
+	return plaintext, nil
+}
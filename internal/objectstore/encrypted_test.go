@@ -0,0 +1,110 @@
+package objectstore_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/objectstore"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryStore keeps objects in a map.
+type memoryStore map[string][]byte
+
+func (m memoryStore) Download(_ context.Context, key string) ([]byte, error) {
+	return m[key], nil
+}
+
+func (m memoryStore) Upload(_ context.Context, key string, data []byte) error {
+	m[key] = data
+
+	return nil
+}
+
+func TestEncryptedStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	oldKey := bytes.Repeat([]byte{1}, 32)
+	newKey := bytes.Repeat([]byte{2}, 16)
+	plaintext := []byte("The secret chapter.")
+
+	backing := memoryStore{}
+
+	oldKeys, err := objectstore.NewStaticKeys("2025", map[string][]byte{"2025": oldKey})
+	require.NoError(t, err)
+	require.NoError(t, objectstore.NewEncrypted(backing, oldKeys, false).Upload(ctx, "old.wav", plaintext))
+
+	keys, err := objectstore.NewStaticKeys("2026", map[string][]byte{"2025": oldKey, "2026": newKey})
+	require.NoError(t, err)
+
+	store := objectstore.NewEncrypted(backing, keys, false)
+	require.NoError(t, store.Upload(ctx, "new.wav", plaintext))
+	assert.NotContains(t, string(backing["new.wav"]), "secret", "the object is encrypted at rest")
+
+	for _, key := range []string{"old.wav", "new.wav"} {
+		data, downloadErr := store.Download(ctx, key)
+		require.NoError(t, downloadErr, key)
+		assert.Equal(t, plaintext, data, key)
+	}
+
+	backing["copy.wav"] = backing["new.wav"]
+	_, err = store.Download(ctx, "copy.wav")
+	require.ErrorIs(t, err, objectstore.ErrDecrypt, "an object is bound to its key")
+
+	tampered := bytes.Clone(backing["new.wav"])
+	tampered[len(tampered)-1] ^= 1
+	backing["tampered.wav"] = tampered
+	_, err = store.Download(ctx, "tampered.wav")
+	require.ErrorIs(t, err, objectstore.ErrDecrypt)
+
+	_, err = objectstore.NewEncrypted(backing, oldKeys, false).Download(ctx, "new.wav")
+	require.ErrorIs(t, err, objectstore.ErrDecrypt, "a removed key cannot decrypt")
+}
+
+func TestEncryptedStore_Plaintext(t *testing.T) {
+	t.Parallel()
+
+	keys, err := objectstore.NewStaticKeys("k", map[string][]byte{"k": bytes.Repeat([]byte{3}, 24)})
+	require.NoError(t, err)
+
+	backing := memoryStore{"text.txt": []byte("plain")}
+
+	data, err := objectstore.NewEncrypted(backing, keys, true).Download(context.Background(), "text.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", string(data))
+
+	_, err = objectstore.NewEncrypted(backing, keys, false).Download(context.Background(), "text.txt")
+	require.ErrorIs(t, err, objectstore.ErrNotEncrypted)
+}
+
+func TestEncryptedStore_Compression(t *testing.T) {
+	t.Parallel()
+
+	keys, err := objectstore.NewStaticKeys("k", map[string][]byte{"k": bytes.Repeat([]byte{4}, 32)})
+	require.NoError(t, err)
+
+	backing := memoryStore{}
+	store := objectstore.NewEncrypted(backing, keys, false)
+	require.NoError(t, store.SetCompression(objectstore.Compression{Encoding: objectstore.EncodingZstd, MinBytes: 0}))
+
+	text := bytes.Repeat([]byte("Chapter one. "), 500)
+	require.NoError(t, store.Upload(context.Background(), "book.txt", text))
+	assert.Less(t, len(backing["book.txt"]), len(text)/10, "text is compressed before it is encrypted")
+
+	data, err := store.Download(context.Background(), "book.txt")
+	require.NoError(t, err)
+	assert.Equal(t, text, data)
+}
+
+func TestNewStaticKeys_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := objectstore.NewStaticKeys("k", map[string][]byte{"k": []byte("short")})
+	require.ErrorIs(t, err, objectstore.ErrInvalidKey)
+
+	_, err = objectstore.NewStaticKeys("missing", map[string][]byte{"k": bytes.Repeat([]byte{1}, 32)})
+	require.ErrorIs(t, err, objectstore.ErrInvalidKey)
+}
@@ -1,13 +1,18 @@
-// Package objectstore provides a NATS-based implementation of the ObjectStore interface.
+// Package objectstore provides a NATS-based implementation of the ObjectStore
+// interface, and envelope encryption for any ObjectStore.
 package objectstore
 
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"strings"
 
+	"github.com/book-expert/tts-service/internal/core"
 	"github.com/nats-io/nats.go"
 	"github.com/nats-io/nats.go/jetstream"
 )
@@ -17,6 +22,7 @@ type NatsObjectStore struct {
 	jetstreamContext nats.JetStreamContext
 	bucket           string
 	store            nats.ObjectStore
+	compression      Compression
 }
 
 // New creates and initializes a new NatsObjectStore.
@@ -51,10 +57,31 @@ func New(jetstreamContext nats.JetStreamContext, bucketName string) (*NatsObject
 		jetstreamContext: jetstreamContext,
 		bucket:           bucketName,
 		store:            store,
+		compression:      Compression{},
 	}, nil
 }
 
-// Download retrieves an object from the NATS object store.
+// SetCompression compresses the text objects uploaded from now on. Objects
+// are decompressed on download whatever the setting, by their
+// content-encoding metadata.
+func (n *NatsObjectStore) SetCompression(compression Compression) error {
+	err := compression.Validate()
+	if err != nil {
+		return err
+	}
+
+	n.compression = compression
+
+	return nil
+}
+
+// ChecksumMetadata is the object metadata key that holds the hex-encoded
+// SHA-256 digest of an object's content.
+const ChecksumMetadata = "sha256"
+
+// Download retrieves an object from the NATS object store and decompresses
+// it. Objects uploaded with a checksum are verified; a mismatch wraps
+// core.ErrChecksumMismatch.
 func (n *NatsObjectStore) Download(_ context.Context, key string) ([]byte, error) {
 	obj, err := n.store.Get(key)
 	if err != nil {
@@ -72,20 +99,50 @@ func (n *NatsObjectStore) Download(_ context.Context, key string) ([]byte, error
 		return data, fmt.Errorf("failed to close object '%s': %w", key, closeErr)
 	}
 
+	info, err := obj.Info()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get info of object '%s': %w", key, err)
+	}
+
+	data, err = decompress(info.Metadata[EncodingMetadata], data)
+	if err != nil {
+		return nil, fmt.Errorf("object '%s' in bucket '%s': %w", key, n.bucket, err)
+	}
+
+	want := info.Metadata[ChecksumMetadata]
+	if want != "" {
+		digest := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(digest[:]), want) {
+			return nil, fmt.Errorf("%w: object '%s' in bucket '%s': expected %s, got %x",
+				core.ErrChecksumMismatch, key, n.bucket, want, digest)
+		}
+	}
+
 	return data, nil
 }
 
-// Upload saves an object to the NATS object store.
+// Upload saves an object to the NATS object store, compressed when it is text
+// and compression is set, with the SHA-256 digest of data in its metadata.
 func (n *NatsObjectStore) Upload(_ context.Context, key string, data []byte) error {
-	reader := bytes.NewReader(data)
+	digest := sha256.Sum256(data)
+	metadata := map[string]string{ChecksumMetadata: hex.EncodeToString(digest[:])}
 
-	_, err := n.store.Put(&nats.ObjectMeta{
+	stored, encoding, err := n.compression.compress(data)
+	if err != nil {
+		return fmt.Errorf("failed to compress object '%s': %w", key, err)
+	}
+
+	if encoding != "" {
+		metadata[EncodingMetadata] = encoding
+	}
+
+	_, err = n.store.Put(&nats.ObjectMeta{
 		Name:        key,
 		Description: "",
 		Headers:     nil,
-		Metadata:    nil,
+		Metadata:    metadata,
 		Opts:        nil,
-	}, reader)
+	}, bytes.NewReader(stored))
 	if err != nil {
 		return fmt.Errorf("failed to put object '%s' to bucket '%s': %w", key, n.bucket, err)
 	}
@@ -3,12 +3,15 @@ package objectstore_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/book-expert/tts-service/internal/core"
 	"github.com/book-expert/tts-service/internal/objectstore"
 	"github.com/nats-io/nats-server/v2/server"
 	"github.com/nats-io/nats-server/v2/test"
 	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -60,3 +63,96 @@ func TestNatsObjectStore_UploadDownload(t *testing.T) {
 	// 5. Assert
 	require.Equal(t, uploadData, downloadData)
 }
+
+func TestNatsObjectStore_DetectsCorruption(t *testing.T) {
+	t.Parallel()
+
+	natsServer, natsConnection := StartTestServer(t)
+	defer natsServer.Shutdown()
+	defer natsConnection.Close()
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	store, err := objectstore.New(jetstreamContext, "corrupt-bucket")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	require.NoError(t, store.Upload(ctx, "chunk.wav", []byte("audio")))
+
+	raw, err := jetstreamContext.ObjectStore("corrupt-bucket")
+	require.NoError(t, err)
+
+	info, err := raw.GetInfo("chunk.wav")
+	require.NoError(t, err)
+	require.NotEmpty(t, info.Metadata[objectstore.ChecksumMetadata], "the digest is stored with the object")
+
+	// Replace the content but keep the digest of the original.
+	_, err = raw.Put(&nats.ObjectMeta{
+		Name:        "chunk.wav",
+		Description: "",
+		Headers:     nil,
+		Metadata:    info.Metadata,
+		Opts:        nil,
+	}, strings.NewReader("audjo"))
+	require.NoError(t, err)
+
+	_, err = store.Download(ctx, "chunk.wav")
+	require.ErrorIs(t, err, core.ErrChecksumMismatch)
+
+	_, err = raw.PutBytes("foreign.txt", []byte("text"))
+	require.NoError(t, err)
+
+	data, err := store.Download(ctx, "foreign.txt")
+	require.NoError(t, err, "objects without a digest are not verified")
+	require.Equal(t, "text", string(data))
+}
+
+func TestNatsObjectStore_Compression(t *testing.T) {
+	t.Parallel()
+
+	natsServer, natsConnection := StartTestServer(t)
+	defer natsServer.Shutdown()
+	defer natsConnection.Close()
+
+	jetstreamContext, err := natsConnection.JetStream()
+	require.NoError(t, err)
+
+	store, err := objectstore.New(jetstreamContext, "compressed-bucket")
+	require.NoError(t, err)
+
+	raw, err := jetstreamContext.ObjectStore("compressed-bucket")
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	text := []byte(strings.Repeat("It was a dark and stormy night. ", 200))
+	audio := append([]byte("RIFF"), text...)
+
+	for _, encoding := range []string{objectstore.EncodingGzip, objectstore.EncodingZstd} {
+		require.NoError(t, store.SetCompression(objectstore.Compression{Encoding: encoding, MinBytes: 0}))
+		require.NoError(t, store.Upload(ctx, encoding+".txt", text))
+		require.NoError(t, store.Upload(ctx, encoding+".wav", audio))
+
+		info, infoErr := raw.GetInfo(encoding + ".txt")
+		require.NoError(t, infoErr)
+		assert.Equal(t, encoding, info.Metadata[objectstore.EncodingMetadata])
+		assert.Less(t, info.Size, uint64(len(text))/10, encoding)
+
+		info, infoErr = raw.GetInfo(encoding + ".wav")
+		require.NoError(t, infoErr)
+		assert.Empty(t, info.Metadata[objectstore.EncodingMetadata], "audio is stored as it is")
+
+		data, downloadErr := store.Download(ctx, encoding+".txt")
+		require.NoError(t, downloadErr)
+		assert.Equal(t, text, data)
+	}
+
+	require.NoError(t, store.SetCompression(objectstore.Compression{Encoding: "", MinBytes: 0}))
+
+	data, err := store.Download(ctx, "zstd.txt")
+	require.NoError(t, err, "objects are decompressed whatever the setting")
+	assert.Equal(t, text, data)
+
+	err = store.SetCompression(objectstore.Compression{Encoding: "brotli", MinBytes: 0})
+	require.ErrorIs(t, err, objectstore.ErrUnknownEncoding)
+}
@@ -0,0 +1,131 @@
+// Package quality checks synthesized speech for the failures neural TTS is
+// prone to: chunks that are silent, heavily clipped, or far too short or long
+// for their text.
+package quality
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Gate defaults.
+const (
+	// DefaultSilenceDB is the peak level, in dBFS, below which a chunk is silent.
+	DefaultSilenceDB = -50.0
+	// DefaultMaxClippedRatio is the share of samples allowed at full scale.
+	DefaultMaxClippedRatio = 0.01
+	// DefaultMinCharsPerSecond is the slowest plausible pace at rate 1.
+	DefaultMinCharsPerSecond = 4.0
+	// DefaultMaxCharsPerSecond is the fastest plausible pace at rate 1.
+	DefaultMaxCharsPerSecond = 40.0
+)
+
+const (
+	// clipLevel is the magnitude from which a sample counts as clipped.
+	clipLevel = 0.999
+	// durationSlack allows for the pauses a backend adds around the speech,
+	// which matter most for short texts.
+	durationSlack = 2 * time.Second
+)
+
+// ErrRejected indicates audio that failed the gate.
+var ErrRejected = errors.New("audio failed the quality gate")
+
+// Gate holds the limits audio is checked against. Zero fields use the defaults.
+type Gate struct {
+	// SilenceDB rejects audio whose peak is below it, in dBFS.
+	SilenceDB float64
+	// MaxClippedRatio rejects audio with a larger share of clipped samples.
+	MaxClippedRatio float64
+	// MinCharsPerSecond and MaxCharsPerSecond bound the pace of the speech at
+	// rate 1, counting characters as worker estimates do.
+	MinCharsPerSecond float64
+	MaxCharsPerSecond float64
+}
+
+// Check returns an error wrapping ErrRejected when audio, the speech for text
+// spoken at rate, where zero means 1, is silent, clipped or of an implausible
+// duration.
+func (g Gate) Check(audio wav.Audio, text []byte, rate float64) error {
+	g = g.withDefaults()
+
+	peak, clipped := levels(audio.Samples)
+
+	peakDB := math.Inf(-1)
+	if peak > 0 {
+		peakDB = 20 * math.Log10(peak)
+	}
+
+	if peakDB < g.SilenceDB {
+		return fmt.Errorf("%w: the audio is silent, its peak is %.1f dBFS", ErrRejected, peakDB)
+	}
+
+	clippedRatio := float64(clipped) / float64(len(audio.Samples))
+	if clippedRatio > g.MaxClippedRatio {
+		return fmt.Errorf("%w: %.1f%% of the samples are clipped, the limit is %.1f%%",
+			ErrRejected, 100*clippedRatio, 100*g.MaxClippedRatio)
+	}
+
+	if rate <= 0 {
+		rate = 1
+	}
+
+	characters := utf8.RuneCountInString(strings.Join(strings.Fields(string(text)), " "))
+	duration := time.Duration(float64(audio.Frames()) / float64(audio.SampleRate) * float64(time.Second))
+	shortest := time.Duration(float64(characters) / (g.MaxCharsPerSecond * rate) * float64(time.Second))
+	longest := time.Duration(float64(characters)/(g.MinCharsPerSecond*rate)*float64(time.Second)) + durationSlack
+
+	if duration < shortest || duration > longest {
+		return fmt.Errorf("%w: %s of audio for %d characters, expected %s to %s",
+			ErrRejected, duration.Round(time.Millisecond), characters,
+			shortest.Round(time.Millisecond), longest.Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+// withDefaults fills the zero limits of g.
+func (g Gate) withDefaults() Gate {
+	if g.SilenceDB == 0 {
+		g.SilenceDB = DefaultSilenceDB
+	}
+
+	if g.MaxClippedRatio == 0 {
+		g.MaxClippedRatio = DefaultMaxClippedRatio
+	}
+
+	if g.MinCharsPerSecond == 0 {
+		g.MinCharsPerSecond = DefaultMinCharsPerSecond
+	}
+
+	if g.MaxCharsPerSecond == 0 {
+		g.MaxCharsPerSecond = DefaultMaxCharsPerSecond
+	}
+
+	return g
+}
+
+// levels returns the peak magnitude of samples and the number clipped.
+func levels(samples []float32) (float64, int) {
+	var (
+		peak    float64
+		clipped int
+	)
+
+	for _, sample := range samples {
+		magnitude := math.Abs(float64(sample))
+		peak = max(peak, magnitude)
+
+		if magnitude >= clipLevel {
+			clipped++
+		}
+	}
+
+	return peak, clipped
+}
@@ -0,0 +1,64 @@
+package quality_test
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/quality"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tone returns seconds of a 440 Hz mono sine at 24 kHz with the given peak.
+func tone(amplitude, seconds float64) wav.Audio {
+	samples := make([]float32, int(seconds*24000))
+	for i := range samples {
+		samples[i] = float32(amplitude * math.Sin(2*math.Pi*440*float64(i)/24000))
+	}
+
+	return wav.Audio{SampleRate: 24000, Channels: 1, Samples: samples}
+}
+
+func TestGate_Check(t *testing.T) {
+	t.Parallel()
+
+	// 150 characters: 10 seconds at the usual 15 characters per second.
+	text := []byte(strings.Repeat("Fourteen char. ", 10))
+
+	clipped := tone(0.5, 10)
+	for i := 0; i < len(clipped.Samples); i += 50 {
+		clipped.Samples[i] = 1
+	}
+
+	for name, test := range map[string]struct {
+		gate   quality.Gate
+		audio  wav.Audio
+		text   []byte
+		rate   float64
+		reject bool
+	}{
+		"speech":           {quality.Gate{}, tone(0.5, 10), text, 0, false},
+		"silence":          {quality.Gate{}, tone(0, 10), text, 0, true},
+		"near silence":     {quality.Gate{}, tone(0.001, 10), text, 0, true},
+		"quiet speech":     {quality.Gate{SilenceDB: -70}, tone(0.001, 10), text, 0, false},
+		"no samples":       {quality.Gate{}, tone(0.5, 0), text, 0, true},
+		"clipped":          {quality.Gate{}, clipped, text, 0, true},
+		"clipping allowed": {quality.Gate{MaxClippedRatio: 0.05}, clipped, text, 0, false},
+		"truncated":        {quality.Gate{}, tone(0.5, 3), text, 0, true},
+		"babbling on":      {quality.Gate{}, tone(0.5, 45), text, 0, true},
+		"slow rate":        {quality.Gate{}, tone(0.5, 45), text, 0.5, false},
+		"fast rate":        {quality.Gate{}, tone(0.5, 25), text, 2, true},
+		"custom pace":      {quality.Gate{MinCharsPerSecond: 2}, tone(0.5, 45), text, 0, false},
+		"pauses":           {quality.Gate{}, tone(0.5, 2), []byte("Hi."), 0, false},
+		"long pauses":      {quality.Gate{}, tone(0.5, 10), []byte("Hi."), 0, true},
+	} {
+		err := test.gate.Check(test.audio, test.text, test.rate)
+		if test.reject {
+			require.ErrorIs(t, err, quality.ErrRejected, name)
+		} else {
+			assert.NoError(t, err, name)
+		}
+	}
+}
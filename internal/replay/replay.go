@@ -0,0 +1,144 @@
+// Package replay selects failed or archived TTS jobs and prepares them to be
+// published again, optionally with changed settings.
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Replay errors.
+var (
+	// ErrInvalidRecord indicates a record that holds no job event.
+	ErrInvalidRecord = errors.New("invalid replay record")
+	// ErrInvalidOverride indicates an override that does not fit the job event.
+	ErrInvalidOverride = errors.New("invalid override")
+)
+
+// Job is one job to replay.
+type Job struct {
+	// Event is the job message as it was originally published.
+	Event json.RawMessage
+	// WorkflowID is the workflow of the job.
+	WorkflowID string
+	// Time is when the job failed, or for an archived job event, its timestamp.
+	Time time.Time
+}
+
+// Decode reads one record: a core.TTSJobFailedEvent as published on the
+// failure subject, or a job event, e.g. from an archive.
+func Decode(data []byte) (Job, error) {
+	var failure core.TTSJobFailedEvent
+
+	err := json.Unmarshal(data, &failure)
+	if err != nil {
+		return Job{}, fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+	}
+
+	if len(failure.Event) > 0 && !failure.FailedAt.IsZero() {
+		event, decodeErr := decodeEvent(failure.Event)
+		if decodeErr != nil {
+			return Job{}, fmt.Errorf("failed job of workflow '%s': %w", failure.Header.WorkflowID, decodeErr)
+		}
+
+		return Job{Event: failure.Event, WorkflowID: event.Header.WorkflowID, Time: failure.FailedAt}, nil
+	}
+
+	event, err := decodeEvent(data)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return Job{Event: data, WorkflowID: event.Header.WorkflowID, Time: event.Header.Timestamp}, nil
+}
+
+// decodeEvent parses a job event, which must name the text to synthesize.
+// Messages the worker could not parse are kept as JSON strings and fail here.
+func decodeEvent(data []byte) (core.JobEvent, error) {
+	var event core.JobEvent
+
+	err := json.Unmarshal(data, &event)
+	if err != nil {
+		return core.JobEvent{}, fmt.Errorf("%w: not a job event: %w", ErrInvalidRecord, err)
+	}
+
+	if event.TextKey == "" {
+		return core.JobEvent{}, fmt.Errorf("%w: the job event has no text_key", ErrInvalidRecord)
+	}
+
+	return event, nil
+}
+
+// Filter selects the jobs to replay. Zero fields select every job.
+type Filter struct {
+	// WorkflowIDs lists the workflows to replay.
+	WorkflowIDs []string
+	// Since and Until bound Job.Time; Until is exclusive.
+	Since time.Time
+	Until time.Time
+}
+
+// Match reports whether the filter selects job.
+func (f Filter) Match(job Job) bool {
+	if len(f.WorkflowIDs) > 0 && !slices.Contains(f.WorkflowIDs, job.WorkflowID) {
+		return false
+	}
+
+	if !f.Since.IsZero() && job.Time.Before(f.Since) {
+		return false
+	}
+
+	if !f.Until.IsZero() && !job.Time.Before(f.Until) {
+		return false
+	}
+
+	return true
+}
+
+// Override sets top-level fields of a job event by their JSON names, such as
+// "voice", "model" or "temperature". A value that is valid JSON is used as
+// such, so "0.5" sets a number; any other value is a string.
+func Override(event json.RawMessage, overrides map[string]string) (json.RawMessage, error) {
+	if len(overrides) == 0 {
+		return event, nil
+	}
+
+	var fields map[string]json.RawMessage
+
+	err := json.Unmarshal(event, &fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidRecord, err)
+	}
+
+	for key, value := range overrides {
+		raw := json.RawMessage(value)
+
+		if !json.Valid(raw) {
+			raw, err = json.Marshal(value)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %w", ErrInvalidOverride, key, err)
+			}
+		}
+
+		fields[key] = raw
+	}
+
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOverride, err)
+	}
+
+	var check core.JobEvent
+
+	err = json.Unmarshal(updated, &check)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidOverride, err)
+	}
+
+	return updated, nil
+}
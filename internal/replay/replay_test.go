@@ -0,0 +1,91 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/replay"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const jobEvent = `{"header":{"timestamp":"2026-03-01T10:00:00Z","workflow_id":"book-1"},` +
+	`"text_key":"book-1/page-3.txt","page_number":3,"voice":"tara","temperature":0.7}`
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	failure := `{"header":{"workflow_id":"book-1"},"error_class":"synthesis","error":"chatllm exploded",` +
+		`"attempt":3,"event":` + jobEvent + `,"failed_at":"2026-03-02T08:30:00Z"}`
+
+	job, err := replay.Decode([]byte(failure))
+	require.NoError(t, err)
+	assert.JSONEq(t, jobEvent, string(job.Event), "the original job is replayed")
+	assert.Equal(t, "book-1", job.WorkflowID)
+	assert.Equal(t, time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC), job.Time, "failures are dated by failed_at")
+
+	job, err = replay.Decode([]byte(jobEvent))
+	require.NoError(t, err)
+	assert.JSONEq(t, jobEvent, string(job.Event))
+	assert.Equal(t, time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), job.Time, "archived jobs are dated by their header")
+
+	for name, record := range map[string]string{
+		"not JSON":           `chatllm exploded`,
+		"unparsable failure": `{"header":{},"event":"not json","failed_at":"2026-03-02T08:30:00Z"}`,
+		"no text key":        `{"header":{"workflow_id":"book-1"},"voice":"tara"}`,
+	} {
+		_, err = replay.Decode([]byte(record))
+		require.ErrorIs(t, err, replay.ErrInvalidRecord, name)
+	}
+}
+
+func TestFilter_Match(t *testing.T) {
+	t.Parallel()
+
+	job := replay.Job{Event: nil, WorkflowID: "book-1", Time: time.Date(2026, 3, 2, 8, 30, 0, 0, time.UTC)}
+
+	tests := []struct {
+		name   string
+		filter replay.Filter
+		want   bool
+	}{
+		{"everything", replay.Filter{WorkflowIDs: nil, Since: time.Time{}, Until: time.Time{}}, true},
+		{"workflow", replay.Filter{WorkflowIDs: []string{"book-2", "book-1"}, Since: time.Time{}, Until: time.Time{}}, true},
+		{"other workflow", replay.Filter{WorkflowIDs: []string{"book-2"}, Since: time.Time{}, Until: time.Time{}}, false},
+		{"since", replay.Filter{WorkflowIDs: nil, Since: job.Time, Until: time.Time{}}, true},
+		{"too early", replay.Filter{WorkflowIDs: nil, Since: job.Time.Add(time.Second), Until: time.Time{}}, false},
+		{"until is exclusive", replay.Filter{WorkflowIDs: nil, Since: time.Time{}, Until: job.Time}, false},
+		{"before until", replay.Filter{WorkflowIDs: nil, Since: time.Time{}, Until: job.Time.Add(time.Hour)}, true},
+	}
+
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, tc.filter.Match(job), tc.name)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	t.Parallel()
+
+	updated, err := replay.Override(json.RawMessage(jobEvent), map[string]string{
+		"voice":       "leo",
+		"temperature": "0.5",
+		"model":       "narrator",
+	})
+	require.NoError(t, err)
+
+	var fields map[string]any
+
+	require.NoError(t, json.Unmarshal(updated, &fields))
+	assert.Equal(t, "leo", fields["voice"])
+	assert.InDelta(t, 0.5, fields["temperature"], 1e-9)
+	assert.Equal(t, "narrator", fields["model"])
+	assert.Equal(t, "book-1/page-3.txt", fields["text_key"], "other fields are kept")
+
+	unchanged, err := replay.Override(json.RawMessage(jobEvent), nil)
+	require.NoError(t, err)
+	assert.JSONEq(t, jobEvent, string(unchanged))
+
+	_, err = replay.Override(json.RawMessage(jobEvent), map[string]string{"temperature": "hot"})
+	require.ErrorIs(t, err, replay.ErrInvalidOverride, "a string cannot set a number")
+}
@@ -0,0 +1,97 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// ErrInvalidPattern indicates a deny-list pattern that is not a valid regular
+// expression.
+var ErrInvalidPattern = errors.New("invalid deny-list pattern")
+
+// DenyList rejects or redacts text that contains any of its entries.
+type DenyList struct {
+	entries     []entry
+	redact      bool
+	replacement string
+}
+
+// entry is one compiled deny-list word or pattern.
+type entry struct {
+	source string
+	match  *regexp.Regexp
+}
+
+// NewDenyList creates a deny-list of words, matched as whole words regardless
+// of case, and patterns, regular expressions in Go syntax. With redact, every
+// match is replaced with replacement; otherwise text with a match is
+// rejected.
+func NewDenyList(words, patterns []string, redact bool, replacement string) (*DenyList, error) {
+	list := &DenyList{
+		entries:     make([]entry, 0, len(words)+len(patterns)),
+		redact:      redact,
+		replacement: replacement,
+	}
+
+	for _, word := range words {
+		if word == "" {
+			continue
+		}
+
+		list.entries = append(list.entries, entry{source: word, match: regexp.MustCompile(wordPattern(word))})
+	}
+
+	for _, pattern := range patterns {
+		match, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %w", ErrInvalidPattern, pattern, err)
+		}
+
+		list.entries = append(list.entries, entry{source: pattern, match: match})
+	}
+
+	return list, nil
+}
+
+// wordPattern matches word regardless of case, with word boundaries at the
+// ends where word starts or ends with an ASCII letter or digit, since Go's
+// word boundaries only know those.
+func wordPattern(word string) string {
+	pattern := "(?i)" + regexp.QuoteMeta(word)
+
+	first, _ := utf8.DecodeRuneInString(word)
+	if first < utf8.RuneSelf && (unicode.IsLetter(first) || unicode.IsDigit(first)) {
+		pattern = `(?i)\b` + regexp.QuoteMeta(word)
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(word)
+	if last < utf8.RuneSelf && (unicode.IsLetter(last) || unicode.IsDigit(last)) {
+		pattern += `\b`
+	}
+
+	return pattern
+}
+
+// Filter rejects text with a match, naming the entry but not the text, or
+// returns it with every match replaced.
+func (d *DenyList) Filter(_ context.Context, _ string, text []byte) ([]byte, error) {
+	for _, item := range d.entries {
+		if !item.match.Match(text) {
+			continue
+		}
+
+		if !d.redact {
+			return nil, fmt.Errorf("%w: matches deny-list entry %q", core.ErrContentRejected, item.source)
+		}
+
+		text = item.match.ReplaceAllLiteral(text, []byte(d.replacement))
+	}
+
+	return text, nil
+}
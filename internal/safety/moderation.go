@@ -0,0 +1,107 @@
+package safety
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// DefaultModerationTimeout bounds one moderation request when no timeout is given.
+const DefaultModerationTimeout = 10 * time.Second
+
+// maxModerationReply bounds the reply read from a moderation API.
+const maxModerationReply = 1 << 20
+
+// ErrModerationFailed indicates that the moderation API could not be reached
+// or answered with an error. The text is not synthesized.
+var ErrModerationFailed = errors.New("moderation request failed")
+
+// moderationRequest is the body posted to a moderation API.
+type moderationRequest struct {
+	Text     string `json:"text"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// moderationReply is the reply expected from a moderation API.
+type moderationReply struct {
+	Flagged    bool     `json:"flagged"`
+	Categories []string `json:"categories"`
+}
+
+// Moderation asks an external moderation API whether text may be synthesized.
+// It posts {"text": ..., "tenant_id": ...} as JSON and expects a 200 reply of
+// {"flagged": bool, "categories": [...]}. Flagged text is rejected; when the
+// API fails, the text is not synthesized either.
+type Moderation struct {
+	client *http.Client
+	url    string
+}
+
+// NewModeration creates a moderation filter that posts to url. A zero
+// timeout uses DefaultModerationTimeout.
+func NewModeration(url string, timeout time.Duration) *Moderation {
+	if timeout <= 0 {
+		timeout = DefaultModerationTimeout
+	}
+
+	return &Moderation{
+		client: &http.Client{
+			Transport:     nil,
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       timeout,
+		},
+		url: url,
+	}
+}
+
+// Filter returns text unchanged when the API does not flag it.
+func (m *Moderation) Filter(ctx context.Context, tenant string, text []byte) ([]byte, error) {
+	body, err := json.Marshal(moderationRequest{Text: string(text), TenantID: tenant})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrModerationFailed, err)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrModerationFailed, err)
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := m.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrModerationFailed, err)
+	}
+	defer response.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(response.Body, maxModerationReply))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrModerationFailed, err)
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: status %s", ErrModerationFailed, response.Status)
+	}
+
+	var reply moderationReply
+
+	err = json.Unmarshal(data, &reply)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid reply: %w", ErrModerationFailed, err)
+	}
+
+	if reply.Flagged {
+		return nil, fmt.Errorf("%w: flagged by moderation (%s)", core.ErrContentRejected, strings.Join(reply.Categories, ", "))
+	}
+
+	return text, nil
+}
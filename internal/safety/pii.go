@@ -0,0 +1,121 @@
+package safety
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+)
+
+// PII categories the Redactor can mask.
+const (
+	CategoryEmail = "email"
+	CategoryPhone = "phone"
+	CategoryCard  = "card"
+)
+
+// ErrUnknownCategory indicates a PII category the Redactor does not know.
+var ErrUnknownCategory = errors.New("unknown PII category")
+
+// Categories lists every PII category, in the order the Redactor masks them.
+// Card numbers go first, so their digits are not taken for phone numbers.
+func Categories() []string {
+	return []string{CategoryCard, CategoryEmail, CategoryPhone}
+}
+
+// piiPatterns find candidates of each category. Card candidates are confirmed
+// with the Luhn checksum.
+var piiPatterns = map[string]*regexp.Regexp{
+	CategoryEmail: regexp.MustCompile(`(?i)\b[a-z0-9._%+\-]+@[a-z0-9\-]+(?:\.[a-z0-9\-]+)*\.[a-z]{2,}\b`),
+	CategoryPhone: regexp.MustCompile(
+		`(?:\+\d{1,3}[ .\-]?)?(?:\(\d{2,4}\)[ .\-]?|\b\d{2,4}[ .\-])\d{3,4}[ .\-]?\d{3,4}\b|\+\d{8,15}\b`,
+	),
+	CategoryCard: regexp.MustCompile(`\b\d(?:[ \-]?\d){12,18}\b`),
+}
+
+// piiLabels replace the matches of each category when no replacement is
+// given. They read naturally when spoken.
+var piiLabels = map[string]string{
+	CategoryEmail: "redacted email",
+	CategoryPhone: "redacted phone number",
+	CategoryCard:  "redacted card number",
+}
+
+// Redactor masks personal data in text: email addresses, phone numbers and
+// credit-card-like numbers, as selected. It never rejects text.
+type Redactor struct {
+	categories  []string
+	replacement string
+}
+
+// NewRedactor creates a redactor for categories, or for every category when
+// none are given. An empty replacement masks each match with a spoken label
+// of its category.
+func NewRedactor(categories []string, replacement string) (*Redactor, error) {
+	selected := make(map[string]bool, len(categories))
+
+	for _, category := range categories {
+		_, ok := piiPatterns[category]
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownCategory, category)
+		}
+
+		selected[category] = true
+	}
+
+	redactor := &Redactor{categories: nil, replacement: replacement}
+
+	for _, category := range Categories() {
+		if len(selected) == 0 || selected[category] {
+			redactor.categories = append(redactor.categories, category)
+		}
+	}
+
+	return redactor, nil
+}
+
+// Filter returns text with every match of the redactor's categories masked.
+func (r *Redactor) Filter(_ context.Context, _ string, text []byte) ([]byte, error) {
+	for _, category := range r.categories {
+		replacement := r.replacement
+		if replacement == "" {
+			replacement = piiLabels[category]
+		}
+
+		text = piiPatterns[category].ReplaceAllFunc(text, func(match []byte) []byte {
+			if category == CategoryCard && !luhnValid(match) {
+				return match
+			}
+
+			return []byte(replacement)
+		})
+	}
+
+	return text, nil
+}
+
+// luhnValid reports whether the digits of number pass the Luhn checksum used
+// by payment cards. Separators are skipped.
+func luhnValid(number []byte) bool {
+	sum := 0
+	double := false
+
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
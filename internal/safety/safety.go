@@ -0,0 +1,53 @@
+// Package safety screens the text of jobs before synthesis, for deployments
+// that take text from the public: deny-lists reject or redact text, an
+// external moderation API can reject it, and each tenant gets its policy. The
+// Redactor masks personal data for compliance-sensitive deployments.
+package safety
+
+import (
+	"context"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Chain applies filters in order, each to the text the previous one returned.
+type Chain []core.TextFilter
+
+// Filter passes text through every filter of the chain. The first rejection
+// stops it.
+func (c Chain) Filter(ctx context.Context, tenant string, text []byte) ([]byte, error) {
+	for _, filter := range c {
+		var err error
+
+		text, err = filter.Filter(ctx, tenant, text)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return text, nil
+}
+
+// Policies chooses the filter of each job by its tenant.
+type Policies struct {
+	// Default filters the text of tenants without a policy of their own; nil
+	// passes it through.
+	Default core.TextFilter
+	// Tenants are the filters of tenants with their own policy, by tenant ID.
+	// A nil filter exempts the tenant from Default.
+	Tenants map[string]core.TextFilter
+}
+
+// Filter applies the tenant's policy to text.
+func (p Policies) Filter(ctx context.Context, tenant string, text []byte) ([]byte, error) {
+	filter, ok := p.Tenants[tenant]
+	if !ok {
+		filter = p.Default
+	}
+
+	if filter == nil {
+		return text, nil
+	}
+
+	return filter.Filter(ctx, tenant, text)
+}
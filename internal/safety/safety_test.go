@@ -0,0 +1,142 @@
+package safety_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/safety"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDenyList_Rejects(t *testing.T) {
+	t.Parallel()
+
+	list, err := safety.NewDenyList([]string{"darn", "über"}, []string{`\d{3}-\d{4}`}, false, "")
+	require.NoError(t, err)
+
+	for _, text := range []string{"Well, DARN it.", "Call 555-1234.", "Es ist ÜBER."} {
+		_, err = list.Filter(context.Background(), "", []byte(text))
+		require.ErrorIs(t, err, core.ErrContentRejected, text)
+		assert.NotContains(t, err.Error(), text, "the text is not repeated")
+	}
+
+	text, err := list.Filter(context.Background(), "", []byte("The darning needle."))
+	require.NoError(t, err, "words match whole words only")
+	assert.Equal(t, "The darning needle.", string(text))
+}
+
+func TestDenyList_Redacts(t *testing.T) {
+	t.Parallel()
+
+	list, err := safety.NewDenyList([]string{"darn"}, []string{`\d{3}-\d{4}`}, true, "beep")
+	require.NoError(t, err)
+
+	text, err := list.Filter(context.Background(), "", []byte("Darn, call 555-1234, darn it."))
+	require.NoError(t, err)
+	assert.Equal(t, "beep, call beep, beep it.", string(text))
+}
+
+func TestNewDenyList_InvalidPattern(t *testing.T) {
+	t.Parallel()
+
+	_, err := safety.NewDenyList(nil, []string{"("}, false, "")
+	require.ErrorIs(t, err, safety.ErrInvalidPattern)
+}
+
+func TestModeration(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var body struct {
+			Text     string `json:"text"`
+			TenantID string `json:"tenant_id"`
+		}
+
+		if json.NewDecoder(request.Body).Decode(&body) != nil || body.TenantID == "" {
+			writer.WriteHeader(http.StatusBadRequest)
+
+			return
+		}
+
+		flagged := body.Text == "bad words"
+		_ = json.NewEncoder(writer).Encode(map[string]any{"flagged": flagged, "categories": []string{"harassment"}})
+	}))
+	defer server.Close()
+
+	moderation := safety.NewModeration(server.URL, time.Second)
+
+	text, err := moderation.Filter(context.Background(), "acme", []byte("kind words"))
+	require.NoError(t, err)
+	assert.Equal(t, "kind words", string(text))
+
+	_, err = moderation.Filter(context.Background(), "acme", []byte("bad words"))
+	require.ErrorIs(t, err, core.ErrContentRejected)
+	assert.Contains(t, err.Error(), "harassment")
+
+	_, err = moderation.Filter(context.Background(), "", []byte("kind words"))
+	require.ErrorIs(t, err, safety.ErrModerationFailed, "an API error does not pass the text")
+	require.NotErrorIs(t, err, core.ErrContentRejected)
+}
+
+func TestPolicies(t *testing.T) {
+	t.Parallel()
+
+	strict, err := safety.NewDenyList([]string{"darn"}, nil, false, "")
+	require.NoError(t, err)
+
+	mild, err := safety.NewDenyList([]string{"darn"}, nil, true, "")
+	require.NoError(t, err)
+
+	trim, err := safety.NewDenyList(nil, []string{`\s+$`}, true, "")
+	require.NoError(t, err)
+
+	policies := safety.Policies{
+		Default: strict,
+		Tenants: map[string]core.TextFilter{"kids": safety.Chain{mild, trim}, "internal": nil},
+	}
+
+	_, err = policies.Filter(context.Background(), "public", []byte("darn "))
+	require.ErrorIs(t, err, core.ErrContentRejected)
+
+	text, err := policies.Filter(context.Background(), "kids", []byte("Oh darn "))
+	require.NoError(t, err)
+	assert.Equal(t, "Oh", string(text))
+
+	text, err = policies.Filter(context.Background(), "internal", []byte("darn "))
+	require.NoError(t, err)
+	assert.Equal(t, "darn ", string(text))
+}
+
+func TestRedactor(t *testing.T) {
+	t.Parallel()
+
+	redactor, err := safety.NewRedactor(nil, "")
+	require.NoError(t, err)
+
+	text, err := redactor.Filter(context.Background(), "", []byte(
+		"Write to jane.doe@example.co.uk or call +1 (555) 123-4567, 030 1234 5678 or +441632960961. "+
+			"Pay with 4111 1111 1111 1111, not order 1234567890123 from 1984."))
+	require.NoError(t, err)
+	assert.Equal(t, "Write to redacted email or call redacted phone number, redacted phone number or redacted phone number. "+
+		"Pay with redacted card number, not order 1234567890123 from 1984.", string(text))
+}
+
+func TestRedactor_Categories(t *testing.T) {
+	t.Parallel()
+
+	redactor, err := safety.NewRedactor([]string{safety.CategoryEmail}, "***")
+	require.NoError(t, err)
+
+	text, err := redactor.Filter(context.Background(), "", []byte("Mail a@b.io, call 555-123-4567."))
+	require.NoError(t, err)
+	assert.Equal(t, "Mail ***, call 555-123-4567.", string(text))
+
+	_, err = safety.NewRedactor([]string{"ssn"}, "")
+	require.ErrorIs(t, err, safety.ErrUnknownCategory)
+}
@@ -0,0 +1,229 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron field bounds.
+const (
+	cronFieldCount = 5
+	minuteMax      = 59
+	hourMax        = 23
+	dayMin         = 1
+	dayMax         = 31
+	monthMin       = 1
+	monthMax       = 12
+	weekdayMax     = 7 // Both 0 and 7 mean Sunday.
+	searchYears    = 5
+)
+
+// Static errors.
+var (
+	ErrCronFieldCount = errors.New("cron expression must have 5 fields")
+	ErrCronField      = errors.New("invalid cron field")
+	ErrCronNoMatch    = errors.New("cron expression never matches")
+)
+
+// cronDescriptors maps the supported shorthand descriptors to their expansions.
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronSchedule is a parsed standard 5-field cron expression
+// (minute, hour, day of month, month, day of week).
+type CronSchedule struct {
+	minutes      uint64
+	hours        uint64
+	days         uint64
+	months       uint64
+	weekdays     uint64
+	daysStar     bool
+	weekdaysStar bool
+}
+
+// ParseCron parses a standard 5-field cron expression or one of the
+// @yearly, @monthly, @weekly, @daily, @midnight and @hourly descriptors.
+func ParseCron(expr string) (*CronSchedule, error) {
+	trimmed := strings.TrimSpace(expr)
+	if expansion, ok := cronDescriptors[trimmed]; ok {
+		trimmed = expansion
+	}
+
+	fields := strings.Fields(trimmed)
+	if len(fields) != cronFieldCount {
+		return nil, fmt.Errorf("%w: got %d in '%s'", ErrCronFieldCount, len(fields), expr)
+	}
+
+	minutes, err := parseCronField(fields[0], 0, minuteMax)
+	if err != nil {
+		return nil, err
+	}
+
+	hours, err := parseCronField(fields[1], 0, hourMax)
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := parseCronField(fields[2], dayMin, dayMax)
+	if err != nil {
+		return nil, err
+	}
+
+	months, err := parseCronField(fields[3], monthMin, monthMax)
+	if err != nil {
+		return nil, err
+	}
+
+	weekdays, err := parseCronField(fields[4], 0, weekdayMax)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fold 7 (Sunday) onto 0.
+	if weekdays&(1<<weekdayMax) != 0 {
+		weekdays |= 1
+	}
+
+	return &CronSchedule{
+		minutes:      minutes,
+		hours:        hours,
+		days:         days,
+		months:       months,
+		weekdays:     weekdays,
+		daysStar:     strings.HasPrefix(fields[2], "*"),
+		weekdaysStar: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parseCronField parses one comma-separated cron field into a bit set.
+func parseCronField(field string, minValue, maxValue int) (uint64, error) {
+	var bits uint64
+
+	for part := range strings.SplitSeq(field, ",") {
+		partBits, err := parseCronRange(part, minValue, maxValue)
+		if err != nil {
+			return 0, err
+		}
+
+		bits |= partBits
+	}
+
+	return bits, nil
+}
+
+// parseCronRange parses a single "*", "a", "a-b" term with an optional "/step".
+func parseCronRange(term string, minValue, maxValue int) (uint64, error) {
+	rangePart, stepPart, hasStep := strings.Cut(term, "/")
+
+	step := 1
+
+	if hasStep {
+		parsedStep, err := strconv.Atoi(stepPart)
+		if err != nil || parsedStep <= 0 {
+			return 0, fmt.Errorf("%w: bad step in '%s'", ErrCronField, term)
+		}
+
+		step = parsedStep
+	}
+
+	low, high := minValue, maxValue
+
+	switch {
+	case rangePart == "*":
+	case strings.Contains(rangePart, "-"):
+		lowPart, highPart, _ := strings.Cut(rangePart, "-")
+
+		parsedLow, lowErr := strconv.Atoi(lowPart)
+		parsedHigh, highErr := strconv.Atoi(highPart)
+
+		if lowErr != nil || highErr != nil {
+			return 0, fmt.Errorf("%w: bad range in '%s'", ErrCronField, term)
+		}
+
+		low, high = parsedLow, parsedHigh
+	default:
+		value, err := strconv.Atoi(rangePart)
+		if err != nil {
+			return 0, fmt.Errorf("%w: bad value in '%s'", ErrCronField, term)
+		}
+
+		low = value
+		if !hasStep {
+			high = value
+		}
+	}
+
+	if low < minValue || high > maxValue || low > high {
+		return 0, fmt.Errorf("%w: '%s' outside %d-%d", ErrCronField, term, minValue, maxValue)
+	}
+
+	var bits uint64
+	for value := low; value <= high; value += step {
+		bits |= 1 << value
+	}
+
+	return bits, nil
+}
+
+// Next returns the first activation time strictly after the given time.
+// Times are evaluated in the location of after.
+func (c *CronSchedule) Next(after time.Time) (time.Time, error) {
+	loc := after.Location()
+	candidate := after.Truncate(time.Minute).Add(time.Minute)
+	limit := candidate.AddDate(searchYears, 0, 0)
+
+	for candidate.Before(limit) {
+		if c.months&(1<<int(candidate.Month())) == 0 {
+			candidate = time.Date(candidate.Year(), candidate.Month()+1, 1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if !c.dayMatches(candidate) {
+			candidate = time.Date(candidate.Year(), candidate.Month(), candidate.Day()+1, 0, 0, 0, 0, loc)
+
+			continue
+		}
+
+		if c.hours&(1<<candidate.Hour()) == 0 {
+			candidate = time.Date(
+				candidate.Year(), candidate.Month(), candidate.Day(), candidate.Hour()+1, 0, 0, 0, loc,
+			)
+
+			continue
+		}
+
+		if c.minutes&(1<<candidate.Minute()) == 0 {
+			candidate = candidate.Add(time.Minute)
+
+			continue
+		}
+
+		return candidate, nil
+	}
+
+	return time.Time{}, ErrCronNoMatch
+}
+
+// dayMatches applies the cron rule that, when both day of month and day of
+// week are restricted, a day matching either field is accepted.
+func (c *CronSchedule) dayMatches(candidate time.Time) bool {
+	dayMatch := c.days&(1<<candidate.Day()) != 0
+	weekdayMatch := c.weekdays&(1<<int(candidate.Weekday())) != 0
+
+	if c.daysStar || c.weekdaysStar {
+		return dayMatch && weekdayMatch
+	}
+
+	return dayMatch || weekdayMatch
+}
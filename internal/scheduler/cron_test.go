@@ -0,0 +1,64 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/scheduler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCron_Invalid(t *testing.T) {
+	t.Parallel()
+
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := scheduler.ParseCron(expr)
+		assert.Error(t, err, "expression %q should be rejected", expr)
+	}
+}
+
+func TestCronSchedule_Next(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2025, time.March, 14, 10, 30, 45, 0, time.UTC) // Friday
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2025, time.March, 14, 10, 31, 0, 0, time.UTC)},
+		{"nightly", "@daily", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"step minutes", "*/20 * * * *", time.Date(2025, time.March, 14, 10, 40, 0, 0, time.UTC)},
+		{"hour range", "15 2-4 * * *", time.Date(2025, time.March, 15, 2, 15, 0, 0, time.UTC)},
+		{"weekday list", "0 9 * * 1,3", time.Date(2025, time.March, 17, 9, 0, 0, 0, time.UTC)},
+		{"sunday as seven", "0 0 * * 7", time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"month rollover", "0 0 1 * *", time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"day or weekday", "0 0 20 * 6", time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			schedule, err := scheduler.ParseCron(tt.expr)
+			require.NoError(t, err)
+
+			got, err := schedule.Next(base)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestCronSchedule_NeverMatches(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := scheduler.ParseCron("0 0 31 2 *")
+	require.NoError(t, err)
+
+	_, err = schedule.Next(time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC))
+	require.ErrorIs(t, err, scheduler.ErrCronNoMatch)
+}
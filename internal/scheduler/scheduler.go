@@ -0,0 +1,411 @@
+// Package scheduler persists deferred and recurring TTS jobs in a NATS KV
+// bucket and dispatches them to the normal processing subject when due.
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// DefaultPollInterval is how often the scheduler looks for due jobs.
+const DefaultPollInterval = 10 * time.Second
+
+// Static errors.
+var (
+	ErrNoTrigger = errors.New("schedule requires not_before or cron")
+)
+
+// Job is a scheduled TTS job as persisted in the KV bucket.
+type Job struct {
+	ID        string        `json:"id"`
+	NotBefore *time.Time    `json:"not_before,omitempty"`
+	Cron      string        `json:"cron,omitempty"`
+	Event     core.JobEvent `json:"event"`
+	NextRun   time.Time     `json:"next_run"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// Request is the payload accepted on the schedule subject.
+// NotBefore defers a one-shot job; Cron makes the job recurring, with
+// NotBefore (if set) bounding the first activation. Cron is evaluated in UTC.
+type Request struct {
+	NotBefore *time.Time    `json:"not_before,omitempty"`
+	Cron      string        `json:"cron,omitempty"`
+	Event     core.JobEvent `json:"event"`
+}
+
+// Response is the reply sent for a schedule request.
+type Response struct {
+	ID      string    `json:"id,omitempty"`
+	NextRun time.Time `json:"next_run"`
+	Error   string    `json:"error,omitempty"`
+}
+
+// Scheduler accepts scheduled jobs and dispatches them when due.
+type Scheduler struct {
+	natsConnection  *nats.Conn
+	kv              nats.KeyValue
+	requestSubject  string
+	dispatchSubject string
+	replySubject    string
+	pollInterval    time.Duration
+	log             *logger.Logger
+}
+
+// New creates a Scheduler backed by the given KV bucket, creating it if needed.
+// Due jobs are published to dispatchSubject with replySubject as the reply
+// address, so the worker's AudioChunkCreatedEvent flows to downstream consumers.
+func New(
+	natsConnection *nats.Conn,
+	jetstreamContext nats.JetStreamContext,
+	bucketName string,
+	requestSubject string,
+	dispatchSubject string,
+	replySubject string,
+	pollInterval time.Duration,
+	log *logger.Logger,
+) (*Scheduler, error) {
+	// Use a "create-first" approach.
+	kv, err := jetstreamContext.CreateKeyValue(&nats.KeyValueConfig{
+		Bucket:       bucketName,
+		Description:  fmt.Sprintf("Scheduled jobs for the %s bucket.", bucketName),
+		MaxValueSize: 0,
+		History:      1,
+		TTL:          0,
+		MaxBytes:     0,
+		Storage:      nats.FileStorage,
+		Replicas:     1,
+		Placement:    nil,
+		RePublish:    nil,
+		Mirror:       nil,
+		Sources:      nil,
+		Compression:  false,
+	})
+
+	// If the bucket already exists with a different configuration, bind to it.
+	if err != nil {
+		if errors.Is(err, nats.ErrStreamNameAlreadyInUse) {
+			kv, err = jetstreamContext.KeyValue(bucketName)
+			if err != nil {
+				return nil, fmt.Errorf("failed to bind to existing key-value bucket '%s': %w", bucketName, err)
+			}
+		} else {
+			return nil, fmt.Errorf("failed to create key-value bucket '%s': %w", bucketName, err)
+		}
+	}
+
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+
+	return &Scheduler{
+		natsConnection:  natsConnection,
+		kv:              kv,
+		requestSubject:  requestSubject,
+		dispatchSubject: dispatchSubject,
+		replySubject:    replySubject,
+		pollInterval:    pollInterval,
+		log:             log,
+	}, nil
+}
+
+// Run accepts schedule requests and dispatches due jobs until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) error {
+	sub, err := s.natsConnection.Subscribe(s.requestSubject, s.handleRequest)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe to subject %s: %w", s.requestSubject, err)
+	}
+
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			drainErr := sub.Drain()
+			if drainErr != nil {
+				return fmt.Errorf("failed to drain subscription: %w", drainErr)
+			}
+
+			return nil
+		case now := <-ticker.C:
+			_, dispatchErr := s.DispatchDue(now)
+			if dispatchErr != nil {
+				s.log.Error("Failed to dispatch scheduled jobs: %v", dispatchErr)
+			}
+		}
+	}
+}
+
+// Schedule validates and persists a job, returning it with its first run time.
+func (s *Scheduler) Schedule(req Request, now time.Time) (Job, error) {
+	now = now.UTC()
+
+	job := Job{
+		ID:        uuid.NewString(),
+		NotBefore: req.NotBefore,
+		Cron:      req.Cron,
+		Event:     req.Event,
+		NextRun:   time.Time{},
+		CreatedAt: now,
+	}
+
+	nextRun, err := firstRun(req, now)
+	if err != nil {
+		return job, err
+	}
+
+	job.NextRun = nextRun
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return job, fmt.Errorf("failed to marshal scheduled job: %w", err)
+	}
+
+	_, err = s.kv.Create(job.ID, data)
+	if err != nil {
+		return job, fmt.Errorf("failed to store scheduled job '%s': %w", job.ID, err)
+	}
+
+	return job, nil
+}
+
+// DispatchDue publishes every job whose next run is at or before now and
+// returns how many were dispatched. Each job is claimed with a revision-checked
+// update or delete before it is published, so concurrent schedulers sharing a
+// bucket never dispatch the same activation twice.
+func (s *Scheduler) DispatchDue(now time.Time) (int, error) {
+	keys, err := s.kv.Keys()
+	if err != nil {
+		if errors.Is(err, nats.ErrNoKeysFound) {
+			return 0, nil
+		}
+
+		return 0, fmt.Errorf("failed to list scheduled jobs: %w", err)
+	}
+
+	var (
+		dispatched   int
+		dispatchErrs []error
+	)
+
+	for _, key := range keys {
+		ok, jobErr := s.dispatchIfDue(key, now.UTC())
+		if jobErr != nil {
+			dispatchErrs = append(dispatchErrs, jobErr)
+
+			continue
+		}
+
+		if ok {
+			dispatched++
+		}
+	}
+
+	return dispatched, errors.Join(dispatchErrs...)
+}
+
+func (s *Scheduler) dispatchIfDue(key string, now time.Time) (bool, error) {
+	entry, err := s.kv.Get(key)
+	if err != nil {
+		if errors.Is(err, nats.ErrKeyNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get scheduled job '%s': %w", key, err)
+	}
+
+	var job Job
+
+	err = json.Unmarshal(entry.Value(), &job)
+	if err != nil {
+		return false, fmt.Errorf("failed to unmarshal scheduled job '%s': %w", key, err)
+	}
+
+	if job.NextRun.After(now) {
+		return false, nil
+	}
+
+	claimed, claimRevision, err := s.claim(&job, entry.Revision(), now)
+	if err != nil || !claimed {
+		return false, err
+	}
+
+	err = s.publish(job.Event, now)
+	if err != nil {
+		publishErr := fmt.Errorf("failed to dispatch scheduled job '%s': %w", job.ID, err)
+
+		restoreErr := s.restore(job.ID, entry.Value(), claimRevision)
+		if restoreErr != nil {
+			return false, errors.Join(publishErr, restoreErr)
+		}
+
+		return false, publishErr
+	}
+
+	s.log.Info("Dispatched scheduled job %s for workflow %s", job.ID, job.Event.Header.WorkflowID)
+
+	return true, nil
+}
+
+// claim advances a recurring job to its next run or removes a one-shot job.
+// It reports false when another scheduler claimed the job first. For a
+// recurring job it also returns the revision written by the claim.
+func (s *Scheduler) claim(job *Job, revision uint64, now time.Time) (bool, uint64, error) {
+	var (
+		claimRevision uint64
+		err           error
+	)
+
+	if job.Cron == "" {
+		err = s.kv.Delete(job.ID, nats.LastRevision(revision))
+	} else {
+		claimRevision, err = s.advance(job, revision, now)
+	}
+
+	if err != nil {
+		var apiErr *nats.APIError
+		if errors.As(err, &apiErr) && apiErr.ErrorCode == nats.JSErrCodeStreamWrongLastSequence {
+			return false, 0, nil
+		}
+
+		return false, 0, fmt.Errorf("failed to claim scheduled job '%s': %w", job.ID, err)
+	}
+
+	return true, claimRevision, nil
+}
+
+func (s *Scheduler) advance(job *Job, revision uint64, now time.Time) (uint64, error) {
+	schedule, err := ParseCron(job.Cron)
+	if err != nil {
+		return 0, err
+	}
+
+	nextRun, err := schedule.Next(now)
+	if err != nil {
+		return 0, err
+	}
+
+	job.NextRun = nextRun
+
+	data, err := json.Marshal(job)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal scheduled job: %w", err)
+	}
+
+	newRevision, err := s.kv.Update(job.ID, data, revision)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update scheduled job: %w", err)
+	}
+
+	return newRevision, nil
+}
+
+// restore puts a claimed job back in its pre-claim state after its activation
+// could not be published, so the next poll retries it. A one-shot job is
+// recreated; a recurring job is rewound, unless it changed since the claim.
+func (s *Scheduler) restore(jobID string, original []byte, claimRevision uint64) error {
+	var err error
+
+	if claimRevision == 0 {
+		_, err = s.kv.Create(jobID, original)
+	} else {
+		_, err = s.kv.Update(jobID, original, claimRevision)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to restore scheduled job '%s' after a failed dispatch: %w", jobID, err)
+	}
+
+	return nil
+}
+
+// publish sends one activation of a job to the processing subject. Every
+// activation gets a fresh event ID and timestamp.
+func (s *Scheduler) publish(event core.JobEvent, now time.Time) error {
+	event.Header.EventID = uuid.NewString()
+	event.Header.Timestamp = now
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+
+	if s.replySubject == "" {
+		err = s.natsConnection.Publish(s.dispatchSubject, data)
+	} else {
+		err = s.natsConnection.PublishRequest(s.dispatchSubject, s.replySubject, data)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to publish to subject %s: %w", s.dispatchSubject, err)
+	}
+
+	return nil
+}
+
+func (s *Scheduler) handleRequest(msg *nats.Msg) {
+	var (
+		req      Request
+		response Response
+	)
+
+	err := json.Unmarshal(msg.Data, &req)
+	if err != nil {
+		response.Error = fmt.Sprintf("failed to unmarshal schedule request: %v", err)
+	} else {
+		job, scheduleErr := s.Schedule(req, time.Now())
+		if scheduleErr != nil {
+			response.Error = scheduleErr.Error()
+		} else {
+			response.ID = job.ID
+			response.NextRun = job.NextRun
+			s.log.Info("Scheduled job %s for workflow %s, next run %s",
+				job.ID, job.Event.Header.WorkflowID, job.NextRun.Format(time.RFC3339))
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		s.log.Error("Failed to marshal schedule response: %v", err)
+
+		return
+	}
+
+	err = msg.Respond(data)
+	if err != nil {
+		s.log.Error("Failed to respond to schedule request: %v", err)
+	}
+}
+
+// firstRun computes the first activation time of a schedule request.
+func firstRun(req Request, now time.Time) (time.Time, error) {
+	if req.Cron == "" {
+		if req.NotBefore == nil {
+			return time.Time{}, ErrNoTrigger
+		}
+
+		return req.NotBefore.UTC(), nil
+	}
+
+	schedule, err := ParseCron(req.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	after := now
+	if req.NotBefore != nil && req.NotBefore.After(now) {
+		// Allow an activation exactly at NotBefore.
+		after = req.NotBefore.UTC().Add(-time.Minute)
+	}
+
+	return schedule.Next(after)
+}
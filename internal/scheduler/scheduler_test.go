@@ -0,0 +1,213 @@
+// Package scheduler_test tests the job scheduler.
+package scheduler_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/book-expert/events"
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/scheduler"
+	"github.com/nats-io/nats-server/v2/test"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func connectTestServer(t *testing.T) func() *nats.Conn {
+	t.Helper()
+
+	opts := test.DefaultTestOptions
+	opts.Port = -1 // Use a random port
+	opts.JetStream = true
+	opts.StoreDir = t.TempDir()
+	natsServer := test.RunServer(&opts)
+	t.Cleanup(natsServer.Shutdown)
+
+	return func() *nats.Conn {
+		natsConnection, err := nats.Connect(natsServer.ClientURL())
+		require.NoError(t, err)
+		t.Cleanup(natsConnection.Close)
+
+		return natsConnection
+	}
+}
+
+func setupScheduler(t *testing.T) (*scheduler.Scheduler, *nats.Conn) {
+	t.Helper()
+
+	natsConnection := connectTestServer(t)()
+
+	return newTestScheduler(t, natsConnection, natsConnection), natsConnection
+}
+
+// newTestScheduler creates a scheduler that publishes on publishConnection and
+// keeps its jobs in a KV bucket reached through kvConnection.
+func newTestScheduler(t *testing.T, publishConnection, kvConnection *nats.Conn) *scheduler.Scheduler {
+	t.Helper()
+
+	jetstreamContext, err := kvConnection.JetStream()
+	require.NoError(t, err)
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	jobScheduler, err := scheduler.New(
+		publishConnection, jetstreamContext, "test-schedules", "test.schedule",
+		"text.processed", "audio.chunk.created", time.Hour, testLogger,
+	)
+	require.NoError(t, err)
+
+	return jobScheduler
+}
+
+func newScheduleRequest(notBefore *time.Time, cron string) scheduler.Request {
+	return scheduler.Request{
+		NotBefore: notBefore,
+		Cron:      cron,
+		Event: core.JobEvent{
+			TextProcessedEvent: events.TextProcessedEvent{
+				Header: events.EventHeader{
+					Timestamp:  time.Time{},
+					WorkflowID: "workflow-1",
+					EventID:    "",
+					UserID:     "",
+					TenantID:   "",
+				},
+				TextKey:           "text-key",
+				PNGKey:            "",
+				PageNumber:        1,
+				TotalPages:        1,
+				Voice:             "default",
+				Seed:              0,
+				NGL:               0,
+				TopP:              0.95,
+				RepetitionPenalty: 1.1,
+				Temperature:       0.7,
+			},
+			Model:          "narrator",
+			Language:       "en",
+			Rate:           0,
+			Pitch:          0,
+			Style:          "",
+			TimeoutSeconds: 0,
+		},
+	}
+}
+
+func TestScheduler_OneShot(t *testing.T) {
+	t.Parallel()
+
+	jobScheduler, natsConnection := setupScheduler(t)
+
+	sub, err := natsConnection.SubscribeSync("text.processed")
+	require.NoError(t, err)
+
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+	notBefore := now.Add(time.Hour)
+
+	job, err := jobScheduler.Schedule(newScheduleRequest(&notBefore, ""), now)
+	require.NoError(t, err)
+	assert.Equal(t, notBefore, job.NextRun)
+
+	dispatched, err := jobScheduler.DispatchDue(now)
+	require.NoError(t, err)
+	assert.Zero(t, dispatched, "job must not run before not_before")
+
+	dispatched, err = jobScheduler.DispatchDue(notBefore)
+	require.NoError(t, err)
+	assert.Equal(t, 1, dispatched)
+
+	msg, err := sub.NextMsg(time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "audio.chunk.created", msg.Reply)
+
+	var event core.JobEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &event))
+	assert.Equal(t, "workflow-1", event.Header.WorkflowID)
+	assert.Equal(t, "text-key", event.TextKey)
+	assert.Equal(t, "narrator", event.Model, "the model selection must survive scheduling")
+	assert.Equal(t, "en", event.Language)
+	assert.NotEmpty(t, event.Header.EventID)
+
+	dispatched, err = jobScheduler.DispatchDue(notBefore.Add(24 * time.Hour))
+	require.NoError(t, err)
+	assert.Zero(t, dispatched, "one-shot job must be removed after dispatch")
+}
+
+func TestScheduler_Recurring(t *testing.T) {
+	t.Parallel()
+
+	jobScheduler, _ := setupScheduler(t)
+
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+
+	job, err := jobScheduler.Schedule(newScheduleRequest(nil, "@daily"), now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC), job.NextRun)
+
+	for day := range 3 {
+		runAt := job.NextRun.AddDate(0, 0, day)
+
+		dispatched, dispatchErr := jobScheduler.DispatchDue(runAt)
+		require.NoError(t, dispatchErr)
+		assert.Equal(t, 1, dispatched)
+
+		dispatched, dispatchErr = jobScheduler.DispatchDue(runAt)
+		require.NoError(t, dispatchErr)
+		assert.Zero(t, dispatched, "recurring job must not run twice for one activation")
+	}
+}
+
+func TestScheduler_RejectsMissingTrigger(t *testing.T) {
+	t.Parallel()
+
+	jobScheduler, _ := setupScheduler(t)
+
+	_, err := jobScheduler.Schedule(newScheduleRequest(nil, ""), time.Now())
+	require.ErrorIs(t, err, scheduler.ErrNoTrigger)
+
+	_, err = jobScheduler.Schedule(newScheduleRequest(nil, "not a cron"), time.Now())
+	require.ErrorIs(t, err, scheduler.ErrCronFieldCount)
+}
+
+func TestScheduler_PublishFailureKeepsJob(t *testing.T) {
+	t.Parallel()
+
+	connect := connectTestServer(t)
+	publishConnection, kvConnection := connect(), connect()
+	jobScheduler := newTestScheduler(t, publishConnection, kvConnection)
+
+	now := time.Date(2025, time.March, 14, 10, 0, 0, 0, time.UTC)
+
+	oneShot, err := jobScheduler.Schedule(newScheduleRequest(&now, ""), now)
+	require.NoError(t, err)
+
+	recurring, err := jobScheduler.Schedule(newScheduleRequest(nil, "@hourly"), now)
+	require.NoError(t, err)
+
+	publishConnection.Close()
+
+	dispatched, err := jobScheduler.DispatchDue(recurring.NextRun)
+	require.Error(t, err)
+	require.ErrorIs(t, err, nats.ErrConnectionClosed)
+	assert.Zero(t, dispatched)
+
+	// Both activations are still pending once publishing works again.
+	retryScheduler := newTestScheduler(t, kvConnection, kvConnection)
+
+	sub, err := kvConnection.SubscribeSync("text.processed")
+	require.NoError(t, err)
+
+	dispatched, err = retryScheduler.DispatchDue(recurring.NextRun)
+	require.NoError(t, err)
+	assert.Equal(t, 2, dispatched, "jobs %s and %s should be dispatched on retry", oneShot.ID, recurring.ID)
+
+	for range 2 {
+		_, err = sub.NextMsg(time.Second)
+		require.NoError(t, err)
+	}
+}
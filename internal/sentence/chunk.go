@@ -0,0 +1,70 @@
+package sentence
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunk splits text into chunks of at most maxChars characters. Chunks end at
+// sentence ends or line breaks where possible, else between words, and a word
+// longer than maxChars is cut. Chunks are trimmed; none is empty. A maxChars
+// below 1 is read as 1.
+func Chunk(text string, maxChars int) []string {
+	maxChars = max(maxChars, 1)
+
+	var (
+		chunks  []string
+		current strings.Builder
+		chars   int
+	)
+
+	flush := func() {
+		chunk := strings.TrimSpace(current.String())
+		if chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+
+		current.Reset()
+
+		chars = 0
+	}
+
+	for _, sentence := range Split(text) {
+		for _, piece := range fit(sentence, maxChars) {
+			pieceChars := utf8.RuneCountInString(piece)
+			if chars > 0 && chars+pieceChars > maxChars {
+				flush()
+			}
+
+			current.WriteString(piece)
+
+			chars += pieceChars
+		}
+	}
+
+	flush()
+
+	return chunks
+}
+
+// fit splits a sentence longer than maxChars between words, and cuts words
+// that are longer still.
+func fit(sentence string, maxChars int) []string {
+	if utf8.RuneCountInString(sentence) <= maxChars {
+		return []string{sentence}
+	}
+
+	var pieces []string
+
+	for _, word := range strings.SplitAfter(sentence, " ") {
+		runes := []rune(word)
+		for len(runes) > maxChars {
+			pieces = append(pieces, string(runes[:maxChars]))
+			runes = runes[maxChars:]
+		}
+
+		pieces = append(pieces, string(runes))
+	}
+
+	return pieces
+}
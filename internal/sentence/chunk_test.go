@@ -0,0 +1,72 @@
+package sentence_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/sentence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChunk(t *testing.T) {
+	t.Parallel()
+
+	for name, test := range map[string]struct {
+		text     string
+		maxChars int
+		want     []string
+	}{
+		"sentences":  {"One. Two. Three.", 10, []string{"One. Two.", "Three."}},
+		"line break": {"Title\n\nBody text.", 100, []string{"Title\n\nBody text."}},
+		"words":      {"A long sentence without an end", 12, []string{"A long", "sentence", "without an", "end"}},
+		"long word":  {"Supercalifragilistic", 8, []string{"Supercal", "ifragili", "stic"}},
+		"empty":      {" \n\t ", 10, nil},
+		"no limit":   {"Ab", 0, []string{"A", "b"}},
+	} {
+		assert.Equal(t, test.want, sentence.Chunk(test.text, test.maxChars), name)
+	}
+}
+
+// FuzzChunk checks that Chunk never panics or loops and returns trimmed,
+// valid UTF-8 chunks within the limit that keep every word of the text.
+func FuzzChunk(f *testing.F) {
+	for _, seed := range []struct {
+		text     string
+		maxChars int
+	}{
+		{"One. Two! Three? Four… Five", 10},
+		{"Dr. Watson arrived.\n\nJ. R. R. Tolkien wrote it.", 1},
+		{"Supercalifragilistic 日本語の文章です。", 4},
+		{"   \xff\xfe. .\n", 0},
+	} {
+		f.Add(seed.text, seed.maxChars)
+	}
+
+	f.Fuzz(func(t *testing.T, text string, maxChars int) {
+		maxChars = maxChars%200 - 10
+
+		chunks := sentence.Chunk(text, maxChars)
+		if !utf8.ValidString(text) {
+			return
+		}
+
+		for _, chunk := range chunks {
+			if chunk == "" || chunk != strings.TrimSpace(chunk) {
+				t.Fatalf("chunk %q is empty or not trimmed", chunk)
+			}
+
+			if !utf8.ValidString(chunk) {
+				t.Fatalf("chunk %q is not valid UTF-8", chunk)
+			}
+
+			if chars := utf8.RuneCountInString(chunk); chars > max(maxChars, 1) {
+				t.Fatalf("chunk %q has %d characters, the limit is %d", chunk, chars, maxChars)
+			}
+		}
+
+		if got, want := strings.Join(strings.Fields(strings.Join(chunks, " ")), ""), strings.Join(strings.Fields(text), ""); got != want {
+			t.Fatalf("chunks %q lose or add text of %q", chunks, text)
+		}
+	})
+}
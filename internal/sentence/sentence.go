@@ -0,0 +1,114 @@
+// Package sentence finds sentence boundaries in narrative text without
+// breaking sentences at abbreviations, initials or decimal points.
+package sentence
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sentence boundaries used by Split.
+const (
+	ends = ".!?…"
+	// closingMarks may follow the end of a sentence, as in `"Run!" she said`.
+	closingMarks = `"'”’)]`
+	// openingMarks may precede the first word of a sentence.
+	openingMarks = `"'“‘([`
+)
+
+// titles are abbreviations, in lower case and without their final period,
+// that are always followed by more of the sentence, usually a name.
+var titles = map[string]bool{
+	"mr": true, "mrs": true, "ms": true, "dr": true, "prof": true, "st": true, "mt": true,
+	"rev": true, "hon": true, "gen": true, "col": true, "capt": true, "lt": true, "sgt": true,
+	"gov": true, "sen": true, "rep": true, "e.g": true, "i.e": true, "cf": true, "vs": true,
+	"viz": true, "approx": true,
+}
+
+// numberPrefixes are abbreviations that do not end a sentence when a number
+// follows, as in "see No. 5" or "on p. 12".
+var numberPrefixes = map[string]bool{
+	"no": true, "nos": true, "p": true, "pp": true, "fig": true, "figs": true,
+	"vol": true, "ch": true, "sec": true, "art": true,
+}
+
+// Scanning states of Split.
+const (
+	inSentence = iota
+	afterEnd
+	inBreak
+)
+
+// Split splits text after each sentence end and line break, keeping the
+// whitespace that follows with the sentence, so the parts join back into the
+// text. A period does not end a sentence when it is not followed by
+// whitespace, as in "3.5", when the next word starts in lower case, or when
+// it follows a title such as "Dr.", an initial such as the "J." of "J. Smith"
+// (but not the pronoun "I"), or a number prefix such as "No." before a number.
+func Split(text string) []string {
+	var parts []string
+
+	start := 0
+	state := inSentence
+
+	for i, r := range text {
+		space := unicode.IsSpace(r)
+
+		if state == inBreak && !space {
+			parts = append(parts, text[start:i])
+			start = i
+			state = inSentence
+		}
+
+		switch {
+		case r == '\n':
+			state = inBreak
+		case state == afterEnd && space:
+			state = inSentence
+			if boundary(text[start:i], text[i:]) {
+				state = inBreak
+			}
+		case state == inBreak:
+		case strings.ContainsRune(ends, r):
+			state = afterEnd
+		case state == afterEnd && strings.ContainsRune(closingMarks, r):
+		default:
+			state = inSentence
+		}
+	}
+
+	if start < len(text) {
+		parts = append(parts, text[start:])
+	}
+
+	return parts
+}
+
+// boundary reports whether the sentence end at the end of before, followed by
+// whitespace and then after, ends the sentence.
+func boundary(before, after string) bool {
+	before = strings.TrimRight(before, closingMarks)
+	if !strings.HasSuffix(before, ".") {
+		return true
+	}
+
+	next, _ := utf8.DecodeRuneInString(strings.TrimLeft(strings.TrimLeftFunc(after, unicode.IsSpace), openingMarks))
+	if unicode.IsLower(next) {
+		return false
+	}
+
+	word := before[strings.LastIndexFunc(before, unicode.IsSpace)+1:]
+	word = strings.TrimSuffix(strings.TrimLeft(word, openingMarks), ".")
+
+	switch {
+	case titles[strings.ToLower(word)]:
+		return false
+	case numberPrefixes[strings.ToLower(word)] && unicode.IsDigit(next):
+		return false
+	case utf8.RuneCountInString(word) == 1 && word != "I" && unicode.IsUpper([]rune(word)[0]):
+		return false
+	default:
+		return true
+	}
+}
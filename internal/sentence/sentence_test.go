@@ -0,0 +1,67 @@
+package sentence_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/sentence"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	for name, test := range map[string]struct {
+		text string
+		want []string
+	}{
+		"sentences":      {"One. Two! Three? Four… Five", []string{"One. ", "Two! ", "Three? ", "Four… ", "Five"}},
+		"decimal point":  {"It cost 3.5 coins. Cheap.", []string{"It cost 3.5 coins. ", "Cheap."}},
+		"title":          {"Dr. Watson arrived. Mrs. Hudson did not.", []string{"Dr. Watson arrived. ", "Mrs. Hudson did not."}},
+		"latin":          {"Fruit, e.g. Apples, is good. I.e. Healthy.", []string{"Fruit, e.g. Apples, is good. ", "I.e. Healthy."}},
+		"initials":       {"J. R. R. Tolkien wrote it. So did I. Then we left.", []string{"J. R. R. Tolkien wrote it. ", "So did I. ", "Then we left."}},
+		"number prefix":  {"See No. 5 here. The answer was No. Then silence.", []string{"See No. 5 here. ", "The answer was No. ", "Then silence."}},
+		"lower case":     {"He bought eggs, milk, etc. and bread. Done.", []string{"He bought eggs, milk, etc. and bread. ", "Done."}},
+		"quotes":         {`"Why?" Bob asked. "Because." She left.`, []string{`"Why?" `, `Bob asked. `, `"Because." `, "She left."}},
+		"quoted title":   {`He said "Mr. Smith" twice.`, []string{`He said "Mr. Smith" twice.`}},
+		"line breaks":    {"Title\n\nFirst line. Second.\n", []string{"Title\n\n", "First line. ", "Second.\n"}},
+		"no terminator":  {"Just words", []string{"Just words"}},
+		"trailing space": {"End.  ", []string{"End.  "}},
+	} {
+		got := sentence.Split(test.text)
+		assert.Equal(t, test.want, got, name)
+		assert.Equal(t, test.text, strings.Join(got, ""), "%s: parts join back into the text", name)
+	}
+}
+
+// FuzzSplit checks that Split never panics, loses or adds nothing and keeps
+// the text valid UTF-8, since it receives untrusted document text.
+func FuzzSplit(f *testing.F) {
+	for _, seed := range []string{
+		"One. Two! Three? Four… Five",
+		"Dr. Watson arrived.\n\nJ. R. R. Tolkien wrote it.",
+		`"Why?" Bob asked. "Because." She left.`,
+		"Ünïcödé… 日本語。    . ",
+		"\xff\xfe. .\n",
+	} {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, text string) {
+		parts := sentence.Split(text)
+		if strings.Join(parts, "") != text {
+			t.Fatalf("parts %q do not join back into %q", parts, text)
+		}
+
+		for _, part := range parts {
+			if part == "" {
+				t.Fatalf("empty part in %q", parts)
+			}
+
+			if utf8.ValidString(text) && !utf8.ValidString(part) {
+				t.Fatalf("part %q of valid text is not valid UTF-8", part)
+			}
+		}
+	})
+}
@@ -0,0 +1,512 @@
+// Package snac decodes SNAC (Multi-Scale Neural Audio Codec) codes to audio
+// in pure Go, so speech models that emit SNAC codes, such as Orpheus, can run
+// without an external decoder.
+//
+// Weights are read from a safetensors export of the PyTorch model, e.g. the
+// hubertsiuzdak/snac_24khz checkpoint. Only the decoder path is implemented;
+// checkpoints that use local attention in the decoder are not supported.
+package snac
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"runtime"
+	"sync"
+)
+
+// Decoder errors.
+var (
+	ErrMissingTensor  = errors.New("missing tensor")
+	ErrTensorShape    = errors.New("unexpected tensor shape")
+	ErrInvalidCodes   = errors.New("invalid SNAC codes")
+	ErrInvalidConfig  = errors.New("invalid SNAC config")
+	errNoCodebookRows = errors.New("codebook has no rows")
+)
+
+const (
+	snakeEpsilon        = 1e-9
+	decoderPadding      = 3
+	residualUnitsPerBlk = 3
+)
+
+// residualDilations are the dilations of the residual units in each decoder block.
+var residualDilations = [residualUnitsPerBlk]int{1, 3, 9}
+
+// Config describes the architecture of a SNAC checkpoint.
+type Config struct {
+	SampleRate   int
+	CodebookSize int
+	LatentDim    int
+	DecoderDim   int
+	DecoderRates []int
+	VQStrides    []int
+	Depthwise    bool
+	Noise        bool
+}
+
+// Config24kHz returns the configuration of the 24 kHz speech model used by Orpheus.
+func Config24kHz() Config {
+	return Config{
+		SampleRate:   24000,
+		CodebookSize: 4096,
+		LatentDim:    768,
+		DecoderDim:   1024,
+		DecoderRates: []int{8, 8, 4, 2},
+		VQStrides:    []int{4, 2, 1},
+		Depthwise:    true,
+		Noise:        true,
+	}
+}
+
+// Decoder turns SNAC codes into audio samples.
+type Decoder struct {
+	cfg        Config
+	quantizers []quantizer
+	input      []*conv1d
+	blocks     []decoderBlock
+	finalSnake []float32
+	output     *conv1d
+}
+
+type quantizer struct {
+	codebook Tensor
+	outProj  *conv1d
+}
+
+type decoderBlock struct {
+	snake    []float32
+	upsample *convTranspose1d
+	noise    *conv1d
+	units    [residualUnitsPerBlk]residualUnit
+}
+
+type residualUnit struct {
+	snake1 []float32
+	conv1  *conv1d
+	snake2 []float32
+	conv2  *conv1d
+}
+
+// Load reads a safetensors checkpoint and builds the decoder for cfg.
+func Load(path string, cfg Config) (*Decoder, error) {
+	tensors, err := LoadSafetensors(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(tensors, cfg)
+}
+
+// New builds a decoder from the tensors of a SNAC checkpoint.
+func New(tensors map[string]Tensor, cfg Config) (*Decoder, error) {
+	if len(cfg.VQStrides) == 0 || len(cfg.DecoderRates) == 0 || cfg.CodebookSize <= 0 {
+		return nil, fmt.Errorf("%w: %+v", ErrInvalidConfig, cfg)
+	}
+
+	weights := weightSet(tensors)
+
+	decoder := &Decoder{
+		cfg:        cfg,
+		quantizers: make([]quantizer, 0, len(cfg.VQStrides)),
+		input:      nil,
+		blocks:     make([]decoderBlock, 0, len(cfg.DecoderRates)),
+		finalSnake: nil,
+		output:     nil,
+	}
+
+	err := decoder.loadQuantizers(weights)
+	if err != nil {
+		return nil, err
+	}
+
+	err = decoder.loadDecoder(weights)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder, nil
+}
+
+// SampleRate returns the sample rate of the decoded audio.
+func (d *Decoder) SampleRate() int {
+	return d.cfg.SampleRate
+}
+
+func (d *Decoder) loadQuantizers(weights weightSet) error {
+	for i := range d.cfg.VQStrides {
+		prefix := fmt.Sprintf("quantizer.quantizers.%d.", i)
+
+		codebook, err := weights.tensor(prefix + "codebook.weight")
+		if err != nil {
+			return err
+		}
+
+		if len(codebook.Shape) != 2 || codebook.Shape[0] < d.cfg.CodebookSize {
+			return fmt.Errorf("%w: codebook %d has shape %v", ErrTensorShape, i, codebook.Shape)
+		}
+
+		outProj, err := weights.conv1d(prefix+"out_proj", 1, 1, 0)
+		if err != nil {
+			return err
+		}
+
+		if outProj.in != codebook.Shape[1] || outProj.out != d.cfg.LatentDim {
+			return fmt.Errorf("%w: out_proj %d maps %d to %d channels", ErrTensorShape, i, outProj.in, outProj.out)
+		}
+
+		d.quantizers = append(d.quantizers, quantizer{codebook: codebook, outProj: outProj})
+	}
+
+	return nil
+}
+
+func (d *Decoder) loadDecoder(weights weightSet) error {
+	layer := 0
+
+	if d.cfg.Depthwise {
+		depthwise, err := weights.conv1d(layerName(layer), d.cfg.LatentDim, 1, decoderPadding)
+		if err != nil {
+			return err
+		}
+
+		pointwise, err := weights.conv1d(layerName(layer+1), 1, 1, 0)
+		if err != nil {
+			return err
+		}
+
+		d.input = []*conv1d{depthwise, pointwise}
+		layer += 2
+	} else {
+		conv, err := weights.conv1d(layerName(layer), 1, 1, decoderPadding)
+		if err != nil {
+			return err
+		}
+
+		d.input = []*conv1d{conv}
+		layer++
+	}
+
+	for i, rate := range d.cfg.DecoderRates {
+		outputDim := d.cfg.DecoderDim >> (i + 1)
+
+		groups := 1
+		if d.cfg.Depthwise {
+			groups = outputDim
+		}
+
+		block, err := weights.decoderBlock(layerName(layer)+".block.", rate, groups, d.cfg.Noise)
+		if err != nil {
+			return err
+		}
+
+		d.blocks = append(d.blocks, block)
+		layer++
+	}
+
+	finalSnake, err := weights.snake(layerName(layer))
+	if err != nil {
+		return err
+	}
+
+	output, err := weights.conv1d(layerName(layer+1), 1, 1, decoderPadding)
+	if err != nil {
+		return err
+	}
+
+	d.finalSnake, d.output = finalSnake, output
+
+	return nil
+}
+
+func layerName(index int) string {
+	return fmt.Sprintf("decoder.model.%d", index)
+}
+
+// Decode converts one code sequence per codebook into audio samples in [-1, 1].
+// Codebook i must hold n/VQStrides[i] codes for a common latent length n. The
+// decoder's noise blocks draw from rng, so a fixed seed gives identical audio.
+func (d *Decoder) Decode(codes [][]int, rng *rand.Rand) ([]float32, error) {
+	latent, err := d.fromCodes(codes)
+	if err != nil {
+		return nil, err
+	}
+
+	x := latent
+	for _, conv := range d.input {
+		x = conv.forward(x)
+	}
+
+	for _, block := range d.blocks {
+		x = block.forward(x, rng)
+	}
+
+	snake(x, d.finalSnake)
+	x = d.output.forward(x)
+
+	samples := x[0]
+	for i, sample := range samples {
+		samples[i] = float32(math.Tanh(float64(sample)))
+	}
+
+	return samples, nil
+}
+
+// fromCodes looks up and projects every codebook's codes and sums them at the
+// latent frame rate.
+func (d *Decoder) fromCodes(codes [][]int) ([][]float32, error) {
+	if len(codes) != len(d.quantizers) {
+		return nil, fmt.Errorf("%w: got %d codebooks, want %d", ErrInvalidCodes, len(codes), len(d.quantizers))
+	}
+
+	frames := len(codes[0]) * d.cfg.VQStrides[0]
+	if frames == 0 {
+		return nil, fmt.Errorf("%w: no codes", ErrInvalidCodes)
+	}
+
+	latent := newActivations(d.cfg.LatentDim, frames)
+
+	for i, level := range codes {
+		stride := d.cfg.VQStrides[i]
+		if len(level)*stride != frames {
+			return nil, fmt.Errorf("%w: codebook %d has %d codes, want %d", ErrInvalidCodes, i, len(level), frames/stride)
+		}
+
+		embedded, err := d.quantizers[i].embed(level, d.cfg.CodebookSize)
+		if err != nil {
+			return nil, fmt.Errorf("codebook %d: %w", i, err)
+		}
+
+		projected := d.quantizers[i].outProj.forward(embedded)
+
+		for channel, row := range projected {
+			for t, value := range row {
+				for repeat := range stride {
+					latent[channel][t*stride+repeat] += value
+				}
+			}
+		}
+	}
+
+	return latent, nil
+}
+
+func (q quantizer) embed(level []int, codebookSize int) ([][]float32, error) {
+	dim := q.codebook.Shape[1]
+	if dim == 0 {
+		return nil, errNoCodebookRows
+	}
+
+	embedded := newActivations(dim, len(level))
+
+	for t, code := range level {
+		if code < 0 || code >= codebookSize {
+			return nil, fmt.Errorf("%w: code %d is outside [0, %d)", ErrInvalidCodes, code, codebookSize)
+		}
+
+		row := q.codebook.Data[code*dim : (code+1)*dim]
+		for channel, value := range row {
+			embedded[channel][t] = value
+		}
+	}
+
+	return embedded, nil
+}
+
+func (b decoderBlock) forward(x [][]float32, rng *rand.Rand) [][]float32 {
+	snake(x, b.snake)
+	x = b.upsample.forward(x)
+
+	if b.noise != nil {
+		h := b.noise.forward(x)
+
+		noise := make([]float32, len(x[0]))
+		for t := range noise {
+			noise[t] = float32(rng.NormFloat64())
+		}
+
+		for channel, row := range x {
+			for t := range row {
+				row[t] += noise[t] * h[channel][t]
+			}
+		}
+	}
+
+	for _, unit := range b.units {
+		x = unit.forward(x)
+	}
+
+	return x
+}
+
+func (u residualUnit) forward(x [][]float32) [][]float32 {
+	y := copyActivations(x)
+	snake(y, u.snake1)
+	y = u.conv1.forward(y)
+	snake(y, u.snake2)
+	y = u.conv2.forward(y)
+
+	for channel, row := range y {
+		for t := range row {
+			row[t] += x[channel][t]
+		}
+	}
+
+	return y
+}
+
+// snake applies x + sin²(αx)/α per channel in place.
+func snake(x [][]float32, alpha []float32) {
+	parallelFor(len(x), func(channel int) {
+		a := float64(alpha[channel])
+		inverse := 1 / (a + snakeEpsilon)
+
+		row := x[channel]
+		for t, value := range row {
+			s := math.Sin(a * float64(value))
+			row[t] = value + float32(inverse*s*s)
+		}
+	})
+}
+
+// conv1d is a 1-D convolution with stride 1. Weights are [out][in/groups][kernel].
+type conv1d struct {
+	weight   []float32
+	bias     []float32
+	in       int
+	out      int
+	kernel   int
+	groups   int
+	dilation int
+	padding  int
+}
+
+func (c *conv1d) forward(x [][]float32) [][]float32 {
+	length := len(x[0])
+	outLength := length + 2*c.padding - c.dilation*(c.kernel-1)
+	inPerGroup := c.in / c.groups
+	outPerGroup := c.out / c.groups
+	y := newActivations(c.out, outLength)
+
+	parallelFor(c.out, func(o int) {
+		row := y[o]
+		if c.bias != nil {
+			for t := range row {
+				row[t] = c.bias[o]
+			}
+		}
+
+		firstInput := (o / outPerGroup) * inPerGroup
+
+		for ic := range inPerGroup {
+			input := x[firstInput+ic]
+			weights := c.weight[(o*inPerGroup+ic)*c.kernel : (o*inPerGroup+ic+1)*c.kernel]
+
+			for k, weight := range weights {
+				offset := k*c.dilation - c.padding
+				low, high := max(0, -offset), min(outLength, length-offset)
+
+				for t := low; t < high; t++ {
+					row[t] += weight * input[t+offset]
+				}
+			}
+		}
+	})
+
+	return y
+}
+
+// convTranspose1d is a transposed 1-D convolution. Weights are [in][out][kernel].
+type convTranspose1d struct {
+	weight        []float32
+	bias          []float32
+	in            int
+	out           int
+	kernel        int
+	stride        int
+	padding       int
+	outputPadding int
+}
+
+func (c *convTranspose1d) forward(x [][]float32) [][]float32 {
+	length := len(x[0])
+	outLength := (length-1)*c.stride - 2*c.padding + c.kernel + c.outputPadding
+	y := newActivations(c.out, outLength)
+
+	parallelFor(c.out, func(o int) {
+		row := y[o]
+		if c.bias != nil {
+			for t := range row {
+				row[t] = c.bias[o]
+			}
+		}
+
+		for ic := range c.in {
+			input := x[ic]
+			weights := c.weight[(ic*c.out+o)*c.kernel : (ic*c.out+o+1)*c.kernel]
+
+			for k, weight := range weights {
+				for t, value := range input {
+					index := t*c.stride + k - c.padding
+					if index >= 0 && index < outLength {
+						row[index] += weight * value
+					}
+				}
+			}
+		}
+	})
+
+	return y
+}
+
+func newActivations(channels, length int) [][]float32 {
+	backing := make([]float32, channels*length)
+	rows := make([][]float32, channels)
+
+	for channel := range rows {
+		rows[channel] = backing[channel*length : (channel+1)*length : (channel+1)*length]
+	}
+
+	return rows
+}
+
+func copyActivations(x [][]float32) [][]float32 {
+	y := newActivations(len(x), len(x[0]))
+	for channel, row := range x {
+		copy(y[channel], row)
+	}
+
+	return y
+}
+
+// parallelFor runs fn for every index in [0, n) on all available CPUs.
+func parallelFor(n int, fn func(int)) {
+	workers := min(runtime.GOMAXPROCS(0), n)
+	if workers <= 1 {
+		for i := range n {
+			fn(i)
+		}
+
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	chunk := (n + workers - 1) / workers
+	for start := 0; start < n; start += chunk {
+		end := min(start+chunk, n)
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := start; i < end; i++ {
+				fn(i)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
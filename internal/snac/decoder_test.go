@@ -0,0 +1,285 @@
+package snac_test
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/snac"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tinyAlpha makes snake nearly the identity: x + sin²(αx)/α ≈ x + αx².
+const tinyAlpha = 1e-6
+
+// checkpoint collects named F32 tensors and writes them as safetensors.
+type checkpoint map[string]snac.Tensor
+
+func (c checkpoint) add(name string, shape []int, values ...float32) {
+	c[name] = snac.Tensor{Shape: shape, Data: values}
+}
+
+func (c checkpoint) fill(name string, shape []int, value func(int) float32) {
+	size := 1
+	for _, dim := range shape {
+		size *= dim
+	}
+
+	values := make([]float32, size)
+	for i := range values {
+		values[i] = value(i)
+	}
+
+	c.add(name, shape, values...)
+}
+
+func (c checkpoint) encode(t *testing.T) []byte {
+	t.Helper()
+
+	header := map[string]any{"__metadata__": map[string]string{"format": "pt"}}
+
+	var body []byte
+
+	for name, tensor := range c {
+		start := len(body)
+		for _, value := range tensor.Data {
+			body = binary.LittleEndian.AppendUint32(body, math.Float32bits(value))
+		}
+
+		header[name] = map[string]any{"dtype": "F32", "shape": tensor.Shape, "data_offsets": []int{start, len(body)}}
+	}
+
+	headerJSON, err := json.Marshal(header)
+	require.NoError(t, err)
+
+	data := binary.LittleEndian.AppendUint64(nil, uint64(len(headerJSON)))
+	data = append(data, headerJSON...)
+
+	return append(data, body...)
+}
+
+func (c checkpoint) write(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "snac.safetensors")
+	require.NoError(t, os.WriteFile(path, c.encode(t), 0o600))
+
+	return path
+}
+
+// addIdentityResidualUnits adds residual units whose convolutions are zero, so
+// each unit passes its input through unchanged.
+func (c checkpoint) addIdentityResidualUnits(prefix string, first, channels, groups int) {
+	for unit := range 3 {
+		unitPrefix := fmt.Sprintf("%s%d.block.", prefix, first+unit)
+		c.fill(unitPrefix+"0.alpha", []int{1, channels, 1}, func(int) float32 { return tinyAlpha })
+		c.fill(unitPrefix+"1.weight", []int{channels, channels / groups, 7}, func(int) float32 { return 0 })
+		c.fill(unitPrefix+"1.bias", []int{channels}, func(int) float32 { return 0 })
+		c.fill(unitPrefix+"2.alpha", []int{1, channels, 1}, func(int) float32 { return tinyAlpha })
+		c.fill(unitPrefix+"3.weight", []int{channels, channels, 1}, func(int) float32 { return 0 })
+		c.fill(unitPrefix+"3.bias", []int{channels}, func(int) float32 { return 0 })
+	}
+}
+
+// centerTap returns kernel-7 weights that copy the input channel selected by keep.
+func centerTap(keep func(row int) bool) func(int) float32 {
+	return func(i int) float32 {
+		if i%7 == 3 && keep(i/7) {
+			return 1
+		}
+
+		return 0
+	}
+}
+
+func TestDecoder_KnownAnswer(t *testing.T) {
+	t.Parallel()
+
+	// A model small enough to follow by hand: with near-identity snakes and
+	// pass-through residual units, the output is tanh of the upsampling layer.
+	weights := checkpoint{}
+	weights.add("quantizer.quantizers.0.codebook.weight", []int{3, 1}, 0.1, 0.2, -0.3)
+	weights.add("quantizer.quantizers.0.out_proj.weight", []int{1, 1, 1}, 1)
+	weights.fill("decoder.model.0.weight", []int{2, 1, 7}, centerTap(func(row int) bool { return row == 0 }))
+	weights.add("decoder.model.1.block.0.alpha", []int{1, 2, 1}, tinyAlpha, tinyAlpha)
+	// Weight-normalized upsampling: g=0.5, v=[3, 4] folds to [0.3, 0.4].
+	weights.add("decoder.model.1.block.1.weight_g", []int{2, 1, 1}, 0.5, 1)
+	weights.add("decoder.model.1.block.1.weight_v", []int{2, 1, 2}, 3, 4, 1, 0)
+	weights.addIdentityResidualUnits("decoder.model.1.block.", 2, 1, 1)
+	weights.add("decoder.model.2.alpha", []int{1, 1, 1}, tinyAlpha)
+	weights.fill("decoder.model.3.parametrizations.weight.original1", []int{1, 1, 7}, centerTap(func(int) bool { return true }))
+	weights.add("decoder.model.3.parametrizations.weight.original0", []int{1, 1, 1}, 1)
+
+	decoder, err := snac.Load(weights.write(t), snac.Config{
+		SampleRate:   24000,
+		CodebookSize: 3,
+		LatentDim:    1,
+		DecoderDim:   2,
+		DecoderRates: []int{1},
+		VQStrides:    []int{1},
+		Depthwise:    false,
+		Noise:        false,
+	})
+	require.NoError(t, err)
+
+	samples, err := decoder.Decode([][]int{{0, 1, 2}}, rand.New(rand.NewPCG(1, 1)))
+	require.NoError(t, err)
+
+	// With stride 1 the upsampling computes y[t] = 0.4*x[t] + 0.3*x[t+1].
+	want := []float64{math.Tanh(0.4*0.1 + 0.3*0.2), math.Tanh(0.4*0.2 - 0.3*0.3), math.Tanh(-0.4 * 0.3)}
+
+	require.Len(t, samples, len(want))
+
+	for i, sample := range samples {
+		assert.InDelta(t, want[i], sample, 1e-5, "sample %d", i)
+	}
+}
+
+// newRandomCheckpoint builds a depthwise checkpoint with noise blocks, two
+// codebooks with strides 2 and 1, and upsampling rates 2 and 3.
+func newRandomCheckpoint(rng *rand.Rand) (checkpoint, snac.Config) {
+	cfg := snac.Config{
+		SampleRate:   24000,
+		CodebookSize: 5,
+		LatentDim:    4,
+		DecoderDim:   8,
+		DecoderRates: []int{2, 3},
+		VQStrides:    []int{2, 1},
+		Depthwise:    true,
+		Noise:        true,
+	}
+
+	random := func(int) float32 { return float32(rng.NormFloat64() * 0.3) }
+	positive := func(int) float32 { return float32(0.5 + rng.Float64()) }
+
+	weights := checkpoint{}
+
+	for level := range cfg.VQStrides {
+		prefix := fmt.Sprintf("quantizer.quantizers.%d.", level)
+		weights.fill(prefix+"codebook.weight", []int{cfg.CodebookSize, 2}, random)
+		weights.fill(prefix+"out_proj.weight_g", []int{cfg.LatentDim, 1, 1}, positive)
+		weights.fill(prefix+"out_proj.weight_v", []int{cfg.LatentDim, 2, 1}, random)
+		weights.fill(prefix+"out_proj.bias", []int{cfg.LatentDim}, random)
+	}
+
+	weights.fill("decoder.model.0.weight", []int{cfg.LatentDim, 1, 7}, random)
+	weights.fill("decoder.model.0.bias", []int{cfg.LatentDim}, random)
+	weights.fill("decoder.model.1.weight", []int{cfg.DecoderDim, cfg.LatentDim, 1}, random)
+
+	layer := 2
+	channels := cfg.DecoderDim
+
+	for _, rate := range cfg.DecoderRates {
+		prefix := fmt.Sprintf("decoder.model.%d.block.", layer)
+		weights.fill(prefix+"0.alpha", []int{1, channels, 1}, positive)
+		weights.fill(prefix+"1.weight_g", []int{channels, 1, 1}, positive)
+		weights.fill(prefix+"1.weight_v", []int{channels, channels / 2, 2 * rate}, random)
+		weights.fill(prefix+"1.bias", []int{channels / 2}, random)
+		channels /= 2
+		weights.fill(prefix+"2.linear.weight", []int{channels, channels, 1}, random)
+		weights.addIdentityResidualUnits(prefix, 3, channels, channels)
+		weights.fill(prefix+"3.block.1.weight", []int{channels, 1, 7}, random)
+		layer++
+	}
+
+	weights.fill(fmt.Sprintf("decoder.model.%d.alpha", layer), []int{1, channels, 1}, positive)
+	weights.fill(fmt.Sprintf("decoder.model.%d.weight", layer+1), []int{1, channels, 7}, random)
+
+	return weights, cfg
+}
+
+func TestDecoder_Decode(t *testing.T) {
+	t.Parallel()
+
+	weights, cfg := newRandomCheckpoint(rand.New(rand.NewPCG(7, 7)))
+
+	decoder, err := snac.Load(weights.write(t), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 24000, decoder.SampleRate())
+
+	codes := [][]int{{0, 4, 2}, {1, 2, 3, 4, 0, 1}}
+
+	first, err := decoder.Decode(codes, rand.New(rand.NewPCG(1, 2)))
+	require.NoError(t, err)
+
+	// 6 latent frames, upsampled by 2 and then 3.
+	require.Len(t, first, 36)
+
+	for _, sample := range first {
+		assert.LessOrEqual(t, math.Abs(float64(sample)), 1.0)
+		assert.False(t, math.IsNaN(float64(sample)))
+	}
+
+	again, err := decoder.Decode(codes, rand.New(rand.NewPCG(1, 2)))
+	require.NoError(t, err)
+	assert.Equal(t, first, again, "the same seed must give the same audio")
+
+	other, err := decoder.Decode(codes, rand.New(rand.NewPCG(3, 4)))
+	require.NoError(t, err)
+	assert.NotEqual(t, first, other, "the noise blocks should depend on the seed")
+}
+
+func TestDecoder_InvalidCodes(t *testing.T) {
+	t.Parallel()
+
+	weights, cfg := newRandomCheckpoint(rand.New(rand.NewPCG(7, 7)))
+
+	decoder, err := snac.New(weights, cfg)
+	require.NoError(t, err)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+
+	for _, codes := range [][][]int{
+		{{0, 1}},                  // one codebook missing
+		{{0, 1}, {0, 1, 2}},       // lengths do not match the strides
+		{{0, 5}, {0, 1, 2, 3}},    // code outside the codebook
+		{{}, {}},                  // no codes
+		{{0, -1}, {0, 1, 2, 3}},   // negative code
+		{{0, 1, 2}, {0, 1, 2, 3}}, // too few codes in the finest codebook
+	} {
+		_, err = decoder.Decode(codes, rng)
+		require.ErrorIs(t, err, snac.ErrInvalidCodes, "codes %v", codes)
+	}
+}
+
+func TestNew_MissingTensor(t *testing.T) {
+	t.Parallel()
+
+	weights, cfg := newRandomCheckpoint(rand.New(rand.NewPCG(7, 7)))
+	delete(weights, "decoder.model.2.block.1.weight_v")
+
+	_, err := snac.New(weights, cfg)
+	require.ErrorIs(t, err, snac.ErrMissingTensor)
+	assert.Contains(t, err.Error(), "decoder.model.2.block.1.weight")
+}
+
+func TestParseSafetensors_HalfPrecision(t *testing.T) {
+	t.Parallel()
+
+	header := `{"half":{"dtype":"F16","shape":[3],"data_offsets":[0,6]},` +
+		`"brain":{"dtype":"BF16","shape":[2],"data_offsets":[6,10]}}`
+
+	data := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	data = append(data, header...)
+	// F16: 1.0, -2.0 and the smallest subnormal 2^-24.
+	data = binary.LittleEndian.AppendUint16(data, 0x3c00)
+	data = binary.LittleEndian.AppendUint16(data, 0xc000)
+	data = binary.LittleEndian.AppendUint16(data, 0x0001)
+	// BF16: 1.5 and -0.25.
+	data = binary.LittleEndian.AppendUint16(data, 0x3fc0)
+	data = binary.LittleEndian.AppendUint16(data, 0xbe80)
+
+	tensors, err := snac.ParseSafetensors(data)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{1, -2, float32(math.Ldexp(1, -24))}, tensors["half"].Data)
+	assert.Equal(t, []float32{1.5, -0.25}, tensors["brain"].Data)
+
+	_, err = snac.ParseSafetensors(data[:12])
+	require.ErrorIs(t, err, snac.ErrInvalidSafetensors)
+}
@@ -0,0 +1,158 @@
+package snac
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+)
+
+const (
+	safetensorsLengthSize = 8
+	safetensorsMetadata   = "__metadata__"
+	maxHeaderSize         = 100 << 20
+)
+
+// Safetensors errors.
+var (
+	ErrInvalidSafetensors = errors.New("invalid safetensors file")
+	ErrUnsupportedDType   = errors.New("unsupported tensor dtype")
+)
+
+// Tensor is a dense float32 tensor in row-major order.
+type Tensor struct {
+	Shape []int
+	Data  []float32
+}
+
+type tensorInfo struct {
+	DType       string   `json:"dtype"`
+	Shape       []int    `json:"shape"`
+	DataOffsets [2]int64 `json:"data_offsets"`
+}
+
+// LoadSafetensors reads every tensor of a safetensors file, converting F16 and
+// BF16 tensors to float32.
+func LoadSafetensors(path string) (map[string]Tensor, error) {
+	data, err := os.ReadFile(path) // #nosec G304 -- the SNAC model path comes from the service configuration
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %w", path, err)
+	}
+
+	return ParseSafetensors(data)
+}
+
+// ParseSafetensors decodes an in-memory safetensors file.
+func ParseSafetensors(data []byte) (map[string]Tensor, error) {
+	if len(data) < safetensorsLengthSize {
+		return nil, fmt.Errorf("%w: file is too short", ErrInvalidSafetensors)
+	}
+
+	headerSize := binary.LittleEndian.Uint64(data)
+	if headerSize > maxHeaderSize || headerSize > uint64(len(data)-safetensorsLengthSize) {
+		return nil, fmt.Errorf("%w: header size %d", ErrInvalidSafetensors, headerSize)
+	}
+
+	var header map[string]json.RawMessage
+
+	err := json.Unmarshal(data[safetensorsLengthSize:safetensorsLengthSize+headerSize], &header)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSafetensors, err)
+	}
+
+	body := data[safetensorsLengthSize+headerSize:]
+	tensors := make(map[string]Tensor, len(header))
+
+	for name, raw := range header {
+		if name == safetensorsMetadata {
+			continue
+		}
+
+		var info tensorInfo
+
+		err = json.Unmarshal(raw, &info)
+		if err != nil {
+			return nil, fmt.Errorf("%w: tensor '%s': %w", ErrInvalidSafetensors, name, err)
+		}
+
+		tensor, err := decodeTensor(info, body)
+		if err != nil {
+			return nil, fmt.Errorf("tensor '%s': %w", name, err)
+		}
+
+		tensors[name] = tensor
+	}
+
+	return tensors, nil
+}
+
+func decodeTensor(info tensorInfo, body []byte) (Tensor, error) {
+	start, end := info.DataOffsets[0], info.DataOffsets[1]
+	if start < 0 || end < start || end > int64(len(body)) {
+		return Tensor{}, fmt.Errorf("%w: data offsets %v", ErrInvalidSafetensors, info.DataOffsets)
+	}
+
+	raw := body[start:end]
+
+	elements := 1
+	for _, dim := range info.Shape {
+		elements *= dim
+	}
+
+	var elementSize int
+
+	switch info.DType {
+	case "F32":
+		elementSize = 4
+	case "F16", "BF16":
+		elementSize = 2
+	default:
+		return Tensor{}, fmt.Errorf("%w: %s", ErrUnsupportedDType, info.DType)
+	}
+
+	if len(raw) != elements*elementSize {
+		return Tensor{}, fmt.Errorf("%w: %d bytes for shape %v", ErrInvalidSafetensors, len(raw), info.Shape)
+	}
+
+	values := make([]float32, elements)
+
+	for i := range values {
+		switch info.DType {
+		case "F32":
+			values[i] = math.Float32frombits(binary.LittleEndian.Uint32(raw[4*i:]))
+		case "F16":
+			values[i] = float16ToFloat32(binary.LittleEndian.Uint16(raw[2*i:]))
+		default:
+			values[i] = math.Float32frombits(uint32(binary.LittleEndian.Uint16(raw[2*i:])) << 16)
+		}
+	}
+
+	return Tensor{Shape: info.Shape, Data: values}, nil
+}
+
+// float16ToFloat32 converts an IEEE 754 half-precision value.
+func float16ToFloat32(half uint16) float32 {
+	sign := uint32(half>>15) << 31
+	exponent := int(half>>10) & 0x1f
+	mantissa := uint32(half) & 0x3ff
+
+	switch {
+	case exponent == 0 && mantissa == 0:
+		return math.Float32frombits(sign)
+	case exponent == 0:
+		// Subnormal: shift the mantissa until its leading bit is implicit.
+		for mantissa&0x400 == 0 {
+			mantissa <<= 1
+			exponent--
+		}
+
+		exponent++
+		mantissa &= 0x3ff
+	case exponent == 0x1f:
+		return math.Float32frombits(sign | 0x7f800000 | mantissa<<13)
+	}
+
+	return math.Float32frombits(sign | uint32(exponent+112)<<23 | mantissa<<13) // #nosec G115 -- exponent+112 is positive
+}
@@ -0,0 +1,224 @@
+package snac
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// weightSet looks up the tensors of a checkpoint by PyTorch parameter name.
+type weightSet map[string]Tensor
+
+func (w weightSet) tensor(name string) (Tensor, error) {
+	tensor, ok := w[name]
+	if !ok {
+		return Tensor{}, fmt.Errorf("%w: '%s'", ErrMissingTensor, name)
+	}
+
+	return tensor, nil
+}
+
+// weight returns the weight of a layer, folding PyTorch weight normalization
+// (weight = g * v / ||v||, per slice of the first dimension) when present.
+func (w weightSet) weight(prefix string) (Tensor, error) {
+	plain, ok := w[prefix+".weight"]
+	if ok {
+		return plain, nil
+	}
+
+	magnitude, direction, err := w.weightNormPair(prefix)
+	if err != nil {
+		return Tensor{}, err
+	}
+
+	rows := direction.Shape[0]
+	if len(magnitude.Data) != rows || rows == 0 {
+		return Tensor{}, fmt.Errorf("%w: '%s' weight_g has shape %v for weight_v %v",
+			ErrTensorShape, prefix, magnitude.Shape, direction.Shape)
+	}
+
+	rowSize := len(direction.Data) / rows
+	folded := make([]float32, len(direction.Data))
+
+	for row := range rows {
+		values := direction.Data[row*rowSize : (row+1)*rowSize]
+
+		var sumSquares float64
+		for _, value := range values {
+			sumSquares += float64(value) * float64(value)
+		}
+
+		scale := float64(magnitude.Data[row]) / math.Sqrt(sumSquares)
+		for i, value := range values {
+			folded[row*rowSize+i] = float32(float64(value) * scale)
+		}
+	}
+
+	return Tensor{Shape: direction.Shape, Data: folded}, nil
+}
+
+// weightNormPair finds g and v under either the legacy weight_norm names or the
+// torch.nn.utils.parametrizations names.
+func (w weightSet) weightNormPair(prefix string) (Tensor, Tensor, error) {
+	for _, names := range [][2]string{
+		{".weight_g", ".weight_v"},
+		{".parametrizations.weight.original0", ".parametrizations.weight.original1"},
+	} {
+		magnitude, hasMagnitude := w[prefix+names[0]]
+		direction, hasDirection := w[prefix+names[1]]
+
+		if hasMagnitude && hasDirection {
+			return magnitude, direction, nil
+		}
+	}
+
+	return Tensor{}, Tensor{}, fmt.Errorf("%w: '%s.weight'", ErrMissingTensor, prefix)
+}
+
+func (w weightSet) bias(prefix string) []float32 {
+	bias, ok := w[prefix+".bias"]
+	if !ok {
+		return nil
+	}
+
+	return bias.Data
+}
+
+func (w weightSet) conv1d(prefix string, groups, dilation, padding int) (*conv1d, error) {
+	weight, err := w.weight(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(weight.Shape) != 3 || weight.Shape[0]%groups != 0 {
+		return nil, fmt.Errorf("%w: '%s' has shape %v for %d groups", ErrTensorShape, prefix, weight.Shape, groups)
+	}
+
+	bias := w.bias(prefix)
+	if bias != nil && len(bias) != weight.Shape[0] {
+		return nil, fmt.Errorf("%w: '%s.bias' has %d values", ErrTensorShape, prefix, len(bias))
+	}
+
+	return &conv1d{
+		weight:   weight.Data,
+		bias:     bias,
+		in:       weight.Shape[1] * groups,
+		out:      weight.Shape[0],
+		kernel:   weight.Shape[2],
+		groups:   groups,
+		dilation: dilation,
+		padding:  padding,
+	}, nil
+}
+
+// convTranspose1d loads an upsampling layer configured as in SNAC: kernel
+// 2*stride, padding ceil(stride/2) and output padding stride%2.
+func (w weightSet) convTranspose1d(prefix string, stride int) (*convTranspose1d, error) {
+	weight, err := w.weight(prefix)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(weight.Shape) != 3 {
+		return nil, fmt.Errorf("%w: '%s' has shape %v", ErrTensorShape, prefix, weight.Shape)
+	}
+
+	bias := w.bias(prefix)
+	if bias != nil && len(bias) != weight.Shape[1] {
+		return nil, fmt.Errorf("%w: '%s.bias' has %d values", ErrTensorShape, prefix, len(bias))
+	}
+
+	return &convTranspose1d{
+		weight:        weight.Data,
+		bias:          bias,
+		in:            weight.Shape[0],
+		out:           weight.Shape[1],
+		kernel:        weight.Shape[2],
+		stride:        stride,
+		padding:       (stride + 1) / 2,
+		outputPadding: stride % 2,
+	}, nil
+}
+
+func (w weightSet) snake(prefix string) ([]float32, error) {
+	alpha, err := w.tensor(prefix + ".alpha")
+	if err != nil {
+		return nil, err
+	}
+
+	return alpha.Data, nil
+}
+
+// decoderBlock loads a SNAC DecoderBlock: snake, upsampling, an optional noise
+// block, and residual units with dilations 1, 3 and 9.
+func (w weightSet) decoderBlock(prefix string, stride, groups int, noise bool) (decoderBlock, error) {
+	var block decoderBlock
+
+	var err error
+
+	block.snake, err = w.snake(prefix + "0")
+	if err != nil {
+		return decoderBlock{}, err
+	}
+
+	block.upsample, err = w.convTranspose1d(prefix+"1", stride)
+	if err != nil {
+		return decoderBlock{}, err
+	}
+
+	layer := 2
+
+	if noise {
+		block.noise, err = w.conv1d(prefix+"2.linear", 1, 1, 0)
+		if err != nil {
+			return decoderBlock{}, err
+		}
+
+		layer++
+	}
+
+	for i, dilation := range residualDilations {
+		block.units[i], err = w.residualUnit(prefix+strconv.Itoa(layer+i)+".block.", dilation, groups)
+		if err != nil {
+			return decoderBlock{}, err
+		}
+	}
+
+	return block, nil
+}
+
+func (w weightSet) residualUnit(prefix string, dilation, groups int) (residualUnit, error) {
+	var (
+		unit residualUnit
+		err  error
+	)
+
+	unit.snake1, err = w.snake(prefix + "0")
+	if err != nil {
+		return residualUnit{}, err
+	}
+
+	kernel, err := w.weight(prefix + "1")
+	if err != nil {
+		return residualUnit{}, err
+	}
+
+	padding := (kernel.Shape[len(kernel.Shape)-1] - 1) * dilation / 2
+
+	unit.conv1, err = w.conv1d(prefix+"1", groups, dilation, padding)
+	if err != nil {
+		return residualUnit{}, err
+	}
+
+	unit.snake2, err = w.snake(prefix + "2")
+	if err != nil {
+		return residualUnit{}, err
+	}
+
+	unit.conv2, err = w.conv1d(prefix+"3", 1, 1, 0)
+	if err != nil {
+		return residualUnit{}, err
+	}
+
+	return unit, nil
+}
@@ -0,0 +1,154 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// Adaptive concurrency tuning.
+const (
+	// adaptiveBackoff scales the limit down after a timeout or 5xx response.
+	adaptiveBackoff = 0.5
+	// adaptiveLatencyBackoff scales the limit down when latency climbs.
+	adaptiveLatencyBackoff = 0.9
+	// adaptiveLatencyTolerance is how far above the baseline latency a request
+	// may take before the service is considered saturated.
+	adaptiveLatencyTolerance = 2
+	// adaptiveBaselineDrift lets the baseline latency rise by up to 1/drift per
+	// request, so a permanently slower service is not read as saturated forever.
+	adaptiveBaselineDrift = 100
+)
+
+// AdaptiveLimiter caps the requests in flight with an AIMD controller: the
+// limit grows by about one per round of successful requests while latency stays
+// near its baseline, shrinks a little when latency climbs, and halves after a
+// timeout or 5xx response.
+//
+// Every successful Acquire must be finished with Success, Overload or Ignore.
+// A nil limiter allows every request.
+type AdaptiveLimiter struct {
+	mu       sync.Mutex
+	minLimit int
+	maxLimit int
+	limit    float64
+	inFlight int
+	baseline time.Duration
+	changed  chan struct{}
+}
+
+// NewAdaptiveLimiter creates an AdaptiveLimiter that starts at minLimit
+// requests in flight and never goes below minLimit or above maxLimit.
+// A maxLimit of zero leaves the limit unbounded.
+func NewAdaptiveLimiter(minLimit, maxLimit int) *AdaptiveLimiter {
+	minLimit = max(minLimit, 1)
+	if maxLimit <= 0 {
+		maxLimit = math.MaxInt32
+	}
+
+	maxLimit = max(maxLimit, minLimit)
+
+	return &AdaptiveLimiter{
+		mu:       sync.Mutex{},
+		minLimit: minLimit,
+		maxLimit: maxLimit,
+		limit:    float64(minLimit),
+		inFlight: 0,
+		baseline: 0,
+		changed:  make(chan struct{}),
+	}
+}
+
+// Limit returns the current number of requests allowed in flight.
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return int(l.limit)
+}
+
+// Acquire waits until a request may be sent.
+func (l *AdaptiveLimiter) Acquire(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	for {
+		l.mu.Lock()
+
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mu.Unlock()
+
+			return nil
+		}
+
+		changed := l.changed
+		l.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for a request slot: %w", ctx.Err())
+		}
+	}
+}
+
+// Success finishes a request that the service answered in latency.
+func (l *AdaptiveLimiter) Success(latency time.Duration) {
+	if l == nil {
+		return
+	}
+
+	l.release(func() {
+		if l.baseline == 0 || latency < l.baseline {
+			l.baseline = latency
+		} else {
+			l.baseline = min(latency, l.baseline+l.baseline/adaptiveBaselineDrift)
+		}
+
+		if latency > l.baseline*adaptiveLatencyTolerance {
+			l.limit *= adaptiveLatencyBackoff
+		} else {
+			l.limit += 1 / l.limit
+		}
+	})
+}
+
+// Overload finishes a request that timed out or got a 5xx response.
+func (l *AdaptiveLimiter) Overload() {
+	if l == nil {
+		return
+	}
+
+	l.release(func() {
+		l.limit *= adaptiveBackoff
+	})
+}
+
+// Ignore finishes a request whose outcome says nothing about the service's
+// capacity, such as one cancelled by the caller.
+func (l *AdaptiveLimiter) Ignore() {
+	if l == nil {
+		return
+	}
+
+	l.release(func() {})
+}
+
+// release frees a slot, applies adjust to the limit, and wakes waiting requests.
+func (l *AdaptiveLimiter) release(adjust func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inFlight--
+
+	adjust()
+
+	l.limit = min(max(l.limit, float64(l.minLimit)), float64(l.maxLimit))
+
+	close(l.changed)
+	l.changed = make(chan struct{})
+}
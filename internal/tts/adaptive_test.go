@@ -0,0 +1,80 @@
+package tts_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdaptiveLimiter(t *testing.T) {
+	t.Parallel()
+
+	limiter := tts.NewAdaptiveLimiter(1, 4)
+	assert.Equal(t, 1, limiter.Limit())
+
+	for range 20 {
+		require.NoError(t, limiter.Acquire(context.Background()))
+		limiter.Success(10 * time.Millisecond)
+	}
+
+	assert.Equal(t, 4, limiter.Limit(), "stable latency should grow the limit up to the maximum")
+
+	require.NoError(t, limiter.Acquire(context.Background()))
+	limiter.Overload()
+	assert.Equal(t, 2, limiter.Limit(), "a timeout or 5xx should halve the limit")
+
+	require.NoError(t, limiter.Acquire(context.Background()))
+	limiter.Success(time.Second)
+	assert.Equal(t, 1, limiter.Limit(), "latency far above the baseline should shrink the limit")
+
+	require.NoError(t, limiter.Acquire(context.Background()))
+	limiter.Ignore()
+	assert.Equal(t, 1, limiter.Limit(), "ignored requests should not change the limit")
+
+	require.NoError(t, limiter.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, limiter.Acquire(ctx), context.DeadlineExceeded, "a full limiter should make requests wait")
+
+	acquired := make(chan error, 1)
+
+	go func() {
+		acquired <- limiter.Acquire(context.Background())
+	}()
+
+	limiter.Ignore()
+	require.NoError(t, <-acquired, "releasing a slot should wake a waiting request")
+}
+
+func TestHTTPClient_AdaptiveConcurrency(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		http.Error(writer, "overloaded", http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+	client.SetCircuitBreaker(nil)
+	client.SetAdaptiveConcurrency(1, 8)
+
+	for range 3 {
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		require.ErrorIs(t, err, tts.ErrServiceNonOKStatus)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// 5xx responses keep the limit at its minimum, but every slot is released.
+	_, err := client.GenerateSpeech(ctx, newSpeechRequest())
+	require.ErrorIs(t, err, tts.ErrServiceNonOKStatus)
+}
@@ -0,0 +1,327 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"mime"
+	"net/http"
+	"net/url"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Request fields that an API maps to the names of its service.
+const (
+	FieldText           = "text"
+	FieldVoice          = "voice"
+	FieldSpeakerRefPath = "speaker_ref_path"
+	FieldLanguage       = "language"
+	FieldModel          = "model"
+	FieldTemperature    = "temperature"
+	FieldSeed           = "seed"
+	FieldStyle          = "style"
+)
+
+// Named API profiles of common TTS servers.
+const (
+	// ProfileDefault is the book-expert TTS HTTP service.
+	ProfileDefault = "default"
+	// ProfileOpenAI is the OpenAI speech API and the servers compatible with it.
+	ProfileOpenAI = "openai"
+	// ProfileCoqui is the Coqui TTS server.
+	ProfileCoqui = "coqui"
+	// ProfilePiper is the Piper HTTP server.
+	ProfilePiper = "piper"
+)
+
+// API errors.
+var (
+	// ErrUnknownProfile indicates an API profile that is not one of Profiles.
+	ErrUnknownProfile = errors.New("unknown API profile")
+	// ErrInvalidAPI indicates an API that cannot be used to send requests.
+	ErrInvalidAPI = errors.New("invalid API")
+	// ErrMissingAudio indicates a JSON response without audio in its audio field.
+	ErrMissingAudio = errors.New("response has no audio")
+)
+
+// wavContentTypes are the media types servers use for WAV audio.
+var wavContentTypes = []string{"audio/wav", "audio/x-wav", "audio/wave", "audio/vnd.wave"}
+
+// API describes the HTTP interface of a TTS service, so the client can talk to
+// servers other than the book-expert one.
+type API struct {
+	// Method is POST, which sends the fields as a JSON object, or GET, which
+	// sends them as query parameters.
+	Method string
+	// SpeechPath is the path of the synthesis endpoint.
+	SpeechPath string
+	// HealthPath is the path that HealthCheck expects a 200 response from;
+	// empty skips the check.
+	HealthPath string
+	// Fields maps each request field, such as FieldText, to its name in the
+	// service's requests. Unmapped fields, and fields other than the text
+	// whose value is zero, are not sent.
+	Fields map[string]string
+	// Extra are fields sent with every request, such as the audio format.
+	Extra map[string]string
+	// Headers are sent with every request, such as an API key.
+	Headers map[string]string
+	// AudioField names the field of a JSON response that holds the audio as
+	// base64, with dots for nested objects. Empty expects a WAV response.
+	AudioField string
+	// ErrorDetailField and ErrorCodeField locate the description and code of
+	// the error in JSON error responses, with dots for nested objects.
+	ErrorDetailField string
+	ErrorCodeField   string
+}
+
+// Profiles returns the names of the built-in API profiles.
+func Profiles() []string {
+	return []string{ProfileDefault, ProfileOpenAI, ProfileCoqui, ProfilePiper}
+}
+
+// Fields returns the request fields an API can map.
+func Fields() []string {
+	return []string{
+		FieldText, FieldVoice, FieldSpeakerRefPath, FieldLanguage,
+		FieldModel, FieldTemperature, FieldSeed, FieldStyle,
+	}
+}
+
+// DefaultAPI returns the API of the book-expert TTS HTTP service.
+func DefaultAPI() API {
+	return API{
+		Method:     http.MethodPost,
+		SpeechPath: apiGenerateSpeech,
+		HealthPath: apiHealth,
+		Fields: map[string]string{
+			FieldText:           "text",
+			FieldSpeakerRefPath: "speakerRefPath",
+			FieldLanguage:       "language",
+			FieldModel:          "model",
+			FieldTemperature:    "temperature",
+			FieldSeed:           "seed",
+			FieldStyle:          "style",
+		},
+		Extra:            nil,
+		Headers:          nil,
+		AudioField:       "",
+		ErrorDetailField: "detail",
+		ErrorCodeField:   "errorCode",
+	}
+}
+
+// Profile returns the API of a named profile. The empty name is the default.
+func Profile(name string) (API, error) {
+	api := DefaultAPI()
+
+	switch name {
+	case "", ProfileDefault:
+	case ProfileOpenAI:
+		api.SpeechPath = "/v1/audio/speech"
+		api.HealthPath = ""
+		api.Fields = map[string]string{FieldText: "input", FieldVoice: "voice", FieldModel: "model"}
+		api.Extra = map[string]string{"response_format": "wav"}
+		api.ErrorDetailField = "error.message"
+		api.ErrorCodeField = "error.code"
+	case ProfileCoqui:
+		api.Method = http.MethodGet
+		api.SpeechPath = "/api/tts"
+		api.HealthPath = ""
+		api.Fields = map[string]string{FieldText: "text", FieldVoice: "speaker_id", FieldLanguage: "language_id"}
+	case ProfilePiper:
+		api.SpeechPath = "/"
+		api.HealthPath = ""
+		api.Fields = map[string]string{FieldText: "text", FieldVoice: "voice"}
+	default:
+		return API{}, fmt.Errorf("%w: %q (profiles: %s)", ErrUnknownProfile, name, strings.Join(Profiles(), ", "))
+	}
+
+	return api, nil
+}
+
+// validate checks that requests can be built with the API.
+func (a API) validate() error {
+	if a.Method != http.MethodPost && a.Method != http.MethodGet {
+		return fmt.Errorf("%w: method %q, must be POST or GET", ErrInvalidAPI, a.Method)
+	}
+
+	if !strings.HasPrefix(a.SpeechPath, "/") {
+		return fmt.Errorf("%w: speech path %q must start with /", ErrInvalidAPI, a.SpeechPath)
+	}
+
+	if a.HealthPath != "" && !strings.HasPrefix(a.HealthPath, "/") {
+		return fmt.Errorf("%w: health path %q must start with /", ErrInvalidAPI, a.HealthPath)
+	}
+
+	if a.Fields[FieldText] == "" {
+		return fmt.Errorf("%w: the text field has no name", ErrInvalidAPI)
+	}
+
+	for field := range a.Fields {
+		if !slices.Contains(Fields(), field) {
+			return fmt.Errorf("%w: unknown field %q (fields: %s)", ErrInvalidAPI, field, strings.Join(Fields(), ", "))
+		}
+	}
+
+	return nil
+}
+
+// values returns the fields of req to send, by their names in the service.
+func (a API) values(req Request) map[string]any {
+	fields := map[string]any{
+		FieldText:           req.Text,
+		FieldVoice:          req.Voice,
+		FieldSpeakerRefPath: req.SpeakerRefPath,
+		FieldLanguage:       req.Language,
+		FieldModel:          req.Model,
+		FieldTemperature:    req.Temperature,
+		FieldSeed:           req.Seed,
+		FieldStyle:          req.Style,
+	}
+
+	values := make(map[string]any, len(a.Extra)+len(a.Fields))
+	for name, value := range a.Extra {
+		values[name] = value
+	}
+
+	for field, name := range a.Fields {
+		value := fields[field]
+		if name == "" || (field != FieldText && reflect.ValueOf(value).IsZero()) {
+			continue
+		}
+
+		values[name] = value
+	}
+
+	return values
+}
+
+// newRequest builds the HTTP request of req: a JSON body for POST, query
+// parameters for GET.
+func (a API) newRequest(ctx context.Context, baseURL string, req Request) (*http.Request, error) {
+	values := a.values(req)
+	target := baseURL + a.SpeechPath
+
+	var body io.Reader = http.NoBody
+
+	if a.Method == http.MethodGet {
+		query := url.Values{}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			query.Set(name, fmt.Sprint(values[name]))
+		}
+
+		target += "?" + query.Encode()
+	} else {
+		data, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal request: %w", err)
+		}
+
+		body = bytes.NewReader(data)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, a.Method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	if a.Method != http.MethodGet {
+		httpReq.Header.Set(headerContentType, contentTypeJSON)
+	}
+
+	httpReq.Header.Set(headerAccept, contentTypeWAV)
+
+	for name, value := range a.Headers {
+		httpReq.Header.Set(name, value)
+	}
+
+	return httpReq, nil
+}
+
+// audio returns the audio of a successful response: its body for WAV
+// responses, or the base64 AudioField of a JSON response.
+func (a API) audio(resp *http.Response) ([]byte, error) {
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio data: %w", err)
+	}
+
+	contentType := resp.Header.Get(headerContentType)
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	if a.AudioField != "" && mediaType == contentTypeJSON {
+		var reply any
+
+		err = json.Unmarshal(data, &reply)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid JSON: %w", ErrMissingAudio, err)
+		}
+
+		encoded, ok := lookup(reply, a.AudioField).(string)
+		if !ok || encoded == "" {
+			return nil, fmt.Errorf("%w: no %q field", ErrMissingAudio, a.AudioField)
+		}
+
+		data, err = base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q is not base64: %w", ErrMissingAudio, a.AudioField, err)
+		}
+	} else if !slices.Contains(wavContentTypes, mediaType) {
+		return nil, newUnexpectedContentTypeError(contentType)
+	}
+
+	if len(data) == 0 {
+		return nil, ErrReceivedEmptyAudio
+	}
+
+	return data, nil
+}
+
+// serviceError returns the error of a failed response: its detail and code
+// when it is JSON with a detail, or else its raw body.
+func (a API) serviceError(resp *http.Response) error {
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read error response body: %w", err)
+	}
+
+	var reply any
+	if json.Unmarshal(body, &reply) == nil {
+		if detail, ok := lookup(reply, a.ErrorDetailField).(string); ok {
+			code := lookup(reply, a.ErrorCodeField)
+			if code == nil {
+				code = ""
+			}
+
+			return newServiceErrorWithCodeError(resp.Status, detail, fmt.Sprint(code))
+		}
+	}
+
+	return newServiceNonOKStatusError(resp.Status, string(body))
+}
+
+// lookup returns the value at a dotted path of JSON objects, or nil.
+func lookup(value any, path string) any {
+	if path == "" {
+		return nil
+	}
+
+	for _, key := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+
+		value = object[key]
+	}
+
+	return value
+}
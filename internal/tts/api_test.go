@@ -0,0 +1,249 @@
+package tts_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// reply is a response of the given status, content type and body.
+func reply(request *http.Request, status int, contentType, body string) *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{contentType}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+		Request:    request,
+	}
+}
+
+// newAPIClient returns a client with api whose responses come from respond.
+func newAPIClient(t *testing.T, api tts.API, respond func(*http.Request) *http.Response) *tts.HTTPClient {
+	t.Helper()
+
+	client := tts.NewHTTPClient("http://tts.test", 5*time.Second)
+	client.SetCircuitBreaker(nil)
+	require.NoError(t, client.SetAPI(api))
+	client.SetRoundTripper(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		return respond(request), nil
+	}))
+
+	return client
+}
+
+func profile(t *testing.T, name string) tts.API {
+	t.Helper()
+
+	api, err := tts.Profile(name)
+	require.NoError(t, err)
+
+	return api
+}
+
+func TestProfile(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range append(tts.Profiles(), "") {
+		api, err := tts.Profile(name)
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, api.Fields[tts.FieldText], name)
+	}
+
+	_, err := tts.Profile("espeak")
+	require.ErrorIs(t, err, tts.ErrUnknownProfile)
+
+	defaultAPI := profile(t, "")
+	assert.Equal(t, tts.DefaultAPI(), defaultAPI)
+}
+
+func TestHTTPClient_SetAPIRejectsInvalidAPIs(t *testing.T) {
+	t.Parallel()
+
+	client := tts.NewHTTPClient("http://tts.test", time.Second)
+
+	for name, change := range map[string]func(*tts.API){
+		"method":        func(api *tts.API) { api.Method = http.MethodPut },
+		"relative path": func(api *tts.API) { api.SpeechPath = "speak" },
+		"no text":       func(api *tts.API) { delete(api.Fields, tts.FieldText) },
+		"unknown field": func(api *tts.API) { api.Fields["pitch"] = "pitch" },
+	} {
+		api := tts.DefaultAPI()
+		change(&api)
+		require.ErrorIs(t, client.SetAPI(api), tts.ErrInvalidAPI, name)
+	}
+}
+
+// TestHTTPClient_APIProfiles checks the requests each profile sends.
+func TestHTTPClient_APIProfiles(t *testing.T) {
+	t.Parallel()
+
+	for name, want := range map[string]struct {
+		method string
+		url    string
+		body   map[string]any
+	}{
+		tts.ProfileDefault: {
+			method: http.MethodPost,
+			url:    "http://tts.test/v1/generate/speech",
+			body:   map[string]any{"text": "hello", "language": "en", "temperature": 0.5},
+		},
+		tts.ProfileOpenAI: {
+			method: http.MethodPost,
+			url:    "http://tts.test/v1/audio/speech",
+			body:   map[string]any{"input": "hello", "voice": "tara", "response_format": "wav"},
+		},
+		tts.ProfileCoqui: {
+			method: http.MethodGet,
+			url:    "http://tts.test/api/tts?language_id=en&speaker_id=tara&text=hello",
+			body:   nil,
+		},
+		tts.ProfilePiper: {
+			method: http.MethodPost,
+			url:    "http://tts.test/",
+			body:   map[string]any{"text": "hello", "voice": "tara"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				method, url string
+				body        map[string]any
+			)
+
+			client := newAPIClient(t, profile(t, name), func(request *http.Request) *http.Response {
+				method, url = request.Method, request.URL.String()
+
+				data, err := io.ReadAll(request.Body)
+				assert.NoError(t, err)
+
+				if len(data) > 0 {
+					assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+					assert.NoError(t, json.Unmarshal(data, &body))
+				}
+
+				return reply(request, http.StatusOK, "audio/x-wav; rate=24000", "RIFF")
+			})
+
+			request := newSpeechRequest()
+			request.Voice = "tara"
+			request.Temperature = 0.5
+
+			audio, err := client.GenerateSpeech(context.Background(), request)
+			require.NoError(t, err)
+			assert.Equal(t, []byte("RIFF"), audio)
+			assert.Equal(t, want.method, method)
+			assert.Equal(t, want.url, url)
+			assert.Equal(t, want.body, body)
+		})
+	}
+}
+
+func TestHTTPClient_APIHeadersAndAudioField(t *testing.T) {
+	t.Parallel()
+
+	api := tts.DefaultAPI()
+	api.Headers = map[string]string{"Authorization": "Bearer secret"}
+	api.Extra = map[string]string{"format": "wav"}
+	api.AudioField = "result.audio"
+
+	var body map[string]any
+
+	client := newAPIClient(t, api, func(request *http.Request) *http.Response {
+		assert.Equal(t, "Bearer secret", request.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+
+		encoded := base64.StdEncoding.EncodeToString([]byte("RIFF"))
+
+		return reply(request, http.StatusOK, "application/json", `{"result": {"audio": "`+encoded+`"}}`)
+	})
+
+	audio, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+	assert.Equal(t, "wav", body["format"])
+
+	client = newAPIClient(t, api, func(request *http.Request) *http.Response {
+		return reply(request, http.StatusOK, "application/json", `{"result": {}}`)
+	})
+
+	_, err = client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.ErrorIs(t, err, tts.ErrMissingAudio)
+}
+
+func TestHTTPClient_APIResponses(t *testing.T) {
+	t.Parallel()
+
+	for name, test := range map[string]struct {
+		profile     string
+		status      int
+		contentType string
+		body        string
+		want        error
+		message     string
+	}{
+		"detail": {
+			profile: tts.ProfileDefault, status: http.StatusBadRequest, contentType: "application/json",
+			body: `{"detail": "bad speaker", "errorCode": "E_SPEAKER"}`,
+			want: tts.ErrServiceError, message: "bad speaker (code: E_SPEAKER)",
+		},
+		"nested error": {
+			profile: tts.ProfileOpenAI, status: http.StatusUnauthorized, contentType: "application/json",
+			body: `{"error": {"message": "invalid key", "code": "invalid_api_key"}}`,
+			want: tts.ErrServiceError, message: "invalid key (code: invalid_api_key)",
+		},
+		"JSON without detail": {
+			profile: tts.ProfileDefault, status: http.StatusBadRequest, contentType: "application/json",
+			body: `{"message": "no"}`,
+			want: tts.ErrServiceNonOKStatus, message: `{"message": "no"}`,
+		},
+		"plain text": {
+			profile: tts.ProfileCoqui, status: http.StatusInternalServerError, contentType: "text/plain",
+			body: "model crashed",
+			want: tts.ErrServiceNonOKStatus, message: "model crashed",
+		},
+		"not audio": {
+			profile: tts.ProfilePiper, status: http.StatusOK, contentType: "text/html",
+			body: "<html>",
+			want: tts.ErrUnexpectedContentType, message: "text/html",
+		},
+		"empty audio": {
+			profile: tts.ProfileDefault, status: http.StatusOK, contentType: "audio/wave",
+			body: "",
+			want: tts.ErrReceivedEmptyAudio, message: "",
+		},
+	} {
+		client := newAPIClient(t, profile(t, test.profile), func(request *http.Request) *http.Response {
+			return reply(request, test.status, test.contentType, test.body)
+		})
+
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		require.ErrorIs(t, err, test.want, name)
+		assert.Contains(t, err.Error(), test.message, name)
+	}
+}
+
+func TestHTTPClient_HealthCheckWithoutHealthPath(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+
+	client := newAPIClient(t, profile(t, tts.ProfileOpenAI), func(request *http.Request) *http.Response {
+		hits.Add(1)
+
+		return reply(request, http.StatusNotFound, "text/plain", "")
+	})
+
+	require.NoError(t, client.HealthCheck(context.Background()))
+	assert.Zero(t, hits.Load(), "services without a health path are not checked")
+}
@@ -0,0 +1,257 @@
+// Package audio post-processes synthesized speech: loudness measurement and
+// normalization to broadcast targets, resampling and channel remixing.
+package audio
+
+import (
+	"math"
+
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// DefaultTruePeakDB is the true-peak ceiling used when none is configured.
+const DefaultTruePeakDB = -1.0
+
+// ITU-R BS.1770-4 measurement constants.
+const (
+	blockSeconds       = 0.4
+	blockOverlap       = 4 // 400 ms blocks every 100 ms
+	absoluteGateLUFS   = -70.0
+	relativeGateLU     = -10.0
+	loudnessOffsetDB   = -0.691
+	truePeakOversample = 4
+	truePeakTaps       = 12 // interpolation taps on each side of the output sample
+)
+
+// biquad is a direct form I second-order IIR filter.
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+
+	return y
+}
+
+// kWeighting returns the BS.1770 pre-filter (a high shelf modelling the head)
+// followed by the RLB high-pass, designed for sampleRate. At 48 kHz the
+// coefficients match the ones tabulated in the standard.
+func kWeighting(sampleRate int) [2]biquad {
+	const (
+		shelfFrequency = 1681.974450955533
+		shelfGainDB    = 3.999843853973347
+		shelfQ         = 0.7071752369554196
+		passFrequency  = 38.13547087602444
+		passQ          = 0.5003270373238773
+	)
+
+	k := math.Tan(math.Pi * shelfFrequency / float64(sampleRate))
+	vh := math.Pow(10, shelfGainDB/20)
+	vb := math.Pow(vh, 0.4996667741545416)
+	a0 := 1 + k/shelfQ + k*k
+
+	shelf := biquad{
+		b0: (vh + vb*k/shelfQ + k*k) / a0,
+		b1: 2 * (k*k - vh) / a0,
+		b2: (vh - vb*k/shelfQ + k*k) / a0,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/shelfQ + k*k) / a0,
+		x1: 0, x2: 0, y1: 0, y2: 0,
+	}
+
+	k = math.Tan(math.Pi * passFrequency / float64(sampleRate))
+	a0 = 1 + k/passQ + k*k
+
+	highPass := biquad{
+		b0: 1,
+		b1: -2,
+		b2: 1,
+		a1: 2 * (k*k - 1) / a0,
+		a2: (1 - k/passQ + k*k) / a0,
+		x1: 0, x2: 0, y1: 0, y2: 0,
+	}
+
+	return [2]biquad{shelf, highPass}
+}
+
+// Loudness returns the integrated loudness of the audio in LUFS, gated as in
+// ITU-R BS.1770-4. Every channel is weighted 1, which is exact for mono and
+// stereo. Audio shorter than one 400 ms block is measured as a single block.
+// Silence returns negative infinity.
+func Loudness(audio wav.Audio) float64 {
+	frames := audio.Frames()
+	if frames == 0 || audio.SampleRate <= 0 {
+		return math.Inf(-1)
+	}
+
+	// Squared K-weighted samples, summed over channels.
+	power := make([]float64, frames)
+
+	for channel := range audio.Channels {
+		filters := kWeighting(audio.SampleRate)
+
+		for frame := range frames {
+			value := float64(audio.Samples[frame*audio.Channels+channel])
+			value = filters[1].process(filters[0].process(value))
+			power[frame] += value * value
+		}
+	}
+
+	blockSize := min(int(blockSeconds*float64(audio.SampleRate)), frames)
+	step := max(blockSize/blockOverlap, 1)
+
+	blocks := make([]float64, 0, frames/step+1)
+
+	for start := 0; start+blockSize <= frames; start += step {
+		var sum float64
+		for _, value := range power[start : start+blockSize] {
+			sum += value
+		}
+
+		blocks = append(blocks, sum/float64(blockSize))
+	}
+
+	absoluteGate := loudnessPower(absoluteGateLUFS)
+	relativeGate := loudnessPower(blockLoudness(gatedMean(blocks, absoluteGate)) + relativeGateLU)
+
+	return blockLoudness(gatedMean(blocks, max(absoluteGate, relativeGate)))
+}
+
+// blockLoudness converts a mean square to LUFS.
+func blockLoudness(meanSquare float64) float64 {
+	if meanSquare <= 0 {
+		return math.Inf(-1)
+	}
+
+	return loudnessOffsetDB + 10*math.Log10(meanSquare)
+}
+
+// loudnessPower converts LUFS back to a mean square.
+func loudnessPower(lufs float64) float64 {
+	return math.Pow(10, (lufs-loudnessOffsetDB)/10)
+}
+
+// gatedMean averages the blocks louder than gate.
+func gatedMean(blocks []float64, gate float64) float64 {
+	var (
+		sum   float64
+		count int
+	)
+
+	for _, block := range blocks {
+		if block > gate {
+			sum += block
+			count++
+		}
+	}
+
+	if count == 0 {
+		return 0
+	}
+
+	return sum / float64(count)
+}
+
+// TruePeak returns the true peak of the audio in dBTP: the largest absolute
+// sample after 4x oversampling, so inter-sample peaks that a DAC would
+// reconstruct are counted. Silence returns negative infinity.
+func TruePeak(audio wav.Audio) float64 {
+	kernel := interpolationKernel()
+	frames := audio.Frames()
+
+	var peak float64
+
+	for channel := range audio.Channels {
+		sample := func(frame int) float64 {
+			if frame < 0 || frame >= frames {
+				return 0
+			}
+
+			return float64(audio.Samples[frame*audio.Channels+channel])
+		}
+
+		for frame := range frames {
+			peak = max(peak, math.Abs(sample(frame)))
+
+			for phase := 1; phase < truePeakOversample; phase++ {
+				var value float64
+				for tap := -truePeakTaps + 1; tap <= truePeakTaps; tap++ {
+					value += sample(frame+tap) * kernel[phase][tap+truePeakTaps-1]
+				}
+
+				peak = max(peak, math.Abs(value))
+			}
+		}
+	}
+
+	if peak == 0 {
+		return math.Inf(-1)
+	}
+
+	return 20 * math.Log10(peak)
+}
+
+// interpolationKernel returns Hann-windowed sinc taps for each fractional
+// phase between two samples; kernel[phase][i] weighs the sample at offset
+// i-truePeakTaps+1.
+func interpolationKernel() [truePeakOversample][2 * truePeakTaps]float64 {
+	var kernel [truePeakOversample][2 * truePeakTaps]float64
+
+	for phase := 1; phase < truePeakOversample; phase++ {
+		fraction := float64(phase) / truePeakOversample
+
+		for i := range 2 * truePeakTaps {
+			distance := float64(i-truePeakTaps+1) - fraction
+			window := 0.5 + 0.5*math.Cos(math.Pi*distance/truePeakTaps)
+			kernel[phase][i] = sinc(distance) * window
+		}
+	}
+
+	return kernel
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// Normalize scales the audio to targetLUFS. When that would push the true peak
+// above truePeakDB, the gain is lowered until the peak sits at the ceiling, so
+// quiet-but-peaky audio ends up below the target rather than clipped. It
+// returns the normalized audio and the gain applied in dB. Silence is returned
+// unchanged.
+func Normalize(audio wav.Audio, targetLUFS, truePeakDB float64) (wav.Audio, float64) {
+	loudness := Loudness(audio)
+	if math.IsInf(loudness, -1) {
+		return audio, 0
+	}
+
+	gainDB := targetLUFS - loudness
+
+	peak := TruePeak(audio) + gainDB
+	if peak > truePeakDB {
+		gainDB -= peak - truePeakDB
+	}
+
+	return Gain(audio, gainDB), gainDB
+}
+
+// Gain returns a copy of the audio scaled by gainDB.
+func Gain(audio wav.Audio, gainDB float64) wav.Audio {
+	factor := math.Pow(10, gainDB/20)
+
+	samples := make([]float32, len(audio.Samples))
+	for i, sample := range audio.Samples {
+		samples[i] = float32(float64(sample) * factor)
+	}
+
+	audio.Samples = samples
+
+	return audio
+}
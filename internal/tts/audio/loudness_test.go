@@ -0,0 +1,87 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+)
+
+// sine returns seconds of a sine wave at frequency with the given peak
+// amplitude and phase, copied to every channel.
+func sine(sampleRate, channels int, frequency, amplitude, phase, seconds float64) wav.Audio {
+	frames := int(seconds * float64(sampleRate))
+	samples := make([]float32, frames*channels)
+
+	for frame := range frames {
+		value := amplitude * math.Sin(2*math.Pi*frequency*float64(frame)/float64(sampleRate)+phase)
+		for channel := range channels {
+			samples[frame*channels+channel] = float32(value)
+		}
+	}
+
+	return wav.Audio{SampleRate: sampleRate, Channels: channels, Samples: samples}
+}
+
+func TestLoudness_ReferenceTone(t *testing.T) {
+	t.Parallel()
+
+	// BS.1770: a 997 Hz full-scale sine in one channel reads -3.01 LUFS.
+	for _, sampleRate := range []int{48000, 44100, 24000} {
+		assert.InDelta(t, -3.01, audio.Loudness(sine(sampleRate, 1, 997, 1, 0, 3)), 0.05, "%d Hz", sampleRate)
+	}
+
+	// 20 dB quieter, and in both channels of a stereo file, which adds 3 dB.
+	assert.InDelta(t, -20.0, audio.Loudness(sine(48000, 2, 997, 0.1, 0, 3)), 0.05)
+}
+
+func TestLoudness_Gating(t *testing.T) {
+	t.Parallel()
+
+	tone := sine(48000, 1, 997, 0.1, 0, 2)
+	withSilence := tone
+	withSilence.Samples = append(append([]float32{}, tone.Samples...), make([]float32, 48000*4)...)
+
+	// Ungated, four seconds of silence would lower the reading by 4.8 dB; only
+	// the blocks straddling the end of the tone still count.
+	assert.InDelta(t, audio.Loudness(tone), audio.Loudness(withSilence), 0.5, "silence is gated out")
+	assert.True(t, math.IsInf(audio.Loudness(wav.Audio{SampleRate: 48000, Channels: 1, Samples: make([]float32, 48000)}), -1))
+}
+
+func TestTruePeak_InterSamplePeak(t *testing.T) {
+	t.Parallel()
+
+	// A quarter-rate sine at 45° only hits ±0.707 on the samples, but
+	// reconstructs to a full-scale wave.
+	tone := sine(48000, 1, 12000, 1, math.Pi/4, 1)
+
+	var samplePeak float64
+	for _, sample := range tone.Samples {
+		samplePeak = max(samplePeak, math.Abs(float64(sample)))
+	}
+
+	assert.InDelta(t, -3.01, 20*math.Log10(samplePeak), 0.01)
+	assert.InDelta(t, 0.0, audio.TruePeak(tone), 0.2)
+}
+
+func TestNormalize(t *testing.T) {
+	t.Parallel()
+
+	quiet := sine(24000, 1, 440, 0.01, 0, 2)
+
+	normalized, gainDB := audio.Normalize(quiet, -16, -1)
+	assert.InDelta(t, -16.0, audio.Loudness(normalized), 0.05)
+	assert.Greater(t, gainDB, 20.0)
+
+	// Reaching -2 LUFS would need a peak above 0 dBTP, so the ceiling wins.
+	limited, _ := audio.Normalize(quiet, -2, -1)
+	assert.InDelta(t, -1.0, audio.TruePeak(limited), 0.05)
+	assert.Less(t, audio.Loudness(limited), -2.0)
+
+	silence := wav.Audio{SampleRate: 24000, Channels: 1, Samples: make([]float32, 100)}
+	unchanged, gainDB := audio.Normalize(silence, -16, -1)
+	assert.Equal(t, silence, unchanged)
+	assert.Zero(t, gainDB)
+}
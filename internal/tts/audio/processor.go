@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Options selects the post-processing applied to every chunk.
+type Options struct {
+	// SampleRate is the output sample rate; zero keeps the backend's rate.
+	SampleRate int
+	// Channels is the output channel count; zero keeps the backend's layout.
+	Channels int
+	// TargetLUFS is the integrated loudness each chunk is normalized to;
+	// zero leaves loudness unchanged.
+	TargetLUFS float64
+	// TruePeakDB caps the true peak after normalization; zero uses DefaultTruePeakDB.
+	TruePeakDB float64
+}
+
+// Processor wraps a core.TTSProcessor and post-processes the WAV audio it
+// returns: the format and loudness options apply to every job, and each job's
+// Rate and Pitch are applied to its own audio. Post-processed audio is 16-bit
+// PCM; when there is nothing to do, the backend's audio is returned untouched.
+type Processor struct {
+	inner   core.TTSProcessor
+	options Options
+}
+
+// NewProcessor creates a post-processing wrapper around inner.
+func NewProcessor(inner core.TTSProcessor, options Options) *Processor {
+	if options.TruePeakDB == 0 {
+		options.TruePeakDB = DefaultTruePeakDB
+	}
+
+	return &Processor{
+		inner:   inner,
+		options: options,
+	}
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig checks the job's rate and pitch, then delegates to the
+// wrapped processor when it validates jobs.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	err := ValidateRateAndPitch(cfg.Rate, cfg.Pitch)
+	if err != nil {
+		return err
+	}
+
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateConfig(cfg)
+}
+
+// Process synthesizes the text with the wrapped processor and post-processes
+// the result: remixing, rate and pitch, resampling, then loudness
+// normalization, so the true peak is measured on the audio that is delivered.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	data, err := p.inner.Process(ctx, text, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("synthesizing audio for post-processing: %w", err)
+	}
+
+	if !p.changesAudio(cfg) {
+		return data, nil
+	}
+
+	decoded, err := wav.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode synthesized audio: %w", err)
+	}
+
+	decoded, err = Remix(decoded, p.options.Channels)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded = stretch(decoded, cfg.Rate, cfg.Pitch)
+	decoded = Resample(decoded, p.options.SampleRate)
+
+	if p.options.TargetLUFS != 0 {
+		decoded, _ = Normalize(decoded, p.options.TargetLUFS, p.options.TruePeakDB)
+	}
+
+	return wav.Encode(decoded), nil
+}
+
+// changesAudio reports whether the options or the job ask for any post-processing.
+func (p *Processor) changesAudio(cfg core.TTSConfig) bool {
+	return p.options.SampleRate != 0 || p.options.Channels != 0 || p.options.TargetLUFS != 0 ||
+		(cfg.Rate != 0 && cfg.Rate != 1) || cfg.Pitch != 0
+}
@@ -0,0 +1,119 @@
+package audio_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errRejected = errors.New("rejected")
+
+// jobConfig returns a job configuration with the given voice, rate and pitch.
+func jobConfig(voice string, rate, pitch float64) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              rate,
+		Pitch:             pitch,
+		Style:             "",
+	}
+}
+
+// wavProcessor is a TTSProcessor that returns fixed audio.
+type wavProcessor struct {
+	audio []byte
+}
+
+func (p *wavProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *wavProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	if cfg.Voice == "unknown" {
+		return errRejected
+	}
+
+	return nil
+}
+
+func (p *wavProcessor) Process(_ context.Context, _ []byte, _ core.TTSConfig) ([]byte, error) {
+	return p.audio, nil
+}
+
+func TestProcessor_Normalizes(t *testing.T) {
+	t.Parallel()
+
+	inner := &wavProcessor{audio: wav.Encode(sine(24000, 1, 440, 0.02, 0, 1))}
+	processor := audio.NewProcessor(inner, audio.Options{SampleRate: 0, Channels: 0, TargetLUFS: -16, TruePeakDB: 0})
+
+	data, err := processor.Process(context.Background(), []byte("text"), core.TTSConfig{})
+	require.NoError(t, err)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.InDelta(t, -16.0, audio.Loudness(decoded), 0.1)
+
+	require.ErrorIs(t, processor.ValidateConfig(jobConfig("unknown", 0, 0)), errRejected)
+}
+
+func TestProcessor_RejectsNonWAV(t *testing.T) {
+	t.Parallel()
+
+	processor := audio.NewProcessor(&wavProcessor{audio: []byte("ID3 mp3 data")}, audio.Options{SampleRate: 0, Channels: 0, TargetLUFS: -16, TruePeakDB: 0})
+
+	_, err := processor.Process(context.Background(), []byte("text"), core.TTSConfig{})
+	require.ErrorIs(t, err, wav.ErrInvalidWAV)
+}
+
+func TestProcessor_ConvertsFormat(t *testing.T) {
+	t.Parallel()
+
+	inner := &wavProcessor{audio: wav.Encode(sine(24000, 1, 440, 0.5, 0, 0.5))}
+	processor := audio.NewProcessor(inner, audio.Options{SampleRate: 44100, Channels: 2, TargetLUFS: 0, TruePeakDB: 0})
+
+	data, err := processor.Process(context.Background(), []byte("text"), core.TTSConfig{})
+	require.NoError(t, err)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, 44100, decoded.SampleRate)
+	assert.Equal(t, 2, decoded.Channels)
+	assert.Equal(t, 22050, decoded.Frames())
+}
+
+func TestProcessor_RateAndPitch(t *testing.T) {
+	t.Parallel()
+
+	original := wav.Encode(sine(24000, 1, 220, 0.5, 0, 1))
+	processor := audio.NewProcessor(&wavProcessor{audio: original}, audio.Options{
+		SampleRate: 0, Channels: 0, TargetLUFS: 0, TruePeakDB: 0,
+	})
+
+	data, err := processor.Process(context.Background(), []byte("text"), jobConfig("default", 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, original, data, "neutral jobs are passed through")
+
+	data, err = processor.Process(context.Background(), []byte("text"), jobConfig("default", 1.25, 2))
+	require.NoError(t, err)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.InDelta(t, 24000/1.25, decoded.Frames(), 1)
+
+	require.ErrorIs(t, processor.ValidateConfig(jobConfig("default", 4, 0)), audio.ErrRateOutOfRange)
+}
@@ -0,0 +1,103 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// ErrUnsupportedRemix indicates a channel conversion other than to or from mono.
+var ErrUnsupportedRemix = errors.New("unsupported channel remix")
+
+// resampleZeroCrossings is how many sinc lobes the resampler uses on each side
+// of an output sample. More lobes give a steeper anti-aliasing filter.
+const resampleZeroCrossings = 16
+
+// Resample converts the audio to sampleRate with a Blackman-windowed sinc
+// filter. When downsampling, the filter cutoff moves to the new Nyquist
+// frequency so that content above it is removed instead of aliased.
+func Resample(audio wav.Audio, sampleRate int) wav.Audio {
+	if sampleRate <= 0 || sampleRate == audio.SampleRate || audio.SampleRate <= 0 {
+		return audio
+	}
+
+	resampled := resampleRatio(audio, float64(sampleRate)/float64(audio.SampleRate))
+	resampled.SampleRate = sampleRate
+
+	return resampled
+}
+
+// resampleRatio scales the number of frames by ratio, keeping the sample rate.
+func resampleRatio(audio wav.Audio, ratio float64) wav.Audio {
+	cutoff := min(1, ratio)
+	// The kernel widens as the cutoff drops, keeping the same number of lobes.
+	halfWidth := resampleZeroCrossings / cutoff
+
+	frames := audio.Frames()
+	outFrames := int(math.Round(float64(frames) * ratio))
+	samples := make([]float32, outFrames*audio.Channels)
+
+	for out := range outFrames {
+		position := float64(out) / ratio
+		first := max(int(math.Ceil(position-halfWidth)), 0)
+		last := min(int(math.Floor(position+halfWidth)), frames-1)
+
+		for channel := range audio.Channels {
+			var value float64
+
+			for in := first; in <= last; in++ {
+				distance := position - float64(in)
+				weight := cutoff * sinc(cutoff*distance) * blackman(distance/halfWidth)
+				value += float64(audio.Samples[in*audio.Channels+channel]) * weight
+			}
+
+			samples[out*audio.Channels+channel] = float32(value)
+		}
+	}
+
+	return wav.Audio{SampleRate: audio.SampleRate, Channels: audio.Channels, Samples: samples}
+}
+
+// blackman evaluates a Blackman window centred on zero, reaching zero at ±1.
+func blackman(x float64) float64 {
+	if math.Abs(x) >= 1 {
+		return 0
+	}
+
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
+
+// Remix converts the audio to channels. Mono is copied to every output channel,
+// and any layout is downmixed to mono by averaging its channels.
+func Remix(audio wav.Audio, channels int) (wav.Audio, error) {
+	if channels <= 0 || channels == audio.Channels {
+		return audio, nil
+	}
+
+	frames := audio.Frames()
+	samples := make([]float32, frames*channels)
+
+	switch {
+	case audio.Channels == 1:
+		for frame, sample := range audio.Samples {
+			for channel := range channels {
+				samples[frame*channels+channel] = sample
+			}
+		}
+	case channels == 1:
+		for frame := range frames {
+			var sum float32
+			for _, sample := range audio.Samples[frame*audio.Channels : (frame+1)*audio.Channels] {
+				sum += sample
+			}
+
+			samples[frame] = sum / float32(audio.Channels)
+		}
+	default:
+		return wav.Audio{}, fmt.Errorf("%w: %d to %d channels", ErrUnsupportedRemix, audio.Channels, channels)
+	}
+
+	return wav.Audio{SampleRate: audio.SampleRate, Channels: channels, Samples: samples}, nil
+}
@@ -0,0 +1,69 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rms returns the root mean square of the samples, skipping edge frames where
+// the filter runs off the signal.
+func rms(samples []float32, edge int) float64 {
+	var sum float64
+	for _, sample := range samples[edge : len(samples)-edge] {
+		sum += float64(sample) * float64(sample)
+	}
+
+	return math.Sqrt(sum / float64(len(samples)-2*edge))
+}
+
+func TestResample_Upsample(t *testing.T) {
+	t.Parallel()
+
+	resampled := audio.Resample(sine(24000, 1, 1000, 0.5, 0, 1), 48000)
+	want := sine(48000, 1, 1000, 0.5, 0, 1)
+
+	require.Equal(t, 48000, resampled.SampleRate)
+	require.Len(t, resampled.Samples, len(want.Samples))
+
+	// Away from the edges the result is the same sine sampled at the new rate.
+	for i := 1000; i < len(want.Samples)-1000; i++ {
+		assert.InDelta(t, want.Samples[i], resampled.Samples[i], 1e-3, "sample %d", i)
+	}
+}
+
+func TestResample_DownsampleRemovesAliases(t *testing.T) {
+	t.Parallel()
+
+	// 7 kHz is below the 8 kHz Nyquist frequency of 16 kHz audio; 10 kHz is not.
+	kept := audio.Resample(sine(48000, 1, 7000, 0.5, 0, 1), 16000)
+	removed := audio.Resample(sine(48000, 1, 10000, 0.5, 0, 1), 16000)
+
+	assert.Len(t, kept.Samples, 16000)
+	assert.InDelta(t, 0.5/math.Sqrt2, rms(kept.Samples, 500), 0.01)
+	assert.Less(t, 20*math.Log10(rms(removed.Samples, 500)/(0.5/math.Sqrt2)), -40.0,
+		"a 10 kHz tone would alias to 6 kHz")
+}
+
+func TestRemix(t *testing.T) {
+	t.Parallel()
+
+	mono := wav.Audio{SampleRate: 8000, Channels: 1, Samples: []float32{0.5, -0.25}}
+
+	stereo, err := audio.Remix(mono, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.5, 0.5, -0.25, -0.25}, stereo.Samples)
+
+	stereo.Samples = []float32{1, 0, -0.5, 0.5}
+
+	downmixed, err := audio.Remix(stereo, 1)
+	require.NoError(t, err)
+	assert.Equal(t, wav.Audio{SampleRate: 8000, Channels: 1, Samples: []float32{0.5, 0}}, downmixed)
+
+	_, err = audio.Remix(stereo, 6)
+	require.ErrorIs(t, err, audio.ErrUnsupportedRemix)
+}
@@ -0,0 +1,165 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Rate and pitch errors.
+var (
+	ErrRateOutOfRange  = errors.New("rate must be between 0.5 and 2.0")
+	ErrPitchOutOfRange = errors.New("pitch must be between -12 and 12 semitones")
+)
+
+// Rate and pitch limits. Beyond them WSOLA artifacts become obvious.
+const (
+	MinRate      = 0.5
+	MaxRate      = 2.0
+	MaxSemitones = 12.0
+)
+
+// WSOLA tuning, in seconds.
+const (
+	wsolaFrameSeconds     = 0.03
+	wsolaToleranceSeconds = 0.005
+)
+
+// ValidateRateAndPitch checks a job's rate factor and pitch shift in semitones.
+// A zero rate means unchanged.
+func ValidateRateAndPitch(rate, semitones float64) error {
+	if rate != 0 && (rate < MinRate || rate > MaxRate) {
+		return fmt.Errorf("%w: got %g", ErrRateOutOfRange, rate)
+	}
+
+	if math.Abs(semitones) > MaxSemitones {
+		return fmt.Errorf("%w: got %g", ErrPitchOutOfRange, semitones)
+	}
+
+	return nil
+}
+
+// TimeStretch changes the speaking rate without changing the pitch: a rate of
+// 1.25 makes speech 25% faster.
+func TimeStretch(audio wav.Audio, rate float64) wav.Audio {
+	return stretch(audio, rate, 0)
+}
+
+// PitchShift raises or lowers the pitch by semitones without changing the duration.
+func PitchShift(audio wav.Audio, semitones float64) wav.Audio {
+	return stretch(audio, 1, semitones)
+}
+
+// stretch applies a rate change and a pitch shift in a single WSOLA pass: the
+// audio is time-stretched by rate/factor, then resampled by 1/factor, which
+// scales the pitch by factor and brings the duration back to 1/rate.
+func stretch(audio wav.Audio, rate, semitones float64) wav.Audio {
+	if rate <= 0 {
+		rate = 1
+	}
+
+	factor := math.Pow(2, semitones/12)
+	if rate == 1 && factor == 1 || audio.Frames() == 0 {
+		return audio
+	}
+
+	stretched := wsola(audio, rate/factor)
+	if factor == 1 {
+		return stretched
+	}
+
+	return resampleRatio(stretched, 1/factor)
+}
+
+// wsola time-stretches the audio by waveform-similarity overlap-add. Frames are
+// written every half frame; each is read from around rate times that position,
+// shifted within a small tolerance to the offset whose waveform best continues
+// the previous frame, so periods line up and no phasing is heard.
+func wsola(audio wav.Audio, rate float64) wav.Audio {
+	frames := audio.Frames()
+	frameSize := max(int(wsolaFrameSeconds*float64(audio.SampleRate)), 2)
+	synthesisHop := frameSize / 2
+	tolerance := int(wsolaToleranceSeconds * float64(audio.SampleRate))
+
+	window := make([]float64, frameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSize))
+	}
+
+	// The channels are summed to pick one offset for all of them.
+	mix := make([]float64, frames)
+	for i, sample := range audio.Samples {
+		mix[i/audio.Channels] += float64(sample)
+	}
+
+	outFrames := int(math.Round(float64(frames) / rate))
+	output := make([]float64, (outFrames+frameSize)*audio.Channels)
+	weights := make([]float64, outFrames+frameSize)
+
+	previous := 0
+
+	for out := 0; out < outFrames; out += synthesisHop {
+		nominal := int(math.Round(float64(out) * rate))
+
+		start := nominal
+		if out > 0 {
+			start = bestOffset(mix, previous+synthesisHop, nominal, tolerance, synthesisHop)
+		}
+
+		for i, weight := range window {
+			in := start + i
+			if in >= frames {
+				break
+			}
+
+			for channel := range audio.Channels {
+				output[(out+i)*audio.Channels+channel] += float64(audio.Samples[in*audio.Channels+channel]) * weight
+			}
+
+			weights[out+i] += weight
+		}
+
+		previous = start
+	}
+
+	samples := make([]float32, outFrames*audio.Channels)
+	for i := range samples {
+		// Dividing by the summed windows undoes the fade-in of the first frame.
+		weight := weights[i/audio.Channels]
+		if weight > 1e-3 {
+			samples[i] = float32(output[i] / weight)
+		}
+	}
+
+	return wav.Audio{SampleRate: audio.SampleRate, Channels: audio.Channels, Samples: samples}
+}
+
+// bestOffset returns the read position within tolerance of nominal whose next
+// length samples correlate best with the natural continuation at target.
+func bestOffset(mix []float64, target, nominal, tolerance, length int) int {
+	best, bestScore := nominal, math.Inf(-1)
+
+	for candidate := nominal - tolerance; candidate <= nominal+tolerance; candidate++ {
+		if candidate < 0 {
+			continue
+		}
+
+		var score float64
+
+		for i := range length {
+			if candidate+i >= len(mix) || target+i >= len(mix) {
+				break
+			}
+
+			score += mix[candidate+i] * mix[target+i]
+		}
+
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+
+	return best
+}
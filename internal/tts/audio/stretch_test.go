@@ -0,0 +1,68 @@
+package audio_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// frequency estimates the frequency of a tone from its rising zero crossings,
+// skipping a tenth of the audio at either end.
+func frequency(samples []float32, sampleRate int) float64 {
+	edge := len(samples) / 10
+
+	var first, last, crossings int
+
+	for i := edge + 1; i < len(samples)-edge; i++ {
+		if samples[i-1] < 0 && samples[i] >= 0 {
+			if crossings == 0 {
+				first = i
+			}
+
+			last = i
+			crossings++
+		}
+	}
+
+	return float64(crossings-1) * float64(sampleRate) / float64(last-first)
+}
+
+func TestTimeStretch_KeepsPitch(t *testing.T) {
+	t.Parallel()
+
+	for _, rate := range []float64{0.8, 1.25, 1.5} {
+		stretched := audio.TimeStretch(sine(24000, 2, 220, 0.5, 0, 2), rate)
+
+		assert.Equal(t, 2, stretched.Channels)
+		assert.InDelta(t, 48000/rate, float64(stretched.Frames()), 1, "rate %g", rate)
+		left, err := audio.Remix(stretched, 1)
+		require.NoError(t, err)
+		assert.InDelta(t, 220, frequency(left.Samples, 24000), 2, "rate %g", rate)
+		assert.InDelta(t, 0.5/math.Sqrt2, rms(stretched.Samples, 2400), 0.03, "overlapping frames add up in phase")
+	}
+}
+
+func TestPitchShift_KeepsDuration(t *testing.T) {
+	t.Parallel()
+
+	tone := sine(24000, 1, 220, 0.5, 0, 2)
+
+	up := audio.PitchShift(tone, 12)
+	assert.InDelta(t, tone.Frames(), up.Frames(), 1)
+	assert.InDelta(t, 440, frequency(up.Samples, 24000), 3)
+
+	down := audio.PitchShift(tone, -5)
+	assert.InDelta(t, 220*math.Pow(2, -5.0/12), frequency(down.Samples, 24000), 2)
+}
+
+func TestValidateRateAndPitch(t *testing.T) {
+	t.Parallel()
+
+	require.NoError(t, audio.ValidateRateAndPitch(0, 0))
+	require.NoError(t, audio.ValidateRateAndPitch(1.25, -3))
+	require.ErrorIs(t, audio.ValidateRateAndPitch(3, 0), audio.ErrRateOutOfRange)
+	require.ErrorIs(t, audio.ValidateRateAndPitch(1, 13), audio.ErrPitchOutOfRange)
+}
@@ -0,0 +1,120 @@
+package tts
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned while a circuit breaker short-circuits calls.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops calls to a backend after consecutive failures. Once the
+// cool-down has passed, the breaker is half-open: exactly one call is let
+// through as a trial while the others keep failing fast. Success closes the
+// breaker, failure opens it for another cool-down.
+//
+// Every allowed call must be finished with Success, Failure or Ignore.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+	clock     Clock
+}
+
+// NewCircuitBreaker creates a CircuitBreaker that opens after threshold
+// consecutive failures. A threshold below 1 disables the breaker.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		mu:        sync.Mutex{},
+		threshold: threshold,
+		cooldown:  cooldown,
+		failures:  0,
+		openUntil: time.Time{},
+		trial:     false,
+		clock:     SystemClock{},
+	}
+}
+
+// SetClock replaces the clock that times the cool-down. It must be called
+// before the breaker is used.
+func (b *CircuitBreaker) SetClock(clock Clock) {
+	b.clock = clock
+}
+
+// Allow returns ErrCircuitOpen while the breaker is open, or while it is
+// half-open and another call is already the trial. A nil breaker allows every call.
+func (b *CircuitBreaker) Allow() error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.clock.Now()
+	if now.Before(b.openUntil) {
+		return fmt.Errorf("%w after %d consecutive failures, retrying in %s",
+			ErrCircuitOpen, b.failures, b.openUntil.Sub(now).Round(time.Second))
+	}
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return nil
+	}
+
+	if b.trial {
+		return fmt.Errorf("%w after %d consecutive failures, trial call in progress", ErrCircuitOpen, b.failures)
+	}
+
+	b.trial = true
+
+	return nil
+}
+
+// Success records a successful call and closes the breaker.
+func (b *CircuitBreaker) Success() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.trial = false
+}
+
+// Failure records a failed call and opens the breaker once the threshold is reached.
+func (b *CircuitBreaker) Failure() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = b.clock.Now().Add(b.cooldown)
+	}
+}
+
+// Ignore finishes a call whose outcome says nothing about the backend, such as
+// one cancelled by the caller. A half-open trial is released for the next call.
+func (b *CircuitBreaker) Ignore() {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
@@ -0,0 +1,62 @@
+package tts_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	breaker := tts.NewCircuitBreaker(2, time.Minute)
+	breaker.SetClock(clock)
+
+	breaker.Failure()
+	require.NoError(t, breaker.Allow())
+
+	breaker.Failure()
+	require.ErrorIs(t, breaker.Allow(), tts.ErrCircuitOpen)
+
+	clock.Advance(59 * time.Second)
+	require.ErrorIs(t, breaker.Allow(), tts.ErrCircuitOpen, "the cool-down has not passed")
+
+	clock.Advance(time.Second)
+	require.NoError(t, breaker.Allow(), "the breaker should allow a trial after the cool-down")
+
+	breaker.Failure()
+	require.ErrorIs(t, breaker.Allow(), tts.ErrCircuitOpen, "a failed trial should reopen the breaker")
+
+	breaker.Success()
+	require.NoError(t, breaker.Allow())
+
+	var disabled *tts.CircuitBreaker
+
+	disabled.Failure()
+	require.NoError(t, disabled.Allow())
+}
+
+func TestCircuitBreaker_HalfOpenSingleTrial(t *testing.T) {
+	t.Parallel()
+
+	clock := newFakeClock()
+	breaker := tts.NewCircuitBreaker(1, time.Minute)
+	breaker.SetClock(clock)
+
+	breaker.Failure()
+	clock.Advance(time.Minute)
+	require.NoError(t, breaker.Allow())
+
+	require.ErrorIs(t, breaker.Allow(), tts.ErrCircuitOpen, "only one trial may run while half-open")
+
+	breaker.Ignore()
+	require.NoError(t, breaker.Allow(), "an ignored trial should let the next call try")
+	require.ErrorIs(t, breaker.Allow(), tts.ErrCircuitOpen)
+
+	breaker.Success()
+	require.NoError(t, breaker.Allow())
+	require.NoError(t, breaker.Allow(), "a closed breaker allows concurrent calls")
+}
@@ -6,15 +6,19 @@
 package tts
 
 import (
-	"bytes"
 	"context"
-	"encoding/json"
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
-	"io"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/time/rate"
 )
 
 // API endpoints and paths.
@@ -35,16 +39,35 @@ const (
 const (
 	defaultTemperature = 0.75
 	defaultLanguage    = "en"
+
+	// The circuit breaker opens after this many consecutive failed requests
+	// and short-circuits requests for the cool-down period.
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+
+	// Connection reuse defaults. Go keeps only two idle connections per host,
+	// so workers sending requests in parallel would reconnect constantly.
+	DefaultMaxIdleConnsPerHost = 32
+	DefaultIdleConnTimeout     = 90 * time.Second
+	DefaultKeepAlive           = 30 * time.Second
+	defaultDialTimeout         = 30 * time.Second
 )
 
 // Static errors.
 var (
 	ErrTextCannotBeEmpty     = errors.New("text cannot be empty")
+	ErrTextNotUTF8           = errors.New("text is not valid UTF-8")
+	ErrTextTooLong           = errors.New("text is too long")
 	ErrUnexpectedContentType = errors.New("unexpected content type")
 	ErrReceivedEmptyAudio    = errors.New("received empty audio data")
 	ErrHealthCheckFailed     = errors.New("health check failed")
 	ErrServiceError          = errors.New("TTS service error")
 	ErrServiceNonOKStatus    = errors.New("TTS service returned non-OK status")
+	ErrIncompleteTLS         = errors.New("TLS client certificate needs both cert_file and key_file")
+	ErrInvalidCA             = errors.New("CA file has no PEM certificates")
+	// ErrRequestTimeout indicates a request without a complete response
+	// within the client's timeout. It is also a context.DeadlineExceeded.
+	ErrRequestTimeout = errors.New("request timed out")
 )
 
 // Helper functions for dynamic error messages.
@@ -79,7 +102,16 @@ func newServiceNonOKStatusError(status, body string) error {
 // speech generation and health monitoring.
 type HTTPClient struct {
 	httpClient *http.Client
+	breaker    *CircuitBreaker
+	limiter    *rate.Limiter
+	inFlight   chan struct{}
+	adaptive   *AdaptiveLimiter
+	clock      Clock
+	api        API
 	baseURL    string
+	timeout    time.Duration
+	// maxTextChars rejects longer requests before they are sent; zero disables it.
+	maxTextChars int
 }
 
 // Request defines the JSON payload structure for TTS generation requests.
@@ -89,6 +121,10 @@ type Request struct {
 	// Must be non-empty and within reasonable length limits.
 	Text string `json:"text"`
 
+	// Voice optionally names one of the service's voices, for services that
+	// select voices by name rather than by speaker reference.
+	Voice string `json:"voice,omitempty"`
+
 	// SpeakerRefPath optionally specifies a server-side path to a speaker
 	// reference file for voice cloning. If empty, default speaker is used.
 	SpeakerRefPath string `json:"speakerRefPath,omitempty"`
@@ -97,9 +133,22 @@ type Request struct {
 	// Defaults to "en" if not specified.
 	Language string `json:"language"`
 
+	// Model optionally selects a registered model by name.
+	// If empty, the service's default model is used.
+	Model string `json:"model,omitempty"`
+
 	// Temperature controls randomness in speech generation.
 	// Valid range: 0.0 (deterministic) to 2.0 (highly random).
 	Temperature float64 `json:"temperature"`
+
+	// Seed optionally fixes the random seed, so identical requests return
+	// identical audio when the service's model supports it. Zero leaves the
+	// choice to the service.
+	Seed int `json:"seed,omitempty"`
+
+	// Style optionally names a speaking style or emotion, e.g. "calm".
+	// If empty, the voice's default delivery is used.
+	Style string `json:"style,omitempty"`
 }
 
 // ErrorResponse represents a structured error response from the TTS service.
@@ -114,41 +163,370 @@ type ErrorResponse struct {
 
 // NewHTTPClient creates and configures an HTTP client for the TTS service.
 // The baseURL should include the protocol and port (e.g., "http://localhost:8000").
-// The timeout applies to all HTTP requests made by this client.
+// The timeout applies to all HTTP requests made by this client, up to the
+// end of the response body; zero disables it.
+// A circuit breaker stops requests after repeated failures; see SetCircuitBreaker.
 func NewHTTPClient(baseURL string, timeout time.Duration) *HTTPClient {
 	return &HTTPClient{
-		baseURL: baseURL,
+		baseURL:  baseURL,
+		timeout:  timeout,
+		clock:    SystemClock{},
+		api:      DefaultAPI(),
+		breaker:  NewCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+		limiter:  nil,
+		inFlight: nil,
+		adaptive: nil,
+		// The service enforces its own limit unless one is set here.
+		maxTextChars: 0,
+		// The timeout is kept on the client's clock instead, see withTimeout.
 		httpClient: &http.Client{
-			Transport:     nil,
+			Transport:     newTransport(TransportOptions{}),
 			CheckRedirect: nil,
 			Jar:           nil,
-			Timeout:       timeout,
+			Timeout:       0,
 		},
 	}
 }
 
+// TransportOptions tune the connections to the service. Zero values use the
+// package defaults.
+type TransportOptions struct {
+	// MaxIdleConnsPerHost is the number of idle connections kept open for
+	// reuse; set it to at least the number of requests in flight.
+	MaxIdleConnsPerHost int
+	// IdleConnTimeout closes idle connections after this long.
+	IdleConnTimeout time.Duration
+	// KeepAlive is the interval of TCP keep-alive probes.
+	KeepAlive time.Duration
+	// HTTP2 sends every request over HTTP/2: negotiated with TLS for https
+	// URLs, and with prior knowledge (h2c) for http URLs. The service must
+	// support it. Requests are then multiplexed over one connection.
+	HTTP2 bool
+	// TLS verifies the service and authenticates the client on https URLs.
+	TLS TLSOptions
+}
+
+// TLSOptions secure the connection to an https service. Empty values keep the
+// system roots and send no client certificate.
+type TLSOptions struct {
+	// CAFile is a PEM bundle of the CAs that sign the service's certificate.
+	CAFile string
+	// CertFile and KeyFile are the PEM client certificate and key for mutual TLS.
+	CertFile string
+	KeyFile  string
+	// ServerName overrides the host name checked against the certificate.
+	ServerName string
+}
+
+// SetTransport replaces the client's connection settings. It must be called
+// before the client is used.
+func (c *HTTPClient) SetTransport(options TransportOptions) error {
+	transport := newTransport(options)
+
+	if options.TLS != (TLSOptions{}) {
+		tlsConfig, err := newTLSConfig(options.TLS)
+		if err != nil {
+			return err
+		}
+
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	c.httpClient.Transport = transport
+
+	return nil
+}
+
+// SetRoundTripper sends the client's requests through roundTripper instead of
+// its own connections, e.g. to record them or to answer them in tests. It
+// replaces the settings of SetTransport and must be called before the client
+// is used.
+func (c *HTTPClient) SetRoundTripper(roundTripper http.RoundTripper) {
+	c.httpClient.Transport = roundTripper
+}
+
+// SetClock replaces the clock that times the client's requests, latencies and
+// rate limit. The circuit breaker has its own; see CircuitBreaker.SetClock.
+// It must be called before the client is used.
+func (c *HTTPClient) SetClock(clock Clock) {
+	c.clock = clock
+}
+
+// SetAPI replaces the paths, request fields, headers and response formats the
+// client uses, so it can talk to TTS servers other than the book-expert one;
+// see Profile. It must be called before the client is used.
+func (c *HTTPClient) SetAPI(api API) error {
+	err := api.validate()
+	if err != nil {
+		return err
+	}
+
+	c.api = api
+
+	return nil
+}
+
+// newTLSConfig loads the CA bundle and client certificate of options.
+func newTLSConfig(options TLSOptions) (*tls.Config, error) {
+	if (options.CertFile == "") != (options.KeyFile == "") {
+		return nil, ErrIncompleteTLS
+	}
+
+	tlsConfig := new(tls.Config)
+	tlsConfig.MinVersion = tls.VersionTLS12
+	tlsConfig.ServerName = options.ServerName
+
+	if options.CAFile != "" {
+		pem, err := os.ReadFile(options.CAFile) // #nosec G304 -- the CA file is chosen by the operator
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidCA, options.CAFile)
+		}
+	}
+
+	if options.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(options.CertFile, options.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+// newTransport creates a transport with the options, filling in the defaults.
+func newTransport(options TransportOptions) *http.Transport {
+	if options.MaxIdleConnsPerHost <= 0 {
+		options.MaxIdleConnsPerHost = DefaultMaxIdleConnsPerHost
+	}
+
+	if options.IdleConnTimeout <= 0 {
+		options.IdleConnTimeout = DefaultIdleConnTimeout
+	}
+
+	if options.KeepAlive <= 0 {
+		options.KeepAlive = DefaultKeepAlive
+	}
+
+	// Start from the default transport to keep its proxy and TLS settings.
+	transport := new(http.Transport)
+	if defaultTransport, ok := http.DefaultTransport.(*http.Transport); ok {
+		transport = defaultTransport.Clone()
+	}
+
+	dialer := new(net.Dialer)
+	dialer.Timeout = defaultDialTimeout
+	dialer.KeepAlive = options.KeepAlive
+	transport.DialContext = dialer.DialContext
+	transport.MaxIdleConns = 0
+	transport.MaxIdleConnsPerHost = options.MaxIdleConnsPerHost
+	transport.IdleConnTimeout = options.IdleConnTimeout
+
+	if options.HTTP2 {
+		protocols := new(http.Protocols)
+		protocols.SetHTTP2(true)
+		protocols.SetUnencryptedHTTP2(true)
+		transport.Protocols = protocols
+		transport.ForceAttemptHTTP2 = true
+	}
+
+	return transport
+}
+
+// SetCircuitBreaker replaces the client's circuit breaker. Nil disables it.
+// Transport errors and 5xx responses count as failures; other responses show
+// that the service is up and close the breaker. Requests whose context is
+// cancelled or past its deadline are not counted.
+func (c *HTTPClient) SetCircuitBreaker(breaker *CircuitBreaker) {
+	c.breaker = breaker
+}
+
+// SetRateLimit limits the requests this client sends to the service, independently
+// of how many goroutines call it. requestsPerSecond is a token bucket refilled at
+// that rate with a burst of one second's worth of requests; maxConcurrent caps the
+// requests in flight. Zero disables either limit. It must be called before the
+// client is used.
+func (c *HTTPClient) SetRateLimit(requestsPerSecond float64, maxConcurrent int) {
+	c.limiter = nil
+	if requestsPerSecond > 0 {
+		c.limiter = rate.NewLimiter(rate.Limit(requestsPerSecond), max(1, int(requestsPerSecond)))
+	}
+
+	c.inFlight = nil
+	if maxConcurrent > 0 {
+		c.inFlight = make(chan struct{}, maxConcurrent)
+	}
+}
+
+// SetMaxTextLength rejects requests whose text has more than maxChars
+// characters before they are sent, so an oversized text fails fast instead of
+// occupying the service. Zero disables the limit.
+func (c *HTTPClient) SetMaxTextLength(maxChars int) {
+	c.maxTextChars = maxChars
+}
+
+// SetAdaptiveConcurrency replaces the fixed concurrency cap with an
+// AdaptiveLimiter that tunes the requests in flight between minLimit and
+// maxLimit from the service's latency and errors. It must be called before the
+// client is used.
+func (c *HTTPClient) SetAdaptiveConcurrency(minLimit, maxLimit int) {
+	c.inFlight = nil
+	c.adaptive = NewAdaptiveLimiter(minLimit, maxLimit)
+}
+
+// acquire waits for a concurrency slot and a rate limit token. The returned
+// function releases the slot.
+func (c *HTTPClient) acquire(ctx context.Context) (func(), error) {
+	release := func() {}
+
+	if c.inFlight != nil {
+		select {
+		case c.inFlight <- struct{}{}:
+			release = func() { <-c.inFlight }
+		case <-ctx.Done():
+			return nil, fmt.Errorf("waiting for a request slot: %w", ctx.Err())
+		}
+	}
+
+	if c.limiter != nil {
+		now := c.clock.Now()
+		reservation := c.limiter.ReserveN(now, 1)
+		delay := reservation.DelayFrom(now)
+
+		// Context deadlines are in real time, whatever the client's clock.
+		var err error
+		if deadline, ok := ctx.Deadline(); ok && delay > time.Until(deadline) {
+			err = fmt.Errorf("%w: the rate limit allows the request after its deadline", context.DeadlineExceeded)
+		} else if !c.sleep(ctx, delay) {
+			err = ctx.Err()
+		}
+
+		if err != nil {
+			reservation.CancelAt(c.clock.Now())
+			release()
+
+			return nil, fmt.Errorf("waiting for the rate limiter: %w", err)
+		}
+	}
+
+	return release, nil
+}
+
+// sleep waits for d on the client's clock and reports whether it did, or
+// false when ctx was done first.
+func (c *HTTPClient) sleep(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return true
+	}
+
+	done := make(chan struct{})
+	timer := c.clock.AfterFunc(d, func() { close(done) })
+
+	select {
+	case <-done:
+		return true
+	case <-ctx.Done():
+		timer.Stop()
+
+		return false
+	}
+}
+
+// withTimeout returns ctx limited to the client's timeout on its clock. The
+// returned function must be called once the response has been read.
+func (c *HTTPClient) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	timer := c.clock.AfterFunc(c.timeout, func() {
+		cancel(fmt.Errorf("%w after %s: %w", ErrRequestTimeout, c.timeout, context.DeadlineExceeded))
+	})
+
+	return ctx, func() {
+		timer.Stop()
+		cancel(context.Canceled)
+	}
+}
+
+// timeoutError returns the timeout of a request that withTimeout cancelled,
+// or else err.
+func (c *HTTPClient) timeoutError(ctx context.Context, err error) error {
+	cause := context.Cause(ctx)
+	if errors.Is(cause, ErrRequestTimeout) {
+		return fmt.Errorf("TTS service at %s: %w", c.baseURL, cause)
+	}
+
+	return err
+}
+
 // GenerateSpeech sends a TTS generation request and returns the raw audio data.
 // This method validates input parameters, constructs the HTTP request according
 // to the API contract, and handles both successful responses and error conditions.
 //
 // The returned audio data is in WAV format as specified by the service contract.
 // Callers are responsible for writing this data to files or streaming it as needed.
+//
+// While the circuit breaker is open, requests fail immediately with ErrCircuitOpen.
+// Requests wait for the rate limits set with SetRateLimit and SetAdaptiveConcurrency.
 func (c *HTTPClient) GenerateSpeech(ctx context.Context, req Request) ([]byte, error) {
 	err := c.validateRequest(&req)
 	if err != nil {
 		return nil, err
 	}
 
-	httpReq, err := c.buildHTTPRequest(ctx, req)
+	release, err := c.acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer release()
 
-	resp, err := c.sendRequest(httpReq)
+	err = c.adaptive.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.breaker.Allow()
+	if err != nil {
+		c.adaptive.Ignore()
+
+		return nil, fmt.Errorf("TTS service at %s is unavailable: %w", c.baseURL, err)
+	}
+
+	requestCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	httpReq, err := c.buildHTTPRequest(requestCtx, req)
 	if err != nil {
+		c.breaker.Ignore()
+		c.adaptive.Ignore()
+
 		return nil, err
 	}
 
+	start := c.clock.Now()
+
+	resp, err := c.sendRequest(httpReq)
+	if err != nil {
+		// A request cancelled by the caller says nothing about the service.
+		if ctx.Err() != nil {
+			c.breaker.Ignore()
+			c.adaptive.Ignore()
+		} else {
+			c.breaker.Failure()
+			c.adaptive.Overload()
+		}
+
+		return nil, c.timeoutError(requestCtx, err)
+	}
+
 	defer func() {
 		closeErr := resp.Body.Close()
 		if closeErr != nil {
@@ -156,7 +534,20 @@ func (c *HTTPClient) GenerateSpeech(ctx context.Context, req Request) ([]byte, e
 		}
 	}()
 
-	return c.processResponse(resp)
+	if resp.StatusCode >= http.StatusInternalServerError {
+		c.breaker.Failure()
+		c.adaptive.Overload()
+	} else {
+		c.breaker.Success()
+		c.adaptive.Success(c.clock.Now().Sub(start))
+	}
+
+	audio, err := c.processResponse(resp)
+	if err != nil {
+		return nil, c.timeoutError(requestCtx, err)
+	}
+
+	return audio, nil
 }
 
 // HealthCheck verifies that the TTS service is running and operational.
@@ -165,21 +556,30 @@ func (c *HTTPClient) GenerateSpeech(ctx context.Context, req Request) ([]byte, e
 //
 // Health checks should be performed before processing large workloads to fail fast
 // and provide clear diagnostics when the service is unavailable.
+//
+// Services whose API has no health path are assumed to be healthy.
 func (c *HTTPClient) HealthCheck(ctx context.Context) error {
-	url := c.baseURL + apiHealth
+	if c.api.HealthPath == "" {
+		return nil
+	}
+
+	url := c.baseURL + c.api.HealthPath
+
+	requestCtx, cancel := c.withTimeout(ctx)
+	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, http.NoBody)
+	req, err := http.NewRequestWithContext(requestCtx, http.MethodGet, url, http.NoBody)
 	if err != nil {
 		return fmt.Errorf("failed to create health check request: %w", err)
 	}
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf(
+		return c.timeoutError(requestCtx, fmt.Errorf(
 			"health check failed for service at %s: %w",
 			c.baseURL,
 			err,
-		)
+		))
 	}
 
 	defer func() {
@@ -202,6 +602,15 @@ func (c *HTTPClient) validateRequest(req *Request) error {
 		return ErrTextCannotBeEmpty
 	}
 
+	if !utf8.ValidString(req.Text) {
+		return ErrTextNotUTF8
+	}
+
+	chars := utf8.RuneCountInString(req.Text)
+	if c.maxTextChars > 0 && chars > c.maxTextChars {
+		return fmt.Errorf("%w: %d characters, the limit is %d", ErrTextTooLong, chars, c.maxTextChars)
+	}
+
 	if req.Temperature == 0 {
 		req.Temperature = defaultTemperature
 	}
@@ -213,32 +622,12 @@ func (c *HTTPClient) validateRequest(req *Request) error {
 	return nil
 }
 
-// buildHTTPRequest constructs the HTTP request with proper headers and body.
+// buildHTTPRequest constructs the HTTP request of the client's API.
 func (c *HTTPClient) buildHTTPRequest(
 	ctx context.Context,
 	req Request,
 ) (*http.Request, error) {
-	requestBody, err := json.Marshal(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	url := c.baseURL + apiGenerateSpeech
-
-	httpReq, err := http.NewRequestWithContext(
-		ctx,
-		http.MethodPost,
-		url,
-		bytes.NewBuffer(requestBody),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	httpReq.Header.Set(headerContentType, contentTypeJSON)
-	httpReq.Header.Set(headerAccept, contentTypeWAV)
-
-	return httpReq, nil
+	return c.api.newRequest(ctx, c.baseURL, req)
 }
 
 // sendRequest executes the HTTP request and returns the response.
@@ -255,64 +644,13 @@ func (c *HTTPClient) sendRequest(httpReq *http.Request) (*http.Response, error)
 	return resp, nil
 }
 
-// processResponse handles the HTTP response and extracts audio data.
+// processResponse handles the HTTP response and extracts audio data. Errors
+// with a detail in the API's error fields are reported as ErrServiceError,
+// others as ErrServiceNonOKStatus with the raw response body.
 func (c *HTTPClient) processResponse(resp *http.Response) ([]byte, error) {
 	if resp.StatusCode != http.StatusOK {
-		return nil, c.parseErrorResponse(resp)
-	}
-
-	err := c.validateResponseContentType(resp)
-	if err != nil {
-		return nil, err
-	}
-
-	return c.readAudioData(resp)
-}
-
-// validateResponseContentType ensures the response has the expected content type.
-func (c *HTTPClient) validateResponseContentType(resp *http.Response) error {
-	contentType := resp.Header.Get("Content-Type")
-	if contentType != contentTypeWAV {
-		return newUnexpectedContentTypeError(contentType)
-	}
-
-	return nil
-}
-
-// readAudioData reads and validates the audio response data.
-func (c *HTTPClient) readAudioData(resp *http.Response) ([]byte, error) {
-	audioData, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read audio data: %w", err)
-	}
-
-	if len(audioData) == 0 {
-		return nil, ErrReceivedEmptyAudio
-	}
-
-	return audioData, nil
-}
-
-// parseErrorResponse attempts to decode a structured JSON error from the service.
-// If structured parsing fails, it falls back to returning the raw response body
-// to ensure diagnostic information is preserved.
-func (c *HTTPClient) parseErrorResponse(resp *http.Response) error {
-	var errorResp ErrorResponse
-
-	err := json.NewDecoder(resp.Body).Decode(&errorResp)
-	if err == nil {
-		return newServiceErrorWithCodeError(
-			resp.Status,
-			errorResp.Detail,
-			errorResp.ErrorCode,
-		)
-	}
-
-	// Fallback to raw response for non-JSON errors
-	body, readErr := io.ReadAll(resp.Body)
-	if readErr != nil {
-		return fmt.Errorf("failed to read error response body: %w", readErr)
+		return nil, c.api.serviceError(resp)
 	}
 
-	return newServiceNonOKStatusError(resp.Status, string(body))
+	return c.api.audio(resp)
 }
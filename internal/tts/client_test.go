@@ -0,0 +1,502 @@
+package tts_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSpeechRequest() tts.Request {
+	return tts.Request{
+		Text:           "hello",
+		Voice:          "",
+		SpeakerRefPath: "",
+		Language:       "",
+		Model:          "",
+		Temperature:    0,
+		Seed:           0,
+		Style:          "",
+	}
+}
+
+func TestHTTPClient_GenerateSpeech(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+
+	audio, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+}
+
+func TestHTTPClient_CircuitBreaker(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		http.Error(writer, "model crashed", http.StatusInternalServerError)
+	}))
+	t.Cleanup(server.Close)
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+	client.SetCircuitBreaker(tts.NewCircuitBreaker(3, time.Hour))
+
+	for range 3 {
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		require.ErrorIs(t, err, tts.ErrServiceNonOKStatus)
+	}
+
+	for range 10 {
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		require.ErrorIs(t, err, tts.ErrCircuitOpen)
+	}
+
+	assert.Equal(t, int32(3), hits.Load(), "an open breaker should not reach the service")
+}
+
+func TestHTTPClient_CircuitBreakerIgnoresCancelledRequests(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		select {
+		case <-release:
+			writer.Header().Set("Content-Type", "audio/wav")
+			_, _ = writer.Write([]byte("RIFF"))
+		case <-request.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+	client.SetCircuitBreaker(tts.NewCircuitBreaker(1, time.Hour))
+
+	for range 3 {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+
+		_, err := client.GenerateSpeech(ctx, newSpeechRequest())
+
+		cancel()
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := client.GenerateSpeech(cancelled, newSpeechRequest())
+	require.ErrorIs(t, err, context.Canceled)
+	require.NotErrorIs(t, err, tts.ErrCircuitOpen, "cancelled requests must not open the breaker")
+}
+
+// roundTripperFunc answers requests without a network, in place of a server.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+// wavResponse is a successful reply to request.
+func wavResponse(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"audio/wav"}},
+		Body:       io.NopCloser(strings.NewReader("RIFF")),
+		Request:    request,
+	}
+}
+
+func TestHTTPClient_RateLimit(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+
+	clock := newFakeClock()
+	client := tts.NewHTTPClient("http://tts.invalid", 5*time.Second)
+	client.SetClock(clock)
+	client.SetRoundTripper(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		hits.Add(1)
+
+		return wavResponse(request), nil
+	}))
+	client.SetRateLimit(2, 0)
+
+	for range 2 {
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		require.NoError(t, err, "the burst is sent at once")
+	}
+
+	done := make(chan error)
+
+	go func() {
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		done <- err
+	}()
+
+	// The request timeouts of the first two were stopped; the third waits
+	// for a token.
+	require.Eventually(t, func() bool { return clock.Pending() == 1 }, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), hits.Load())
+
+	clock.Advance(500 * time.Millisecond)
+	require.NoError(t, <-done, "a token is added every half second")
+	assert.Equal(t, int32(3), hits.Load())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	_, err := client.GenerateSpeech(ctx, newSpeechRequest())
+	require.ErrorIs(t, err, context.DeadlineExceeded, "a request due after its deadline fails at once")
+	assert.Equal(t, int32(3), hits.Load())
+}
+
+func TestHTTPClient_MaxConcurrent(t *testing.T) {
+	t.Parallel()
+
+	var active, peak atomic.Int32
+
+	release := make(chan struct{})
+
+	client := tts.NewHTTPClient("http://tts.invalid", 5*time.Second)
+	client.SetRoundTripper(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		current := active.Add(1)
+		defer active.Add(-1)
+
+		for {
+			seen := peak.Load()
+			if current <= seen || peak.CompareAndSwap(seen, current) {
+				break
+			}
+		}
+
+		<-release
+
+		return wavResponse(request), nil
+	}))
+	client.SetRateLimit(0, 2)
+
+	var waitGroup sync.WaitGroup
+
+	for range 5 {
+		waitGroup.Go(func() {
+			_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+			assert.NoError(t, err)
+		})
+	}
+
+	require.Eventually(t, func() bool { return active.Load() == 2 }, 5*time.Second, time.Millisecond)
+	close(release)
+	waitGroup.Wait()
+
+	assert.Equal(t, int32(2), peak.Load(), "no more than two requests should be in flight")
+}
+
+func TestHTTPClient_Timeout(t *testing.T) {
+	t.Parallel()
+
+	started := make(chan struct{}, 1)
+
+	clock := newFakeClock()
+	client := tts.NewHTTPClient("http://tts.invalid", 30*time.Second)
+	client.SetClock(clock)
+	client.SetCircuitBreaker(tts.NewCircuitBreaker(1, time.Hour))
+	client.SetRoundTripper(roundTripperFunc(func(request *http.Request) (*http.Response, error) {
+		started <- struct{}{}
+		<-request.Context().Done()
+
+		return nil, request.Context().Err()
+	}))
+
+	done := make(chan error)
+
+	go func() {
+		_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+		done <- err
+	}()
+
+	<-started
+	clock.Advance(29 * time.Second)
+
+	select {
+	case err := <-done:
+		require.FailNow(t, "the request ended before its timeout", "%v", err)
+	default:
+	}
+
+	clock.Advance(time.Second)
+
+	err := <-done
+	require.ErrorIs(t, err, tts.ErrRequestTimeout)
+	require.ErrorIs(t, err, context.DeadlineExceeded, "timeouts are classified as such")
+
+	_, err = client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.ErrorIs(t, err, tts.ErrCircuitOpen, "a timeout counts as a failure of the service")
+}
+
+func TestHTTPClient_ReusesConnections(t *testing.T) {
+	t.Parallel()
+
+	var connections atomic.Int32
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			connections.Add(1)
+		}
+	}
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+
+	const parallel = 8
+
+	for range 5 {
+		var waitGroup sync.WaitGroup
+
+		for range parallel {
+			waitGroup.Go(func() {
+				_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+				assert.NoError(t, err)
+			})
+		}
+
+		waitGroup.Wait()
+	}
+
+	assert.LessOrEqual(t, connections.Load(), int32(parallel), "idle connections are kept for every request in flight")
+}
+
+func TestHTTPClient_HTTP2WithoutTLS(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.ProtoMajor != 2 {
+			http.Error(writer, request.Proto, http.StatusHTTPVersionNotSupported)
+
+			return
+		}
+
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	server.Config.Protocols = new(http.Protocols)
+	server.Config.Protocols.SetHTTP1(true)
+	server.Config.Protocols.SetUnencryptedHTTP2(true)
+	server.Start()
+	t.Cleanup(server.Close)
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+
+	_, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.Error(t, err, "HTTP/1.1 by default")
+
+	require.NoError(t, client.SetTransport(tts.TransportOptions{
+		MaxIdleConnsPerHost: 0, IdleConnTimeout: 0, KeepAlive: 0, HTTP2: true, TLS: tts.TLSOptions{},
+	}))
+
+	audio, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+}
+
+// testPKI is a CA with a server certificate for "tts.internal" and a client
+// certificate, written as PEM files.
+type testPKI struct {
+	caFile, clientCertFile, clientKeyFile string
+	caPool                                *x509.CertPool
+	server                                tls.Certificate
+}
+
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, name string, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, keyErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, keyErr)
+
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: name},
+			DNSNames:     []string{name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, certErr := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+		require.NoError(t, certErr)
+
+		keyDER, keyErr := x509.MarshalECPrivateKey(key)
+		require.NoError(t, keyErr)
+
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+
+		return path
+	}
+
+	serverCert, serverKey := issue(2, "tts.internal", x509.ExtKeyUsageServerAuth)
+	server, err := tls.X509KeyPair(serverCert, serverKey)
+	require.NoError(t, err)
+
+	clientCert, clientKey := issue(3, "tts-service", x509.ExtKeyUsageClientAuth)
+
+	pki := testPKI{
+		caFile:         write("ca.pem", pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER})),
+		clientCertFile: write("client.pem", clientCert),
+		clientKeyFile:  write("client-key.pem", clientKey),
+		caPool:         x509.NewCertPool(),
+		server:         server,
+	}
+	pki.caPool.AddCert(caCert)
+
+	return pki
+}
+
+func TestHTTPClient_MutualTLS(t *testing.T) {
+	t.Parallel()
+
+	pki := newTestPKI(t)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pki.server},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pki.caPool,
+		MinVersion:   tls.VersionTLS12,
+	}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	options := tts.TransportOptions{
+		MaxIdleConnsPerHost: 0,
+		IdleConnTimeout:     0,
+		KeepAlive:           0,
+		HTTP2:               false,
+		TLS: tts.TLSOptions{
+			CAFile:     pki.caFile,
+			CertFile:   pki.clientCertFile,
+			KeyFile:    pki.clientKeyFile,
+			ServerName: "tts.internal",
+		},
+	}
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+	require.NoError(t, client.SetTransport(options))
+
+	audio, err := client.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+
+	// Without a client certificate the server refuses the handshake.
+	options.TLS.CertFile, options.TLS.KeyFile = "", ""
+	anonymous := tts.NewHTTPClient(server.URL, 5*time.Second)
+	anonymous.SetCircuitBreaker(nil)
+	require.NoError(t, anonymous.SetTransport(options))
+
+	_, err = anonymous.GenerateSpeech(context.Background(), newSpeechRequest())
+	require.Error(t, err)
+
+	options.TLS.CertFile = pki.clientCertFile
+	require.ErrorIs(t, anonymous.SetTransport(options), tts.ErrIncompleteTLS)
+
+	options.TLS.CAFile = pki.clientKeyFile
+	options.TLS.CertFile = ""
+	require.ErrorIs(t, anonymous.SetTransport(options), tts.ErrInvalidCA)
+}
+
+func TestHTTPClient_RejectsInvalidText(t *testing.T) {
+	t.Parallel()
+
+	var hits atomic.Int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		hits.Add(1)
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	t.Cleanup(server.Close)
+
+	client := tts.NewHTTPClient(server.URL, 5*time.Second)
+	client.SetMaxTextLength(5)
+
+	request := newSpeechRequest()
+	request.Text = "héllo"
+
+	_, err := client.GenerateSpeech(context.Background(), request)
+	require.NoError(t, err, "the limit counts characters, not bytes")
+
+	request.Text = "hello!"
+	_, err = client.GenerateSpeech(context.Background(), request)
+	require.ErrorIs(t, err, tts.ErrTextTooLong)
+
+	request.Text = "\xffhi"
+	_, err = client.GenerateSpeech(context.Background(), request)
+	require.ErrorIs(t, err, tts.ErrTextNotUTF8)
+
+	assert.Equal(t, int32(1), hits.Load(), "rejected requests are not sent")
+}
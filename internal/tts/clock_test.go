@@ -0,0 +1,115 @@
+package tts_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeClock is a tts.Clock whose time only moves when a test advances it.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// fakeTimer is a call pending on a fakeClock.
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	call  func()
+	done  bool
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{
+		mu:     sync.Mutex{},
+		now:    time.Date(2026, time.March, 1, 12, 0, 0, 0, time.UTC),
+		timers: nil,
+	}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *fakeClock) AfterFunc(d time.Duration, call func()) tts.Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	timer := &fakeTimer{clock: c, at: c.now.Add(d), call: call, done: false}
+	c.timers = append(c.timers, timer)
+
+	return timer
+}
+
+// Advance moves the time forward by d and starts the calls that are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+
+	var due []func()
+
+	for _, timer := range c.timers {
+		if !timer.done && !timer.at.After(c.now) {
+			timer.done = true
+			due = append(due, timer.call)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, call := range due {
+		go call()
+	}
+}
+
+// Pending returns the number of calls that are not yet due or stopped.
+func (c *fakeClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	pending := 0
+
+	for _, timer := range c.timers {
+		if !timer.done {
+			pending++
+		}
+	}
+
+	return pending
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	pending := !t.done
+	t.done = true
+
+	return pending
+}
+
+func TestSystemClock(t *testing.T) {
+	t.Parallel()
+
+	clock := tts.SystemClock{}
+	assert.WithinDuration(t, time.Now(), clock.Now(), time.Second)
+
+	called := make(chan struct{})
+	clock.AfterFunc(time.Millisecond, func() { close(called) })
+
+	select {
+	case <-called:
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "AfterFunc did not call")
+	}
+
+	require.True(t, clock.AfterFunc(time.Hour, func() {}).Stop())
+}
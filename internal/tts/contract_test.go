@@ -0,0 +1,199 @@
+package tts_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// contractTimeout bounds one request to the live service, which may
+// synthesize on a CPU.
+const contractTimeout = 5 * time.Minute
+
+// contractURL returns the TTS HTTP service the contract tests run against,
+// or skips the test when TTS_CONTRACT_URL is not set.
+func contractURL(t *testing.T) string {
+	t.Helper()
+
+	url := strings.TrimRight(os.Getenv("TTS_CONTRACT_URL"), "/")
+	if url == "" {
+		t.Skip("set TTS_CONTRACT_URL to run the contract tests against a TTS HTTP service")
+	}
+
+	return url
+}
+
+// postSpeech posts body to the speech endpoint as the client does and returns
+// the response with its body read.
+func postSpeech(t *testing.T, url string, body any) (*http.Response, []byte) {
+	t.Helper()
+
+	data, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), contractTimeout)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url+"/v1/generate/speech", bytes.NewReader(data))
+	require.NoError(t, err)
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "audio/wav")
+
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+
+	defer response.Body.Close()
+
+	reply, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	return response, reply
+}
+
+// requireErrorSchema checks that a failed request was answered with a client
+// error in the ErrorResponse schema: a JSON object with a non-empty "detail"
+// string and an optional "errorCode" string.
+func requireErrorSchema(t *testing.T, response *http.Response, reply []byte) {
+	t.Helper()
+
+	require.GreaterOrEqual(t, response.StatusCode, http.StatusBadRequest, "%s", reply)
+	require.Less(t, response.StatusCode, http.StatusInternalServerError, "invalid requests are client errors: %s", reply)
+	require.True(t, strings.HasPrefix(response.Header.Get("Content-Type"), "application/json"),
+		"errors are JSON, got %q", response.Header.Get("Content-Type"))
+
+	var fields map[string]any
+	require.NoError(t, json.Unmarshal(reply, &fields), "%s", reply)
+
+	detail, ok := fields["detail"].(string)
+	require.True(t, ok, "detail is a string: %s", reply)
+	assert.NotEmpty(t, detail)
+
+	if code, present := fields["errorCode"]; present {
+		_, ok = code.(string)
+		assert.True(t, ok, "errorCode is a string: %s", reply)
+	}
+}
+
+// requireWAV checks that the service answered with WAV audio.
+func requireWAV(t *testing.T, audio []byte) {
+	t.Helper()
+
+	info, err := wav.Inspect(audio)
+	require.NoError(t, err)
+	assert.Positive(t, info.SampleRate)
+	assert.Positive(t, info.Channels)
+}
+
+// TestContract_Health checks /health against the client.
+func TestContract_Health(t *testing.T) {
+	t.Parallel()
+
+	client := tts.NewHTTPClient(contractURL(t), contractTimeout)
+	require.NoError(t, client.HealthCheck(context.Background()))
+}
+
+// TestContract_GenerateSpeech checks that the requests the client sends are
+// answered with WAV audio.
+func TestContract_GenerateSpeech(t *testing.T) {
+	t.Parallel()
+
+	client := tts.NewHTTPClient(contractURL(t), contractTimeout)
+	client.SetCircuitBreaker(nil)
+
+	for name, request := range map[string]tts.Request{
+		"defaults": newSpeechRequest(),
+		"every field": {
+			Text:           "Every optional field is set.",
+			Voice:          "",
+			SpeakerRefPath: "",
+			Language:       "en",
+			Model:          "",
+			Temperature:    0.5,
+			Seed:           42,
+			Style:          "",
+		},
+		"unicode": {
+			Text:           "Café crème, naïve façade — “quoted” and ‘single’… 10 °C, 5 € and 日本語.",
+			Voice:          "",
+			SpeakerRefPath: "",
+			Language:       "",
+			Model:          "",
+			Temperature:    0,
+			Seed:           0,
+			Style:          "",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			audio, err := client.GenerateSpeech(context.Background(), request)
+			require.NoError(t, err)
+			requireWAV(t, audio)
+		})
+	}
+}
+
+// TestContract_LongText checks that a long text is either synthesized or
+// refused with a client error in the error schema, never a server error.
+func TestContract_LongText(t *testing.T) {
+	t.Parallel()
+
+	url := contractURL(t)
+	text := strings.Repeat("The rain fell on the roofs and the gardens of the town. ", 200)
+
+	response, reply := postSpeech(t, url, map[string]any{"text": text, "language": "en", "temperature": 0.75})
+	if response.StatusCode == http.StatusOK {
+		assert.Equal(t, "audio/wav", response.Header.Get("Content-Type"))
+		requireWAV(t, reply)
+
+		return
+	}
+
+	requireErrorSchema(t, response, reply)
+}
+
+// TestContract_Errors checks that invalid requests are refused in the error
+// schema, and that the client reports them as structured service errors.
+func TestContract_Errors(t *testing.T) {
+	t.Parallel()
+
+	url := contractURL(t)
+
+	for name, body := range map[string]any{
+		"bad speaker path": map[string]any{
+			"text": "Hello.", "language": "en", "temperature": 0.75,
+			"speakerRefPath": "/nonexistent/contract-test-speaker.wav",
+		},
+		"empty text":      map[string]any{"text": "", "language": "en", "temperature": 0.75},
+		"missing text":    map[string]any{"language": "en", "temperature": 0.75},
+		"bad temperature": map[string]any{"text": "Hello.", "language": "en", "temperature": "warm"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			response, reply := postSpeech(t, url, body)
+			requireErrorSchema(t, response, reply)
+		})
+	}
+
+	client := tts.NewHTTPClient(url, contractTimeout)
+	client.SetCircuitBreaker(nil)
+
+	request := newSpeechRequest()
+	request.SpeakerRefPath = "/nonexistent/contract-test-speaker.wav"
+
+	_, err := client.GenerateSpeech(context.Background(), request)
+	require.ErrorIs(t, err, tts.ErrServiceError, "the client parses the error schema")
+}
@@ -0,0 +1,173 @@
+// Package dialogue splits narrative text into narration and quoted speech and
+// voices each character with its own cast voice.
+package dialogue
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Segment is a run of narration or a quotation.
+type Segment struct {
+	// Text is the segment without its quotation marks.
+	Text string
+	// Quoted marks speech between quotation marks.
+	Quoted bool
+	// Speaker is the attributed character, as written in the text; empty for
+	// narration and for speech without an attribution.
+	Speaker string
+}
+
+// speechVerbs introduce or follow speech in an attribution such as
+// `"Run," said Alice` or `Alice whispered, "Run."`.
+const speechVerbs = `said|says|asked|asks|replied|replies|answered|whispered|shouted|cried|called|` +
+	`muttered|murmured|yelled|added|continued|exclaimed|began|snapped|sighed|laughed|told`
+
+// speakerName matches one or two capitalized words.
+const speakerName = `([A-Z][\p{L}'-]*(?:\s+[A-Z][\p{L}'-]*)?)`
+
+var (
+	// attributionAfter matches the start of the narration after a quotation:
+	// "said Alice" or "Alice said".
+	attributionAfter = regexp.MustCompile(
+		`^[\s,]*(?:(?:` + speechVerbs + `)\s+` + speakerName + `|` + speakerName + `\s+(?:` + speechVerbs + `))\b`)
+	// attributionBefore matches the end of the narration before a quotation:
+	// "Alice said," or "Alice said:".
+	attributionBefore = regexp.MustCompile(speakerName + `\s+(?:` + speechVerbs + `)\b[^.!?"“”]*[,:]\s*$`)
+)
+
+// sentenceOpeners are capitalized words that start a sentence before a name,
+// as in "Then Bob said", and are not part of the name.
+var sentenceOpeners = map[string]bool{
+	"And": true, "As": true, "But": true, "Finally": true, "Now": true, "Slowly": true,
+	"So": true, "Suddenly": true, "Then": true, "When": true, "While": true, "Yet": true,
+}
+
+// quotePairs maps each opening quotation mark to its closing mark.
+var quotePairs = map[rune]rune{
+	'"': '"',
+	'“': '”',
+}
+
+// Parse splits text into narration and quotations and attributes each
+// quotation to a speaker when the adjacent narration names one. A quotation
+// without an attribution that follows an attributed one in the same paragraph
+// is given the same speaker. An unclosed quotation runs to the end of the text.
+// Segments without letters or digits, such as the comma between a quotation
+// and its attribution, are dropped.
+func Parse(text string) []Segment {
+	segments := split(text)
+
+	previousSpeaker := ""
+
+	for i := range segments {
+		if !segments[i].Quoted {
+			if strings.Contains(segments[i].Text, "\n") {
+				previousSpeaker = ""
+			}
+
+			continue
+		}
+
+		speaker := attribution(segments, i)
+		if speaker == "" {
+			speaker = previousSpeaker
+		}
+
+		segments[i].Speaker = speaker
+		previousSpeaker = speaker
+	}
+
+	kept := segments[:0]
+
+	for _, segment := range segments {
+		segment.Text = strings.TrimSpace(segment.Text)
+		if strings.IndexFunc(segment.Text, isWordRune) >= 0 {
+			kept = append(kept, segment)
+		}
+	}
+
+	return kept
+}
+
+// split separates quotations from narration, keeping the text as written.
+func split(text string) []Segment {
+	var (
+		segments []Segment
+		current  strings.Builder
+		closing  rune
+		quoted   bool
+	)
+
+	flush := func() {
+		if current.Len() > 0 {
+			segments = append(segments, Segment{Text: current.String(), Quoted: quoted, Speaker: ""})
+		}
+
+		current.Reset()
+	}
+
+	for _, r := range text {
+		switch {
+		case quoted && r == closing:
+			flush()
+
+			quoted = false
+		case !quoted && quotePairs[r] != 0:
+			flush()
+
+			quoted = true
+			closing = quotePairs[r]
+		default:
+			current.WriteRune(r)
+		}
+	}
+
+	flush()
+
+	return segments
+}
+
+// attribution returns the speaker named next to the quotation at index i.
+func attribution(segments []Segment, index int) string {
+	if index+1 < len(segments) && !segments[index+1].Quoted {
+		match := attributionAfter.FindStringSubmatch(segments[index+1].Text)
+		if match != nil {
+			return speaker(firstNonEmpty(match[1], match[2]))
+		}
+	}
+
+	if index > 0 && !segments[index-1].Quoted {
+		match := attributionBefore.FindStringSubmatch(segments[index-1].Text)
+		if match != nil {
+			return speaker(match[1])
+		}
+	}
+
+	return ""
+}
+
+// speaker drops a sentence opener captured in front of a name.
+func speaker(name string) string {
+	first, rest, found := strings.Cut(name, " ")
+	if found && sentenceOpeners[first] {
+		return strings.TrimSpace(rest)
+	}
+
+	return name
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,82 @@
+package dialogue_test
+
+import (
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts/dialogue"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		text string
+		want []dialogue.Segment
+	}{
+		{
+			name: "narration only",
+			text: "The house was quiet.",
+			want: []dialogue.Segment{{Text: "The house was quiet.", Quoted: false, Speaker: ""}},
+		},
+		{
+			name: "attribution after the quote",
+			text: `"Run," said Alice. The door slammed.`,
+			want: []dialogue.Segment{
+				{Text: "Run,", Quoted: true, Speaker: "Alice"},
+				{Text: "said Alice. The door slammed.", Quoted: false, Speaker: ""},
+			},
+		},
+		{
+			name: "name before the verb",
+			text: `“Where?” Bob asked.`,
+			want: []dialogue.Segment{
+				{Text: "Where?", Quoted: true, Speaker: "Bob"},
+				{Text: "Bob asked.", Quoted: false, Speaker: ""},
+			},
+		},
+		{
+			name: "attribution before the quote",
+			text: `Mary Jane whispered softly, "Not here."`,
+			want: []dialogue.Segment{
+				{Text: "Mary Jane whispered softly,", Quoted: false, Speaker: ""},
+				{Text: "Not here.", Quoted: true, Speaker: "Mary Jane"},
+			},
+		},
+		{
+			name: "continued speech keeps the speaker within a paragraph",
+			text: "\"Hello,\" said Bob. \"How are you?\"\n\n\"Fine.\"",
+			want: []dialogue.Segment{
+				{Text: "Hello,", Quoted: true, Speaker: "Bob"},
+				{Text: "said Bob.", Quoted: false, Speaker: ""},
+				{Text: "How are you?", Quoted: true, Speaker: "Bob"},
+				{Text: "Fine.", Quoted: true, Speaker: ""},
+			},
+		},
+		{
+			name: "sentence opener before the name",
+			text: `Then Bob said, "Go."`,
+			want: []dialogue.Segment{
+				{Text: "Then Bob said,", Quoted: false, Speaker: ""},
+				{Text: "Go.", Quoted: true, Speaker: "Bob"},
+			},
+		},
+		{
+			name: "unclosed quote",
+			text: `He said, "wait`,
+			want: []dialogue.Segment{
+				{Text: "He said,", Quoted: false, Speaker: ""},
+				{Text: "wait", Quoted: true, Speaker: "He"},
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.want, dialogue.Parse(tc.text))
+		})
+	}
+}
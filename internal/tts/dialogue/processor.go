@@ -0,0 +1,168 @@
+package dialogue
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Cast assigns voices to the narrator and the characters.
+type Cast struct {
+	// Narrator voices narration; empty uses the job's voice.
+	Narrator string
+	// Dialogue voices speech whose speaker is unknown or not in Characters;
+	// empty uses the narrator's voice.
+	Dialogue string
+	// Characters maps character names, matched case-insensitively, to voices.
+	Characters map[string]string
+}
+
+// voiceFor returns the voice of a segment in a job whose voice is jobVoice.
+func (c Cast) voiceFor(segment Segment, jobVoice string) string {
+	narrator := c.Narrator
+	if narrator == "" {
+		narrator = jobVoice
+	}
+
+	if !segment.Quoted {
+		return narrator
+	}
+
+	for name, voice := range c.Characters {
+		if strings.EqualFold(name, segment.Speaker) {
+			return voice
+		}
+	}
+
+	if c.Dialogue != "" {
+		return c.Dialogue
+	}
+
+	return narrator
+}
+
+// Processor wraps a core.TTSProcessor and voices the narration and each
+// character of a text with the cast's voices: the text is split with Parse,
+// consecutive segments with the same voice are synthesized together, and the
+// WAV results are joined in order. Text with a single voice is passed through.
+type Processor struct {
+	inner core.TTSProcessor
+	cast  Cast
+}
+
+// NewProcessor creates a multi-voice wrapper around inner.
+func NewProcessor(inner core.TTSProcessor, cast Cast) *Processor {
+	return &Processor{
+		inner: inner,
+		cast:  cast,
+	}
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig checks the job with every cast voice it may be synthesized
+// with, when the wrapped processor validates jobs.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	voices := []string{cfg.Voice, p.cast.Narrator, p.cast.Dialogue}
+	for _, voice := range p.cast.Characters {
+		voices = append(voices, voice)
+	}
+
+	for _, voice := range voices {
+		if voice == "" {
+			continue
+		}
+
+		voiceCfg := cfg
+		voiceCfg.Voice = voice
+
+		err := validator.ValidateConfig(voiceCfg)
+		if err != nil {
+			return fmt.Errorf("cast voice '%s': %w", voice, err)
+		}
+	}
+
+	return nil
+}
+
+// part is a run of consecutive segments with the same voice.
+type part struct {
+	voice string
+	text  string
+}
+
+// Process synthesizes each voice's parts and joins the audio.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	parts := p.parts(string(text), cfg.Voice)
+	if len(parts) <= 1 {
+		if len(parts) == 1 {
+			cfg.Voice = parts[0].voice
+		}
+
+		return p.inner.Process(ctx, text, cfg)
+	}
+
+	var joined wav.Audio
+
+	for i, part := range parts {
+		partCfg := cfg
+		partCfg.Voice = part.voice
+
+		data, err := p.inner.Process(ctx, []byte(part.text), partCfg)
+		if err != nil {
+			return nil, fmt.Errorf("synthesizing part %d of %d with voice '%s': %w", i+1, len(parts), part.voice, err)
+		}
+
+		decoded, err := wav.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		if i == 0 {
+			joined = decoded
+
+			continue
+		}
+
+		// Voices of different models may differ in format; follow the first part.
+		decoded, err = audio.Remix(audio.Resample(decoded, joined.SampleRate), joined.Channels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		joined.Samples = append(joined.Samples, decoded.Samples...)
+	}
+
+	return wav.Encode(joined), nil
+}
+
+// parts splits text by voice, merging consecutive segments with the same voice.
+func (p *Processor) parts(text, jobVoice string) []part {
+	var parts []part
+
+	for _, segment := range Parse(text) {
+		voice := p.cast.voiceFor(segment, jobVoice)
+
+		if len(parts) > 0 && parts[len(parts)-1].voice == voice {
+			parts[len(parts)-1].text += " " + segment.Text
+
+			continue
+		}
+
+		parts = append(parts, part{voice: voice, text: segment.Text})
+	}
+
+	return parts
+}
@@ -0,0 +1,139 @@
+package dialogue_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/dialogue"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errRejected = errors.New("rejected")
+
+// jobConfig returns a job configuration with the given voice.
+func jobConfig(voice string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+// call is one request seen by recordingProcessor.
+type call struct {
+	voice string
+	text  string
+}
+
+// recordingProcessor returns one frame of audio per character of text, at a
+// sample rate chosen per voice, and records its calls.
+type recordingProcessor struct {
+	mu    sync.Mutex
+	calls []call
+	rates map[string]int
+}
+
+func (p *recordingProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *recordingProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	if cfg.Voice == "unknown" {
+		return errRejected
+	}
+
+	return nil
+}
+
+func (p *recordingProcessor) Process(_ context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, call{voice: cfg.Voice, text: string(text)})
+	p.mu.Unlock()
+
+	rate := p.rates[cfg.Voice]
+	if rate == 0 {
+		rate = 24000
+	}
+
+	return wav.EncodePCM16(make([]float32, len(text)*rate/24000), rate), nil
+}
+
+func newCast() dialogue.Cast {
+	return dialogue.Cast{
+		Narrator:   "",
+		Dialogue:   "extra",
+		Characters: map[string]string{"alice": "tara", "Bob": "leo"},
+	}
+}
+
+func TestProcessor_CastsVoices(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil, rates: map[string]int{"leo": 48000}}
+	processor := dialogue.NewProcessor(inner, newCast())
+
+	text := "\"Run,\" said Alice. Then Bob whispered, \"Why?\"\n\n\"Because,\" someone answered."
+
+	data, err := processor.Process(context.Background(), []byte(text), jobConfig("narrator"))
+	require.NoError(t, err)
+
+	assert.Equal(t, []call{
+		{voice: "tara", text: "Run,"},
+		{voice: "narrator", text: "said Alice. Then Bob whispered,"},
+		{voice: "leo", text: "Why?"},
+		{voice: "extra", text: "Because,"},
+		{voice: "narrator", text: "someone answered."},
+	}, inner.calls)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, 24000, decoded.SampleRate, "parts follow the format of the first part")
+
+	wantFrames := 0
+	for _, c := range inner.calls {
+		wantFrames += len(c.text)
+	}
+
+	assert.InDelta(t, wantFrames, decoded.Frames(), 2)
+}
+
+func TestProcessor_SingleVoicePassesThrough(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil, rates: nil}
+	processor := dialogue.NewProcessor(inner, newCast())
+
+	_, err := processor.Process(context.Background(), []byte("Chapter one. It rained."), jobConfig("narrator"))
+	require.NoError(t, err)
+	assert.Equal(t, []call{{voice: "narrator", text: "Chapter one. It rained."}}, inner.calls)
+}
+
+func TestProcessor_ValidatesCastVoices(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil, rates: nil}
+
+	require.NoError(t, dialogue.NewProcessor(inner, newCast()).ValidateConfig(jobConfig("narrator")))
+
+	cast := newCast()
+	cast.Characters["carol"] = "unknown"
+
+	err := dialogue.NewProcessor(inner, cast).ValidateConfig(jobConfig("narrator"))
+	require.ErrorIs(t, err, errRejected)
+}
@@ -0,0 +1,141 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// ErrAllBackendsFailed is returned when every stage of a fallback chain failed or was skipped.
+var ErrAllBackendsFailed = errors.New("all TTS backends failed")
+
+// FallbackStage is one backend in a fallback chain.
+type FallbackStage struct {
+	Name      string
+	Processor core.TTSProcessor
+	// Timeout bounds a single attempt on this backend. Zero means no extra limit.
+	Timeout time.Duration
+	// Breaker skips the backend after repeated failures. Nil disables it.
+	Breaker *CircuitBreaker
+}
+
+// FallbackProcessor tries its stages in order and returns the first successful result.
+type FallbackProcessor struct {
+	stages []FallbackStage
+}
+
+// NewFallback creates a FallbackProcessor. The first stage is the primary backend.
+func NewFallback(stages []FallbackStage) *FallbackProcessor {
+	return &FallbackProcessor{stages: stages}
+}
+
+// GetConfig returns the configuration of the primary backend.
+func (p *FallbackProcessor) GetConfig() core.TTSConfig {
+	return p.stages[0].Processor.GetConfig()
+}
+
+// ValidateConfig accepts a job that at least one stage accepts, since the chain
+// moves on from stages that cannot serve it.
+func (p *FallbackProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	var stageErrors []error
+
+	for _, stage := range p.stages {
+		validator, ok := stage.Processor.(core.ConfigValidator)
+		if !ok {
+			return nil
+		}
+
+		stageConfig := stage.Processor.GetConfig()
+		cfg.ModelPath = stageConfig.ModelPath
+		cfg.SnacModelPath = stageConfig.SnacModelPath
+
+		err := validator.ValidateConfig(cfg)
+		if err == nil {
+			return nil
+		}
+
+		stageErrors = append(stageErrors, fmt.Errorf("%s: %w", stage.Name, err))
+	}
+
+	return fmt.Errorf("%w: %w", ErrAllBackendsFailed, errors.Join(stageErrors...))
+}
+
+// Process runs the job on the first backend that is available and succeeds.
+// Each stage uses its own model paths; the voice and sampling settings are shared.
+//
+// When ctx has a deadline, each attempt is also limited to an equal share of the
+// remaining time, so a hanging stage cannot leave the later stages without any.
+// Attempts cut short by ctx itself do not count against a stage's breaker.
+func (p *FallbackProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	var stageErrors []error
+
+	for index, stage := range p.stages {
+		err := stage.Breaker.Allow()
+		if err != nil {
+			stageErrors = append(stageErrors, fmt.Errorf("%s: %w", stage.Name, err))
+
+			continue
+		}
+
+		timeout := stageTimeout(ctx, stage.Timeout, len(p.stages)-index)
+
+		audio, err := p.attempt(ctx, stage, timeout, text, cfg)
+		if err == nil {
+			stage.Breaker.Success()
+
+			return audio, nil
+		}
+
+		stageErrors = append(stageErrors, fmt.Errorf("%s: %w", stage.Name, err))
+
+		if ctx.Err() != nil {
+			stage.Breaker.Ignore()
+
+			break
+		}
+
+		stage.Breaker.Failure()
+	}
+
+	return nil, fmt.Errorf("%w: %w", ErrAllBackendsFailed, errors.Join(stageErrors...))
+}
+
+// stageTimeout limits an attempt to the stage's own timeout and, when ctx has a
+// deadline, to an equal share of the time left for the stages that remain.
+func stageTimeout(ctx context.Context, timeout time.Duration, remainingStages int) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+
+	share := time.Until(deadline) / time.Duration(remainingStages)
+	if timeout <= 0 || share < timeout {
+		return share
+	}
+
+	return timeout
+}
+
+func (p *FallbackProcessor) attempt(
+	ctx context.Context,
+	stage FallbackStage,
+	timeout time.Duration,
+	text []byte,
+	cfg core.TTSConfig,
+) ([]byte, error) {
+	if timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	stageConfig := stage.Processor.GetConfig()
+	cfg.ModelPath = stageConfig.ModelPath
+	cfg.SnacModelPath = stageConfig.SnacModelPath
+
+	return stage.Processor.Process(ctx, text, cfg)
+}
@@ -0,0 +1,134 @@
+package tts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errBackendDown = errors.New("backend down")
+
+// flakyProcessor fails while down is set and records the calls it receives.
+type flakyProcessor struct {
+	modelPath string
+	down      bool
+	calls     int
+	lastCfg   core.TTSConfig
+}
+
+func (p *flakyProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         p.modelPath,
+		SnacModelPath:     "",
+		Voice:             "default",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+func (p *flakyProcessor) Process(_ context.Context, _ []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.calls++
+	p.lastCfg = cfg
+
+	if p.down {
+		return nil, errBackendDown
+	}
+
+	return []byte(p.modelPath), nil
+}
+
+func TestFallbackProcessor(t *testing.T) {
+	t.Parallel()
+
+	primary := &flakyProcessor{modelPath: "gpu.gguf", down: true, calls: 0, lastCfg: core.TTSConfig{}}
+	secondary := &flakyProcessor{modelPath: "cpu.onnx", down: false, calls: 0, lastCfg: core.TTSConfig{}}
+
+	fallback := tts.NewFallback([]tts.FallbackStage{
+		{Name: "gpu", Processor: primary, Timeout: time.Second, Breaker: tts.NewCircuitBreaker(2, time.Hour)},
+		{Name: "cpu", Processor: secondary, Timeout: 0, Breaker: nil},
+	})
+
+	assert.Equal(t, "gpu.gguf", fallback.GetConfig().ModelPath)
+
+	for range 3 {
+		audio, err := fallback.Process(context.Background(), []byte("hello"), fallback.GetConfig())
+		require.NoError(t, err)
+		assert.Equal(t, []byte("cpu.onnx"), audio)
+	}
+
+	assert.Equal(t, 2, primary.calls, "the breaker should skip the primary after two failures")
+	assert.Equal(t, 3, secondary.calls)
+	assert.Equal(t, "cpu.onnx", secondary.lastCfg.ModelPath, "each stage should use its own model")
+
+	secondary.down = true
+
+	_, err := fallback.Process(context.Background(), []byte("hello"), fallback.GetConfig())
+	require.ErrorIs(t, err, tts.ErrAllBackendsFailed)
+	require.ErrorIs(t, err, tts.ErrCircuitOpen)
+	require.ErrorIs(t, err, errBackendDown)
+}
+
+// hangingProcessor blocks until its context is done.
+type hangingProcessor struct{}
+
+func (hangingProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (hangingProcessor) Process(ctx context.Context, _ []byte, _ core.TTSConfig) ([]byte, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+func TestFallbackProcessor_SharesDeadline(t *testing.T) {
+	t.Parallel()
+
+	secondary := &flakyProcessor{modelPath: "cpu.onnx", down: false, calls: 0, lastCfg: core.TTSConfig{}}
+
+	fallback := tts.NewFallback([]tts.FallbackStage{
+		{Name: "gpu", Processor: hangingProcessor{}, Timeout: time.Hour, Breaker: nil},
+		{Name: "cpu", Processor: secondary, Timeout: time.Hour, Breaker: nil},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	audio, err := fallback.Process(ctx, []byte("hello"), secondary.GetConfig())
+	require.NoError(t, err, "the hanging primary must leave time for the secondary")
+	assert.Equal(t, []byte("cpu.onnx"), audio)
+	assert.Less(t, time.Since(start), 1500*time.Millisecond, "the primary should only get its share of the deadline")
+}
+
+func TestFallbackProcessor_CancelledJobsKeepBreakerClosed(t *testing.T) {
+	t.Parallel()
+
+	breaker := tts.NewCircuitBreaker(1, time.Hour)
+	fallback := tts.NewFallback([]tts.FallbackStage{
+		{Name: "gpu", Processor: hangingProcessor{}, Timeout: 0, Breaker: breaker},
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	_, err := fallback.Process(ctx, []byte("hello"), core.TTSConfig{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.NoError(t, breaker.Allow(), "a job deadline says nothing about the backend")
+}
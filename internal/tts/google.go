@@ -0,0 +1,176 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// DefaultGoogleEndpoint is the Google Cloud Text-to-Speech synthesize endpoint.
+const DefaultGoogleEndpoint = "https://texttospeech.googleapis.com/v1/text:synthesize"
+
+// googleAudioEncoding requests 16-bit PCM, which Google returns with a WAV header.
+const googleAudioEncoding = "LINEAR16"
+
+// Google provider errors.
+var (
+	ErrUnmappedVoice   = errors.New("voice has no provider mapping")
+	ErrMissingAPIKey   = errors.New("provider API key is not set")
+	ErrEmptyAudioReply = errors.New("provider returned no audio")
+)
+
+// GoogleConfig configures the Google Cloud Text-to-Speech adapter.
+type GoogleConfig struct {
+	// Endpoint is the synthesize URL. Empty selects DefaultGoogleEndpoint.
+	Endpoint string
+	// APIKey authenticates the requests.
+	APIKey string
+	// LanguageCode is the BCP-47 language of the voices, e.g. "en-US".
+	LanguageCode string
+	// Voices maps the service's voice names to Google voice names.
+	Voices map[string]string
+	// Timeout bounds each synthesize request.
+	Timeout time.Duration
+}
+
+// GoogleProcessor implements the core.TTSProcessor interface with the Google
+// Cloud Text-to-Speech API, so jobs can burst to the cloud when local capacity
+// is saturated.
+type GoogleProcessor struct {
+	httpClient *http.Client
+	provider   GoogleConfig
+	config     core.TTSConfig
+}
+
+// NewGoogle creates a new GoogleProcessor. cfg carries the default voice.
+func NewGoogle(provider GoogleConfig, cfg core.TTSConfig) (*GoogleProcessor, error) {
+	if provider.APIKey == "" {
+		return nil, ErrMissingAPIKey
+	}
+
+	if provider.Endpoint == "" {
+		provider.Endpoint = DefaultGoogleEndpoint
+	}
+
+	return &GoogleProcessor{
+		httpClient: &http.Client{
+			Transport:     nil,
+			CheckRedirect: nil,
+			Jar:           nil,
+			Timeout:       provider.Timeout,
+		},
+		provider: provider,
+		config:   cfg,
+	}, nil
+}
+
+// GetConfig returns the TTS configuration.
+func (p *GoogleProcessor) GetConfig() core.TTSConfig {
+	return p.config
+}
+
+type googleSynthesizeRequest struct {
+	Input       googleInput       `json:"input"`
+	Voice       googleVoice       `json:"voice"`
+	AudioConfig googleAudioConfig `json:"audioConfig"`
+}
+
+type googleInput struct {
+	Text string `json:"text"`
+}
+
+type googleVoice struct {
+	LanguageCode string `json:"languageCode"`
+	Name         string `json:"name"`
+}
+
+type googleAudioConfig struct {
+	AudioEncoding string `json:"audioEncoding"`
+}
+
+type googleSynthesizeResponse struct {
+	AudioContent string `json:"audioContent"`
+}
+
+// ValidateConfig checks that the job's voice maps to a Google voice.
+func (p *GoogleProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	_, ok := p.provider.Voices[cfg.Voice]
+	if !ok {
+		return fmt.Errorf("%w: '%s'", ErrUnmappedVoice, cfg.Voice)
+	}
+
+	return nil
+}
+
+// Process takes text and returns WAV audio synthesized by Google.
+func (p *GoogleProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	voiceName, ok := p.provider.Voices[cfg.Voice]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnmappedVoice, cfg.Voice)
+	}
+
+	requestBody, err := json.Marshal(googleSynthesizeRequest{
+		Input:       googleInput{Text: string(text)},
+		Voice:       googleVoice{LanguageCode: p.provider.LanguageCode, Name: voiceName},
+		AudioConfig: googleAudioConfig{AudioEncoding: googleAudioEncoding},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.provider.Endpoint, bytes.NewReader(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	httpReq.Header.Set(headerContentType, contentTypeJSON)
+	httpReq.Header.Set("X-Goog-Api-Key", p.provider.APIKey)
+
+	resp, err := p.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request to Google TTS: %w", err)
+	}
+
+	defer func() {
+		closeErr := resp.Body.Close()
+		if closeErr != nil {
+			log.Printf("Warning: failed to close response body: %v", closeErr)
+		}
+	}()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read Google TTS response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newServiceNonOKStatusError(resp.Status, string(body))
+	}
+
+	var response googleSynthesizeResponse
+
+	err = json.Unmarshal(body, &response)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Google TTS response: %w", err)
+	}
+
+	audioData, err := base64.StdEncoding.DecodeString(response.AudioContent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode Google TTS audio: %w", err)
+	}
+
+	if len(audioData) == 0 {
+		return nil, ErrEmptyAudioReply
+	}
+
+	return audioData, nil
+}
@@ -0,0 +1,110 @@
+package tts_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newGoogleTestConfig(endpoint string) tts.GoogleConfig {
+	return tts.GoogleConfig{
+		Endpoint:     endpoint,
+		APIKey:       "secret",
+		LanguageCode: "en-US",
+		Voices:       map[string]string{"female1": "en-US-Neural2-F"},
+		Timeout:      5 * time.Second,
+	}
+}
+
+func newGoogleJobConfig(voice string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "cloud",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+func TestGoogleProcessor_Process(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "secret", request.Header.Get("X-Goog-Api-Key"))
+
+		var body map[string]map[string]string
+
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+		assert.Equal(t, "hello", body["input"]["text"])
+		assert.Equal(t, "en-US-Neural2-F", body["voice"]["name"])
+		assert.Equal(t, "LINEAR16", body["audioConfig"]["audioEncoding"])
+
+		_ = json.NewEncoder(writer).Encode(map[string]string{
+			"audioContent": base64.StdEncoding.EncodeToString([]byte("RIFF audio")),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	processor, err := tts.NewGoogle(newGoogleTestConfig(server.URL), newGoogleJobConfig("female1"))
+	require.NoError(t, err)
+
+	audio, err := processor.Process(context.Background(), []byte("hello"), newGoogleJobConfig("female1"))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF audio"), audio)
+
+	_, err = processor.Process(context.Background(), []byte("hello"), newGoogleJobConfig("male1"))
+	require.ErrorIs(t, err, tts.ErrUnmappedVoice)
+}
+
+func TestGoogleProcessor_Errors(t *testing.T) {
+	t.Parallel()
+
+	_, err := tts.NewGoogle(tts.GoogleConfig{
+		Endpoint:     "",
+		APIKey:       "",
+		LanguageCode: "en-US",
+		Voices:       nil,
+		Timeout:      0,
+	}, newGoogleJobConfig("female1"))
+	require.ErrorIs(t, err, tts.ErrMissingAPIKey)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		http.Error(writer, `{"error": {"message": "quota exceeded"}}`, http.StatusTooManyRequests)
+	}))
+	t.Cleanup(server.Close)
+
+	processor, err := tts.NewGoogle(newGoogleTestConfig(server.URL), newGoogleJobConfig("female1"))
+	require.NoError(t, err)
+
+	_, err = processor.Process(context.Background(), []byte("hello"), newGoogleJobConfig("female1"))
+	require.ErrorIs(t, err, tts.ErrServiceNonOKStatus)
+	assert.Contains(t, err.Error(), "quota exceeded")
+}
+
+func TestGoogleProcessor_ValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	processor, err := tts.NewGoogle(newGoogleTestConfig(""), newGoogleJobConfig("female1"))
+	require.NoError(t, err)
+
+	require.NoError(t, processor.ValidateConfig(newGoogleJobConfig("female1")))
+	require.ErrorIs(t, processor.ValidateConfig(newGoogleJobConfig("male1")), tts.ErrUnmappedVoice)
+}
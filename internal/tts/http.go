@@ -0,0 +1,51 @@
+package tts
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// HTTPProcessor implements the core.TTSProcessor interface with a standalone
+// TTS HTTP service, through an HTTPClient that carries its circuit breaker and
+// rate limits.
+type HTTPProcessor struct {
+	client *HTTPClient
+	model  string
+	config core.TTSConfig
+}
+
+// NewHTTPProcessor creates an HTTPProcessor. model is the service's model name;
+// empty uses the service default. cfg carries the default voice.
+func NewHTTPProcessor(client *HTTPClient, model string, cfg core.TTSConfig) *HTTPProcessor {
+	return &HTTPProcessor{
+		client: client,
+		model:  model,
+		config: cfg,
+	}
+}
+
+// GetConfig returns the TTS configuration.
+func (p *HTTPProcessor) GetConfig() core.TTSConfig {
+	return p.config
+}
+
+// Process sends the text to the service and returns its WAV audio.
+func (p *HTTPProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	audio, err := p.client.GenerateSpeech(ctx, Request{
+		Text:           string(text),
+		Voice:          cfg.Voice,
+		SpeakerRefPath: "",
+		Language:       cfg.Language,
+		Model:          p.model,
+		Temperature:    cfg.Temperature,
+		Seed:           cfg.Seed,
+		Style:          cfg.Style,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate speech over HTTP: %w", err)
+	}
+
+	return audio, nil
+}
@@ -0,0 +1,110 @@
+package tts_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPProcessor_Process(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var body tts.Request
+
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+		assert.Equal(t, "hello", body.Text)
+		assert.Equal(t, "xtts", body.Model)
+		assert.Equal(t, "de", body.Language)
+		assert.Equal(t, "calm", body.Style)
+
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             "default",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0.7,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+
+	processor := tts.NewHTTPProcessor(tts.NewHTTPClient(server.URL, 5*time.Second), "xtts", cfg)
+	assert.Equal(t, cfg, processor.GetConfig())
+
+	job := cfg
+	job.Language = "de"
+	job.Style = "calm"
+
+	audio, err := processor.Process(context.Background(), []byte("hello"), job)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("RIFF"), audio)
+}
+
+// TestHTTPProcessor_SendsSeed checks that the job's seed is forwarded to the
+// service in the request body, and left out when it is zero.
+func TestHTTPProcessor_SendsSeed(t *testing.T) {
+	t.Parallel()
+
+	bodies := make(chan map[string]any, 2)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var body map[string]any
+
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&body))
+
+		bodies <- body
+
+		writer.Header().Set("Content-Type", "audio/wav")
+		_, _ = writer.Write([]byte("RIFF"))
+	}))
+	t.Cleanup(server.Close)
+
+	job := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             "default",
+		Seed:              7,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+
+	processor := tts.NewHTTPProcessor(tts.NewHTTPClient(server.URL, 5*time.Second), "", job)
+
+	_, err := processor.Process(context.Background(), []byte("hello"), job)
+	require.NoError(t, err)
+	assert.InDelta(t, 7, (<-bodies)["seed"], 0, "the seed reaches the request body")
+
+	job.Seed = 0
+
+	_, err = processor.Process(context.Background(), []byte("hello"), job)
+	require.NoError(t, err)
+	assert.NotContains(t, <-bodies, "seed", "a zero seed leaves the choice to the service")
+}
@@ -0,0 +1,32 @@
+package tts
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrRuntimeExceeded is returned when chatllm is killed for running longer
+// than ResourceLimits.MaxRuntime.
+var ErrRuntimeExceeded = errors.New("chatllm exceeded its maximum runtime")
+
+// ResourceLimits bound the resources of each chatllm process, so a runaway
+// inference cannot take down the host. Zero fields leave a resource unlimited.
+type ResourceLimits struct {
+	// Nice is the scheduling niceness of the process, 0 to 19; higher runs
+	// at a lower priority than the service.
+	Nice int
+	// MaxMemoryBytes caps the address space of the process. GPU drivers
+	// reserve large address ranges, so it suits CPU inference.
+	MaxMemoryBytes uint64
+	// MaxCPUSeconds caps the CPU time of the process; the kernel kills it
+	// when the limit is reached.
+	MaxCPUSeconds uint64
+	// MaxRuntime kills the process when it runs longer, even when the job's
+	// own deadline is later.
+	MaxRuntime time.Duration
+}
+
+// isZero reports whether no limit is set on the process itself.
+func (l ResourceLimits) isZero() bool {
+	return l.Nice == 0 && l.MaxMemoryBytes == 0 && l.MaxCPUSeconds == 0
+}
@@ -0,0 +1,33 @@
+package tts
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// apply sets the limits on the running process pid.
+func (l ResourceLimits) apply(pid int) error {
+	if l.Nice != 0 {
+		err := unix.Setpriority(unix.PRIO_PROCESS, pid, l.Nice)
+		if err != nil {
+			return fmt.Errorf("failed to set niceness %d: %w", l.Nice, err)
+		}
+	}
+
+	if l.MaxMemoryBytes != 0 {
+		err := unix.Prlimit(pid, unix.RLIMIT_AS, &unix.Rlimit{Cur: l.MaxMemoryBytes, Max: l.MaxMemoryBytes}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to limit memory to %d bytes: %w", l.MaxMemoryBytes, err)
+		}
+	}
+
+	if l.MaxCPUSeconds != 0 {
+		err := unix.Prlimit(pid, unix.RLIMIT_CPU, &unix.Rlimit{Cur: l.MaxCPUSeconds, Max: l.MaxCPUSeconds}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to limit CPU time to %d seconds: %w", l.MaxCPUSeconds, err)
+		}
+	}
+
+	return nil
+}
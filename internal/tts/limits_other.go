@@ -0,0 +1,14 @@
+//go:build !linux
+
+package tts
+
+import "errors"
+
+// ErrLimitsUnsupported is returned when process limits are set on a platform
+// that cannot apply them.
+var ErrLimitsUnsupported = errors.New("chatllm resource limits are only supported on Linux")
+
+// apply reports that the limits cannot be set on this platform.
+func (l ResourceLimits) apply(_ int) error {
+	return ErrLimitsUnsupported
+}
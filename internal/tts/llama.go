@@ -0,0 +1,30 @@
+package tts
+
+import "errors"
+
+// Default llama.cpp settings applied to zero-valued LlamaOptions fields.
+const (
+	DefaultLlamaContextSize = 8192
+	DefaultLlamaMaxTokens   = 4096
+)
+
+// llama.cpp errors.
+var (
+	ErrLlamaUnavailable = errors.New("llama.cpp support is not built in; rebuild with -tags llamacpp")
+	ErrLlamaModelLoad   = errors.New("llama.cpp failed to load model")
+	ErrLlamaContext     = errors.New("llama.cpp failed to create a context")
+	ErrLlamaTokenize    = errors.New("llama.cpp failed to tokenize the prompt")
+	ErrLlamaDecode      = errors.New("llama.cpp decode failed")
+	ErrNoAudioTokens    = errors.New("model generated no audio tokens")
+	ErrLlamaClosed      = errors.New("llama.cpp model is closed")
+)
+
+// LlamaOptions tunes the in-process llama.cpp backend.
+type LlamaOptions struct {
+	// ContextSize is the context length in tokens, prompt included.
+	ContextSize int
+	// MaxTokens caps the number of generated tokens per job.
+	MaxTokens int
+	// Threads is the number of CPU threads; zero uses the llama.cpp default.
+	Threads int
+}
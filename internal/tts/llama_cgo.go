@@ -0,0 +1,275 @@
+//go:build llamacpp
+
+package tts
+
+/*
+#cgo LDFLAGS: -lllama
+#include <stdlib.h>
+#include <llama.h>
+*/
+import "C"
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"unsafe"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/snac"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Orpheus prompt and stop tokens in the Llama 3 vocabulary.
+const (
+	orpheusStartOfHuman  = 128259
+	orpheusEndOfText     = 128009
+	orpheusEndOfHuman    = 128260
+	orpheusStartOfAI     = 128261
+	orpheusStartOfSpeech = 128257
+	orpheusEndOfSpeech   = 128258
+
+	// orpheusPenaltyWindow is how many recent tokens the repetition penalty covers.
+	orpheusPenaltyWindow = 64
+)
+
+var llamaBackendOnce sync.Once
+
+// LlamaProcessor implements the core.TTSProcessor interface in-process: an
+// Orpheus model runs through llama.cpp and the generated SNAC codes are decoded
+// to audio in Go, so no external binary is spawned.
+//
+// The model is loaded once; every job gets its own llama.cpp context, so jobs
+// may run concurrently. NGL is fixed when the model is loaded. Close frees the
+// model.
+type LlamaProcessor struct {
+	config  core.TTSConfig
+	options LlamaOptions
+	// mu guards model and vocab: jobs hold it for reading, Close for writing.
+	mu      sync.RWMutex
+	model   *C.struct_llama_model
+	vocab   *C.struct_llama_vocab
+	decoder *snac.Decoder
+	log     *logger.Logger
+}
+
+// NewLlama loads cfg.ModelPath (an Orpheus GGUF) with cfg.NGL layers on the GPU
+// and the SNAC decoder from cfg.SnacModelPath (a safetensors export of
+// hubertsiuzdak/snac_24khz).
+func NewLlama(cfg core.TTSConfig, options LlamaOptions, log *logger.Logger) (core.TTSProcessor, error) {
+	llamaBackendOnce.Do(func() {
+		C.llama_backend_init()
+	})
+
+	decoder, err := snac.Load(cfg.SnacModelPath, snac.Config24kHz())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load SNAC decoder '%s': %w", cfg.SnacModelPath, err)
+	}
+
+	params := C.llama_model_default_params()
+	params.n_gpu_layers = C.int32_t(cfg.NGL)
+
+	modelPath := C.CString(cfg.ModelPath)
+	defer C.free(unsafe.Pointer(modelPath))
+
+	model := C.llama_model_load_from_file(modelPath, params)
+	if model == nil {
+		return nil, fmt.Errorf("%w: '%s'", ErrLlamaModelLoad, cfg.ModelPath)
+	}
+
+	log.Info("Loaded llama.cpp model %s with NGL %d", cfg.ModelPath, cfg.NGL)
+
+	return &LlamaProcessor{
+		config:  cfg,
+		options: options.withDefaults(),
+		mu:      sync.RWMutex{},
+		model:   model,
+		vocab:   C.llama_model_get_vocab(model),
+		decoder: decoder,
+		log:     log,
+	}, nil
+}
+
+// withDefaults fills zero-valued options.
+func (o LlamaOptions) withDefaults() LlamaOptions {
+	if o.ContextSize <= 0 {
+		o.ContextSize = DefaultLlamaContextSize
+	}
+
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = DefaultLlamaMaxTokens
+	}
+
+	return o
+}
+
+// GetConfig returns the TTS configuration.
+func (p *LlamaProcessor) GetConfig() core.TTSConfig {
+	return p.config
+}
+
+// Close frees the model once the jobs running on it have finished. Jobs that
+// start after it fail with ErrLlamaClosed.
+func (p *LlamaProcessor) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.model != nil {
+		C.llama_model_free(p.model)
+		p.model = nil
+		p.vocab = nil
+
+		p.log.Info("Freed llama.cpp model %s", p.config.ModelPath)
+	}
+
+	return nil
+}
+
+// Process generates Orpheus audio tokens for the text, decodes them with SNAC
+// and returns a 16-bit PCM WAV file.
+func (p *LlamaProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	tokens, err := p.tokens(ctx, text, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := OrpheusCodes(tokens)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(codes[0]) == 0 {
+		return nil, ErrNoAudioTokens
+	}
+
+	seed := uint64(cfg.Seed) // #nosec G115 -- any seed value is fine for the noise generator
+
+	samples, err := p.decoder.Decode(codes, rand.New(rand.NewPCG(seed, seed))) // #nosec G404 -- synthesis noise, not security
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode SNAC codes: %w", err)
+	}
+
+	return wav.EncodePCM16(samples, p.decoder.SampleRate()), nil
+}
+
+// tokens generates the Orpheus tokens of the text while holding the model.
+func (p *LlamaProcessor) tokens(ctx context.Context, text []byte, cfg core.TTSConfig) ([]int32, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.model == nil {
+		return nil, ErrLlamaClosed
+	}
+
+	prompt, err := p.promptTokens(cfg.Voice, text)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.generate(ctx, prompt, cfg)
+}
+
+// promptTokens builds the Orpheus prompt: the tokenized "voice: text" between
+// the human and speech markers.
+func (p *LlamaProcessor) promptTokens(voice string, text []byte) ([]C.llama_token, error) {
+	prompt := voice + ": " + sanitizePromptText(text)
+
+	cPrompt := C.CString(prompt)
+	defer C.free(unsafe.Pointer(cPrompt))
+
+	// A token never covers less than one byte, plus BOS.
+	capacity := len(prompt) + 1
+	tokens := make([]C.llama_token, capacity)
+
+	count := C.llama_tokenize(p.vocab, cPrompt, C.int32_t(len(prompt)),
+		&tokens[0], C.int32_t(capacity), C.bool(true), C.bool(false))
+	if count < 0 {
+		return nil, fmt.Errorf("%w: %d tokens needed", ErrLlamaTokenize, -count)
+	}
+
+	result := make([]C.llama_token, 0, int(count)+5)
+	result = append(result, orpheusStartOfHuman)
+	result = append(result, tokens[:count]...)
+
+	return append(result, orpheusEndOfText, orpheusEndOfHuman, orpheusStartOfAI, orpheusStartOfSpeech), nil
+}
+
+// generate samples tokens after the prompt until the end of speech, the token
+// limit, or the context is done.
+func (p *LlamaProcessor) generate(ctx context.Context, prompt []C.llama_token, cfg core.TTSConfig) ([]int32, error) {
+	if len(prompt)+1 >= p.options.ContextSize {
+		return nil, fmt.Errorf("%w: prompt of %d tokens does not fit a context of %d",
+			ErrLlamaTokenize, len(prompt), p.options.ContextSize)
+	}
+
+	params := C.llama_context_default_params()
+	params.n_ctx = C.uint32_t(p.options.ContextSize)
+	params.n_batch = C.uint32_t(max(len(prompt), int(params.n_batch)))
+	params.no_perf = C.bool(true)
+
+	if p.options.Threads > 0 {
+		params.n_threads = C.int32_t(p.options.Threads)
+		params.n_threads_batch = C.int32_t(p.options.Threads)
+	}
+
+	llamaCtx := C.llama_init_from_model(p.model, params)
+	if llamaCtx == nil {
+		return nil, ErrLlamaContext
+	}
+	defer C.llama_free(llamaCtx)
+
+	sampler := newOrpheusSampler(cfg)
+	defer C.llama_sampler_free(sampler)
+
+	// Batches point into C memory, since llama_decode reads the tokens after the call starts.
+	size := C.size_t(unsafe.Sizeof(C.llama_token(0)))
+
+	buffer := (*C.llama_token)(C.malloc(C.size_t(len(prompt)) * size))
+	defer C.free(unsafe.Pointer(buffer))
+
+	copy(unsafe.Slice(buffer, len(prompt)), prompt)
+
+	batch := C.llama_batch_get_one(buffer, C.int32_t(len(prompt)))
+	limit := min(p.options.MaxTokens, p.options.ContextSize-len(prompt))
+	generated := make([]int32, 0, limit)
+
+	for len(generated) < limit {
+		err := ctx.Err()
+		if err != nil {
+			return nil, fmt.Errorf("llama.cpp generation cancelled: %w", err)
+		}
+
+		status := C.llama_decode(llamaCtx, batch)
+		if status != 0 {
+			return nil, fmt.Errorf("%w: status %d", ErrLlamaDecode, int(status))
+		}
+
+		token := C.llama_sampler_sample(sampler, llamaCtx, -1)
+		if token == orpheusEndOfSpeech || bool(C.llama_vocab_is_eog(p.vocab, token)) {
+			break
+		}
+
+		generated = append(generated, int32(token))
+
+		*buffer = token
+		batch = C.llama_batch_get_one(buffer, 1)
+	}
+
+	return generated, nil
+}
+
+// newOrpheusSampler chains the repetition penalty, top-p, temperature and a
+// seeded distribution sampler.
+func newOrpheusSampler(cfg core.TTSConfig) *C.struct_llama_sampler {
+	chain := C.llama_sampler_chain_init(C.llama_sampler_chain_default_params())
+
+	C.llama_sampler_chain_add(chain, C.llama_sampler_init_penalties(
+		orpheusPenaltyWindow, C.float(cfg.RepetitionPenalty), 0, 0))
+	C.llama_sampler_chain_add(chain, C.llama_sampler_init_top_p(C.float(cfg.TopP), 1))
+	C.llama_sampler_chain_add(chain, C.llama_sampler_init_temp(C.float(cfg.Temperature)))
+	C.llama_sampler_chain_add(chain, C.llama_sampler_init_dist(C.uint32_t(cfg.Seed))) // #nosec G115 -- seeds wrap
+
+	return chain
+}
@@ -0,0 +1,13 @@
+//go:build !llamacpp
+
+package tts
+
+import (
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// NewLlama reports that the binary was built without llama.cpp support.
+func NewLlama(_ core.TTSConfig, _ LlamaOptions, _ *logger.Logger) (core.TTSProcessor, error) {
+	return nil, ErrLlamaUnavailable
+}
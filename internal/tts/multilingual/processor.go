@@ -0,0 +1,148 @@
+package multilingual
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Processor wraps a core.TTSProcessor and synthesizes each language run of a
+// text with the model and voice its language resolves to: the text is split
+// with a Splitter, runs in the job's language use the job's configuration, and
+// the WAV results are joined in order, each matched to the format and loudness
+// of the first. Text in a single language is passed through.
+type Processor struct {
+	inner     core.TTSProcessor
+	languages core.LanguageResolver
+	splitter  Splitter
+}
+
+// NewProcessor creates a mixed-language wrapper around inner that resolves
+// foreign runs with languages and detects them by the scripts of NewSplitter.
+func NewProcessor(inner core.TTSProcessor, languages core.LanguageResolver, scripts map[string]string) *Processor {
+	return &Processor{
+		inner:     inner,
+		languages: languages,
+		splitter:  NewSplitter(scripts),
+	}
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig checks the job with the wrapped processor, when it validates
+// jobs. Foreign runs are checked when they are resolved.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateConfig(cfg)
+}
+
+// Process synthesizes each language run and joins the audio.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	runs := p.runs(string(text), cfg.Language)
+	if len(runs) == 0 || (len(runs) == 1 && runs[0].Language == "" && !Tagged(string(text))) {
+		return p.inner.Process(ctx, text, cfg)
+	}
+
+	var (
+		joined   wav.Audio
+		loudness float64
+	)
+
+	for i, run := range runs {
+		runCfg, err := p.config(cfg, run.Language)
+		if err != nil {
+			return nil, fmt.Errorf("run %d of %d: %w", i+1, len(runs), err)
+		}
+
+		data, err := p.inner.Process(ctx, []byte(run.Text), runCfg)
+		if err != nil {
+			return nil, fmt.Errorf("synthesizing run %d of %d in language '%s': %w",
+				i+1, len(runs), runCfg.Language, err)
+		}
+
+		decoded, err := wav.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode run %d of %d: %w", i+1, len(runs), err)
+		}
+
+		if i == 0 {
+			joined = decoded
+			loudness = audio.Loudness(decoded)
+
+			continue
+		}
+
+		// Models of different languages may differ in format and level; follow the first run.
+		decoded, err = audio.Remix(audio.Resample(decoded, joined.SampleRate), joined.Channels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join run %d of %d: %w", i+1, len(runs), err)
+		}
+
+		if !math.IsInf(loudness, -1) {
+			decoded, _ = audio.Normalize(decoded, loudness, audio.DefaultTruePeakDB)
+		}
+
+		joined.Samples = append(joined.Samples, decoded.Samples...)
+	}
+
+	return wav.Encode(joined), nil
+}
+
+// runs splits text by language, treating runs in the job's language as
+// untagged and merging consecutive runs that end up in the same language.
+func (p *Processor) runs(text, jobLanguage string) []Run {
+	var runs []Run
+
+	for _, run := range p.splitter.Split(text) {
+		if strings.EqualFold(run.Language, jobLanguage) {
+			run.Language = ""
+		}
+
+		if len(runs) > 0 && strings.EqualFold(runs[len(runs)-1].Language, run.Language) {
+			runs[len(runs)-1].Text += " " + run.Text
+
+			continue
+		}
+
+		runs = append(runs, run)
+	}
+
+	return runs
+}
+
+// config returns the configuration of a run in language: the job's for its
+// own language, and otherwise the job's with the model, paths and voice the
+// language resolves to.
+func (p *Processor) config(cfg core.TTSConfig, language string) (core.TTSConfig, error) {
+	if language == "" {
+		return cfg, nil
+	}
+
+	base, err := p.languages.ResolveLanguage(language)
+	if err != nil {
+		return core.TTSConfig{}, fmt.Errorf("failed to resolve language: %w", err)
+	}
+
+	cfg.Model = base.Model
+	cfg.ModelPath = base.ModelPath
+	cfg.SnacModelPath = base.SnacModelPath
+	cfg.Language = language
+
+	if base.Voice != "" {
+		cfg.Voice = base.Voice
+	}
+
+	return cfg, nil
+}
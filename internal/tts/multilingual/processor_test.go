@@ -0,0 +1,114 @@
+package multilingual_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/multilingual"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnsupported = errors.New("unsupported")
+
+// jobConfig returns an English job configuration for the default model.
+func jobConfig() core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         "english.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "tara",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "en",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+// resolver serves Russian with a model of its own.
+type resolver struct{}
+
+func (resolver) ResolveLanguage(language string) (core.TTSConfig, error) {
+	if language != "ru" {
+		return core.TTSConfig{}, errUnsupported
+	}
+
+	cfg := jobConfig()
+	cfg.Model = "russian"
+	cfg.ModelPath = "russian.gguf"
+	cfg.Voice = "irina"
+
+	return cfg, nil
+}
+
+// recordingProcessor returns one frame of audio per byte of text, at 22.05 kHz
+// and half the level for the Russian model, and records its calls.
+type recordingProcessor struct {
+	mu    sync.Mutex
+	calls []core.TTSConfig
+	texts []string
+}
+
+func (p *recordingProcessor) GetConfig() core.TTSConfig {
+	return jobConfig()
+}
+
+func (p *recordingProcessor) Process(_ context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, cfg)
+	p.texts = append(p.texts, string(text))
+	p.mu.Unlock()
+
+	rate, level := 24000, float32(0.4)
+	if cfg.Model == "russian" {
+		rate, level = 22050, 0.2
+	}
+
+	samples := make([]float32, len(text)*rate/100)
+	for i := range samples {
+		samples[i] = level * float32(i%20-10) / 10
+	}
+
+	return wav.EncodePCM16(samples, rate), nil
+}
+
+func TestProcessor_Process(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil, texts: nil}
+	processor := multilingual.NewProcessor(inner, resolver{}, map[string]string{"Cyrillic": "ru"})
+
+	data, err := processor.Process(context.Background(), []byte("He said «Да» twice."), jobConfig())
+	require.NoError(t, err)
+
+	require.Len(t, inner.calls, 3)
+	assert.Equal(t, []string{"He said «", "Да»", "twice."}, inner.texts)
+	assert.Equal(t, "", inner.calls[0].Model)
+	assert.Equal(t, "tara", inner.calls[0].Voice)
+	assert.Equal(t, "russian", inner.calls[1].Model)
+	assert.Equal(t, "russian.gguf", inner.calls[1].ModelPath)
+	assert.Equal(t, "irina", inner.calls[1].Voice)
+	assert.Equal(t, "ru", inner.calls[1].Language)
+
+	joined, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, 24000, joined.SampleRate, "runs follow the format of the first")
+
+	// Text in the job's language is passed through untouched.
+	_, err = processor.Process(context.Background(), []byte("  Only English.  "), jobConfig())
+	require.NoError(t, err)
+	assert.Equal(t, "  Only English.  ", inner.texts[3])
+
+	_, err = processor.Process(context.Background(), []byte(`Say <lang xml:lang="de">Hallo</lang>.`), jobConfig())
+	require.ErrorIs(t, err, errUnsupported)
+}
@@ -0,0 +1,145 @@
+// Package multilingual splits text that switches language into runs, such as
+// a quotation in Russian inside an English book, and synthesizes each run with
+// the model and voice of its language.
+package multilingual
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Run is a stretch of text in one language.
+type Run struct {
+	Text string
+	// Language is the language code of the run; empty for the job's language.
+	Language string
+}
+
+// langTag matches an SSML lang element, which marks a phrase in a language
+// that cannot be told apart by its script, such as French in English text.
+var langTag = regexp.MustCompile(`(?s)<lang\s+xml:lang\s*=\s*["']([A-Za-z0-9-]+)["']\s*>(.*?)</lang>`)
+
+// Splitter detects language runs by the Unicode script of their letters.
+type Splitter struct {
+	scripts []script
+}
+
+// script maps the letters of one Unicode script to a language.
+type script struct {
+	table    *unicode.RangeTable
+	language string
+}
+
+// NewSplitter creates a Splitter that assigns letters of each script in
+// scripts, named as in unicode.Scripts (e.g. "Cyrillic"), to its language
+// code. Letters of other scripts belong to the job's language, and unknown
+// script names are ignored.
+func NewSplitter(scripts map[string]string) Splitter {
+	var splitter Splitter
+
+	for name, language := range scripts {
+		table, ok := unicode.Scripts[name]
+		if ok {
+			splitter.scripts = append(splitter.scripts, script{table: table, language: language})
+		}
+	}
+
+	return splitter
+}
+
+// Split returns the language runs of text. Text inside a lang element is one
+// run in the element's language; elsewhere a run ends at the first letter of
+// another language, so spaces, digits and punctuation stay with the text
+// before them, except before the first letter. Adjacent runs in the same
+// language are merged, and runs without letters or digits are dropped.
+func (s Splitter) Split(text string) []Run {
+	var runs []Run
+
+	position := 0
+
+	for _, match := range langTag.FindAllStringSubmatchIndex(text, -1) {
+		runs = append(runs, s.detect(text[position:match[0]])...)
+		runs = append(runs, Run{Text: text[match[4]:match[5]], Language: text[match[2]:match[3]]})
+		position = match[1]
+	}
+
+	runs = append(runs, s.detect(text[position:])...)
+
+	return merge(runs)
+}
+
+// Tagged reports whether text marks any phrase with a lang element.
+func Tagged(text string) bool {
+	return langTag.MatchString(text)
+}
+
+// detect splits untagged text at changes of script language.
+func (s Splitter) detect(text string) []Run {
+	var (
+		runs    []Run
+		current strings.Builder
+	)
+
+	language := ""
+	lettered := false
+
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			next := s.language(r)
+			if next != language && lettered {
+				runs = append(runs, Run{Text: current.String(), Language: language})
+				current.Reset()
+			}
+
+			language = next
+			lettered = true
+		}
+
+		current.WriteRune(r)
+	}
+
+	if current.Len() > 0 {
+		runs = append(runs, Run{Text: current.String(), Language: language})
+	}
+
+	return runs
+}
+
+// language returns the language of a letter.
+func (s Splitter) language(r rune) string {
+	for _, script := range s.scripts {
+		if unicode.Is(script.table, r) {
+			return script.language
+		}
+	}
+
+	return ""
+}
+
+// merge joins adjacent runs in the same language, trims them and drops those
+// without letters or digits.
+func merge(runs []Run) []Run {
+	var merged []Run
+
+	for _, run := range runs {
+		run.Text = strings.TrimSpace(run.Text)
+		if strings.IndexFunc(run.Text, isWordRune) < 0 {
+			continue
+		}
+
+		if len(merged) > 0 && strings.EqualFold(merged[len(merged)-1].Language, run.Language) {
+			merged[len(merged)-1].Text += " " + run.Text
+
+			continue
+		}
+
+		merged = append(merged, run)
+	}
+
+	return merged
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
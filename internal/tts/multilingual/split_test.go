@@ -0,0 +1,54 @@
+package multilingual_test
+
+import (
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts/multilingual"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitter_Split(t *testing.T) {
+	t.Parallel()
+
+	splitter := multilingual.NewSplitter(map[string]string{"Cyrillic": "ru", "Greek": "el", "Klingon": "tlh"})
+
+	for name, test := range map[string]struct {
+		text string
+		want []multilingual.Run
+	}{
+		"one language": {
+			text: "He waited. Nothing happened.",
+			want: []multilingual.Run{{Text: "He waited. Nothing happened.", Language: ""}},
+		},
+		"script change": {
+			text: "She whispered «Привет, мир!» and left, 2 hours later.",
+			want: []multilingual.Run{
+				{Text: "She whispered «", Language: ""},
+				{Text: "Привет, мир!»", Language: "ru"},
+				{Text: "and left, 2 hours later.", Language: ""},
+			},
+		},
+		"two scripts": {
+			text: "Λόγος и слово",
+			want: []multilingual.Run{{Text: "Λόγος", Language: "el"}, {Text: "и слово", Language: "ru"}},
+		},
+		"lang element": {
+			text: `The sign read <lang xml:lang="fr">Défense de fumer</lang>. He ignored it.`,
+			want: []multilingual.Run{
+				{Text: "The sign read", Language: ""},
+				{Text: "Défense de fumer", Language: "fr"},
+				{Text: ". He ignored it.", Language: ""},
+			},
+		},
+		"same language merged": {
+			text: `<lang xml:lang='ru'>Да</lang> — да, конечно.`,
+			want: []multilingual.Run{{Text: "Да — да, конечно.", Language: "ru"}},
+		},
+		"no words": {
+			text: " ... ",
+			want: nil,
+		},
+	} {
+		assert.Equal(t, test.want, splitter.Split(test.text), name)
+	}
+}
@@ -0,0 +1,56 @@
+package tts
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Orpheus audio token layout in the Llama 3 vocabulary.
+const (
+	// orpheusAudioTokenBase is the ID of <custom_token_10>, the first audio token.
+	orpheusAudioTokenBase = 128266
+	orpheusFrameTokens    = 7
+	orpheusCodebookSize   = 4096
+)
+
+// ErrInvalidAudioToken is returned when a generated audio token does not fit its position in a frame.
+var ErrInvalidAudioToken = errors.New("invalid Orpheus audio token")
+
+// orpheusFrameLayers maps each token position in a 7-token Orpheus frame to its
+// SNAC codebook: one coarse code, two medium codes and four fine codes.
+var orpheusFrameLayers = [orpheusFrameTokens]int{0, 1, 2, 2, 1, 2, 2}
+
+// OrpheusCodes converts the audio tokens generated by an Orpheus model into
+// codes for the three SNAC codebooks. Tokens below the audio range are skipped
+// and an incomplete trailing frame is dropped.
+func OrpheusCodes(tokens []int32) ([][]int, error) {
+	audio := make([]int, 0, len(tokens))
+
+	for _, token := range tokens {
+		if token >= orpheusAudioTokenBase {
+			audio = append(audio, int(token-orpheusAudioTokenBase))
+		}
+	}
+
+	frames := len(audio) / orpheusFrameTokens
+	codes := [][]int{
+		make([]int, 0, frames),
+		make([]int, 0, 2*frames),
+		make([]int, 0, 4*frames),
+	}
+
+	for i, value := range audio[:frames*orpheusFrameTokens] {
+		position := i % orpheusFrameTokens
+
+		code := value - position*orpheusCodebookSize
+		if code < 0 || code >= orpheusCodebookSize {
+			return nil, fmt.Errorf("%w: token %d at frame position %d", ErrInvalidAudioToken,
+				value+orpheusAudioTokenBase, position)
+		}
+
+		layer := orpheusFrameLayers[position]
+		codes[layer] = append(codes[layer], code)
+	}
+
+	return codes, nil
+}
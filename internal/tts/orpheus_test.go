@@ -0,0 +1,40 @@
+package tts_test
+
+import (
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// audioToken returns the Orpheus token for a code at a frame position.
+func audioToken(position, code int) int32 {
+	return int32(128266 + position*4096 + code)
+}
+
+func TestOrpheusCodes(t *testing.T) {
+	t.Parallel()
+
+	tokens := []int32{128257} // start of speech is skipped
+	for frame := range 2 {
+		for position := range 7 {
+			tokens = append(tokens, audioToken(position, 10*frame+position))
+		}
+	}
+
+	tokens = append(tokens, audioToken(0, 99)) // incomplete trailing frame
+
+	codes, err := tts.OrpheusCodes(tokens)
+	require.NoError(t, err)
+	assert.Equal(t, [][]int{
+		{0, 10},
+		{1, 4, 11, 14},
+		{2, 3, 5, 6, 12, 13, 15, 16},
+	}, codes)
+
+	tokens[3] = audioToken(0, 1) // a coarse code in a fine position
+
+	_, err = tts.OrpheusCodes(tokens)
+	require.ErrorIs(t, err, tts.ErrInvalidAudioToken)
+}
@@ -0,0 +1,114 @@
+package tts
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// outputTailLines is the number of trailing output lines kept for the error
+// of a failed run.
+const outputTailLines = 20
+
+// maxOutputLine bounds one line of backend output; longer lines are split.
+const maxOutputLine = 64 * 1024
+
+var (
+	// tokenRatePattern matches a generation rate such as "25.3 tokens per
+	// second" or "25.3 tok/s".
+	tokenRatePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*(?:tokens per second|tokens/s|tok/s)`)
+	// tokenCountPattern matches a token count such as "/ 200 tokens".
+	tokenCountPattern = regexp.MustCompile(`(?i)(\d+)\s+tokens\b`)
+)
+
+// outputScanner reads the output of a backend process line by line as it is
+// written. It logs the lines when asked to, reports the progress lines to the
+// job's core.ProgressFunc, and keeps the last lines for error messages.
+type outputScanner struct {
+	name     string
+	logLines bool
+	progress func(progress core.Progress)
+	log      *logger.Logger
+	tail     []string
+}
+
+// newOutputScanner creates a scanner for the output of the named process,
+// reporting progress to the core.ProgressFunc of ctx.
+func newOutputScanner(ctx context.Context, name string, logLines bool, log *logger.Logger) *outputScanner {
+	return &outputScanner{
+		name:     name,
+		logLines: logLines,
+		progress: func(progress core.Progress) {
+			core.ReportProgress(ctx, progress)
+		},
+		log:  log,
+		tail: nil,
+	}
+}
+
+// scan reads r until it is closed.
+func (s *outputScanner) scan(r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxOutputLine)
+
+	for scanner.Scan() {
+		s.line(scanner.Text())
+	}
+
+	// A line too long to scan ends the scan. Keep draining, or the process would block writing to a full pipe.
+	_, _ = io.Copy(io.Discard, r)
+}
+
+// line handles one line of output.
+func (s *outputScanner) line(line string) {
+	line = strings.TrimRight(line, "\r")
+	if strings.TrimSpace(line) == "" {
+		return
+	}
+
+	if s.logLines {
+		s.log.Info("%s: %s", s.name, line)
+	}
+
+	progress, ok := parseProgress(line)
+	if ok {
+		s.progress(progress)
+	}
+
+	s.tail = append(s.tail, line)
+	if len(s.tail) > outputTailLines {
+		s.tail = s.tail[len(s.tail)-outputTailLines:]
+	}
+}
+
+// output returns the last lines of output.
+func (s *outputScanner) output() string {
+	return strings.Join(s.tail, "\n")
+}
+
+// parseProgress reads the token count and rate from a progress or timing line,
+// e.g. "eval time = 8000.00 ms / 200 tokens (40.00 ms per token, 25.00 tokens
+// per second)". It reports false for lines that carry neither.
+func parseProgress(line string) (core.Progress, bool) {
+	var progress core.Progress
+
+	rate := tokenRatePattern.FindStringSubmatch(line)
+	if rate != nil {
+		progress.TokensPerSecond, _ = strconv.ParseFloat(rate[1], 64)
+		// The rate also ends in "tokens"; it is not a count.
+		line = strings.Replace(line, rate[0], "", 1)
+	}
+
+	count := tokenCountPattern.FindStringSubmatch(line)
+	if count != nil {
+		progress.Tokens, _ = strconv.Atoi(count[1])
+	}
+
+	return progress, rate != nil || count != nil
+}
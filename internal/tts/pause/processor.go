@@ -0,0 +1,153 @@
+package pause
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// silenceThreshold is the level below which samples at the edges of a part
+// count as the model's own silence: -60 dBFS.
+const silenceThreshold = 0.001
+
+// Processor wraps a core.TTSProcessor and paces its audio: the text is split
+// with Split, each part is synthesized on its own, and the WAV results are
+// joined in order, each matched to the format of the first, with the part's
+// pause before it. The model's silence at the edges where parts meet is
+// trimmed, so the pauses are what the rules say. Text with a single part and
+// no break element is passed through.
+type Processor struct {
+	inner core.TTSProcessor
+	rules Rules
+}
+
+// NewProcessor creates a pacing wrapper around inner. Zero rules use the
+// defaults.
+func NewProcessor(inner core.TTSProcessor, rules Rules) *Processor {
+	return &Processor{
+		inner: inner,
+		rules: rules.withDefaults(),
+	}
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig checks the job with the wrapped processor, when it validates
+// jobs.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateConfig(cfg)
+}
+
+// Process synthesizes each part and joins the audio with the pauses.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	parts := Split(string(text), p.rules)
+	if len(parts) == 0 || (len(parts) == 1 && parts[0].Pause == 0) {
+		return p.inner.Process(ctx, text, cfg)
+	}
+
+	var (
+		joined  wav.Audio
+		decoded = make([]wav.Audio, len(parts))
+		spoken  = 0
+	)
+
+	for i, part := range parts {
+		if part.Text == "" {
+			continue
+		}
+
+		data, err := p.inner.Process(ctx, []byte(part.Text), cfg)
+		if err != nil {
+			return nil, fmt.Errorf("synthesizing part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		decoded[i], err = wav.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		if spoken == 0 {
+			joined.SampleRate = decoded[i].SampleRate
+			joined.Channels = decoded[i].Channels
+		} else {
+			// Parts may be routed to different models; follow the first part.
+			decoded[i], err = audio.Remix(audio.Resample(decoded[i], joined.SampleRate), joined.Channels)
+			if err != nil {
+				return nil, fmt.Errorf("failed to join part %d of %d: %w", i+1, len(parts), err)
+			}
+		}
+
+		spoken++
+	}
+
+	if spoken == 0 {
+		return p.inner.Process(ctx, text, cfg)
+	}
+
+	seen := 0
+
+	for i, part := range parts {
+		joined.Samples = append(joined.Samples, silence(joined, part.Pause)...)
+
+		if part.Text == "" {
+			continue
+		}
+
+		seen++
+
+		joined.Samples = append(joined.Samples, trim(decoded[i], seen > 1, seen < spoken)...)
+	}
+
+	return wav.Encode(joined), nil
+}
+
+// silence returns the samples of duration of silence in the format of format.
+func silence(format wav.Audio, duration time.Duration) []float32 {
+	frames := int(duration.Seconds() * float64(format.SampleRate))
+
+	return make([]float32, frames*format.Channels)
+}
+
+// trim returns the samples of decoded without the quiet frames at its start
+// and end, as selected.
+func trim(decoded wav.Audio, start, end bool) []float32 {
+	frames := decoded.Frames()
+	first, last := 0, frames
+
+	if start {
+		for first < last && quiet(decoded, first) {
+			first++
+		}
+	}
+
+	if end {
+		for last > first && quiet(decoded, last-1) {
+			last--
+		}
+	}
+
+	return decoded.Samples[first*decoded.Channels : last*decoded.Channels]
+}
+
+// quiet reports whether every sample of frame is below silenceThreshold.
+func quiet(decoded wav.Audio, frame int) bool {
+	for _, sample := range decoded.Samples[frame*decoded.Channels : (frame+1)*decoded.Channels] {
+		if sample > silenceThreshold || sample < -silenceThreshold {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,123 @@
+package pause_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/pause"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errRejected = errors.New("rejected")
+
+// toneProcessor returns 100 frames of tone per character of text, with 50
+// frames of silence on either side, at 1000 Hz, and records the texts.
+type toneProcessor struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (p *toneProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *toneProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	if cfg.Voice == "unknown" {
+		return errRejected
+	}
+
+	return nil
+}
+
+func (p *toneProcessor) Process(_ context.Context, text []byte, _ core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	p.texts = append(p.texts, string(text))
+	p.mu.Unlock()
+
+	samples := make([]float32, 50+100*len(text)+50)
+	for i := 50; i < len(samples)-50; i++ {
+		samples[i] = 0.5
+	}
+
+	return wav.EncodePCM16(samples, 1000), nil
+}
+
+// jobConfig returns a job configuration with the given voice.
+func jobConfig(voice string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+func TestProcessor_InsertsPauses(t *testing.T) {
+	t.Parallel()
+
+	inner := &toneProcessor{mu: sync.Mutex{}, texts: nil}
+	processor := pause.NewProcessor(inner, rules)
+
+	data, err := processor.Process(context.Background(), []byte("Hi.\n\nYes."), jobConfig(""))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hi.", "Yes."}, inner.texts)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+
+	// Lead-in, "Hi.", the paragraph pause in place of the trimmed edges, "Yes.", tail.
+	assert.InDelta(t, 50+300+700+400+50, decoded.Frames(), 2)
+	assert.InDelta(t, 0, decoded.Samples[50+300+350], 0.001, "the pause should be silent")
+}
+
+func TestProcessor_SinglePartPassesThrough(t *testing.T) {
+	t.Parallel()
+
+	inner := &toneProcessor{mu: sync.Mutex{}, texts: nil}
+
+	data, err := pause.NewProcessor(inner, rules).Process(context.Background(), []byte("Just one."), jobConfig(""))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Just one."}, inner.texts)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, 50+900+50, decoded.Frames())
+}
+
+func TestProcessor_TrailingBreak(t *testing.T) {
+	t.Parallel()
+
+	inner := &toneProcessor{mu: sync.Mutex{}, texts: nil}
+
+	data, err := pause.NewProcessor(inner, rules).Process(
+		context.Background(), []byte(`Go.<break time="2s"/>`), jobConfig(""))
+	require.NoError(t, err)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, 50+300+50+2000, decoded.Frames())
+}
+
+func TestProcessor_ValidatesWithInner(t *testing.T) {
+	t.Parallel()
+
+	processor := pause.NewProcessor(&toneProcessor{mu: sync.Mutex{}, texts: nil}, rules)
+
+	require.NoError(t, processor.ValidateConfig(jobConfig("tara")))
+	require.ErrorIs(t, processor.ValidateConfig(jobConfig("unknown")), errRejected)
+}
@@ -0,0 +1,211 @@
+// Package pause paces long-form narration: it synthesizes a text sentence by
+// sentence and joins the audio with silences after sentences, paragraphs and
+// chapter breaks, and wherever an SSML break element asks for one.
+package pause
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/book-expert/tts-service/internal/sentence"
+)
+
+// Default pauses, used for zero Rules fields.
+const (
+	DefaultSentence  = 300 * time.Millisecond
+	DefaultParagraph = 700 * time.Millisecond
+	DefaultChapter   = 1500 * time.Millisecond
+	// MaxBreak caps the pause of one break element.
+	MaxBreak = 10 * time.Second
+)
+
+// Rules are the silences inserted between the parts of a text.
+type Rules struct {
+	// Sentence follows a sentence or a line.
+	Sentence time.Duration
+	// Paragraph follows a paragraph, which ends at a blank line.
+	Paragraph time.Duration
+	// Chapter follows a chapter break: a form feed, or a line of only
+	// asterisks, hashes, dashes, tildes or underscores, such as "* * *".
+	Chapter time.Duration
+}
+
+// withDefaults returns the rules with zero fields set to the defaults.
+func (r Rules) withDefaults() Rules {
+	if r.Sentence == 0 {
+		r.Sentence = DefaultSentence
+	}
+
+	if r.Paragraph == 0 {
+		r.Paragraph = DefaultParagraph
+	}
+
+	if r.Chapter == 0 {
+		r.Chapter = DefaultChapter
+	}
+
+	return r
+}
+
+// Part is a stretch of text to synthesize and the silence before it. The
+// text of a trailing pause is empty.
+type Part struct {
+	Pause time.Duration
+	Text  string
+}
+
+var (
+	// breakTag matches an SSML break element, such as <break time="500ms"/>
+	// or <break strength="strong"/>.
+	breakTag = regexp.MustCompile(`<break\b([^>]*?)/?>`)
+	// breakTime and breakStrength match the attributes of a break element.
+	breakTime     = regexp.MustCompile(`\btime\s*=\s*["']\s*([0-9.]+)\s*(ms|s)\s*["']`)
+	breakStrength = regexp.MustCompile(`\bstrength\s*=\s*["']\s*([a-z-]+)\s*["']`)
+	// sceneBreak matches a line that marks a chapter or scene break.
+	sceneBreak = regexp.MustCompile(`^[*#~_\-\s]+$`)
+)
+
+// Split returns the parts of text with the pause before each. Natural pauses
+// follow sentence ends, line breaks, blank lines and chapter breaks; where
+// several meet, the longest applies. A break element replaces the natural
+// pause at its place, so <break strength="none"/> joins two sentences
+// without one; consecutive break elements add up. Natural pauses before the
+// first part and after the last are dropped, break elements there are kept.
+// A sentence end inside an SSML lang element does not end a part, so the
+// element stays whole.
+func Split(text string, rules Rules) []Part {
+	rules = rules.withDefaults()
+
+	var parts splitter
+
+	position := 0
+
+	for _, match := range breakTag.FindAllStringSubmatchIndex(text, -1) {
+		parts.addText(text[position:match[0]], rules)
+		parts.addPause(breakPause(text[match[2]:match[3]], rules), true)
+
+		position = match[1]
+	}
+
+	parts.addText(text[position:], rules)
+
+	return parts.finish()
+}
+
+// breakPause returns the pause of a break element with attributes attrs. A
+// time wins over a strength; an element with neither is a medium break.
+func breakPause(attrs string, rules Rules) time.Duration {
+	if match := breakTime.FindStringSubmatch(attrs); match != nil {
+		value, err := strconv.ParseFloat(match[1], 64)
+		if err == nil {
+			unit := time.Second
+			if match[2] == "ms" {
+				unit = time.Millisecond
+			}
+
+			return min(time.Duration(value*float64(unit)), MaxBreak)
+		}
+	}
+
+	strength := "medium"
+	if match := breakStrength.FindStringSubmatch(attrs); match != nil {
+		strength = match[1]
+	}
+
+	switch strength {
+	case "none":
+		return 0
+	case "x-weak":
+		return rules.Sentence / 2
+	case "strong":
+		return rules.Paragraph
+	case "x-strong":
+		return rules.Chapter
+	default:
+		return rules.Sentence
+	}
+}
+
+// splitter collects parts and the pause that precedes the next one.
+type splitter struct {
+	parts    []Part
+	pending  time.Duration
+	explicit bool
+}
+
+// addText adds the sentences of text, with the natural pauses between them.
+func (s *splitter) addText(text string, rules Rules) {
+	for i, page := range strings.Split(text, "\f") {
+		if i > 0 {
+			s.addPause(rules.Chapter, false)
+		}
+
+		for _, piece := range sentence.Split(page) {
+			trimmed := strings.TrimSpace(piece)
+			if trimmed != "" && sceneBreak.MatchString(trimmed) {
+				s.addPause(rules.Chapter, false)
+
+				continue
+			}
+
+			if trimmed != "" {
+				s.addPart(trimmed)
+				s.addPause(rules.Sentence, false)
+			}
+
+			// A blank line in the whitespace after the sentence ends a paragraph.
+			if strings.Count(piece[len(strings.TrimRightFunc(piece, unicode.IsSpace)):], "\n") > 1 {
+				s.addPause(rules.Paragraph, false)
+			}
+		}
+	}
+}
+
+// addPause adds a pause before the next part. Break elements replace natural
+// pauses and add up; natural pauses keep the longest.
+func (s *splitter) addPause(pause time.Duration, explicit bool) {
+	switch {
+	case explicit && s.explicit:
+		s.pending += pause
+	case explicit:
+		s.pending = pause
+		s.explicit = true
+	case !s.explicit:
+		s.pending = max(s.pending, pause)
+	}
+}
+
+// addPart adds text as a part, or to the last part while that has an open
+// lang element.
+func (s *splitter) addPart(text string) {
+	if last := len(s.parts) - 1; last >= 0 && openLang(s.parts[last].Text) {
+		s.parts[last].Text += " " + text
+	} else {
+		if len(s.parts) == 0 && !s.explicit {
+			s.pending = 0
+		}
+
+		s.parts = append(s.parts, Part{Pause: s.pending, Text: text})
+	}
+
+	s.pending = 0
+	s.explicit = false
+}
+
+// finish returns the parts, with a trailing pause when a break element ends
+// the text.
+func (s *splitter) finish() []Part {
+	if s.explicit && s.pending > 0 {
+		s.parts = append(s.parts, Part{Pause: s.pending, Text: ""})
+	}
+
+	return s.parts
+}
+
+// openLang reports whether text opens more lang elements than it closes.
+func openLang(text string) bool {
+	return strings.Count(text, "<lang") > strings.Count(text, "</lang>")
+}
@@ -0,0 +1,77 @@
+package pause_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/tts/pause"
+	"github.com/stretchr/testify/assert"
+)
+
+var rules = pause.Rules{Sentence: 300 * time.Millisecond, Paragraph: 700 * time.Millisecond, Chapter: 1500 * time.Millisecond}
+
+func TestSplit(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text string
+		want []pause.Part
+	}{
+		"sentences": {
+			"It rained. Dr. Smith stayed in.",
+			[]pause.Part{{Pause: 0, Text: "It rained."}, {Pause: 300 * time.Millisecond, Text: "Dr. Smith stayed in."}},
+		},
+		"paragraphs": {
+			"\n\nIt rained.\n\nHe stayed in.\n",
+			[]pause.Part{{Pause: 0, Text: "It rained."}, {Pause: 700 * time.Millisecond, Text: "He stayed in."}},
+		},
+		"chapter breaks": {
+			"The end.\n\n* * *\n\nMorning came.\fChapter Two",
+			[]pause.Part{
+				{Pause: 0, Text: "The end."},
+				{Pause: 1500 * time.Millisecond, Text: "Morning came."},
+				{Pause: 1500 * time.Millisecond, Text: "Chapter Two"},
+			},
+		},
+		"break time": {
+			`Wait.<break time="1.2s"/> Now. <break time="250ms"/><break time="250ms"/>Go.`,
+			[]pause.Part{
+				{Pause: 0, Text: "Wait."},
+				{Pause: 1200 * time.Millisecond, Text: "Now."},
+				{Pause: 500 * time.Millisecond, Text: "Go."},
+			},
+		},
+		"break strength": {
+			`One. <break strength="none"/>Two. <break strength="x-strong"/>Three <break/>four.`,
+			[]pause.Part{
+				{Pause: 0, Text: "One."},
+				{Pause: 0, Text: "Two."},
+				{Pause: 1500 * time.Millisecond, Text: "Three"},
+				{Pause: 300 * time.Millisecond, Text: "four."},
+			},
+		},
+		"breaks at the edges": {
+			`<break time="1s"/>Hello.<break time="20s"/>`,
+			[]pause.Part{{Pause: time.Second, Text: "Hello."}, {Pause: pause.MaxBreak, Text: ""}},
+		},
+		"lang element": {
+			`He said <lang xml:lang="fr">Non. Jamais.</lang> Then left.`,
+			[]pause.Part{{Pause: 0, Text: `He said <lang xml:lang="fr">Non. Jamais.</lang> Then left.`}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, pause.Split(test.text, rules))
+		})
+	}
+}
+
+func TestSplit_DefaultRules(t *testing.T) {
+	t.Parallel()
+
+	parts := pause.Split("One.\n\nTwo.", pause.Rules{Sentence: 0, Paragraph: 0, Chapter: 0})
+	assert.Equal(t, []pause.Part{{Pause: 0, Text: "One."}, {Pause: pause.DefaultParagraph, Text: "Two."}}, parts)
+}
@@ -0,0 +1,77 @@
+package tts
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// DefaultPiperCommand is the piper binary looked up on PATH.
+const DefaultPiperCommand = "piper"
+
+// ErrPiperCommandEmpty is returned when no piper binary is configured.
+var ErrPiperCommandEmpty = errors.New("piper command cannot be empty")
+
+// PiperProcessor implements the core.TTSProcessor interface by calling the piper
+// binary with an ONNX voice. It runs on the CPU and ignores the sampling, NGL and
+// device settings used by chatllm.
+type PiperProcessor struct {
+	config    core.TTSConfig
+	command   string
+	workspace Workspace
+	log       *logger.Logger
+}
+
+// NewPiper creates a new PiperProcessor that runs command, usually
+// DefaultPiperCommand. cfg.ModelPath is the ONNX voice file.
+func NewPiper(cfg core.TTSConfig, command string, log *logger.Logger) (*PiperProcessor, error) {
+	if command == "" {
+		return nil, ErrPiperCommandEmpty
+	}
+
+	return &PiperProcessor{
+		config:    cfg,
+		command:   command,
+		workspace: Workspace{},
+		log:       log,
+	}, nil
+}
+
+// SetWorkspace selects where job files are written. It must be called before
+// the processor is used.
+func (p *PiperProcessor) SetWorkspace(workspace Workspace) {
+	p.workspace = workspace
+}
+
+// GetConfig returns the TTS configuration.
+func (p *PiperProcessor) GetConfig() core.TTSConfig {
+	return p.config
+}
+
+// Process takes text and returns the raw audio data by calling the piper binary.
+// The job's model path selects the voice; empty uses the processor's own.
+func (p *PiperProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	modelPath := cfg.ModelPath
+	if modelPath == "" {
+		modelPath = p.config.ModelPath
+	}
+
+	return p.workspace.runJob(p.log, func(dir, output string) error {
+		// #nosec G204 -- the command and model path come from the service configuration
+		cmd := exec.CommandContext(ctx, p.command, "--model", modelPath, "--output_file", output)
+		cmd.Dir = dir
+		cmd.Stdin = bytes.NewReader(text)
+
+		combinedOutput, err := cmd.CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("piper binary execution failed: %w - output: %s", err, string(combinedOutput))
+		}
+
+		return nil
+	})
+}
@@ -0,0 +1,78 @@
+package tts_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePiper follows the piper command line: it writes the voice and the text
+// read from stdin to --output_file, and fails for the voice "broken.onnx".
+const fakePiper = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--model) model=$2; shift ;;
+	--output_file) output=$2; shift ;;
+	esac
+	shift
+done
+if [ "$model" = broken.onnx ]; then
+	echo "cannot load $model" >&2
+	exit 1
+fi
+{ printf '%s:' "$model"; cat; } > "$output"
+`
+
+func newPiperJobConfig(modelPath string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         modelPath,
+		SnacModelPath:     "",
+		Voice:             "default",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+func TestPiperProcessor(t *testing.T) {
+	t.Parallel()
+
+	command := filepath.Join(t.TempDir(), "piper")
+	require.NoError(t, os.WriteFile(command, []byte(fakePiper), 0o700))
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	processor, err := tts.NewPiper(newPiperJobConfig("voice.onnx"), command, testLogger)
+	require.NoError(t, err)
+
+	audio, err := processor.Process(context.Background(), []byte("hello"), newPiperJobConfig(""))
+	require.NoError(t, err)
+	assert.Equal(t, "voice.onnx:hello", string(audio), "jobs without a model path use the processor's voice")
+
+	audio, err = processor.Process(context.Background(), []byte("hi"), newPiperJobConfig("other.onnx"))
+	require.NoError(t, err)
+	assert.Equal(t, "other.onnx:hi", string(audio))
+
+	_, err = processor.Process(context.Background(), []byte("hello"), newPiperJobConfig("broken.onnx"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "cannot load broken.onnx")
+
+	_, err = tts.NewPiper(newPiperJobConfig("voice.onnx"), "", testLogger)
+	require.ErrorIs(t, err, tts.ErrPiperCommandEmpty)
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tts
+
+import "os/exec"
+
+// killProcessGroup leaves cmd as it is: process groups are only used on
+// Unix, so cancelling cmd kills only its own process.
+func killProcessGroup(_ *exec.Cmd) {}
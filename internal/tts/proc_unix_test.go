@@ -0,0 +1,83 @@
+//go:build unix
+
+package tts_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hangingChatLLM starts a child process that outlives it, records the child's
+// PID in $CHILD_PID_FILE, and hangs.
+const hangingChatLLM = `#!/bin/sh
+sleep 30 &
+echo $! > "$CHILD_PID_FILE"
+sleep 30
+`
+
+func TestChatLLMProcessor_KilledAtDeadline(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "chatllm"), []byte(hangingChatLLM), 0o700)) // #nosec G306 -- test script
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	childPIDFile := filepath.Join(t.TempDir(), "child.pid")
+	t.Setenv("CHILD_PID_FILE", childPIDFile)
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "female1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New(t.TempDir(), "test.log")
+	require.NoError(t, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	workDir := t.TempDir()
+	processor.SetWorkspace(tts.Workspace{Root: workDir})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	started := time.Now()
+	_, err = processor.Process(ctx, []byte("Hello."), cfg)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second, "the job ends at its deadline")
+
+	pidText, err := os.ReadFile(childPIDFile) // #nosec G304 -- test file
+	require.NoError(t, err)
+
+	childPID, err := strconv.Atoi(strings.TrimSpace(string(pidText)))
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return errors.Is(syscall.Kill(childPID, 0), syscall.ESRCH)
+	}, 5*time.Second, 10*time.Millisecond, "processes started by chatllm are killed too")
+
+	assert.Empty(t, jobDirs(t, workDir), "the job directory is removed")
+}
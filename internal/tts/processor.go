@@ -5,55 +5,226 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"regexp"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
 
 	"github.com/book-expert/logger"
 	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/wav"
 )
 
 // ErrNotImplemented is returned when a method is not yet implemented.
 var ErrNotImplemented = errors.New("not yet implemented")
 
+// Chatllm configuration errors.
+var (
+	// ErrModelPathEmpty indicates that a chatllm job has no model path.
+	ErrModelPathEmpty = errors.New("model path cannot be empty")
+	// ErrSnacModelPathEmpty indicates that a chatllm job has no SNAC model path.
+	ErrSnacModelPathEmpty = errors.New("snac model path cannot be empty")
+	// ErrUnsupportedVoice indicates that the voice is not one of the chatllm speakers.
+	ErrUnsupportedVoice = errors.New("unsupported voice")
+)
+
+// chatllmWaitDelay bounds the wait for chatllm's output after it was killed,
+// in case a process it started still holds the output pipe.
+const chatllmWaitDelay = 5 * time.Second
+
+// chatllmSampleRate is the sample rate of the audio chatllm writes with the
+// SNAC decoder.
+const chatllmSampleRate = 24000
+
+// chatllmVoices are the speakers the chatllm Orpheus prompt accepts.
+var chatllmVoices = []string{"default", "male1", "female1"}
+
 // ChatLLMProcessor implements the core.TTSProcessor interface by calling the chatllm binary.
 type ChatLLMProcessor struct {
-	config core.TTSConfig
-	log    *logger.Logger
+	mu        sync.RWMutex
+	config    core.TTSConfig
+	workspace Workspace
+	logOutput bool
+	limits    ResourceLimits
+	log       *logger.Logger
 }
 
 // New creates a new ChatLLMProcessor.
 func New(cfg core.TTSConfig, log *logger.Logger) (*ChatLLMProcessor, error) {
 	return &ChatLLMProcessor{
-		config: cfg,
-		log:    log,
+		mu:        sync.RWMutex{},
+		config:    cfg,
+		workspace: Workspace{},
+		logOutput: false,
+		limits:    ResourceLimits{},
+		log:       log,
 	}, nil
 }
 
+// SetWorkspace selects where job files are written. It must be called before
+// the processor is used.
+func (p *ChatLLMProcessor) SetWorkspace(workspace Workspace) {
+	p.workspace = workspace
+}
+
+// SetOutputLogging logs every line chatllm writes, not only its progress. It
+// must be called before the processor is used.
+func (p *ChatLLMProcessor) SetOutputLogging(enabled bool) {
+	p.logOutput = enabled
+}
+
+// SetResourceLimits bounds the resources of every chatllm process. It must be
+// called before the processor is used.
+func (p *ChatLLMProcessor) SetResourceLimits(limits ResourceLimits) {
+	p.limits = limits
+}
+
 // GetConfig returns the TTS configuration.
 func (p *ChatLLMProcessor) GetConfig() core.TTSConfig {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
 	return p.config
 }
 
+// SetModelPaths replaces the models used by subsequent jobs.
+func (p *ChatLLMProcessor) SetModelPaths(modelPath, snacModelPath string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.config.ModelPath = modelPath
+	p.config.SnacModelPath = snacModelPath
+}
+
+// modelPaths returns the model paths selected for a job, falling back to the
+// processor's own models when the job does not name any.
+func (p *ChatLLMProcessor) modelPaths(cfg core.TTSConfig) (string, string) {
+	current := p.GetConfig()
+
+	modelPath, snacModelPath := cfg.ModelPath, cfg.SnacModelPath
+	if modelPath == "" {
+		modelPath = current.ModelPath
+	}
+
+	if snacModelPath == "" {
+		snacModelPath = current.SnacModelPath
+	}
+
+	return modelPath, snacModelPath
+}
+
+// ValidateConfig checks that the job names both models and a chatllm speaker.
+func (p *ChatLLMProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	modelPath, snacModelPath := p.modelPaths(cfg)
+
+	return validateChatLLMConfig(modelPath, snacModelPath, cfg.Voice)
+}
+
+// validateChatLLMConfig checks the settings every chatllm process needs.
+func validateChatLLMConfig(modelPath, snacModelPath, voice string) error {
+	if modelPath == "" {
+		return ErrModelPathEmpty
+	}
+
+	if snacModelPath == "" {
+		return ErrSnacModelPathEmpty
+	}
+
+	if !slices.Contains(chatllmVoices, voice) {
+		return fmt.Errorf("%w: '%s' (chatllm voices: %s)", ErrUnsupportedVoice, voice, strings.Join(chatllmVoices, ", "))
+	}
+
+	return nil
+}
+
+// promptSpecialToken matches model control tokens such as <|eot_id|> and
+// <custom_token_42>. Emotion tags such as <laugh> are plain text and kept.
+var promptSpecialToken = regexp.MustCompile(`<\|[^<>]*\|>|<custom_token_\d+>`)
+
+// chatllmPrompt builds the chatllm TTS prompt for a voice and text.
+func chatllmPrompt(voice string, text []byte) string {
+	return fmt.Sprintf("{%s}: %s", voice, sanitizePromptText(text))
+}
+
+// sanitizePromptText keeps text from changing the structure of a prompt that
+// starts with the voice: braces, which mark a voice such as {female1}, become
+// parentheses; model control tokens are removed; and control characters and
+// line breaks, which chatllm could read as the end of the prompt, become
+// spaces. Runs of whitespace are collapsed. When removing a token joins the
+// text around it into another, as in "<|a<|eot_id|>|>", angle brackets become
+// parentheses too. The text is passed to chatllm as a single argument after
+// the voice, so it cannot add command-line flags.
+func sanitizePromptText(text []byte) string {
+	cleaned := promptSpecialToken.ReplaceAllString(string(text), " ")
+	if promptSpecialToken.MatchString(cleaned) {
+		cleaned = strings.NewReplacer("<", "(", ">", ")").Replace(cleaned)
+	}
+
+	cleaned = strings.Map(func(r rune) rune {
+		switch {
+		case r == '{':
+			return '('
+		case r == '}':
+			return ')'
+		case unicode.IsControl(r), unicode.In(r, unicode.Zl, unicode.Zp):
+			return ' '
+		default:
+			return r
+		}
+	}, cleaned)
+
+	return strings.Join(strings.Fields(cleaned), " ")
+}
+
 // Process takes text and returns the raw audio data by calling the chatllm binary.
+// chatllm runs in the job's directory, so any other file it writes is removed
+// with it.
 func (p *ChatLLMProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
-	tempFile, err := os.CreateTemp("", "tts-output-*.wav")
+	audioData, err := p.workspace.runJob(p.log, func(dir, output string) error {
+		return p.run(ctx, text, cfg, dir, output)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file for tts output: %w", err)
+		return nil, err
 	}
 
-	defer func() {
-		removeErr := os.Remove(tempFile.Name())
-		if removeErr != nil {
-			p.log.Warn("Failed to remove temp file '%s': %v", tempFile.Name(), removeErr)
-		}
-	}()
+	return checkWAV(audioData, chatllmSampleRate)
+}
+
+// checkWAV returns audioData if it is a WAV file with samples at sampleRate.
+// chatllm can exit successfully after writing a truncated or garbled file,
+// e.g. when it runs out of memory while exporting.
+func checkWAV(audioData []byte, sampleRate int) ([]byte, error) {
+	info, err := wav.Inspect(audioData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", core.ErrInvalidAudio, err)
+	}
+
+	if info.Frames == 0 {
+		return nil, fmt.Errorf("%w: the WAV file has no samples", core.ErrInvalidAudio)
+	}
+
+	if info.SampleRate != sampleRate {
+		return nil, fmt.Errorf("%w: the sample rate is %d Hz, expected %d Hz", core.ErrInvalidAudio, info.SampleRate, sampleRate)
+	}
+
+	return audioData, nil
+}
+
+// run synthesizes text with the chatllm binary into output.
+func (p *ChatLLMProcessor) run(ctx context.Context, text []byte, cfg core.TTSConfig, dir, output string) error {
+	modelPath, snacModelPath := p.modelPaths(cfg)
 
 	args := []string{
-		"-m", p.config.ModelPath,
-		"--snac_model", p.config.SnacModelPath,
-		"-p", fmt.Sprintf("{%s}: %s", cfg.Voice, string(text)),
-		"--tts_export", tempFile.Name(),
+		"-m", modelPath,
+		"--snac_model", snacModelPath,
+		"-p", chatllmPrompt(cfg.Voice, text),
+		"--tts_export", output,
 		"--seed", strconv.Itoa(cfg.Seed),
 		"-ngl", strconv.Itoa(cfg.NGL),
 		"--top_p", fmt.Sprintf("%.2f", cfg.TopP),
@@ -61,18 +232,72 @@ func (p *ChatLLMProcessor) Process(ctx context.Context, text []byte, cfg core.TT
 		"--temp", fmt.Sprintf("%.2f", cfg.Temperature),
 	}
 
+	runCtx := ctx
+
+	if p.limits.MaxRuntime > 0 {
+		var cancel context.CancelFunc
+
+		runCtx, cancel = context.WithTimeout(ctx, p.limits.MaxRuntime)
+		defer cancel()
+	}
+
 	// #nosec G204 -- arguments are validated via core.TTSConfig validation
-	cmd := exec.CommandContext(ctx, "chatllm", args...)
+	cmd := exec.CommandContext(runCtx, "chatllm", args...)
+	cmd.Dir = dir
 
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return nil, fmt.Errorf("chatllm binary execution failed: %w - output: %s", err, string(output))
+	// When the job's deadline passes or the job is cancelled, chatllm is
+	// killed with any process it started.
+	killProcessGroup(cmd)
+	cmd.WaitDelay = chatllmWaitDelay
+
+	if cfg.Device != "" {
+		cmd.Env = append(os.Environ(), "CUDA_VISIBLE_DEVICES="+cfg.Device, "HIP_VISIBLE_DEVICES="+cfg.Device)
 	}
 
-	audioData, err := os.ReadFile(tempFile.Name())
+	// chatllm's output is read as it is written, so long jobs show their
+	// progress while they run.
+	reader, writer := io.Pipe()
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+
+	scanner := newOutputScanner(ctx, "chatllm", p.logOutput, p.log)
+
+	err := cmd.Start()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read audio data from temp file: %w", err)
+		return fmt.Errorf("failed to start chatllm: %w", err)
 	}
 
-	return audioData, nil
+	var scanned sync.WaitGroup
+
+	scanned.Go(func() {
+		scanner.scan(reader)
+	})
+
+	var limitErr error
+
+	if !p.limits.isZero() {
+		limitErr = p.limits.apply(cmd.Process.Pid)
+		if limitErr != nil {
+			_ = cmd.Process.Kill()
+		}
+	}
+
+	err = cmd.Wait()
+
+	_ = writer.Close()
+
+	scanned.Wait()
+
+	switch {
+	case limitErr != nil:
+		return fmt.Errorf("failed to limit chatllm resources: %w", limitErr)
+	case err != nil && runCtx.Err() != nil && ctx.Err() == nil:
+		return fmt.Errorf("%w of %s - output: %s", ErrRuntimeExceeded, p.limits.MaxRuntime, scanner.output())
+	case err != nil && ctx.Err() != nil:
+		return fmt.Errorf("chatllm was killed: %w", ctx.Err())
+	case err != nil:
+		return fmt.Errorf("chatllm binary execution failed: %w - output: %s", err, scanner.output())
+	}
+
+	return nil
 }
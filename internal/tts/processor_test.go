@@ -3,11 +3,21 @@ package tts_test
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/book-expert/logger"
 	"github.com/book-expert/tts-service/internal/core"
 	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -15,6 +25,7 @@ func TestNewChatLLMProcessor(t *testing.T) {
 	t.Parallel()
 
 	cfg := core.TTSConfig{
+		Model:             "",
 		ModelPath:         "",
 		SnacModelPath:     "",
 		Voice:             "",
@@ -23,6 +34,11 @@ func TestNewChatLLMProcessor(t *testing.T) {
 		TopP:              0,
 		RepetitionPenalty: 0,
 		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
 	}
 	testLogger, err := logger.New("/tmp", "test-log.log")
 	require.NoError(t, err)
@@ -35,6 +51,7 @@ func TestChatLLMProcessor_Process(t *testing.T) {
 	t.Parallel()
 
 	cfg := core.TTSConfig{
+		Model:             "",
 		ModelPath:         "",
 		SnacModelPath:     "",
 		Voice:             "",
@@ -43,6 +60,11 @@ func TestChatLLMProcessor_Process(t *testing.T) {
 		TopP:              0,
 		RepetitionPenalty: 0,
 		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
 	}
 	testLogger, err := logger.New("/tmp", "test-log.log")
 	require.NoError(t, err)
@@ -53,6 +75,7 @@ func TestChatLLMProcessor_Process(t *testing.T) {
 	// The Process method will fail because the dummy binary path doesn't exist.
 	// We just check that it returns any error.
 	_, err = processor.Process(context.Background(), []byte("hello"), core.TTSConfig{
+		Model:             "",
 		ModelPath:         "",
 		SnacModelPath:     "",
 		Voice:             "",
@@ -61,6 +84,403 @@ func TestChatLLMProcessor_Process(t *testing.T) {
 		TopP:              0,
 		RepetitionPenalty: 0,
 		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
 	})
 	require.Error(t, err)
 }
+
+func TestChatLLMProcessor_ValidateConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "female1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	job := cfg
+	job.ModelPath = ""
+	require.NoError(t, processor.ValidateConfig(job), "jobs fall back to the processor's models")
+
+	job.Voice = "tara"
+	require.ErrorIs(t, processor.ValidateConfig(job), tts.ErrUnsupportedVoice)
+
+	empty := cfg
+	empty.ModelPath = ""
+	empty.SnacModelPath = ""
+
+	processor, err = tts.New(empty, testLogger)
+	require.NoError(t, err)
+	require.ErrorIs(t, processor.ValidateConfig(empty), tts.ErrModelPathEmpty)
+
+	empty.ModelPath = "model.gguf"
+	require.ErrorIs(t, processor.ValidateConfig(empty), tts.ErrSnacModelPathEmpty)
+}
+
+// fakeWAVHeader is a printf format for the header of a 24 kHz mono 16-bit WAV
+// file of unknown length, as streaming encoders write it. The fake backends
+// write what a test checks after it, as the samples.
+const fakeWAVHeader = `RIFF\377\377\377\377WAVEfmt \020\000\000\000\001\000\001\000` +
+	`\300\135\000\000\200\273\000\000\002\000\020\000data\377\377\377\377`
+
+// wavPayload checks that a fake backend wrote a 24 kHz WAV file and returns
+// what it wrote after the header.
+func wavPayload(t *testing.T, audio []byte) string {
+	t.Helper()
+
+	info, err := wav.Inspect(audio)
+	require.NoError(t, err)
+	require.Equal(t, 24000, info.SampleRate)
+
+	return string(audio[44:])
+}
+
+// fakeChatLLM writes the prompt it was given to the export file, as the audio,
+// and any argument that looks like an unexpected flag to stderr.
+const fakeChatLLM = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-p) prompt=$2; shift ;;
+	--tts_export) output=$2; shift ;;
+	-m|--snac_model|--seed|-ngl|--top_p|--repetition_penalty|--temp) shift ;;
+	*) echo "unexpected argument: $1" >&2; exit 1 ;;
+	esac
+	shift
+done
+{ printf '` + fakeWAVHeader + `'; printf '%s' "$prompt"; } > "$output"
+`
+
+func TestChatLLMProcessor_SanitizesPrompt(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "chatllm"), []byte(fakeChatLLM), 0o700)) // #nosec G306 -- test script
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "female1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New(t.TempDir(), "test.log")
+	require.NoError(t, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	workDir := t.TempDir()
+	processor.SetWorkspace(tts.Workspace{Root: workDir})
+
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"plain text", "Hello, world.", "{female1}: Hello, world."},
+		{"voice switch", "Hi. {male1}: I am someone else.", "{female1}: Hi. (male1): I am someone else."},
+		{"line breaks", "First line.\n\nSecond\r\nline.", "{female1}: First line. Second line."},
+		{"control characters", "Bell\a and escape\x1b[31m and nul\x00.", "{female1}: Bell and escape [31m and nul ."},
+		{"special tokens", "Stop<|eot_id|> here <custom_token_7> now.", "{female1}: Stop here now."},
+		{"emotion tags are kept", "That is funny <laugh> really.", "{female1}: That is funny <laugh> really."},
+		{"nested special tokens", "Nested <|a<|eot_id|>|> <laugh>.", "{female1}: Nested (|a |) (laugh)."},
+		{"flags stay in the prompt", "--tts_export /etc/passwd -m evil.gguf", "{female1}: --tts_export /etc/passwd -m evil.gguf"},
+	}
+
+	for _, tc := range tests {
+		prompt, processErr := processor.Process(context.Background(), []byte(tc.text), cfg)
+		require.NoError(t, processErr, tc.name)
+		assert.Equal(t, tc.want, wavPayload(t, prompt), tc.name)
+	}
+
+	assert.Empty(t, jobDirs(t, workDir), "job directories are removed")
+}
+
+// jobDirs returns the job directories in a workspace root.
+func jobDirs(t *testing.T, root string) []string {
+	t.Helper()
+
+	dirs, err := filepath.Glob(filepath.Join(root, "tts-job-*"))
+	require.NoError(t, err)
+
+	return dirs
+}
+
+// FuzzChatLLMProcessor_Prompt checks that no text, valid UTF-8 or not, can
+// switch the voice, add a line, a control character or a model control token
+// to the prompt, or make it invalid UTF-8.
+func FuzzChatLLMProcessor_Prompt(f *testing.F) {
+	binDir := f.TempDir()
+	require.NoError(f, os.WriteFile(filepath.Join(binDir, "chatllm"), []byte(fakeChatLLM), 0o700)) // #nosec G306 -- test script
+	f.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "female1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New(f.TempDir(), "test.log")
+	require.NoError(f, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(f, err)
+
+	processor.SetWorkspace(tts.Workspace{Root: f.TempDir()})
+
+	for _, seed := range []string{
+		"Hi. {male1}: I am someone else.",
+		"First line.\n\nSecond\r\nline.\u2028Third.",
+		"Stop<|eot_id|> here <custom_token_7> now <laugh>.",
+		"Nested <|a<|eot_id|>|> tokens.",
+		"--tts_export /etc/passwd \x00\x1b[31m \xff\xfe",
+	} {
+		f.Add(seed)
+	}
+
+	controlToken := regexp.MustCompile(`<\|[^<>]*\|>|<custom_token_\d+>`)
+
+	f.Fuzz(func(t *testing.T, text string) {
+		audio, err := processor.Process(context.Background(), []byte(text), cfg)
+		require.NoError(t, err)
+
+		prompt := wavPayload(t, audio)
+		require.True(t, utf8.ValidString(prompt), "the prompt %q is valid UTF-8", prompt)
+
+		body, ok := strings.CutPrefix(prompt, "{female1}: ")
+		if !ok {
+			t.Fatalf("the prompt %q does not start with the voice", prompt)
+		}
+
+		require.NotContains(t, body, "{", "the text cannot switch the voice")
+		require.False(t, controlToken.MatchString(body), "the prompt %q has a control token", prompt)
+
+		for _, r := range body {
+			if unicode.IsControl(r) || unicode.In(r, unicode.Zl, unicode.Zp) {
+				t.Fatalf("the prompt %q has the control character %U", prompt, r)
+			}
+		}
+	})
+}
+
+// garbledChatLLM writes $FAKE_OUTPUT, a printf format, as the audio.
+const garbledChatLLM = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--tts_export) output=$2; shift ;;
+	esac
+	shift
+done
+printf "$FAKE_OUTPUT" > "$output"
+`
+
+func TestChatLLMProcessor_RejectsInvalidAudio(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "chatllm"), []byte(garbledChatLLM), 0o700)) // #nosec G306 -- test script
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New(t.TempDir(), "test.log")
+	require.NoError(t, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	processor.SetWorkspace(tts.Workspace{Root: t.TempDir()})
+
+	for name, output := range map[string]string{
+		"garbled":      "error: tensor mismatch\n",
+		"no samples":   fakeWAVHeader,
+		"16 kHz audio": strings.Replace(fakeWAVHeader, `\300\135\000\000\200\273`, `\200\076\000\000\000\175`, 1) + "audio!",
+	} {
+		t.Setenv("FAKE_OUTPUT", output)
+
+		_, processErr := processor.Process(context.Background(), []byte("Hello."), cfg)
+		require.ErrorIs(t, processErr, core.ErrInvalidAudio, name)
+	}
+}
+
+// progressChatLLM prints chatllm-style timing lines while it runs and fails
+// when the prompt asks it to.
+const progressChatLLM = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-p) prompt=$2; shift ;;
+	--tts_export) output=$2; shift ;;
+	esac
+	shift
+done
+echo "loading model..."
+echo "prompt eval time = 120.00 ms / 24 tokens (5.00 ms per token, 200.00 tokens per second)" >&2
+echo "eval time = 8000.00 ms / 200 tokens (40.00 ms per token, 25.00 tokens per second)"
+case "$prompt" in
+*fail*) echo "out of memory" >&2; exit 1 ;;
+esac
+printf '` + fakeWAVHeader + `audio' > "$output"
+`
+
+func TestChatLLMProcessor_ReportsProgress(t *testing.T) {
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "chatllm"), []byte(progressChatLLM), 0o700)) // #nosec G306 -- test script
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "female1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New(t.TempDir(), "test.log")
+	require.NoError(t, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	processor.SetOutputLogging(true)
+
+	var reported []core.Progress
+
+	ctx := core.WithProgress(context.Background(), func(progress core.Progress) {
+		reported = append(reported, progress)
+	})
+
+	audio, err := processor.Process(ctx, []byte("Hello."), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "audio", wavPayload(t, audio))
+	assert.ElementsMatch(t, []core.Progress{
+		{Tokens: 24, TokensPerSecond: 200},
+		{Tokens: 200, TokensPerSecond: 25},
+	}, reported)
+
+	_, err = processor.Process(context.Background(), []byte("Please fail."), cfg)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "out of memory", "the error carries chatllm's last output")
+}
+
+// limitedChatLLM writes its niceness to the export file, or hangs when the
+// prompt asks it to.
+const limitedChatLLM = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	-p) prompt=$2; shift ;;
+	--tts_export) output=$2; shift ;;
+	esac
+	shift
+done
+case "$prompt" in
+*hang*) exec sleep 10 ;;
+esac
+sleep 0.2
+{ printf '` + fakeWAVHeader + `'; cut -d' ' -f19 /proc/$$/stat | tr -d '\n'; } > "$output"
+`
+
+func TestChatLLMProcessor_ResourceLimits(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("resource limits are applied on Linux only")
+	}
+
+	binDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(binDir, "chatllm"), []byte(limitedChatLLM), 0o700)) // #nosec G306 -- test script
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	cfg := core.TTSConfig{
+		Model:             "",
+		ModelPath:         "model.gguf",
+		SnacModelPath:     "snac.gguf",
+		Voice:             "female1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+	testLogger, err := logger.New(t.TempDir(), "test.log")
+	require.NoError(t, err)
+
+	processor, err := tts.New(cfg, testLogger)
+	require.NoError(t, err)
+
+	processor.SetResourceLimits(tts.ResourceLimits{
+		Nice:           15,
+		MaxMemoryBytes: 1 << 30,
+		MaxCPUSeconds:  60,
+		MaxRuntime:     time.Second,
+	})
+
+	niceness, err := processor.Process(context.Background(), []byte("Hello."), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "15", wavPayload(t, niceness))
+
+	started := time.Now()
+	_, err = processor.Process(context.Background(), []byte("Please hang."), cfg)
+	require.ErrorIs(t, err, tts.ErrRuntimeExceeded)
+	assert.Less(t, time.Since(started), 5*time.Second, "chatllm is killed at its maximum runtime")
+}
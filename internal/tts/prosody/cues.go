@@ -0,0 +1,150 @@
+// Package prosody reads expressive cues from punctuation, such as questions,
+// exclamations, asides between dashes and words in capitals, and speaks each
+// cued part of a text in a speaking style chosen for its cue.
+package prosody
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/sentence"
+)
+
+// Cue is the expressive cue of a part of a text.
+type Cue int
+
+// Cues found by Parse.
+const (
+	// None marks text without a cue.
+	None Cue = iota
+	// Question marks a sentence that ends with a question mark.
+	Question
+	// Exclamation marks a sentence that ends with an exclamation mark.
+	Exclamation
+	// Aside marks text between two dashes inside a sentence.
+	Aside
+	// Emphasis marks a sentence with a word in capitals, such as "NEVER".
+	Emphasis
+)
+
+// minEmphasisLetters is the length of the shortest word in capitals that is
+// read as emphasis rather than as an abbreviation such as "BBC".
+const minEmphasisLetters = 4
+
+// closingMarks may follow the end mark of a sentence.
+const closingMarks = `"'”’)]`
+
+// dash matches an em dash, or a double hyphen standing for one, with the
+// spaces around it.
+var dash = regexp.MustCompile(`\s*(?:—|--)\s*`)
+
+// Part is a stretch of text and its cue.
+type Part struct {
+	Text string
+	Cue  Cue
+}
+
+// Parse splits text into sentences, and the asides of a sentence from the
+// text around them, and gives each part its cue. A sentence with a word in
+// capitals is Emphasis, else its end mark decides; asides are Aside. The
+// dashes stay with the text before them. Parts are trimmed; none is empty.
+func Parse(text string) []Part {
+	var parts []Part
+
+	for _, piece := range sentence.Split(text) {
+		piece = strings.TrimSpace(piece)
+		if piece == "" {
+			continue
+		}
+
+		cue := sentenceCue(piece)
+
+		for i, segment := range asides(piece) {
+			if segment == "" {
+				continue
+			}
+
+			part := Part{Text: segment, Cue: cue}
+			if i%2 == 1 {
+				part.Cue = Aside
+			}
+
+			parts = append(parts, part)
+		}
+	}
+
+	return parts
+}
+
+// asides splits a sentence at its dashes. Segments at odd indexes are
+// asides, except a last one that no dash closes.
+func asides(text string) []string {
+	matches := dash.FindAllStringIndex(text, -1)
+	if len(matches) < 2 {
+		return []string{text}
+	}
+
+	segments := make([]string, 0, len(matches)+1)
+	position := 0
+
+	for _, match := range matches {
+		// A dash at the very start or end of the sentence opens or closes nothing.
+		if match[0] == 0 || match[1] == len(text) {
+			continue
+		}
+
+		segments = append(segments, strings.TrimSpace(text[position:match[1]]))
+		position = match[1]
+	}
+
+	segments = append(segments, strings.TrimSpace(text[position:]))
+
+	if len(segments)%2 == 0 {
+		// The last aside is not closed; it stays with the text before it.
+		last := len(segments) - 1
+		segments = append(segments[:last-1], segments[last-1]+" "+segments[last])
+	}
+
+	return segments
+}
+
+// sentenceCue returns the cue of a trimmed sentence.
+func sentenceCue(text string) Cue {
+	if emphasized(text) {
+		return Emphasis
+	}
+
+	end, _ := utf8.DecodeLastRuneInString(strings.TrimRight(text, closingMarks))
+
+	switch end {
+	case '?':
+		return Question
+	case '!':
+		return Exclamation
+	default:
+		return None
+	}
+}
+
+// emphasized reports whether a sentence with lower-case letters has a word in
+// capitals of at least minEmphasisLetters letters that is not a Roman numeral.
+// A sentence all in capitals, such as a heading, is not emphasized.
+func emphasized(text string) bool {
+	if strings.IndexFunc(text, unicode.IsLower) < 0 {
+		return false
+	}
+
+	for _, word := range strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if utf8.RuneCountInString(word) < minEmphasisLetters || strings.IndexFunc(word, unicode.IsLower) >= 0 {
+			continue
+		}
+
+		if strings.Trim(word, "IVXLCDM") != "" {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,61 @@
+package prosody_test
+
+import (
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts/prosody"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text string
+		want []prosody.Part
+	}{
+		"end marks": {
+			`It rained. Why? "Run!" she said. Stop?!`,
+			[]prosody.Part{
+				{Text: "It rained.", Cue: prosody.None},
+				{Text: "Why?", Cue: prosody.Question},
+				{Text: `"Run!"`, Cue: prosody.Exclamation},
+				{Text: "she said.", Cue: prosody.None},
+				{Text: "Stop?!", Cue: prosody.Exclamation},
+			},
+		},
+		"quoted question": {
+			`She asked, "Who is there?"`,
+			[]prosody.Part{{Text: `She asked, "Who is there?"`, Cue: prosody.Question}},
+		},
+		"asides": {
+			"He was — or so they said — a fool. She left -- quickly.",
+			[]prosody.Part{
+				{Text: "He was —", Cue: prosody.None},
+				{Text: "or so they said —", Cue: prosody.Aside},
+				{Text: "a fool.", Cue: prosody.None},
+				{Text: "She left -- quickly.", Cue: prosody.None},
+			},
+		},
+		"emphasis": {
+			"I will NEVER go. The BBC said so. Henry VIII met Louis XIV.",
+			[]prosody.Part{
+				{Text: "I will NEVER go.", Cue: prosody.Emphasis},
+				{Text: "The BBC said so.", Cue: prosody.None},
+				{Text: "Henry VIII met Louis XIV.", Cue: prosody.None},
+			},
+		},
+		"headings": {
+			"CHAPTER ONE\nTHE END",
+			[]prosody.Part{{Text: "CHAPTER ONE", Cue: prosody.None}, {Text: "THE END", Cue: prosody.None}},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, prosody.Parse(test.text))
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package prosody
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/audio"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// Hints name the speaking style of each cue, as defined for the style
+// processor. An empty name leaves parts with that cue in the job's style.
+type Hints struct {
+	Question    string
+	Exclamation string
+	Aside       string
+	Emphasis    string
+}
+
+// style returns the style name of cue.
+func (h Hints) style(cue Cue) string {
+	switch cue {
+	case Question:
+		return h.Question
+	case Exclamation:
+		return h.Exclamation
+	case Aside:
+		return h.Aside
+	case Emphasis:
+		return h.Emphasis
+	default:
+		return ""
+	}
+}
+
+// Processor wraps a core.TTSProcessor, usually a style processor, and speaks
+// the cued parts of a text in the styles of hints: the text is split with
+// Parse, consecutive parts in the same style are synthesized together, and
+// the WAV results are joined in order, each matched to the format of the
+// first. A hint is skipped when the wrapped processor rejects its style, for
+// example because the job's voice does not support it. Jobs that set a style
+// and text in a single style are passed through.
+type Processor struct {
+	inner core.TTSProcessor
+	hints Hints
+}
+
+// NewProcessor creates a prosody wrapper around inner.
+func NewProcessor(inner core.TTSProcessor, hints Hints) *Processor {
+	return &Processor{
+		inner: inner,
+		hints: hints,
+	}
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig checks the job with the wrapped processor, when it validates
+// jobs. Hinted styles are checked when they are used.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateConfig(cfg)
+}
+
+// styledPart is a run of consecutive parts in the same style.
+type styledPart struct {
+	style string
+	text  string
+}
+
+// Process synthesizes each style's parts and joins the audio.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	if cfg.Style != "" {
+		return p.inner.Process(ctx, text, cfg)
+	}
+
+	parts := p.parts(string(text), cfg)
+	if len(parts) <= 1 {
+		if len(parts) == 1 {
+			cfg.Style = parts[0].style
+		}
+
+		return p.inner.Process(ctx, text, cfg)
+	}
+
+	var joined wav.Audio
+
+	for i, part := range parts {
+		partCfg := cfg
+		partCfg.Style = part.style
+
+		data, err := p.inner.Process(ctx, []byte(part.text), partCfg)
+		if err != nil {
+			return nil, fmt.Errorf("synthesizing part %d of %d in style '%s': %w", i+1, len(parts), part.style, err)
+		}
+
+		decoded, err := wav.Decode(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		if i == 0 {
+			joined = decoded
+
+			continue
+		}
+
+		decoded, err = audio.Remix(audio.Resample(decoded, joined.SampleRate), joined.Channels)
+		if err != nil {
+			return nil, fmt.Errorf("failed to join part %d of %d: %w", i+1, len(parts), err)
+		}
+
+		joined.Samples = append(joined.Samples, decoded.Samples...)
+	}
+
+	return wav.Encode(joined), nil
+}
+
+// parts splits text by style, merging consecutive parts in the same style.
+func (p *Processor) parts(text string, cfg core.TTSConfig) []styledPart {
+	var (
+		parts     []styledPart
+		supported = map[string]bool{"": true}
+	)
+
+	for _, part := range Parse(text) {
+		style := p.hints.style(part.Cue)
+
+		ok, checked := supported[style]
+		if !checked {
+			ok = p.supports(cfg, style)
+			supported[style] = ok
+		}
+
+		if !ok {
+			style = ""
+		}
+
+		if len(parts) > 0 && parts[len(parts)-1].style == style {
+			parts[len(parts)-1].text += " " + part.Text
+
+			continue
+		}
+
+		parts = append(parts, styledPart{style: style, text: part.Text})
+	}
+
+	return parts
+}
+
+// supports reports whether the wrapped processor accepts the job in style.
+func (p *Processor) supports(cfg core.TTSConfig, style string) bool {
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return true
+	}
+
+	cfg.Style = style
+
+	return validator.ValidateConfig(cfg) == nil
+}
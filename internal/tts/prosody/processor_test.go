@@ -0,0 +1,143 @@
+package prosody_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/prosody"
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errUnsupported = errors.New("unsupported")
+
+// jobConfig returns a job configuration with the given voice and style.
+func jobConfig(voice, style string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             style,
+	}
+}
+
+// call is one request seen by recordingProcessor.
+type call struct {
+	style string
+	text  string
+}
+
+// recordingProcessor returns one frame of audio per character of text and
+// records its calls. It rejects the style "whisper" for the voice "leo".
+type recordingProcessor struct {
+	mu    sync.Mutex
+	calls []call
+}
+
+func (p *recordingProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *recordingProcessor) ValidateConfig(cfg core.TTSConfig) error {
+	if cfg.Voice == "leo" && cfg.Style == "whisper" {
+		return errUnsupported
+	}
+
+	return nil
+}
+
+func (p *recordingProcessor) Process(_ context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	p.calls = append(p.calls, call{style: cfg.Style, text: string(text)})
+	p.mu.Unlock()
+
+	return wav.EncodePCM16(make([]float32, len(text)), 24000), nil
+}
+
+func newHints() prosody.Hints {
+	return prosody.Hints{Question: "curious", Exclamation: "excited", Aside: "whisper", Emphasis: ""}
+}
+
+func TestProcessor_StylesCues(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil}
+	processor := prosody.NewProcessor(inner, newHints())
+
+	text := "It rained. It was — she thought — cold. Why? I will NEVER go! Go now!"
+
+	data, err := processor.Process(context.Background(), []byte(text), jobConfig("tara", ""))
+	require.NoError(t, err)
+
+	assert.Equal(t, []call{
+		{style: "", text: "It rained. It was —"},
+		{style: "whisper", text: "she thought —"},
+		{style: "", text: "cold."},
+		{style: "curious", text: "Why?"},
+		{style: "", text: "I will NEVER go!"},
+		{style: "excited", text: "Go now!"},
+	}, inner.calls)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+
+	wantFrames := 0
+	for _, c := range inner.calls {
+		wantFrames += len(c.text)
+	}
+
+	assert.Equal(t, wantFrames, decoded.Frames())
+}
+
+func TestProcessor_SkipsUnsupportedStyles(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil}
+
+	_, err := prosody.NewProcessor(inner, newHints()).Process(
+		context.Background(), []byte("It was — she thought — cold."), jobConfig("leo", ""))
+	require.NoError(t, err)
+	assert.Equal(t, []call{{style: "", text: "It was — she thought — cold."}}, inner.calls)
+}
+
+func TestProcessor_PassesThrough(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		text  string
+		style string
+		want  call
+	}{
+		"job style":         {"Why? Go!", "calm", call{style: "calm", text: "Why? Go!"}},
+		"single style":      {"Why? Who?", "", call{style: "curious", text: "Why? Who?"}},
+		"without a cue":     {"It rained.\n\nIt stopped.", "", call{style: "", text: "It rained.\n\nIt stopped."}},
+		"unhinted emphasis": {"I will NEVER go.", "", call{style: "", text: "I will NEVER go."}},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			inner := &recordingProcessor{mu: sync.Mutex{}, calls: nil}
+
+			_, err := prosody.NewProcessor(inner, newHints()).Process(
+				context.Background(), []byte(test.text), jobConfig("tara", test.style))
+			require.NoError(t, err)
+			assert.Equal(t, []call{test.want}, inner.calls)
+		})
+	}
+}
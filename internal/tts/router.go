@@ -0,0 +1,252 @@
+package tts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Router errors.
+var (
+	// ErrUnknownModel is returned when a job selects a model that is not registered.
+	ErrUnknownModel = errors.New("unknown model")
+	// ErrUnsupportedLanguage is returned when a job's language is not served by
+	// its model, or by any model when it selected none.
+	ErrUnsupportedLanguage = core.ErrUnsupportedLanguage
+)
+
+// Route is a model served by the Router.
+type Route struct {
+	Processor core.TTSProcessor
+	// DefaultVoice is used for jobs that do not select a voice.
+	DefaultVoice string
+	// Languages lists the language codes the model serves. Empty accepts any language.
+	Languages []string
+}
+
+// LanguageRoute is the model and voice used for jobs in one language that do
+// not select a model.
+type LanguageRoute struct {
+	// Model names a registered model; empty selects the default.
+	Model string
+	// Voice overrides the model's default voice when set.
+	Voice string
+}
+
+// Router dispatches each job to the processor registered for its model.
+// The empty model name selects the default route.
+type Router struct {
+	routes    map[string]Route
+	languages map[string]LanguageRoute
+}
+
+// NewRouter creates a Router with a default route, additional named routes and
+// a table of routes by language code for jobs that select no model.
+func NewRouter(defaultRoute Route, named map[string]Route, languages map[string]LanguageRoute) *Router {
+	routes := make(map[string]Route, len(named)+1)
+	for name, route := range named {
+		routes[name] = route
+	}
+
+	routes[""] = defaultRoute
+
+	table := make(map[string]LanguageRoute, len(languages))
+	for language, route := range languages {
+		table[strings.ToLower(language)] = route
+	}
+
+	return &Router{routes: routes, languages: table}
+}
+
+// Models returns the sorted names of the registered models, excluding the default.
+func (r *Router) Models() []string {
+	names := make([]string, 0, len(r.routes))
+
+	for name := range r.routes {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+
+	slices.Sort(names)
+
+	return names
+}
+
+// ResolveModel returns the base configuration for a model: its paths, its
+// default voice, and the model name itself.
+func (r *Router) ResolveModel(name string) (core.TTSConfig, error) {
+	route, err := r.route(name)
+	if err != nil {
+		return core.TTSConfig{}, err
+	}
+
+	cfg := route.Processor.GetConfig()
+	cfg.Model = name
+
+	if route.DefaultVoice != "" {
+		cfg.Voice = route.DefaultVoice
+	}
+
+	return cfg, nil
+}
+
+// ResolveLanguage returns the base configuration for a job in language that
+// selects no model. The language table is consulted first, then the models
+// that list the language, the default model before the others; a default model
+// that lists no languages serves the rest. Otherwise the job is rejected with a
+// core.LanguageError listing the languages that are served.
+func (r *Router) ResolveLanguage(language string) (core.TTSConfig, error) {
+	entry, ok := r.languages[strings.ToLower(language)]
+	if ok {
+		cfg, err := r.ResolveModel(entry.Model)
+		if err != nil {
+			return core.TTSConfig{}, fmt.Errorf("language '%s': %w", language, err)
+		}
+
+		if entry.Voice != "" {
+			cfg.Voice = entry.Voice
+		}
+
+		return cfg, nil
+	}
+
+	for _, name := range append([]string{""}, r.Models()...) {
+		route := r.routes[name]
+		if len(route.Languages) > 0 && route.serves(language) {
+			return r.ResolveModel(name)
+		}
+	}
+
+	if len(r.routes[""].Languages) == 0 {
+		return r.ResolveModel("")
+	}
+
+	return core.TTSConfig{}, &core.LanguageError{Language: language, Model: "", Supported: r.Languages()}
+}
+
+// Languages returns the sorted, lower-cased codes of the languages in the
+// language table or listed by a model.
+func (r *Router) Languages() []string {
+	languages := make([]string, 0, len(r.languages))
+
+	for language := range r.languages {
+		languages = append(languages, language)
+	}
+
+	for _, route := range r.routes {
+		for _, language := range route.Languages {
+			languages = append(languages, strings.ToLower(language))
+		}
+	}
+
+	slices.Sort(languages)
+
+	return slices.Compact(languages)
+}
+
+// ValidateConfig checks the job against its model: the language must be served,
+// and the model's processor validates the rest when it can.
+func (r *Router) ValidateConfig(cfg core.TTSConfig) error {
+	route, err := r.route(cfg.Model)
+	if err != nil {
+		return err
+	}
+
+	if !route.serves(cfg.Language) {
+		return route.languageError(cfg)
+	}
+
+	validator, ok := route.Processor.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	err = validator.ValidateConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("model '%s': %w", cfg.Model, err)
+	}
+
+	return nil
+}
+
+// GetConfig returns the configuration of the default model.
+func (r *Router) GetConfig() core.TTSConfig {
+	return r.routes[""].Processor.GetConfig()
+}
+
+// Close closes the processors of every route that hold resources, such as the
+// models loaded in-process by llama.cpp. Each is closed once, even if it
+// serves several routes.
+func (r *Router) Close() error {
+	closed := map[io.Closer]bool{}
+
+	var errs []error
+
+	for name, route := range r.routes {
+		closer, ok := route.Processor.(io.Closer)
+		if !ok || closed[closer] {
+			continue
+		}
+
+		closed[closer] = true
+
+		err := closer.Close()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("model '%s': %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// Process runs the job on the processor registered for cfg.Model, after checking
+// that the model serves cfg.Language.
+func (r *Router) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	route, err := r.route(cfg.Model)
+	if err != nil {
+		return nil, err
+	}
+
+	if !route.serves(cfg.Language) {
+		return nil, route.languageError(cfg)
+	}
+
+	audio, err := route.Processor.Process(ctx, text, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("model '%s': %w", cfg.Model, err)
+	}
+
+	return audio, nil
+}
+
+func (r *Router) route(name string) (Route, error) {
+	route, ok := r.routes[name]
+	if !ok {
+		return Route{}, fmt.Errorf("%w: '%s' (available: %s)", ErrUnknownModel, name, strings.Join(r.Models(), ", "))
+	}
+
+	return route, nil
+}
+
+// languageError describes a job whose language the route does not serve.
+func (r Route) languageError(cfg core.TTSConfig) error {
+	return &core.LanguageError{Language: cfg.Language, Model: cfg.Model, Supported: r.Languages}
+}
+
+// serves reports whether the route accepts a job in language. Jobs without a
+// language and routes without languages always match.
+func (r Route) serves(language string) bool {
+	if language == "" || len(r.Languages) == 0 {
+		return true
+	}
+
+	return slices.ContainsFunc(r.Languages, func(candidate string) bool {
+		return strings.EqualFold(candidate, language)
+	})
+}
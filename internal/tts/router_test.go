@@ -0,0 +1,235 @@
+package tts_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingProcessor is a TTSProcessor that records the configuration it was called with.
+type recordingProcessor struct {
+	config      core.TTSConfig
+	processed   core.TTSConfig
+	processHits int
+}
+
+func (p *recordingProcessor) GetConfig() core.TTSConfig {
+	return p.config
+}
+
+func (p *recordingProcessor) Process(_ context.Context, _ []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.processed = cfg
+	p.processHits++
+
+	return []byte(p.config.ModelPath), nil
+}
+
+func newRecordingProcessor(modelPath, voice string) *recordingProcessor {
+	return &recordingProcessor{
+		config: core.TTSConfig{
+			Model:             "",
+			ModelPath:         modelPath,
+			SnacModelPath:     modelPath + ".snac",
+			Voice:             voice,
+			Seed:              0,
+			NGL:               0,
+			TopP:              0,
+			RepetitionPenalty: 0,
+			Temperature:       0,
+			Device:            "",
+			Language:          "",
+			Rate:              0,
+			Pitch:             0,
+			Style:             "",
+		},
+		processed:   core.TTSConfig{},
+		processHits: 0,
+	}
+}
+
+func newTestRouter() (*tts.Router, *recordingProcessor, *recordingProcessor) {
+	defaultProcessor := newRecordingProcessor("default.gguf", "default")
+	narrator := newRecordingProcessor("narrator.gguf", "")
+
+	router := tts.NewRouter(
+		tts.Route{Processor: defaultProcessor, DefaultVoice: "", Languages: nil},
+		map[string]tts.Route{
+			"narrator": {Processor: narrator, DefaultVoice: "female1", Languages: []string{"en"}},
+		},
+		nil,
+	)
+
+	return router, defaultProcessor, narrator
+}
+
+func TestRouter_ResolveModel(t *testing.T) {
+	t.Parallel()
+
+	router, _, _ := newTestRouter()
+
+	assert.Equal(t, []string{"narrator"}, router.Models())
+
+	cfg, err := router.ResolveModel("narrator")
+	require.NoError(t, err)
+	assert.Equal(t, "narrator", cfg.Model)
+	assert.Equal(t, "narrator.gguf", cfg.ModelPath)
+	assert.Equal(t, "female1", cfg.Voice)
+
+	cfg, err = router.ResolveModel("")
+	require.NoError(t, err)
+	assert.Equal(t, "default.gguf", cfg.ModelPath)
+	assert.Equal(t, "default", cfg.Voice)
+
+	_, err = router.ResolveModel("missing")
+	require.ErrorIs(t, err, tts.ErrUnknownModel)
+	assert.Contains(t, err.Error(), "narrator")
+}
+
+func TestRouter_Process(t *testing.T) {
+	t.Parallel()
+
+	router, defaultProcessor, narrator := newTestRouter()
+
+	cfg, err := router.ResolveModel("narrator")
+	require.NoError(t, err)
+
+	audio, err := router.Process(context.Background(), []byte("hello"), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("narrator.gguf"), audio)
+	assert.Equal(t, 1, narrator.processHits)
+	assert.Equal(t, 0, defaultProcessor.processHits)
+	assert.Equal(t, "female1", narrator.processed.Voice)
+
+	cfg.Model = "missing"
+
+	_, err = router.Process(context.Background(), []byte("hello"), cfg)
+	require.ErrorIs(t, err, tts.ErrUnknownModel)
+}
+
+func TestRouter_Languages(t *testing.T) {
+	t.Parallel()
+
+	router, _, narrator := newTestRouter()
+
+	cfg, err := router.ResolveModel("narrator")
+	require.NoError(t, err)
+
+	for _, language := range []string{"", "en", "EN"} {
+		cfg.Language = language
+
+		_, err = router.Process(context.Background(), []byte("hello"), cfg)
+		require.NoError(t, err, "language %q", language)
+	}
+
+	cfg.Language = "de"
+
+	_, err = router.Process(context.Background(), []byte("hello"), cfg)
+	require.ErrorIs(t, err, tts.ErrUnsupportedLanguage)
+
+	var languageErr *core.LanguageError
+
+	require.ErrorAs(t, err, &languageErr)
+	assert.Equal(t, []string{"en"}, languageErr.Supported)
+	assert.Equal(t, 3, narrator.processHits, "a rejected job must not reach the processor")
+
+	// The default model declares no languages and accepts any.
+	cfg, err = router.ResolveModel("")
+	require.NoError(t, err)
+
+	cfg.Language = "de"
+
+	_, err = router.Process(context.Background(), []byte("hello"), cfg)
+	require.NoError(t, err)
+}
+
+func TestRouter_ResolveLanguage(t *testing.T) {
+	t.Parallel()
+
+	defaultProcessor := newRecordingProcessor("default.gguf", "default")
+	german := newRecordingProcessor("german.gguf", "")
+	french := newRecordingProcessor("french.gguf", "")
+
+	router := tts.NewRouter(
+		tts.Route{Processor: defaultProcessor, DefaultVoice: "", Languages: []string{"en"}},
+		map[string]tts.Route{
+			"german": {Processor: german, DefaultVoice: "anna", Languages: []string{"de", "en"}},
+			"french": {Processor: french, DefaultVoice: "claire", Languages: []string{"fr"}},
+		},
+		map[string]tts.LanguageRoute{
+			"FR": {Model: "french", Voice: "remy"},
+			"es": {Model: "", Voice: "lucia"},
+		},
+	)
+
+	for language, want := range map[string]struct{ model, voice string }{
+		"fr": {"french", "remy"},
+		"es": {"", "lucia"},
+		"de": {"german", "anna"},
+		"EN": {"", "default"},
+	} {
+		cfg, err := router.ResolveLanguage(language)
+		require.NoError(t, err, language)
+		assert.Equal(t, want.model, cfg.Model, language)
+		assert.Equal(t, want.voice, cfg.Voice, language)
+	}
+
+	_, err := router.ResolveLanguage("it")
+	require.ErrorIs(t, err, tts.ErrUnsupportedLanguage)
+
+	var languageErr *core.LanguageError
+
+	require.ErrorAs(t, err, &languageErr)
+	assert.Equal(t, []string{"de", "en", "es", "fr"}, languageErr.Supported)
+	assert.Equal(t, "it", languageErr.Language)
+
+	// A default model that lists no languages serves every other language.
+	router = tts.NewRouter(tts.Route{Processor: defaultProcessor, DefaultVoice: "", Languages: nil}, nil, nil)
+
+	cfg, err := router.ResolveLanguage("it")
+	require.NoError(t, err)
+	assert.Equal(t, "default.gguf", cfg.ModelPath)
+}
+
+// closingProcessor is a recordingProcessor that holds a resource.
+type closingProcessor struct {
+	*recordingProcessor
+
+	closes int
+	err    error
+}
+
+func (p *closingProcessor) Close() error {
+	p.closes++
+
+	return p.err
+}
+
+func TestRouter_Close(t *testing.T) {
+	t.Parallel()
+
+	shared := &closingProcessor{recordingProcessor: newRecordingProcessor("orpheus.gguf", ""), closes: 0, err: nil}
+	failing := &closingProcessor{
+		recordingProcessor: newRecordingProcessor("broken.gguf", ""), closes: 0, err: errors.New("busy"),
+	}
+
+	router := tts.NewRouter(
+		tts.Route{Processor: shared, DefaultVoice: "", Languages: nil},
+		map[string]tts.Route{
+			"orpheus": {Processor: shared, DefaultVoice: "", Languages: nil},
+			"broken":  {Processor: failing, DefaultVoice: "", Languages: nil},
+			"plain":   {Processor: newRecordingProcessor("plain.gguf", ""), DefaultVoice: "", Languages: nil},
+		},
+		nil,
+	)
+
+	err := router.Close()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "model 'broken': busy")
+	assert.Equal(t, 1, shared.closes, "a processor behind several routes is closed once")
+	assert.Equal(t, 1, failing.closes)
+}
@@ -0,0 +1,125 @@
+// Package style applies a job's speaking style, such as "calm" or "excited",
+// by turning it into the prompt prefix and model parameters of the backend.
+package style
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/book-expert/tts-service/internal/core"
+)
+
+// Style errors.
+var (
+	// ErrUnknownStyle indicates that the job names a style that is not defined.
+	ErrUnknownStyle = errors.New("unknown style")
+	// ErrUnsupportedStyle indicates that the style is not available for the job's voice.
+	ErrUnsupportedStyle = errors.New("style not supported by voice")
+)
+
+// Style is how one speaking style is produced.
+type Style struct {
+	// Prefix is prepended to the text, e.g. an emotion tag the model was
+	// trained on. Empty leaves the text unchanged.
+	Prefix string
+	// Temperature and TopP replace the job's values; zero keeps them.
+	Temperature float64
+	TopP        float64
+	// Voices lists the voices that support the style; empty allows every voice.
+	Voices []string
+}
+
+// Processor wraps a core.TTSProcessor and applies the style of each job. The
+// style name is still passed on, so a backend such as an HTTP service can
+// apply its own; such styles can be defined without a prefix or parameters.
+// Jobs without a style pass through unchanged.
+type Processor struct {
+	inner  core.TTSProcessor
+	styles map[string]Style
+}
+
+// NewProcessor creates a style wrapper around inner with the defined styles.
+func NewProcessor(inner core.TTSProcessor, styles map[string]Style) *Processor {
+	return &Processor{
+		inner:  inner,
+		styles: styles,
+	}
+}
+
+// GetConfig returns the configuration of the wrapped processor.
+func (p *Processor) GetConfig() core.TTSConfig {
+	return p.inner.GetConfig()
+}
+
+// ValidateConfig checks that the job's style is defined and supported by its
+// voice, then delegates to the wrapped processor when it validates jobs.
+func (p *Processor) ValidateConfig(cfg core.TTSConfig) error {
+	_, err := p.lookup(cfg)
+	if err != nil {
+		return err
+	}
+
+	validator, ok := p.inner.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	return validator.ValidateConfig(cfg)
+}
+
+// Process applies the job's style and synthesizes the text.
+func (p *Processor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	style, err := p.lookup(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if style.Prefix != "" {
+		text = append([]byte(style.Prefix+" "), text...)
+	}
+
+	if style.Temperature != 0 {
+		cfg.Temperature = style.Temperature
+	}
+
+	if style.TopP != 0 {
+		cfg.TopP = style.TopP
+	}
+
+	return p.inner.Process(ctx, text, cfg)
+}
+
+// lookup returns the job's style; a job without a style gets the zero Style.
+func (p *Processor) lookup(cfg core.TTSConfig) (Style, error) {
+	if cfg.Style == "" {
+		return Style{}, nil
+	}
+
+	style, ok := p.styles[cfg.Style]
+	if !ok {
+		return Style{}, fmt.Errorf("%w: '%s' (styles: %s)", ErrUnknownStyle, cfg.Style, strings.Join(p.names(), ", "))
+	}
+
+	if len(style.Voices) > 0 && !slices.Contains(style.Voices, cfg.Voice) {
+		return Style{}, fmt.Errorf("%w: '%s' with voice '%s' (voices: %s)",
+			ErrUnsupportedStyle, cfg.Style, cfg.Voice, strings.Join(style.Voices, ", "))
+	}
+
+	return style, nil
+}
+
+// names returns the defined style names in order.
+func (p *Processor) names() []string {
+	names := make([]string, 0, len(p.styles))
+	for name := range p.styles {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
@@ -0,0 +1,94 @@
+package style_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/tts/style"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// jobConfig returns a job configuration with the given voice and style.
+func jobConfig(voice, styleName string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         "",
+		SnacModelPath:     "",
+		Voice:             voice,
+		Seed:              0,
+		NGL:               0,
+		TopP:              0.9,
+		RepetitionPenalty: 0,
+		Temperature:       0.6,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             styleName,
+	}
+}
+
+// recordingProcessor records the text and configuration it is called with.
+type recordingProcessor struct {
+	text string
+	cfg  core.TTSConfig
+}
+
+func (p *recordingProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *recordingProcessor) Process(_ context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.text = string(text)
+	p.cfg = cfg
+
+	return []byte("audio"), nil
+}
+
+func newProcessor(inner core.TTSProcessor) *style.Processor {
+	return style.NewProcessor(inner, map[string]style.Style{
+		"excited": {Prefix: "<gasp>", Temperature: 0.9, TopP: 0, Voices: []string{"tara", "leo"}},
+		"calm":    {Prefix: "", Temperature: 0.4, TopP: 0.8, Voices: nil},
+	})
+}
+
+func TestProcessor_AppliesStyle(t *testing.T) {
+	t.Parallel()
+
+	inner := &recordingProcessor{text: "", cfg: core.TTSConfig{}}
+	processor := newProcessor(inner)
+
+	_, err := processor.Process(context.Background(), []byte("We won!"), jobConfig("tara", "excited"))
+	require.NoError(t, err)
+	assert.Equal(t, "<gasp> We won!", inner.text)
+	assert.InDelta(t, 0.9, inner.cfg.Temperature, 1e-9)
+	assert.InDelta(t, 0.9, inner.cfg.TopP, 1e-9, "zero keeps the job's top_p")
+	assert.Equal(t, "excited", inner.cfg.Style, "the style is passed on")
+
+	_, err = processor.Process(context.Background(), []byte("Breathe."), jobConfig("anyone", "calm"))
+	require.NoError(t, err)
+	assert.Equal(t, "Breathe.", inner.text)
+	assert.InDelta(t, 0.4, inner.cfg.Temperature, 1e-9)
+	assert.InDelta(t, 0.8, inner.cfg.TopP, 1e-9)
+
+	_, err = processor.Process(context.Background(), []byte("Plain."), jobConfig("tara", ""))
+	require.NoError(t, err)
+	assert.Equal(t, "Plain.", inner.text)
+	assert.Equal(t, jobConfig("tara", ""), inner.cfg)
+}
+
+func TestProcessor_ValidatesStyle(t *testing.T) {
+	t.Parallel()
+
+	processor := newProcessor(&recordingProcessor{text: "", cfg: core.TTSConfig{}})
+
+	require.NoError(t, processor.ValidateConfig(jobConfig("leo", "excited")))
+	require.NoError(t, processor.ValidateConfig(jobConfig("anyone", "")))
+	require.ErrorIs(t, processor.ValidateConfig(jobConfig("tara", "angry")), style.ErrUnknownStyle)
+	require.ErrorIs(t, processor.ValidateConfig(jobConfig("zoe", "excited")), style.ErrUnsupportedStyle)
+
+	_, err := processor.Process(context.Background(), []byte("text"), jobConfig("zoe", "excited"))
+	require.ErrorIs(t, err, style.ErrUnsupportedStyle)
+}
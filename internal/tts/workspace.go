@@ -0,0 +1,237 @@
+package tts
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/book-expert/logger"
+)
+
+// jobDirPrefix starts the name of every job directory, followed by the
+// instance ID of the service that created it, so the directories of a crashed
+// service can be told apart from those of a running one.
+const jobDirPrefix = "tts-job-"
+
+// Each running service holds a lock on the file named by lockPrefix, its
+// instance ID and lockSuffix in the root, as long as it runs.
+const (
+	lockPrefix = "tts-instance-"
+	lockSuffix = ".lock"
+)
+
+// instanceID identifies this start of the service. Unlike a PID, it is not
+// reused by later starts, e.g. in a container where the service is always
+// PID 1.
+var instanceID = rand.Text()
+
+// instanceLocks are the open lock files of this instance by root. They are
+// never closed, so the locks are held until the process exits.
+var (
+	instanceLocksMu sync.Mutex
+	instanceLocks   = map[string]*os.File{}
+)
+
+// jobOutputName is the file a backend writes a job's audio to.
+const jobOutputName = "output.wav"
+
+// Workspace holds the private directories that backends write a job's files
+// to. Each job gets its own directory, readable only by the service, which is
+// removed with everything in it when the job ends.
+type Workspace struct {
+	// Root is the directory the job directories are created in, e.g. a fast
+	// local disk or a tmpfs. Empty uses the system temp directory.
+	Root string
+}
+
+// root returns the directory job directories are created in.
+func (w Workspace) root() string {
+	if w.Root == "" {
+		return os.TempDir()
+	}
+
+	return w.Root
+}
+
+// Prepare creates the root, locks it for this instance and removes the job
+// directories left behind by services that are no longer running, e.g. after
+// a crash. It returns the number of directories removed.
+func (w Workspace) Prepare() (int, error) {
+	root := w.root()
+
+	err := w.lock()
+	if err != nil {
+		return 0, err
+	}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read work directory %s: %w", root, err)
+	}
+
+	removed := 0
+
+	var errs []error
+
+	for _, entry := range entries {
+		if !entry.IsDir() || !isStaleJobDir(root, entry.Name()) {
+			continue
+		}
+
+		removeErr := os.RemoveAll(filepath.Join(root, entry.Name()))
+		if removeErr != nil {
+			errs = append(errs, removeErr)
+
+			continue
+		}
+
+		removed++
+	}
+
+	for _, entry := range entries {
+		id, found := instanceOfLock(entry.Name())
+		if found && !instanceRunning(root, id) {
+			errs = append(errs, removeStale(filepath.Join(root, entry.Name())))
+		}
+	}
+
+	return removed, errors.Join(errs...)
+}
+
+// lock creates the root and takes this instance's lock in it, unless it
+// already holds it. Jobs are only created in a locked root, so another
+// service never takes their directories for those of a dead one.
+func (w Workspace) lock() error {
+	root := w.root()
+
+	instanceLocksMu.Lock()
+	defer instanceLocksMu.Unlock()
+
+	if instanceLocks[root] != nil {
+		return nil
+	}
+
+	err := os.MkdirAll(root, 0o700)
+	if err != nil {
+		return fmt.Errorf("failed to create work directory %s: %w", root, err)
+	}
+
+	path := filepath.Join(root, lockPrefix+instanceID+lockSuffix)
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600) // #nosec G304 -- the path is built from the root and instance ID
+	if err != nil {
+		return fmt.Errorf("failed to create lock file %s: %w", path, err)
+	}
+
+	err = lockFile(file)
+	if err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("failed to lock %s: %w", path, err)
+	}
+
+	instanceLocks[root] = file
+
+	return nil
+}
+
+// runJob creates a private directory for one job and calls synthesize with it
+// and the path the audio is to be written to. The audio is read back from that
+// path, and the directory is removed with its contents, even on failure.
+func (w Workspace) runJob(log *logger.Logger, synthesize func(dir, output string) error) ([]byte, error) {
+	err := w.lock()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := os.MkdirTemp(w.root(), jobDirPrefix+instanceID+"-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	defer func() {
+		removeErr := os.RemoveAll(dir)
+		if removeErr != nil {
+			log.Warn("Failed to remove job directory '%s': %v", dir, removeErr)
+		}
+	}()
+
+	output := filepath.Join(dir, jobOutputName)
+
+	err = synthesize(dir, output)
+	if err != nil {
+		return nil, err
+	}
+
+	audioData, err := os.ReadFile(output) // #nosec G304 -- the path is inside the job's own directory
+	if err != nil {
+		return nil, fmt.Errorf("failed to read audio data from job directory: %w", err)
+	}
+
+	return audioData, nil
+}
+
+// isStaleJobDir reports whether name is a job directory in root of an
+// instance that is no longer running.
+func isStaleJobDir(root, name string) bool {
+	rest, found := strings.CutPrefix(name, jobDirPrefix)
+	if !found {
+		return false
+	}
+
+	id, _, found := strings.Cut(rest, "-")
+	if !found || id == "" {
+		return false
+	}
+
+	return !instanceRunning(root, id)
+}
+
+// instanceOfLock returns the instance ID of a lock file name.
+func instanceOfLock(name string) (string, bool) {
+	rest, found := strings.CutPrefix(name, lockPrefix)
+	if !found {
+		return "", false
+	}
+
+	id, found := strings.CutSuffix(rest, lockSuffix)
+
+	return id, found && id != ""
+}
+
+// instanceRunning reports whether the instance id holds its lock in root. An
+// instance without a lock file has exited: a running one creates it before
+// its first job directory.
+func instanceRunning(root, id string) bool {
+	if id == instanceID {
+		return true
+	}
+
+	file, err := os.Open(filepath.Join(root, lockPrefix+id+lockSuffix)) // #nosec G304 -- the path is built from the root and instance ID
+	if errors.Is(err, fs.ErrNotExist) {
+		return false
+	}
+
+	if err != nil {
+		return true
+	}
+
+	defer func() { _ = file.Close() }()
+
+	return locked(file)
+}
+
+// removeStale removes the lock file of an instance that is no longer running.
+func removeStale(path string) error {
+	err := os.Remove(path)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove lock file %s: %w", path, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package tts
+
+import "os"
+
+// lockFile does nothing: instance locks are only taken on Unix.
+func lockFile(_ *os.File) error {
+	return nil
+}
+
+// locked reports every lock as held, since it cannot be checked on this
+// platform, so job directories are never taken for those of a dead service.
+func locked(_ *os.File) bool {
+	return true
+}
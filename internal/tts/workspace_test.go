@@ -0,0 +1,51 @@
+package tts_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkspace_PrepareRemovesStaleJobDirs(t *testing.T) {
+	t.Parallel()
+
+	root := filepath.Join(t.TempDir(), "work")
+
+	// An instance without a lock file is not running.
+	gone := filepath.Join(root, "tts-job-GONE-1")
+	require.NoError(t, os.MkdirAll(gone, 0o700))
+	require.NoError(t, os.WriteFile(filepath.Join(gone, "output.wav"), []byte("RIFF"), 0o600))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "models"), 0o700))
+
+	workspace := tts.Workspace{Root: root}
+
+	removed, err := workspace.Prepare()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+	assert.NoDirExists(t, gone)
+	assert.DirExists(t, filepath.Join(root, "models"), "other directories are kept")
+
+	info, err := os.Stat(root)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	// The service's own jobs are kept, even when it prepares the root again.
+	locks, err := filepath.Glob(filepath.Join(root, "tts-instance-*.lock"))
+	require.NoError(t, err)
+	require.Len(t, locks, 1, "the service locks the root")
+
+	ownID := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(locks[0]), "tts-instance-"), ".lock")
+	own := filepath.Join(root, "tts-job-"+ownID+"-2")
+	require.NoError(t, os.MkdirAll(own, 0o700))
+
+	removed, err = workspace.Prepare()
+	require.NoError(t, err)
+	assert.Zero(t, removed)
+	assert.DirExists(t, own)
+	assert.FileExists(t, locks[0])
+}
@@ -0,0 +1,26 @@
+//go:build unix
+
+package tts
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// lockFile takes an exclusive lock on file, held until it is closed.
+func lockFile(file *os.File) error {
+	err := unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB)
+	if err != nil {
+		return fmt.Errorf("flock: %w", err)
+	}
+
+	return nil
+}
+
+// locked reports whether another open file holds the lock on file. A lock
+// that cannot be checked is taken as held.
+func locked(file *os.File) bool {
+	return unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB) != nil
+}
@@ -0,0 +1,43 @@
+//go:build unix
+
+package tts_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestWorkspace_PrepareKeepsJobDirsOfLockedInstances(t *testing.T) {
+	t.Parallel()
+
+	root := t.TempDir()
+
+	for _, id := range []string{"RUNNING", "CRASHED"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "tts-job-"+id+"-1"), 0o700))
+		require.NoError(t, os.WriteFile(filepath.Join(root, "tts-instance-"+id+".lock"), nil, 0o600))
+	}
+
+	// Another service holds its lock; the crashed one's lock was released
+	// when it exited.
+	lock, err := os.Open(filepath.Join(root, "tts-instance-RUNNING.lock"))
+	require.NoError(t, err)
+
+	defer func() { _ = lock.Close() }()
+
+	require.NoError(t, unix.Flock(int(lock.Fd()), unix.LOCK_EX|unix.LOCK_NB))
+
+	removed, err := tts.Workspace{Root: root}.Prepare()
+	require.NoError(t, err)
+	assert.Equal(t, 1, removed)
+
+	assert.DirExists(t, filepath.Join(root, "tts-job-RUNNING-1"), "a running service's jobs are kept")
+	assert.FileExists(t, filepath.Join(root, "tts-instance-RUNNING.lock"))
+	assert.NoDirExists(t, filepath.Join(root, "tts-job-CRASHED-1"))
+	assert.NoFileExists(t, filepath.Join(root, "tts-instance-CRASHED.lock"))
+}
@@ -0,0 +1,270 @@
+// Package wav encodes and decodes PCM audio as WAV files.
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// WAV errors.
+var (
+	ErrInvalidWAV        = errors.New("invalid WAV data")
+	ErrUnsupportedFormat = errors.New("unsupported WAV sample format")
+)
+
+// WAV layout constants for 16-bit PCM.
+const (
+	headerSize     = 44
+	fmtChunkSize   = 16
+	formatPCM      = 1
+	formatFloat    = 3
+	formatExtended = 0xfffe
+	bitsPerSample  = 16
+	bytesPerSample = bitsPerSample / 8
+
+	// riffHeaderSize covers "RIFF", the file size and "WAVE".
+	riffHeaderSize = 12
+	// chunkHeaderSize covers a chunk's ID and size.
+	chunkHeaderSize = 8
+)
+
+// Audio is decoded PCM audio. Samples are interleaved by channel and scaled to [-1, 1].
+type Audio struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// Frames returns the number of samples per channel.
+func (a Audio) Frames() int {
+	if a.Channels <= 0 {
+		return 0
+	}
+
+	return len(a.Samples) / a.Channels
+}
+
+// Info describes a WAV file without its samples.
+type Info struct {
+	SampleRate    int
+	Channels      int
+	BitsPerSample int
+	Frames        int
+}
+
+// Duration returns the playing time of the audio.
+func (i Info) Duration() time.Duration {
+	if i.SampleRate <= 0 {
+		return 0
+	}
+
+	return time.Duration(i.Frames) * time.Second / time.Duration(i.SampleRate)
+}
+
+// EncodePCM16 encodes mono samples in [-1, 1] as a 16-bit PCM WAV file.
+// Samples outside that range are clipped.
+func EncodePCM16(samples []float32, sampleRate int) []byte {
+	return Encode(Audio{SampleRate: sampleRate, Channels: 1, Samples: samples})
+}
+
+// Encode writes audio as a 16-bit PCM WAV file. Samples outside [-1, 1] are clipped.
+func Encode(audio Audio) []byte {
+	channels := max(audio.Channels, 1)
+	dataSize := len(audio.Samples) * bytesPerSample
+
+	var buf bytes.Buffer
+	buf.Grow(headerSize + dataSize)
+
+	buf.WriteString("RIFF")
+	writeUint32(&buf, uint32(headerSize-8+dataSize)) // #nosec G115 -- WAV sizes are 32-bit by definition
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	writeUint32(&buf, fmtChunkSize)
+	writeUint16(&buf, formatPCM)
+	writeUint16(&buf, uint16(channels))                                 // #nosec G115 -- channel counts are small
+	writeUint32(&buf, uint32(audio.SampleRate))                         // #nosec G115 -- sample rates are small
+	writeUint32(&buf, uint32(audio.SampleRate*channels*bytesPerSample)) // #nosec G115 -- byte rate
+	writeUint16(&buf, uint16(channels*bytesPerSample))                  // #nosec G115 -- block align
+	writeUint16(&buf, bitsPerSample)
+
+	buf.WriteString("data")
+	writeUint32(&buf, uint32(dataSize)) // #nosec G115 -- WAV sizes are 32-bit by definition
+
+	for _, sample := range audio.Samples {
+		clipped := max(-1, min(1, float64(sample)))
+		writeUint16(&buf, uint16(int16(math.Round(clipped*math.MaxInt16)))) // #nosec G115 -- two's complement PCM
+	}
+
+	return buf.Bytes()
+}
+
+// format is the parsed "fmt " chunk.
+type format struct {
+	tag           uint16
+	channels      int
+	sampleRate    int
+	bitsPerSample int
+}
+
+// Decode parses a WAV file with 8, 16, 24 or 32-bit integer PCM or 32 or 64-bit
+// float samples. A data chunk size larger than the file, as written by streaming
+// encoders, is read up to the end of the file.
+func Decode(data []byte) (Audio, error) {
+	parsed, samples, err := parseChunks(data)
+	if err != nil {
+		return Audio{}, err
+	}
+
+	return decodeSamples(parsed, samples)
+}
+
+// Inspect reads the format and length of a WAV file without decoding its
+// samples. It accepts the same formats as Decode.
+func Inspect(data []byte) (Info, error) {
+	parsed, samples, err := parseChunks(data)
+	if err != nil {
+		return Info{}, err
+	}
+
+	_, err = sampleConverter(parsed)
+	if err != nil {
+		return Info{}, err
+	}
+
+	return Info{
+		SampleRate:    parsed.sampleRate,
+		Channels:      parsed.channels,
+		BitsPerSample: parsed.bitsPerSample,
+		Frames:        len(samples) / (parsed.bitsPerSample / 8 * parsed.channels),
+	}, nil
+}
+
+// parseChunks walks the RIFF chunks and returns the format and the data chunk.
+func parseChunks(data []byte) (format, []byte, error) {
+	if len(data) < riffHeaderSize || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WAVE" {
+		return format{}, nil, fmt.Errorf("%w: missing RIFF/WAVE header", ErrInvalidWAV)
+	}
+
+	var (
+		parsed    format
+		hasFormat bool
+	)
+
+	offset := riffHeaderSize
+	for offset+chunkHeaderSize <= len(data) {
+		chunkID := string(data[offset : offset+4])
+		chunkSize := int(binary.LittleEndian.Uint32(data[offset+4 : offset+8]))
+		start := offset + chunkHeaderSize
+		end := min(start+chunkSize, len(data))
+
+		switch chunkID {
+		case "fmt ":
+			var err error
+
+			parsed, err = parseFormat(data[start:end])
+			if err != nil {
+				return format{}, nil, err
+			}
+
+			hasFormat = true
+		case "data":
+			if !hasFormat {
+				return format{}, nil, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidWAV)
+			}
+
+			return parsed, data[start:end], nil
+		}
+
+		// Chunks are padded to an even size.
+		offset = end + (end-start)%2
+	}
+
+	return format{}, nil, fmt.Errorf("%w: no data chunk", ErrInvalidWAV)
+}
+
+// parseFormat reads a "fmt " chunk, resolving WAVE_FORMAT_EXTENSIBLE to its sub-format.
+func parseFormat(chunk []byte) (format, error) {
+	if len(chunk) < fmtChunkSize {
+		return format{}, fmt.Errorf("%w: fmt chunk of %d bytes", ErrInvalidWAV, len(chunk))
+	}
+
+	parsed := format{
+		tag:           binary.LittleEndian.Uint16(chunk[0:2]),
+		channels:      int(binary.LittleEndian.Uint16(chunk[2:4])),
+		sampleRate:    int(binary.LittleEndian.Uint32(chunk[4:8])),
+		bitsPerSample: int(binary.LittleEndian.Uint16(chunk[14:16])),
+	}
+
+	// The extensible format stores the real format tag at the start of its sub-format GUID.
+	const subFormatOffset = 24
+	if parsed.tag == formatExtended && len(chunk) >= subFormatOffset+2 {
+		parsed.tag = binary.LittleEndian.Uint16(chunk[subFormatOffset : subFormatOffset+2])
+	}
+
+	if parsed.channels == 0 || parsed.sampleRate == 0 {
+		return format{}, fmt.Errorf("%w: %d channels at %d Hz", ErrInvalidWAV, parsed.channels, parsed.sampleRate)
+	}
+
+	return parsed, nil
+}
+
+// decodeSamples converts the data chunk to interleaved float samples.
+func decodeSamples(parsed format, data []byte) (Audio, error) {
+	convert, err := sampleConverter(parsed)
+	if err != nil {
+		return Audio{}, err
+	}
+
+	width := parsed.bitsPerSample / 8
+	frameSize := width * parsed.channels
+	count := len(data) / frameSize * parsed.channels
+
+	samples := make([]float32, count)
+	for i := range samples {
+		samples[i] = convert(data[i*width : (i+1)*width])
+	}
+
+	return Audio{SampleRate: parsed.sampleRate, Channels: parsed.channels, Samples: samples}, nil
+}
+
+// sampleConverter returns the function that decodes one sample of the format.
+func sampleConverter(parsed format) (func([]byte) float32, error) {
+	switch {
+	case parsed.tag == formatPCM && parsed.bitsPerSample == 8:
+		// 8-bit PCM is unsigned.
+		return func(b []byte) float32 { return (float32(b[0]) - 128) / 128 }, nil
+	case parsed.tag == formatPCM && parsed.bitsPerSample == 16:
+		return func(b []byte) float32 {
+			return float32(int16(binary.LittleEndian.Uint16(b))) / (math.MaxInt16 + 1) // #nosec G115 -- two's complement PCM
+		}, nil
+	case parsed.tag == formatPCM && parsed.bitsPerSample == 24:
+		return func(b []byte) float32 {
+			value := int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24) >> 8 // #nosec G115 -- sign-extends the 24-bit sample
+
+			return float32(value) / (1 << 23)
+		}, nil
+	case parsed.tag == formatPCM && parsed.bitsPerSample == 32:
+		return func(b []byte) float32 {
+			return float32(float64(int32(binary.LittleEndian.Uint32(b))) / (math.MaxInt32 + 1)) // #nosec G115 -- two's complement PCM
+		}, nil
+	case parsed.tag == formatFloat && parsed.bitsPerSample == 32:
+		return func(b []byte) float32 { return math.Float32frombits(binary.LittleEndian.Uint32(b)) }, nil
+	case parsed.tag == formatFloat && parsed.bitsPerSample == 64:
+		return func(b []byte) float32 { return float32(math.Float64frombits(binary.LittleEndian.Uint64(b))) }, nil
+	default:
+		return nil, fmt.Errorf("%w: format %d with %d bits per sample", ErrUnsupportedFormat, parsed.tag, parsed.bitsPerSample)
+	}
+}
+
+func writeUint16(buf *bytes.Buffer, value uint16) {
+	_ = binary.Write(buf, binary.LittleEndian, value)
+}
+
+func writeUint32(buf *bytes.Buffer, value uint32) {
+	_ = binary.Write(buf, binary.LittleEndian, value)
+}
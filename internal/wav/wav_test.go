@@ -0,0 +1,136 @@
+package wav_test
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/wav"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodePCM16(t *testing.T) {
+	t.Parallel()
+
+	data := wav.EncodePCM16([]float32{0, 1, -1, 2}, 24000)
+
+	assert.Len(t, data, 44+4*2)
+	assert.Equal(t, "RIFF", string(data[0:4]))
+	assert.Equal(t, uint32(len(data)-8), binary.LittleEndian.Uint32(data[4:8]))
+	assert.Equal(t, "WAVE", string(data[8:12]))
+	assert.Equal(t, "fmt ", string(data[12:16]))
+	assert.Equal(t, uint16(1), binary.LittleEndian.Uint16(data[22:24]), "mono")
+	assert.Equal(t, uint32(24000), binary.LittleEndian.Uint32(data[24:28]))
+	assert.Equal(t, uint16(16), binary.LittleEndian.Uint16(data[34:36]))
+	assert.Equal(t, "data", string(data[36:40]))
+	assert.Equal(t, uint32(8), binary.LittleEndian.Uint32(data[40:44]))
+
+	samples := make([]int16, 4)
+	for i := range samples {
+		samples[i] = int16(binary.LittleEndian.Uint16(data[44+2*i:]))
+	}
+
+	assert.Equal(t, []int16{0, 32767, -32767, 32767}, samples, "out-of-range samples are clipped")
+}
+
+func TestDecode_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	original := wav.Audio{SampleRate: 44100, Channels: 2, Samples: []float32{0, 0.5, -0.25, 1, -1, 0.125}}
+
+	decoded, err := wav.Decode(wav.Encode(original))
+	require.NoError(t, err)
+
+	assert.Equal(t, 44100, decoded.SampleRate)
+	assert.Equal(t, 2, decoded.Channels)
+	assert.Equal(t, 3, decoded.Frames())
+	require.Len(t, decoded.Samples, len(original.Samples))
+
+	for i, sample := range decoded.Samples {
+		assert.InDelta(t, original.Samples[i], sample, 1.0/32767, "sample %d", i)
+	}
+}
+
+// riff wraps chunks in a RIFF/WAVE header.
+func riff(chunks ...[]byte) []byte {
+	body := []byte("WAVE")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+
+	data := append([]byte("RIFF"), binary.LittleEndian.AppendUint32(nil, uint32(len(body)))...)
+
+	return append(data, body...)
+}
+
+func chunk(id string, size uint32, payload []byte) []byte {
+	data := append([]byte(id), binary.LittleEndian.AppendUint32(nil, size)...)
+
+	return append(data, payload...)
+}
+
+func fmtChunk(tag, channels uint16, sampleRate uint32, bits uint16) []byte {
+	payload := binary.LittleEndian.AppendUint16(nil, tag)
+	payload = binary.LittleEndian.AppendUint16(payload, channels)
+	payload = binary.LittleEndian.AppendUint32(payload, sampleRate)
+	payload = binary.LittleEndian.AppendUint32(payload, sampleRate*uint32(channels*bits/8))
+	payload = binary.LittleEndian.AppendUint16(payload, channels*bits/8)
+	payload = binary.LittleEndian.AppendUint16(payload, bits)
+
+	return chunk("fmt ", uint32(len(payload)), payload)
+}
+
+func TestDecode_Formats(t *testing.T) {
+	t.Parallel()
+
+	// 24-bit PCM: 0.5 and -1, after an odd-sized LIST chunk with its pad byte.
+	data := riff(
+		chunk("LIST", 3, []byte{1, 2, 3, 0}),
+		fmtChunk(1, 1, 48000, 24),
+		chunk("data", 6, []byte{0x00, 0x00, 0x40, 0x00, 0x00, 0x80}),
+	)
+
+	decoded, err := wav.Decode(data)
+	require.NoError(t, err)
+	assert.Equal(t, []float32{0.5, -1}, decoded.Samples)
+
+	// 32-bit float with a streaming data size that runs past the end of the file.
+	payload := binary.LittleEndian.AppendUint32(nil, math.Float32bits(0.75))
+	payload = binary.LittleEndian.AppendUint32(payload, math.Float32bits(-0.5))
+
+	decoded, err = wav.Decode(riff(fmtChunk(3, 2, 22050, 32), chunk("data", math.MaxUint32, payload)))
+	require.NoError(t, err)
+	assert.Equal(t, 22050, decoded.SampleRate)
+	assert.Equal(t, []float32{0.75, -0.5}, decoded.Samples)
+}
+
+func TestDecode_Invalid(t *testing.T) {
+	t.Parallel()
+
+	_, err := wav.Decode([]byte("not a wav file"))
+	require.ErrorIs(t, err, wav.ErrInvalidWAV)
+
+	_, err = wav.Decode(riff(fmtChunk(1, 1, 16000, 16)))
+	require.ErrorIs(t, err, wav.ErrInvalidWAV, "no data chunk")
+
+	_, err = wav.Decode(riff(chunk("data", 2, []byte{0, 0}), fmtChunk(1, 1, 16000, 16)))
+	require.ErrorIs(t, err, wav.ErrInvalidWAV, "data before fmt")
+
+	_, err = wav.Decode(riff(fmtChunk(2, 1, 16000, 4), chunk("data", 2, []byte{0, 0})))
+	require.ErrorIs(t, err, wav.ErrUnsupportedFormat, "ADPCM")
+
+	_, err = wav.Inspect(riff(fmtChunk(2, 1, 16000, 4), chunk("data", 2, []byte{0, 0})))
+	require.ErrorIs(t, err, wav.ErrUnsupportedFormat, "ADPCM")
+}
+
+func TestInspect(t *testing.T) {
+	t.Parallel()
+
+	info, err := wav.Inspect(wav.Encode(wav.Audio{SampleRate: 24000, Channels: 2, Samples: make([]float32, 2*36000)}))
+	require.NoError(t, err)
+
+	assert.Equal(t, wav.Info{SampleRate: 24000, Channels: 2, BitsPerSample: 16, Frames: 36000}, info)
+	assert.Equal(t, 1500*time.Millisecond, info.Duration())
+}
@@ -0,0 +1,406 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/diff"
+	"github.com/book-expert/tts-service/internal/sentence"
+	"github.com/nats-io/nats.go"
+)
+
+// Document limits.
+const (
+	// DefaultChunkChars is the chunk size when a document's text is split and
+	// the document sets no chunk_chars: about a book page.
+	DefaultChunkChars = 2_000
+	// MaxDocumentChunks bounds the chunks of one document.
+	MaxDocumentChunks = 1_000
+)
+
+// ErrChunkCharsRange indicates a document whose chunk_chars is negative or
+// larger than Options.MaxTextChars.
+var ErrChunkCharsRange = errors.New("chunk_chars must be between 0 and the service's max_text_chars")
+
+// documentChunk is one chunk job of a document. Text is nil for chunks that
+// are downloaded from TextKey when their turn comes; textHash is set once the
+// text is known.
+type documentChunk struct {
+	event    core.JobEvent
+	text     []byte
+	textHash string
+}
+
+// handleDocument expands a document into its chunk jobs, synthesizes them in
+// order and replies with the audio of the whole document. The first chunk
+// that fails fails the document: it is reported like a failed job, and the
+// chunks after it are not synthesized.
+func (w *NatsWorker) handleDocument(parent context.Context, msg *nats.Msg) {
+	if !w.awaitCapacity(parent, msg) {
+		return
+	}
+
+	jobTimeout, defaults := w.settings()
+
+	var document core.DocumentProcessedEvent
+
+	err := json.Unmarshal(msg.Data, &document)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+		w.log.Error("Failed to parse document event: %v", err)
+		w.publishFailure(msg, nil, err)
+
+		return
+	}
+
+	event := &document.JobEvent
+
+	timeout, err := w.timeoutFor(event, jobTimeout)
+	if err != nil {
+		w.log.Error("Rejected document for event %s: %v", event.Header.WorkflowID, err)
+		w.publishFailure(msg, event, err)
+
+		return
+	}
+
+	ttsCfg, err := w.jobConfig(event, defaults)
+	if err != nil {
+		w.log.Error("Rejected document for event %s: %v", event.Header.WorkflowID, err)
+		w.publishFailure(msg, event, err)
+
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	chunks, err := w.documentChunks(ctx, &document)
+
+	cancel()
+
+	if err != nil {
+		w.log.Error("Failed to expand document for event %s: %v", event.Header.WorkflowID, err)
+		w.publishFailure(msg, event, err)
+
+		return
+	}
+
+	if w.dryRun != nil {
+		w.estimateDocument(parent, msg, event, chunks, ttsCfg, timeout)
+
+		return
+	}
+
+	configHash := diff.ConfigHash(effectiveConfig(ttsCfg))
+	previous := w.previousManifest(parent, &document)
+
+	reply, err := w.synthesizeDocument(parent, msg, event, chunks, ttsCfg, timeout, diff.NewIndex(previous, configHash))
+	if err != nil {
+		return
+	}
+
+	w.saveManifest(parent, &document, previous, configHash, chunks, reply)
+
+	replyData, err := json.Marshal(reply)
+	if err != nil {
+		w.log.Error("Failed to marshal document reply for workflow %s: %v", event.Header.WorkflowID, err)
+
+		return
+	}
+
+	err = msg.Respond(replyData)
+	if err != nil {
+		w.log.Error("Failed to publish document reply for workflow %s: %v", event.Header.WorkflowID, err)
+	}
+}
+
+// documentChunks returns the chunk jobs of a document. Chunks listed in
+// TextKeys are downloaded later, one at a time; a single TextKey is
+// downloaded now and split.
+func (w *NatsWorker) documentChunks(ctx context.Context, document *core.DocumentProcessedEvent) ([]documentChunk, error) {
+	var texts [][]byte
+
+	keys := document.TextKeys
+
+	if len(keys) == 0 {
+		if document.TextKey == "" {
+			return nil, fmt.Errorf("%w: the document has neither text_keys nor text_key", ErrInvalidEvent)
+		}
+
+		if document.ChunkChars < 0 || document.ChunkChars > w.maxTextChars {
+			return nil, fmt.Errorf("%w: %w: got %d, the limit is %d",
+				ErrInvalidConfig, ErrChunkCharsRange, document.ChunkChars, w.maxTextChars)
+		}
+
+		chunkChars := document.ChunkChars
+		if chunkChars == 0 {
+			chunkChars = min(DefaultChunkChars, w.maxTextChars)
+		}
+
+		textData, err := w.loadText(ctx, document.TextKey, chunkChars*MaxDocumentChunks)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, chunk := range sentence.Chunk(string(textData), chunkChars) {
+			texts = append(texts, []byte(chunk))
+		}
+
+		if len(texts) == 0 {
+			return nil, fmt.Errorf("%w: the text for key '%s' is empty", ErrInvalidText, document.TextKey)
+		}
+
+		keys = make([]string, len(texts))
+		for i := range keys {
+			keys[i] = document.TextKey
+		}
+	}
+
+	if len(keys) > MaxDocumentChunks {
+		return nil, fmt.Errorf("%w: %d chunks, the limit is %d", ErrTextTooLong, len(keys), MaxDocumentChunks)
+	}
+
+	chunks := make([]documentChunk, len(keys))
+
+	for i, key := range keys {
+		chunks[i].event = document.JobEvent
+		chunks[i].event.TextKey = key
+		chunks[i].event.PageNumber = i + 1
+		chunks[i].event.TotalPages = len(keys)
+
+		if texts != nil {
+			chunks[i].text = texts[i]
+		}
+	}
+
+	return chunks, nil
+}
+
+// synthesizeDocument synthesizes the chunks in order, each under its own
+// timeout, and describes the finished document. Chunks whose text is in
+// previous reuse that audio instead. All chunks are recorded as received
+// first, so the workflow status counts the whole document.
+func (w *NatsWorker) synthesizeDocument(
+	parent context.Context,
+	msg *nats.Msg,
+	event *core.JobEvent,
+	chunks []documentChunk,
+	ttsCfg core.TTSConfig,
+	timeout time.Duration,
+	previous diff.Index,
+) (*core.AudioDocumentCreatedEvent, error) {
+	for i := range chunks {
+		w.recordStatus(parent, &chunks[i].event.TextProcessedEvent, core.JobStateReceived, "", nil)
+	}
+
+	reply := &core.AudioDocumentCreatedEvent{
+		Header:          event.Header,
+		AudioKeys:       make([]string, 0, len(chunks)),
+		Chunks:          make([]core.AudioChunkEvent, 0, len(chunks)),
+		DurationSeconds: 0,
+		ReusedChunks:    0,
+		Build:           buildinfo.Get(),
+	}
+
+	for i := range chunks {
+		chunk := &chunks[i]
+
+		chunkReply, reused, err := w.synthesizeChunk(parent, chunk, ttsCfg, timeout, previous)
+		if err != nil {
+			w.log.Error("Failed to process chunk %d of %d of the document for event %s: %v",
+				chunk.event.PageNumber, chunk.event.TotalPages, event.Header.WorkflowID, err)
+			w.recordStatus(parent, &chunk.event.TextProcessedEvent, core.JobStateFailed, "", err)
+			w.publishFailure(msg, &chunk.event, err)
+
+			return nil, err
+		}
+
+		w.recordStatus(parent, &chunk.event.TextProcessedEvent, core.JobStateCompleted, chunkReply.AudioKey, nil)
+
+		if reused {
+			reply.ReusedChunks++
+		}
+
+		reply.AudioKeys = append(reply.AudioKeys, chunkReply.AudioKey)
+		reply.Chunks = append(reply.Chunks, *chunkReply)
+		reply.DurationSeconds += chunkReply.DurationSeconds
+	}
+
+	return reply, nil
+}
+
+// synthesizeChunk downloads the chunk's text when needed and synthesizes it,
+// unless previous has audio for the same text. It reports whether the audio
+// was reused.
+func (w *NatsWorker) synthesizeChunk(
+	parent context.Context,
+	chunk *documentChunk,
+	ttsCfg core.TTSConfig,
+	timeout time.Duration,
+	previous diff.Index,
+) (*core.AudioChunkEvent, bool, error) {
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	textData := chunk.text
+	if textData == nil {
+		var err error
+
+		textData, err = w.loadText(ctx, chunk.event.TextKey, w.maxTextChars)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	textData, err := w.filterText(ctx, &chunk.event, textData)
+	if err != nil {
+		return nil, false, err
+	}
+
+	chunk.textHash = diff.Hash(textData)
+
+	reused, ok := previous.Lookup(chunk.textHash)
+	if ok {
+		reused.Header = chunk.event.Header
+		reused.PageNumber = chunk.event.PageNumber
+		reused.TotalPages = chunk.event.TotalPages
+
+		return &reused, true, nil
+	}
+
+	chunkReply, err := w.synthesize(ctx, &chunk.event, textData, ttsCfg)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return chunkReply, false, nil
+}
+
+// previousManifest returns the manifest of the document's previous version,
+// or an empty one when there is none or manifests are not kept.
+func (w *NatsWorker) previousManifest(parent context.Context, document *core.DocumentProcessedEvent) core.DocumentManifest {
+	if w.manifests == nil || document.DocumentID == "" {
+		return core.DocumentManifest{}
+	}
+
+	ctx, cancel := context.WithTimeout(parent, handleMessageTimeout)
+	defer cancel()
+
+	manifest, err := w.manifests.Get(ctx, document.DocumentID)
+	if err != nil {
+		if !errors.Is(err, diff.ErrManifestNotFound) {
+			w.log.Warn("Synthesizing every chunk of document %s: %v", document.DocumentID, err)
+		}
+
+		return core.DocumentManifest{}
+	}
+
+	return manifest
+}
+
+// saveManifest records the chunks of a synthesized document as its latest
+// version and logs what changed since the previous one. Failures are logged,
+// since the document is done.
+func (w *NatsWorker) saveManifest(
+	parent context.Context,
+	document *core.DocumentProcessedEvent,
+	previous core.DocumentManifest,
+	configHash string,
+	chunks []documentChunk,
+	reply *core.AudioDocumentCreatedEvent,
+) {
+	if w.manifests == nil || document.DocumentID == "" {
+		return
+	}
+
+	textHashes := make([]string, len(chunks))
+	for i := range chunks {
+		textHashes[i] = chunks[i].textHash
+	}
+
+	changes := diff.Compare(previous, configHash, textHashes)
+	w.log.Info("Document %s: %d chunks unchanged, %d changed or added, %d removed",
+		document.DocumentID, changes.Unchanged, changes.Changed, changes.Removed)
+
+	ctx, cancel := context.WithTimeout(parent, handleMessageTimeout)
+	defer cancel()
+
+	err := w.manifests.Put(ctx, core.DocumentManifest{
+		DocumentID: document.DocumentID,
+		ConfigHash: configHash,
+		TextHashes: textHashes,
+		Chunks:     reply.Chunks,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		w.log.Warn("Failed to record the manifest of document %s: %v", document.DocumentID, err)
+	}
+}
+
+// estimateDocument replies to a document in dry-run mode with one estimate
+// for all of its text. TotalPages is the number of chunks.
+func (w *NatsWorker) estimateDocument(
+	parent context.Context,
+	msg *nats.Msg,
+	event *core.JobEvent,
+	chunks []documentChunk,
+	ttsCfg core.TTSConfig,
+	timeout time.Duration,
+) {
+	var text []byte
+
+	for i := range chunks {
+		ctx, cancel := context.WithTimeout(parent, timeout)
+
+		var err error
+
+		textData := chunks[i].text
+		if textData == nil {
+			textData, err = w.loadText(ctx, chunks[i].event.TextKey, w.maxTextChars)
+		}
+
+		if err == nil {
+			textData, err = w.filterText(ctx, &chunks[i].event, textData)
+		}
+
+		cancel()
+
+		if err != nil {
+			w.log.Error("Dry run of document for event %s failed: %v", event.Header.WorkflowID, err)
+			w.publishFailure(msg, &chunks[i].event, err)
+
+			return
+		}
+
+		text = append(append(text, textData...), ' ')
+	}
+
+	_, stats := w.reportText(event, text, ttsCfg.Rate)
+	estimate := w.dryRun.Estimate(text, ttsCfg.Rate)
+
+	replyData, err := json.Marshal(core.JobEstimate{
+		Header:                     event.Header,
+		PageNumber:                 0,
+		TotalPages:                 len(chunks),
+		DryRun:                     true,
+		Characters:                 estimate.Characters,
+		Words:                      estimate.Words,
+		EstimatedDurationSeconds:   estimate.Duration.Seconds(),
+		EstimatedProcessingSeconds: estimate.Processing.Seconds(),
+		Config:                     effectiveConfig(ttsCfg),
+		TextStats:                  stats,
+	})
+	if err != nil {
+		w.log.Error("Failed to marshal dry-run estimate: %v", err)
+
+		return
+	}
+
+	err = msg.Respond(replyData)
+	if err != nil {
+		w.log.Error("Failed to publish dry-run estimate for workflow %s: %v", event.Header.WorkflowID, err)
+	}
+}
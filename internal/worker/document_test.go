@@ -0,0 +1,288 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/diff"
+	"github.com/book-expert/tts-service/internal/worker"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// textRecorder records every text it synthesizes, in order.
+type textRecorder struct {
+	mu    sync.Mutex
+	texts []string
+}
+
+func (p *textRecorder) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *textRecorder) Process(_ context.Context, text []byte, _ core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.texts = append(p.texts, string(text))
+
+	return sampleAudio, nil
+}
+
+func (p *textRecorder) recorded() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]string(nil), p.texts...)
+}
+
+// memoryManifests is a manifest store kept in memory.
+type memoryManifests struct {
+	mu        sync.Mutex
+	manifests map[string]core.DocumentManifest
+}
+
+func (m *memoryManifests) Put(_ context.Context, manifest core.DocumentManifest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.manifests[manifest.DocumentID] = manifest
+
+	return nil
+}
+
+func (m *memoryManifests) Get(_ context.Context, documentID string) (core.DocumentManifest, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	manifest, ok := m.manifests[documentID]
+	if !ok {
+		return core.DocumentManifest{}, diff.ErrManifestNotFound
+	}
+
+	return manifest, nil
+}
+
+func documentOptions(statusStore core.JobStatusStore) worker.Options {
+	return worker.Options{
+		StatusStore:        statusStore,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "test_documents",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "test_failed",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       20,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	}
+}
+
+func newDocument(textKey string, textKeys []string, chunkChars int) core.DocumentProcessedEvent {
+	return core.DocumentProcessedEvent{
+		JobEvent: core.JobEvent{
+			TextProcessedEvent: *newTestEvent(textKey),
+			Model:              "",
+			Language:           "",
+			Rate:               0,
+			Pitch:              0,
+			Style:              "",
+			TimeoutSeconds:     0,
+		},
+		TextKeys:   textKeys,
+		ChunkChars: chunkChars,
+	}
+}
+
+func requestDocument(t *testing.T, natsConnection *nats.Conn, document core.DocumentProcessedEvent) core.AudioDocumentCreatedEvent {
+	t.Helper()
+
+	data, err := json.Marshal(document)
+	require.NoError(t, err)
+
+	reply := requestWhenReady(t, natsConnection, "test_documents", data)
+
+	var created core.AudioDocumentCreatedEvent
+
+	require.NoError(t, json.Unmarshal(reply.Data, &created))
+
+	return created
+}
+
+func TestDocument_TextKeys(t *testing.T) {
+	t.Parallel()
+
+	statusStore := newMockStatusStore()
+	recorder := &textRecorder{mu: sync.Mutex{}, texts: nil}
+
+	workerInstance, mockStore, ctx, cancel, natsConnection := setupTestWithProcessor(t, recorder, documentOptions(statusStore))
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{
+		"page-1": []byte("First page."),
+		"page-2": []byte("Second page."),
+		"page-3": []byte("Third page."),
+	}
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	document := newDocument("", []string{"page-1", "page-2", "page-3"}, 0)
+	created := requestDocument(t, natsConnection, document)
+
+	assert.Equal(t, []string{"First page.", "Second page.", "Third page."}, recorder.recorded())
+	assert.Equal(t, document.Header.WorkflowID, created.Header.WorkflowID)
+	require.Len(t, created.AudioKeys, 3)
+	require.Len(t, created.Chunks, 3)
+
+	for i, chunk := range created.Chunks {
+		assert.Equal(t, created.AudioKeys[i], chunk.AudioKey, "the audio keys are in chunk order")
+		assert.Equal(t, i+1, chunk.PageNumber)
+		assert.Equal(t, 3, chunk.TotalPages)
+	}
+
+	assert.InDelta(t, 4.5, created.DurationSeconds, 1e-9)
+
+	status, err := statusStore.Get(ctx, document.Header.WorkflowID)
+	require.NoError(t, err)
+	assert.Equal(t, core.JobStateCompleted, status.State)
+	assert.Equal(t, 3, status.PagesCompleted, "the chunks are tracked as the pages of the workflow")
+}
+
+func TestDocument_SplitsText(t *testing.T) {
+	t.Parallel()
+
+	recorder := &textRecorder{mu: sync.Mutex{}, texts: nil}
+
+	workerInstance, mockStore, ctx, cancel, natsConnection := setupTestWithProcessor(t, recorder, documentOptions(nil))
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{
+		"book": []byte("It cost 3.5 coins. \"Why?\" Bob asked.\n\nExtraordinarily expensive."),
+	}
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	created := requestDocument(t, natsConnection, newDocument("book", nil, 20))
+
+	assert.Equal(t, []string{
+		"It cost 3.5 coins.",
+		"\"Why?\" Bob asked.",
+		"Extraordinarily",
+		"expensive.",
+	}, recorder.recorded(), "chunks end at sentences, else between words")
+	assert.Len(t, created.AudioKeys, 4)
+}
+
+func TestDocument_ReusesUnchangedChunks(t *testing.T) {
+	t.Parallel()
+
+	recorder := &textRecorder{mu: sync.Mutex{}, texts: nil}
+	manifests := &memoryManifests{mu: sync.Mutex{}, manifests: map[string]core.DocumentManifest{}}
+
+	opts := documentOptions(nil)
+	opts.Manifests = manifests
+
+	workerInstance, mockStore, ctx, cancel, natsConnection := setupTestWithProcessor(t, recorder, opts)
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{
+		"v1": []byte("First page.\nSecond page.\nThird page."),
+		"v2": []byte("First page.\nSecond page, revised.\nThird\tpage.\nFourth page."),
+	}
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	first := newDocument("v1", nil, 15)
+	first.DocumentID = "book-1"
+	original := requestDocument(t, natsConnection, first)
+
+	assert.Zero(t, original.ReusedChunks)
+	assert.Len(t, recorder.recorded(), 3)
+
+	second := newDocument("v2", nil, 15)
+	second.DocumentID = "book-1"
+	revised := requestDocument(t, natsConnection, second)
+
+	assert.Equal(t, []string{"Second page,", "revised.", "Fourth page."}, recorder.recorded()[3:],
+		"only changed and added chunks are synthesized; layout changes do not count")
+	assert.Equal(t, 2, revised.ReusedChunks)
+	require.Len(t, revised.Chunks, 5)
+	assert.Equal(t, original.AudioKeys[0], revised.AudioKeys[0])
+	assert.Equal(t, original.AudioKeys[2], revised.AudioKeys[3], "the third page reuses its audio")
+	assert.Equal(t, second.Header.WorkflowID, revised.Chunks[3].Header.WorkflowID)
+	assert.Equal(t, 4, revised.Chunks[3].PageNumber)
+	assert.Equal(t, 5, revised.Chunks[3].TotalPages)
+
+	third := newDocument("v2", nil, 15)
+	third.DocumentID = "book-1"
+	third.Voice = "male1"
+	requestDocument(t, natsConnection, third)
+
+	assert.Len(t, recorder.recorded(), 11, "a new voice synthesizes every chunk")
+}
+
+func TestDocument_Failures(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, mockStore, _, ctx, cancel, natsConnection := setupTest(t, documentOptions(nil))
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{"long": []byte("This page is far too long for one chunk.")}
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	// The first request waits for the worker's subscription.
+	requestDocument(t, natsConnection, newDocument("", []string{"short"}, 0))
+
+	for name, test := range map[string]struct {
+		document  core.DocumentProcessedEvent
+		wantClass core.ErrorClass
+		wantPage  int
+	}{
+		"no text":          {newDocument("", nil, 0), core.ErrorClassInvalidEvent, 1},
+		"chunks too large": {newDocument("book", nil, 21), core.ErrorClassInvalidConfig, 1},
+		"chunk too long":   {newDocument("", []string{"short", "long", "short"}, 0), core.ErrorClassTextTooLong, 2},
+	} {
+		data, marshalErr := json.Marshal(test.document)
+		require.NoError(t, marshalErr)
+		require.NoError(t, natsConnection.Publish("test_documents", data))
+
+		msg, nextErr := failures.NextMsg(5 * time.Second)
+		require.NoError(t, nextErr, name)
+
+		var failure core.TTSJobFailedEvent
+
+		require.NoError(t, json.Unmarshal(msg.Data, &failure))
+		assert.Equal(t, test.wantClass, failure.ErrorClass, name)
+		assert.Equal(t, test.wantPage, failure.PageNumber, name)
+		assert.JSONEq(t, string(data), string(failure.Event), name)
+	}
+}
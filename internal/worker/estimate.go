@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Dry-run estimate defaults.
+const (
+	// DefaultCharsPerSecond is a typical narration pace: about 150 words per minute.
+	DefaultCharsPerSecond = 15.0
+	// DefaultRealTimeFactor assumes synthesis takes as long as playback.
+	DefaultRealTimeFactor = 1.0
+)
+
+// Estimator predicts a job's audio duration and processing time from its text,
+// for dry runs. Zero fields use the defaults.
+type Estimator struct {
+	// CharsPerSecond is the speaking pace at rate 1, counting letters, digits,
+	// punctuation and single spaces between words.
+	CharsPerSecond float64
+	// RealTimeFactor is the processing time per second of audio.
+	RealTimeFactor float64
+}
+
+// Estimate is the outcome of Estimator.Estimate.
+type Estimate struct {
+	Characters int
+	Words      int
+	Duration   time.Duration
+	Processing time.Duration
+}
+
+// Estimate predicts the audio for text spoken at rate, where zero means 1.
+// Runs of whitespace count as one character, so layout does not inflate it.
+func (e Estimator) Estimate(text []byte, rate float64) Estimate {
+	charsPerSecond := e.CharsPerSecond
+	if charsPerSecond <= 0 {
+		charsPerSecond = DefaultCharsPerSecond
+	}
+
+	realTimeFactor := e.RealTimeFactor
+	if realTimeFactor <= 0 {
+		realTimeFactor = DefaultRealTimeFactor
+	}
+
+	if rate <= 0 {
+		rate = 1
+	}
+
+	words := strings.Fields(string(text))
+	characters := utf8.RuneCountInString(strings.Join(words, " "))
+	seconds := float64(characters) / charsPerSecond / rate
+
+	return Estimate{
+		Characters: characters,
+		Words:      len(words),
+		Duration:   time.Duration(seconds * float64(time.Second)),
+		Processing: time.Duration(seconds * realTimeFactor * float64(time.Second)),
+	}
+}
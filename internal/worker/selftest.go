@@ -0,0 +1,49 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/wav"
+)
+
+// SelfTestText is the text synthesized by SelfTest.
+const SelfTestText = "Hello."
+
+// ErrSelfTestFailed indicates that the startup self-test produced no usable audio.
+var ErrSelfTestFailed = errors.New("self-test failed")
+
+// SelfTest synthesizes SelfTestText the way a job without settings would be:
+// with the default model, voice and job defaults. It checks that the result is
+// WAV audio with at least one frame. Running it before Run makes a worker with
+// a missing model or backend binary fail at startup instead of on every job.
+func (w *NatsWorker) SelfTest(ctx context.Context) (wav.Info, error) {
+	_, defaults := w.settings()
+
+	var event core.JobEvent
+
+	cfg, err := w.jobConfig(&event, defaults)
+	if err != nil {
+		return wav.Info{}, fmt.Errorf("%w: %w", ErrSelfTestFailed, err)
+	}
+
+	audioData, err := w.processor.Process(ctx, []byte(SelfTestText), cfg)
+	if err != nil {
+		return wav.Info{}, fmt.Errorf("%w: synthesis with model '%s' and voice '%s': %w",
+			ErrSelfTestFailed, cfg.ModelPath, cfg.Voice, err)
+	}
+
+	info, err := wav.Inspect(audioData)
+	if err != nil {
+		return wav.Info{}, fmt.Errorf("%w: the %d bytes of output are not WAV audio: %w",
+			ErrSelfTestFailed, len(audioData), err)
+	}
+
+	if info.Frames == 0 {
+		return wav.Info{}, fmt.Errorf("%w: the audio is empty", ErrSelfTestFailed)
+	}
+
+	return info, nil
+}
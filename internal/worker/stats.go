@@ -0,0 +1,187 @@
+package worker
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/sentence"
+)
+
+// Text report defaults.
+const (
+	// DefaultMaxSentenceWords is the longest sentence, in words, that is not
+	// flagged as long: beyond it, models tend to rush, drift or stop early.
+	DefaultMaxSentenceWords = 60
+	// startWords is the number of words that identify a long sentence.
+	startWords = 8
+)
+
+// clauseEnds are the marks after which a long sentence is preferably split.
+const clauseEnds = ",;:—)"
+
+// TextReport describes the text of every job before synthesis and flags
+// sentences that are too long. Zero fields use the defaults.
+type TextReport struct {
+	// Estimator predicts the audio duration of the text.
+	Estimator Estimator
+	// MaxSentenceWords is the longest sentence that is not flagged.
+	MaxSentenceWords int
+	// SplitLongSentences puts line breaks into the flagged sentences, at the
+	// clause boundaries nearest their middle or else between words, until no
+	// piece is too long. Line breaks end a part for pauses, prosody hints and
+	// document chunks, and are read as spaces otherwise.
+	SplitLongSentences bool
+}
+
+// Analyze returns the statistics of text spoken at rate, where zero means 1,
+// and the text to synthesize: text itself, or text with its long sentences
+// split when SplitLongSentences is set.
+func (r TextReport) Analyze(text []byte, rate float64) ([]byte, core.TextStats) {
+	maxWords := r.MaxSentenceWords
+	if maxWords <= 0 {
+		maxWords = DefaultMaxSentenceWords
+	}
+
+	estimate := r.Estimator.Estimate(text, rate)
+
+	var (
+		stats   core.TextStats
+		lengths []int
+		output  strings.Builder
+	)
+
+	stats.Words = estimate.Words
+	stats.EstimatedDurationSeconds = estimate.Duration.Seconds()
+
+	for _, piece := range sentence.Split(string(text)) {
+		words := len(strings.Fields(piece))
+		if words == 0 {
+			output.WriteString(piece)
+
+			continue
+		}
+
+		lengths = append(lengths, words)
+
+		if words <= maxWords {
+			output.WriteString(piece)
+
+			continue
+		}
+
+		stats.LongSentences = append(stats.LongSentences, core.LongSentence{
+			Index: len(lengths),
+			Words: words,
+			Start: start(piece),
+		})
+
+		if !r.SplitLongSentences {
+			output.WriteString(piece)
+
+			continue
+		}
+
+		body := strings.TrimSpace(piece)
+		leading := piece[:strings.Index(piece, body)]
+		trailing := piece[len(leading)+len(body):]
+
+		output.WriteString(leading + strings.Join(splitSentence(body, maxWords), "\n") + trailing)
+
+		stats.SplitSentences++
+	}
+
+	stats.Sentences = len(lengths)
+	stats.SentenceWords = distribution(lengths)
+
+	return []byte(output.String()), stats
+}
+
+// start returns the first words of a sentence.
+func start(sentence string) string {
+	words := strings.Fields(sentence)
+	if len(words) <= startWords {
+		return strings.Join(words, " ")
+	}
+
+	return strings.Join(words[:startWords], " ") + "…"
+}
+
+// distribution summarizes sentence lengths.
+func distribution(lengths []int) core.SentenceLengths {
+	if len(lengths) == 0 {
+		return core.SentenceLengths{Min: 0, Median: 0, P90: 0, Max: 0, Mean: 0}
+	}
+
+	sorted := slices.Clone(lengths)
+	slices.Sort(sorted)
+
+	total := 0
+	for _, length := range sorted {
+		total += length
+	}
+
+	return core.SentenceLengths{
+		Min:    sorted[0],
+		Median: sorted[(len(sorted)-1)/2],
+		P90:    sorted[int(math.Ceil(0.9*float64(len(sorted))))-1],
+		Max:    sorted[len(sorted)-1],
+		Mean:   float64(total) / float64(len(sorted)),
+	}
+}
+
+// splitSentence splits a trimmed sentence into pieces of at most maxWords
+// words, each time at the clause boundary nearest the middle, or at the
+// space nearest the middle when there is none.
+func splitSentence(text string, maxWords int) []string {
+	total := len(strings.Fields(text))
+	if total <= maxWords {
+		return []string{text}
+	}
+
+	best, bestClause, bestDistance := -1, false, 0
+	words, inWord := 0, false
+
+	for i, r := range text {
+		if !unicode.IsSpace(r) {
+			if !inWord {
+				words++
+			}
+
+			inWord = true
+
+			continue
+		}
+
+		inWord = false
+
+		if r != ' ' || words == 0 {
+			continue
+		}
+
+		distance := words - total/2
+		if distance < 0 {
+			distance = -distance
+		}
+
+		// Clause boundaries win when they are in the middle half of the sentence.
+		before, _ := utf8.DecodeLastRuneInString(strings.TrimRightFunc(text[:i], unicode.IsSpace))
+		clause := strings.ContainsRune(clauseEnds, before) && distance <= total/4
+
+		if best < 0 || (clause && !bestClause) || (clause == bestClause && distance < bestDistance) {
+			best, bestClause, bestDistance = i, clause, distance
+		}
+	}
+
+	if best < 0 {
+		return []string{text}
+	}
+
+	return append(
+		splitSentence(strings.TrimSpace(text[:best]), maxWords),
+		splitSentence(strings.TrimSpace(text[best:]), maxWords)...,
+	)
+}
@@ -0,0 +1,109 @@
+package worker_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/worker"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTextReport_Analyze(t *testing.T) {
+	t.Parallel()
+
+	long := "The rain fell on the roofs and the gardens, on the old church and the new school, " +
+		"on the river and the bridge and the boats that waited there for morning."
+	text := "It rained. Everyone stayed in, reading.\n\n" + long + " Then it stopped."
+
+	report := worker.TextReport{
+		Estimator:          worker.Estimator{CharsPerSecond: 10, RealTimeFactor: 0},
+		MaxSentenceWords:   12,
+		SplitLongSentences: false,
+	}
+
+	output, stats := report.Analyze([]byte(text), 2)
+	assert.Equal(t, text, string(output), "the text is unchanged without splitting")
+
+	assert.Equal(t, len(strings.Fields(text)), stats.Words)
+	assert.Equal(t, 4, stats.Sentences)
+	assert.InDelta(t, float64(len(strings.Join(strings.Fields(text), " ")))/10/2, stats.EstimatedDurationSeconds, 1e-9)
+	assert.Equal(t, core.SentenceLengths{Min: 2, Median: 3, P90: 31, Max: 31, Mean: 10}, stats.SentenceWords)
+	assert.Equal(t, []core.LongSentence{{Index: 3, Words: 31, Start: "The rain fell on the roofs and the…"}}, stats.LongSentences)
+	assert.Zero(t, stats.SplitSentences)
+}
+
+func TestTextReport_SplitsLongSentences(t *testing.T) {
+	t.Parallel()
+
+	long := "The rain fell on the roofs and the gardens, on the old church and the new school, " +
+		"on the river and the bridge and the boats that waited there for morning."
+	report := worker.TextReport{
+		Estimator:          worker.Estimator{CharsPerSecond: 0, RealTimeFactor: 0},
+		MaxSentenceWords:   12,
+		SplitLongSentences: true,
+	}
+
+	output, stats := report.Analyze([]byte("It rained. "+long+"\n\nThen it stopped."), 0)
+	require.Equal(t, 1, stats.SplitSentences)
+
+	assert.Equal(t, "It rained. The rain fell on the roofs and the gardens,\n"+
+		"on the old church and the new school,\n"+
+		"on the river and the bridge and\nthe boats that waited there for morning.\n\nThen it stopped.", string(output))
+
+	for _, line := range strings.Split(string(output), "\n") {
+		assert.LessOrEqual(t, len(strings.Fields(line)), 12)
+	}
+}
+
+func TestTextReport_Empty(t *testing.T) {
+	t.Parallel()
+
+	_, stats := worker.TextReport{}.Analyze([]byte("  \n "), 0)
+	assert.Zero(t, stats.Sentences)
+	assert.Equal(t, core.SentenceLengths{Min: 0, Median: 0, P90: 0, Max: 0, Mean: 0}, stats.SentenceWords)
+}
+
+// FuzzTextReport_Analyze checks that splitting long sentences never panics or
+// loops, keeps every word of the text and keeps the text valid UTF-8.
+func FuzzTextReport_Analyze(f *testing.F) {
+	for _, seed := range []struct {
+		text     string
+		maxWords int
+	}{
+		{"It rained. Everyone stayed in, reading.\n\nThen it stopped.", 3},
+		{"On the roofs, on the gardens; on the church — and the school.", 1},
+		{"日本語 の 文章 です。 Ünïcödé… words and breaks", 2},
+		{" \xff\xfe . . \n", 0},
+	} {
+		f.Add(seed.text, seed.maxWords)
+	}
+
+	f.Fuzz(func(t *testing.T, text string, maxWords int) {
+		report := worker.TextReport{
+			Estimator:          worker.Estimator{CharsPerSecond: 0, RealTimeFactor: 0},
+			MaxSentenceWords:   maxWords % 20,
+			SplitLongSentences: true,
+		}
+
+		output, stats := report.Analyze([]byte(text), 0)
+		if !utf8.ValidString(text) {
+			return
+		}
+
+		if !utf8.Valid(output) {
+			t.Fatalf("output %q of valid text is not valid UTF-8", output)
+		}
+
+		if got, want := strings.Fields(string(output)), strings.Fields(text); !slices.Equal(got, want) {
+			t.Fatalf("output %q changes the words of %q", output, text)
+		}
+
+		if stats.SplitSentences > stats.Sentences {
+			t.Fatalf("%d of %d sentences split", stats.SplitSentences, stats.Sentences)
+		}
+	})
+}
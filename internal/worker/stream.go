@@ -0,0 +1,496 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/book-expert/events"
+	"github.com/book-expert/tts-service/internal/buildinfo"
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/sentence"
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// Stream limits.
+const (
+	// DefaultStreamIdleTimeout ends a stream that has had no delta for this
+	// long when Options.StreamIdleTimeout is not set.
+	DefaultStreamIdleTimeout = time.Minute
+	// StreamSegmentChars is the most characters of one segment. Text without a
+	// sentence end is synthesized once it grows longer, cut between words.
+	StreamSegmentChars = 500
+)
+
+// textStream is an open text stream: the text not yet cut into segments, and
+// the segments waiting for its synthesizer.
+type textStream struct {
+	// event is the first delta, whose header and settings apply to the stream.
+	event   core.TextDeltaEvent
+	ttsCfg  core.TTSConfig
+	timeout time.Duration
+	// mu guards the fields below it.
+	mu sync.Mutex
+	// last is the latest delta, reported when a segment of it fails.
+	last *nats.Msg
+	text strings.Builder
+	// pending are the segments waiting for the synthesizer, which ready
+	// wakes when one is added or the stream closes.
+	pending []streamSegment
+	ready   chan struct{}
+	idle    *time.Timer
+	// touched is when the latest delta arrived.
+	touched time.Time
+	// closed is set once the stream has no more segments; later deltas open
+	// a new stream.
+	closed bool
+	// failed is set once a segment fails; the rest of the stream is dropped.
+	failed atomic.Bool
+}
+
+// streamSegment is a piece of a stream's text to synthesize. The final
+// segment may be empty when the stream ended on a complete segment.
+type streamSegment struct {
+	text  string
+	final bool
+	msg   *nats.Msg
+}
+
+// textStreams holds the open streams of a worker by workflow ID and tracks
+// their synthesizers, so Run can wait for them. A stream's lock may be held
+// while mu is taken, but not the other way round.
+type textStreams struct {
+	mu      sync.Mutex
+	streams map[string]*textStream
+	running sync.WaitGroup
+}
+
+func newTextStreams() *textStreams {
+	return &textStreams{mu: sync.Mutex{}, streams: map[string]*textStream{}, running: sync.WaitGroup{}}
+}
+
+// handleTextDelta appends a delta to its stream, opening the stream on its
+// first delta, and hands the segments that are complete to the stream's
+// synthesizer, whose segments take slots like jobs. It runs on the
+// subscription's goroutine, so the deltas of a stream are applied in order,
+// and it does not wait for synthesis.
+func (w *NatsWorker) handleTextDelta(ctx context.Context, slots *jobSlots, msg *nats.Msg) {
+	var delta core.TextDeltaEvent
+
+	err := json.Unmarshal(msg.Data, &delta)
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+		w.log.Error("Failed to parse text delta: %v", err)
+		w.publishFailure(msg, nil, err)
+
+		return
+	}
+
+	streamID := delta.Header.WorkflowID
+	if streamID == "" {
+		err = fmt.Errorf("%w: the text delta has no workflow_id", ErrInvalidEvent)
+		w.log.Error("Rejected text delta: %v", err)
+		w.publishFailure(msg, &delta.JobEvent, err)
+
+		return
+	}
+
+	// A stream that ended while the delta waited for its lock is replaced.
+	for {
+		if w.applyDelta(w.stream(ctx, slots, msg, &delta), msg, &delta) {
+			return
+		}
+	}
+}
+
+// stream returns the open stream of a delta, opening it on its first delta.
+func (w *NatsWorker) stream(
+	ctx context.Context,
+	slots *jobSlots,
+	msg *nats.Msg,
+	delta *core.TextDeltaEvent,
+) *textStream {
+	w.streams.mu.Lock()
+	defer w.streams.mu.Unlock()
+
+	streamID := delta.Header.WorkflowID
+
+	stream, ok := w.streams.streams[streamID]
+	if !ok {
+		stream = w.openStream(ctx, slots, msg, delta)
+		w.streams.streams[streamID] = stream
+	}
+
+	return stream
+}
+
+// applyDelta appends a delta to its stream and hands the segments that are
+// complete to its synthesizer, ending the stream on a final delta. It returns
+// false, without applying the delta, when the stream has already ended.
+func (w *NatsWorker) applyDelta(stream *textStream, msg *nats.Msg, delta *core.TextDeltaEvent) bool {
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+
+	if stream.closed {
+		return false
+	}
+
+	stream.last = msg
+	stream.touched = time.Now()
+	stream.text.WriteString(delta.Text)
+
+	if delta.Final {
+		w.endStream(stream)
+
+		return true
+	}
+
+	stream.idle.Reset(w.streamIdleTimeout)
+	w.sendSegments(stream, stream.take(false, w.streamSegmentChars()), false)
+
+	return true
+}
+
+// openStream opens the stream of its first delta and starts its synthesizer.
+// A stream whose settings are invalid is reported once and opened as failed,
+// so its other deltas are dropped. w.streams.mu must be held.
+func (w *NatsWorker) openStream(
+	ctx context.Context,
+	slots *jobSlots,
+	msg *nats.Msg,
+	delta *core.TextDeltaEvent,
+) *textStream {
+	jobTimeout, defaults := w.settings()
+	streamID := delta.Header.WorkflowID
+
+	// A stream has no pages; its segments are numbered as they are made.
+	event := delta.JobEvent
+	event.PageNumber = 0
+	event.TotalPages = 0
+
+	stream := &textStream{
+		event:   *delta,
+		ttsCfg:  core.TTSConfig{},
+		timeout: 0,
+		mu:      sync.Mutex{},
+		last:    msg,
+		text:    strings.Builder{},
+		pending: nil,
+		ready:   make(chan struct{}, 1),
+		idle:    nil,
+		touched: time.Now(),
+		closed:  false,
+		failed:  atomic.Bool{},
+	}
+
+	stream.idle = time.AfterFunc(w.streamIdleTimeout, func() {
+		stream.mu.Lock()
+		defer stream.mu.Unlock()
+
+		// A delta may have arrived while the timer fired.
+		if stream.closed || time.Since(stream.touched) < w.streamIdleTimeout {
+			return
+		}
+
+		w.log.Warn("Text stream %s had no delta for %s; ending it", streamID, w.streamIdleTimeout)
+		w.endStream(stream)
+	})
+
+	timeout, err := w.timeoutFor(&event, jobTimeout)
+	if err == nil {
+		stream.timeout = timeout
+		stream.ttsCfg, err = w.jobConfig(&event, defaults)
+	}
+
+	if err != nil {
+		w.log.Error("Rejected text stream %s: %v", streamID, err)
+		w.publishFailure(msg, &event, err)
+		stream.failed.Store(true)
+	}
+
+	w.streams.running.Add(1)
+
+	go w.synthesizeStream(ctx, slots, stream)
+
+	return stream
+}
+
+// endStream hands the rest of the stream's text to its synthesizer as the
+// final segment and closes the stream. stream.mu must be held.
+func (w *NatsWorker) endStream(stream *textStream) {
+	w.sendSegments(stream, stream.take(true, w.streamSegmentChars()), true)
+	stream.close()
+
+	streamID := stream.event.Header.WorkflowID
+
+	w.streams.mu.Lock()
+	defer w.streams.mu.Unlock()
+
+	if w.streams.streams[streamID] == stream {
+		delete(w.streams.streams, streamID)
+	}
+}
+
+// close stops the stream's idle timer and marks it closed, so its
+// synthesizer ends once it has done the segments already sent. stream.mu must
+// be held.
+func (s *textStream) close() {
+	s.idle.Stop()
+	s.closed = true
+	s.wake()
+}
+
+// wake tells the synthesizer that there is a segment or the stream closed,
+// unless it has already been told.
+func (s *textStream) wake() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next waits for the next segment of the stream. It returns false once the
+// stream is closed and its segments are done.
+func (s *textStream) next() (streamSegment, bool) {
+	for {
+		s.mu.Lock()
+
+		if len(s.pending) > 0 {
+			segment := s.pending[0]
+			s.pending = s.pending[1:]
+			s.mu.Unlock()
+
+			return segment, true
+		}
+
+		closed := s.closed
+		s.mu.Unlock()
+
+		if closed {
+			return streamSegment{text: "", final: false, msg: nil}, false
+		}
+
+		<-s.ready
+	}
+}
+
+// sendSegments hands segments to the stream's synthesizer, marking the last
+// one final when the stream ends. Segments of failed streams are dropped.
+// stream.mu must be held.
+func (w *NatsWorker) sendSegments(stream *textStream, texts []string, final bool) {
+	if stream.failed.Load() {
+		return
+	}
+
+	if final && len(texts) == 0 {
+		texts = []string{""}
+	}
+
+	for i, text := range texts {
+		stream.pending = append(stream.pending, streamSegment{text: text, final: final && i == len(texts)-1, msg: stream.last})
+	}
+
+	stream.wake()
+}
+
+// closeStreams ends the open streams without synthesizing their remaining
+// text and waits for their synthesizers, which stop once ctx is done.
+func (w *NatsWorker) closeStreams() {
+	w.streams.mu.Lock()
+	open := w.streams.streams
+	w.streams.streams = map[string]*textStream{}
+	w.streams.mu.Unlock()
+
+	for streamID, stream := range open {
+		stream.mu.Lock()
+
+		if !stream.closed {
+			w.log.Warn("Closing text stream %s on shutdown", streamID)
+			stream.close()
+		}
+
+		stream.mu.Unlock()
+	}
+
+	w.streams.running.Wait()
+}
+
+// streamSegmentChars is the most characters of one segment: StreamSegmentChars,
+// or the job text limit when it is lower.
+func (w *NatsWorker) streamSegmentChars() int {
+	return min(StreamSegmentChars, w.maxTextChars)
+}
+
+// take removes the complete segments from the text of the stream and returns
+// them: every sentence but the last, which may still grow, unless the stream
+// is final. A last sentence longer than maxChars is cut after its last
+// complete word. Segments longer than maxChars are cut between words.
+func (s *textStream) take(final bool, maxChars int) []string {
+	parts := sentence.Split(s.text.String())
+	rest := ""
+
+	if !final && len(parts) > 0 {
+		rest = parts[len(parts)-1]
+		parts = parts[:len(parts)-1]
+
+		if utf8.RuneCountInString(rest) > maxChars {
+			cut := strings.LastIndexFunc(rest, unicode.IsSpace)
+			if cut <= 0 {
+				parts, rest = append(parts, rest), ""
+			} else {
+				parts, rest = append(parts, rest[:cut]), rest[cut:]
+			}
+		}
+	}
+
+	s.text.Reset()
+	s.text.WriteString(rest)
+
+	var segments []string
+
+	for _, part := range parts {
+		segments = append(segments, sentence.Chunk(part, maxChars)...)
+	}
+
+	return segments
+}
+
+// synthesizeStream synthesizes the segments of a stream one at a time and
+// publishes each as soon as it is done, so they are published in order. The
+// first segment that fails is reported like a failed job, and the rest of the
+// stream is dropped.
+func (w *NatsWorker) synthesizeStream(ctx context.Context, slots *jobSlots, stream *textStream) {
+	defer w.streams.running.Done()
+
+	number := 0
+
+	for {
+		segment, ok := stream.next()
+		if !ok {
+			return
+		}
+
+		if stream.failed.Load() || ctx.Err() != nil {
+			continue
+		}
+
+		if segment.text != "" {
+			number++
+		}
+
+		err := w.publishSegment(ctx, slots, stream, segment, number)
+		if err != nil {
+			w.log.Error("Failed to process segment %d of text stream %s: %v",
+				number, stream.event.Header.WorkflowID, err)
+			stream.failed.Store(true)
+
+			event := stream.event.JobEvent
+			event.PageNumber = number
+			event.TotalPages = 0
+			w.publishFailure(segment.msg, &event, err)
+		}
+	}
+}
+
+// publishSegment synthesizes a segment, uploads its audio and publishes its
+// core.AudioSegmentEvent. An empty final segment is published without audio.
+// Like a job, a segment waits for the host to have capacity and for a slot
+// before it is synthesized.
+func (w *NatsWorker) publishSegment(
+	ctx context.Context,
+	slots *jobSlots,
+	stream *textStream,
+	segment streamSegment,
+	number int,
+) error {
+	event := stream.event.JobEvent
+	event.PageNumber = number
+	event.TotalPages = 0
+
+	reply := &core.AudioSegmentEvent{
+		AudioChunkEvent: core.AudioChunkEvent{
+			AudioChunkCreatedEvent: events.AudioChunkCreatedEvent{
+				Header:     event.Header,
+				AudioKey:   "",
+				PageNumber: 0,
+				TotalPages: 0,
+			},
+			DurationSeconds:  0,
+			SampleRate:       0,
+			Channels:         0,
+			SizeBytes:        0,
+			SHA256:           "",
+			Config:           effectiveConfig(stream.ttsCfg),
+			Build:            buildinfo.Get(),
+			SynthesisAttempt: 0,
+			TextStats:        nil,
+		},
+		Text:  "",
+		Final: segment.final,
+	}
+
+	if segment.text != "" {
+		if !w.awaitCapacity(ctx, segment.msg) {
+			return fmt.Errorf("text stream %s: %w", event.Header.WorkflowID, ctx.Err())
+		}
+
+		release, ok := slots.acquire(ctx)
+		if !ok {
+			return fmt.Errorf("text stream %s: %w", event.Header.WorkflowID, ctx.Err())
+		}
+
+		defer release()
+
+		ctx, cancel := context.WithTimeout(ctx, stream.timeout)
+		defer cancel()
+
+		textData, err := cleanText([]byte(segment.text), w.maxTextChars)
+		if err != nil {
+			return err
+		}
+
+		textData, err = w.filterText(ctx, &event, textData)
+		if err != nil {
+			return err
+		}
+
+		audioData, ttsCfg, attempt, err := w.generate(ctx, &event, textData, stream.ttsCfg)
+		if err != nil {
+			return err
+		}
+
+		audioKey := uuid.NewString() + ".wav"
+
+		err = w.store.Upload(ctx, audioKey, audioData)
+		if err != nil {
+			return fmt.Errorf("%w for key '%s': %w", ErrUploadFailed, audioKey, err)
+		}
+
+		reply.AudioChunkEvent = *w.newReplyEvent(&event, audioKey, audioData, ttsCfg)
+		reply.SynthesisAttempt = attempt
+		reply.Text = string(textData)
+	}
+
+	if segment.final {
+		reply.TotalPages = number
+	}
+
+	data, err := json.Marshal(reply)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audio segment: %w", err)
+	}
+
+	err = w.natsConnection.Publish(w.streamAudioSubject, data)
+	if err != nil {
+		return fmt.Errorf("failed to publish audio segment: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+package worker_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/worker"
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func streamOptions() worker.Options {
+	opts := documentOptions(nil)
+	opts.DocumentSubject = ""
+	opts.VersionSubject = "test_version"
+	opts.StreamSubject = "test_stream"
+	opts.StreamAudioSubject = "test_stream_audio"
+
+	return opts
+}
+
+// startStreams runs the worker and subscribes to its audio segments and
+// failures once its subscriptions are in place.
+func startStreams(t *testing.T, natsConnection *nats.Conn, run func()) (*nats.Subscription, *nats.Subscription) {
+	t.Helper()
+
+	segments, err := natsConnection.SubscribeSync("test_stream_audio")
+	require.NoError(t, err)
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go run()
+
+	// The version subject is subscribed after the stream subject.
+	requestWhenReady(t, natsConnection, "test_version", nil)
+
+	return segments, failures
+}
+
+func newDelta(workflowID, text string, final bool) core.TextDeltaEvent {
+	event := newTestEvent("")
+	event.Header.WorkflowID = workflowID
+
+	return core.TextDeltaEvent{
+		JobEvent: core.JobEvent{
+			TextProcessedEvent: *event,
+			Model:              "",
+			Language:           "",
+			Rate:               0,
+			Pitch:              0,
+			Style:              "",
+			TimeoutSeconds:     0,
+		},
+		Text:  text,
+		Final: final,
+	}
+}
+
+func sendDeltas(t *testing.T, natsConnection *nats.Conn, deltas ...core.TextDeltaEvent) {
+	t.Helper()
+
+	for _, delta := range deltas {
+		data, err := json.Marshal(delta)
+		require.NoError(t, err)
+		require.NoError(t, natsConnection.Publish("test_stream", data))
+	}
+}
+
+func nextSegment(t *testing.T, segments *nats.Subscription) core.AudioSegmentEvent {
+	t.Helper()
+
+	msg, err := segments.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+
+	var segment core.AudioSegmentEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &segment))
+
+	return segment
+}
+
+func TestStream_SynthesizesSentencesAsTheyEnd(t *testing.T) {
+	t.Parallel()
+
+	recorder := &textRecorder{mu: sync.Mutex{}, texts: nil}
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, recorder, streamOptions())
+	defer cancel()
+
+	segments, _ := startStreams(t, natsConnection, func() { _ = workerInstance.Run(ctx) })
+
+	sendDeltas(t, natsConnection, newDelta("chat-1", "Hello wor", false), newDelta("chat-1", "ld. How are", false))
+
+	first := nextSegment(t, segments)
+	assert.Equal(t, "Hello world.", first.Text, "a sentence is synthesized once the next one starts")
+	assert.Equal(t, "chat-1", first.Header.WorkflowID)
+	assert.Equal(t, 1, first.PageNumber)
+	assert.Zero(t, first.TotalPages)
+	assert.False(t, first.Final)
+	assert.NotEmpty(t, first.AudioKey)
+	assert.InDelta(t, 1.5, first.DurationSeconds, 1e-9)
+
+	sendDeltas(t, natsConnection, newDelta("chat-1", " you? Fine", false), newDelta("chat-1", ", thanks.", true))
+
+	second := nextSegment(t, segments)
+	last := nextSegment(t, segments)
+
+	assert.Equal(t, "How are you?", second.Text)
+	assert.Equal(t, 2, second.PageNumber)
+	assert.False(t, second.Final)
+	assert.Equal(t, "Fine, thanks.", last.Text, "the final delta ends the last sentence")
+	assert.Equal(t, 3, last.PageNumber)
+	assert.Equal(t, 3, last.TotalPages)
+	assert.True(t, last.Final)
+	assert.Equal(t, []string{"Hello world.", "How are you?", "Fine, thanks."}, recorder.recorded())
+}
+
+func TestStream_CutsLongTextAndEndsWithoutAudio(t *testing.T) {
+	t.Parallel()
+
+	recorder := &textRecorder{mu: sync.Mutex{}, texts: nil}
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, recorder, streamOptions())
+	defer cancel()
+
+	segments, _ := startStreams(t, natsConnection, func() { _ = workerInstance.Run(ctx) })
+
+	sendDeltas(t, natsConnection,
+		newDelta("chat-2", "a long run of words without", false),
+		newDelta("chat-2", " an end", false),
+		newDelta("chat-2", "", true),
+	)
+
+	for i, want := range []string{"a long run of words", "without an end"} {
+		segment := nextSegment(t, segments)
+		assert.Equal(t, want, segment.Text, "text over max_text_chars is cut between words")
+		assert.Equal(t, i+1, segment.PageNumber)
+		assert.Equal(t, i == 1, segment.Final)
+	}
+
+	sendDeltas(t, natsConnection, newDelta("chat-3", "Supercalifragilisticexpialidocious", false), newDelta("chat-3", "", true))
+
+	assert.Equal(t, "Supercalifragilistic", nextSegment(t, segments).Text)
+	assert.Equal(t, "expialidocious", nextSegment(t, segments).Text)
+
+	final := nextSegment(t, segments)
+	assert.True(t, final.Final)
+	assert.Empty(t, final.AudioKey, "a stream ending on a complete segment ends without audio")
+	assert.Zero(t, final.PageNumber)
+	assert.Equal(t, 2, final.TotalPages)
+}
+
+func TestStream_EndsWhenIdle(t *testing.T) {
+	t.Parallel()
+
+	opts := streamOptions()
+	opts.StreamIdleTimeout = 100 * time.Millisecond
+
+	recorder := &textRecorder{mu: sync.Mutex{}, texts: nil}
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, recorder, opts)
+	defer cancel()
+
+	segments, _ := startStreams(t, natsConnection, func() { _ = workerInstance.Run(ctx) })
+
+	sendDeltas(t, natsConnection, newDelta("chat-4", "Are you there", false))
+
+	segment := nextSegment(t, segments)
+	assert.Equal(t, "Are you there", segment.Text)
+	assert.True(t, segment.Final)
+}
+
+func TestStream_Failures(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, _, mockProcessor, ctx, cancel, natsConnection := setupTest(t, streamOptions())
+	defer cancel()
+
+	mockProcessor.processShouldFail = true
+
+	segments, failures := startStreams(t, natsConnection, func() { _ = workerInstance.Run(ctx) })
+
+	invalid := newDelta("chat-5", "Hello. ", false)
+	invalid.Model = "narrator"
+	sendDeltas(t, natsConnection, invalid, newDelta("chat-5", "More text. And more.", true))
+	sendDeltas(t, natsConnection, newDelta("chat-6", "One. Two. Three.", true))
+
+	reported := map[string]core.TTSJobFailedEvent{}
+
+	for range 2 {
+		msg, err := failures.NextMsg(5 * time.Second)
+		require.NoError(t, err)
+
+		var failure core.TTSJobFailedEvent
+
+		require.NoError(t, json.Unmarshal(msg.Data, &failure))
+		reported[failure.Header.WorkflowID] = failure
+	}
+
+	assert.Equal(t, core.ErrorClassInvalidConfig, reported["chat-5"].ErrorClass)
+	assert.Zero(t, reported["chat-5"].PageNumber)
+	assert.Equal(t, core.ErrorClassSynthesis, reported["chat-6"].ErrorClass)
+	assert.Equal(t, 1, reported["chat-6"].PageNumber, "the failed segment is reported")
+
+	_, err := failures.NextMsg(200 * time.Millisecond)
+	require.ErrorIs(t, err, nats.ErrTimeout, "a stream is reported once; the rest of it is dropped")
+
+	_, err = segments.NextMsg(time.Millisecond)
+	require.ErrorIs(t, err, nats.ErrTimeout)
+}
+
+func TestStream_SegmentsTakeJobSlots(t *testing.T) {
+	t.Parallel()
+
+	opts := streamOptions()
+	opts.MaxConcurrentJobs = 2
+
+	processor := &concurrencyProcessor{mu: sync.Mutex{}, active: 0, peak: 0, done: 0}
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, processor, opts)
+	defer cancel()
+
+	startStreams(t, natsConnection, func() { _ = workerInstance.Run(ctx) })
+
+	const streams = 4
+
+	for i := range streams {
+		sendDeltas(t, natsConnection, newDelta(fmt.Sprintf("chat-slots-%d", i), "One. Two.", true))
+	}
+
+	require.Eventually(t, func() bool {
+		_, done := processor.counts()
+
+		return done == 2*streams
+	}, 5*time.Second, 10*time.Millisecond)
+
+	peak, _ := processor.counts()
+	assert.Equal(t, 2, peak, "streams are synthesized in parallel, up to the job limit")
+}
+
+// heldProcessor holds the synthesis of one text until its context ends.
+type heldProcessor struct {
+	held string
+}
+
+func (p *heldProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *heldProcessor) Process(ctx context.Context, text []byte, _ core.TTSConfig) ([]byte, error) {
+	if string(text) == p.held {
+		<-ctx.Done()
+
+		return nil, ctx.Err()
+	}
+
+	return sampleAudio, nil
+}
+
+func TestStream_SlowStreamDoesNotHoldUpOthers(t *testing.T) {
+	t.Parallel()
+
+	opts := streamOptions()
+	opts.MaxConcurrentJobs = 2
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, &heldProcessor{held: "Wait."}, opts)
+	defer cancel()
+
+	segments, _ := startStreams(t, natsConnection, func() { _ = workerInstance.Run(ctx) })
+
+	// The slow stream's segments pile up behind its first one.
+	sendDeltas(t, natsConnection, newDelta("chat-slow", "Wait. "+strings.Repeat("More. ", 200), false))
+	sendDeltas(t, natsConnection, newDelta("chat-fast", "Hello. There", false))
+
+	segment := nextSegment(t, segments)
+	assert.Equal(t, "chat-fast", segment.Header.WorkflowID)
+	assert.Equal(t, "Hello.", segment.Text)
+}
@@ -0,0 +1,37 @@
+package worker
+
+import (
+	"fmt"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultMaxTextChars bounds the text of one job when Options.MaxTextChars is
+// not set. A book page is a few thousand characters.
+const DefaultMaxTextChars = 50_000
+
+// cleanText checks that text is valid UTF-8 of at most maxChars characters and
+// removes the control characters other than tabs and line breaks, so that they
+// never reach a backend's prompt.
+func cleanText(text []byte, maxChars int) ([]byte, error) {
+	if !utf8.Valid(text) {
+		return nil, fmt.Errorf("%w: not valid UTF-8", ErrInvalidText)
+	}
+
+	chars := utf8.RuneCount(text)
+	if chars > maxChars {
+		return nil, fmt.Errorf("%w: %d characters, the limit is %d", ErrTextTooLong, chars, maxChars)
+	}
+
+	cleaned := make([]byte, 0, len(text))
+
+	for _, r := range string(text) {
+		if unicode.IsControl(r) && r != '\t' && r != '\n' && r != '\r' {
+			continue
+		}
+
+		cleaned = utf8.AppendRune(cleaned, r)
+	}
+
+	return cleaned, nil
+}
@@ -2,30 +2,50 @@
 package worker
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/book-expert/events"
 	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/buildinfo"
 	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/jobstatus"
+	"github.com/book-expert/tts-service/internal/load"
+	"github.com/book-expert/tts-service/internal/quality"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/book-expert/tts-service/internal/wav"
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
 )
 
+// handleMessageTimeout bounds a job when Options.JobTimeout is not set, and every status query.
 const handleMessageTimeout = 30 * time.Second
 
+// DefaultQualityAttempts is how often a job is synthesized while its audio
+// fails the quality gate when RetryPolicy.Attempts is not set.
+const DefaultQualityAttempts = 2
+
 var (
-	// ErrModelPathEmpty indicates that the model path is empty.
-	ErrModelPathEmpty = errors.New("model path cannot be empty")
-	// ErrSnacModelPathEmpty indicates that the SNAC model path is empty.
-	ErrSnacModelPathEmpty = errors.New("snac model path cannot be empty")
+	// ErrModelPathEmpty indicates that the model path is empty. It is the
+	// error of the chatllm backend, which checks it.
+	ErrModelPathEmpty = tts.ErrModelPathEmpty
+	// ErrSnacModelPathEmpty indicates that the SNAC model path is empty. It is
+	// the error of the chatllm backend, which checks it.
+	ErrSnacModelPathEmpty = tts.ErrSnacModelPathEmpty
 	// ErrVoiceEmpty indicates that the voice is empty.
 	ErrVoiceEmpty = errors.New("voice cannot be empty")
 	// ErrUnsupportedVoice indicates that the provided voice is not supported.
-	ErrUnsupportedVoice = errors.New("unsupported voice")
+	// It is the error of the chatllm backend, which checks it.
+	ErrUnsupportedVoice = tts.ErrUnsupportedVoice
 	// ErrTopPRange indicates that the TopP parameter is out of the valid range [0.0, 1.0].
 	ErrTopPRange = errors.New("top_p must be between 0.0 and 1.0")
 	// ErrRepetitionPenaltyRange indicates that the RepetitionPenalty parameter is out of the valid range [1.0, ...).
@@ -34,8 +54,183 @@ var (
 	ErrTemperatureRange = errors.New("temperature must be >= 0.0")
 	// ErrNGLNegative indicates that the NGL (number of GPU layers) parameter is negative.
 	ErrNGLNegative = errors.New("n_gpu_layers must be non-negative")
+	// ErrModelSelectionUnsupported indicates that a job selected a model but no model registry is configured.
+	ErrModelSelectionUnsupported = errors.New("model selection requires a model registry")
+	// ErrStatusTrackingDisabled indicates that a status query arrived while no status store is configured.
+	ErrStatusTrackingDisabled = errors.New("job status tracking is disabled")
+	// ErrInvalidEvent indicates a job message that could not be parsed.
+	ErrInvalidEvent = errors.New("invalid job event")
+	// ErrInvalidConfig indicates a job whose model, voice or parameters were rejected.
+	ErrInvalidConfig = errors.New("invalid job configuration")
+	// ErrDownloadFailed indicates that the job's text could not be downloaded.
+	ErrDownloadFailed = errors.New("failed to download text data")
+	// ErrSynthesisFailed indicates that the backend failed to produce audio.
+	ErrSynthesisFailed = errors.New("failed to process text to speech")
+	// ErrUploadFailed indicates that the job's audio could not be uploaded.
+	ErrUploadFailed = errors.New("failed to upload audio data")
+	// ErrInvalidText indicates that the job's text is not valid UTF-8.
+	ErrInvalidText = errors.New("invalid job text")
+	// ErrTextTooLong indicates that the job's text exceeds Options.MaxTextChars.
+	ErrTextTooLong = errors.New("job text is too long")
+	// ErrTimeoutNegative indicates that a job asked for a negative timeout.
+	ErrTimeoutNegative = errors.New("timeout_seconds must be non-negative")
 )
 
+// Options holds the optional collaborators of a NatsWorker.
+type Options struct {
+	// StatusStore records job lifecycle transitions. A nil store disables tracking.
+	StatusStore core.JobStatusStore
+	// StatusSubject is the request/reply subject answering job status queries.
+	// An empty subject disables the query API.
+	StatusSubject string
+	// VersionSubject is the request/reply subject answering version requests
+	// with a core.VersionInfo. An empty subject disables them.
+	VersionSubject string
+	// DocumentSubject receives core.DocumentProcessedEvents, answered with a
+	// core.AudioDocumentCreatedEvent. An empty subject disables documents.
+	DocumentSubject string
+	// Models resolves the model selected by a job. A nil resolver only allows the default model.
+	Models core.ModelResolver
+	// JobTimeout bounds the processing of one job. Zero uses 30 seconds.
+	JobTimeout time.Duration
+	// MaxJobTimeout caps the timeout a job may ask for. A job may always ask
+	// for up to JobTimeout, so zero only lets jobs shorten it.
+	MaxJobTimeout time.Duration
+	// FailureSubject receives a core.TTSJobFailedEvent for every failed job.
+	// An empty subject only logs failures.
+	FailureSubject string
+	// Defaults fill the settings a job leaves empty.
+	Defaults JobDefaults
+	// DryRun, when set, answers every valid job with a core.JobEstimate instead
+	// of synthesizing it. Nothing is uploaded and no status is recorded.
+	DryRun *Estimator
+	// MaxTextChars rejects jobs whose text has more characters. Zero uses
+	// DefaultMaxTextChars.
+	MaxTextChars int
+	// Quality, when set, checks the audio of every job before it is uploaded.
+	// Audio that fails is synthesized again as Retry describes.
+	Quality *quality.Gate
+	// Retry varies the settings of the attempts after audio failed Quality.
+	Retry RetryPolicy
+	// Manifests keeps the chunks of every document that has a document_id, so
+	// its next version reuses the audio of unchanged chunks. A nil store
+	// synthesizes every chunk.
+	Manifests core.ManifestStore
+	// TextReport, when set, describes the text of every job in the log and in
+	// its reply, and flags or splits long sentences.
+	TextReport *TextReport
+	// Filter screens the text of every job as soon as it is downloaded, before
+	// it is logged, reported or synthesized, and may redact it; rejected jobs
+	// fail as content_rejected. A nil filter passes all text.
+	Filter core.TextFilter
+	// Load, when set, holds new jobs while the host is over its load
+	// thresholds, and hands JetStream jobs back while the local queue is full.
+	Load *load.Monitor
+	// MaxConcurrentJobs is the number of jobs and documents synthesized at
+	// once. Zero handles each subscription's messages one at a time.
+	MaxConcurrentJobs int
+	// MaxPendingMessages caps the messages of each job subscription waiting in
+	// the client; NATS drops messages beyond it as a slow consumer. Zero uses
+	// the NATS default.
+	MaxPendingMessages int
+	// StreamSubject receives core.TextDeltaEvents, whose text is synthesized
+	// a sentence at a time as it arrives. Each segment is published to
+	// StreamAudioSubject as a core.AudioSegmentEvent. An empty subject
+	// disables streams.
+	StreamSubject      string
+	StreamAudioSubject string
+	// StreamIdleTimeout ends a stream that has had no delta for this long, as
+	// if its last delta were final. Zero uses DefaultStreamIdleTimeout.
+	StreamIdleTimeout time.Duration
+}
+
+// RetryPolicy describes how a job whose audio failed the quality gate is
+// synthesized again. Every retry uses the next seed.
+type RetryPolicy struct {
+	// Attempts is the most times a job is synthesized. Zero uses
+	// DefaultQualityAttempts.
+	Attempts int
+	// TemperatureStep lowers the temperature of each retry by this much, down
+	// to zero, since babble is more likely at high temperatures.
+	TemperatureStep float64
+}
+
+// attempts returns the most times a job is synthesized.
+func (p RetryPolicy) attempts() int {
+	if p.Attempts <= 0 {
+		return DefaultQualityAttempts
+	}
+
+	return p.Attempts
+}
+
+// next returns the settings of the attempt after one made with cfg.
+func (p RetryPolicy) next(cfg core.TTSConfig) core.TTSConfig {
+	cfg.Seed++
+	cfg.Temperature = max(cfg.Temperature-p.TemperatureStep, 0)
+
+	return cfg
+}
+
+// JobDefaults fill the settings a job leaves at zero. The voice applies after
+// the selected model's default voice. Zero defaults leave the job unchanged.
+type JobDefaults struct {
+	Voice             string
+	Seed              int
+	NGL               int
+	TopP              float64
+	RepetitionPenalty float64
+	Temperature       float64
+	// Voices are the profiles of voices tuned apart from the defaults, by
+	// voice name. A profile applies before the defaults above.
+	Voices map[string]VoiceProfile
+}
+
+// VoiceProfile holds the settings a voice sounds best with. Zero values fall
+// back to the job defaults.
+type VoiceProfile struct {
+	TopP              float64
+	RepetitionPenalty float64
+	Temperature       float64
+	Rate              float64
+}
+
+// apply fills the zero-valued settings of cfg, first from the profile of its
+// voice and then from the defaults.
+func (d JobDefaults) apply(cfg core.TTSConfig) core.TTSConfig {
+	if cfg.Voice == "" {
+		cfg.Voice = d.Voice
+	}
+
+	profile := d.Voices[cfg.Voice]
+
+	if cfg.Seed == 0 {
+		cfg.Seed = d.Seed
+	}
+
+	if cfg.NGL == 0 {
+		cfg.NGL = d.NGL
+	}
+
+	cfg.TopP = firstNonZero(cfg.TopP, profile.TopP, d.TopP)
+	cfg.RepetitionPenalty = firstNonZero(cfg.RepetitionPenalty, profile.RepetitionPenalty, d.RepetitionPenalty)
+	cfg.Temperature = firstNonZero(cfg.Temperature, profile.Temperature, d.Temperature)
+	cfg.Rate = firstNonZero(cfg.Rate, profile.Rate)
+
+	return cfg
+}
+
+// firstNonZero returns the first of values that is not zero, or zero.
+func firstNonZero(values ...float64) float64 {
+	for _, value := range values {
+		if value != 0 {
+			return value
+		}
+	}
+
+	return 0
+}
+
 // NatsWorker listens for TTS jobs on a NATS subject and processes them.
 type NatsWorker struct {
 	natsConnection   *nats.Conn
@@ -44,6 +239,38 @@ type NatsWorker struct {
 	store            core.ObjectStore
 	processor        core.TTSProcessor
 	log              *logger.Logger
+	statusStore      core.JobStatusStore
+	statusSubject    string
+	versionSubject   string
+	documentSubject  string
+	models           core.ModelResolver
+	failureSubject   string
+	dryRun           *Estimator
+	quality          *quality.Gate
+	retry            RetryPolicy
+	manifests        core.ManifestStore
+	textReport       *TextReport
+	filter           core.TextFilter
+	load             *load.Monitor
+	maxConcurrent    int
+	maxPending       int
+
+	streamSubject      string
+	streamAudioSubject string
+	streamIdleTimeout  time.Duration
+	streams            *textStreams
+
+	// queuesMu guards the subscriptions whose pending messages are queued jobs.
+	queuesMu sync.Mutex
+	queues   []*nats.Subscription
+
+	// settingsMu guards the settings that can be reloaded at runtime.
+	settingsMu sync.RWMutex
+	jobTimeout time.Duration
+	defaults   JobDefaults
+
+	maxJobTimeout time.Duration
+	maxTextChars  int
 }
 
 // NewNatsWorker creates a new instance of a NATS worker.
@@ -54,27 +281,154 @@ func NewNatsWorker(
 	store core.ObjectStore,
 	processor core.TTSProcessor,
 	log *logger.Logger,
+	opts Options,
 ) (*NatsWorker, error) {
-	return &NatsWorker{
-		natsConnection:   natsConnection,
-		jetstreamContext: jetstreamContext,
-		subject:          subject,
-		store:            store,
-		processor:        processor,
-		log:              log,
-	}, nil
+	natsWorker := &NatsWorker{
+		natsConnection:     natsConnection,
+		jetstreamContext:   jetstreamContext,
+		subject:            subject,
+		store:              store,
+		processor:          processor,
+		log:                log,
+		statusStore:        opts.StatusStore,
+		statusSubject:      opts.StatusSubject,
+		versionSubject:     opts.VersionSubject,
+		documentSubject:    opts.DocumentSubject,
+		models:             opts.Models,
+		failureSubject:     opts.FailureSubject,
+		dryRun:             opts.DryRun,
+		quality:            opts.Quality,
+		retry:              opts.Retry,
+		manifests:          opts.Manifests,
+		textReport:         opts.TextReport,
+		filter:             opts.Filter,
+		load:               opts.Load,
+		maxConcurrent:      opts.MaxConcurrentJobs,
+		maxPending:         opts.MaxPendingMessages,
+		streamSubject:      opts.StreamSubject,
+		streamAudioSubject: opts.StreamAudioSubject,
+		streamIdleTimeout:  opts.StreamIdleTimeout,
+		streams:            newTextStreams(),
+		queuesMu:           sync.Mutex{},
+		queues:             nil,
+		settingsMu:         sync.RWMutex{},
+		jobTimeout:         0,
+		defaults:           JobDefaults{},
+		maxJobTimeout:      opts.MaxJobTimeout,
+		maxTextChars:       opts.MaxTextChars,
+	}
+
+	if natsWorker.maxTextChars <= 0 {
+		natsWorker.maxTextChars = DefaultMaxTextChars
+	}
+
+	if natsWorker.streamIdleTimeout <= 0 {
+		natsWorker.streamIdleTimeout = DefaultStreamIdleTimeout
+	}
+
+	natsWorker.UpdateSettings(opts.JobTimeout, opts.Defaults)
+
+	return natsWorker, nil
 }
 
-// Run starts the worker and begins listening for messages.
+// UpdateSettings replaces the job timeout and defaults for subsequent jobs;
+// jobs already running keep theirs. A zero timeout uses 30 seconds.
+func (w *NatsWorker) UpdateSettings(jobTimeout time.Duration, defaults JobDefaults) {
+	if jobTimeout <= 0 {
+		jobTimeout = handleMessageTimeout
+	}
+
+	w.settingsMu.Lock()
+	defer w.settingsMu.Unlock()
+
+	w.jobTimeout = jobTimeout
+	w.defaults = defaults
+}
+
+// settings returns the current job timeout and defaults.
+func (w *NatsWorker) settings() (time.Duration, JobDefaults) {
+	w.settingsMu.RLock()
+	defer w.settingsMu.RUnlock()
+
+	return w.jobTimeout, w.defaults
+}
+
+// Run starts the worker and begins listening for messages. Jobs and status
+// queries run under ctx, so cancelling it also cancels in-flight synthesis.
 func (w *NatsWorker) Run(ctx context.Context) error {
-	sub, err := w.natsConnection.Subscribe(w.subject, w.handleMessage)
+	subs := make([]*nats.Subscription, 0, 5)
+	slots := newJobSlots(w.maxConcurrent)
+
+	sub, err := w.natsConnection.Subscribe(w.subject, slots.handler(ctx, w.handleMessage))
 	if err != nil {
 		return fmt.Errorf("failed to subscribe to subject %s: %w", w.subject, err)
 	}
 
+	subs = append(subs, sub)
+
+	err = w.watchQueue(sub)
+	if err != nil {
+		_ = sub.Unsubscribe()
+
+		return err
+	}
+
+	// Stream segments share the job slots. Without a bound, they are
+	// synthesized one at a time, like jobs and documents.
+	streamSlots := slots
+	if streamSlots == nil {
+		streamSlots = newJobSlots(1)
+	}
+
+	// Documents, streams and the query APIs are only served when their
+	// subject is set. Text deltas are handled in order on the subscription's
+	// goroutine.
+	queries := []struct {
+		subject string
+		handle  nats.MsgHandler
+	}{
+		{w.documentSubject, slots.handler(ctx, w.handleDocument)},
+		{w.streamSubject, func(msg *nats.Msg) { w.handleTextDelta(ctx, streamSlots, msg) }},
+		{w.statusSubject, func(msg *nats.Msg) { w.handleStatusQuery(ctx, msg) }},
+		{w.versionSubject, w.handleVersionQuery},
+	}
+
+	for _, query := range queries {
+		if query.subject == "" {
+			continue
+		}
+
+		querySub, queryErr := w.natsConnection.Subscribe(query.subject, query.handle)
+		if queryErr != nil {
+			drainErr := drainSubscriptions(subs)
+			if drainErr != nil {
+				w.log.Warn("Failed to drain subscriptions after subscribe error: %v", drainErr)
+			}
+
+			return fmt.Errorf("failed to subscribe to subject %s: %w", query.subject, queryErr)
+		}
+
+		subs = append(subs, querySub)
+
+		if query.subject == w.documentSubject {
+			queryErr = w.watchQueue(querySub)
+			if queryErr != nil {
+				_ = drainSubscriptions(subs)
+
+				return queryErr
+			}
+		}
+	}
+
 	<-ctx.Done()
 
-	drainErr := sub.Drain()
+	drainErr := drainSubscriptions(subs)
+
+	// Jobs running on their own goroutines are cancelled with ctx; wait for
+	// them to report before returning.
+	slots.close()
+	w.closeStreams()
+
 	if drainErr != nil {
 		return fmt.Errorf("failed to drain subscription: %w", drainErr)
 	}
@@ -82,30 +436,150 @@ func (w *NatsWorker) Run(ctx context.Context) error {
 	return nil
 }
 
-func (w *NatsWorker) handleMessage(msg *nats.Msg) {
-	ctx, cancel := context.WithTimeout(context.Background(), handleMessageTimeout)
-	defer cancel()
+// jobSlots bounds the jobs that run at once and tracks them, so Run can wait
+// for them. A nil *jobSlots sets no bound.
+type jobSlots struct {
+	slots   chan struct{}
+	mu      sync.Mutex
+	closed  bool
+	running sync.WaitGroup
+}
+
+// newJobSlots creates slots for limit jobs, or returns nil when limit is zero.
+func newJobSlots(limit int) *jobSlots {
+	if limit <= 0 {
+		return nil
+	}
+
+	return &jobSlots{slots: make(chan struct{}, limit), mu: sync.Mutex{}, closed: false, running: sync.WaitGroup{}}
+}
+
+// handler returns a message handler that runs handle. Without a bound, it
+// runs on the subscription's goroutine, one message at a time. With one, each
+// message runs on its own goroutine once a slot is free; while every slot is
+// busy, the subscription's delivery waits, so messages queue in the client
+// instead of starting.
+func (j *jobSlots) handler(ctx context.Context, handle func(context.Context, *nats.Msg)) nats.MsgHandler {
+	if j == nil {
+		return func(msg *nats.Msg) { handle(ctx, msg) }
+	}
+
+	return func(msg *nats.Msg) {
+		select {
+		case j.slots <- struct{}{}:
+		case <-ctx.Done():
+			return
+		}
+
+		j.mu.Lock()
+
+		if j.closed {
+			j.mu.Unlock()
+			<-j.slots
+
+			return
+		}
+
+		j.running.Add(1)
+		j.mu.Unlock()
+
+		go func() {
+			defer j.running.Done()
+			defer func() { <-j.slots }()
+
+			handle(ctx, msg)
+		}()
+	}
+}
+
+// acquire waits for a free slot for work that is not started by handler, such
+// as a segment of a text stream, and returns the function that frees it. It
+// returns false if ctx ends first.
+func (j *jobSlots) acquire(ctx context.Context) (func(), bool) {
+	if j == nil {
+		return func() {}, true
+	}
+
+	select {
+	case j.slots <- struct{}{}:
+		return func() { <-j.slots }, true
+	case <-ctx.Done():
+		return nil, false
+	}
+}
+
+// close stops new jobs from starting and waits for the running ones.
+func (j *jobSlots) close() {
+	if j == nil {
+		return
+	}
+
+	j.mu.Lock()
+	j.closed = true
+	j.mu.Unlock()
+
+	j.running.Wait()
+}
+
+// drainSubscriptions drains every subscription and joins any errors.
+func drainSubscriptions(subs []*nats.Subscription) error {
+	var drainErrs []error
+
+	for _, sub := range subs {
+		drainErr := sub.Drain()
+		if drainErr != nil {
+			drainErrs = append(drainErrs, fmt.Errorf("subject %s: %w", sub.Subject, drainErr))
+		}
+	}
+
+	return errors.Join(drainErrs...)
+}
+
+func (w *NatsWorker) handleMessage(parent context.Context, msg *nats.Msg) {
+	if !w.awaitCapacity(parent, msg) {
+		return
+	}
+
+	jobTimeout, defaults := w.settings()
 
 	event, err := w.parseAndValidateEvent(msg)
 	if err != nil {
 		w.log.Error("Failed to parse and validate event: %v", err)
+		w.publishFailure(msg, nil, err)
+
+		return
+	}
+
+	timeout, err := w.timeoutFor(event, jobTimeout)
+	if err != nil {
+		w.log.Error("Rejected TTS job for event %s: %v", event.Header.WorkflowID, err)
+		w.publishFailure(msg, event, err)
+
+		return
+	}
+
+	// Processors run under this deadline; chatllm is killed when it expires.
+	ctx, cancel := context.WithTimeout(parent, timeout)
+	defer cancel()
+
+	if w.dryRun != nil {
+		w.handleDryRun(ctx, msg, event, defaults)
 
 		return
 	}
 
-	audioKey, processErr := w.processTTSJob(ctx, event)
+	w.recordStatus(ctx, &event.TextProcessedEvent, core.JobStateReceived, "", nil)
+
+	replyEvent, processErr := w.processTTSJob(ctx, event, defaults)
 	if processErr != nil {
 		w.log.Error("Failed to process TTS job for event %s: %v", event.Header.WorkflowID, processErr)
+		w.recordStatus(ctx, &event.TextProcessedEvent, core.JobStateFailed, "", processErr)
+		w.publishFailure(msg, event, processErr)
 
 		return
 	}
 
-	replyEvent := &events.AudioChunkCreatedEvent{
-		Header:     event.Header,
-		AudioKey:   audioKey,
-		PageNumber: event.PageNumber,
-		TotalPages: event.TotalPages,
-	}
+	w.recordStatus(ctx, &event.TextProcessedEvent, core.JobStateCompleted, replyEvent.AudioKey, nil)
 
 	err = w.publishReplyEvent(msg, replyEvent)
 	if err != nil {
@@ -113,48 +587,477 @@ func (w *NatsWorker) handleMessage(msg *nats.Msg) {
 	}
 }
 
-// processTTSJob handles the core logic of downloading text, processing it, and uploading audio.
-func (w *NatsWorker) processTTSJob(ctx context.Context, event *events.TextProcessedEvent) (string, error) {
-	textData, err := w.store.Download(ctx, event.TextKey)
+// timeoutFor returns the timeout of a job: the timeout it asks for, capped at
+// the larger of Options.MaxJobTimeout and jobTimeout, or else jobTimeout.
+func (w *NatsWorker) timeoutFor(event *core.JobEvent, jobTimeout time.Duration) (time.Duration, error) {
+	if event.TimeoutSeconds < 0 {
+		return 0, fmt.Errorf("%w: %w: got %d", ErrInvalidConfig, ErrTimeoutNegative, event.TimeoutSeconds)
+	}
+
+	if event.TimeoutSeconds == 0 {
+		return jobTimeout, nil
+	}
+
+	requested := time.Duration(event.TimeoutSeconds) * time.Second
+
+	return min(requested, max(w.maxJobTimeout, jobTimeout)), nil
+}
+
+// handleDryRun prepares the job like a real one and replies with its estimate.
+func (w *NatsWorker) handleDryRun(ctx context.Context, msg *nats.Msg, event *core.JobEvent, defaults JobDefaults) {
+	textData, ttsCfg, err := w.prepareJob(ctx, event, defaults)
+	if err != nil {
+		w.log.Error("Dry run of TTS job for event %s failed: %v", event.Header.WorkflowID, err)
+		w.publishFailure(msg, event, err)
+
+		return
+	}
+
+	_, stats := w.reportText(event, textData, ttsCfg.Rate)
+	estimate := w.dryRun.Estimate(textData, ttsCfg.Rate)
+
+	replyData, err := json.Marshal(core.JobEstimate{
+		Header:                     event.Header,
+		PageNumber:                 event.PageNumber,
+		TotalPages:                 event.TotalPages,
+		DryRun:                     true,
+		Characters:                 estimate.Characters,
+		Words:                      estimate.Words,
+		EstimatedDurationSeconds:   estimate.Duration.Seconds(),
+		EstimatedProcessingSeconds: estimate.Processing.Seconds(),
+		Config:                     effectiveConfig(ttsCfg),
+		TextStats:                  stats,
+	})
+	if err != nil {
+		w.log.Error("Failed to marshal dry-run estimate: %v", err)
+
+		return
+	}
+
+	err = msg.Respond(replyData)
+	if err != nil {
+		w.log.Error("Failed to publish dry-run estimate for workflow %s: %v", event.Header.WorkflowID, err)
+	}
+}
+
+// processTTSJob handles the core logic of downloading text, processing it, and
+// uploading audio. It returns the reply event describing the uploaded chunk.
+func (w *NatsWorker) processTTSJob(
+	ctx context.Context,
+	event *core.JobEvent,
+	defaults JobDefaults,
+) (*core.AudioChunkEvent, error) {
+	textData, ttsCfg, err := w.prepareJob(ctx, event, defaults)
+	if err != nil {
+		return nil, err
+	}
+
+	return w.synthesize(ctx, event, textData, ttsCfg)
+}
+
+// synthesize turns the prepared text of a job into audio and uploads it.
+func (w *NatsWorker) synthesize(
+	ctx context.Context,
+	event *core.JobEvent,
+	textData []byte,
+	ttsCfg core.TTSConfig,
+) (*core.AudioChunkEvent, error) {
+	w.recordStatus(ctx, &event.TextProcessedEvent, core.JobStateProcessing, "", nil)
+
+	progressCtx := core.WithProgress(ctx, func(progress core.Progress) {
+		w.recordProgress(ctx, &event.TextProcessedEvent, progress)
+	})
+
+	textData, stats := w.reportText(event, textData, ttsCfg.Rate)
+
+	audioData, ttsCfg, attempt, err := w.generate(progressCtx, event, textData, ttsCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	audioKey := uuid.NewString() + ".wav"
+
+	err = w.store.Upload(ctx, audioKey, audioData)
+	if err != nil {
+		return nil, fmt.Errorf("%w for key '%s': %w", ErrUploadFailed, audioKey, err)
+	}
+
+	reply := w.newReplyEvent(event, audioKey, audioData, ttsCfg)
+	reply.SynthesisAttempt = attempt
+	reply.TextStats = stats
+
+	return reply, nil
+}
+
+// generate synthesizes the text and, with a quality gate, checks the audio.
+// Audio that fails the gate is synthesized again with the settings the retry
+// policy gives. It returns the accepted audio, the configuration it was made
+// with and its attempt, counting from 1.
+func (w *NatsWorker) generate(
+	ctx context.Context,
+	event *core.JobEvent,
+	textData []byte,
+	ttsCfg core.TTSConfig,
+) ([]byte, core.TTSConfig, int, error) {
+	attempts := w.retry.attempts()
+
+	for attempt := 1; ; attempt++ {
+		audioData, err := w.processor.Process(ctx, textData, ttsCfg)
+		if err != nil {
+			return nil, core.TTSConfig{}, 0, fmt.Errorf("%w: %w", ErrSynthesisFailed, err)
+		}
+
+		err = w.checkQuality(event, audioData, textData, ttsCfg.Rate)
+		if err == nil {
+			if attempt > 1 {
+				w.log.Info("Audio for workflow %s page %d accepted on attempt %d, with seed %d and temperature %.2f",
+					event.Header.WorkflowID, event.PageNumber, attempt, ttsCfg.Seed, ttsCfg.Temperature)
+			}
+
+			return audioData, ttsCfg, attempt, nil
+		}
+
+		if attempt >= attempts {
+			return nil, core.TTSConfig{}, 0, fmt.Errorf("after %d attempts: %w", attempt, err)
+		}
+
+		w.log.Warn("Audio for workflow %s page %d with seed %d is rejected on attempt %d of %d, synthesizing it again: %v",
+			event.Header.WorkflowID, event.PageNumber, ttsCfg.Seed, attempt, attempts, err)
+
+		ttsCfg = w.retry.next(ttsCfg)
+	}
+}
+
+// reportText describes the text of a job with the text report, if any, and
+// logs the description. It returns the text to synthesize and its statistics,
+// or text itself and nil without a report.
+func (w *NatsWorker) reportText(event *core.JobEvent, textData []byte, rate float64) ([]byte, *core.TextStats) {
+	if w.textReport == nil {
+		return textData, nil
+	}
+
+	textData, stats := w.textReport.Analyze(textData, rate)
+
+	w.log.Info("Text for workflow %s page %d: %d words in %d sentences of %d to %d words (median %d), about %.0f s of audio",
+		event.Header.WorkflowID, event.PageNumber, stats.Words, stats.Sentences,
+		stats.SentenceWords.Min, stats.SentenceWords.Max, stats.SentenceWords.Median, stats.EstimatedDurationSeconds)
+
+	for _, long := range stats.LongSentences {
+		w.log.Warn("Sentence %d for workflow %s page %d has %d words and may be synthesized poorly: %q",
+			long.Index, event.Header.WorkflowID, event.PageNumber, long.Words, long.Start)
+	}
+
+	if stats.SplitSentences > 0 {
+		w.log.Info("Split %d long sentences for workflow %s page %d",
+			stats.SplitSentences, event.Header.WorkflowID, event.PageNumber)
+	}
+
+	return textData, &stats
+}
+
+// checkQuality checks audio against the quality gate, if any. Audio that is
+// not WAV cannot be measured and passes.
+func (w *NatsWorker) checkQuality(event *core.JobEvent, audioData, textData []byte, rate float64) error {
+	if w.quality == nil {
+		return nil
+	}
+
+	decoded, err := wav.Decode(audioData)
+	if err != nil {
+		w.log.Warn("Skipping the quality gate for workflow %s: the audio is not a readable WAV file: %v",
+			event.Header.WorkflowID, err)
+
+		return nil
+	}
+
+	return w.quality.Check(decoded, textData, rate)
+}
+
+// prepareJob downloads the job's text and resolves and validates its configuration.
+func (w *NatsWorker) prepareJob(
+	ctx context.Context,
+	event *core.JobEvent,
+	defaults JobDefaults,
+) ([]byte, core.TTSConfig, error) {
+	textData, err := w.loadText(ctx, event.TextKey, w.maxTextChars)
+	if err != nil {
+		return nil, core.TTSConfig{}, err
+	}
+
+	textData, err = w.filterText(ctx, event, textData)
+	if err != nil {
+		return nil, core.TTSConfig{}, err
+	}
+
+	ttsCfg, err := w.jobConfig(event, defaults)
+	if err != nil {
+		return nil, core.TTSConfig{}, err
+	}
+
+	return textData, ttsCfg, nil
+}
+
+// loadText downloads the text at key and cleans it, allowing at most maxChars characters.
+func (w *NatsWorker) loadText(ctx context.Context, key string, maxChars int) ([]byte, error) {
+	textData, err := w.store.Download(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w for key '%s': %w", ErrDownloadFailed, key, err)
+	}
+
+	textData, err = cleanText(textData, maxChars)
 	if err != nil {
-		return "", fmt.Errorf("failed to download text data for key '%s': %w", event.TextKey, err)
+		return nil, fmt.Errorf("text for key '%s': %w", key, err)
+	}
+
+	return textData, nil
+}
+
+// watchQueue counts the pending messages of sub as queued jobs, and applies
+// the pending message limit to it.
+func (w *NatsWorker) watchQueue(sub *nats.Subscription) error {
+	if w.maxPending > 0 {
+		err := sub.SetPendingLimits(w.maxPending, nats.DefaultSubPendingBytesLimit)
+		if err != nil {
+			return fmt.Errorf("failed to limit pending messages on subject %s: %w", sub.Subject, err)
+		}
+	}
+
+	w.queuesMu.Lock()
+	defer w.queuesMu.Unlock()
+
+	w.queues = append(w.queues, sub)
+
+	return nil
+}
+
+// queued returns the number of jobs and documents received but not started.
+func (w *NatsWorker) queued() int {
+	w.queuesMu.Lock()
+	defer w.queuesMu.Unlock()
+
+	total := 0
+
+	for _, sub := range w.queues {
+		pending, _, err := sub.Pending()
+		if err == nil {
+			total += pending
+		}
+	}
+
+	return total
+}
+
+// awaitCapacity decides whether the worker takes msg now. While the local
+// queue is full, JetStream messages are handed back for redelivery, possibly
+// to another worker, and false is returned. While the host is over its load
+// thresholds, it waits, telling JetStream the message is in progress so it is
+// not redelivered meanwhile; it returns false if ctx ends first.
+func (w *NatsWorker) awaitCapacity(ctx context.Context, msg *nats.Msg) bool {
+	if w.load == nil {
+		return true
+	}
+
+	_, jetStreamErr := msg.Metadata()
+	jetStream := jetStreamErr == nil
+
+	if jetStream && w.load.QueueFull(w.queued()) {
+		err := msg.NakWithDelay(w.load.Interval())
+		if err != nil {
+			w.log.Warn("Failed to hand back a job while the queue is full: %v", err)
+		}
+
+		return false
+	}
+
+	reason := w.load.Overloaded()
+	if reason == "" {
+		return true
+	}
+
+	w.log.Warn("Pausing job intake: %s.", reason)
+
+	ticker := time.NewTicker(w.load.Interval())
+	defer ticker.Stop()
+
+	for reason != "" {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-ticker.C:
+		}
+
+		if jetStream {
+			err := msg.InProgress()
+			if err != nil {
+				w.log.Warn("Failed to extend the ack deadline of a held job: %v", err)
+			}
+		}
+
+		reason = w.load.Overloaded()
+	}
+
+	w.log.Info("Resuming job intake.")
+
+	return true
+}
+
+// filterText screens the text of a job with the filter, if any, under the
+// policy of the job's tenant.
+func (w *NatsWorker) filterText(ctx context.Context, event *core.JobEvent, textData []byte) ([]byte, error) {
+	if w.filter == nil {
+		return textData, nil
+	}
+
+	filtered, err := w.filter.Filter(ctx, event.Header.TenantID, textData)
+	if err != nil {
+		return nil, fmt.Errorf("text for key '%s': %w", event.TextKey, err)
+	}
+
+	if !bytes.Equal(filtered, textData) {
+		w.log.Info("Redacted the text of workflow %s page %d (tenant '%s')",
+			event.Header.WorkflowID, event.PageNumber, event.Header.TenantID)
+	}
+
+	return filtered, nil
+}
+
+// jobConfig resolves the job's model and fills and validates its configuration.
+func (w *NatsWorker) jobConfig(event *core.JobEvent, defaults JobDefaults) (core.TTSConfig, error) {
+	base, err := w.resolveModel(event.Model, event.Language)
+	if err != nil {
+		return core.TTSConfig{}, fmt.Errorf("%w: %w", ErrInvalidConfig, err)
 	}
 
 	ttsCfg := core.TTSConfig{
-		ModelPath:         w.processor.GetConfig().ModelPath,
-		SnacModelPath:     w.processor.GetConfig().SnacModelPath,
+		Model:             base.Model,
+		ModelPath:         base.ModelPath,
+		SnacModelPath:     base.SnacModelPath,
 		Voice:             event.Voice,
 		Seed:              event.Seed,
 		NGL:               event.NGL,
 		TopP:              event.TopP,
 		RepetitionPenalty: event.RepetitionPenalty,
 		Temperature:       event.Temperature,
+		Device:            "",
+		Language:          event.Language,
+		Rate:              event.Rate,
+		Pitch:             event.Pitch,
+		Style:             event.Style,
 	}
 
+	if ttsCfg.Voice == "" {
+		ttsCfg.Voice = base.Voice
+	}
+
+	ttsCfg = defaults.apply(ttsCfg)
+
 	validationErr := w.validateTTSConfig(ttsCfg)
 	if validationErr != nil {
 		w.log.Error("Invalid TTS configuration for workflow %s: %v", event.Header.WorkflowID, validationErr)
 
-		return "", validationErr
+		return core.TTSConfig{}, fmt.Errorf("%w: %w", ErrInvalidConfig, validationErr)
 	}
 
-	audioData, err := w.processor.Process(ctx, textData, ttsCfg)
+	return ttsCfg, nil
+}
+
+// newReplyEvent describes an uploaded chunk: its format and duration when it is
+// a WAV file, its size and digest, and the configuration it was synthesized with.
+func (w *NatsWorker) newReplyEvent(
+	event *core.JobEvent,
+	audioKey string,
+	audioData []byte,
+	cfg core.TTSConfig,
+) *core.AudioChunkEvent {
+	digest := sha256.Sum256(audioData)
+
+	reply := &core.AudioChunkEvent{
+		AudioChunkCreatedEvent: events.AudioChunkCreatedEvent{
+			Header:     event.Header,
+			AudioKey:   audioKey,
+			PageNumber: event.PageNumber,
+			TotalPages: event.TotalPages,
+		},
+		DurationSeconds:  0,
+		SampleRate:       0,
+		Channels:         0,
+		SizeBytes:        len(audioData),
+		SHA256:           hex.EncodeToString(digest[:]),
+		Config:           effectiveConfig(cfg),
+		Build:            buildinfo.Get(),
+		SynthesisAttempt: 1,
+		TextStats:        nil,
+	}
+
+	info, err := wav.Inspect(audioData)
 	if err != nil {
-		return "", fmt.Errorf("failed to process text to speech: %w", err)
+		w.log.Warn("Audio for workflow %s is not a readable WAV file: %v", event.Header.WorkflowID, err)
+
+		return reply
 	}
 
-	audioKey := uuid.NewString() + ".wav"
+	reply.DurationSeconds = info.Duration().Seconds()
+	reply.SampleRate = info.SampleRate
+	reply.Channels = info.Channels
 
-	err = w.store.Upload(ctx, audioKey, audioData)
+	return reply
+}
+
+// effectiveConfig returns the part of cfg reported to consumers.
+func effectiveConfig(cfg core.TTSConfig) core.EffectiveConfig {
+	return core.EffectiveConfig{
+		Model:             cfg.Model,
+		ModelFile:         fileName(cfg.ModelPath),
+		Voice:             cfg.Voice,
+		Language:          cfg.Language,
+		Seed:              cfg.Seed,
+		NGL:               cfg.NGL,
+		TopP:              cfg.TopP,
+		RepetitionPenalty: cfg.RepetitionPenalty,
+		Temperature:       cfg.Temperature,
+		Rate:              cfg.Rate,
+		Pitch:             cfg.Pitch,
+		Style:             cfg.Style,
+	}
+}
+
+// resolveModel returns the base configuration for the selected model. Without a
+// model registry only the default model is available and voices are not defaulted.
+// Jobs that select a language but no model are routed by language when the
+// registry supports it.
+func (w *NatsWorker) resolveModel(model, language string) (core.TTSConfig, error) {
+	if w.models == nil {
+		if model != "" {
+			return core.TTSConfig{}, fmt.Errorf("%w: got '%s'", ErrModelSelectionUnsupported, model)
+		}
+
+		base := w.processor.GetConfig()
+		base.Model = ""
+		base.Voice = ""
+
+		return base, nil
+	}
+
+	languages, ok := w.models.(core.LanguageResolver)
+	if ok && model == "" && language != "" {
+		base, err := languages.ResolveLanguage(language)
+		if err != nil {
+			return core.TTSConfig{}, fmt.Errorf("failed to resolve language: %w", err)
+		}
+
+		return base, nil
+	}
+
+	base, err := w.models.ResolveModel(model)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload audio data for key '%s': %w", audioKey, err)
+		return core.TTSConfig{}, fmt.Errorf("failed to resolve model: %w", err)
 	}
 
-	return audioKey, nil
+	return base, nil
 }
 
-// publishReplyEvent marshals and responds with the AudioChunkCreatedEvent.
-func (w *NatsWorker) publishReplyEvent(msg *nats.Msg, replyEvent *events.AudioChunkCreatedEvent) error {
+// publishReplyEvent marshals and responds with the AudioChunkEvent.
+func (w *NatsWorker) publishReplyEvent(msg *nats.Msg, replyEvent *core.AudioChunkEvent) error {
 	replyData, err := json.Marshal(replyEvent)
 	if err != nil {
 		return fmt.Errorf("failed to marshal reply event: %w", err)
@@ -168,46 +1071,250 @@ func (w *NatsWorker) publishReplyEvent(msg *nats.Msg, replyEvent *events.AudioCh
 	return nil
 }
 
-func (w *NatsWorker) parseAndValidateEvent(msg *nats.Msg) (*events.TextProcessedEvent, error) {
-	var event events.TextProcessedEvent
+// publishFailure publishes a core.TTSJobFailedEvent for a failed job to the
+// failure subject. event is nil when the message could not be parsed. Failures
+// to publish are logged, since the job has already failed.
+func (w *NatsWorker) publishFailure(msg *nats.Msg, event *core.JobEvent, jobErr error) {
+	if w.failureSubject == "" {
+		return
+	}
 
-	err := json.Unmarshal(msg.Data, &event)
+	original := json.RawMessage(msg.Data)
+	if !json.Valid(msg.Data) {
+		// Marshalling a string cannot fail.
+		original, _ = json.Marshal(string(msg.Data))
+	}
+
+	failure := core.TTSJobFailedEvent{
+		Header:             events.EventHeader{},
+		PageNumber:         0,
+		TotalPages:         0,
+		ErrorClass:         classifyError(jobErr),
+		Error:              jobErr.Error(),
+		SupportedLanguages: nil,
+		Attempt:            deliveryAttempt(msg),
+		Event:              original,
+		FailedAt:           time.Now().UTC(),
+	}
+
+	var languageErr *core.LanguageError
+	if errors.As(jobErr, &languageErr) {
+		failure.SupportedLanguages = languageErr.Supported
+	}
+
+	if event != nil {
+		failure.Header = event.Header
+		failure.PageNumber = event.PageNumber
+		failure.TotalPages = event.TotalPages
+	}
+
+	data, err := json.Marshal(failure)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal event: %w", err)
+		w.log.Error("Failed to marshal failure event for workflow %s: %v", failure.Header.WorkflowID, err)
+
+		return
 	}
 
-	return &event, nil
+	err = w.natsConnection.Publish(w.failureSubject, data)
+	if err != nil {
+		w.log.Error("Failed to publish failure event for workflow %s: %v", failure.Header.WorkflowID, err)
+	}
 }
 
-// validateTTSConfig ensures that the TTSConfig contains valid and safe values.
-func (w *NatsWorker) validateTTSConfig(cfg core.TTSConfig) error {
-	// Validate ModelPath
-	if cfg.ModelPath == "" {
-		return ErrModelPathEmpty
+// classifyError maps a job error to its failure class. Running out of time or
+// being cancelled takes precedence over the stage that noticed it.
+func classifyError(err error) core.ErrorClass {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return core.ErrorClassTimeout
+	case errors.Is(err, context.Canceled):
+		return core.ErrorClassCancelled
+	case errors.Is(err, ErrInvalidEvent):
+		return core.ErrorClassInvalidEvent
+	case errors.Is(err, core.ErrUnsupportedLanguage):
+		return core.ErrorClassUnsupportedLanguage
+	case errors.Is(err, ErrInvalidConfig):
+		return core.ErrorClassInvalidConfig
+	case errors.Is(err, ErrInvalidText):
+		return core.ErrorClassInvalidText
+	case errors.Is(err, ErrTextTooLong):
+		return core.ErrorClassTextTooLong
+	case errors.Is(err, core.ErrContentRejected):
+		return core.ErrorClassContentRejected
+	case errors.Is(err, core.ErrChecksumMismatch):
+		return core.ErrorClassCorruptObject
+	case errors.Is(err, ErrDownloadFailed):
+		return core.ErrorClassDownload
+	case errors.Is(err, core.ErrInvalidAudio):
+		return core.ErrorClassInvalidAudio
+	case errors.Is(err, quality.ErrRejected):
+		return core.ErrorClassQuality
+	case errors.Is(err, ErrSynthesisFailed):
+		return core.ErrorClassSynthesis
+	case errors.Is(err, ErrUploadFailed):
+		return core.ErrorClassUpload
+	default:
+		return core.ErrorClassInternal
+	}
+}
+
+// deliveryAttempt returns the JetStream delivery count of the message, or 1
+// for a message delivered outside JetStream.
+func deliveryAttempt(msg *nats.Msg) int {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return 1
+	}
+
+	return int(metadata.NumDelivered) // #nosec G115 -- delivery counts are small
+}
+
+// recordStatus stores a job lifecycle transition. Failures are logged but never
+// interrupt processing, since status tracking is advisory.
+func (w *NatsWorker) recordStatus(
+	ctx context.Context,
+	event *events.TextProcessedEvent,
+	state core.JobState,
+	audioKey string,
+	jobErr error,
+) {
+	if w.statusStore == nil {
+		return
 	}
-	// For simplicity, assuming ModelPath is always absolute and trusted for now.
-	// In a real-world scenario, more robust path validation (e.g., against whitelists,
-	// checking for directory traversal) would be needed.
 
-	// Validate SnacModelPath
-	if cfg.SnacModelPath == "" {
-		return ErrSnacModelPathEmpty
+	status := core.JobStatus{
+		WorkflowID: event.Header.WorkflowID,
+		State:      state,
+		PageNumber: event.PageNumber,
+		TotalPages: event.TotalPages,
+		AudioKey:   audioKey,
+		Error:      "",
+		Progress:   nil,
+		UpdatedAt:  time.Now().UTC(),
 	}
-	// Similar to ModelPath, assuming trusted for now.
 
-	// Validate Voice (example with a simple whitelist)
-	allowedVoices := map[string]struct{}{
-		"default": {},
-		"male1":   {},
-		"female1": {},
+	if jobErr != nil {
+		status.Error = jobErr.Error()
 	}
 
-	if cfg.Voice == "" {
-		return ErrVoiceEmpty
+	err := w.statusStore.Put(ctx, status)
+	if err != nil {
+		w.log.Warn("Failed to record '%s' status for workflow %s: %v", state, event.Header.WorkflowID, err)
 	}
+}
+
+// recordProgress logs the inference progress of a job and records it with
+// its processing status.
+func (w *NatsWorker) recordProgress(ctx context.Context, event *events.TextProcessedEvent, progress core.Progress) {
+	w.log.Info("Workflow %s page %d: %d tokens generated, %.1f tokens/s",
+		event.Header.WorkflowID, event.PageNumber, progress.Tokens, progress.TokensPerSecond)
 
-	if _, ok := allowedVoices[cfg.Voice]; !ok {
-		return fmt.Errorf("%w: '%s'", ErrUnsupportedVoice, cfg.Voice)
+	if w.statusStore == nil {
+		return
+	}
+
+	err := w.statusStore.Put(ctx, core.JobStatus{
+		WorkflowID: event.Header.WorkflowID,
+		State:      core.JobStateProcessing,
+		PageNumber: event.PageNumber,
+		TotalPages: event.TotalPages,
+		AudioKey:   "",
+		Error:      "",
+		Progress:   &progress,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		w.log.Warn("Failed to record progress for workflow %s: %v", event.Header.WorkflowID, err)
+	}
+}
+
+// handleStatusQuery answers a job status request. The request payload is the workflow ID.
+func (w *NatsWorker) handleStatusQuery(parent context.Context, msg *nats.Msg) {
+	ctx, cancel := context.WithTimeout(parent, handleMessageTimeout)
+	defer cancel()
+
+	var response core.JobStatusResponse
+
+	workflowID := strings.TrimSpace(string(msg.Data))
+
+	// The ID names keys in the status bucket, so it must not match others.
+	validErr := jobstatus.ValidateWorkflowID(workflowID)
+
+	switch {
+	case w.statusStore == nil:
+		response.Error = ErrStatusTrackingDisabled.Error()
+	case validErr != nil:
+		response.Error = validErr.Error()
+	default:
+		status, err := w.statusStore.Get(ctx, workflowID)
+		if err != nil {
+			response.Error = err.Error()
+		} else {
+			response.Status = &status
+		}
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.log.Error("Failed to marshal status response for workflow %s: %v", workflowID, err)
+
+		return
+	}
+
+	err = msg.Respond(data)
+	if err != nil {
+		w.log.Error("Failed to respond to status query for workflow %s: %v", workflowID, err)
+	}
+}
+
+// handleVersionQuery answers a version request with the service build and the
+// files of the default model.
+func (w *NatsWorker) handleVersionQuery(msg *nats.Msg) {
+	cfg := w.processor.GetConfig()
+
+	data, err := json.Marshal(core.VersionInfo{
+		Build:     buildinfo.Get(),
+		ModelFile: fileName(cfg.ModelPath),
+		SnacFile:  fileName(cfg.SnacModelPath),
+	})
+	if err != nil {
+		w.log.Error("Failed to marshal version response: %v", err)
+
+		return
+	}
+
+	err = msg.Respond(data)
+	if err != nil {
+		w.log.Error("Failed to respond to version request: %v", err)
+	}
+}
+
+// fileName returns the last element of a model path; empty for an empty path.
+func fileName(path string) string {
+	if path == "" {
+		return ""
+	}
+
+	return filepath.Base(path)
+}
+
+func (w *NatsWorker) parseAndValidateEvent(msg *nats.Msg) (*core.JobEvent, error) {
+	var event core.JobEvent
+
+	err := json.Unmarshal(msg.Data, &event)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidEvent, err)
+	}
+
+	return &event, nil
+}
+
+// validateTTSConfig ensures that the TTSConfig contains valid and safe values.
+// Model paths and voices depend on the backend, so the processor checks those
+// when it implements core.ConfigValidator.
+func (w *NatsWorker) validateTTSConfig(cfg core.TTSConfig) error {
+	if cfg.Voice == "" {
+		return ErrVoiceEmpty
 	}
 
 	// Validate numeric parameters
@@ -226,7 +1333,16 @@ func (w *NatsWorker) validateTTSConfig(cfg core.TTSConfig) error {
 	if cfg.NGL < 0 {
 		return fmt.Errorf("%w: got %d", ErrNGLNegative, cfg.NGL)
 	}
-	// Seed is typically just an int, no specific range usually enforced beyond non-negative if desired.
+
+	validator, ok := w.processor.(core.ConfigValidator)
+	if !ok {
+		return nil
+	}
+
+	err := validator.ValidateConfig(cfg)
+	if err != nil {
+		return fmt.Errorf("invalid configuration for the selected backend: %w", err)
+	}
 
 	return nil
 }
@@ -2,15 +2,34 @@
 package worker_test
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/book-expert/events"
 	"github.com/book-expert/logger"
+	"github.com/book-expert/tts-service/internal/buildinfo"
 	"github.com/book-expert/tts-service/internal/core"
+	"github.com/book-expert/tts-service/internal/jobstatus"
+	"github.com/book-expert/tts-service/internal/load"
+	"github.com/book-expert/tts-service/internal/quality"
+	"github.com/book-expert/tts-service/internal/tts"
+	"github.com/book-expert/tts-service/internal/wav"
 	"github.com/book-expert/tts-service/internal/worker"
 	"github.com/google/uuid"
 
@@ -21,13 +40,20 @@ import (
 )
 
 var (
-	errMockDownload = errors.New("mock download error")
-	errMockUpload   = errors.New("mock upload error")
-	errMockProcess  = errors.New("mock process error")
+	errMockUnknownModel = errors.New("mock unknown model")
+	errMockDownload     = errors.New("mock download error")
+	errMockUpload       = errors.New("mock upload error")
+	errMockProcess      = errors.New("mock process error")
+
+	errMockStatusNotFound = errors.New("mock status not found")
 )
 
 // mockObjectStore is a mock implementation of the ObjectStore interface.
 type mockObjectStore struct {
+	// mu guards the recorded keys and data, since jobs may run concurrently.
+	mu sync.Mutex
+	// texts maps text keys to their text; other keys download "sample text".
+	texts              map[string][]byte
 	downloadShouldFail bool
 	uploadShouldFail   bool
 	downloadedKey      string
@@ -40,8 +66,16 @@ func (m *mockObjectStore) Download(_ context.Context, key string) ([]byte, error
 		return nil, errMockDownload
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.downloadedKey = key
 
+	text, ok := m.texts[key]
+	if ok {
+		return text, nil
+	}
+
 	return []byte("sample text"), nil
 }
 
@@ -50,12 +84,18 @@ func (m *mockObjectStore) Upload(_ context.Context, key string, data []byte) err
 		return errMockUpload
 	}
 
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	m.uploadedKey = key
 	m.uploadedData = data
 
 	return nil
 }
 
+// sampleAudio is the audio returned by mockTTSProcessor: 1.5 seconds of silence.
+var sampleAudio = wav.EncodePCM16(make([]float32, 36000), 24000)
+
 // mockTTSProcessor is a mock implementation of the TTSProcessor interface.
 type mockTTSProcessor struct {
 	processShouldFail bool
@@ -68,15 +108,132 @@ func (m *mockTTSProcessor) GetConfig() core.TTSConfig {
 	return m.config
 }
 
-func (m *mockTTSProcessor) Process(_ context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
+func (m *mockTTSProcessor) Process(ctx context.Context, text []byte, cfg core.TTSConfig) ([]byte, error) {
 	if m.processShouldFail {
 		return nil, errMockProcess
 	}
 
+	core.ReportProgress(ctx, core.Progress{Tokens: 120, TokensPerSecond: 40})
+
 	m.processedText = text
 	m.processedCfg = cfg
 
-	return []byte("sample audio"), nil
+	return sampleAudio, nil
+}
+
+// mockStatusStore is a mock implementation of the JobStatusStore interface.
+type mockStatusStore struct {
+	mu      sync.Mutex
+	history []core.JobStatus
+	pages   map[string]map[int]core.JobStatus
+}
+
+// mockModelResolver is a mock implementation of the ModelResolver interface.
+type mockModelResolver struct {
+	models map[string]core.TTSConfig
+}
+
+func (m *mockModelResolver) ResolveModel(name string) (core.TTSConfig, error) {
+	cfg, ok := m.models[name]
+	if !ok {
+		return core.TTSConfig{}, errMockUnknownModel
+	}
+
+	return cfg, nil
+}
+
+func newMockStatusStore() *mockStatusStore {
+	return &mockStatusStore{
+		mu:      sync.Mutex{},
+		history: nil,
+		pages:   make(map[string]map[int]core.JobStatus),
+	}
+}
+
+func (m *mockStatusStore) Put(_ context.Context, status core.JobStatus) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.history = append(m.history, status)
+
+	if m.pages[status.WorkflowID] == nil {
+		m.pages[status.WorkflowID] = make(map[int]core.JobStatus)
+	}
+
+	m.pages[status.WorkflowID][status.PageNumber] = status
+
+	return nil
+}
+
+func (m *mockStatusStore) Get(_ context.Context, workflowID string) (core.WorkflowStatus, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	pages, ok := m.pages[workflowID]
+	if !ok {
+		return core.WorkflowStatus{}, errMockStatusNotFound
+	}
+
+	statuses := make([]core.JobStatus, 0, len(pages))
+	for _, status := range pages {
+		statuses = append(statuses, status)
+	}
+
+	return jobstatus.Summarize(workflowID, statuses), nil
+}
+
+func (m *mockStatusStore) states() []core.JobState {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	states := make([]core.JobState, 0, len(m.history))
+	for _, status := range m.history {
+		states = append(states, status.State)
+	}
+
+	return states
+}
+
+// requestWhenReady retries a request until the worker's subscription is in place.
+func requestWhenReady(t *testing.T, natsConnection *nats.Conn, subject string, data []byte) *nats.Msg {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+
+	for {
+		replyMsg, err := natsConnection.Request(subject, data, 5*time.Second)
+		if errors.Is(err, nats.ErrNoResponders) && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+
+			continue
+		}
+
+		require.NoError(t, err, "Request should succeed and receive a reply")
+
+		return replyMsg
+	}
+}
+
+func newTestEvent(textKey string) *events.TextProcessedEvent {
+	return &events.TextProcessedEvent{
+		Header: events.EventHeader{
+			Timestamp:  time.Now(),
+			WorkflowID: uuid.NewString(),
+			EventID:    uuid.NewString(),
+			UserID:     "",
+			TenantID:   "",
+		},
+		TextKey:           textKey,
+		PNGKey:            "",
+		PageNumber:        1,
+		TotalPages:        3,
+		Voice:             "default",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0.95,
+		RepetitionPenalty: 1.1,
+		Temperature:       0.7,
+	}
 }
 
 func createTestNatsClient(t *testing.T) (*nats.Conn, func()) {
@@ -100,7 +257,7 @@ func createTestNatsClient(t *testing.T) (*nats.Conn, func()) {
 	return natsConnection, cleanup
 }
 
-func setupTest(t *testing.T) (
+func setupTest(t *testing.T, opts worker.Options) (
 	*worker.NatsWorker,
 	*mockObjectStore,
 	*mockTTSProcessor,
@@ -110,17 +267,11 @@ func setupTest(t *testing.T) (
 ) {
 	t.Helper()
 
-	mockStore := &mockObjectStore{
-		downloadShouldFail: false,
-		uploadShouldFail:   false,
-		downloadedKey:      "",
-		uploadedKey:        "",
-		uploadedData:       nil,
-	}
 	mockProcessor := &mockTTSProcessor{
 		processShouldFail: false,
 		processedText:     nil,
 		processedCfg: core.TTSConfig{
+			Model:             "",
 			ModelPath:         "dummy_model_path",
 			SnacModelPath:     "dummy_snac_model_path",
 			Voice:             "dummy_voice",
@@ -129,8 +280,14 @@ func setupTest(t *testing.T) (
 			TopP:              0.0,
 			RepetitionPenalty: 0.0,
 			Temperature:       0.0,
+			Device:            "",
+			Language:          "",
+			Rate:              0,
+			Pitch:             0,
+			Style:             "",
 		},
 		config: core.TTSConfig{
+			Model:             "",
 			ModelPath:         "dummy_model_path",
 			SnacModelPath:     "dummy_snac_model_path",
 			Voice:             "dummy_voice",
@@ -139,9 +296,38 @@ func setupTest(t *testing.T) (
 			TopP:              0.0,
 			RepetitionPenalty: 0.0,
 			Temperature:       0.0,
+			Device:            "",
+			Language:          "",
+			Rate:              0,
+			Pitch:             0,
+			Style:             "",
 		},
 	}
 
+	workerInstance, mockStore, ctx, cancel, natsConnection := setupTestWithProcessor(t, mockProcessor, opts)
+
+	return workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection
+}
+
+func setupTestWithProcessor(t *testing.T, processor core.TTSProcessor, opts worker.Options) (
+	*worker.NatsWorker,
+	*mockObjectStore,
+	context.Context,
+	context.CancelFunc,
+	*nats.Conn,
+) {
+	t.Helper()
+
+	mockStore := &mockObjectStore{
+		mu:                 sync.Mutex{},
+		texts:              nil,
+		downloadShouldFail: false,
+		uploadShouldFail:   false,
+		downloadedKey:      "",
+		uploadedKey:        "",
+		uploadedData:       nil,
+	}
+
 	natsConnection, natsCleanup := createTestNatsClient(t)
 	t.Cleanup(natsCleanup)
 
@@ -152,19 +338,43 @@ func setupTest(t *testing.T) (
 	require.NoError(t, err)
 
 	workerInstance, err := worker.NewNatsWorker(
-		natsConnection, jetstreamContext, "test_subject", mockStore, mockProcessor, testLogger,
+		natsConnection, jetstreamContext, "test_subject", mockStore, processor, testLogger, opts,
 	)
 	require.NoError(t, err)
 
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection
+	return workerInstance, mockStore, ctx, cancel, natsConnection
 }
 
 func TestMessageHandler_Success(t *testing.T) {
 	t.Parallel()
 
-	workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection := setupTest(t)
+	statusStore := newMockStatusStore()
+	workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        statusStore,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
 	defer cancel()
 
 	errChan := make(chan error, 1)
@@ -173,32 +383,13 @@ func TestMessageHandler_Success(t *testing.T) {
 		errChan <- workerInstance.Run(ctx)
 	}()
 
-	testEvent := &events.TextProcessedEvent{
-		Header: events.EventHeader{
-			Timestamp:  time.Now(),
-			WorkflowID: uuid.NewString(),
-			EventID:    uuid.NewString(),
-			UserID:     "",
-			TenantID:   "",
-		},
-		TextKey:           "test-text-key",
-		PNGKey:            "",
-		PageNumber:        0,
-		TotalPages:        0,
-		Voice:             "",
-		Seed:              0,
-		NGL:               0,
-		TopP:              0,
-		RepetitionPenalty: 0,
-		Temperature:       0,
-	}
+	testEvent := newTestEvent("test-text-key")
 	eventData, err := json.Marshal(testEvent)
 	require.NoError(t, err)
 
-	replyMsg, err := natsConnection.Request("test_subject", eventData, 5*time.Second)
-	require.NoError(t, err, "Request should succeed and receive a reply")
+	replyMsg := requestWhenReady(t, natsConnection, "test_subject", eventData)
 
-	var replyEvent events.AudioChunkCreatedEvent
+	var replyEvent core.AudioChunkEvent
 
 	err = json.Unmarshal(replyMsg.Data, &replyEvent)
 	require.NoError(t, err)
@@ -206,13 +397,1427 @@ func TestMessageHandler_Success(t *testing.T) {
 	assert.Equal(t, "test-text-key", mockStore.downloadedKey)
 	assert.Equal(t, []byte("sample text"), mockProcessor.processedText)
 	assert.NotEmpty(t, mockStore.uploadedKey, "An audio key should have been generated and uploaded")
-	assert.Equal(t, []byte("sample audio"), mockStore.uploadedData)
+	assert.Equal(t, sampleAudio, mockStore.uploadedData)
 
 	assert.Equal(t, mockStore.uploadedKey, replyEvent.AudioKey)
 	assert.Equal(t, testEvent.Header.WorkflowID, replyEvent.Header.WorkflowID)
 
+	digest := sha256.Sum256(sampleAudio)
+	assert.InDelta(t, 1.5, replyEvent.DurationSeconds, 1e-9)
+	assert.Equal(t, 24000, replyEvent.SampleRate)
+	assert.Equal(t, 1, replyEvent.Channels)
+	assert.Equal(t, len(sampleAudio), replyEvent.SizeBytes)
+	assert.Equal(t, hex.EncodeToString(digest[:]), replyEvent.SHA256)
+	assert.Equal(t, 1, replyEvent.SynthesisAttempt)
+	assert.Equal(t, testEvent.Voice, replyEvent.Config.Voice)
+	assert.InDelta(t, testEvent.Temperature, replyEvent.Config.Temperature, 1e-9)
+	assert.Equal(t, "dummy_model_path", replyEvent.Config.ModelFile)
+	assert.Equal(t, buildinfo.Get(), replyEvent.Build, "the chunk names the build that made it")
+
+	assert.Equal(t,
+		[]core.JobState{core.JobStateReceived, core.JobStateProcessing, core.JobStateProcessing, core.JobStateCompleted},
+		statusStore.states(),
+	)
+	assert.Equal(t, &core.Progress{Tokens: 120, TokensPerSecond: 40}, statusStore.history[2].Progress,
+		"the processor's progress is recorded")
+
 	cancel()
 
 	shutdownErr := <-errChan
 	assert.NoError(t, shutdownErr, "worker.Run should not error on graceful shutdown")
 }
+
+func TestVersionQuery(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, _, _, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "test_version",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	replyMsg := requestWhenReady(t, natsConnection, "test_version", nil)
+
+	var version core.VersionInfo
+
+	require.NoError(t, json.Unmarshal(replyMsg.Data, &version))
+	assert.Equal(t, buildinfo.Get(), version.Build)
+	assert.Equal(t, "dummy_model_path", version.ModelFile)
+	assert.Equal(t, "dummy_snac_model_path", version.SnacFile)
+}
+
+func TestStatusQuery(t *testing.T) {
+	t.Parallel()
+
+	statusStore := newMockStatusStore()
+	workerInstance, _, _, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        statusStore,
+		StatusSubject:      "test_status",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	stored := core.JobStatus{
+		WorkflowID: "workflow-42",
+		State:      core.JobStateFailed,
+		PageNumber: 4,
+		TotalPages: 9,
+		AudioKey:   "",
+		Error:      "chatllm exploded",
+		Progress:   nil,
+		UpdatedAt:  time.Now().UTC(),
+	}
+	require.NoError(t, statusStore.Put(context.Background(), stored))
+
+	replyMsg := requestWhenReady(t, natsConnection, "test_status", []byte("workflow-42"))
+
+	var response core.JobStatusResponse
+
+	require.NoError(t, json.Unmarshal(replyMsg.Data, &response))
+	require.NotNil(t, response.Status)
+	assert.Empty(t, response.Error)
+	assert.Equal(t, core.JobStateFailed, response.Status.State)
+	assert.Equal(t, 1, response.Status.PagesFailed)
+	require.Len(t, response.Status.Pages, 1)
+	assert.Equal(t, "chatllm exploded", response.Status.Pages[0].Error)
+	assert.Equal(t, 4, response.Status.Pages[0].PageNumber)
+
+	replyMsg = requestWhenReady(t, natsConnection, "test_status", []byte("unknown"))
+
+	response = core.JobStatusResponse{Status: nil, Error: ""}
+	require.NoError(t, json.Unmarshal(replyMsg.Data, &response))
+	assert.Nil(t, response.Status)
+	assert.Contains(t, response.Error, errMockStatusNotFound.Error())
+
+	for _, query := range []string{"*", ">", "workflow-42.*", "workflow 42"} {
+		replyMsg = requestWhenReady(t, natsConnection, "test_status", []byte(query))
+
+		response = core.JobStatusResponse{Status: nil, Error: ""}
+		require.NoError(t, json.Unmarshal(replyMsg.Data, &response))
+		assert.Nil(t, response.Status, query)
+		assert.Contains(t, response.Error, jobstatus.ErrInvalidWorkflowID.Error(), query)
+	}
+}
+
+func TestMessageHandler_ModelSelection(t *testing.T) {
+	t.Parallel()
+
+	statusStore := newMockStatusStore()
+	resolver := &mockModelResolver{models: map[string]core.TTSConfig{
+		"narrator": {
+			Model:             "narrator",
+			ModelPath:         "narrator.gguf",
+			SnacModelPath:     "narrator-snac.gguf",
+			Voice:             "female1",
+			Seed:              0,
+			NGL:               0,
+			TopP:              0,
+			RepetitionPenalty: 0,
+			Temperature:       0,
+			Device:            "",
+			Language:          "",
+			Rate:              0,
+			Pitch:             0,
+			Style:             "",
+		},
+	}}
+	workerInstance, _, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        statusStore,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             resolver,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	testEvent := newTestEvent("test-text-key")
+	testEvent.Voice = ""
+
+	eventData, err := json.Marshal(core.JobEvent{
+		TextProcessedEvent: *testEvent, Model: "narrator", Language: "en", Rate: 0, Pitch: 0, Style: "calm", TimeoutSeconds: 0,
+	})
+	require.NoError(t, err)
+
+	requestWhenReady(t, natsConnection, "test_subject", eventData)
+
+	assert.Equal(t, "narrator", mockProcessor.processedCfg.Model)
+	assert.Equal(t, "en", mockProcessor.processedCfg.Language)
+	assert.Equal(t, "calm", mockProcessor.processedCfg.Style)
+	assert.Equal(t, "narrator.gguf", mockProcessor.processedCfg.ModelPath)
+	assert.Equal(t, "female1", mockProcessor.processedCfg.Voice, "the model's default voice should apply")
+
+	unknownEvent := newTestEvent("test-text-key")
+
+	eventData, err = json.Marshal(core.JobEvent{TextProcessedEvent: *unknownEvent, Model: "missing", Language: "", Rate: 0, Pitch: 0, Style: "", TimeoutSeconds: 0})
+	require.NoError(t, err)
+
+	require.NoError(t, natsConnection.Publish("test_subject", eventData))
+
+	require.Eventually(t, func() bool {
+		status, getErr := statusStore.Get(context.Background(), unknownEvent.Header.WorkflowID)
+
+		return getErr == nil && status.State == core.JobStateFailed
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+// fakePiper writes the voice and the text from stdin to piper's --output_file.
+const fakePiper = `#!/bin/sh
+while [ $# -gt 0 ]; do
+	case "$1" in
+	--model) model=$2; shift ;;
+	--output_file) output=$2; shift ;;
+	esac
+	shift
+done
+{ printf '%s:' "$model"; cat; } > "$output"
+`
+
+func newBackendConfig(modelPath, snacModelPath string) core.TTSConfig {
+	return core.TTSConfig{
+		Model:             "",
+		ModelPath:         modelPath,
+		SnacModelPath:     snacModelPath,
+		Voice:             "",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0,
+		Device:            "",
+		Language:          "",
+		Rate:              0,
+		Pitch:             0,
+		Style:             "",
+	}
+}
+
+// newBackendRouter routes the default model to chatllm, "fast" to piper and
+// "cloud" to a fake Google endpoint.
+func newBackendRouter(t *testing.T) *tts.Router {
+	t.Helper()
+
+	testLogger, err := logger.New("/tmp", "test-log.log")
+	require.NoError(t, err)
+
+	chatllm, err := tts.New(newBackendConfig("model.gguf", "snac.gguf"), testLogger)
+	require.NoError(t, err)
+
+	command := filepath.Join(t.TempDir(), "piper")
+	require.NoError(t, os.WriteFile(command, []byte(fakePiper), 0o700))
+
+	piper, err := tts.NewPiper(newBackendConfig("voice.onnx", ""), command, testLogger)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(writer).Encode(map[string]string{
+			"audioContent": base64.StdEncoding.EncodeToString([]byte("cloud audio")),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	google, err := tts.NewGoogle(tts.GoogleConfig{
+		Endpoint:     server.URL,
+		APIKey:       "secret",
+		LanguageCode: "en-US",
+		Voices:       map[string]string{"female1": "en-US-Neural2-F"},
+		Timeout:      5 * time.Second,
+	}, newBackendConfig("", ""))
+	require.NoError(t, err)
+
+	return tts.NewRouter(
+		tts.Route{Processor: chatllm, DefaultVoice: "", Languages: nil},
+		map[string]tts.Route{
+			"fast":  {Processor: piper, DefaultVoice: "amy", Languages: nil},
+			"cloud": {Processor: google, DefaultVoice: "female1", Languages: nil},
+		},
+		nil,
+	)
+}
+
+func TestMessageHandler_BackendValidation(t *testing.T) {
+	t.Parallel()
+
+	router := newBackendRouter(t)
+	statusStore := newMockStatusStore()
+	workerInstance, mockStore, ctx, cancel, natsConnection := setupTestWithProcessor(t, router, worker.Options{
+		StatusStore:        statusStore,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             router,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	publishJob := func(model, voice string) {
+		testEvent := newTestEvent("test-text-key")
+		testEvent.Voice = voice
+
+		eventData, err := json.Marshal(core.JobEvent{TextProcessedEvent: *testEvent, Model: model, Language: "", Rate: 0, Pitch: 0, Style: "", TimeoutSeconds: 0})
+		require.NoError(t, err)
+
+		requestWhenReady(t, natsConnection, "test_subject", eventData)
+	}
+
+	waitForFailure := func(model, voice string) string {
+		testEvent := newTestEvent("test-text-key")
+		testEvent.Voice = voice
+
+		eventData, err := json.Marshal(core.JobEvent{TextProcessedEvent: *testEvent, Model: model, Language: "", Rate: 0, Pitch: 0, Style: "", TimeoutSeconds: 0})
+		require.NoError(t, err)
+		require.NoError(t, natsConnection.Publish("test_subject", eventData))
+
+		var status core.WorkflowStatus
+
+		require.Eventually(t, func() bool {
+			var getErr error
+
+			status, getErr = statusStore.Get(context.Background(), testEvent.Header.WorkflowID)
+
+			return getErr == nil && status.State == core.JobStateFailed
+		}, 5*time.Second, 10*time.Millisecond)
+
+		return status.Pages[0].Error
+	}
+
+	// Piper needs no SNAC model and accepts any voice name.
+	publishJob("fast", "")
+	assert.Equal(t, "voice.onnx:sample text", string(mockStore.uploadedData))
+
+	publishJob("cloud", "")
+	assert.Equal(t, "cloud audio", string(mockStore.uploadedData))
+
+	assert.Contains(t, waitForFailure("cloud", "male1"), tts.ErrUnmappedVoice.Error())
+	assert.Contains(t, waitForFailure("", "amy"), worker.ErrUnsupportedVoice.Error())
+}
+
+func TestMessageHandler_LanguageRouting(t *testing.T) {
+	t.Parallel()
+
+	english := &mockTTSProcessor{
+		processShouldFail: false,
+		processedText:     nil,
+		processedCfg:      core.TTSConfig{},
+		config:            newBackendConfig("english.gguf", "snac.gguf"),
+	}
+	german := &mockTTSProcessor{
+		processShouldFail: false,
+		processedText:     nil,
+		processedCfg:      core.TTSConfig{},
+		config:            newBackendConfig("german.gguf", "snac.gguf"),
+	}
+	router := tts.NewRouter(
+		tts.Route{Processor: english, DefaultVoice: "tara", Languages: []string{"en"}},
+		map[string]tts.Route{"german": {Processor: german, DefaultVoice: "", Languages: []string{"de"}}},
+		map[string]tts.LanguageRoute{"de": {Model: "german", Voice: "anna"}},
+	)
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, router, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             router,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "test_failed",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	jobData := func(language string) []byte {
+		testEvent := newTestEvent("test-text-key")
+		testEvent.Voice = ""
+
+		eventData, marshalErr := json.Marshal(core.JobEvent{
+			TextProcessedEvent: *testEvent, Model: "", Language: language, Rate: 0, Pitch: 0, Style: "", TimeoutSeconds: 0,
+		})
+		require.NoError(t, marshalErr)
+
+		return eventData
+	}
+
+	requestWhenReady(t, natsConnection, "test_subject", jobData("de"))
+	assert.Equal(t, "german", german.processedCfg.Model)
+	assert.Equal(t, "anna", german.processedCfg.Voice)
+	assert.Nil(t, english.processedText)
+
+	require.NoError(t, natsConnection.Publish("test_subject", jobData("it")))
+
+	msg, err := failures.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+
+	var failure core.TTSJobFailedEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &failure))
+	assert.Equal(t, core.ErrorClassUnsupportedLanguage, failure.ErrorClass)
+	assert.Equal(t, []string{"de", "en"}, failure.SupportedLanguages)
+}
+
+// blockingProcessor blocks until the job context is done and reports why for the first job.
+type blockingProcessor struct {
+	once    sync.Once
+	started chan struct{}
+	stopped chan error
+}
+
+func (p *blockingProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *blockingProcessor) Process(ctx context.Context, _ []byte, _ core.TTSConfig) ([]byte, error) {
+	p.once.Do(func() { close(p.started) })
+	<-ctx.Done()
+
+	select {
+	case p.stopped <- ctx.Err():
+	default:
+	}
+
+	return nil, ctx.Err()
+}
+
+// concurrencyProcessor records how many jobs it synthesizes at once.
+type concurrencyProcessor struct {
+	mu     sync.Mutex
+	active int
+	peak   int
+	done   int
+}
+
+func (p *concurrencyProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *concurrencyProcessor) Process(_ context.Context, _ []byte, _ core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	p.active++
+	p.peak = max(p.peak, p.active)
+	p.mu.Unlock()
+
+	time.Sleep(100 * time.Millisecond)
+
+	p.mu.Lock()
+	p.active--
+	p.done++
+	p.mu.Unlock()
+
+	return sampleAudio, nil
+}
+
+func (p *concurrencyProcessor) counts() (int, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.peak, p.done
+}
+
+func TestRun_LimitsConcurrentJobs(t *testing.T) {
+	t.Parallel()
+
+	processor := &concurrencyProcessor{mu: sync.Mutex{}, active: 0, peak: 0, done: 0}
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, processor, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  2,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(newTestEvent("test-text-key"))
+	require.NoError(t, err)
+
+	// The first job waits for the subscription; the others arrive together.
+	requestWhenReady(t, natsConnection, "test_subject", eventData)
+
+	const burst = 5
+
+	for range burst {
+		require.NoError(t, natsConnection.PublishRequest("test_subject", "test_replies", eventData))
+	}
+
+	require.Eventually(t, func() bool {
+		_, done := processor.counts()
+
+		return done == burst+1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	peak, _ := processor.counts()
+	assert.Equal(t, 2, peak, "jobs run in parallel, up to the limit")
+}
+
+func TestRun_CancelStopsInFlightJobs(t *testing.T) {
+	t.Parallel()
+
+	processor := &blockingProcessor{once: sync.Once{}, started: make(chan struct{}), stopped: make(chan error, 1)}
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, processor, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         time.Hour,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(newTestEvent("test-text-key"))
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_ = natsConnection.Publish("test_subject", eventData)
+
+		select {
+		case <-processor.started:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+
+	select {
+	case stopErr := <-processor.stopped:
+		require.ErrorIs(t, stopErr, context.Canceled)
+	case <-time.After(5 * time.Second):
+		t.Fatal("cancelling Run should cancel the in-flight job")
+	}
+}
+
+func TestMessageHandler_JobTimeout(t *testing.T) {
+	t.Parallel()
+
+	processor := &blockingProcessor{once: sync.Once{}, started: make(chan struct{}), stopped: make(chan error, 1)}
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, processor, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         time.Hour,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(core.JobEvent{
+		TextProcessedEvent: *newTestEvent("test-text-key"),
+		Model:              "",
+		Language:           "",
+		Rate:               0,
+		Pitch:              0,
+		Style:              "",
+		TimeoutSeconds:     1,
+	})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_ = natsConnection.Publish("test_subject", eventData)
+
+		select {
+		case <-processor.started:
+			return true
+		case <-time.After(50 * time.Millisecond):
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+
+	select {
+	case stopErr := <-processor.stopped:
+		require.ErrorIs(t, stopErr, context.DeadlineExceeded, "the job's own timeout applies")
+	case <-time.After(5 * time.Second):
+		t.Fatal("the job should time out after its timeout_seconds")
+	}
+}
+
+func TestMessageHandler_PublishesFailures(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, _, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "test_failed",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	mockProcessor.processShouldFail = true
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	// publishUntilFailure retries until the worker's subscription is in place.
+	publishUntilFailure := func(data []byte) core.TTSJobFailedEvent {
+		var failure core.TTSJobFailedEvent
+
+		require.Eventually(t, func() bool {
+			require.NoError(t, natsConnection.Publish("test_subject", data))
+
+			msg, nextErr := failures.NextMsg(100 * time.Millisecond)
+			if nextErr != nil {
+				return false
+			}
+
+			require.NoError(t, json.Unmarshal(msg.Data, &failure))
+
+			return true
+		}, 5*time.Second, 10*time.Millisecond)
+
+		return failure
+	}
+
+	testEvent := newTestEvent("test-text-key")
+	eventData, err := json.Marshal(testEvent)
+	require.NoError(t, err)
+
+	failure := publishUntilFailure(eventData)
+	assert.Equal(t, core.ErrorClassSynthesis, failure.ErrorClass)
+	assert.Contains(t, failure.Error, errMockProcess.Error())
+	assert.Equal(t, testEvent.Header.WorkflowID, failure.Header.WorkflowID)
+	assert.Equal(t, testEvent.PageNumber, failure.PageNumber)
+	assert.Equal(t, 1, failure.Attempt)
+	assert.JSONEq(t, string(eventData), string(failure.Event))
+
+	// The worker is subscribed now, so one message is enough; skip failures of
+	// any retried copies of the first job.
+	require.NoError(t, natsConnection.Publish("test_subject", []byte("not json")))
+
+	for failure.ErrorClass != core.ErrorClassInvalidEvent {
+		msg, nextErr := failures.NextMsg(5 * time.Second)
+		require.NoError(t, nextErr)
+		require.NoError(t, json.Unmarshal(msg.Data, &failure))
+	}
+
+	assert.Empty(t, failure.Header.WorkflowID)
+	assert.JSONEq(t, `"not json"`, string(failure.Event))
+}
+
+func TestMessageHandler_ValidatesText(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "test_failed",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       20,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{
+		"control": []byte("Héllo\x00 world\x1b\a\n"),
+		"long":    []byte(strings.Repeat("a", 21)),
+		"binary":  {0xff, 0xfe, 'h', 'i'},
+	}
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(newTestEvent("control"))
+	require.NoError(t, err)
+
+	requestWhenReady(t, natsConnection, "test_subject", eventData)
+	assert.Equal(t, "Héllo world\n", string(mockProcessor.processedText), "control characters are removed")
+
+	for key, wantClass := range map[string]core.ErrorClass{
+		"long":   core.ErrorClassTextTooLong,
+		"binary": core.ErrorClassInvalidText,
+	} {
+		eventData, err = json.Marshal(newTestEvent(key))
+		require.NoError(t, err)
+		require.NoError(t, natsConnection.Publish("test_subject", eventData))
+
+		msg, nextErr := failures.NextMsg(5 * time.Second)
+		require.NoError(t, nextErr)
+
+		var failure core.TTSJobFailedEvent
+
+		require.NoError(t, json.Unmarshal(msg.Data, &failure))
+		assert.Equal(t, wantClass, failure.ErrorClass, key)
+	}
+}
+
+// wordFilter rejects text that contains "forbidden" and redacts "secret".
+type wordFilter struct{}
+
+func (wordFilter) Filter(_ context.Context, tenant string, text []byte) ([]byte, error) {
+	if bytes.Contains(text, []byte("forbidden")) {
+		return nil, fmt.Errorf("%w: tenant %s", core.ErrContentRejected, tenant)
+	}
+
+	return bytes.ReplaceAll(text, []byte("secret"), []byte("[redacted]")), nil
+}
+
+func TestMessageHandler_FiltersText(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "test_failed",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             wordFilter{},
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{
+		"secret":    []byte("The secret is out."),
+		"forbidden": []byte("A forbidden word."),
+	}
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(newTestEvent("secret"))
+	require.NoError(t, err)
+
+	requestWhenReady(t, natsConnection, "test_subject", eventData)
+	assert.Equal(t, "The [redacted] is out.", string(mockProcessor.processedText))
+
+	eventData, err = json.Marshal(newTestEvent("forbidden"))
+	require.NoError(t, err)
+	require.NoError(t, natsConnection.Publish("test_subject", eventData))
+
+	msg, err := failures.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+
+	var failure core.TTSJobFailedEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &failure))
+	assert.Equal(t, core.ErrorClassContentRejected, failure.ErrorClass)
+	assert.NotContains(t, failure.Error, "forbidden word", "the text is not repeated")
+}
+
+func TestMessageHandler_HoldsJobsUnderLoad(t *testing.T) {
+	t.Parallel()
+
+	testLogger, err := logger.New(t.TempDir(), "load-test.log")
+	require.NoError(t, err)
+
+	var overloaded atomic.Bool
+
+	overloaded.Store(true)
+
+	monitor := load.NewMonitor(
+		load.Thresholds{CPU: 0.9, Memory: 0, GPU: 0, Queue: 0},
+		load.Sources{
+			CPU: func() (float64, error) {
+				if overloaded.Load() {
+					return 1, nil
+				}
+
+				return 0.1, nil
+			},
+			Memory: nil,
+			GPU:    nil,
+		},
+		10*time.Millisecond, nil, testLogger,
+	)
+	monitor.Refresh(context.Background())
+
+	workerInstance, mockStore, _, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               monitor,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	mockStore.texts = map[string][]byte{"held": []byte("Hello.")}
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	const holdTime = 200 * time.Millisecond
+
+	go func() {
+		time.Sleep(holdTime)
+		overloaded.Store(false)
+		monitor.Refresh(context.Background())
+	}()
+
+	eventData, err := json.Marshal(newTestEvent("held"))
+	require.NoError(t, err)
+
+	start := time.Now()
+	reply := requestWhenReady(t, natsConnection, "test_subject", eventData)
+
+	assert.GreaterOrEqual(t, time.Since(start), holdTime, "the job waits until the CPU frees up")
+	assert.NotEmpty(t, reply.Data)
+}
+
+// invalidAudioProcessor stands for a backend whose output is not audio.
+type invalidAudioProcessor struct{}
+
+func (invalidAudioProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (invalidAudioProcessor) Process(_ context.Context, _ []byte, _ core.TTSConfig) ([]byte, error) {
+	return nil, fmt.Errorf("%w: the output has no samples", core.ErrInvalidAudio)
+}
+
+func TestMessageHandler_ClassifiesInvalidAudio(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, invalidAudioProcessor{}, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "test_failed",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(newTestEvent("test-text-key"))
+	require.NoError(t, err)
+
+	var failure core.TTSJobFailedEvent
+
+	// Publish until the worker's subscription is in place.
+	require.Eventually(t, func() bool {
+		require.NoError(t, natsConnection.Publish("test_subject", eventData))
+
+		msg, nextErr := failures.NextMsg(100 * time.Millisecond)
+		if nextErr != nil {
+			return false
+		}
+
+		require.NoError(t, json.Unmarshal(msg.Data, &failure))
+
+		return true
+	}, 5*time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, core.ErrorClassInvalidAudio, failure.ErrorClass)
+}
+
+// seedProcessor returns silence for the seeds in silent and a tone for the
+// others, recording the configuration of every call.
+type seedProcessor struct {
+	mu      sync.Mutex
+	silent  map[int]bool
+	configs []core.TTSConfig
+}
+
+func (p *seedProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *seedProcessor) Process(_ context.Context, _ []byte, cfg core.TTSConfig) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.configs = append(p.configs, cfg)
+
+	samples := make([]float32, 24000)
+	if !p.silent[cfg.Seed] {
+		for i := range samples {
+			samples[i] = float32(0.5 * math.Sin(2*math.Pi*440*float64(i)/24000))
+		}
+	}
+
+	return wav.EncodePCM16(samples, 24000), nil
+}
+
+// recorded returns the seeds and temperatures of the calls so far.
+func (p *seedProcessor) recorded() ([]int, []float64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	seeds := make([]int, len(p.configs))
+	temperatures := make([]float64, len(p.configs))
+
+	for i, cfg := range p.configs {
+		seeds[i] = cfg.Seed
+		temperatures[i] = cfg.Temperature
+	}
+
+	return seeds, temperatures
+}
+
+func TestMessageHandler_QualityGate(t *testing.T) {
+	t.Parallel()
+
+	processor := &seedProcessor{mu: sync.Mutex{}, silent: map[int]bool{7: true, 8: true, 20: true, 21: true, 22: true}, configs: nil}
+
+	workerInstance, _, ctx, cancel, natsConnection := setupTestWithProcessor(t, processor, worker.Options{
+		StatusStore:     nil,
+		StatusSubject:   "",
+		VersionSubject:  "",
+		DocumentSubject: "",
+		Models:          nil,
+		JobTimeout:      0,
+		MaxJobTimeout:   0,
+		FailureSubject:  "test_failed",
+		Defaults:        worker.JobDefaults{},
+		DryRun:          nil,
+		MaxTextChars:    0,
+		Quality: &quality.Gate{
+			SilenceDB:         0,
+			MaxClippedRatio:   0,
+			MinCharsPerSecond: 0,
+			MaxCharsPerSecond: 0,
+		},
+		Retry:              worker.RetryPolicy{Attempts: 3, TemperatureStep: 0.4},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	failures, err := natsConnection.SubscribeSync("test_failed")
+	require.NoError(t, err)
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	event := newTestEvent("test-text-key")
+	event.Seed = 7
+
+	eventData, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	var reply core.AudioChunkEvent
+
+	require.NoError(t, json.Unmarshal(requestWhenReady(t, natsConnection, "test_subject", eventData).Data, &reply))
+
+	seeds, temperatures := processor.recorded()
+	assert.Equal(t, []int{7, 8, 9}, seeds, "rejected audio is synthesized again with the next seed")
+	assert.InDeltaSlice(t, []float64{0.7, 0.3, 0}, temperatures, 1e-9, "and a lower temperature, down to zero")
+	assert.Equal(t, 3, reply.SynthesisAttempt)
+	assert.Equal(t, 9, reply.Config.Seed, "the reply reports the settings of the accepted audio")
+	assert.Zero(t, reply.Config.Temperature)
+
+	event.Seed = 20
+
+	eventData, err = json.Marshal(event)
+	require.NoError(t, err)
+	require.NoError(t, natsConnection.Publish("test_subject", eventData))
+
+	msg, err := failures.NextMsg(5 * time.Second)
+	require.NoError(t, err)
+
+	var failure core.TTSJobFailedEvent
+
+	require.NoError(t, json.Unmarshal(msg.Data, &failure))
+	assert.Equal(t, core.ErrorClassQuality, failure.ErrorClass)
+
+	seeds, _ = processor.recorded()
+	assert.Equal(t, []int{7, 8, 9, 20, 21, 22}, seeds, "the job fails after three attempts")
+}
+
+func TestMessageHandler_DefaultsAndReload(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, _, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:     nil,
+		StatusSubject:   "",
+		VersionSubject:  "",
+		DocumentSubject: "",
+		Models:          nil,
+		JobTimeout:      0,
+		MaxJobTimeout:   0,
+		FailureSubject:  "",
+		Defaults: worker.JobDefaults{
+			Voice:             "female1",
+			Seed:              42,
+			NGL:               0,
+			TopP:              0,
+			RepetitionPenalty: 0,
+			Temperature:       0.6,
+			Voices:            nil,
+		},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	testEvent := newTestEvent("test-text-key")
+	testEvent.Voice = ""
+	testEvent.Temperature = 0
+	testEvent.Seed = 0
+
+	eventData, err := json.Marshal(testEvent)
+	require.NoError(t, err)
+
+	requestWhenReady(t, natsConnection, "test_subject", eventData)
+	assert.Equal(t, "female1", mockProcessor.processedCfg.Voice)
+	assert.InDelta(t, 0.6, mockProcessor.processedCfg.Temperature, 1e-9)
+	assert.Equal(t, 42, mockProcessor.processedCfg.Seed)
+	assert.InDelta(t, testEvent.TopP, mockProcessor.processedCfg.TopP, 1e-9, "values set by the job are kept")
+
+	workerInstance.UpdateSettings(time.Minute, worker.JobDefaults{
+		Voice:             "male1",
+		Seed:              0,
+		NGL:               0,
+		TopP:              0,
+		RepetitionPenalty: 0,
+		Temperature:       0.3,
+		Voices: map[string]worker.VoiceProfile{
+			"male1": {TopP: 0, RepetitionPenalty: 1.3, Temperature: 0.4, Rate: 1.25},
+		},
+	})
+
+	requestWhenReady(t, natsConnection, "test_subject", eventData)
+	assert.Equal(t, "male1", mockProcessor.processedCfg.Voice)
+	assert.InDelta(t, 0.4, mockProcessor.processedCfg.Temperature, 1e-9, "the voice profile applies before the defaults")
+	assert.InDelta(t, 1.25, mockProcessor.processedCfg.Rate, 1e-9)
+	assert.InDelta(t, testEvent.RepetitionPenalty, mockProcessor.processedCfg.RepetitionPenalty, 1e-9,
+		"values set by the job override the profile")
+}
+
+func TestMessageHandler_DryRun(t *testing.T) {
+	t.Parallel()
+
+	workerInstance, mockStore, mockProcessor, ctx, cancel, natsConnection := setupTest(t, worker.Options{
+		StatusStore:        nil,
+		StatusSubject:      "",
+		VersionSubject:     "",
+		DocumentSubject:    "",
+		Models:             nil,
+		JobTimeout:         0,
+		MaxJobTimeout:      0,
+		FailureSubject:     "",
+		Defaults:           worker.JobDefaults{},
+		DryRun:             &worker.Estimator{CharsPerSecond: 11, RealTimeFactor: 0.5},
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         &worker.TextReport{Estimator: worker.Estimator{}, MaxSentenceWords: 0, SplitLongSentences: false},
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	})
+	defer cancel()
+
+	go func() {
+		_ = workerInstance.Run(ctx)
+	}()
+
+	eventData, err := json.Marshal(core.JobEvent{
+		TextProcessedEvent: *newTestEvent("test-text-key"),
+		Model:              "",
+		Language:           "",
+		Rate:               0.5,
+		Pitch:              0,
+		Style:              "",
+		TimeoutSeconds:     0,
+	})
+	require.NoError(t, err)
+
+	reply := requestWhenReady(t, natsConnection, "test_subject", eventData)
+
+	var estimate core.JobEstimate
+
+	require.NoError(t, json.Unmarshal(reply.Data, &estimate))
+	assert.True(t, estimate.DryRun)
+	assert.Equal(t, len("sample text"), estimate.Characters)
+	assert.Equal(t, 2, estimate.Words)
+	assert.InDelta(t, 2.0, estimate.EstimatedDurationSeconds, 1e-9, "11 characters at 11 per second, half speed")
+	assert.InDelta(t, 1.0, estimate.EstimatedProcessingSeconds, 1e-9)
+	assert.Equal(t, "default", estimate.Config.Voice)
+	require.NotNil(t, estimate.TextStats)
+	assert.Equal(t, 2, estimate.TextStats.Words)
+	assert.Equal(t, 1, estimate.TextStats.Sentences)
+
+	assert.Equal(t, "test-text-key", mockStore.downloadedKey)
+	assert.Nil(t, mockProcessor.processedText, "the engine is not called")
+	assert.Empty(t, mockStore.uploadedKey, "nothing is uploaded")
+}
+
+func TestEstimator_Estimate(t *testing.T) {
+	t.Parallel()
+
+	estimate := worker.Estimator{CharsPerSecond: 0, RealTimeFactor: 0}.Estimate([]byte("  Hello,\n\n   wide   world. "), 0)
+	assert.Equal(t, len("Hello, wide world."), estimate.Characters, "whitespace runs count once")
+	assert.Equal(t, 3, estimate.Words)
+	assert.Equal(t, time.Duration(float64(estimate.Characters)/worker.DefaultCharsPerSecond*float64(time.Second)), estimate.Duration)
+	assert.Equal(t, estimate.Duration, estimate.Processing)
+}
+
+// rawProcessor returns its output unchanged, whatever it is asked to synthesize.
+type rawProcessor struct {
+	output []byte
+}
+
+func (p *rawProcessor) GetConfig() core.TTSConfig {
+	return core.TTSConfig{}
+}
+
+func (p *rawProcessor) Process(_ context.Context, _ []byte, _ core.TTSConfig) ([]byte, error) {
+	return p.output, nil
+}
+
+func TestNatsWorker_SelfTest(t *testing.T) {
+	t.Parallel()
+
+	opts := worker.Options{
+		StatusStore:     nil,
+		StatusSubject:   "",
+		VersionSubject:  "",
+		DocumentSubject: "",
+		Models:          nil,
+		JobTimeout:      0,
+		MaxJobTimeout:   0,
+		FailureSubject:  "",
+		Defaults: worker.JobDefaults{
+			Voice:             "tara",
+			Seed:              0,
+			NGL:               0,
+			TopP:              0.9,
+			RepetitionPenalty: 1.1,
+			Temperature:       0.6,
+			Voices:            nil,
+		},
+		DryRun:             nil,
+		MaxTextChars:       0,
+		Quality:            nil,
+		Retry:              worker.RetryPolicy{},
+		Manifests:          nil,
+		TextReport:         nil,
+		Filter:             nil,
+		Load:               nil,
+		MaxConcurrentJobs:  0,
+		MaxPendingMessages: 0,
+		StreamSubject:      "",
+		StreamAudioSubject: "",
+		StreamIdleTimeout:  0,
+	}
+
+	workerInstance, _, mockProcessor, _, cancel, _ := setupTest(t, opts)
+	defer cancel()
+
+	info, err := workerInstance.SelfTest(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []byte(worker.SelfTestText), mockProcessor.processedText)
+	assert.Equal(t, "tara", mockProcessor.processedCfg.Voice, "the job defaults apply")
+	assert.Equal(t, 24000, info.SampleRate)
+	assert.Equal(t, 36000, info.Frames)
+
+	mockProcessor.processShouldFail = true
+	_, err = workerInstance.SelfTest(context.Background())
+	require.ErrorIs(t, err, worker.ErrSelfTestFailed)
+	require.ErrorIs(t, err, errMockProcess)
+
+	for name, output := range map[string][]byte{
+		"not WAV": []byte("chatllm: model not found"),
+		"empty":   wav.EncodePCM16(nil, 24000),
+	} {
+		rawWorker, _, _, rawCancel, _ := setupTestWithProcessor(t, &rawProcessor{output: output}, opts)
+
+		_, err = rawWorker.SelfTest(context.Background())
+		require.ErrorIs(t, err, worker.ErrSelfTestFailed, name)
+
+		rawCancel()
+	}
+
+	opts.Defaults.Voice = ""
+	invalidWorker, _, _, invalidCancel, _ := setupTestWithProcessor(t, &rawProcessor{output: nil}, opts)
+	defer invalidCancel()
+
+	_, err = invalidWorker.SelfTest(context.Background())
+	require.ErrorIs(t, err, worker.ErrSelfTestFailed)
+	require.ErrorIs(t, err, worker.ErrInvalidConfig, "an invalid default configuration fails too")
+}